@@ -4,10 +4,18 @@ import (
 	"context"
 	"dental-clinic-system/models/appointment"
 	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/consent"
 	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/resource"
+	"dental-clinic-system/models/schedule"
 	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
@@ -17,17 +25,25 @@ import (
 type UserService interface {
 	GetUser(ctx context.Context, id uint) (user.UserGetModel, error)
 	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+	GetUsersByRole(ctx context.Context, clinicID uint, roleName user.RoleName) ([]user.UserGetModel, error)
 }
 
 // AppointmentService defines methods to interact with appointment data
 type AppointmentService interface {
-	GetAppointments(ctx context.Context, ClinicID uint) ([]appointment.Appointment, error)
+	GetAppointments(ctx context.Context, filter appointment.ListFilter) (appointment.Page, error)
 	GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error)
 	CreateAppointment(ctx context.Context, appointment appointment.Appointment) (appointment.Appointment, error)
 	UpdateAppointment(ctx context.Context, appointment appointment.Appointment) (appointment.Appointment, error)
 	DeleteAppointment(ctx context.Context, id uint) error
 	GetDoctorAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error)
 	GetPatientAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error)
+	GetAvailableSlots(ctx context.Context, clinicID uint, doctorIDs []uint, from, to time.Time, duration time.Duration) ([]appointment.AvailableSlot, error)
+	TransitionStatus(ctx context.Context, id uint, to appointment.Status, changedByID uint, reason string) (appointment.Appointment, error)
+	GetStatusHistory(ctx context.Context, id uint) ([]appointment.StatusHistory, error)
+	CreateSeries(ctx context.Context, series appointment.Series) (appointment.Series, error)
+	GetSeries(ctx context.Context, id uint) (appointment.Series, error)
+	UpdateSeries(ctx context.Context, seriesID, appointmentID uint, scope appointment.EditScope, changes appointment.Appointment) ([]appointment.Appointment, error)
+	CancelSeries(ctx context.Context, seriesID, appointmentID uint, scope appointment.EditScope, changedByID uint, reason string) ([]appointment.Appointment, error)
 }
 
 type JwtService interface {
@@ -39,28 +55,50 @@ type PatientService interface {
 	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
 }
 
+// ProcedureService defines methods to interact with procedure data
+type ProcedureService interface {
+	GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error)
+}
+
 // AppointmentHandler handles appointment-related HTTP requests
 type AppointmentHandler struct {
 	appointmentService AppointmentService
 	userService        UserService
 	patientService     PatientService
+	procedureService   ProcedureService
 	jwtService         JwtService
 }
 
 // NewAppointmentHandler creates a new AppointmentHandler
-func NewAppointmentHandler(as AppointmentService, us UserService, ps PatientService, jwtService JwtService) *AppointmentHandler {
+func NewAppointmentHandler(as AppointmentService, us UserService, ps PatientService, prs ProcedureService, jwtService JwtService) *AppointmentHandler {
 	return &AppointmentHandler{
 		appointmentService: as,
 		userService:        us,
 		patientService:     ps,
+		procedureService:   prs,
 		jwtService:         jwtService,
 	}
 }
 
-// GetAppointments retrieves all appointments for the authenticated user's clinic
+// Paging headers of the appointment listing
+const (
+	HeaderTotalCount = "X-Total-Count"
+	HeaderNextCursor = "X-Next-Cursor"
+)
+
+// GetAppointments retrieves a page of the authenticated user's clinic appointments as a JSON array.
+// Supported query parameters: from, to (RFC3339), doctor_id, patient_id, procedure_id,
+// status (comma separated), sort (scheduled_time or created_at, "-" prefix for descending), limit and cursor.
+// The number of matching appointments is returned in X-Total-Count, and X-Next-Cursor holds the cursor of
+// the next page when there is one.
 func (h *AppointmentHandler) GetAppointments(c *fiber.Ctx) error {
 	ctx := c.Context()
 
+	filter, ok := parseListFilter(c)
+	if !ok {
+		return nil
+	}
+
 	claims, err := h.jwtService.ParseTokenFromCookie(c)
 	if err != nil {
 		log.Error().Err(err).Msg("Invalid token")
@@ -77,15 +115,97 @@ func (h *AppointmentHandler) GetAppointments(c *fiber.Ctx) error {
 		})
 	}
 
-	appointments, err := h.appointmentService.GetAppointments(ctx, authenticatedUser.ClinicID)
+	filter.ClinicID = authenticatedUser.ClinicID
+	page, err := h.appointmentService.GetAppointments(ctx, filter)
 	if err != nil {
+		if errors.Is(err, appointment.ErrInvalidListFilter) {
+			log.Warn().Err(err).Msg("Invalid appointment listing filter")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		log.Error().Err(err).Msg("Failed to fetch appointments")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch appointments",
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(appointments)
+	// The body stays a plain array for existing clients; paging details travel in headers
+	c.Set(HeaderTotalCount, strconv.FormatInt(page.TotalCount, 10))
+	if page.NextCursor != "" {
+		c.Set(HeaderNextCursor, page.NextCursor)
+	}
+	return c.Status(fiber.StatusOK).JSON(page.Appointments)
+}
+
+// parseListFilter reads the listing query parameters. When it returns false the error response has already been written.
+func parseListFilter(c *fiber.Ctx) (appointment.ListFilter, bool) {
+	var filter appointment.ListFilter
+
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Warn().Msgf("Invalid %s time: %s", name, value)
+			_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid %s time, expected RFC3339", name),
+			})
+			return appointment.ListFilter{}, false
+		}
+		*target = parsed
+	}
+
+	ids := map[string]*uint{"doctor_id": &filter.DoctorID, "patient_id": &filter.PatientID, "procedure_id": &filter.ProcedureID}
+	for name, target := range ids {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			log.Warn().Msgf("Invalid %s: %s", name, value)
+			_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid %s", name),
+			})
+			return appointment.ListFilter{}, false
+		}
+		*target = uint(id)
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			log.Warn().Msgf("Invalid limit: %s", value)
+			_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit",
+			})
+			return appointment.ListFilter{}, false
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			filter.Statuses = append(filter.Statuses, appointment.Status(strings.TrimSpace(status)))
+		}
+	}
+
+	sortBy, descending, err := appointment.ParseSort(c.Query("sort"))
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid appointment sort")
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+		return appointment.ListFilter{}, false
+	}
+	filter.SortBy = sortBy
+	filter.Descending = descending
+	filter.Cursor = c.Query("cursor")
+
+	return filter, true
 }
 
 // GetAppointment retrieves a single appointment by ID
@@ -166,10 +286,7 @@ func (h *AppointmentHandler) CreateAppointment(c *fiber.Ctx) error {
 
 	createdAppointment, err := h.appointmentService.CreateAppointment(ctx, newAppointment)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create appointment")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create appointment",
-		})
+		return writeScheduleError(c, err, "Failed to create appointment")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(createdAppointment)
@@ -237,13 +354,19 @@ func (h *AppointmentHandler) UpdateAppointment(c *fiber.Ctx) error {
 
 	// Ensure the appointment belongs to the clinic
 	updatedAppointment.ClinicID = authenticatedUser.ClinicID
+	// Status only changes through the transition endpoints
+	updatedAppointment.Status = existingAppointment.Status
+	updatedAppointment.CancellationReason = existingAppointment.CancellationReason
+	updatedAppointment.SeriesID = existingAppointment.SeriesID
+	updatedAppointment.OccurrenceIndex = existingAppointment.OccurrenceIndex
+	// Omitting both resource fields keeps the current reservations, an empty list releases them
+	if updatedAppointment.Resources == nil && updatedAppointment.ResourceIDs == nil {
+		updatedAppointment.ResourceIDs = existingAppointment.ResourceIDList()
+	}
 
 	updatedAppointment, err = h.appointmentService.UpdateAppointment(ctx, updatedAppointment)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to update appointment")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update appointment",
-		})
+		return writeScheduleError(c, err, "Failed to update appointment")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(updatedAppointment)
@@ -463,3 +586,340 @@ func (h *AppointmentHandler) GetPatientAppointments(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(appointments)
 }
+
+// GetAvailableSlots lists bookable slots for a doctor, or for every doctor of the clinic with the given role.
+// The slot length comes from duration (minutes), then from the procedure's default duration.
+func (h *AppointmentHandler) GetAvailableSlots(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	from := time.Now()
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Warn().Msgf("Invalid from time: %s", value)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid from time, expected RFC3339",
+			})
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, 7)
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Warn().Msgf("Invalid to time: %s", value)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid to time, expected RFC3339",
+			})
+		}
+		to = parsed
+	}
+
+	doctorID := c.QueryInt("doctor_id")
+	role := c.Query("role")
+	if (doctorID <= 0) == (role == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Either doctor_id or role must be given",
+		})
+	}
+
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid token")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+	}
+
+	authenticatedUser, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("User not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	duration := time.Duration(c.QueryInt("duration")) * time.Minute
+	if procedureID := c.QueryInt("procedure_id"); duration <= 0 && procedureID > 0 {
+		procedureModel, err := h.procedureService.GetProcedure(ctx, uint(procedureID))
+		if err != nil || procedureModel.ClinicID != authenticatedUser.ClinicID {
+			log.Warn().Err(err).Msg("Procedure not found")
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Procedure not found",
+			})
+		}
+		duration = time.Duration(procedureModel.DefaultDurationMinutes) * time.Minute
+	}
+	if duration <= 0 {
+		duration = appointment.DefaultDuration
+	}
+
+	var doctorIDs []uint
+	if doctorID > 0 {
+		doctor, err := h.userService.GetUser(ctx, uint(doctorID))
+		if err != nil {
+			log.Error().Err(err).Msg("Doctor not found")
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Doctor not found",
+			})
+		}
+		if doctor.ClinicID != authenticatedUser.ClinicID {
+			log.Warn().Msg("Forbidden access to doctor's slots")
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+		doctorIDs = append(doctorIDs, doctor.ID)
+	} else {
+		doctors, err := h.userService.GetUsersByRole(ctx, authenticatedUser.ClinicID, user.RoleName(role))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to fetch doctors by role")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch doctors",
+			})
+		}
+		for _, doctor := range doctors {
+			doctorIDs = append(doctorIDs, doctor.ID)
+		}
+	}
+
+	slots, err := h.appointmentService.GetAvailableSlots(ctx, authenticatedUser.ClinicID, doctorIDs, from, to, duration)
+	if err != nil {
+		if errors.Is(err, appointment.ErrInvalidSlotSearch) {
+			log.Warn().Err(err).Msg("Invalid slot search")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Error().Err(err).Msg("Failed to fetch available slots")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch available slots",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(slots)
+}
+
+// ConfirmAppointment marks a booked appointment as confirmed by the patient
+func (h *AppointmentHandler) ConfirmAppointment(c *fiber.Ctx) error {
+	return h.transitionAppointment(c, appointment.StatusConfirmed, "")
+}
+
+// CheckInAppointment marks the patient as arrived at the clinic
+func (h *AppointmentHandler) CheckInAppointment(c *fiber.Ctx) error {
+	return h.transitionAppointment(c, appointment.StatusCheckedIn, "")
+}
+
+// StartAppointment marks the patient as in the chair
+func (h *AppointmentHandler) StartAppointment(c *fiber.Ctx) error {
+	return h.transitionAppointment(c, appointment.StatusInChair, "")
+}
+
+// CompleteAppointment marks the visit as completed
+func (h *AppointmentHandler) CompleteAppointment(c *fiber.Ctx) error {
+	return h.transitionAppointment(c, appointment.StatusCompleted, "")
+}
+
+// NoShowAppointment marks the patient as not having shown up
+func (h *AppointmentHandler) NoShowAppointment(c *fiber.Ctx) error {
+	return h.transitionAppointment(c, appointment.StatusNoShow, "")
+}
+
+// CancelAppointment cancels an appointment; the request body must contain a reason
+func (h *AppointmentHandler) CancelAppointment(c *fiber.Ctx) error {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	return h.transitionAppointment(c, appointment.StatusCancelled, request.Reason)
+}
+
+// GetAppointmentHistory retrieves the status history of an appointment
+func (h *AppointmentHandler) GetAppointmentHistory(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	existingAppointment, _, ok := h.clinicAppointment(c)
+	if !ok {
+		return nil
+	}
+
+	history, err := h.appointmentService.GetStatusHistory(ctx, existingAppointment.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch appointment history")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch appointment history",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(history)
+}
+
+// transitionAppointment moves the appointment in the :id route parameter to a new status
+func (h *AppointmentHandler) transitionAppointment(c *fiber.Ctx, to appointment.Status, reason string) error {
+	ctx := c.Context()
+
+	existingAppointment, authenticatedUser, ok := h.clinicAppointment(c)
+	if !ok {
+		return nil
+	}
+
+	updatedAppointment, err := h.appointmentService.TransitionStatus(ctx, existingAppointment.ID, to, authenticatedUser.ID, reason)
+	if err != nil {
+		var transitionErr *appointment.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			log.Warn().Err(err).Msg("Invalid appointment status transition")
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+				"from":  transitionErr.From,
+				"to":    transitionErr.To,
+			})
+		}
+		if errors.Is(err, appointment.ErrCancellationReasonRequired) {
+			log.Warn().Err(err).Msg("Cancellation reason missing")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		var consentErr *consent.MissingConsentError
+		if errors.As(err, &consentErr) {
+			log.Warn().Err(err).Msg("Procedure started without signed consent")
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":            consent.ErrConsentRequired.Error(),
+				"procedure_id":     consentErr.ProcedureID,
+				"missing_consents": consentErr.Missing,
+			})
+		}
+		log.Error().Err(err).Msg("Failed to change appointment status")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change appointment status",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updatedAppointment)
+}
+
+// clinicAppointment resolves the appointment in the :id route parameter and ensures it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *AppointmentHandler) clinicAppointment(c *fiber.Ctx) (appointment.Appointment, user.UserGetModel, bool) {
+	ctx := c.Context()
+	idStr := c.Params("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		log.Warn().Msgf("Invalid appointment ID: %s", idStr)
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid appointment ID",
+		})
+		return appointment.Appointment{}, user.UserGetModel{}, false
+	}
+
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid token")
+		_ = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+		return appointment.Appointment{}, user.UserGetModel{}, false
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	var authenticatedUser user.UserGetModel
+	var existingAppointment appointment.Appointment
+	var userErr, appointmentErr error
+
+	go func() {
+		defer wg.Done()
+		authenticatedUser, userErr = h.userService.GetUserByEmail(ctx, claims.Email)
+	}()
+
+	go func() {
+		defer wg.Done()
+		existingAppointment, appointmentErr = h.appointmentService.GetAppointment(ctx, uint(id))
+	}()
+
+	wg.Wait()
+
+	if userErr != nil {
+		log.Error().Err(userErr).Msg("User not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+		return appointment.Appointment{}, user.UserGetModel{}, false
+	}
+
+	if appointmentErr != nil {
+		log.Error().Err(appointmentErr).Msg("Appointment not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Appointment not found",
+		})
+		return appointment.Appointment{}, user.UserGetModel{}, false
+	}
+
+	if authenticatedUser.ClinicID != existingAppointment.ClinicID {
+		log.Warn().Msg("Unauthorized access to appointment")
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden",
+		})
+		return appointment.Appointment{}, user.UserGetModel{}, false
+	}
+
+	return existingAppointment, authenticatedUser, true
+}
+
+// writeScheduleError maps scheduling errors returned by the appointment service to HTTP responses
+func writeScheduleError(c *fiber.Ctx, err error, message string) error {
+	var conflictErr *appointment.ConflictError
+	if errors.As(err, &conflictErr) {
+		log.Warn().Err(err).Msg("Appointment conflicts with an existing booking")
+		response := fiber.Map{
+			"error":                      "Appointment conflicts with an existing booking",
+			"participant":                conflictErr.Participant,
+			"conflicting_appointment_id": conflictErr.ConflictingAppointmentID,
+		}
+		if conflictErr.Participant == appointment.ConflictResource {
+			response["resource_id"] = conflictErr.ResourceID
+		}
+		return c.Status(fiber.StatusConflict).JSON(response)
+	}
+
+	if errors.Is(err, resource.ErrResourceNotFound) || errors.Is(err, resource.ErrResourceInactive) {
+		log.Warn().Err(err).Msg("Appointment reserves an unavailable resource")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if errors.Is(err, schedule.ErrOutsideWorkingHours) {
+		log.Warn().Err(err).Msg("Appointment is outside working hours")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Appointment is outside working hours",
+		})
+	}
+
+	if errors.Is(err, appointment.ErrAppointmentNotEditable) {
+		log.Warn().Err(err).Msg("Appointment can no longer be rescheduled")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if errors.Is(err, appointment.ErrInvalidAppointmentTime) {
+		log.Warn().Err(err).Msg("Invalid appointment time")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -6,8 +6,20 @@ import (
 
 func RegisterAppointmentRoutes(router fiber.Router, handler *AppointmentHandler) {
 	router.Get("/appointments", handler.GetAppointments)
+	router.Get("/appointments/available-slots", handler.GetAvailableSlots)
 	router.Get("/appointments/:id", handler.GetAppointment)
 	router.Post("/appointments", handler.CreateAppointment)
 	router.Put("/appointment/:id", handler.UpdateAppointment)
 	router.Delete("/appointment/:id", handler.DeleteAppointment)
+	router.Get("/appointments/:id/history", handler.GetAppointmentHistory)
+	router.Post("/appointments/:id/confirm", handler.ConfirmAppointment)
+	router.Post("/appointments/:id/check-in", handler.CheckInAppointment)
+	router.Post("/appointments/:id/start", handler.StartAppointment)
+	router.Post("/appointments/:id/complete", handler.CompleteAppointment)
+	router.Post("/appointments/:id/cancel", handler.CancelAppointment)
+	router.Post("/appointments/:id/no-show", handler.NoShowAppointment)
+	router.Post("/appointment-series", handler.CreateAppointmentSeries)
+	router.Get("/appointment-series/:id", handler.GetAppointmentSeries)
+	router.Put("/appointment-series/:id/occurrences/:appointmentId", handler.UpdateAppointmentSeries)
+	router.Post("/appointment-series/:id/occurrences/:appointmentId/cancel", handler.CancelAppointmentSeries)
 }
@@ -0,0 +1,248 @@
+package appointment
+
+import (
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateAppointmentSeries books a recurring series of appointments from an RRULE
+func (h *AppointmentHandler) CreateAppointmentSeries(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var newSeries appointment.Series
+	if err := c.BodyParser(&newSeries); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid token")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+	}
+
+	authenticatedUser, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("User not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	newSeries.ID = 0
+	newSeries.ClinicID = authenticatedUser.ClinicID
+
+	createdSeries, err := h.appointmentService.CreateSeries(ctx, newSeries)
+	if err != nil {
+		return writeSeriesError(c, err, "Failed to create appointment series")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(createdSeries)
+}
+
+// GetAppointmentSeries retrieves a series with all of its occurrences
+func (h *AppointmentHandler) GetAppointmentSeries(c *fiber.Ctx) error {
+	series, ok := h.clinicSeries(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(series)
+}
+
+// UpdateAppointmentSeries edits an occurrence of a series; the scope query parameter selects
+// this occurrence, this and following occurrences, or all occurrences
+func (h *AppointmentHandler) UpdateAppointmentSeries(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var changes appointment.Appointment
+	if err := c.BodyParser(&changes); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	appointmentID, err := strconv.Atoi(c.Params("appointmentId"))
+	if err != nil || appointmentID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid appointment ID",
+		})
+	}
+
+	series, ok := h.clinicSeries(c)
+	if !ok {
+		return nil
+	}
+
+	if changes.DoctorID != 0 {
+		doctor, err := h.userService.GetUser(ctx, changes.DoctorID)
+		if err != nil || doctor.ClinicID != series.ClinicID {
+			log.Warn().Err(err).Msg("Doctor not found")
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Doctor not found",
+			})
+		}
+	}
+
+	scope := appointment.EditScope(c.Query("scope", string(appointment.ScopeThis)))
+	updated, err := h.appointmentService.UpdateSeries(ctx, series.ID, uint(appointmentID), scope, changes)
+	if err != nil {
+		return writeSeriesError(c, err, "Failed to update appointment series")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// CancelAppointmentSeries cancels an occurrence of a series with the given scope; the body must contain a reason
+func (h *AppointmentHandler) CancelAppointmentSeries(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	appointmentID, err := strconv.Atoi(c.Params("appointmentId"))
+	if err != nil || appointmentID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid appointment ID",
+		})
+	}
+
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid token")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+	}
+
+	authenticatedUser, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("User not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	series, ok := h.clinicSeries(c)
+	if !ok {
+		return nil
+	}
+
+	scope := appointment.EditScope(c.Query("scope", string(appointment.ScopeThis)))
+	cancelled, err := h.appointmentService.CancelSeries(ctx, series.ID, uint(appointmentID), scope, authenticatedUser.ID, request.Reason)
+	if err != nil {
+		return writeSeriesError(c, err, "Failed to cancel appointment series")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(cancelled)
+}
+
+// clinicSeries resolves the series in the :id route parameter and ensures it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *AppointmentHandler) clinicSeries(c *fiber.Ctx) (appointment.Series, bool) {
+	ctx := c.Context()
+	idStr := c.Params("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		log.Warn().Msgf("Invalid series ID: %s", idStr)
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid series ID",
+		})
+		return appointment.Series{}, false
+	}
+
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid token")
+		_ = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+		return appointment.Series{}, false
+	}
+
+	authenticatedUser, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("User not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+		return appointment.Series{}, false
+	}
+
+	series, err := h.appointmentService.GetSeries(ctx, uint(id))
+	if err != nil {
+		_ = writeSeriesError(c, err, "Failed to fetch appointment series")
+		return appointment.Series{}, false
+	}
+
+	if series.ClinicID != authenticatedUser.ClinicID {
+		log.Warn().Msg("Unauthorized access to appointment series")
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden",
+		})
+		return appointment.Series{}, false
+	}
+
+	return series, true
+}
+
+// writeSeriesError maps series errors to HTTP responses, naming the occurrence that failed
+func writeSeriesError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, appointment.ErrSeriesNotFound):
+		log.Warn().Err(err).Msg("Appointment series not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Appointment series not found",
+		})
+	case errors.Is(err, appointment.ErrInvalidRecurrence),
+		errors.Is(err, appointment.ErrInvalidEditScope),
+		errors.Is(err, appointment.ErrAppointmentNotInSeries),
+		errors.Is(err, appointment.ErrCancellationReasonRequired):
+		log.Warn().Err(err).Msg("Invalid appointment series request")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var occurrenceErr *appointment.OccurrenceError
+	if errors.As(err, &occurrenceErr) {
+		status := fiber.StatusInternalServerError
+		var conflictErr *appointment.ConflictError
+		switch {
+		case errors.As(err, &conflictErr):
+			status = fiber.StatusConflict
+		case errors.Is(err, schedule.ErrOutsideWorkingHours):
+			status = fiber.StatusUnprocessableEntity
+		case errors.Is(err, appointment.ErrInvalidAppointmentTime):
+			status = fiber.StatusBadRequest
+		}
+
+		if status != fiber.StatusInternalServerError {
+			log.Warn().Err(err).Msg("Series occurrence can not be booked")
+			return c.Status(status).JSON(fiber.Map{
+				"error":            err.Error(),
+				"occurrence":       occurrenceErr.Index + 1,
+				"occurrence_start": occurrenceErr.Start,
+			})
+		}
+	}
+
+	return writeScheduleError(c, err, message)
+}
@@ -0,0 +1,313 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// maxFieldBytes is the longest text field accepted in an upload form
+const maxFieldBytes = 4 << 10
+
+// multipartOverhead allows for the boundaries and headers around an uploaded file
+const multipartOverhead = 64 << 10
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// AttachmentService defines methods to store and serve patients' files
+type AttachmentService interface {
+	MaxBytes() int64
+	Upload(ctx context.Context, upload attachment.Upload, r io.Reader) (attachment.Attachment, error)
+	GetAttachments(ctx context.Context, clinicID, patientID uint, category attachment.Category) ([]attachment.Attachment, error)
+	GetAttachment(ctx context.Context, id uint) (attachment.Attachment, error)
+	DeleteAttachment(ctx context.Context, id uint) error
+	DownloadLink(a attachment.Attachment) attachment.DownloadLink
+	OpenDownload(ctx context.Context, id uint, expires, signature string) (attachment.Attachment, io.ReadCloser, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// AttachmentHandler handles patient attachment related HTTP requests
+type AttachmentHandler struct {
+	attachmentService AttachmentService
+	patientService    PatientService
+	userService       UserService
+	jwtService        JwtService
+}
+
+// NewAttachmentHandler creates a new AttachmentHandler
+func NewAttachmentHandler(as AttachmentService, ps PatientService, us UserService, jwtService JwtService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: as,
+		patientService:    ps,
+		userService:       us,
+		jwtService:        jwtService,
+	}
+}
+
+// GetAttachments lists the patient's attachments, filtered by ?category=
+func (h *AttachmentHandler) GetAttachments(c *fiber.Ctx) error {
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	attachments, err := h.attachmentService.GetAttachments(c.Context(), authenticatedUser.ClinicID, patientModel.ID, attachment.Category(c.Query("category")))
+	if err != nil {
+		return writeAttachmentError(c, err, "Failed to fetch attachments")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(attachments)
+}
+
+// UploadAttachment attaches a file to the patient. The multipart form is read as a stream: the category
+// and description fields must come before the file field, which is stored without being held in memory.
+func (h *AttachmentHandler) UploadAttachment(c *fiber.Ctx) error {
+	if contentLength := c.Request().Header.ContentLength(); contentLength > 0 &&
+		int64(contentLength) > h.attachmentService.MaxBytes()+multipartOverhead {
+		return writeAttachmentError(c, attachment.ErrFileTooLarge, "File is too large")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || mediaType != fiber.MIMEMultipartForm || params["boundary"] == "" {
+		log.Warn().Err(err).Msg("Attachment upload is not a multipart form")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Upload the file as multipart/form-data",
+		})
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	form := multipart.NewReader(body, params["boundary"])
+
+	upload := attachment.Upload{
+		ClinicID:     authenticatedUser.ClinicID,
+		PatientID:    patientModel.ID,
+		UploadedByID: authenticatedUser.ID,
+	}
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("Invalid multipart form")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid multipart form",
+			})
+		}
+
+		switch part.FormName() {
+		case "category", "description":
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes))
+			if err != nil {
+				log.Warn().Err(err).Msg("Invalid multipart form")
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid multipart form",
+				})
+			}
+			if part.FormName() == "category" {
+				upload.Category = attachment.Category(strings.TrimSpace(string(value)))
+			} else {
+				upload.Description = string(value)
+			}
+		case "file":
+			upload.FileName = part.FileName()
+			created, err := h.attachmentService.Upload(c.Context(), upload, part)
+			if err != nil {
+				return writeAttachmentError(c, err, "Failed to store attachment")
+			}
+			return c.Status(fiber.StatusCreated).JSON(created)
+		}
+	}
+
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "A file is required in the file field",
+	})
+}
+
+// GetAttachment returns an attachment's details
+func (h *AttachmentHandler) GetAttachment(c *fiber.Ctx) error {
+	existing, ok := h.clinicAttachment(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(existing)
+}
+
+// GetDownloadLink returns a short-lived link that downloads the attachment without the session cookie,
+// e.g. for an image viewer or a new browser tab
+func (h *AttachmentHandler) GetDownloadLink(c *fiber.Ctx) error {
+	existing, ok := h.clinicAttachment(c)
+	if !ok {
+		return nil
+	}
+
+	link := h.attachmentService.DownloadLink(existing)
+	link.URL = c.BaseURL() + link.URL
+	return c.Status(fiber.StatusOK).JSON(link)
+}
+
+// DeleteAttachment removes an attachment from the patient's record
+func (h *AttachmentHandler) DeleteAttachment(c *fiber.Ctx) error {
+	existing, ok := h.clinicAttachment(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.attachmentService.DeleteAttachment(c.Context(), existing.ID); err != nil {
+		return writeAttachmentError(c, err, "Failed to delete attachment")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Download serves the file behind a signed download link
+func (h *AttachmentHandler) Download(c *fiber.Ctx) error {
+	id, ok := helpers.ParseID(c, "attachment")
+	if !ok {
+		return nil
+	}
+
+	a, file, err := h.attachmentService.OpenDownload(c.Context(), id, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		return writeAttachmentError(c, err, "Failed to open attachment")
+	}
+
+	c.Set(fiber.HeaderContentType, a.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s"; filename*=UTF-8''%s`,
+		asciiFileName(a.FileName), url.PathEscape(a.FileName)))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	c.Set("X-Content-Type-Options", "nosniff")
+	c.Set("X-Checksum-Sha256", a.SHA256)
+	return c.Status(fiber.StatusOK).SendStream(file, int(a.SizeBytes))
+}
+
+// clinicAttachment resolves the :id attachment and checks it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *AttachmentHandler) clinicAttachment(c *fiber.Ctx) (attachment.Attachment, bool) {
+	id, ok := helpers.ParseID(c, "attachment")
+	if !ok {
+		return attachment.Attachment{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return attachment.Attachment{}, false
+	}
+
+	existing, err := h.attachmentService.GetAttachment(c.Context(), id)
+	if err != nil {
+		_ = writeAttachmentError(c, err, "Failed to fetch attachment")
+		return attachment.Attachment{}, false
+	}
+
+	return existing, helpers.SameClinic(c, authenticatedUser, existing.ClinicID, "patient attachments")
+}
+
+// clinicPatient resolves the caller and the :id patient and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *AttachmentHandler) clinicPatient(c *fiber.Ctx) (user.UserGetModel, patient.Patient, bool) {
+	id, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	patientModel, err := h.patientService.GetPatient(c.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "patient attachments") {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	return authenticatedUser, patientModel, true
+}
+
+// asciiFileName replaces characters that can not appear in a quoted Content-Disposition file name
+func asciiFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// writeAttachmentError maps errors returned by the attachment service to HTTP responses
+func writeAttachmentError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, attachment.ErrAttachmentValidation):
+		log.Warn().Err(err).Msg("Attachment validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, attachment.ErrUnsupportedType):
+		log.Warn().Err(err).Msg("Attachment type rejected")
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, attachment.ErrFileTooLarge):
+		log.Warn().Err(err).Msg("Attachment too large")
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, attachment.ErrInvalidSignature):
+		log.Warn().Err(err).Msg("Invalid attachment download link")
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, attachment.ErrAttachmentNotFound), errors.Is(err, attachment.ErrObjectNotFound):
+		log.Warn().Err(err).Msg("Attachment not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
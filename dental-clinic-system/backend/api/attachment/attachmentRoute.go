@@ -0,0 +1,30 @@
+package attachment
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterAttachmentDownloadRoutes registers the public download endpoint, which is authenticated by the link's signature
+func RegisterAttachmentDownloadRoutes(router fiber.Router, handler *AttachmentHandler) {
+	router.Get("/attachments/:id/download", handler.Download)
+}
+
+func RegisterAttachmentRoutes(router fiber.Router, handler *AttachmentHandler) {
+	requireRecordManager := rbacMiddleware.RequireRole(append([]user.RoleName{user.RoleClinicAdmin}, user.ClinicalRoles...)...)
+
+	router.Get("/patients/:id/attachments", handler.GetAttachments)
+	router.Post("/patients/:id/attachments", handler.UploadAttachment)
+	router.Get("/attachments/:id", handler.GetAttachment)
+	router.Get("/attachments/:id/link", handler.GetDownloadLink)
+	router.Delete("/attachments/:id", requireRecordManager, handler.DeleteAttachment)
+}
+
+// IsUpload reports whether the request uploads an attachment. Uploads are streamed, so they are exempt
+// from the global body size limit and request timeout.
+func IsUpload(c *fiber.Ctx) bool {
+	return c.Method() == fiber.MethodPost && strings.HasPrefix(c.Path(), "/api/patients/") && strings.HasSuffix(c.Path(), "/attachments")
+}
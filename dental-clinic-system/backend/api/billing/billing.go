@@ -0,0 +1,347 @@
+package billing
+
+import (
+	"context"
+	"dental-clinic-system/application/billingService"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/user"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// BillingService defines methods to issue invoices and take payments
+type BillingService interface {
+	GetInvoices(ctx context.Context, clinicID, patientID uint, status billing.InvoiceStatus) ([]billing.Invoice, error)
+	GetUnpaidInvoices(ctx context.Context, clinicID, patientID uint) ([]billing.Invoice, error)
+	GetInvoice(ctx context.Context, id uint) (billing.Invoice, error)
+	GetPatientBalances(ctx context.Context, clinicID, patientID uint) ([]billing.Balance, error)
+	GetAuditTrail(ctx context.Context, invoiceID uint) ([]billing.AuditEntry, error)
+	CreateInvoice(ctx context.Context, clinicID, issuedByID uint, req billingService.InvoiceRequest) (billing.Invoice, error)
+	RecordPayment(ctx context.Context, invoiceID, recordedByID uint, req billingService.PaymentRequest) (billing.Invoice, error)
+	RecordRefund(ctx context.Context, invoiceID, recordedByID uint, req billingService.PaymentRequest) (billing.Invoice, error)
+	VoidInvoice(ctx context.Context, invoiceID, actorID uint, reason string) (billing.Invoice, error)
+	GetInstallmentPlan(ctx context.Context, invoiceID uint) (billing.InstallmentPlan, error)
+	CreateInstallmentPlan(ctx context.Context, invoiceID, actorID uint, req billingService.InstallmentPlanRequest) (billing.InstallmentPlan, error)
+	DeleteInstallmentPlan(ctx context.Context, invoiceID, actorID uint) error
+	GetOverdueReport(ctx context.Context, clinicID uint, asOf string) (billing.OverdueReport, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// BillingHandler handles invoice and payment related HTTP requests
+type BillingHandler struct {
+	billingService BillingService
+	patientService PatientService
+	userService    UserService
+	jwtService     JwtService
+}
+
+// NewBillingHandler creates a new BillingHandler
+func NewBillingHandler(bs BillingService, ps PatientService, us UserService, jwtService JwtService) *BillingHandler {
+	return &BillingHandler{
+		billingService: bs,
+		patientService: ps,
+		userService:    us,
+		jwtService:     jwtService,
+	}
+}
+
+// GetInvoices lists the clinic's invoices, filtered by ?patient_id= and ?status=
+func (h *BillingHandler) GetInvoices(c *fiber.Ctx) error {
+	patientID, ok := queryPatientID(c)
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	invoices, err := h.billingService.GetInvoices(c.Context(), authenticatedUser.ClinicID, patientID, billing.InvoiceStatus(c.Query("status")))
+	if err != nil {
+		return writeBillingError(c, err, "Failed to fetch invoices")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(invoices)
+}
+
+// GetUnpaidInvoices lists the clinic's invoices with an outstanding balance, filtered by ?patient_id=
+func (h *BillingHandler) GetUnpaidInvoices(c *fiber.Ctx) error {
+	patientID, ok := queryPatientID(c)
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	invoices, err := h.billingService.GetUnpaidInvoices(c.Context(), authenticatedUser.ClinicID, patientID)
+	if err != nil {
+		return writeBillingError(c, err, "Failed to fetch unpaid invoices")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(invoices)
+}
+
+// GetInvoice returns an invoice with its lines and payments
+func (h *BillingHandler) GetInvoice(c *fiber.Ctx) error {
+	invoice, _, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(invoice)
+}
+
+// GetAuditTrail returns every recorded change of an invoice
+func (h *BillingHandler) GetAuditTrail(c *fiber.Ctx) error {
+	invoice, _, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	entries, err := h.billingService.GetAuditTrail(c.Context(), invoice.ID)
+	if err != nil {
+		return writeBillingError(c, err, "Failed to fetch billing audit trail")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
+
+// CreateInvoice issues an invoice to a patient of the caller's clinic
+func (h *BillingHandler) CreateInvoice(c *fiber.Ctx) error {
+	var req billingService.InvoiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	if !h.checkPatient(c, req.PatientID, authenticatedUser) {
+		return nil
+	}
+
+	invoice, err := h.billingService.CreateInvoice(c.Context(), authenticatedUser.ClinicID, authenticatedUser.ID, req)
+	if err != nil {
+		return writeBillingError(c, err, "Failed to create invoice")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(invoice)
+}
+
+// RecordPayment books a cash, card or bank transfer payment against an invoice
+func (h *BillingHandler) RecordPayment(c *fiber.Ctx) error {
+	return h.recordPayment(c, h.billingService.RecordPayment, "Failed to record payment")
+}
+
+// RecordRefund books money paid back to the patient
+func (h *BillingHandler) RecordRefund(c *fiber.Ctx) error {
+	return h.recordPayment(c, h.billingService.RecordRefund, "Failed to record refund")
+}
+
+// VoidInvoice cancels an invoice that has no payments
+func (h *BillingHandler) VoidInvoice(c *fiber.Ctx) error {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	invoice, authenticatedUser, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	voided, err := h.billingService.VoidInvoice(c.Context(), invoice.ID, authenticatedUser.ID, req.Reason)
+	if err != nil {
+		return writeBillingError(c, err, "Failed to void invoice")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(voided)
+}
+
+// GetPatientBalance returns what the :id patient has been invoiced and still owes, per currency
+func (h *BillingHandler) GetPatientBalance(c *fiber.Ctx) error {
+	id, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	if !h.checkPatient(c, id, authenticatedUser) {
+		return nil
+	}
+
+	balances, err := h.billingService.GetPatientBalances(c.Context(), authenticatedUser.ClinicID, id)
+	if err != nil {
+		return writeBillingError(c, err, "Failed to calculate patient balance")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"patient_id": id,
+		"balances":   balances,
+	})
+}
+
+type recordFunc func(ctx context.Context, invoiceID, recordedByID uint, req billingService.PaymentRequest) (billing.Invoice, error)
+
+func (h *BillingHandler) recordPayment(c *fiber.Ctx, record recordFunc, message string) error {
+	var req billingService.PaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	invoice, authenticatedUser, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	updated, err := record(c.Context(), invoice.ID, authenticatedUser.ID, req)
+	if err != nil {
+		return writeBillingError(c, err, message)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(updated)
+}
+
+// clinicInvoice resolves the caller and the :id invoice and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *BillingHandler) clinicInvoice(c *fiber.Ctx) (billing.Invoice, user.UserGetModel, bool) {
+	id, ok := helpers.ParseID(c, "invoice")
+	if !ok {
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	invoice, err := h.billingService.GetInvoice(c.Context(), id)
+	if err != nil {
+		_ = writeBillingError(c, err, "Failed to fetch invoice")
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, invoice.ClinicID, "invoice") {
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	return invoice, authenticatedUser, true
+}
+
+// checkPatient verifies that the patient exists and belongs to the authenticated user's clinic.
+// When it returns false the error response has already been written.
+func (h *BillingHandler) checkPatient(c *fiber.Ctx, patientID uint, authenticatedUser user.UserGetModel) bool {
+	patientModel, err := h.patientService.GetPatient(c.Context(), patientID)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "patient") {
+		return false
+	}
+
+	return true
+}
+
+// queryPatientID reads the optional ?patient_id= filter.
+// When it returns false the error response has already been written.
+func queryPatientID(c *fiber.Ctx) (uint, bool) {
+	value := c.Query("patient_id")
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		log.Warn().Msgf("Invalid patient ID: %s", value)
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeBillingError maps errors returned by the billing service to HTTP responses
+func writeBillingError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, billing.ErrInvoiceValidation), errors.Is(err, billing.ErrPaymentValidation), errors.Is(err, billing.ErrInstallmentValidation):
+		log.Warn().Err(err).Msg("Billing validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, billing.ErrInvoiceNotFound),
+		errors.Is(err, billing.ErrBillingSourceNotFound),
+		errors.Is(err, billing.ErrInstallmentPlanNotFound),
+		errors.Is(err, procedure.ErrProcedureNotFound):
+		log.Warn().Err(err).Msg("Billing record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, billing.ErrAlreadyInvoiced),
+		errors.Is(err, billing.ErrInvoiceVoid),
+		errors.Is(err, billing.ErrOverpayment),
+		errors.Is(err, billing.ErrRefundExceedsPaid),
+		errors.Is(err, billing.ErrInvoiceHasPayments),
+		errors.Is(err, billing.ErrInstallmentPlanExists),
+		errors.Is(err, billing.ErrInvoiceSettled):
+		log.Warn().Err(err).Msg("Billing change conflicts with the invoice")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, billing.ErrSourceNotBillable), errors.Is(err, billing.ErrCurrencyMismatch), errors.Is(err, procedure.ErrPriceNotFound):
+		log.Warn().Err(err).Msg("Invoice line can not be billed")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
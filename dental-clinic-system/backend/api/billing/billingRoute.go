@@ -0,0 +1,28 @@
+package billing
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterBillingRoutes(router fiber.Router, handler *BillingHandler) {
+	requireBilling := rbacMiddleware.RequireRole(user.BillingRoles...)
+	requireAccountant := rbacMiddleware.RequireRole(user.RoleAccountant, user.RoleClinicAdmin)
+	requireReports := rbacMiddleware.RequireRole(user.RoleAccountant, user.RoleClinicAdmin, user.RoleManager)
+
+	router.Get("/invoices", requireBilling, handler.GetInvoices)
+	router.Get("/invoices/unpaid", requireBilling, handler.GetUnpaidInvoices)
+	router.Get("/invoices/:id", requireBilling, handler.GetInvoice)
+	router.Get("/invoices/:id/audit", requireReports, handler.GetAuditTrail)
+	router.Post("/invoices", requireBilling, handler.CreateInvoice)
+	router.Post("/invoices/:id/payments", requireBilling, handler.RecordPayment)
+	router.Post("/invoices/:id/refunds", requireAccountant, handler.RecordRefund)
+	router.Post("/invoices/:id/void", requireAccountant, handler.VoidInvoice)
+	router.Get("/invoices/:id/installments", requireBilling, handler.GetInstallmentPlan)
+	router.Post("/invoices/:id/installments", requireBilling, handler.CreateInstallmentPlan)
+	router.Delete("/invoices/:id/installments", requireAccountant, handler.DeleteInstallmentPlan)
+	router.Get("/patients/:id/balance", requireBilling, handler.GetPatientBalance)
+	router.Get("/reports/overdue-receivables", requireReports, handler.GetOverdueReport)
+}
@@ -0,0 +1,76 @@
+package billing
+
+import (
+	"dental-clinic-system/application/billingService"
+	"dental-clinic-system/helpers"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// GetInstallmentPlan returns the installment plan of an invoice
+func (h *BillingHandler) GetInstallmentPlan(c *fiber.Ctx) error {
+	invoice, _, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	plan, err := h.billingService.GetInstallmentPlan(c.Context(), invoice.ID)
+	if err != nil {
+		return writeBillingError(c, err, "Failed to fetch installment plan")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(plan)
+}
+
+// CreateInstallmentPlan spreads the balance of an invoice over dated installments
+func (h *BillingHandler) CreateInstallmentPlan(c *fiber.Ctx) error {
+	var req billingService.InstallmentPlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	invoice, authenticatedUser, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	plan, err := h.billingService.CreateInstallmentPlan(c.Context(), invoice.ID, authenticatedUser.ID, req)
+	if err != nil {
+		return writeBillingError(c, err, "Failed to create installment plan")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(plan)
+}
+
+// DeleteInstallmentPlan removes the installment plan of an invoice
+func (h *BillingHandler) DeleteInstallmentPlan(c *fiber.Ctx) error {
+	invoice, authenticatedUser, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.billingService.DeleteInstallmentPlan(c.Context(), invoice.ID, authenticatedUser.ID); err != nil {
+		return writeBillingError(c, err, "Failed to delete installment plan")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetOverdueReport returns the clinic's overdue installments by patient, as of ?as_of= or today
+func (h *BillingHandler) GetOverdueReport(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	report, err := h.billingService.GetOverdueReport(c.Context(), authenticatedUser.ClinicID, c.Query("as_of"))
+	if err != nil {
+		return writeBillingError(c, err, "Failed to build overdue receivables report")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
@@ -0,0 +1,249 @@
+package calendar
+
+import (
+	"context"
+	"dental-clinic-system/application/calendarService"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/calendar"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUser(ctx context.Context, id uint) (user.UserGetModel, error)
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// AppointmentService defines methods to interact with appointment data
+type AppointmentService interface {
+	GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error)
+}
+
+// CalendarService defines methods to build calendar feeds and manage feed tokens
+type CalendarService interface {
+	CreateFeedToken(ctx context.Context, clinicID, doctorID, createdByID uint) (calendar.FeedToken, string, error)
+	GetFeedTokens(ctx context.Context, doctorID uint) ([]calendar.FeedToken, error)
+	GetFeedToken(ctx context.Context, id uint) (calendar.FeedToken, error)
+	RevokeFeedToken(ctx context.Context, id uint) error
+	GetDoctorFeed(ctx context.Context, tokenValue string) ([]byte, error)
+	GetAppointmentCalendar(appt appointment.Appointment) []byte
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// CalendarHandler handles iCalendar feed related HTTP requests
+type CalendarHandler struct {
+	calendarService    CalendarService
+	appointmentService AppointmentService
+	userService        UserService
+	jwtService         JwtService
+}
+
+// NewCalendarHandler creates a new CalendarHandler
+func NewCalendarHandler(cs CalendarService, as AppointmentService, us UserService, jwtService JwtService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService:    cs,
+		appointmentService: as,
+		userService:        us,
+		jwtService:         jwtService,
+	}
+}
+
+// feedManagerRoles may manage the calendar feeds of any doctor in their clinic
+var feedManagerRoles = []user.RoleName{user.RoleClinicAdmin, user.RoleManager}
+
+// GetDoctorFeed serves the read-only calendar feed identified by the token in the URL
+func (h *CalendarHandler) GetDoctorFeed(c *fiber.Ctx) error {
+	ctx := c.Context()
+	tokenValue := strings.TrimSuffix(c.Params("token"), ".ics")
+
+	feed, err := h.calendarService.GetDoctorFeed(ctx, tokenValue)
+	if err != nil {
+		if errors.Is(err, calendar.ErrFeedTokenNotFound) {
+			log.Warn().Msg("Unknown or revoked calendar feed token")
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Calendar feed not found",
+			})
+		}
+		log.Error().Err(err).Msg("Failed to build calendar feed")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build calendar feed",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, calendarService.ContentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.Status(fiber.StatusOK).Send(feed)
+}
+
+// DownloadAppointment returns a single appointment as an .ics file
+func (h *CalendarHandler) DownloadAppointment(c *fiber.Ctx) error {
+	ctx := c.Context()
+	id, ok := helpers.ParseID(c, "appointment")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	retrievedAppointment, err := h.appointmentService.GetAppointment(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("Appointment not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Appointment not found",
+		})
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, retrievedAppointment.ClinicID, "appointment") {
+		return nil
+	}
+
+	c.Set(fiber.HeaderContentType, calendarService.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="appointment-%d.ics"`, retrievedAppointment.ID))
+	return c.Status(fiber.StatusOK).Send(h.calendarService.GetAppointmentCalendar(retrievedAppointment))
+}
+
+// CreateFeedToken issues a new calendar feed token for a doctor.
+// The token is only returned in this response.
+func (h *CalendarHandler) CreateFeedToken(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	doctor, authenticatedUser, ok := h.feedDoctor(c)
+	if !ok {
+		return nil
+	}
+
+	token, value, err := h.calendarService.CreateFeedToken(ctx, doctor.ClinicID, doctor.ID, authenticatedUser.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create calendar feed token")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create calendar feed token",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"feed_token": token,
+		"token":      value,
+		"feed_path":  "/calendar/feeds/" + value + ".ics",
+	})
+}
+
+// GetFeedTokens lists the calendar feed tokens of a doctor
+func (h *CalendarHandler) GetFeedTokens(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	doctor, _, ok := h.feedDoctor(c)
+	if !ok {
+		return nil
+	}
+
+	tokens, err := h.calendarService.GetFeedTokens(ctx, doctor.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch calendar feed tokens")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch calendar feed tokens",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// RevokeFeedToken revokes a calendar feed token
+func (h *CalendarHandler) RevokeFeedToken(c *fiber.Ctx) error {
+	ctx := c.Context()
+	id, ok := helpers.ParseID(c, "feed token")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	token, err := h.calendarService.GetFeedToken(ctx, id)
+	if err != nil {
+		if errors.Is(err, calendar.ErrFeedTokenNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Calendar feed token not found",
+			})
+		}
+		log.Error().Err(err).Msg("Failed to fetch calendar feed token")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch calendar feed token",
+		})
+	}
+
+	if !canManageFeed(authenticatedUser, token.ClinicID, token.DoctorID) {
+		log.Warn().Msg("Forbidden access to calendar feed token")
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden",
+		})
+	}
+
+	if err := h.calendarService.RevokeFeedToken(ctx, token.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to revoke calendar feed token")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke calendar feed token",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Calendar feed token revoked successfully",
+	})
+}
+
+// canManageFeed reports whether the user may manage the feeds of the doctor: doctors manage their own,
+// clinic admins and managers manage every doctor of their clinic
+func canManageFeed(u user.UserGetModel, clinicID, doctorID uint) bool {
+	if u.ClinicID != clinicID {
+		return false
+	}
+	return u.ID == doctorID || u.HasRole(feedManagerRoles...)
+}
+
+// feedDoctor resolves the doctor in the :id route parameter and ensures the caller may manage their feeds.
+// When it returns false the error response has already been written.
+func (h *CalendarHandler) feedDoctor(c *fiber.Ctx) (user.UserGetModel, user.UserGetModel, bool) {
+	doctorID, ok := helpers.ParseID(c, "doctor")
+	if !ok {
+		return user.UserGetModel{}, user.UserGetModel{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, user.UserGetModel{}, false
+	}
+
+	doctor, err := h.userService.GetUser(c.Context(), doctorID)
+	if err != nil {
+		log.Error().Err(err).Msg("Doctor not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Doctor not found",
+		})
+		return user.UserGetModel{}, user.UserGetModel{}, false
+	}
+
+	if !canManageFeed(authenticatedUser, doctor.ClinicID, doctor.ID) {
+		log.Warn().Msg("Forbidden access to doctor's calendar feeds")
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden",
+		})
+		return user.UserGetModel{}, user.UserGetModel{}, false
+	}
+
+	return doctor, authenticatedUser, true
+}
@@ -0,0 +1,17 @@
+package calendar
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterCalendarFeedRoutes registers the public feed endpoint, which is authenticated by the token in the URL
+func RegisterCalendarFeedRoutes(router fiber.Router, handler *CalendarHandler) {
+	router.Get("/calendar/feeds/:token", handler.GetDoctorFeed)
+}
+
+func RegisterCalendarRoutes(router fiber.Router, handler *CalendarHandler) {
+	router.Get("/appointments/:id/ics", handler.DownloadAppointment)
+	router.Get("/doctors/:id/calendar-feeds", handler.GetFeedTokens)
+	router.Post("/doctors/:id/calendar-feeds", handler.CreateFeedToken)
+	router.Delete("/calendar-feeds/:id", handler.RevokeFeedToken)
+}
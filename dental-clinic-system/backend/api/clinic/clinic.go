@@ -6,6 +6,7 @@ import (
 	"dental-clinic-system/models/clinic"
 	"dental-clinic-system/models/user"
 	"errors"
+	"io"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -29,6 +30,8 @@ type ClinicService interface {
 	UpdateClinic(ctx context.Context, clinic clinic.Clinic) (clinic.Clinic, error)
 	DeleteClinic(ctx context.Context, id uint) error
 	CheckClinicExist(ctx context.Context, cln clinic.Clinic) (bool, error)
+	UpdateLogo(ctx context.Context, id uint, data []byte) error
+	DeleteLogo(ctx context.Context, id uint) error
 }
 
 type RoleService interface {
@@ -402,3 +405,125 @@ func (h *ClinicHandler) CheckClinicExist(c *fiber.Ctx) error {
 	response := map[string]bool{"exists": exists}
 	return c.Status(fiber.StatusOK).JSON(response)
 }
+
+// UpdateLogo replaces the logo printed on the documents of the user's clinic
+func (h *ClinicHandler) UpdateLogo(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	fileHeader, err := c.FormFile("logo")
+	if err != nil {
+		log.Warn().
+			Str("operation", "UpdateLogo").
+			Err(err).
+			Msg("Logo file is missing")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "An image file is required in the logo field",
+		})
+	}
+	if fileHeader.Size > clinic.MaxLogoBytes {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": "Logo file is too large",
+		})
+	}
+
+	// Extract authenticatedUser from cookie
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Warn().
+			Str("operation", "UpdateLogo").
+			Err(err).
+			Msg("Unauthorized access - invalid token")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	authenticatedUser, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		log.Warn().
+			Str("operation", "UpdateLogo").
+			Err(err).
+			Msg("Unauthorized access - user not found")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error().
+			Str("operation", "UpdateLogo").
+			Err(err).
+			Msg("Failed to open uploaded logo")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read uploaded file",
+		})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, clinic.MaxLogoBytes+1))
+	if err != nil {
+		log.Error().
+			Str("operation", "UpdateLogo").
+			Err(err).
+			Msg("Failed to read uploaded logo")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read uploaded file",
+		})
+	}
+
+	if err := h.clinicService.UpdateLogo(ctx, authenticatedUser.ClinicID, data); err != nil {
+		return writeLogoError(c, "UpdateLogo", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteLogo removes the logo of the user's clinic
+func (h *ClinicHandler) DeleteLogo(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	// Extract authenticatedUser from cookie
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Warn().
+			Str("operation", "DeleteLogo").
+			Err(err).
+			Msg("Unauthorized access - invalid token")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	authenticatedUser, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		log.Warn().
+			Str("operation", "DeleteLogo").
+			Err(err).
+			Msg("Unauthorized access - user not found")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.clinicService.DeleteLogo(ctx, authenticatedUser.ClinicID); err != nil {
+		return writeLogoError(c, "DeleteLogo", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func writeLogoError(c *fiber.Ctx, operation string, err error) error {
+	switch {
+	case errors.Is(err, clinic.ErrInvalidLogo):
+		log.Warn().Str("operation", operation).Err(err).Msg("Invalid clinic logo")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, clinic.ErrClinicNotFound):
+		log.Warn().Str("operation", operation).Err(err).Msg("Clinic not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Clinic not found",
+		})
+	}
+	log.Error().Str("operation", operation).Err(err).Msg("Failed to update clinic logo")
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to update clinic logo",
+	})
+}
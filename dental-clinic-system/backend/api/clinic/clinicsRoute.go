@@ -12,5 +12,7 @@ func RegisterClinicRoutes(router fiber.Router, handler *ClinicHandler) {
 	router.Get("/clinic/:id", handler.GetClinic)
 	//router.Post("/clinic", handler.CreateClinic)
 	router.Put("/clinic", rbacMiddleware.RequireRole(user.RoleClinicAdmin, user.RoleSuperAdmin), handler.UpdateClinic)
+	router.Put("/clinic/logo", rbacMiddleware.RequireRole(user.RoleClinicAdmin, user.RoleSuperAdmin), handler.UpdateLogo)
+	router.Delete("/clinic/logo", rbacMiddleware.RequireRole(user.RoleClinicAdmin, user.RoleSuperAdmin), handler.DeleteLogo)
 	//router.Delete("/clinic/{id}", handler.DeleteClinic)
 }
@@ -0,0 +1,343 @@
+package clinicalNote
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/clinicalnote"
+	"dental-clinic-system/models/user"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// ClinicalNoteService defines methods to manage note templates and clinical notes
+type ClinicalNoteService interface {
+	GetTemplates(ctx context.Context, clinicID uint) ([]clinicalnote.Template, error)
+	GetTemplate(ctx context.Context, id uint) (clinicalnote.Template, error)
+	CreateTemplate(ctx context.Context, template clinicalnote.Template) (clinicalnote.Template, error)
+	UpdateTemplate(ctx context.Context, template clinicalnote.Template) (clinicalnote.Template, error)
+	GetNotes(ctx context.Context, clinicID, patientID, appointmentID uint) ([]clinicalnote.Note, error)
+	GetNote(ctx context.Context, id uint) (clinicalnote.Note, error)
+	CreateNote(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error)
+	UpdateNote(ctx context.Context, id, editorID uint, changes clinicalnote.Note) (clinicalnote.Note, error)
+	SignNote(ctx context.Context, id, signerID uint) (clinicalnote.Note, error)
+	DeleteNote(ctx context.Context, id, userID uint) error
+	AddAddendum(ctx context.Context, noteID, authorID uint, body string) (clinicalnote.Addendum, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// ClinicalNoteHandler handles clinical note related HTTP requests
+type ClinicalNoteHandler struct {
+	clinicalNoteService ClinicalNoteService
+	userService         UserService
+	jwtService          JwtService
+}
+
+// NewClinicalNoteHandler creates a new ClinicalNoteHandler
+func NewClinicalNoteHandler(cns ClinicalNoteService, us UserService, jwtService JwtService) *ClinicalNoteHandler {
+	return &ClinicalNoteHandler{
+		clinicalNoteService: cns,
+		userService:         us,
+		jwtService:          jwtService,
+	}
+}
+
+// GetTemplates lists the clinic's note templates
+func (h *ClinicalNoteHandler) GetTemplates(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	templates, err := h.clinicalNoteService.GetTemplates(c.Context(), authenticatedUser.ClinicID)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to fetch clinical note templates")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(templates)
+}
+
+// CreateTemplate adds a note template to the clinic
+func (h *ClinicalNoteHandler) CreateTemplate(c *fiber.Ctx) error {
+	var template clinicalnote.Template
+	if !parseBody(c, &template) {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+	template.ClinicID = authenticatedUser.ClinicID
+
+	created, err := h.clinicalNoteService.CreateTemplate(c.Context(), template)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to create clinical note template")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateTemplate changes a note template; setting active to false retires it for new notes
+func (h *ClinicalNoteHandler) UpdateTemplate(c *fiber.Ctx) error {
+	var template clinicalnote.Template
+	if !parseBody(c, &template) {
+		return nil
+	}
+
+	id, ok := helpers.ParseID(c, "template")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	existing, err := h.clinicalNoteService.GetTemplate(c.Context(), id)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to fetch clinical note template")
+	}
+	if !helpers.SameClinic(c, authenticatedUser, existing.ClinicID, "clinical note") {
+		return nil
+	}
+
+	template.Model = existing.Model
+	template.ClinicID = existing.ClinicID
+
+	updated, err := h.clinicalNoteService.UpdateTemplate(c.Context(), template)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to update clinical note template")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// GetNotes lists the clinic's clinical notes, newest first, filtered by ?patient_id= and ?appointment_id=
+func (h *ClinicalNoteHandler) GetNotes(c *fiber.Ctx) error {
+	patientID, ok := parseQueryID(c, "patient_id", "patient")
+	if !ok {
+		return nil
+	}
+	appointmentID, ok := parseQueryID(c, "appointment_id", "appointment")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	notes, err := h.clinicalNoteService.GetNotes(c.Context(), authenticatedUser.ClinicID, patientID, appointmentID)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to fetch clinical notes")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(notes)
+}
+
+// GetNote returns a clinical note with its sections and addenda
+func (h *ClinicalNoteHandler) GetNote(c *fiber.Ctx) error {
+	_, note, ok := h.clinicNote(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(note)
+}
+
+// CreateNote starts a draft note for an appointment, written by the caller
+func (h *ClinicalNoteHandler) CreateNote(c *fiber.Ctx) error {
+	var note clinicalnote.Note
+	if !parseBody(c, &note) {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	note.ClinicID = authenticatedUser.ClinicID
+	note.AuthorID = authenticatedUser.ID
+
+	created, err := h.clinicalNoteService.CreateNote(c.Context(), note)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to create clinical note")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateNote changes the title and sections of a draft written by the caller
+func (h *ClinicalNoteHandler) UpdateNote(c *fiber.Ctx) error {
+	var changes clinicalnote.Note
+	if !parseBody(c, &changes) {
+		return nil
+	}
+
+	authenticatedUser, note, ok := h.clinicNote(c)
+	if !ok {
+		return nil
+	}
+
+	updated, err := h.clinicalNoteService.UpdateNote(c.Context(), note.ID, authenticatedUser.ID, changes)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to update clinical note")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// SignNote signs a draft written by the caller, after which it can only be corrected by addenda
+func (h *ClinicalNoteHandler) SignNote(c *fiber.Ctx) error {
+	authenticatedUser, note, ok := h.clinicNote(c)
+	if !ok {
+		return nil
+	}
+
+	signed, err := h.clinicalNoteService.SignNote(c.Context(), note.ID, authenticatedUser.ID)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to sign clinical note")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(signed)
+}
+
+// DeleteNote discards a draft written by the caller
+func (h *ClinicalNoteHandler) DeleteNote(c *fiber.Ctx) error {
+	authenticatedUser, note, ok := h.clinicNote(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.clinicalNoteService.DeleteNote(c.Context(), note.ID, authenticatedUser.ID); err != nil {
+		return writeNoteError(c, err, "Failed to delete clinical note")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AddAddendum appends a correction to a signed note
+func (h *ClinicalNoteHandler) AddAddendum(c *fiber.Ctx) error {
+	var req struct {
+		Body string `json:"body"`
+	}
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	authenticatedUser, note, ok := h.clinicNote(c)
+	if !ok {
+		return nil
+	}
+
+	addendum, err := h.clinicalNoteService.AddAddendum(c.Context(), note.ID, authenticatedUser.ID, req.Body)
+	if err != nil {
+		return writeNoteError(c, err, "Failed to add addendum")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(addendum)
+}
+
+// clinicNote resolves the caller and the :id note and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *ClinicalNoteHandler) clinicNote(c *fiber.Ctx) (user.UserGetModel, clinicalnote.Note, bool) {
+	id, ok := helpers.ParseID(c, "note")
+	if !ok {
+		return user.UserGetModel{}, clinicalnote.Note{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, clinicalnote.Note{}, false
+	}
+
+	note, err := h.clinicalNoteService.GetNote(c.Context(), id)
+	if err != nil {
+		_ = writeNoteError(c, err, "Failed to fetch clinical note")
+		return user.UserGetModel{}, clinicalnote.Note{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, note.ClinicID, "clinical note") {
+		return user.UserGetModel{}, clinicalnote.Note{}, false
+	}
+
+	return authenticatedUser, note, true
+}
+
+// parseQueryID reads an optional ID query parameter, 0 when it is absent.
+// When it returns false the error response has already been written.
+func parseQueryID(c *fiber.Ctx, key, name string) (uint, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		log.Warn().Msgf("Invalid %s ID: %s", name, value)
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid " + name + " ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseBody decodes the request payload. When it returns false the error response has already been written.
+func parseBody(c *fiber.Ctx, out interface{}) bool {
+	if err := c.BodyParser(out); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+		return false
+	}
+	return true
+}
+
+// writeNoteError maps errors returned by the clinical note service to HTTP responses
+func writeNoteError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, clinicalnote.ErrNoteValidation), errors.Is(err, clinicalnote.ErrTemplateValidation):
+		log.Warn().Err(err).Msg("Clinical note validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, clinicalnote.ErrNoteNotFound), errors.Is(err, clinicalnote.ErrTemplateNotFound),
+		errors.Is(err, clinicalnote.ErrAppointmentNotFound):
+		log.Warn().Err(err).Msg("Clinical note record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, clinicalnote.ErrNotAuthor):
+		log.Warn().Err(err).Msg("Clinical note changed by someone other than its author")
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, clinicalnote.ErrNoteSigned), errors.Is(err, clinicalnote.ErrNoteNotSigned),
+		errors.Is(err, clinicalnote.ErrAppointmentNotDocumentable):
+		log.Warn().Err(err).Msg("Clinical note is not in a state that allows the change")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,25 @@
+package clinicalNote
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterClinicalNoteRoutes(router fiber.Router, handler *ClinicalNoteHandler) {
+	requireNoteReader := rbacMiddleware.RequireRole(user.ClinicalNoteRoles...)
+	requireNoteAuthor := rbacMiddleware.RequireRole(user.NoteSigningRoles...)
+	requireTemplateManager := rbacMiddleware.RequireRole(append([]user.RoleName{user.RoleClinicAdmin}, user.NoteSigningRoles...)...)
+
+	router.Get("/clinical-note-templates", requireNoteReader, handler.GetTemplates)
+	router.Post("/clinical-note-templates", requireTemplateManager, handler.CreateTemplate)
+	router.Put("/clinical-note-templates/:id", requireTemplateManager, handler.UpdateTemplate)
+	router.Get("/clinical-notes", requireNoteReader, handler.GetNotes)
+	router.Get("/clinical-notes/:id", requireNoteReader, handler.GetNote)
+	router.Post("/clinical-notes", requireNoteAuthor, handler.CreateNote)
+	router.Put("/clinical-notes/:id", requireNoteAuthor, handler.UpdateNote)
+	router.Delete("/clinical-notes/:id", requireNoteAuthor, handler.DeleteNote)
+	router.Post("/clinical-notes/:id/sign", requireNoteAuthor, handler.SignNote)
+	router.Post("/clinical-notes/:id/addenda", requireNoteAuthor, handler.AddAddendum)
+}
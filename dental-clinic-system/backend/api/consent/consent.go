@@ -0,0 +1,374 @@
+package consent
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// ConsentService defines methods to manage consent templates and patients' consent forms
+type ConsentService interface {
+	GetTemplates(ctx context.Context, clinicID uint) ([]consent.Template, error)
+	GetTemplate(ctx context.Context, id uint) (consent.Template, error)
+	CreateTemplate(ctx context.Context, template consent.Template, createdByID uint) (consent.Template, error)
+	UpdateTemplate(ctx context.Context, template consent.Template, editorID uint) (consent.Template, error)
+	GetTemplateVersions(ctx context.Context, templateID uint) ([]consent.TemplateVersion, error)
+	GetForms(ctx context.Context, clinicID, patientID uint) ([]consent.Form, error)
+	GetForm(ctx context.Context, id uint) (consent.Form, error)
+	PrepareForm(ctx context.Context, form consent.Form) (consent.Form, error)
+	SignForm(ctx context.Context, id uint, req consent.SignRequest, witnessID uint, ip string) (consent.Form, error)
+	DeleteForm(ctx context.Context, id uint) error
+	DocumentLink(ctx context.Context, form consent.Form) (attachment.DownloadLink, error)
+	RequiredConsents(ctx context.Context, clinicID, patientID, procedureID uint) ([]consent.Requirement, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// ConsentHandler handles consent form related HTTP requests
+type ConsentHandler struct {
+	consentService ConsentService
+	patientService PatientService
+	userService    UserService
+	jwtService     JwtService
+}
+
+// NewConsentHandler creates a new ConsentHandler
+func NewConsentHandler(cs ConsentService, ps PatientService, us UserService, jwtService JwtService) *ConsentHandler {
+	return &ConsentHandler{
+		consentService: cs,
+		patientService: ps,
+		userService:    us,
+		jwtService:     jwtService,
+	}
+}
+
+// GetTemplates lists the clinic's consent templates
+func (h *ConsentHandler) GetTemplates(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	templates, err := h.consentService.GetTemplates(c.Context(), authenticatedUser.ClinicID)
+	if err != nil {
+		return writeConsentError(c, err, "Failed to fetch consent templates")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(templates)
+}
+
+// CreateTemplate adds a consent template to the clinic
+func (h *ConsentHandler) CreateTemplate(c *fiber.Ctx) error {
+	var template consent.Template
+	if !parseBody(c, &template) {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+	template.ClinicID = authenticatedUser.ClinicID
+
+	created, err := h.consentService.CreateTemplate(c.Context(), template, authenticatedUser.ID)
+	if err != nil {
+		return writeConsentError(c, err, "Failed to create consent template")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateTemplate changes a consent template. A new title or body becomes a new version; forms that were
+// already prepared keep the text they were prepared with. Setting active to false retires the template.
+func (h *ConsentHandler) UpdateTemplate(c *fiber.Ctx) error {
+	var template consent.Template
+	if !parseBody(c, &template) {
+		return nil
+	}
+
+	authenticatedUser, existing, ok := h.clinicTemplate(c)
+	if !ok {
+		return nil
+	}
+
+	template.Model = existing.Model
+	template.ClinicID = existing.ClinicID
+
+	updated, err := h.consentService.UpdateTemplate(c.Context(), template, authenticatedUser.ID)
+	if err != nil {
+		return writeConsentError(c, err, "Failed to update consent template")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// GetTemplateVersions lists every version of a consent template, newest first
+func (h *ConsentHandler) GetTemplateVersions(c *fiber.Ctx) error {
+	_, existing, ok := h.clinicTemplate(c)
+	if !ok {
+		return nil
+	}
+
+	versions, err := h.consentService.GetTemplateVersions(c.Context(), existing.ID)
+	if err != nil {
+		return writeConsentError(c, err, "Failed to fetch consent template versions")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(versions)
+}
+
+// GetForms lists the patient's consent forms, newest first
+func (h *ConsentHandler) GetForms(c *fiber.Ctx) error {
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	forms, err := h.consentService.GetForms(c.Context(), authenticatedUser.ClinicID, patientModel.ID)
+	if err != nil {
+		return writeConsentError(c, err, "Failed to fetch consent forms")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(forms)
+}
+
+// PrepareForm prepares a consent form for the patient from a template and procedure, ready to be signed
+func (h *ConsentHandler) PrepareForm(c *fiber.Ctx) error {
+	var form consent.Form
+	if !parseBody(c, &form) {
+		return nil
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	form.ClinicID = authenticatedUser.ClinicID
+	form.PatientID = patientModel.ID
+	form.PreparedByID = authenticatedUser.ID
+
+	created, err := h.consentService.PrepareForm(c.Context(), form)
+	if err != nil {
+		return writeConsentError(c, err, "Failed to prepare consent form")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// GetRequiredConsents lists the consents the ?procedure_id= procedure needs and which of them the patient has signed
+func (h *ConsentHandler) GetRequiredConsents(c *fiber.Ctx) error {
+	procedureStr := c.Query("procedure_id")
+	procedureID, err := strconv.Atoi(procedureStr)
+	if err != nil || procedureID <= 0 {
+		log.Warn().Msgf("Invalid procedure ID: %s", procedureStr)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid procedure ID",
+		})
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	requirements, err := h.consentService.RequiredConsents(c.Context(), authenticatedUser.ClinicID, patientModel.ID, uint(procedureID))
+	if err != nil {
+		return writeConsentError(c, err, "Failed to fetch required consents")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(requirements)
+}
+
+// GetForm returns a consent form
+func (h *ConsentHandler) GetForm(c *fiber.Ctx) error {
+	_, form, ok := h.clinicForm(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(form)
+}
+
+// SignForm records the patient's signature on a pending form, witnessed by the caller
+func (h *ConsentHandler) SignForm(c *fiber.Ctx) error {
+	var req consent.SignRequest
+	if !parseBody(c, &req) {
+		return nil
+	}
+
+	authenticatedUser, form, ok := h.clinicForm(c)
+	if !ok {
+		return nil
+	}
+
+	signed, err := h.consentService.SignForm(c.Context(), form.ID, req, authenticatedUser.ID, c.IP())
+	if err != nil {
+		return writeConsentError(c, err, "Failed to sign consent form")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(signed)
+}
+
+// DeleteForm removes a form that has not been signed
+func (h *ConsentHandler) DeleteForm(c *fiber.Ctx) error {
+	_, form, ok := h.clinicForm(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.consentService.DeleteForm(c.Context(), form.ID); err != nil {
+		return writeConsentError(c, err, "Failed to delete consent form")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetDocumentLink returns a short-lived link that downloads the signed PDF of a form
+func (h *ConsentHandler) GetDocumentLink(c *fiber.Ctx) error {
+	_, form, ok := h.clinicForm(c)
+	if !ok {
+		return nil
+	}
+
+	link, err := h.consentService.DocumentLink(c.Context(), form)
+	if err != nil {
+		return writeConsentError(c, err, "Failed to fetch signed consent form")
+	}
+	link.URL = c.BaseURL() + link.URL
+
+	return c.Status(fiber.StatusOK).JSON(link)
+}
+
+// clinicTemplate resolves the caller and the :id template and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *ConsentHandler) clinicTemplate(c *fiber.Ctx) (user.UserGetModel, consent.Template, bool) {
+	id, ok := helpers.ParseID(c, "template")
+	if !ok {
+		return user.UserGetModel{}, consent.Template{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, consent.Template{}, false
+	}
+
+	template, err := h.consentService.GetTemplate(c.Context(), id)
+	if err != nil {
+		_ = writeConsentError(c, err, "Failed to fetch consent template")
+		return user.UserGetModel{}, consent.Template{}, false
+	}
+
+	return authenticatedUser, template, helpers.SameClinic(c, authenticatedUser, template.ClinicID, "consent forms")
+}
+
+// clinicForm resolves the caller and the :id form and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *ConsentHandler) clinicForm(c *fiber.Ctx) (user.UserGetModel, consent.Form, bool) {
+	id, ok := helpers.ParseID(c, "consent form")
+	if !ok {
+		return user.UserGetModel{}, consent.Form{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, consent.Form{}, false
+	}
+
+	form, err := h.consentService.GetForm(c.Context(), id)
+	if err != nil {
+		_ = writeConsentError(c, err, "Failed to fetch consent form")
+		return user.UserGetModel{}, consent.Form{}, false
+	}
+
+	return authenticatedUser, form, helpers.SameClinic(c, authenticatedUser, form.ClinicID, "consent forms")
+}
+
+// clinicPatient resolves the caller and the :id patient and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *ConsentHandler) clinicPatient(c *fiber.Ctx) (user.UserGetModel, patient.Patient, bool) {
+	id, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	patientModel, err := h.patientService.GetPatient(c.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "consent forms") {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	return authenticatedUser, patientModel, true
+}
+
+// parseBody decodes the request payload. When it returns false the error response has already been written.
+func parseBody(c *fiber.Ctx, out interface{}) bool {
+	if err := c.BodyParser(out); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+		return false
+	}
+	return true
+}
+
+// writeConsentError maps errors returned by the consent service to HTTP responses
+func writeConsentError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, consent.ErrTemplateValidation), errors.Is(err, consent.ErrFormValidation):
+		log.Warn().Err(err).Msg("Consent validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, consent.ErrTemplateNotFound), errors.Is(err, consent.ErrFormNotFound),
+		errors.Is(err, consent.ErrProcedureNotFound), errors.Is(err, attachment.ErrAttachmentNotFound):
+		log.Warn().Err(err).Msg("Consent record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, consent.ErrFormSigned), errors.Is(err, consent.ErrFormNotSigned):
+		log.Warn().Err(err).Msg("Consent form state conflict")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
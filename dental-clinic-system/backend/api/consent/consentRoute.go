@@ -0,0 +1,25 @@
+package consent
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterConsentRoutes(router fiber.Router, handler *ConsentHandler) {
+	requireConsentStaff := rbacMiddleware.RequireRole(user.ConsentRoles...)
+	requireTemplateManager := rbacMiddleware.RequireRole(append([]user.RoleName{user.RoleClinicAdmin}, user.NoteSigningRoles...)...)
+
+	router.Get("/consent-templates", requireConsentStaff, handler.GetTemplates)
+	router.Post("/consent-templates", requireTemplateManager, handler.CreateTemplate)
+	router.Put("/consent-templates/:id", requireTemplateManager, handler.UpdateTemplate)
+	router.Get("/consent-templates/:id/versions", requireConsentStaff, handler.GetTemplateVersions)
+	router.Get("/patients/:id/consents", requireConsentStaff, handler.GetForms)
+	router.Post("/patients/:id/consents", requireConsentStaff, handler.PrepareForm)
+	router.Get("/patients/:id/consents/required", requireConsentStaff, handler.GetRequiredConsents)
+	router.Get("/consents/:id", requireConsentStaff, handler.GetForm)
+	router.Post("/consents/:id/sign", requireConsentStaff, handler.SignForm)
+	router.Delete("/consents/:id", requireConsentStaff, handler.DeleteForm)
+	router.Get("/consents/:id/document", requireConsentStaff, handler.GetDocumentLink)
+}
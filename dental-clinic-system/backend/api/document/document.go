@@ -0,0 +1,172 @@
+package document
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/document"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// DocumentService defines methods to render and email patient documents
+type DocumentService interface {
+	InvoicePDF(ctx context.Context, invoiceID uint) (document.Document, error)
+	TreatmentPlanPDF(ctx context.Context, planID uint) (document.Document, error)
+	VisitSummaryPDF(ctx context.Context, appointmentID uint) (document.Document, error)
+	EmailDocument(ctx context.Context, doc document.Document, to string) (string, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// DocumentHandler handles PDF download and email HTTP requests
+type DocumentHandler struct {
+	documentService DocumentService
+	userService     UserService
+	jwtService      JwtService
+}
+
+// NewDocumentHandler creates a new DocumentHandler
+func NewDocumentHandler(ds DocumentService, us UserService, jwtService JwtService) *DocumentHandler {
+	return &DocumentHandler{
+		documentService: ds,
+		userService:     us,
+		jwtService:      jwtService,
+	}
+}
+
+// DownloadInvoice returns an invoice as a PDF file
+func (h *DocumentHandler) DownloadInvoice(c *fiber.Ctx) error {
+	return h.download(c, "invoice", h.documentService.InvoicePDF)
+}
+
+// EmailInvoice emails an invoice as a PDF attachment
+func (h *DocumentHandler) EmailInvoice(c *fiber.Ctx) error {
+	return h.email(c, "invoice", h.documentService.InvoicePDF)
+}
+
+// DownloadTreatmentPlan returns the cost estimate of a treatment plan as a PDF file
+func (h *DocumentHandler) DownloadTreatmentPlan(c *fiber.Ctx) error {
+	return h.download(c, "treatment plan", h.documentService.TreatmentPlanPDF)
+}
+
+// EmailTreatmentPlan emails the cost estimate of a treatment plan as a PDF attachment
+func (h *DocumentHandler) EmailTreatmentPlan(c *fiber.Ctx) error {
+	return h.email(c, "treatment plan", h.documentService.TreatmentPlanPDF)
+}
+
+// DownloadVisitSummary returns the summary of an appointment as a PDF file
+func (h *DocumentHandler) DownloadVisitSummary(c *fiber.Ctx) error {
+	return h.download(c, "appointment", h.documentService.VisitSummaryPDF)
+}
+
+// EmailVisitSummary emails the summary of an appointment as a PDF attachment
+func (h *DocumentHandler) EmailVisitSummary(c *fiber.Ctx) error {
+	return h.email(c, "appointment", h.documentService.VisitSummaryPDF)
+}
+
+type renderFunc func(ctx context.Context, id uint) (document.Document, error)
+
+func (h *DocumentHandler) download(c *fiber.Ctx, source string, render renderFunc) error {
+	doc, ok := h.clinicDocument(c, source, render)
+	if !ok {
+		return nil
+	}
+
+	c.Set(fiber.HeaderContentType, doc.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, doc.FileName))
+	return c.Status(fiber.StatusOK).Send(doc.Content)
+}
+
+func (h *DocumentHandler) email(c *fiber.Ctx, source string, render renderFunc) error {
+	var req document.EmailRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			log.Warn().Err(err).Msg("Invalid document email request body")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	doc, ok := h.clinicDocument(c, source, render)
+	if !ok {
+		return nil
+	}
+
+	to, err := h.documentService.EmailDocument(c.Context(), doc, req.To)
+	if err != nil {
+		return writeDocumentError(c, err, "Failed to email document")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":   "Document queued for delivery",
+		"to":        to,
+		"file_name": doc.FileName,
+	})
+}
+
+// clinicDocument renders the document of the :id source and checks it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *DocumentHandler) clinicDocument(c *fiber.Ctx, source string, render renderFunc) (document.Document, bool) {
+	id, ok := helpers.ParseID(c, source)
+	if !ok {
+		return document.Document{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return document.Document{}, false
+	}
+
+	doc, err := render(c.Context(), id)
+	if err != nil {
+		_ = writeDocumentError(c, err, "Failed to render document")
+		return document.Document{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, doc.ClinicID, source) {
+		return document.Document{}, false
+	}
+
+	return doc, true
+}
+
+// writeDocumentError maps errors returned by the document service to HTTP responses
+func writeDocumentError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, billing.ErrInvoiceNotFound), errors.Is(err, treatment.ErrPlanNotFound), errors.Is(err, document.ErrAppointmentNotFound):
+		log.Warn().Err(err).Msg("Document source not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, document.ErrInvalidRecipient):
+		log.Warn().Err(err).Msg("Invalid document recipient")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, document.ErrNoRecipient), errors.Is(err, document.ErrAttachmentTooBig):
+		log.Warn().Err(err).Msg("Document can not be emailed")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,19 @@
+package document
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterDocumentRoutes(router fiber.Router, handler *DocumentHandler) {
+	requireBilling := rbacMiddleware.RequireRole(user.BillingRoles...)
+
+	router.Get("/invoices/:id/pdf", requireBilling, handler.DownloadInvoice)
+	router.Post("/invoices/:id/pdf/email", requireBilling, handler.EmailInvoice)
+	router.Get("/treatment-plans/:id/pdf", handler.DownloadTreatmentPlan)
+	router.Post("/treatment-plans/:id/pdf/email", handler.EmailTreatmentPlan)
+	router.Get("/appointments/:id/summary/pdf", handler.DownloadVisitSummary)
+	router.Post("/appointments/:id/summary/pdf/email", handler.EmailVisitSummary)
+}
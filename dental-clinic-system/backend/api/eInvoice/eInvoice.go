@@ -0,0 +1,182 @@
+package eInvoice
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/einvoice"
+	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	contentTypeXML = "application/xml; charset=utf-8"
+	contentTypeZip = "application/zip"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// BillingService is used to check that an invoice belongs to the caller's clinic
+type BillingService interface {
+	GetInvoice(ctx context.Context, id uint) (billing.Invoice, error)
+}
+
+// EInvoiceService defines methods to export and submit e-invoices
+type EInvoiceService interface {
+	ExportInvoice(ctx context.Context, invoiceID uint) (einvoice.Document, error)
+	ExportRange(ctx context.Context, clinicID uint, from, to string) ([]byte, int, error)
+	GetSubmission(ctx context.Context, invoiceID uint) (einvoice.Submission, error)
+	SubmitInvoice(ctx context.Context, invoiceID, submittedByID uint) (einvoice.Submission, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// EInvoiceHandler handles e-invoice export and submission HTTP requests
+type EInvoiceHandler struct {
+	eInvoiceService EInvoiceService
+	billingService  BillingService
+	userService     UserService
+	jwtService      JwtService
+}
+
+// NewEInvoiceHandler creates a new EInvoiceHandler
+func NewEInvoiceHandler(es EInvoiceService, bs BillingService, us UserService, jwtService JwtService) *EInvoiceHandler {
+	return &EInvoiceHandler{
+		eInvoiceService: es,
+		billingService:  bs,
+		userService:     us,
+		jwtService:      jwtService,
+	}
+}
+
+// DownloadInvoice returns the UBL-TR document of an invoice as an .xml file
+func (h *EInvoiceHandler) DownloadInvoice(c *fiber.Ctx) error {
+	invoice, _, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	doc, err := h.eInvoiceService.ExportInvoice(c.Context(), invoice.ID)
+	if err != nil {
+		return writeEInvoiceError(c, err, "Failed to export e-invoice")
+	}
+
+	c.Set(fiber.HeaderContentType, contentTypeXML)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, doc.FileName()))
+	return c.Status(fiber.StatusOK).Send(doc.XML)
+}
+
+// ExportRange returns the e-invoices the clinic issued between ?from= and ?to= as a zip archive
+func (h *EInvoiceHandler) ExportRange(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	archive, _, err := h.eInvoiceService.ExportRange(c.Context(), authenticatedUser.ClinicID, from, to)
+	if err != nil {
+		return writeEInvoiceError(c, err, "Failed to export e-invoices")
+	}
+
+	c.Set(fiber.HeaderContentType, contentTypeZip)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="e-invoices-%s-%s.zip"`, from, to))
+	return c.Status(fiber.StatusOK).Send(archive)
+}
+
+// GetSubmission returns the integrator submission of an invoice
+func (h *EInvoiceHandler) GetSubmission(c *fiber.Ctx) error {
+	invoice, _, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	submission, err := h.eInvoiceService.GetSubmission(c.Context(), invoice.ID)
+	if err != nil {
+		return writeEInvoiceError(c, err, "Failed to fetch e-invoice submission")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(submission)
+}
+
+// SubmitInvoice sends the e-invoice of an invoice to the integrator
+func (h *EInvoiceHandler) SubmitInvoice(c *fiber.Ctx) error {
+	invoice, authenticatedUser, ok := h.clinicInvoice(c)
+	if !ok {
+		return nil
+	}
+
+	submission, err := h.eInvoiceService.SubmitInvoice(c.Context(), invoice.ID, authenticatedUser.ID)
+	if err != nil {
+		return writeEInvoiceError(c, err, "Failed to submit e-invoice")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(submission)
+}
+
+// clinicInvoice resolves the caller and the :id invoice and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *EInvoiceHandler) clinicInvoice(c *fiber.Ctx) (billing.Invoice, user.UserGetModel, bool) {
+	id, ok := helpers.ParseID(c, "invoice")
+	if !ok {
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	invoice, err := h.billingService.GetInvoice(c.Context(), id)
+	if err != nil {
+		_ = writeEInvoiceError(c, err, "Failed to fetch invoice")
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, invoice.ClinicID, "invoice") {
+		return billing.Invoice{}, user.UserGetModel{}, false
+	}
+
+	return invoice, authenticatedUser, true
+}
+
+// writeEInvoiceError maps errors returned by the e-invoice service to HTTP responses
+func writeEInvoiceError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, einvoice.ErrInvalidRange):
+		log.Warn().Err(err).Msg("Invalid e-invoice export range")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, billing.ErrInvoiceNotFound), errors.Is(err, einvoice.ErrSubmissionNotFound):
+		log.Warn().Err(err).Msg("E-invoice record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, einvoice.ErrInvoiceVoid), errors.Is(err, einvoice.ErrAlreadySubmitted):
+		log.Warn().Err(err).Msg("E-invoice conflicts with the invoice")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, einvoice.ErrNotConfigured):
+		log.Warn().Err(err).Msg("Clinic is not set up for e-invoices")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,18 @@
+package eInvoice
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterEInvoiceRoutes(router fiber.Router, handler *EInvoiceHandler) {
+	requireBilling := rbacMiddleware.RequireRole(user.BillingRoles...)
+	requireAccountant := rbacMiddleware.RequireRole(user.RoleAccountant, user.RoleClinicAdmin)
+
+	router.Get("/invoices/:id/e-invoice", requireBilling, handler.DownloadInvoice)
+	router.Get("/invoices/:id/e-invoice/submission", requireBilling, handler.GetSubmission)
+	router.Post("/invoices/:id/e-invoice/submit", requireAccountant, handler.SubmitInvoice)
+	router.Get("/e-invoices/export", requireAccountant, handler.ExportRange)
+}
@@ -0,0 +1,471 @@
+package insurance
+
+import (
+	"context"
+	"dental-clinic-system/application/insuranceService"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/insurance"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// InsuranceService defines methods to manage insurance providers, policies and claims
+type InsuranceService interface {
+	GetProviders(ctx context.Context, clinicID uint) ([]insurance.Provider, error)
+	GetProvider(ctx context.Context, id uint) (insurance.Provider, error)
+	CreateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error)
+	UpdateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error)
+	GetPolicies(ctx context.Context, clinicID, patientID uint) ([]insurance.Policy, error)
+	GetPolicy(ctx context.Context, id uint) (insurance.Policy, error)
+	CreatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error)
+	UpdatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error)
+	GetCoverage(ctx context.Context, clinicID, patientID uint, date string, amount int64) ([]insurance.Coverage, error)
+	GetClaims(ctx context.Context, clinicID, patientID uint, status insurance.ClaimStatus) ([]insurance.Claim, error)
+	GetClaim(ctx context.Context, id uint) (insurance.Claim, error)
+	SubmitClaim(ctx context.Context, clinicID, invoiceID, actorID uint, req insuranceService.ClaimRequest) (insurance.Claim, error)
+	DecideClaim(ctx context.Context, claimID, actorID uint, decision insurance.Decision) (insurance.Claim, error)
+	PayClaim(ctx context.Context, claimID, recordedByID uint, req insuranceService.ClaimPaymentRequest) (insurance.Claim, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// InsuranceHandler handles insurance related HTTP requests
+type InsuranceHandler struct {
+	insuranceService InsuranceService
+	patientService   PatientService
+	userService      UserService
+	jwtService       JwtService
+}
+
+// NewInsuranceHandler creates a new InsuranceHandler
+func NewInsuranceHandler(is InsuranceService, ps PatientService, us UserService, jwtService JwtService) *InsuranceHandler {
+	return &InsuranceHandler{
+		insuranceService: is,
+		patientService:   ps,
+		userService:      us,
+		jwtService:       jwtService,
+	}
+}
+
+// GetProviders retrieves the insurance providers of the authenticated user's clinic
+func (h *InsuranceHandler) GetProviders(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	providers, err := h.insuranceService.GetProviders(c.Context(), authenticatedUser.ClinicID)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to fetch insurance providers")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(providers)
+}
+
+// CreateProvider adds an insurance provider to the authenticated user's clinic
+func (h *InsuranceHandler) CreateProvider(c *fiber.Ctx) error {
+	var provider insurance.Provider
+	if err := c.BodyParser(&provider); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	provider.ID = 0
+	provider.ClinicID = authenticatedUser.ClinicID
+
+	created, err := h.insuranceService.CreateProvider(c.Context(), provider)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to create insurance provider")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateProvider changes the details or the active flag of an insurance provider
+func (h *InsuranceHandler) UpdateProvider(c *fiber.Ctx) error {
+	id, ok := helpers.ParseID(c, "insurance provider")
+	if !ok {
+		return nil
+	}
+
+	var provider insurance.Provider
+	if err := c.BodyParser(&provider); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	existing, err := h.insuranceService.GetProvider(c.Context(), id)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to fetch insurance provider")
+	}
+	if !helpers.SameClinic(c, authenticatedUser, existing.ClinicID, "insurance provider") {
+		return nil
+	}
+
+	provider.Model = existing.Model
+	provider.ClinicID = existing.ClinicID
+
+	updated, err := h.insuranceService.UpdateProvider(c.Context(), provider)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to update insurance provider")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// GetPolicies retrieves the insurance policies of the :id patient
+func (h *InsuranceHandler) GetPolicies(c *fiber.Ctx) error {
+	patientID, authenticatedUser, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	policies, err := h.insuranceService.GetPolicies(c.Context(), authenticatedUser.ClinicID, patientID)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to fetch insurance policies")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(policies)
+}
+
+// CreatePolicy adds an insurance policy to the :id patient
+func (h *InsuranceHandler) CreatePolicy(c *fiber.Ctx) error {
+	var policy insurance.Policy
+	if err := c.BodyParser(&policy); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	patientID, authenticatedUser, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	policy.ID = 0
+	policy.ClinicID = authenticatedUser.ClinicID
+	policy.PatientID = patientID
+
+	created, err := h.insuranceService.CreatePolicy(c.Context(), policy)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to create insurance policy")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdatePolicy changes the coverage, limit, validity or active flag of an insurance policy
+func (h *InsuranceHandler) UpdatePolicy(c *fiber.Ctx) error {
+	id, ok := helpers.ParseID(c, "insurance policy")
+	if !ok {
+		return nil
+	}
+
+	var policy insurance.Policy
+	if err := c.BodyParser(&policy); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	existing, err := h.insuranceService.GetPolicy(c.Context(), id)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to fetch insurance policy")
+	}
+	if !helpers.SameClinic(c, authenticatedUser, existing.ClinicID, "insurance policy") {
+		return nil
+	}
+
+	policy.Model = existing.Model
+	policy.ClinicID = existing.ClinicID
+	policy.PatientID = existing.PatientID
+	policy.Provider = insurance.Provider{}
+
+	updated, err := h.insuranceService.UpdatePolicy(c.Context(), policy)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to update insurance policy")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// GetCoverage reports how much insurance coverage the :id patient has left on ?date= (defaults to today)
+// and, when ?amount= is given in minor units, estimates the insurer's and the patient's share of it
+func (h *InsuranceHandler) GetCoverage(c *fiber.Ctx) error {
+	var amount int64
+	if value := c.Query("amount"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			log.Warn().Msgf("Invalid amount: %s", value)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid amount",
+			})
+		}
+		amount = parsed
+	}
+
+	patientID, authenticatedUser, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	coverages, err := h.insuranceService.GetCoverage(c.Context(), authenticatedUser.ClinicID, patientID, c.Query("date"), amount)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to calculate insurance coverage")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"patient_id": patientID,
+		"policies":   coverages,
+	})
+}
+
+// GetClaims retrieves the insurance claims of the authenticated user's clinic, optionally filtered by
+// ?patient_id= and ?status=
+func (h *InsuranceHandler) GetClaims(c *fiber.Ctx) error {
+	var patientID uint
+	if value := c.Query("patient_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			log.Warn().Msgf("Invalid patient ID: %s", value)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid patient ID",
+			})
+		}
+		patientID = uint(id)
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	claimList, err := h.insuranceService.GetClaims(c.Context(), authenticatedUser.ClinicID, patientID, insurance.ClaimStatus(c.Query("status")))
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to fetch insurance claims")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(claimList)
+}
+
+// GetClaim retrieves an insurance claim
+func (h *InsuranceHandler) GetClaim(c *fiber.Ctx) error {
+	claim, _, ok := h.clinicClaim(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(claim)
+}
+
+// SubmitClaim claims the :id invoice from the insurer of one of the patient's policies
+func (h *InsuranceHandler) SubmitClaim(c *fiber.Ctx) error {
+	invoiceID, ok := helpers.ParseID(c, "invoice")
+	if !ok {
+		return nil
+	}
+
+	var req insuranceService.ClaimRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	claim, err := h.insuranceService.SubmitClaim(c.Context(), authenticatedUser.ClinicID, invoiceID, authenticatedUser.ID, req)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to submit insurance claim")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(claim)
+}
+
+// DecideClaim records whether the insurer approved, partially approved or rejected a claim
+func (h *InsuranceHandler) DecideClaim(c *fiber.Ctx) error {
+	var decision insurance.Decision
+	if err := c.BodyParser(&decision); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	claim, authenticatedUser, ok := h.clinicClaim(c)
+	if !ok {
+		return nil
+	}
+
+	decided, err := h.insuranceService.DecideClaim(c.Context(), claim.ID, authenticatedUser.ID, decision)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to decide insurance claim")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(decided)
+}
+
+// PayClaim records that the insurer paid an approved claim
+func (h *InsuranceHandler) PayClaim(c *fiber.Ctx) error {
+	var req insuranceService.ClaimPaymentRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			log.Warn().Err(err).Msg("Invalid request payload")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request payload",
+			})
+		}
+	}
+
+	claim, authenticatedUser, ok := h.clinicClaim(c)
+	if !ok {
+		return nil
+	}
+
+	paid, err := h.insuranceService.PayClaim(c.Context(), claim.ID, authenticatedUser.ID, req)
+	if err != nil {
+		return writeInsuranceError(c, err, "Failed to record insurance claim payment")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(paid)
+}
+
+// clinicClaim resolves the caller and the :id claim and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *InsuranceHandler) clinicClaim(c *fiber.Ctx) (insurance.Claim, user.UserGetModel, bool) {
+	id, ok := helpers.ParseID(c, "insurance claim")
+	if !ok {
+		return insurance.Claim{}, user.UserGetModel{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return insurance.Claim{}, user.UserGetModel{}, false
+	}
+
+	claim, err := h.insuranceService.GetClaim(c.Context(), id)
+	if err != nil {
+		_ = writeInsuranceError(c, err, "Failed to fetch insurance claim")
+		return insurance.Claim{}, user.UserGetModel{}, false
+	}
+	if !helpers.SameClinic(c, authenticatedUser, claim.ClinicID, "insurance claim") {
+		return insurance.Claim{}, user.UserGetModel{}, false
+	}
+
+	return claim, authenticatedUser, true
+}
+
+// clinicPatient resolves the caller and checks that the :id patient exists and belongs to their clinic.
+// When it returns false the error response has already been written.
+func (h *InsuranceHandler) clinicPatient(c *fiber.Ctx) (uint, user.UserGetModel, bool) {
+	patientID, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return 0, user.UserGetModel{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return 0, user.UserGetModel{}, false
+	}
+
+	patientModel, err := h.patientService.GetPatient(c.Context(), patientID)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return 0, user.UserGetModel{}, false
+	}
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "patient") {
+		return 0, user.UserGetModel{}, false
+	}
+
+	return patientID, authenticatedUser, true
+}
+
+// writeInsuranceError maps errors returned by the insurance service to HTTP responses
+func writeInsuranceError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, insurance.ErrProviderValidation),
+		errors.Is(err, insurance.ErrPolicyValidation),
+		errors.Is(err, insurance.ErrClaimValidation):
+		log.Warn().Err(err).Msg("Insurance validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, insurance.ErrProviderNotFound),
+		errors.Is(err, insurance.ErrPolicyNotFound),
+		errors.Is(err, insurance.ErrClaimNotFound),
+		errors.Is(err, billing.ErrInvoiceNotFound):
+		log.Warn().Err(err).Msg("Insurance record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, insurance.ErrClaimExists),
+		errors.Is(err, insurance.ErrClaimNotSubmitted),
+		errors.Is(err, insurance.ErrClaimNotApproved),
+		errors.Is(err, insurance.ErrInvoiceHasPlan),
+		errors.Is(err, billing.ErrInvoiceVoid),
+		errors.Is(err, billing.ErrOverpayment):
+		log.Warn().Err(err).Msg("Insurance change conflicts with the claim or invoice")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, insurance.ErrProviderInactive),
+		errors.Is(err, insurance.ErrPolicyNotValid),
+		errors.Is(err, insurance.ErrNothingToClaim):
+		log.Warn().Err(err).Msg("Invoice can not be claimed")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,26 @@
+package insurance
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterInsuranceRoutes(router fiber.Router, handler *InsuranceHandler) {
+	requireBilling := rbacMiddleware.RequireRole(user.BillingRoles...)
+
+	router.Get("/insurance/providers", handler.GetProviders)
+	router.Post("/insurance/providers", requireBilling, handler.CreateProvider)
+	router.Put("/insurance/providers/:id", requireBilling, handler.UpdateProvider)
+	router.Get("/patients/:id/policies", handler.GetPolicies)
+	router.Post("/patients/:id/policies", requireBilling, handler.CreatePolicy)
+	router.Put("/insurance/policies/:id", requireBilling, handler.UpdatePolicy)
+	// Doctors check the remaining coverage before proposing a treatment
+	router.Get("/patients/:id/insurance/coverage", handler.GetCoverage)
+	router.Get("/insurance/claims", requireBilling, handler.GetClaims)
+	router.Get("/insurance/claims/:id", requireBilling, handler.GetClaim)
+	router.Post("/invoices/:id/claims", requireBilling, handler.SubmitClaim)
+	router.Post("/insurance/claims/:id/decision", requireBilling, handler.DecideClaim)
+	router.Post("/insurance/claims/:id/payment", requireBilling, handler.PayClaim)
+}
@@ -0,0 +1,482 @@
+package medicalHistory
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// MedicalHistoryService defines methods to read and change patients' medical history
+type MedicalHistoryService interface {
+	GetQuestions() []medical.Question
+	GetRecord(ctx context.Context, clinicID, patientID uint) (medical.Record, error)
+	GetAllergy(ctx context.Context, id uint) (medical.Allergy, error)
+	CreateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error)
+	UpdateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error)
+	DeleteAllergy(ctx context.Context, id uint) error
+	GetMedication(ctx context.Context, id uint) (medical.Medication, error)
+	CreateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error)
+	UpdateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error)
+	DeleteMedication(ctx context.Context, id uint) error
+	GetCondition(ctx context.Context, id uint) (medical.Condition, error)
+	CreateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error)
+	UpdateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error)
+	DeleteCondition(ctx context.Context, id uint) error
+	GetQuestionnaires(ctx context.Context, patientID uint) ([]medical.Questionnaire, error)
+	GetQuestionnaire(ctx context.Context, id uint) (medical.Questionnaire, error)
+	SubmitQuestionnaire(ctx context.Context, questionnaire medical.Questionnaire) (medical.Questionnaire, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// MedicalHistoryHandler handles medical history related HTTP requests
+type MedicalHistoryHandler struct {
+	medicalHistoryService MedicalHistoryService
+	patientService        PatientService
+	userService           UserService
+	jwtService            JwtService
+}
+
+// NewMedicalHistoryHandler creates a new MedicalHistoryHandler
+func NewMedicalHistoryHandler(mhs MedicalHistoryService, ps PatientService, us UserService, jwtService JwtService) *MedicalHistoryHandler {
+	return &MedicalHistoryHandler{
+		medicalHistoryService: mhs,
+		patientService:        ps,
+		userService:           us,
+		jwtService:            jwtService,
+	}
+}
+
+// GetQuestions returns the questions of the medical questionnaire
+func (h *MedicalHistoryHandler) GetQuestions(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(h.medicalHistoryService.GetQuestions())
+}
+
+// GetMedicalHistory returns the patient's allergies, medications, conditions, latest questionnaire and alerts
+func (h *MedicalHistoryHandler) GetMedicalHistory(c *fiber.Ctx) error {
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	record, err := h.medicalHistoryService.GetRecord(c.Context(), authenticatedUser.ClinicID, patientModel.ID)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to fetch medical history")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(record)
+}
+
+// GetAlerts returns the patient's alerts, critical first
+func (h *MedicalHistoryHandler) GetAlerts(c *fiber.Ctx) error {
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	record, err := h.medicalHistoryService.GetRecord(c.Context(), authenticatedUser.ClinicID, patientModel.ID)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to fetch patient alerts")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(record.Alerts)
+}
+
+// CreateAllergy records an allergy of the patient
+func (h *MedicalHistoryHandler) CreateAllergy(c *fiber.Ctx) error {
+	var allergy medical.Allergy
+	if !parseBody(c, &allergy) {
+		return nil
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	allergy.ClinicID = authenticatedUser.ClinicID
+	allergy.PatientID = patientModel.ID
+	allergy.RecordedByID = authenticatedUser.ID
+
+	created, err := h.medicalHistoryService.CreateAllergy(c.Context(), allergy)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to record allergy")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateAllergy changes an allergy, e.g. its severity after a new reaction
+func (h *MedicalHistoryHandler) UpdateAllergy(c *fiber.Ctx) error {
+	var allergy medical.Allergy
+	if !parseBody(c, &allergy) {
+		return nil
+	}
+
+	existing, ok := h.clinicAllergy(c)
+	if !ok {
+		return nil
+	}
+
+	allergy.Model = existing.Model
+	allergy.ClinicID = existing.ClinicID
+	allergy.PatientID = existing.PatientID
+	allergy.RecordedByID = existing.RecordedByID
+
+	updated, err := h.medicalHistoryService.UpdateAllergy(c.Context(), allergy)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to update allergy")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// DeleteAllergy removes an allergy recorded by mistake
+func (h *MedicalHistoryHandler) DeleteAllergy(c *fiber.Ctx) error {
+	existing, ok := h.clinicAllergy(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.medicalHistoryService.DeleteAllergy(c.Context(), existing.ID); err != nil {
+		return writeMedicalError(c, err, "Failed to delete allergy")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateMedication records a medication of the patient
+func (h *MedicalHistoryHandler) CreateMedication(c *fiber.Ctx) error {
+	var medication medical.Medication
+	if !parseBody(c, &medication) {
+		return nil
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	medication.ClinicID = authenticatedUser.ClinicID
+	medication.PatientID = patientModel.ID
+	medication.RecordedByID = authenticatedUser.ID
+
+	created, err := h.medicalHistoryService.CreateMedication(c.Context(), medication)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to record medication")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateMedication changes a medication; setting end_date records that the patient stopped taking it
+func (h *MedicalHistoryHandler) UpdateMedication(c *fiber.Ctx) error {
+	var medication medical.Medication
+	if !parseBody(c, &medication) {
+		return nil
+	}
+
+	existing, ok := h.clinicMedication(c)
+	if !ok {
+		return nil
+	}
+
+	medication.Model = existing.Model
+	medication.ClinicID = existing.ClinicID
+	medication.PatientID = existing.PatientID
+	medication.RecordedByID = existing.RecordedByID
+
+	updated, err := h.medicalHistoryService.UpdateMedication(c.Context(), medication)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to update medication")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// DeleteMedication removes a medication recorded by mistake
+func (h *MedicalHistoryHandler) DeleteMedication(c *fiber.Ctx) error {
+	existing, ok := h.clinicMedication(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.medicalHistoryService.DeleteMedication(c.Context(), existing.ID); err != nil {
+		return writeMedicalError(c, err, "Failed to delete medication")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateCondition records a systemic condition of the patient
+func (h *MedicalHistoryHandler) CreateCondition(c *fiber.Ctx) error {
+	var condition medical.Condition
+	if !parseBody(c, &condition) {
+		return nil
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	condition.ClinicID = authenticatedUser.ClinicID
+	condition.PatientID = patientModel.ID
+	condition.RecordedByID = authenticatedUser.ID
+
+	created, err := h.medicalHistoryService.CreateCondition(c.Context(), condition)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to record condition")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateCondition changes a condition; setting resolved_on records that it no longer applies
+func (h *MedicalHistoryHandler) UpdateCondition(c *fiber.Ctx) error {
+	var condition medical.Condition
+	if !parseBody(c, &condition) {
+		return nil
+	}
+
+	existing, ok := h.clinicCondition(c)
+	if !ok {
+		return nil
+	}
+
+	condition.Model = existing.Model
+	condition.ClinicID = existing.ClinicID
+	condition.PatientID = existing.PatientID
+	condition.RecordedByID = existing.RecordedByID
+
+	updated, err := h.medicalHistoryService.UpdateCondition(c.Context(), condition)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to update condition")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// DeleteCondition removes a condition recorded by mistake
+func (h *MedicalHistoryHandler) DeleteCondition(c *fiber.Ctx) error {
+	existing, ok := h.clinicCondition(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.medicalHistoryService.DeleteCondition(c.Context(), existing.ID); err != nil {
+		return writeMedicalError(c, err, "Failed to delete condition")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetQuestionnaires returns every questionnaire the patient filled in, newest first
+func (h *MedicalHistoryHandler) GetQuestionnaires(c *fiber.Ctx) error {
+	_, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	questionnaires, err := h.medicalHistoryService.GetQuestionnaires(c.Context(), patientModel.ID)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to fetch medical questionnaires")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(questionnaires)
+}
+
+// SubmitQuestionnaire records a questionnaire filled in by the patient
+func (h *MedicalHistoryHandler) SubmitQuestionnaire(c *fiber.Ctx) error {
+	var questionnaire medical.Questionnaire
+	if !parseBody(c, &questionnaire) {
+		return nil
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	questionnaire.ClinicID = authenticatedUser.ClinicID
+	questionnaire.PatientID = patientModel.ID
+	questionnaire.RecordedByID = authenticatedUser.ID
+
+	submitted, err := h.medicalHistoryService.SubmitQuestionnaire(c.Context(), questionnaire)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to record medical questionnaire")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(submitted)
+}
+
+// GetQuestionnaire returns a questionnaire with its answers
+func (h *MedicalHistoryHandler) GetQuestionnaire(c *fiber.Ctx) error {
+	id, ok := helpers.ParseID(c, "questionnaire")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	questionnaire, err := h.medicalHistoryService.GetQuestionnaire(c.Context(), id)
+	if err != nil {
+		return writeMedicalError(c, err, "Failed to fetch medical questionnaire")
+	}
+	if !helpers.SameClinic(c, authenticatedUser, questionnaire.ClinicID, "medical history") {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(questionnaire)
+}
+
+// clinicAllergy resolves the :id allergy and checks it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *MedicalHistoryHandler) clinicAllergy(c *fiber.Ctx) (medical.Allergy, bool) {
+	id, ok := helpers.ParseID(c, "allergy")
+	if !ok {
+		return medical.Allergy{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return medical.Allergy{}, false
+	}
+
+	allergy, err := h.medicalHistoryService.GetAllergy(c.Context(), id)
+	if err != nil {
+		_ = writeMedicalError(c, err, "Failed to fetch allergy")
+		return medical.Allergy{}, false
+	}
+
+	return allergy, helpers.SameClinic(c, authenticatedUser, allergy.ClinicID, "medical history")
+}
+
+// clinicMedication resolves the :id medication and checks it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *MedicalHistoryHandler) clinicMedication(c *fiber.Ctx) (medical.Medication, bool) {
+	id, ok := helpers.ParseID(c, "medication")
+	if !ok {
+		return medical.Medication{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return medical.Medication{}, false
+	}
+
+	medication, err := h.medicalHistoryService.GetMedication(c.Context(), id)
+	if err != nil {
+		_ = writeMedicalError(c, err, "Failed to fetch medication")
+		return medical.Medication{}, false
+	}
+
+	return medication, helpers.SameClinic(c, authenticatedUser, medication.ClinicID, "medical history")
+}
+
+// clinicCondition resolves the :id condition and checks it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *MedicalHistoryHandler) clinicCondition(c *fiber.Ctx) (medical.Condition, bool) {
+	id, ok := helpers.ParseID(c, "condition")
+	if !ok {
+		return medical.Condition{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return medical.Condition{}, false
+	}
+
+	condition, err := h.medicalHistoryService.GetCondition(c.Context(), id)
+	if err != nil {
+		_ = writeMedicalError(c, err, "Failed to fetch condition")
+		return medical.Condition{}, false
+	}
+
+	return condition, helpers.SameClinic(c, authenticatedUser, condition.ClinicID, "medical history")
+}
+
+// clinicPatient resolves the caller and the :id patient and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *MedicalHistoryHandler) clinicPatient(c *fiber.Ctx) (user.UserGetModel, patient.Patient, bool) {
+	id, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	patientModel, err := h.patientService.GetPatient(c.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "medical history") {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	return authenticatedUser, patientModel, true
+}
+
+// parseBody decodes the request payload. When it returns false the error response has already been written.
+func parseBody(c *fiber.Ctx, out interface{}) bool {
+	if err := c.BodyParser(out); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+		return false
+	}
+	return true
+}
+
+// writeMedicalError maps errors returned by the medical history service to HTTP responses
+func writeMedicalError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, medical.ErrMedicalValidation):
+		log.Warn().Err(err).Msg("Medical history validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, medical.ErrAllergyNotFound), errors.Is(err, medical.ErrMedicationNotFound),
+		errors.Is(err, medical.ErrConditionNotFound), errors.Is(err, medical.ErrQuestionnaireNotFound):
+		log.Warn().Err(err).Msg("Medical history record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,29 @@
+package medicalHistory
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterMedicalHistoryRoutes(router fiber.Router, handler *MedicalHistoryHandler) {
+	requireClinician := rbacMiddleware.RequireRole(user.ClinicalRoles...)
+
+	router.Get("/medical/questions", handler.GetQuestions)
+	router.Get("/patients/:id/medical-history", handler.GetMedicalHistory)
+	router.Get("/patients/:id/alerts", handler.GetAlerts)
+	router.Post("/patients/:id/allergies", requireClinician, handler.CreateAllergy)
+	router.Put("/allergies/:id", requireClinician, handler.UpdateAllergy)
+	router.Delete("/allergies/:id", requireClinician, handler.DeleteAllergy)
+	router.Post("/patients/:id/medications", requireClinician, handler.CreateMedication)
+	router.Put("/medications/:id", requireClinician, handler.UpdateMedication)
+	router.Delete("/medications/:id", requireClinician, handler.DeleteMedication)
+	router.Post("/patients/:id/conditions", requireClinician, handler.CreateCondition)
+	router.Put("/conditions/:id", requireClinician, handler.UpdateCondition)
+	router.Delete("/conditions/:id", requireClinician, handler.DeleteCondition)
+	router.Get("/patients/:id/questionnaires", handler.GetQuestionnaires)
+	// Front desk staff enter the questionnaire the patient fills in at check-in
+	router.Post("/patients/:id/questionnaires", handler.SubmitQuestionnaire)
+	router.Get("/questionnaires/:id", handler.GetQuestionnaire)
+}
@@ -0,0 +1,216 @@
+package odontogram
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/odontogram"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// OdontogramService defines methods to read and change patient dental charts
+type OdontogramService interface {
+	GetChart(ctx context.Context, clinicID, patientID uint, date string) (odontogram.Chart, error)
+	GetHistory(ctx context.Context, patientID uint) ([]odontogram.Finding, error)
+	GetFinding(ctx context.Context, id uint) (odontogram.Finding, error)
+	RecordFinding(ctx context.Context, finding odontogram.Finding) (odontogram.Finding, error)
+	RemoveFinding(ctx context.Context, id, removedByID uint, reason string) (odontogram.Finding, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// OdontogramHandler handles dental chart related HTTP requests
+type OdontogramHandler struct {
+	odontogramService OdontogramService
+	patientService    PatientService
+	userService       UserService
+	jwtService        JwtService
+}
+
+// NewOdontogramHandler creates a new OdontogramHandler
+func NewOdontogramHandler(ods OdontogramService, ps PatientService, us UserService, jwtService JwtService) *OdontogramHandler {
+	return &OdontogramHandler{
+		odontogramService: ods,
+		patientService:    ps,
+		userService:       us,
+		jwtService:        jwtService,
+	}
+}
+
+// GetChart returns the patient's chart, as of ?date= (YYYY-MM-DD or RFC3339) when given
+func (h *OdontogramHandler) GetChart(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	chart, err := h.odontogramService.GetChart(ctx, authenticatedUser.ClinicID, patientModel.ID, c.Query("date"))
+	if err != nil {
+		return writeOdontogramError(c, err, "Failed to fetch dental chart")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(chart)
+}
+
+// GetHistory returns every finding ever charted for the patient, oldest first
+func (h *OdontogramHandler) GetHistory(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	_, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	history, err := h.odontogramService.GetHistory(ctx, patientModel.ID)
+	if err != nil {
+		return writeOdontogramError(c, err, "Failed to fetch dental chart history")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(history)
+}
+
+// RecordFinding charts a finding on one of the patient's teeth
+func (h *OdontogramHandler) RecordFinding(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var finding odontogram.Finding
+	if err := c.BodyParser(&finding); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	finding.ClinicID = authenticatedUser.ClinicID
+	finding.PatientID = patientModel.ID
+	finding.RecordedByID = authenticatedUser.ID
+
+	recorded, err := h.odontogramService.RecordFinding(ctx, finding)
+	if err != nil {
+		return writeOdontogramError(c, err, "Failed to record finding")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(recorded)
+}
+
+// RemoveFinding takes a finding off the chart; it stays in the chart history
+func (h *OdontogramHandler) RemoveFinding(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, ok := helpers.ParseID(c, "finding")
+	if !ok {
+		return nil
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			log.Warn().Err(err).Msg("Invalid request payload")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request payload",
+			})
+		}
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	finding, err := h.odontogramService.GetFinding(ctx, id)
+	if err != nil {
+		return writeOdontogramError(c, err, "Failed to fetch finding")
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, finding.ClinicID, "odontogram finding") {
+		return nil
+	}
+
+	removed, err := h.odontogramService.RemoveFinding(ctx, finding.ID, authenticatedUser.ID, req.Reason)
+	if err != nil {
+		return writeOdontogramError(c, err, "Failed to remove finding")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(removed)
+}
+
+// clinicPatient resolves the caller and the :id patient and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *OdontogramHandler) clinicPatient(c *fiber.Ctx) (user.UserGetModel, patient.Patient, bool) {
+	id, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	patientModel, err := h.patientService.GetPatient(c.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "patient chart") {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	return authenticatedUser, patientModel, true
+}
+
+// writeOdontogramError maps errors returned by the odontogram service to HTTP responses
+func writeOdontogramError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, odontogram.ErrFindingValidation):
+		log.Warn().Err(err).Msg("Odontogram validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, odontogram.ErrFindingNotFound):
+		log.Warn().Err(err).Msg("Odontogram finding not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, odontogram.ErrFindingConflict), errors.Is(err, odontogram.ErrFindingNotRemovable):
+		log.Warn().Err(err).Msg("Odontogram change conflicts with the current chart")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,17 @@
+package odontogram
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterOdontogramRoutes(router fiber.Router, handler *OdontogramHandler) {
+	requireClinician := rbacMiddleware.RequireRole(user.ClinicalRoles...)
+
+	router.Get("/patients/:id/odontogram", handler.GetChart)
+	router.Get("/patients/:id/odontogram/history", handler.GetHistory)
+	router.Post("/patients/:id/odontogram/findings", requireClinician, handler.RecordFinding)
+	router.Delete("/odontogram/findings/:id", requireClinician, handler.RemoveFinding)
+}
@@ -5,6 +5,7 @@ import (
 	"dental-clinic-system/models/claims"
 	"dental-clinic-system/models/patient"
 	"dental-clinic-system/models/user"
+	"errors"
 
 	"strconv"
 	"time"
@@ -19,9 +20,16 @@ type UserService interface {
 type PatientService interface {
 	GetPatients(ctx context.Context, ClinicID uint) ([]patient.Patient, error)
 	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+	SearchPatients(ctx context.Context, query patient.SearchQuery) (patient.SearchPage, error)
 	CreatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error)
 	UpdatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error)
 	DeletePatient(ctx context.Context, id uint) error
+	FindDuplicates(ctx context.Context, clinicID uint, minScore float64) ([]patient.Duplicate, error)
+	MergePatients(ctx context.Context, clinicID uint, req patient.MergeRequest, mergedByID uint) (patient.Merge, error)
+	UndoMerge(ctx context.Context, id, undoneByID uint) (patient.Merge, error)
+	GetMerges(ctx context.Context, clinicID uint) ([]patient.Merge, error)
+	GetMerge(ctx context.Context, id uint) (patient.Merge, error)
+	ResolvePatient(ctx context.Context, clinicID, id uint) (patient.Redirect, error)
 }
 
 type JwtService interface {
@@ -63,6 +71,43 @@ func (h *PatientHandler) GetPatients(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(patients)
 }
 
+// SearchPatients finds the caller's clinic's patients by name, national ID prefix, phone number or
+// birth date with ?q=, best matches first, paginated with ?page= and ?page_size=
+func (h *PatientHandler) SearchPatients(c *fiber.Ctx) error {
+	ctx := c.Context()
+	claims, err := h.jwtService.ParseTokenFromCookie(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	user, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	query := patient.SearchQuery{
+		ClinicID: user.ClinicID,
+		Query:    c.Query("q"),
+		Page:     c.QueryInt("page"),
+		PageSize: c.QueryInt("page_size"),
+	}
+	page, err := h.patientService.SearchPatients(ctx, query)
+	if err != nil {
+		if errors.Is(err, patient.ErrInvalidSearch) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search patients",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
 func (h *PatientHandler) GetPatient(c *fiber.Ctx) error {
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancelFunc()
@@ -0,0 +1,174 @@
+package patient
+
+import (
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// GetDuplicates lists pairs of the clinic's patients that are likely the same person, best first.
+// ?min_score= between 0 and 1 sets how alike a pair must be.
+func (h *PatientHandler) GetDuplicates(c *fiber.Ctx) error {
+	minScore := 0.0
+	if value := c.Query("min_score"); value != "" {
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Warn().Msgf("Invalid min_score: %s", value)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid min_score",
+			})
+		}
+		minScore = score
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	duplicates, err := h.patientService.FindDuplicates(c.Context(), authenticatedUser.ClinicID, minScore)
+	if err != nil {
+		return writeMergeError(c, err, "Failed to find duplicate patients")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(duplicates)
+}
+
+// MergePatients merges a duplicate patient into the surviving one
+func (h *PatientHandler) MergePatients(c *fiber.Ctx) error {
+	var req patient.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	merge, err := h.patientService.MergePatients(c.Context(), authenticatedUser.ClinicID, req, authenticatedUser.ID)
+	if err != nil {
+		return writeMergeError(c, err, "Failed to merge patients")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(merge)
+}
+
+// GetMerges lists the clinic's patient merges, newest first
+func (h *PatientHandler) GetMerges(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	merges, err := h.patientService.GetMerges(c.Context(), authenticatedUser.ClinicID)
+	if err != nil {
+		return writeMergeError(c, err, "Failed to fetch patient merges")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(merges)
+}
+
+// GetMerge returns a patient merge with the records it moved
+func (h *PatientHandler) GetMerge(c *fiber.Ctx) error {
+	_, merge, ok := h.clinicMerge(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(merge)
+}
+
+// UndoMerge restores the merged patient and moves its records back from the survivor
+func (h *PatientHandler) UndoMerge(c *fiber.Ctx) error {
+	authenticatedUser, merge, ok := h.clinicMerge(c)
+	if !ok {
+		return nil
+	}
+
+	undone, err := h.patientService.UndoMerge(c.Context(), merge.ID, authenticatedUser.ID)
+	if err != nil {
+		return writeMergeError(c, err, "Failed to undo patient merge")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(undone)
+}
+
+// GetRedirect tells which patient holds the records of a patient now, following merges
+func (h *PatientHandler) GetRedirect(c *fiber.Ctx) error {
+	id, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	redirect, err := h.patientService.ResolvePatient(c.Context(), authenticatedUser.ClinicID, id)
+	if err != nil {
+		return writeMergeError(c, err, "Failed to resolve patient")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(redirect)
+}
+
+// clinicMerge resolves the caller and the :id merge and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *PatientHandler) clinicMerge(c *fiber.Ctx) (user.UserGetModel, patient.Merge, bool) {
+	id, ok := helpers.ParseID(c, "merge")
+	if !ok {
+		return user.UserGetModel{}, patient.Merge{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, patient.Merge{}, false
+	}
+
+	merge, err := h.patientService.GetMerge(c.Context(), id)
+	if err != nil {
+		_ = writeMergeError(c, err, "Failed to fetch patient merge")
+		return user.UserGetModel{}, patient.Merge{}, false
+	}
+	if !helpers.SameClinic(c, authenticatedUser, merge.ClinicID, "patient merges") {
+		return user.UserGetModel{}, patient.Merge{}, false
+	}
+
+	return authenticatedUser, merge, true
+}
+
+// writeMergeError maps errors returned by duplicate search and patient merges to HTTP responses
+func writeMergeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, patient.ErrMergeValidation):
+		log.Warn().Err(err).Msg("Patient merge validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, patient.ErrPatientNotFound), errors.Is(err, patient.ErrMergeNotFound):
+		log.Warn().Err(err).Msg("Patient merge record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, patient.ErrMergeUndone), errors.Is(err, patient.ErrMergeExpired), errors.Is(err, patient.ErrMergeSuperseded):
+		log.Warn().Err(err).Msg("Patient merge can not be undone")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -1,13 +1,25 @@
 package patient
 
 import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 func RegisterPatientsRoutes(router fiber.Router, patientHandler *PatientHandler) {
 	router.Get("/patients", patientHandler.GetPatients)
+	router.Get("/patients/search", patientHandler.SearchPatients)
 	router.Get("/patients/{id}", patientHandler.GetPatient)
 	router.Post("/patients", patientHandler.CreatePatient)
 	router.Put("/patients/{id}", patientHandler.UpdatePatient)
 	router.Delete("/patients/{id}", patientHandler.DeletePatient)
+	router.Get("/patients/:id/redirect", patientHandler.GetRedirect)
+
+	requireMerger := rbacMiddleware.RequireRole(user.PatientMergeRoles...)
+	router.Get("/patient-duplicates", requireMerger, patientHandler.GetDuplicates)
+	router.Get("/patient-merges", requireMerger, patientHandler.GetMerges)
+	router.Post("/patient-merges", requireMerger, patientHandler.MergePatients)
+	router.Get("/patient-merges/:id", requireMerger, patientHandler.GetMerge)
+	router.Post("/patient-merges/:id/undo", requireMerger, patientHandler.UndoMerge)
 }
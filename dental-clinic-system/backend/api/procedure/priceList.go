@@ -0,0 +1,204 @@
+package procedure
+
+import (
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/procedure"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// GetPriceLists returns the price list versions of the caller's clinic, newest first
+func (h *ProcedureHandler) GetPriceLists(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	lists, err := h.procedureService.GetPriceLists(c.Context(), authenticatedUser.ClinicID)
+	if err != nil {
+		return writePriceListError(c, err, "Failed to fetch price lists")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(lists)
+}
+
+// GetPriceList returns a price list with its prices
+func (h *ProcedureHandler) GetPriceList(c *fiber.Ctx) error {
+	list, ok := h.clinicPriceList(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(list)
+}
+
+// CreatePriceList creates a price list version from a JSON body
+func (h *ProcedureHandler) CreatePriceList(c *fiber.Ctx) error {
+	var list procedure.PriceList
+	if err := c.BodyParser(&list); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	list.ID = 0
+	list.ClinicID = authenticatedUser.ClinicID
+	list.CreatedByID = authenticatedUser.ID
+
+	created, err := h.procedureService.CreatePriceList(c.Context(), list)
+	if err != nil {
+		return writePriceListError(c, err, "Failed to create price list")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// ImportPriceList creates a price list version from an uploaded CSV file.
+// The multipart form carries the file as "file" and the list's effective_from, name and currency.
+func (h *ProcedureHandler) ImportPriceList(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		log.Warn().Err(err).Msg("Price list file is missing")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A CSV file is required in the file field",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open uploaded price list")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read uploaded file",
+		})
+	}
+	defer file.Close()
+
+	list := procedure.PriceList{
+		ClinicID:      authenticatedUser.ClinicID,
+		Name:          c.FormValue("name"),
+		Currency:      c.FormValue("currency"),
+		EffectiveFrom: c.FormValue("effective_from"),
+		CreatedByID:   authenticatedUser.ID,
+	}
+
+	created, err := h.procedureService.ImportPriceList(c.Context(), list, file)
+	if err != nil {
+		return writePriceListError(c, err, "Failed to import price list")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// DeletePriceList deletes a price list version that has not taken effect yet
+func (h *ProcedureHandler) DeletePriceList(c *fiber.Ctx) error {
+	list, ok := h.clinicPriceList(c)
+	if !ok {
+		return nil
+	}
+
+	if err := h.procedureService.DeletePriceList(c.Context(), list.ID); err != nil {
+		return writePriceListError(c, err, "Failed to delete price list")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Price list deleted successfully",
+	})
+}
+
+// GetProcedurePrice returns the price of a procedure on ?date= (YYYY-MM-DD), today by default
+func (h *ProcedureHandler) GetProcedurePrice(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, ok := helpers.ParseID(c, "procedure")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	proc, err := h.procedureService.GetProcedure(ctx, id)
+	if err != nil {
+		log.Warn().Err(err).Msg("Procedure not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Procedure not found",
+		})
+	}
+	if !helpers.SameClinic(c, authenticatedUser, proc.ClinicID, "procedure") {
+		return nil
+	}
+
+	price, err := h.procedureService.GetPriceAt(ctx, proc.ID, c.Query("date"))
+	if err != nil {
+		return writePriceListError(c, err, "Failed to fetch procedure price")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(price)
+}
+
+// clinicPriceList resolves the :id price list and checks it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *ProcedureHandler) clinicPriceList(c *fiber.Ctx) (procedure.PriceList, bool) {
+	id, ok := helpers.ParseID(c, "price list")
+	if !ok {
+		return procedure.PriceList{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return procedure.PriceList{}, false
+	}
+
+	list, err := h.procedureService.GetPriceList(c.Context(), id)
+	if err != nil {
+		_ = writePriceListError(c, err, "Failed to fetch price list")
+		return procedure.PriceList{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, list.ClinicID, "price list") {
+		return procedure.PriceList{}, false
+	}
+
+	return list, true
+}
+
+// writePriceListError maps errors returned by the price catalogue to HTTP responses
+func writePriceListError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, procedure.ErrPriceListValidation):
+		log.Warn().Err(err).Msg("Price list validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, procedure.ErrPriceListNotFound), errors.Is(err, procedure.ErrPriceNotFound), errors.Is(err, procedure.ErrProcedureNotFound):
+		log.Warn().Err(err).Msg("Price not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, procedure.ErrPriceListConflict), errors.Is(err, procedure.ErrPriceListInEffect):
+		log.Warn().Err(err).Msg("Price list change conflicts with existing versions")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"dental-clinic-system/models/claims"
 	"dental-clinic-system/models/procedure"
+	procedureModel "dental-clinic-system/models/procedure"
 	"dental-clinic-system/models/user"
+	"errors"
+	"io"
 	"strconv"
 	"time"
 
@@ -17,6 +20,12 @@ type ProcedureService interface {
 	CreateProcedure(ctx context.Context, procedure procedure.Procedure) (procedure.Procedure, error)
 	UpdateProcedure(ctx context.Context, procedure procedure.Procedure) (procedure.Procedure, error)
 	DeleteProcedure(ctx context.Context, id uint) error
+	GetPriceLists(ctx context.Context, clinicID uint) ([]procedure.PriceList, error)
+	GetPriceList(ctx context.Context, id uint) (procedure.PriceList, error)
+	CreatePriceList(ctx context.Context, list procedure.PriceList) (procedure.PriceList, error)
+	ImportPriceList(ctx context.Context, list procedure.PriceList, file io.Reader) (procedure.PriceList, error)
+	DeletePriceList(ctx context.Context, id uint) error
+	GetPriceAt(ctx context.Context, procedureID uint, date string) (procedure.Price, error)
 }
 
 type UserService interface {
@@ -138,6 +147,11 @@ func (h *ProcedureHandler) CreateProcedure(c *fiber.Ctx) error {
 	procedure.ClinicID = user.ClinicID
 	procedure, err = h.procedureService.CreateProcedure(ctx, procedure)
 	if err != nil {
+		if errors.Is(err, procedureModel.ErrProcedureValidation) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Failed to create procedure",
 		})
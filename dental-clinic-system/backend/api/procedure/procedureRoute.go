@@ -1,13 +1,24 @@
 package procedure
 
 import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 func RegisterProcedureRoutes(router fiber.Router, handler *ProcedureHandler) {
+	requirePriceManager := rbacMiddleware.RequireRole(user.RoleClinicAdmin, user.RoleManager, user.RoleAccountant)
+
 	router.Get("/procedures", handler.GetProcedures)
 	router.Get("/procedures/{id}", handler.GetProcedure)
 	router.Post("/procedures", handler.CreateProcedure)
 	router.Put("/procedures/{id}", handler.UpdateProcedure)
 	router.Delete("/procedures/{id}", handler.DeleteProcedure)
+	router.Get("/procedures/:id/price", handler.GetProcedurePrice)
+	router.Get("/price-lists", handler.GetPriceLists)
+	router.Get("/price-lists/:id", handler.GetPriceList)
+	router.Post("/price-lists", requirePriceManager, handler.CreatePriceList)
+	router.Post("/price-lists/import", requirePriceManager, handler.ImportPriceList)
+	router.Delete("/price-lists/:id", requirePriceManager, handler.DeletePriceList)
 }
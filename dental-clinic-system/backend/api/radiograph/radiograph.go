@@ -0,0 +1,286 @@
+package radiograph
+
+import (
+	"bytes"
+	"context"
+	"dental-clinic-system/application/radiographService"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/radiograph"
+	"dental-clinic-system/models/user"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// multipartOverhead allows for the boundaries and headers around an uploaded file
+const multipartOverhead = 64 << 10
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// AttachmentService tells how large uploaded files may be
+type AttachmentService interface {
+	MaxBytes() int64
+}
+
+// RadiographService defines methods to ingest and list DICOM radiographs
+type RadiographService interface {
+	Ingest(ctx context.Context, req radiographService.IngestRequest, r io.Reader) (radiograph.Image, error)
+	GetTimeline(ctx context.Context, clinicID, patientID uint, filter radiograph.Filter) ([]radiograph.Study, error)
+	GetImage(ctx context.Context, id uint) (radiograph.Image, error)
+	GetPreview(ctx context.Context, id uint) ([]byte, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// RadiographHandler handles radiograph related HTTP requests
+type RadiographHandler struct {
+	radiographService RadiographService
+	attachmentService AttachmentService
+	patientService    PatientService
+	userService       UserService
+	jwtService        JwtService
+}
+
+// NewRadiographHandler creates a new RadiographHandler
+func NewRadiographHandler(rs RadiographService, as AttachmentService, ps PatientService, us UserService, jwtService JwtService) *RadiographHandler {
+	return &RadiographHandler{
+		radiographService: rs,
+		attachmentService: as,
+		patientService:    ps,
+		userService:       us,
+		jwtService:        jwtService,
+	}
+}
+
+// IngestRadiograph ingests a DICOM file for the clinic's patient whose national ID is the file's patient ID
+func (h *RadiographHandler) IngestRadiograph(c *fiber.Ctx) error {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	return h.ingest(c, radiographService.IngestRequest{
+		ClinicID:     authenticatedUser.ClinicID,
+		UploadedByID: authenticatedUser.ID,
+	})
+}
+
+// IngestPatientRadiograph ingests a DICOM file for the :id patient. A patient ID in the file must match the patient's national ID.
+func (h *RadiographHandler) IngestPatientRadiograph(c *fiber.Ctx) error {
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	return h.ingest(c, radiographService.IngestRequest{
+		ClinicID:     authenticatedUser.ClinicID,
+		PatientID:    patientModel.ID,
+		UploadedByID: authenticatedUser.ID,
+	})
+}
+
+// GetTimeline lists the patient's radiograph studies, newest first, filtered by ?modality=, ?from= and ?to=
+func (h *RadiographHandler) GetTimeline(c *fiber.Ctx) error {
+	authenticatedUser, patientModel, ok := h.clinicPatient(c)
+	if !ok {
+		return nil
+	}
+
+	studies, err := h.radiographService.GetTimeline(c.Context(), authenticatedUser.ClinicID, patientModel.ID, radiograph.Filter{
+		Modality: c.Query("modality"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+	})
+	if err != nil {
+		return writeRadiographError(c, err, "Failed to fetch radiographs")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(studies)
+}
+
+// GetRadiograph returns the indexed header of a radiograph
+func (h *RadiographHandler) GetRadiograph(c *fiber.Ctx) error {
+	image, ok := h.clinicImage(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(image)
+}
+
+// GetPreview returns the PNG preview of a radiograph
+func (h *RadiographHandler) GetPreview(c *fiber.Ctx) error {
+	image, ok := h.clinicImage(c)
+	if !ok {
+		return nil
+	}
+	if !image.HasPreview {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "This radiograph has no preview",
+		})
+	}
+
+	preview, err := h.radiographService.GetPreview(c.Context(), image.ID)
+	if err != nil {
+		return writeRadiographError(c, err, "Failed to fetch radiograph preview")
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	return c.Status(fiber.StatusOK).Send(preview)
+}
+
+// ingest streams the file field of a multipart upload into the radiograph service
+func (h *RadiographHandler) ingest(c *fiber.Ctx, req radiographService.IngestRequest) error {
+	if contentLength := c.Request().Header.ContentLength(); contentLength > 0 &&
+		int64(contentLength) > h.attachmentService.MaxBytes()+multipartOverhead {
+		return writeRadiographError(c, attachment.ErrFileTooLarge, "File is too large")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || mediaType != fiber.MIMEMultipartForm || params["boundary"] == "" {
+		log.Warn().Err(err).Msg("Radiograph upload is not a multipart form")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Upload the DICOM file as multipart/form-data",
+		})
+	}
+
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	form := multipart.NewReader(body, params["boundary"])
+
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("Invalid multipart form")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid multipart form",
+			})
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		req.FileName = part.FileName()
+		image, err := h.radiographService.Ingest(c.Context(), req, part)
+		if err != nil {
+			return writeRadiographError(c, err, "Failed to ingest radiograph")
+		}
+		return c.Status(fiber.StatusCreated).JSON(image)
+	}
+
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "A DICOM file is required in the file field",
+	})
+}
+
+// clinicImage resolves the :id radiograph and checks it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *RadiographHandler) clinicImage(c *fiber.Ctx) (radiograph.Image, bool) {
+	id, ok := helpers.ParseID(c, "radiograph")
+	if !ok {
+		return radiograph.Image{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return radiograph.Image{}, false
+	}
+
+	image, err := h.radiographService.GetImage(c.Context(), id)
+	if err != nil {
+		_ = writeRadiographError(c, err, "Failed to fetch radiograph")
+		return radiograph.Image{}, false
+	}
+
+	return image, helpers.SameClinic(c, authenticatedUser, image.ClinicID, "radiographs")
+}
+
+// clinicPatient resolves the caller and the :id patient and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *RadiographHandler) clinicPatient(c *fiber.Ctx) (user.UserGetModel, patient.Patient, bool) {
+	id, ok := helpers.ParseID(c, "patient")
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	patientModel, err := h.patientService.GetPatient(c.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "radiographs") {
+		return user.UserGetModel{}, patient.Patient{}, false
+	}
+
+	return authenticatedUser, patientModel, true
+}
+
+// writeRadiographError maps errors returned by the radiograph service to HTTP responses
+func writeRadiographError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, radiograph.ErrInvalidDicom), errors.Is(err, radiograph.ErrRadiographValidation),
+		errors.Is(err, attachment.ErrAttachmentValidation):
+		log.Warn().Err(err).Msg("Radiograph validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, attachment.ErrFileTooLarge):
+		log.Warn().Err(err).Msg("Radiograph too large")
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, radiograph.ErrPatientNotMatched):
+		log.Warn().Err(err).Msg("Radiograph could not be matched to a patient")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, radiograph.ErrPatientMismatch), errors.Is(err, radiograph.ErrDuplicateImage):
+		log.Warn().Err(err).Msg("Radiograph conflicts with existing records")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, radiograph.ErrImageNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		log.Warn().Err(err).Msg("Radiograph not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,26 @@
+package radiograph
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRadiographRoutes(router fiber.Router, handler *RadiographHandler) {
+	requireRadiography := rbacMiddleware.RequireRole(user.RadiographyRoles...)
+
+	router.Post("/radiographs", requireRadiography, handler.IngestRadiograph)
+	router.Post("/patients/:id/radiographs", requireRadiography, handler.IngestPatientRadiograph)
+	router.Get("/patients/:id/radiographs", requireRadiography, handler.GetTimeline)
+	router.Get("/radiographs/:id", requireRadiography, handler.GetRadiograph)
+	router.Get("/radiographs/:id/preview", requireRadiography, handler.GetPreview)
+}
+
+// IsUpload reports whether the request uploads a DICOM file. Uploads are streamed, so they are exempt
+// from the global body size limit and request timeout.
+func IsUpload(c *fiber.Ctx) bool {
+	return c.Method() == fiber.MethodPost && (c.Path() == "/api/radiographs" ||
+		(strings.HasPrefix(c.Path(), "/api/patients/") && strings.HasSuffix(c.Path(), "/radiographs")))
+}
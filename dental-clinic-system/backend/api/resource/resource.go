@@ -0,0 +1,213 @@
+package resource
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/resource"
+	"dental-clinic-system/models/user"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// ResourceService defines methods to manage clinic resources and their utilisation
+type ResourceService interface {
+	GetResources(ctx context.Context, clinicID uint, resourceType resource.Type) ([]resource.Resource, error)
+	GetResource(ctx context.Context, id uint) (resource.Resource, error)
+	CreateResource(ctx context.Context, newResource resource.Resource) (resource.Resource, error)
+	UpdateResource(ctx context.Context, updatedResource resource.Resource) (resource.Resource, error)
+	DeleteResource(ctx context.Context, id uint) error
+	GetUtilisation(ctx context.Context, clinicID uint, date string, resourceType resource.Type) ([]resource.Utilisation, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// ResourceHandler handles resource related HTTP requests
+type ResourceHandler struct {
+	resourceService ResourceService
+	userService     UserService
+	jwtService      JwtService
+}
+
+// NewResourceHandler creates a new ResourceHandler
+func NewResourceHandler(rs ResourceService, us UserService, jwtService JwtService) *ResourceHandler {
+	return &ResourceHandler{
+		resourceService: rs,
+		userService:     us,
+		jwtService:      jwtService,
+	}
+}
+
+// GetResources retrieves the resources of the authenticated user's clinic, optionally filtered by ?type=
+func (h *ResourceHandler) GetResources(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	resources, err := h.resourceService.GetResources(ctx, authenticatedUser.ClinicID, resource.Type(c.Query("type")))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch resources")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch resources",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resources)
+}
+
+// GetUtilisation reports how busy the clinic's resources are on ?date= (defaults to today), chairs unless ?type= is given
+func (h *ResourceHandler) GetUtilisation(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	date := c.Query("date", time.Now().Format("2006-01-02"))
+	report, err := h.resourceService.GetUtilisation(ctx, authenticatedUser.ClinicID, date, resource.Type(c.Query("type")))
+	if err != nil {
+		return writeResourceError(c, err, "Failed to calculate resource utilisation")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// CreateResource adds a resource to the authenticated user's clinic
+func (h *ResourceHandler) CreateResource(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var newResource resource.Resource
+	if err := c.BodyParser(&newResource); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	newResource.ID = 0
+	newResource.ClinicID = authenticatedUser.ClinicID
+
+	createdResource, err := h.resourceService.CreateResource(ctx, newResource)
+	if err != nil {
+		return writeResourceError(c, err, "Failed to create resource")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(createdResource)
+}
+
+// UpdateResource changes the name, type, description or active flag of a resource
+func (h *ResourceHandler) UpdateResource(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, ok := helpers.ParseID(c, "resource")
+	if !ok {
+		return nil
+	}
+
+	var updatedResource resource.Resource
+	if err := c.BodyParser(&updatedResource); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	existingResource, ok := h.clinicResource(c, id)
+	if !ok {
+		return nil
+	}
+
+	updatedResource.Model = existingResource.Model
+	updatedResource.ClinicID = existingResource.ClinicID
+
+	savedResource, err := h.resourceService.UpdateResource(ctx, updatedResource)
+	if err != nil {
+		return writeResourceError(c, err, "Failed to update resource")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(savedResource)
+}
+
+// DeleteResource removes a resource of the authenticated user's clinic
+func (h *ResourceHandler) DeleteResource(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, ok := helpers.ParseID(c, "resource")
+	if !ok {
+		return nil
+	}
+
+	existingResource, ok := h.clinicResource(c, id)
+	if !ok {
+		return nil
+	}
+
+	if err := h.resourceService.DeleteResource(ctx, existingResource.ID); err != nil {
+		return writeResourceError(c, err, "Failed to delete resource")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Resource deleted successfully",
+	})
+}
+
+// clinicResource loads a resource and checks that it belongs to the authenticated user's clinic.
+// When it returns false the error response has already been written.
+func (h *ResourceHandler) clinicResource(c *fiber.Ctx, id uint) (resource.Resource, bool) {
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return resource.Resource{}, false
+	}
+
+	existingResource, err := h.resourceService.GetResource(c.Context(), id)
+	if err != nil {
+		_ = writeResourceError(c, err, "Failed to fetch resource")
+		return resource.Resource{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, existingResource.ClinicID, "resource") {
+		return resource.Resource{}, false
+	}
+
+	return existingResource, true
+}
+
+// writeResourceError maps errors returned by the resource service to HTTP responses
+func writeResourceError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, resource.ErrResourceValidation):
+		log.Warn().Err(err).Msg("Resource validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, resource.ErrResourceNotFound):
+		log.Warn().Err(err).Msg("Resource not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,18 @@
+package resource
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterResourceRoutes(router fiber.Router, handler *ResourceHandler) {
+	requireResourceManager := rbacMiddleware.RequireRole(user.RoleClinicAdmin, user.RoleManager)
+
+	router.Get("/resources", handler.GetResources)
+	router.Get("/resources/utilisation", handler.GetUtilisation)
+	router.Post("/resources", requireResourceManager, handler.CreateResource)
+	router.Put("/resources/:id", requireResourceManager, handler.UpdateResource)
+	router.Delete("/resources/:id", requireResourceManager, handler.DeleteResource)
+}
@@ -0,0 +1,326 @@
+package schedule
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/schedule"
+	"dental-clinic-system/models/user"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUser(ctx context.Context, id uint) (user.UserGetModel, error)
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// ScheduleService defines methods to manage opening hours, doctor schedules and exceptions
+type ScheduleService interface {
+	GetOpeningHours(ctx context.Context, clinicID uint) ([]schedule.OpeningHours, error)
+	SetOpeningHours(ctx context.Context, clinicID uint, hours []schedule.OpeningHours) ([]schedule.OpeningHours, error)
+	GetDoctorSchedules(ctx context.Context, doctorID uint) ([]schedule.DoctorSchedule, error)
+	SetDoctorSchedules(ctx context.Context, clinicID, doctorID uint, schedules []schedule.DoctorSchedule) ([]schedule.DoctorSchedule, error)
+	GetScheduleExceptions(ctx context.Context, clinicID uint, fromDate, toDate string) ([]schedule.ScheduleException, error)
+	GetScheduleException(ctx context.Context, id uint) (schedule.ScheduleException, error)
+	CreateScheduleException(ctx context.Context, exception schedule.ScheduleException) (schedule.ScheduleException, error)
+	DeleteScheduleException(ctx context.Context, id uint) error
+	GetPublicHolidays(ctx context.Context, year int) ([]schedule.PublicHoliday, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// ScheduleHandler handles working hours related HTTP requests
+type ScheduleHandler struct {
+	scheduleService ScheduleService
+	userService     UserService
+	jwtService      JwtService
+}
+
+// NewScheduleHandler creates a new ScheduleHandler
+func NewScheduleHandler(ss ScheduleService, us UserService, jwtService JwtService) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: ss,
+		userService:     us,
+		jwtService:      jwtService,
+	}
+}
+
+// GetOpeningHours retrieves the opening hours of the authenticated user's clinic
+func (h *ScheduleHandler) GetOpeningHours(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	hours, err := h.scheduleService.GetOpeningHours(ctx, authenticatedUser.ClinicID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch opening hours")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch opening hours",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(hours)
+}
+
+// SetOpeningHours replaces the weekly opening hours of the authenticated user's clinic
+func (h *ScheduleHandler) SetOpeningHours(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var hours []schedule.OpeningHours
+	if err := c.BodyParser(&hours); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	savedHours, err := h.scheduleService.SetOpeningHours(ctx, authenticatedUser.ClinicID, hours)
+	if err != nil {
+		return writeScheduleError(c, err, "Failed to save opening hours")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(savedHours)
+}
+
+// GetDoctorSchedule retrieves the weekly schedule of a doctor
+func (h *ScheduleHandler) GetDoctorSchedule(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	doctor, ok := h.clinicDoctor(c)
+	if !ok {
+		return nil
+	}
+
+	schedules, err := h.scheduleService.GetDoctorSchedules(ctx, doctor.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch doctor schedule")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch doctor schedule",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(schedules)
+}
+
+// SetDoctorSchedule replaces the weekly schedule of a doctor
+func (h *ScheduleHandler) SetDoctorSchedule(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var schedules []schedule.DoctorSchedule
+	if err := c.BodyParser(&schedules); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	doctor, ok := h.clinicDoctor(c)
+	if !ok {
+		return nil
+	}
+
+	savedSchedules, err := h.scheduleService.SetDoctorSchedules(ctx, doctor.ClinicID, doctor.ID, schedules)
+	if err != nil {
+		return writeScheduleError(c, err, "Failed to save doctor schedule")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(savedSchedules)
+}
+
+// GetScheduleExceptions lists leaves, closures and holidays of the clinic between from and to (YYYY-MM-DD)
+func (h *ScheduleHandler) GetScheduleExceptions(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	now := time.Now()
+	fromDate := c.Query("from", now.Format(schedule.DateLayout))
+	toDate := c.Query("to", now.AddDate(1, 0, 0).Format(schedule.DateLayout))
+	if _, err := time.Parse(schedule.DateLayout, fromDate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid from date",
+		})
+	}
+	if _, err := time.Parse(schedule.DateLayout, toDate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid to date",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	exceptions, err := h.scheduleService.GetScheduleExceptions(ctx, authenticatedUser.ClinicID, fromDate, toDate)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch schedule exceptions")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch schedule exceptions",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(exceptions)
+}
+
+// CreateScheduleException creates a leave, closure or holiday for the clinic or one of its doctors
+func (h *ScheduleHandler) CreateScheduleException(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var exception schedule.ScheduleException
+	if err := c.BodyParser(&exception); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	if exception.DoctorID != nil {
+		doctor, err := h.userService.GetUser(ctx, *exception.DoctorID)
+		if err != nil {
+			log.Error().Err(err).Msg("Doctor not found")
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Doctor not found",
+			})
+		}
+		if doctor.ClinicID != authenticatedUser.ClinicID {
+			log.Warn().Msg("Forbidden access to doctor's schedule")
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+	}
+
+	exception.ID = 0
+	exception.ClinicID = authenticatedUser.ClinicID
+
+	createdException, err := h.scheduleService.CreateScheduleException(ctx, exception)
+	if err != nil {
+		return writeScheduleError(c, err, "Failed to create schedule exception")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(createdException)
+}
+
+// DeleteScheduleException deletes a schedule exception by ID
+func (h *ScheduleHandler) DeleteScheduleException(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, ok := helpers.ParseID(c, "schedule exception")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	exception, err := h.scheduleService.GetScheduleException(ctx, id)
+	if err != nil {
+		return writeScheduleError(c, err, "Failed to fetch schedule exception")
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, exception.ClinicID, "schedule exception") {
+		return nil
+	}
+
+	if err := h.scheduleService.DeleteScheduleException(ctx, id); err != nil {
+		log.Error().Err(err).Msg("Failed to delete schedule exception")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete schedule exception",
+		})
+	}
+
+	c.Status(fiber.StatusNoContent)
+	return nil
+}
+
+// GetPublicHolidays lists the public holidays of a year, defaulting to the current one
+func (h *ScheduleHandler) GetPublicHolidays(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	year := c.QueryInt("year", time.Now().Year())
+	holidays, err := h.scheduleService.GetPublicHolidays(ctx, year)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch public holidays")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch public holidays",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(holidays)
+}
+
+// clinicDoctor resolves the doctor in the :id route parameter and ensures it belongs to the caller's clinic.
+// When it returns false the error response has already been written.
+func (h *ScheduleHandler) clinicDoctor(c *fiber.Ctx) (user.UserGetModel, bool) {
+	doctorID, ok := helpers.ParseID(c, "doctor")
+	if !ok {
+		return user.UserGetModel{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return user.UserGetModel{}, false
+	}
+
+	doctor, err := h.userService.GetUser(c.Context(), doctorID)
+	if err != nil {
+		log.Error().Err(err).Msg("Doctor not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Doctor not found",
+		})
+		return user.UserGetModel{}, false
+	}
+
+	if authenticatedUser.ClinicID != doctor.ClinicID {
+		log.Warn().Msg("Forbidden access to doctor's schedule")
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden",
+		})
+		return user.UserGetModel{}, false
+	}
+
+	return doctor, true
+}
+
+// writeScheduleError maps errors returned by the schedule service to HTTP responses
+func writeScheduleError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, schedule.ErrScheduleValidation) {
+		log.Warn().Err(err).Msg("Schedule validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if errors.Is(err, schedule.ErrScheduleExceptionNotFound) {
+		log.Warn().Err(err).Msg("Schedule exception not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Schedule exception not found",
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
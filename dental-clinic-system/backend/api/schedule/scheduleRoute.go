@@ -0,0 +1,21 @@
+package schedule
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterScheduleRoutes(router fiber.Router, handler *ScheduleHandler) {
+	requireScheduleManager := rbacMiddleware.RequireRole(user.RoleClinicAdmin, user.RoleManager)
+
+	router.Get("/opening-hours", handler.GetOpeningHours)
+	router.Put("/opening-hours", requireScheduleManager, handler.SetOpeningHours)
+	router.Get("/doctors/:id/schedule", handler.GetDoctorSchedule)
+	router.Put("/doctors/:id/schedule", requireScheduleManager, handler.SetDoctorSchedule)
+	router.Get("/schedule-exceptions", handler.GetScheduleExceptions)
+	router.Post("/schedule-exceptions", requireScheduleManager, handler.CreateScheduleException)
+	router.Delete("/schedule-exceptions/:id", requireScheduleManager, handler.DeleteScheduleException)
+	router.Get("/public-holidays", handler.GetPublicHolidays)
+}
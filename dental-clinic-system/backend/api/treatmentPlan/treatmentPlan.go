@@ -0,0 +1,321 @@
+package treatmentPlan
+
+import (
+	"context"
+	"dental-clinic-system/application/treatmentPlanService"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/resource"
+	"dental-clinic-system/models/schedule"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/models/user"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// PatientService is used to check that a patient belongs to the caller's clinic
+type PatientService interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// TreatmentPlanService defines methods to manage treatment plans
+type TreatmentPlanService interface {
+	GetPlans(ctx context.Context, clinicID, patientID uint, status treatment.PlanStatus) ([]treatment.Plan, error)
+	GetPlan(ctx context.Context, id uint) (treatment.Plan, error)
+	CreatePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error)
+	UpdatePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error)
+	TransitionStatus(ctx context.Context, id uint, to treatment.PlanStatus) (treatment.Plan, error)
+	ScheduleItem(ctx context.Context, planID, itemID uint, req treatmentPlanService.ScheduleRequest) (appointment.Appointment, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// TreatmentPlanHandler handles treatment plan related HTTP requests
+type TreatmentPlanHandler struct {
+	planService    TreatmentPlanService
+	patientService PatientService
+	userService    UserService
+	jwtService     JwtService
+}
+
+// NewTreatmentPlanHandler creates a new TreatmentPlanHandler
+func NewTreatmentPlanHandler(tps TreatmentPlanService, ps PatientService, us UserService, jwtService JwtService) *TreatmentPlanHandler {
+	return &TreatmentPlanHandler{
+		planService:    tps,
+		patientService: ps,
+		userService:    us,
+		jwtService:     jwtService,
+	}
+}
+
+// GetPlans lists the clinic's treatment plans, filtered by ?patient_id= and ?status=
+func (h *TreatmentPlanHandler) GetPlans(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var patientID uint
+	if value := c.Query("patient_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			log.Warn().Msgf("Invalid patient ID: %s", value)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid patient ID",
+			})
+		}
+		patientID = uint(id)
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	plans, err := h.planService.GetPlans(ctx, authenticatedUser.ClinicID, patientID, treatment.PlanStatus(c.Query("status")))
+	if err != nil {
+		return writeTreatmentPlanError(c, err, "Failed to fetch treatment plans")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(plans)
+}
+
+// GetPlan returns a treatment plan with its phases, items and estimates
+func (h *TreatmentPlanHandler) GetPlan(c *fiber.Ctx) error {
+	plan, _, ok := h.clinicPlan(c)
+	if !ok {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(plan)
+}
+
+// CreatePlan creates a draft treatment plan for a patient of the caller's clinic
+func (h *TreatmentPlanHandler) CreatePlan(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var plan treatment.Plan
+	if err := c.BodyParser(&plan); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	if !h.checkPatient(c, plan.PatientID, authenticatedUser) {
+		return nil
+	}
+
+	plan.ClinicID = authenticatedUser.ClinicID
+	plan.CreatedByID = authenticatedUser.ID
+	if plan.DoctorID == 0 {
+		plan.DoctorID = authenticatedUser.ID
+	}
+
+	created, err := h.planService.CreatePlan(ctx, plan)
+	if err != nil {
+		return writeTreatmentPlanError(c, err, "Failed to create treatment plan")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdatePlan replaces the content of a draft treatment plan
+func (h *TreatmentPlanHandler) UpdatePlan(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var plan treatment.Plan
+	if err := c.BodyParser(&plan); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	existing, _, ok := h.clinicPlan(c)
+	if !ok {
+		return nil
+	}
+
+	plan.ID = existing.ID
+	plan.ClinicID = existing.ClinicID
+	plan.PatientID = existing.PatientID
+	plan.CreatedByID = existing.CreatedByID
+	if plan.DoctorID == 0 {
+		plan.DoctorID = existing.DoctorID
+	}
+
+	updated, err := h.planService.UpdatePlan(ctx, plan)
+	if err != nil {
+		return writeTreatmentPlanError(c, err, "Failed to update treatment plan")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// Transition returns a handler that moves the :id plan to the given status
+func (h *TreatmentPlanHandler) Transition(to treatment.PlanStatus) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		plan, _, ok := h.clinicPlan(c)
+		if !ok {
+			return nil
+		}
+
+		updated, err := h.planService.TransitionStatus(c.Context(), plan.ID, to)
+		if err != nil {
+			return writeTreatmentPlanError(c, err, "Failed to change treatment plan status")
+		}
+
+		return c.Status(fiber.StatusOK).JSON(updated)
+	}
+}
+
+// ScheduleItem books an appointment for a plan item and links it to the item
+func (h *TreatmentPlanHandler) ScheduleItem(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	itemIDStr := c.Params("itemId")
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil || itemID <= 0 {
+		log.Warn().Msgf("Invalid treatment plan item ID: %s", itemIDStr)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid treatment plan item ID",
+		})
+	}
+
+	var req treatmentPlanService.ScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	plan, _, ok := h.clinicPlan(c)
+	if !ok {
+		return nil
+	}
+
+	booked, err := h.planService.ScheduleItem(ctx, plan.ID, uint(itemID), req)
+	if err != nil {
+		return writeTreatmentPlanError(c, err, "Failed to schedule treatment plan item")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(booked)
+}
+
+// clinicPlan resolves the caller and the :id plan and checks they belong to the same clinic.
+// When it returns false the error response has already been written.
+func (h *TreatmentPlanHandler) clinicPlan(c *fiber.Ctx) (treatment.Plan, user.UserGetModel, bool) {
+	id, ok := helpers.ParseID(c, "treatment plan")
+	if !ok {
+		return treatment.Plan{}, user.UserGetModel{}, false
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return treatment.Plan{}, user.UserGetModel{}, false
+	}
+
+	plan, err := h.planService.GetPlan(c.Context(), id)
+	if err != nil {
+		_ = writeTreatmentPlanError(c, err, "Failed to fetch treatment plan")
+		return treatment.Plan{}, user.UserGetModel{}, false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, plan.ClinicID, "treatment plan") {
+		return treatment.Plan{}, user.UserGetModel{}, false
+	}
+
+	return plan, authenticatedUser, true
+}
+
+// checkPatient verifies that the patient exists and belongs to the authenticated user's clinic.
+// When it returns false the error response has already been written.
+func (h *TreatmentPlanHandler) checkPatient(c *fiber.Ctx, patientID uint, authenticatedUser user.UserGetModel) bool {
+	patientModel, err := h.patientService.GetPatient(c.Context(), patientID)
+	if err != nil {
+		log.Warn().Err(err).Msg("Patient not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Patient not found",
+		})
+		return false
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, patientModel.ClinicID, "patient") {
+		return false
+	}
+
+	return true
+}
+
+// writeTreatmentPlanError maps errors returned by the treatment plan service to HTTP responses
+func writeTreatmentPlanError(c *fiber.Ctx, err error, message string) error {
+	var conflictErr *appointment.ConflictError
+	switch {
+	case errors.Is(err, treatment.ErrPlanValidation):
+		log.Warn().Err(err).Msg("Treatment plan validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, treatment.ErrPlanNotFound), errors.Is(err, treatment.ErrItemNotFound):
+		log.Warn().Err(err).Msg("Treatment plan not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, treatment.ErrPlanNotEditable),
+		errors.Is(err, treatment.ErrInvalidStatusTransition),
+		errors.Is(err, treatment.ErrPlanNotSchedulable),
+		errors.Is(err, treatment.ErrItemAlreadyBooked),
+		errors.Is(err, treatment.ErrPlanNotComplete):
+		log.Warn().Err(err).Msg("Treatment plan change conflicts with its status")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.As(err, &conflictErr):
+		log.Warn().Err(err).Msg("Appointment conflicts with an existing booking")
+		response := fiber.Map{
+			"error":                      "Appointment conflicts with an existing booking",
+			"participant":                conflictErr.Participant,
+			"conflicting_appointment_id": conflictErr.ConflictingAppointmentID,
+		}
+		if conflictErr.Participant == appointment.ConflictResource {
+			response["resource_id"] = conflictErr.ResourceID
+		}
+		return c.Status(fiber.StatusConflict).JSON(response)
+	case errors.Is(err, resource.ErrResourceNotFound), errors.Is(err, resource.ErrResourceInactive):
+		log.Warn().Err(err).Msg("Appointment reserves an unavailable resource")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, schedule.ErrOutsideWorkingHours):
+		log.Warn().Err(err).Msg("Appointment is outside working hours")
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Appointment is outside working hours",
+		})
+	case errors.Is(err, appointment.ErrInvalidAppointmentTime):
+		log.Warn().Err(err).Msg("Invalid appointment time")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
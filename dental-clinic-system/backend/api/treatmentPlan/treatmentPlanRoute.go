@@ -0,0 +1,24 @@
+package treatmentPlan
+
+import (
+	"dental-clinic-system/middleware/rbacMiddleware"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/models/user"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterTreatmentPlanRoutes(router fiber.Router, handler *TreatmentPlanHandler) {
+	requireClinician := rbacMiddleware.RequireRole(user.ClinicalRoles...)
+
+	router.Get("/treatment-plans", handler.GetPlans)
+	router.Get("/treatment-plans/:id", handler.GetPlan)
+	router.Post("/treatment-plans", requireClinician, handler.CreatePlan)
+	router.Put("/treatment-plans/:id", requireClinician, handler.UpdatePlan)
+	router.Post("/treatment-plans/:id/present", requireClinician, handler.Transition(treatment.PlanPresented))
+	router.Post("/treatment-plans/:id/revise", requireClinician, handler.Transition(treatment.PlanDraft))
+	router.Post("/treatment-plans/:id/accept", requireClinician, handler.Transition(treatment.PlanAccepted))
+	router.Post("/treatment-plans/:id/decline", requireClinician, handler.Transition(treatment.PlanDeclined))
+	router.Post("/treatment-plans/:id/complete", requireClinician, handler.Transition(treatment.PlanCompleted))
+	router.Post("/treatment-plans/:id/items/:itemId/schedule", requireClinician, handler.ScheduleItem)
+}
@@ -0,0 +1,212 @@
+package waitlist
+
+import (
+	"context"
+	"dental-clinic-system/helpers"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/user"
+	"dental-clinic-system/models/waitlist"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// UserService defines methods to interact with user data
+type UserService interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// WaitlistService defines methods to manage the waitlist and its slot offers
+type WaitlistService interface {
+	GetEntries(ctx context.Context, clinicID uint, status waitlist.EntryStatus) ([]waitlist.Entry, error)
+	GetEntry(ctx context.Context, id uint) (waitlist.Entry, error)
+	CreateEntry(ctx context.Context, entry waitlist.Entry) (waitlist.Entry, error)
+	CancelEntry(ctx context.Context, id uint) error
+	GetOffer(ctx context.Context, id uint) (waitlist.Offer, error)
+	AcceptOffer(ctx context.Context, offerID uint) (appointment.Appointment, error)
+	AcceptOfferByToken(ctx context.Context, token string) (appointment.Appointment, error)
+}
+
+type JwtService interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// WaitlistHandler handles waitlist related HTTP requests
+type WaitlistHandler struct {
+	waitlistService WaitlistService
+	userService     UserService
+	jwtService      JwtService
+}
+
+// NewWaitlistHandler creates a new WaitlistHandler
+func NewWaitlistHandler(ws WaitlistService, us UserService, jwtService JwtService) *WaitlistHandler {
+	return &WaitlistHandler{
+		waitlistService: ws,
+		userService:     us,
+		jwtService:      jwtService,
+	}
+}
+
+// GetEntries retrieves the waitlist of the authenticated user's clinic in priority order
+func (h *WaitlistHandler) GetEntries(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	entries, err := h.waitlistService.GetEntries(ctx, authenticatedUser.ClinicID, waitlist.EntryStatus(c.Query("status")))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch waitlist")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch waitlist",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
+
+// CreateEntry adds a patient to the waitlist of the authenticated user's clinic
+func (h *WaitlistHandler) CreateEntry(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var entry waitlist.Entry
+	if err := c.BodyParser(&entry); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	entry.ClinicID = authenticatedUser.ClinicID
+
+	createdEntry, err := h.waitlistService.CreateEntry(ctx, entry)
+	if err != nil {
+		return writeWaitlistError(c, err, "Failed to create waitlist entry")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(createdEntry)
+}
+
+// CancelEntry removes a patient from the waitlist
+func (h *WaitlistHandler) CancelEntry(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, ok := helpers.ParseID(c, "waitlist entry")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	entry, err := h.waitlistService.GetEntry(ctx, id)
+	if err != nil {
+		return writeWaitlistError(c, err, "Failed to fetch waitlist entry")
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, entry.ClinicID, "waitlist entry") {
+		return nil
+	}
+
+	if err := h.waitlistService.CancelEntry(ctx, entry.ID); err != nil {
+		return writeWaitlistError(c, err, "Failed to cancel waitlist entry")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Waitlist entry cancelled successfully",
+	})
+}
+
+// AcceptOffer accepts a slot offer on behalf of the patient, e.g. after a phone call
+func (h *WaitlistHandler) AcceptOffer(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	id, ok := helpers.ParseID(c, "waitlist offer")
+	if !ok {
+		return nil
+	}
+
+	authenticatedUser, ok := helpers.AuthenticatedUser(c, h.jwtService, h.userService)
+	if !ok {
+		return nil
+	}
+
+	offer, err := h.waitlistService.GetOffer(ctx, id)
+	if err != nil {
+		return writeWaitlistError(c, err, "Failed to fetch waitlist offer")
+	}
+
+	if !helpers.SameClinic(c, authenticatedUser, offer.ClinicID, "waitlist offer") {
+		return nil
+	}
+
+	bookedAppointment, err := h.waitlistService.AcceptOffer(ctx, offer.ID)
+	if err != nil {
+		return writeWaitlistError(c, err, "Failed to accept waitlist offer")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(bookedAppointment)
+}
+
+// AcceptOfferByToken accepts a slot offer with the token from the offer email
+func (h *WaitlistHandler) AcceptOfferByToken(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		log.Warn().Msg("Missing waitlist offer token")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token is required",
+		})
+	}
+
+	bookedAppointment, err := h.waitlistService.AcceptOfferByToken(ctx, req.Token)
+	if err != nil {
+		return writeWaitlistError(c, err, "Failed to accept waitlist offer")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"appointment_id": bookedAppointment.ID,
+		"scheduled_time": bookedAppointment.ScheduledTime,
+		"end_time":       bookedAppointment.EndTime,
+	})
+}
+
+// writeWaitlistError maps errors returned by the waitlist service to HTTP responses
+func writeWaitlistError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, waitlist.ErrWaitlistValidation):
+		log.Warn().Err(err).Msg("Waitlist validation failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, waitlist.ErrEntryNotFound), errors.Is(err, waitlist.ErrOfferNotFound):
+		log.Warn().Err(err).Msg("Waitlist record not found")
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, waitlist.ErrOfferNotAvailable), errors.Is(err, waitlist.ErrEntryNotCancellable), errors.Is(err, appointment.ErrAppointmentConflict):
+		log.Warn().Err(err).Msg("Waitlist slot no longer available")
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
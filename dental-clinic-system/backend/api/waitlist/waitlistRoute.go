@@ -0,0 +1,17 @@
+package waitlist
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterWaitlistOfferRoutes registers the public offer acceptance endpoint, which is authenticated by the offer token
+func RegisterWaitlistOfferRoutes(router fiber.Router, handler *WaitlistHandler) {
+	router.Post("/waitlist/offers/accept", handler.AcceptOfferByToken)
+}
+
+func RegisterWaitlistRoutes(router fiber.Router, handler *WaitlistHandler) {
+	router.Get("/waitlist", handler.GetEntries)
+	router.Post("/waitlist", handler.CreateEntry)
+	router.Delete("/waitlist/:id", handler.CancelEntry)
+	router.Post("/waitlist-offers/:id/accept", handler.AcceptOffer)
+}
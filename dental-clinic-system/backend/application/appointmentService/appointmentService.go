@@ -3,52 +3,589 @@ package appointmentService
 import (
 	"context"
 	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/schedule"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
 type AppointmentRepository interface {
-	GetAppointments(ctx context.Context, ClinicID uint) ([]appointment.Appointment, error)
+	GetAppointments(ctx context.Context, filter appointment.ListFilter) (appointment.Page, error)
 	GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error)
 	CreateAppointment(ctx context.Context, appointment appointment.Appointment) (appointment.Appointment, error)
 	UpdateAppointment(ctx context.Context, appointment appointment.Appointment) (appointment.Appointment, error)
 	DeleteAppointment(ctx context.Context, id uint) error
 	GetDoctorAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error)
 	GetPatientAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error)
+	TransitionStatus(ctx context.Context, id uint, from, to appointment.Status, history appointment.StatusHistory) (appointment.Appointment, error)
+	GetStatusHistory(ctx context.Context, id uint) ([]appointment.StatusHistory, error)
+	CreateSeries(ctx context.Context, series appointment.Series, occurrences []appointment.Appointment) (appointment.Series, error)
+	GetSeries(ctx context.Context, id uint) (appointment.Series, error)
+	UpdateSeriesAppointments(ctx context.Context, appointments []appointment.Appointment) ([]appointment.Appointment, error)
+}
+
+// ScheduleService checks bookings against clinic opening hours and doctor schedules
+type ScheduleService interface {
+	CheckWorkingTime(ctx context.Context, clinicID, doctorID uint, start, end time.Time) error
+	GetWorkingIntervals(ctx context.Context, clinicID, doctorID uint, from, to time.Time) ([]schedule.Interval, error)
+}
+
+// SlotReleaser is notified when a booked slot becomes free so it can be offered to the waitlist
+type SlotReleaser interface {
+	ReleaseSlot(ctx context.Context, released appointment.Appointment) error
+}
+
+// AlertProvider supplies the medical alerts of patients, so every appointment shows them before treatment
+type AlertProvider interface {
+	GetAlerts(ctx context.Context, clinicID uint, patientIDs []uint) (map[uint][]medical.Alert, error)
+}
+
+// ConsentChecker tells whether a patient has signed the consents a procedure requires
+type ConsentChecker interface {
+	CheckConsents(ctx context.Context, clinicID, patientID, procedureID uint) error
+}
+
+// PlannedProcedures finds the treatment plan items booked on an appointment
+type PlannedProcedures interface {
+	GetAppointmentProcedureIDs(ctx context.Context, appointmentID uint) ([]uint, error)
 }
 
 type appointmentService struct {
 	appointmentRepository AppointmentRepository
+	scheduleService       ScheduleService
+	slotReleaser          SlotReleaser
+	alertProvider         AlertProvider
+	consentChecker        ConsentChecker
+	plannedProcedures     PlannedProcedures
 }
 
-func NewAppointmentService(appointmentRepository AppointmentRepository) *appointmentService {
+func NewAppointmentService(appointmentRepository AppointmentRepository, scheduleService ScheduleService, slotReleaser SlotReleaser, alertProvider AlertProvider, consentChecker ConsentChecker, plannedProcedures PlannedProcedures) *appointmentService {
 	return &appointmentService{
 		appointmentRepository: appointmentRepository,
+		scheduleService:       scheduleService,
+		slotReleaser:          slotReleaser,
+		alertProvider:         alertProvider,
+		consentChecker:        consentChecker,
+		plannedProcedures:     plannedProcedures,
 	}
 }
 
-func (s *appointmentService) GetAppointments(ctx context.Context, ClinicID uint) ([]appointment.Appointment, error) {
-	return s.appointmentRepository.GetAppointments(ctx, ClinicID)
+// GetAppointments returns one page of the clinic's appointments matching the filter
+func (s *appointmentService) GetAppointments(ctx context.Context, filter appointment.ListFilter) (appointment.Page, error) {
+	if err := filter.Normalize(); err != nil {
+		return appointment.Page{}, err
+	}
+	page, err := s.appointmentRepository.GetAppointments(ctx, filter)
+	if err != nil {
+		return appointment.Page{}, err
+	}
+	if err := s.attachAlerts(ctx, page.Appointments); err != nil {
+		return appointment.Page{}, err
+	}
+	return page, nil
 }
 
 func (s *appointmentService) GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error) {
-	return s.appointmentRepository.GetAppointment(ctx, id)
+	appt, err := s.appointmentRepository.GetAppointment(ctx, id)
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+	appts := []appointment.Appointment{appt}
+	if err := s.attachAlerts(ctx, appts); err != nil {
+		return appointment.Appointment{}, err
+	}
+	return appts[0], nil
 }
 
-func (s *appointmentService) CreateAppointment(ctx context.Context, appointment appointment.Appointment) (appointment.Appointment, error) {
-	return s.appointmentRepository.CreateAppointment(ctx, appointment)
+func (s *appointmentService) CreateAppointment(ctx context.Context, appt appointment.Appointment) (appointment.Appointment, error) {
+	appt.Status = appointment.StatusBooked
+	appt.CancellationReason = ""
+	if err := normalizeSchedule(&appt); err != nil {
+		return appointment.Appointment{}, err
+	}
+	if err := s.scheduleService.CheckWorkingTime(ctx, appt.ClinicID, appt.DoctorID, appt.ScheduledTime, appt.EndTime); err != nil {
+		return appointment.Appointment{}, err
+	}
+	created, err := s.appointmentRepository.CreateAppointment(ctx, appt)
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+	return s.withAlerts(ctx, created), nil
 }
 
-func (s *appointmentService) UpdateAppointment(ctx context.Context, appointment appointment.Appointment) (appointment.Appointment, error) {
-	return s.appointmentRepository.UpdateAppointment(ctx, appointment)
+// UpdateAppointment saves changes to an appointment. Its time, doctor and resources can only be
+// changed while it is booked or confirmed, and a new time or doctor must be within working hours.
+func (s *appointmentService) UpdateAppointment(ctx context.Context, appt appointment.Appointment) (appointment.Appointment, error) {
+	existing, err := s.appointmentRepository.GetAppointment(ctx, appt.ID)
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+	if err := resolveChangedEndTime(&appt, existing); err != nil {
+		return appointment.Appointment{}, err
+	}
+	if err := normalizeSchedule(&appt); err != nil {
+		return appointment.Appointment{}, err
+	}
+	rescheduled := appt.DoctorID != existing.DoctorID || !appt.ScheduledTime.Equal(existing.ScheduledTime) || !appt.EndTime.Equal(existing.EndTime)
+	if (rescheduled || !slices.Equal(appt.ResourceIDList(), existing.ResourceIDList())) && !existing.Status.IsEditable() {
+		return appointment.Appointment{}, appointment.ErrAppointmentNotEditable
+	}
+	// Working hours are only checked for a new time or doctor, so notes can still be fixed on past
+	// appointments and on those a later schedule change left outside working hours
+	if rescheduled {
+		if err := s.scheduleService.CheckWorkingTime(ctx, appt.ClinicID, appt.DoctorID, appt.ScheduledTime, appt.EndTime); err != nil {
+			return appointment.Appointment{}, err
+		}
+	}
+	updated, err := s.appointmentRepository.UpdateAppointment(ctx, appt)
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+	return s.withAlerts(ctx, updated), nil
 }
 
+// DeleteAppointment removes an appointment and offers its slot to the waitlist if it was still occupying it
 func (s *appointmentService) DeleteAppointment(ctx context.Context, id uint) error {
-	return s.appointmentRepository.DeleteAppointment(ctx, id)
+	appt, err := s.appointmentRepository.GetAppointment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.appointmentRepository.DeleteAppointment(ctx, id); err != nil {
+		return err
+	}
+
+	if appt.Status.OccupiesSlot() {
+		s.releaseSlot(ctx, appt)
+	}
+	return nil
 }
 
 func (s *appointmentService) GetDoctorAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error) {
-	return s.appointmentRepository.GetDoctorAppointments(ctx, id)
+	appointments, err := s.appointmentRepository.GetDoctorAppointments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachAlerts(ctx, appointments); err != nil {
+		return nil, err
+	}
+	return appointments, nil
 }
 
 func (s *appointmentService) GetPatientAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error) {
-	return s.appointmentRepository.GetPatientAppointments(ctx, id)
+	appointments, err := s.appointmentRepository.GetPatientAppointments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachAlerts(ctx, appointments); err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// TransitionStatus moves an appointment to a new status if the transition table allows it.
+// Cancelling requires a reason, and the patient must have signed the consents the appointment's
+// procedures require before it is started; every change is stored in the appointment's history.
+func (s *appointmentService) TransitionStatus(ctx context.Context, id uint, to appointment.Status, changedByID uint, reason string) (appointment.Appointment, error) {
+	appt, err := s.appointmentRepository.GetAppointment(ctx, id)
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+
+	if !appointment.CanTransition(appt.Status, to) {
+		return appointment.Appointment{}, &appointment.InvalidTransitionError{From: appt.Status, To: to}
+	}
+
+	if to == appointment.StatusCancelled && reason == "" {
+		return appointment.Appointment{}, appointment.ErrCancellationReasonRequired
+	}
+
+	if to == appointment.StatusInChair {
+		if err := s.checkConsents(ctx, appt); err != nil {
+			return appointment.Appointment{}, err
+		}
+	}
+
+	updated, err := s.appointmentRepository.TransitionStatus(ctx, id, appt.Status, to, appointment.StatusHistory{
+		ChangedByID: changedByID,
+		ChangedAt:   time.Now(),
+		Reason:      reason,
+	})
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+
+	if to == appointment.StatusCancelled {
+		s.releaseSlot(ctx, updated)
+	}
+	return s.withAlerts(ctx, updated), nil
+}
+
+// checkConsents makes sure the patient signed the consents of every procedure the appointment is for:
+// its own procedure and those of the treatment plan items booked on it. Appointments without a known
+// procedure, such as series occurrences and ad-hoc bookings, have no consents to check.
+func (s *appointmentService) checkConsents(ctx context.Context, appt appointment.Appointment) error {
+	procedureIDs, err := s.plannedProcedures.GetAppointmentProcedureIDs(ctx, appt.ID)
+	if err != nil {
+		return err
+	}
+	if appt.ProcedureID != nil {
+		procedureIDs = append([]uint{*appt.ProcedureID}, procedureIDs...)
+	}
+
+	checked := map[uint]bool{}
+	for _, procedureID := range procedureIDs {
+		if checked[procedureID] {
+			continue
+		}
+		checked[procedureID] = true
+		if err := s.consentChecker.CheckConsents(ctx, appt.ClinicID, appt.PatientID, procedureID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseSlot hands a freed slot to the waitlist. Failures are only logged because the
+// cancellation itself has already succeeded.
+func (s *appointmentService) releaseSlot(ctx context.Context, released appointment.Appointment) {
+	if err := s.slotReleaser.ReleaseSlot(ctx, released); err != nil {
+		log.Error().
+			Str("operation", "ReleaseSlot").
+			Err(err).
+			Uint("appointment_id", released.ID).
+			Msg("Failed to offer freed slot to the waitlist")
+	}
+}
+
+func (s *appointmentService) GetStatusHistory(ctx context.Context, id uint) ([]appointment.StatusHistory, error) {
+	return s.appointmentRepository.GetStatusHistory(ctx, id)
+}
+
+// CreateSeries expands the series' recurrence rule and books every occurrence.
+// Each occurrence is checked against working hours and existing bookings; the series is only created if all of them fit.
+func (s *appointmentService) CreateSeries(ctx context.Context, series appointment.Series) (appointment.Series, error) {
+	recurrence, err := appointment.ParseRecurrence(series.RRule)
+	if err != nil {
+		return appointment.Series{}, err
+	}
+
+	starts, err := recurrence.Occurrences(series.StartTime)
+	if err != nil {
+		return appointment.Series{}, err
+	}
+	if len(starts) == 0 {
+		return appointment.Series{}, fmt.Errorf("%w: rule produces no occurrences", appointment.ErrInvalidRecurrence)
+	}
+
+	occurrences := make([]appointment.Appointment, 0, len(starts))
+	for i, start := range starts {
+		occurrence := appointment.Appointment{
+			ClinicID:        series.ClinicID,
+			PatientID:       series.PatientID,
+			DoctorID:        series.DoctorID,
+			ScheduledTime:   start,
+			DurationMinutes: series.DurationMinutes,
+			Status:          appointment.StatusBooked,
+			Treatment:       series.Treatment,
+			Notes:           series.Notes,
+			OccurrenceIndex: i,
+			ResourceIDs:     series.ResourceIDs,
+		}
+		if err := normalizeSchedule(&occurrence); err != nil {
+			return appointment.Series{}, err
+		}
+		if err := s.scheduleService.CheckWorkingTime(ctx, occurrence.ClinicID, occurrence.DoctorID, occurrence.ScheduledTime, occurrence.EndTime); err != nil {
+			return appointment.Series{}, &appointment.OccurrenceError{Index: i, Start: start, Err: err}
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	series.DurationMinutes = occurrences[0].DurationMinutes
+
+	created, err := s.appointmentRepository.CreateSeries(ctx, series, occurrences)
+	if err != nil {
+		return appointment.Series{}, err
+	}
+	s.tryAttachAlerts(ctx, created.Appointments)
+	return created, nil
+}
+
+func (s *appointmentService) GetSeries(ctx context.Context, id uint) (appointment.Series, error) {
+	series, err := s.appointmentRepository.GetSeries(ctx, id)
+	if err != nil {
+		return appointment.Series{}, err
+	}
+	if err := s.attachAlerts(ctx, series.Appointments); err != nil {
+		return appointment.Series{}, err
+	}
+	return series, nil
+}
+
+// UpdateSeries applies changes to one occurrence, to it and the following ones, or to the whole series.
+// A changed scheduled time is applied as a shift, so the spacing between occurrences is kept.
+// Occurrences that are no longer editable (checked in, completed, cancelled, ...) are left untouched.
+func (s *appointmentService) UpdateSeries(ctx context.Context, seriesID, appointmentID uint, scope appointment.EditScope, changes appointment.Appointment) ([]appointment.Appointment, error) {
+	targets, anchor, err := s.seriesTargets(ctx, seriesID, appointmentID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var shift time.Duration
+	if !changes.ScheduledTime.IsZero() {
+		shift = changes.ScheduledTime.Sub(anchor.ScheduledTime)
+	}
+
+	var updated []appointment.Appointment
+	for _, target := range targets {
+		if !target.Status.IsEditable() {
+			continue
+		}
+
+		target.ScheduledTime = target.ScheduledTime.Add(shift)
+		target.EndTime = time.Time{}
+		if changes.DurationMinutes > 0 {
+			target.DurationMinutes = changes.DurationMinutes
+		}
+		if changes.DoctorID != 0 {
+			target.DoctorID = changes.DoctorID
+		}
+		if changes.Treatment != "" {
+			target.Treatment = changes.Treatment
+		}
+		if changes.Notes != "" {
+			target.Notes = changes.Notes
+		}
+
+		if err := normalizeSchedule(&target); err != nil {
+			return nil, err
+		}
+		if err := s.scheduleService.CheckWorkingTime(ctx, target.ClinicID, target.DoctorID, target.ScheduledTime, target.EndTime); err != nil {
+			return nil, &appointment.OccurrenceError{Index: target.OccurrenceIndex, Start: target.ScheduledTime, Err: err}
+		}
+		updated = append(updated, target)
+	}
+
+	if len(updated) == 0 {
+		return []appointment.Appointment{}, nil
+	}
+
+	saved, err := s.appointmentRepository.UpdateSeriesAppointments(ctx, updated)
+	if err != nil {
+		return nil, err
+	}
+	s.tryAttachAlerts(ctx, saved)
+	return saved, nil
+}
+
+// CancelSeries cancels one occurrence, it and the following ones, or the whole series.
+// Occurrences that can no longer be cancelled are skipped.
+func (s *appointmentService) CancelSeries(ctx context.Context, seriesID, appointmentID uint, scope appointment.EditScope, changedByID uint, reason string) ([]appointment.Appointment, error) {
+	if reason == "" {
+		return nil, appointment.ErrCancellationReasonRequired
+	}
+
+	targets, _, err := s.seriesTargets(ctx, seriesID, appointmentID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled := []appointment.Appointment{}
+	for _, target := range targets {
+		if !appointment.CanTransition(target.Status, appointment.StatusCancelled) {
+			continue
+		}
+		updated, err := s.appointmentRepository.TransitionStatus(ctx, target.ID, target.Status, appointment.StatusCancelled, appointment.StatusHistory{
+			ChangedByID: changedByID,
+			ChangedAt:   time.Now(),
+			Reason:      reason,
+		})
+		if err != nil {
+			return cancelled, err
+		}
+		s.releaseSlot(ctx, updated)
+		cancelled = append(cancelled, updated)
+	}
+
+	s.tryAttachAlerts(ctx, cancelled)
+	return cancelled, nil
+}
+
+// attachAlerts fills in the medical alerts of each appointment's patient
+func (s *appointmentService) attachAlerts(ctx context.Context, appointments []appointment.Appointment) error {
+	patientsByClinic := map[uint][]uint{}
+	for _, appt := range appointments {
+		patientsByClinic[appt.ClinicID] = append(patientsByClinic[appt.ClinicID], appt.PatientID)
+	}
+
+	for clinicID, patientIDs := range patientsByClinic {
+		alerts, err := s.alertProvider.GetAlerts(ctx, clinicID, patientIDs)
+		if err != nil {
+			return err
+		}
+		for i := range appointments {
+			if appointments[i].ClinicID != clinicID {
+				continue
+			}
+			appointments[i].PatientAlerts = alerts[appointments[i].PatientID]
+			if appointments[i].PatientAlerts == nil {
+				appointments[i].PatientAlerts = []medical.Alert{}
+			}
+		}
+	}
+	return nil
+}
+
+// tryAttachAlerts fills in the alerts of appointments that were just saved. Failures are only logged
+// because the change itself has already succeeded; the appointments are then returned without alerts.
+func (s *appointmentService) tryAttachAlerts(ctx context.Context, appointments []appointment.Appointment) {
+	if err := s.attachAlerts(ctx, appointments); err != nil {
+		log.Error().
+			Str("operation", "AttachAlerts").
+			Err(err).
+			Int("appointment_count", len(appointments)).
+			Msg("Failed to load patient alerts")
+	}
+}
+
+// withAlerts returns a saved appointment with its patient's alerts
+func (s *appointmentService) withAlerts(ctx context.Context, appt appointment.Appointment) appointment.Appointment {
+	appts := []appointment.Appointment{appt}
+	s.tryAttachAlerts(ctx, appts)
+	return appts[0]
+}
+
+// seriesTargets returns the occurrences of a series selected by scope, anchored at the given appointment
+func (s *appointmentService) seriesTargets(ctx context.Context, seriesID, appointmentID uint, scope appointment.EditScope) ([]appointment.Appointment, appointment.Appointment, error) {
+	if !scope.IsValid() {
+		return nil, appointment.Appointment{}, appointment.ErrInvalidEditScope
+	}
+
+	series, err := s.appointmentRepository.GetSeries(ctx, seriesID)
+	if err != nil {
+		return nil, appointment.Appointment{}, err
+	}
+
+	var anchor *appointment.Appointment
+	for i := range series.Appointments {
+		if series.Appointments[i].ID == appointmentID {
+			anchor = &series.Appointments[i]
+			break
+		}
+	}
+	if anchor == nil {
+		return nil, appointment.Appointment{}, appointment.ErrAppointmentNotInSeries
+	}
+
+	var targets []appointment.Appointment
+	for _, occurrence := range series.Appointments {
+		if scope.Includes(anchor.OccurrenceIndex, occurrence.OccurrenceIndex) {
+			targets = append(targets, occurrence)
+		}
+	}
+
+	return targets, *anchor, nil
+}
+
+// GetAvailableSlots returns the bookable slots of the given doctors between from and to.
+// Slots start on SlotStep boundaries and are sorted by start time.
+func (s *appointmentService) GetAvailableSlots(ctx context.Context, clinicID uint, doctorIDs []uint, from, to time.Time, duration time.Duration) ([]appointment.AvailableSlot, error) {
+	if duration <= 0 || !to.After(from) {
+		return nil, fmt.Errorf("%w: duration must be positive and to must be after from", appointment.ErrInvalidSlotSearch)
+	}
+	if to.Sub(from) > appointment.MaxSlotSearchRange {
+		return nil, fmt.Errorf("%w: range can not exceed %d days", appointment.ErrInvalidSlotSearch, int(appointment.MaxSlotSearchRange.Hours()/24))
+	}
+
+	slots := []appointment.AvailableSlot{}
+	for _, doctorID := range doctorIDs {
+		working, err := s.scheduleService.GetWorkingIntervals(ctx, clinicID, doctorID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		existing, err := s.appointmentRepository.GetDoctorAppointments(ctx, doctorID)
+		if err != nil {
+			return nil, err
+		}
+
+		var booked []schedule.Interval
+		for _, appt := range existing {
+			if appt.Status.OccupiesSlot() && appt.ScheduledTime.Before(to) && appt.EndTime.After(from) {
+				booked = append(booked, schedule.Interval{Start: appt.ScheduledTime, End: appt.EndTime})
+			}
+		}
+
+		for _, free := range schedule.Subtract(working, booked) {
+			slots = append(slots, splitIntoSlots(doctorID, free, duration)...)
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Start.Equal(slots[j].Start) {
+			return slots[i].DoctorID < slots[j].DoctorID
+		}
+		return slots[i].Start.Before(slots[j].Start)
+	})
+
+	return slots, nil
+}
+
+// splitIntoSlots lists every SlotStep aligned start inside the free interval that leaves room for duration
+func splitIntoSlots(doctorID uint, free schedule.Interval, duration time.Duration) []appointment.AvailableSlot {
+	var slots []appointment.AvailableSlot
+	start := free.Start.Truncate(appointment.SlotStep)
+	if start.Before(free.Start) {
+		start = start.Add(appointment.SlotStep)
+	}
+	for ; !start.Add(duration).After(free.End); start = start.Add(appointment.SlotStep) {
+		slots = append(slots, appointment.AvailableSlot{
+			DoctorID: doctorID,
+			Start:    start,
+			End:      start.Add(duration),
+		})
+	}
+	return slots
+}
+
+// resolveChangedEndTime lets a changed end time set the duration of an updated appointment. Clients
+// send the stored duration back with the end time, and it would otherwise win over the new end time.
+// A changed duration that disagrees with a changed end time is rejected.
+func resolveChangedEndTime(appt *appointment.Appointment, existing appointment.Appointment) error {
+	if appt.EndTime.IsZero() || appt.EndTime.Equal(existing.EndTime) || appt.DurationMinutes <= 0 {
+		return nil
+	}
+	if appt.DurationMinutes == existing.DurationMinutes {
+		appt.DurationMinutes = 0
+		return nil
+	}
+	if !appt.EndTime.Equal(appt.ScheduledTime.Add(time.Duration(appt.DurationMinutes) * time.Minute)) {
+		return fmt.Errorf("%w: duration_minutes and end_time disagree", appointment.ErrInvalidAppointmentTime)
+	}
+	return nil
+}
+
+// normalizeSchedule fills in the end time and duration of an appointment.
+// An explicit duration wins over an end time; if neither is given the default duration is used.
+func normalizeSchedule(appt *appointment.Appointment) error {
+	if appt.ScheduledTime.IsZero() {
+		return appointment.ErrInvalidAppointmentTime
+	}
+
+	switch {
+	case appt.DurationMinutes > 0:
+		appt.EndTime = appt.ScheduledTime.Add(time.Duration(appt.DurationMinutes) * time.Minute)
+	case appt.EndTime.IsZero():
+		appt.EndTime = appt.ScheduledTime.Add(appointment.DefaultDuration)
+	}
+
+	if !appt.EndTime.After(appt.ScheduledTime) {
+		return appointment.ErrInvalidAppointmentTime
+	}
+
+	appt.DurationMinutes = int(appt.EndTime.Sub(appt.ScheduledTime) / time.Minute)
+	return nil
 }
@@ -0,0 +1,962 @@
+package appointmentService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAppointmentRepository keeps appointments in memory and performs the
+// conflict check atomically, mirroring the locking done by the real repository
+type fakeAppointmentRepository struct {
+	mu           sync.Mutex
+	nextID       uint
+	appointments map[uint]appointment.Appointment
+	series       map[uint]appointment.Series
+	lastFilter   appointment.ListFilter
+}
+
+func newFakeAppointmentRepository() *fakeAppointmentRepository {
+	return &fakeAppointmentRepository{
+		appointments: map[uint]appointment.Appointment{},
+		series:       map[uint]appointment.Series{},
+	}
+}
+
+func (r *fakeAppointmentRepository) conflict(appt appointment.Appointment) error {
+	for _, existing := range r.appointments {
+		if existing.ID == appt.ID || !existing.Status.OccupiesSlot() || !existing.Overlaps(appt) {
+			continue
+		}
+		if existing.DoctorID == appt.DoctorID {
+			return &appointment.ConflictError{Participant: appointment.ConflictDoctor, ConflictingAppointmentID: existing.ID}
+		}
+		if existing.PatientID == appt.PatientID {
+			return &appointment.ConflictError{Participant: appointment.ConflictPatient, ConflictingAppointmentID: existing.ID}
+		}
+		for _, existingResource := range existing.ResourceIDList() {
+			for _, resourceID := range appt.ResourceIDList() {
+				if existingResource == resourceID {
+					return &appointment.ConflictError{Participant: appointment.ConflictResource, ConflictingAppointmentID: existing.ID, ResourceID: resourceID}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *fakeAppointmentRepository) GetAppointments(ctx context.Context, filter appointment.ListFilter) (appointment.Page, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastFilter = filter
+	return appointment.Page{Appointments: []appointment.Appointment{}}, nil
+}
+
+func (r *fakeAppointmentRepository) GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.appointments[id], nil
+}
+
+func (r *fakeAppointmentRepository) CreateAppointment(ctx context.Context, appt appointment.Appointment) (appointment.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.conflict(appt); err != nil {
+		return appointment.Appointment{}, err
+	}
+	r.nextID++
+	appt.ID = r.nextID
+	r.appointments[appt.ID] = appt
+	return appt, nil
+}
+
+func (r *fakeAppointmentRepository) UpdateAppointment(ctx context.Context, appt appointment.Appointment) (appointment.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.conflict(appt); err != nil {
+		return appointment.Appointment{}, err
+	}
+	r.appointments[appt.ID] = appt
+	return appt, nil
+}
+
+func (r *fakeAppointmentRepository) DeleteAppointment(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.appointments, id)
+	return nil
+}
+
+func (r *fakeAppointmentRepository) GetDoctorAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var doctorAppointments []appointment.Appointment
+	for _, appt := range r.appointments {
+		if appt.DoctorID == id {
+			doctorAppointments = append(doctorAppointments, appt)
+		}
+	}
+	return doctorAppointments, nil
+}
+
+func (r *fakeAppointmentRepository) TransitionStatus(ctx context.Context, id uint, from, to appointment.Status, history appointment.StatusHistory) (appointment.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	appt := r.appointments[id]
+	if appt.Status != from {
+		return appointment.Appointment{}, &appointment.InvalidTransitionError{From: from, To: to}
+	}
+	appt.Status = to
+	r.appointments[id] = appt
+	return appt, nil
+}
+
+func (r *fakeAppointmentRepository) GetStatusHistory(ctx context.Context, id uint) ([]appointment.StatusHistory, error) {
+	return nil, nil
+}
+
+func (r *fakeAppointmentRepository) CreateSeries(ctx context.Context, series appointment.Series, occurrences []appointment.Appointment) (appointment.Series, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	series.ID = r.nextID
+	created := map[uint]appointment.Appointment{}
+	for i := range occurrences {
+		if err := r.conflict(occurrences[i]); err != nil {
+			for id := range created {
+				delete(r.appointments, id)
+			}
+			return appointment.Series{}, &appointment.OccurrenceError{Index: i, Start: occurrences[i].ScheduledTime, Err: err}
+		}
+		r.nextID++
+		occurrences[i].ID = r.nextID
+		occurrences[i].SeriesID = &series.ID
+		r.appointments[occurrences[i].ID] = occurrences[i]
+		created[occurrences[i].ID] = occurrences[i]
+	}
+	r.series[series.ID] = series
+	series.Appointments = occurrences
+	return series, nil
+}
+
+func (r *fakeAppointmentRepository) GetSeries(ctx context.Context, id uint) (appointment.Series, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	series, ok := r.series[id]
+	if !ok {
+		return appointment.Series{}, appointment.ErrSeriesNotFound
+	}
+	series.Appointments = nil
+	for index := 0; ; index++ {
+		found := false
+		for _, appt := range r.appointments {
+			if appt.SeriesID != nil && *appt.SeriesID == id && appt.OccurrenceIndex == index {
+				series.Appointments = append(series.Appointments, appt)
+				found = true
+			}
+		}
+		if !found {
+			return series, nil
+		}
+	}
+}
+
+func (r *fakeAppointmentRepository) UpdateSeriesAppointments(ctx context.Context, appointments []appointment.Appointment) ([]appointment.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := map[uint]appointment.Appointment{}
+	for _, appt := range appointments {
+		previous[appt.ID] = r.appointments[appt.ID]
+		r.appointments[appt.ID] = appt
+	}
+	for _, appt := range appointments {
+		if err := r.conflict(appt); err != nil {
+			for id, old := range previous {
+				r.appointments[id] = old
+			}
+			return nil, &appointment.OccurrenceError{Index: appt.OccurrenceIndex, Start: appt.ScheduledTime, Err: err}
+		}
+	}
+	return appointments, nil
+}
+
+func (r *fakeAppointmentRepository) GetPatientAppointments(ctx context.Context, id uint) ([]appointment.Appointment, error) {
+	return nil, nil
+}
+
+// alwaysOpenSchedule accepts every booking
+type alwaysOpenSchedule struct{}
+
+func (alwaysOpenSchedule) CheckWorkingTime(ctx context.Context, clinicID, doctorID uint, start, end time.Time) error {
+	return nil
+}
+
+func (alwaysOpenSchedule) GetWorkingIntervals(ctx context.Context, clinicID, doctorID uint, from, to time.Time) ([]schedule.Interval, error) {
+	return []schedule.Interval{{Start: from, End: to}}, nil
+}
+
+// recordingSlotReleaser remembers which appointments were handed to the waitlist
+type recordingSlotReleaser struct {
+	mu       sync.Mutex
+	released []uint
+}
+
+func (r *recordingSlotReleaser) ReleaseSlot(ctx context.Context, released appointment.Appointment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.released = append(r.released, released.ID)
+	return nil
+}
+
+// staticAlerts flags every patient with a penicillin allergy
+type staticAlerts struct{}
+
+func (staticAlerts) GetAlerts(ctx context.Context, clinicID uint, patientIDs []uint) (map[uint][]medical.Alert, error) {
+	alerts := map[uint][]medical.Alert{}
+	for _, id := range patientIDs {
+		alerts[id] = []medical.Alert{{Level: medical.AlertCritical, Kind: medical.AlertKindAllergy, SourceID: id, Message: "Allergic to Penicillin (severe)"}}
+	}
+	return alerts, nil
+}
+
+// noConsents requires no consent for any procedure
+type noConsents struct{}
+
+func (noConsents) CheckConsents(ctx context.Context, clinicID, patientID, procedureID uint) error {
+	return nil
+}
+
+// noPlannedProcedures books no treatment plan items on any appointment
+type noPlannedProcedures struct{}
+
+func (noPlannedProcedures) GetAppointmentProcedureIDs(ctx context.Context, appointmentID uint) ([]uint, error) {
+	return nil, nil
+}
+
+// plannedProcedures holds the procedures of the plan items booked on each appointment
+type plannedProcedures map[uint][]uint
+
+func (p plannedProcedures) GetAppointmentProcedureIDs(ctx context.Context, appointmentID uint) ([]uint, error) {
+	return p[appointmentID], nil
+}
+
+// unsignedConsents reports the consents of the listed procedures as missing
+type unsignedConsents struct {
+	procedureIDs []uint
+}
+
+func (c unsignedConsents) CheckConsents(ctx context.Context, clinicID, patientID, procedureID uint) error {
+	for _, id := range c.procedureIDs {
+		if id == procedureID {
+			return &consent.MissingConsentError{ProcedureID: procedureID, Missing: []consent.Requirement{{TemplateID: 1, Name: "Extraction"}}}
+		}
+	}
+	return nil
+}
+
+var baseTime = time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+
+func TestCreateAppointmentConflicts(t *testing.T) {
+	tests := []struct {
+		name            string
+		existing        appointment.Appointment
+		candidate       appointment.Appointment
+		wantParticipant string
+	}{
+		{
+			name:            "Same doctor overlapping",
+			existing:        appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, DurationMinutes: 30},
+			candidate:       appointment.Appointment{DoctorID: 1, PatientID: 2, ScheduledTime: baseTime.Add(15 * time.Minute), DurationMinutes: 30},
+			wantParticipant: appointment.ConflictDoctor,
+		},
+		{
+			name:            "Same patient overlapping",
+			existing:        appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, DurationMinutes: 60},
+			candidate:       appointment.Appointment{DoctorID: 2, PatientID: 1, ScheduledTime: baseTime.Add(30 * time.Minute), DurationMinutes: 30},
+			wantParticipant: appointment.ConflictPatient,
+		},
+		{
+			name:            "Same chair overlapping",
+			existing:        appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, DurationMinutes: 30, ResourceIDs: []uint{7}},
+			candidate:       appointment.Appointment{DoctorID: 2, PatientID: 2, ScheduledTime: baseTime.Add(15 * time.Minute), DurationMinutes: 30, ResourceIDs: []uint{3, 7}},
+			wantParticipant: appointment.ConflictResource,
+		},
+		{
+			name:      "Different chairs",
+			existing:  appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, DurationMinutes: 30, ResourceIDs: []uint{7}},
+			candidate: appointment.Appointment{DoctorID: 2, PatientID: 2, ScheduledTime: baseTime, DurationMinutes: 30, ResourceIDs: []uint{8}},
+		},
+		{
+			name:      "Back to back",
+			existing:  appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, DurationMinutes: 30},
+			candidate: appointment.Appointment{DoctorID: 1, PatientID: 2, ScheduledTime: baseTime.Add(30 * time.Minute), DurationMinutes: 30},
+		},
+		{
+			name:      "Different doctor and patient",
+			existing:  appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, DurationMinutes: 30},
+			candidate: appointment.Appointment{DoctorID: 2, PatientID: 2, ScheduledTime: baseTime, DurationMinutes: 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+			if _, err := service.CreateAppointment(context.Background(), tt.existing); err != nil {
+				t.Fatalf("unexpected error creating existing appointment: %v", err)
+			}
+
+			_, err := service.CreateAppointment(context.Background(), tt.candidate)
+			if tt.wantParticipant == "" {
+				if err != nil {
+					t.Errorf("CreateAppointment() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var conflictErr *appointment.ConflictError
+			if !errors.As(err, &conflictErr) {
+				t.Fatalf("CreateAppointment() error = %v, want ConflictError", err)
+			}
+			if conflictErr.Participant != tt.wantParticipant {
+				t.Errorf("ConflictError.Participant = %s, want %s", conflictErr.Participant, tt.wantParticipant)
+			}
+			if !errors.Is(err, appointment.ErrAppointmentConflict) {
+				t.Errorf("error does not wrap ErrAppointmentConflict")
+			}
+		})
+	}
+}
+
+func TestCreateAppointmentNormalizesSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		appointment  appointment.Appointment
+		wantDuration int
+		wantErr      bool
+	}{
+		{
+			name:         "Default duration",
+			appointment:  appointment.Appointment{ScheduledTime: baseTime},
+			wantDuration: 30,
+		},
+		{
+			name:         "Explicit end time",
+			appointment:  appointment.Appointment{ScheduledTime: baseTime, EndTime: baseTime.Add(45 * time.Minute)},
+			wantDuration: 45,
+		},
+		{
+			name:        "End time before start",
+			appointment: appointment.Appointment{ScheduledTime: baseTime, EndTime: baseTime.Add(-time.Minute)},
+			wantErr:     true,
+		},
+		{
+			name:        "Missing scheduled time",
+			appointment: appointment.Appointment{DurationMinutes: 30},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+			created, err := service.CreateAppointment(context.Background(), tt.appointment)
+			if tt.wantErr {
+				if !errors.Is(err, appointment.ErrInvalidAppointmentTime) {
+					t.Errorf("CreateAppointment() error = %v, want ErrInvalidAppointmentTime", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateAppointment() error = %v", err)
+			}
+			if created.DurationMinutes != tt.wantDuration {
+				t.Errorf("DurationMinutes = %d, want %d", created.DurationMinutes, tt.wantDuration)
+			}
+		})
+	}
+}
+
+func TestCreateAppointmentParallelDoubleBooking(t *testing.T) {
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+
+	const attempts = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, conflicted := 0, 0
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(patientID uint) {
+			defer wg.Done()
+			_, err := service.CreateAppointment(context.Background(), appointment.Appointment{
+				DoctorID:        7,
+				PatientID:       patientID,
+				ScheduledTime:   baseTime,
+				DurationMinutes: 30,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, appointment.ErrAppointmentConflict):
+				conflicted++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(uint(i + 1))
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("succeeded = %d, want exactly 1", succeeded)
+	}
+	if conflicted != attempts-1 {
+		t.Errorf("conflicted = %d, want %d", conflicted, attempts-1)
+	}
+}
+
+func TestGetAvailableSlots(t *testing.T) {
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+	ctx := context.Background()
+
+	// Doctor 1 is busy 10:30-11:00
+	if _, err := service.CreateAppointment(ctx, appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime.Add(30 * time.Minute), DurationMinutes: 30}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slots, err := service.GetAvailableSlots(ctx, 1, []uint{1, 2}, baseTime, baseTime.Add(time.Hour), 30*time.Minute)
+	if err != nil {
+		t.Fatalf("GetAvailableSlots() error = %v", err)
+	}
+
+	got := map[uint][]string{}
+	for _, slot := range slots {
+		got[slot.DoctorID] = append(got[slot.DoctorID], slot.Start.Format("15:04"))
+	}
+
+	want := map[uint][]string{
+		1: {"10:00"},
+		2: {"10:00", "10:15", "10:30"},
+	}
+	for doctorID, starts := range want {
+		if len(got[doctorID]) != len(starts) {
+			t.Fatalf("doctor %d slots = %v, want %v", doctorID, got[doctorID], starts)
+		}
+		for i := range starts {
+			if got[doctorID][i] != starts[i] {
+				t.Errorf("doctor %d slots = %v, want %v", doctorID, got[doctorID], starts)
+			}
+		}
+	}
+
+	if _, err := service.GetAvailableSlots(ctx, 1, []uint{1}, baseTime, baseTime.AddDate(0, 2, 0), 30*time.Minute); !errors.Is(err, appointment.ErrInvalidSlotSearch) {
+		t.Errorf("GetAvailableSlots() error = %v, want ErrInvalidSlotSearch for a too long range", err)
+	}
+}
+
+func TestTransitionStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    []appointment.Status
+		reason  string
+		wantErr error
+	}{
+		{
+			name: "Full visit",
+			path: []appointment.Status{appointment.StatusConfirmed, appointment.StatusCheckedIn, appointment.StatusInChair, appointment.StatusCompleted},
+		},
+		{
+			name:    "Complete without check in",
+			path:    []appointment.Status{appointment.StatusCompleted},
+			wantErr: appointment.ErrInvalidStatusTransition,
+		},
+		{
+			name:    "Cancel without reason",
+			path:    []appointment.Status{appointment.StatusCancelled},
+			wantErr: appointment.ErrCancellationReasonRequired,
+		},
+		{
+			name:   "Cancel with reason",
+			path:   []appointment.Status{appointment.StatusCancelled},
+			reason: "Patient called in sick",
+		},
+		{
+			name:    "No show after completion",
+			path:    []appointment.Status{appointment.StatusCheckedIn, appointment.StatusInChair, appointment.StatusCompleted, appointment.StatusNoShow},
+			wantErr: appointment.ErrInvalidStatusTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+			ctx := context.Background()
+			created, err := service.CreateAppointment(ctx, appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, status := range tt.path {
+				_, err = service.TransitionStatus(ctx, created.ID, status, 1, tt.reason)
+				if err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("TransitionStatus() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("TransitionStatus() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStartRequiresSignedConsent(t *testing.T) {
+	planned := plannedProcedures{}
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, unsignedConsents{procedureIDs: []uint{4}}, planned)
+	ctx := context.Background()
+	extraction, filling := uint(4), uint(5)
+
+	for _, tt := range []struct {
+		name        string
+		procedureID *uint
+		planned     []uint
+		wantErr     error
+	}{
+		{name: "Extraction without consent", procedureID: &extraction, wantErr: consent.ErrConsentRequired},
+		{name: "Filling", procedureID: &filling},
+		{name: "No procedure"},
+		{name: "Planned extraction without a procedure on the appointment", planned: []uint{5, 4}, wantErr: consent.ErrConsentRequired},
+		{name: "Filling with a planned extraction", procedureID: &filling, planned: []uint{4}, wantErr: consent.ErrConsentRequired},
+		{name: "Planned filling", planned: []uint{5}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := service.CreateAppointment(ctx, appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, ProcedureID: tt.procedureID})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			planned[created.ID] = tt.planned
+			defer func() { _ = service.DeleteAppointment(ctx, created.ID) }()
+
+			if _, err := service.TransitionStatus(ctx, created.ID, appointment.StatusCheckedIn, 1, ""); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err = service.TransitionStatus(ctx, created.ID, appointment.StatusInChair, 1, "")
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("TransitionStatus() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("TransitionStatus() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCancelledAppointmentFreesSlot(t *testing.T) {
+	releaser := &recordingSlotReleaser{}
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, releaser, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+	ctx := context.Background()
+
+	first, err := service.CreateAppointment(ctx, appointment.Appointment{DoctorID: 1, PatientID: 1, ScheduledTime: baseTime})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.TransitionStatus(ctx, first.ID, appointment.StatusCancelled, 1, "Rescheduled"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(releaser.released) != 1 || releaser.released[0] != first.ID {
+		t.Errorf("released slots = %v, want the cancelled appointment %d", releaser.released, first.ID)
+	}
+
+	second, err := service.CreateAppointment(ctx, appointment.Appointment{DoctorID: 1, PatientID: 2, ScheduledTime: baseTime})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v, want nil after cancellation", err)
+	}
+
+	if err := service.DeleteAppointment(ctx, second.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(releaser.released) != 2 || releaser.released[1] != second.ID {
+		t.Errorf("released slots = %v, want the deleted appointment %d", releaser.released, second.ID)
+	}
+}
+
+func TestCreateSeries(t *testing.T) {
+	tests := []struct {
+		name      string
+		rrule     string
+		blocking  *appointment.Appointment
+		wantCount int
+		wantErr   error
+	}{
+		{
+			name:      "Every four weeks for a year",
+			rrule:     "FREQ=WEEKLY;INTERVAL=4;COUNT=13",
+			wantCount: 13,
+		},
+		{
+			name:      "Until date",
+			rrule:     "RRULE:FREQ=DAILY;UNTIL=20250307",
+			wantCount: 5,
+		},
+		{
+			name:    "Missing end",
+			rrule:   "FREQ=WEEKLY",
+			wantErr: appointment.ErrInvalidRecurrence,
+		},
+		{
+			name:    "Unsupported part",
+			rrule:   "FREQ=WEEKLY;COUNT=3;BYDAY=MO",
+			wantErr: appointment.ErrInvalidRecurrence,
+		},
+		{
+			name:     "Occurrence conflicts",
+			rrule:    "FREQ=WEEKLY;COUNT=3",
+			blocking: &appointment.Appointment{DoctorID: 1, PatientID: 99, ScheduledTime: baseTime.AddDate(0, 0, 14)},
+			wantErr:  appointment.ErrAppointmentConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeAppointmentRepository()
+			service := NewAppointmentService(repository, alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+			ctx := context.Background()
+			if tt.blocking != nil {
+				if _, err := service.CreateAppointment(ctx, *tt.blocking); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			series, err := service.CreateSeries(ctx, appointment.Series{DoctorID: 1, PatientID: 1, RRule: tt.rrule, StartTime: baseTime, DurationMinutes: 30})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("CreateSeries() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.blocking != nil && len(repository.appointments) != 1 {
+					t.Errorf("%d appointments stored after a failed series, want only the blocking one", len(repository.appointments))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateSeries() error = %v", err)
+			}
+			if len(series.Appointments) != tt.wantCount {
+				t.Errorf("len(Appointments) = %d, want %d", len(series.Appointments), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestUpdateAndCancelSeries(t *testing.T) {
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+	ctx := context.Background()
+
+	series, err := service.CreateSeries(ctx, appointment.Series{DoctorID: 1, PatientID: 1, RRule: "FREQ=WEEKLY;COUNT=4", StartTime: baseTime, DurationMinutes: 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second := series.Appointments[1]
+
+	// Move the second and following occurrences one week later; they must not collide with each other
+	updated, err := service.UpdateSeries(ctx, series.ID, second.ID, appointment.ScopeFollowing, appointment.Appointment{ScheduledTime: second.ScheduledTime.AddDate(0, 0, 7)})
+	if err != nil {
+		t.Fatalf("UpdateSeries() error = %v", err)
+	}
+	if len(updated) != 3 {
+		t.Errorf("UpdateSeries() updated %d occurrences, want 3", len(updated))
+	}
+
+	cancelled, err := service.CancelSeries(ctx, series.ID, series.Appointments[0].ID, appointment.ScopeThis, 1, "Holiday")
+	if err != nil {
+		t.Fatalf("CancelSeries() error = %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0].Status != appointment.StatusCancelled {
+		t.Errorf("CancelSeries() = %+v, want the first occurrence cancelled", cancelled)
+	}
+
+	if _, err := service.CancelSeries(ctx, series.ID, second.ID, appointment.EditScope("some"), 1, "Holiday"); !errors.Is(err, appointment.ErrInvalidEditScope) {
+		t.Errorf("CancelSeries() error = %v, want ErrInvalidEditScope", err)
+	}
+}
+
+func TestGetAppointmentsFilter(t *testing.T) {
+	ascending := appointment.ListFilter{SortBy: appointment.SortScheduledTime}
+	last := appointment.Appointment{ScheduledTime: baseTime}
+	last.ID = 42
+	ascendingCursor := ascending.NextCursor(last)
+
+	tests := []struct {
+		name      string
+		filter    appointment.ListFilter
+		wantLimit int
+		wantErr   bool
+	}{
+		{name: "Defaults", filter: appointment.ListFilter{ClinicID: 1}, wantLimit: appointment.DefaultPageSize},
+		{name: "Explicit limit", filter: appointment.ListFilter{ClinicID: 1, Limit: 10}, wantLimit: 10},
+		{name: "Limit too large", filter: appointment.ListFilter{ClinicID: 1, Limit: appointment.MaxPageSize + 1}, wantErr: true},
+		{name: "To before from", filter: appointment.ListFilter{ClinicID: 1, From: baseTime, To: baseTime.Add(-time.Hour)}, wantErr: true},
+		{name: "Unknown status", filter: appointment.ListFilter{ClinicID: 1, Statuses: []appointment.Status{"lost"}}, wantErr: true},
+		{name: "Unknown sort", filter: appointment.ListFilter{ClinicID: 1, SortBy: "patient_name"}, wantErr: true},
+		{name: "Malformed cursor", filter: appointment.ListFilter{ClinicID: 1, Cursor: "not-a-cursor"}, wantErr: true},
+		{name: "Cursor of another sort order", filter: appointment.ListFilter{ClinicID: 1, Descending: true, Cursor: ascendingCursor}, wantErr: true},
+		{name: "Cursor of the same sort order", filter: appointment.ListFilter{ClinicID: 1, Cursor: ascendingCursor}, wantLimit: appointment.DefaultPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeAppointmentRepository()
+			service := NewAppointmentService(repository, alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+
+			_, err := service.GetAppointments(context.Background(), tt.filter)
+			if tt.wantErr {
+				if !errors.Is(err, appointment.ErrInvalidListFilter) {
+					t.Errorf("GetAppointments() error = %v, want ErrInvalidListFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAppointments() error = %v", err)
+			}
+			if repository.lastFilter.Limit != tt.wantLimit || repository.lastFilter.SortBy != appointment.SortScheduledTime {
+				t.Errorf("repository got limit %d sorted by %q, want %d sorted by scheduled_time", repository.lastFilter.Limit, repository.lastFilter.SortBy, tt.wantLimit)
+			}
+		})
+	}
+
+	cursor, err := appointment.ListFilter{SortBy: appointment.SortScheduledTime, Cursor: ascendingCursor}.After()
+	if err != nil || cursor.ID != 42 || !cursor.Value.Equal(baseTime) {
+		t.Errorf("After() = %+v, %v, want the position of appointment 42", cursor, err)
+	}
+}
+
+func TestAppointmentsCarryPatientAlerts(t *testing.T) {
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+
+	created, err := service.CreateAppointment(context.Background(), appointment.Appointment{ClinicID: 1, DoctorID: 1, PatientID: 4, ScheduledTime: baseTime})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v", err)
+	}
+	if len(created.PatientAlerts) != 1 || created.PatientAlerts[0].SourceID != 4 {
+		t.Errorf("created appointment alerts = %+v, want the alert of patient 4", created.PatientAlerts)
+	}
+
+	fetched, err := service.GetAppointment(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("GetAppointment() error = %v", err)
+	}
+	if len(fetched.PatientAlerts) != 1 || fetched.PatientAlerts[0].Level != medical.AlertCritical {
+		t.Errorf("fetched appointment alerts = %+v, want a critical alert", fetched.PatientAlerts)
+	}
+}
+
+// alertsByPatient returns the alerts stored for each patient record and records which patients were asked for
+type alertsByPatient struct {
+	alerts    map[uint][]medical.Alert
+	requested []uint
+}
+
+func (a *alertsByPatient) GetAlerts(ctx context.Context, clinicID uint, patientIDs []uint) (map[uint][]medical.Alert, error) {
+	a.requested = append(a.requested, patientIDs...)
+	found := map[uint][]medical.Alert{}
+	for _, id := range patientIDs {
+		if alerts, ok := a.alerts[id]; ok {
+			found[id] = alerts
+		}
+	}
+	return found, nil
+}
+
+func TestAlertsFollowThePatientRecord(t *testing.T) {
+	// Patient record 12 is booked with doctor 7; patient record 7 is someone else on anticoagulants
+	alerts := &alertsByPatient{alerts: map[uint][]medical.Alert{
+		7:  {{Level: medical.AlertCritical, Kind: medical.AlertKindMedication, SourceID: 70, Message: "Takes Warfarin"}},
+		12: {{Level: medical.AlertCritical, Kind: medical.AlertKindAllergy, SourceID: 120, Message: "Allergic to Penicillin (severe)"}},
+	}}
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, alerts, noConsents{}, noPlannedProcedures{})
+
+	created, err := service.CreateAppointment(context.Background(), appointment.Appointment{ClinicID: 1, DoctorID: 7, PatientID: 12, ScheduledTime: baseTime})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v", err)
+	}
+	if len(created.PatientAlerts) != 1 || created.PatientAlerts[0].SourceID != 120 {
+		t.Errorf("alerts = %+v, want only the penicillin allergy of patient record 12", created.PatientAlerts)
+	}
+	if len(alerts.requested) != 1 || alerts.requested[0] != 12 {
+		t.Errorf("alerts were loaded for patients %v, want [12]", alerts.requested)
+	}
+}
+
+// recordingConsents requires no consent and records the patient records it was asked about
+type recordingConsents struct {
+	patientIDs []uint
+}
+
+func (c *recordingConsents) CheckConsents(ctx context.Context, clinicID, patientID, procedureID uint) error {
+	c.patientIDs = append(c.patientIDs, patientID)
+	return nil
+}
+
+func TestStartChecksConsentsOfThePatientRecord(t *testing.T) {
+	consents := &recordingConsents{}
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, consents, noPlannedProcedures{})
+	ctx := context.Background()
+	procedureID := uint(4)
+
+	// Patient record 12 sees doctor 7; user 3 seats the patient
+	created, err := service.CreateAppointment(ctx, appointment.Appointment{DoctorID: 7, PatientID: 12, ScheduledTime: baseTime, ProcedureID: &procedureID})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v", err)
+	}
+	for _, status := range []appointment.Status{appointment.StatusCheckedIn, appointment.StatusInChair} {
+		if _, err := service.TransitionStatus(ctx, created.ID, status, 3, ""); err != nil {
+			t.Fatalf("TransitionStatus(%s) error = %v", status, err)
+		}
+	}
+	if len(consents.patientIDs) != 1 || consents.patientIDs[0] != 12 {
+		t.Errorf("consents were checked for patients %v, want [12]", consents.patientIDs)
+	}
+}
+
+func TestUpdateFinishedAppointment(t *testing.T) {
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+	ctx := context.Background()
+	procedureID := uint(5)
+
+	created, err := service.CreateAppointment(ctx, appointment.Appointment{ClinicID: 1, DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, ProcedureID: &procedureID})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v", err)
+	}
+	for _, status := range []appointment.Status{appointment.StatusCheckedIn, appointment.StatusInChair, appointment.StatusCompleted} {
+		if created, err = service.TransitionStatus(ctx, created.ID, status, 1, ""); err != nil {
+			t.Fatalf("TransitionStatus(%s) error = %v", status, err)
+		}
+	}
+
+	moved := created
+	moved.ScheduledTime = baseTime.Add(24 * time.Hour)
+	moved.EndTime = time.Time{}
+	if _, err := service.UpdateAppointment(ctx, moved); !errors.Is(err, appointment.ErrAppointmentNotEditable) {
+		t.Errorf("moving a completed appointment: error = %v, want %v", err, appointment.ErrAppointmentNotEditable)
+	}
+	otherDoctor := created
+	otherDoctor.DoctorID = 2
+	if _, err := service.UpdateAppointment(ctx, otherDoctor); !errors.Is(err, appointment.ErrAppointmentNotEditable) {
+		t.Errorf("changing the doctor of a completed appointment: error = %v, want %v", err, appointment.ErrAppointmentNotEditable)
+	}
+
+	noted := created
+	noted.Notes = "Patient asked for an invoice copy"
+	updated, err := service.UpdateAppointment(ctx, noted)
+	if err != nil {
+		t.Fatalf("changing the notes of a completed appointment: error = %v", err)
+	}
+	if updated.Notes != noted.Notes || !updated.ScheduledTime.Equal(baseTime) {
+		t.Errorf("updated = %+v", updated)
+	}
+}
+
+func TestUpdateAppointmentEndTime(t *testing.T) {
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+	ctx := context.Background()
+
+	created, err := service.CreateAppointment(ctx, appointment.Appointment{ClinicID: 1, DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, DurationMinutes: 30})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		start        time.Time
+		end          time.Time
+		duration     int
+		wantEnd      time.Time
+		wantDuration int
+		wantErr      error
+	}{
+		{"New end time with the stored duration", baseTime, baseTime.Add(45 * time.Minute), 30, baseTime.Add(45 * time.Minute), 45, nil},
+		{"New duration with the stored end time", baseTime, baseTime.Add(30 * time.Minute), 60, baseTime.Add(60 * time.Minute), 60, nil},
+		{"New end time and matching duration", baseTime, baseTime.Add(50 * time.Minute), 50, baseTime.Add(50 * time.Minute), 50, nil},
+		{"New start with the stored end time and duration", baseTime.Add(time.Hour), baseTime.Add(30 * time.Minute), 30, baseTime.Add(90 * time.Minute), 30, nil},
+		{"New end time and disagreeing duration", baseTime, baseTime.Add(50 * time.Minute), 40, time.Time{}, 0, appointment.ErrInvalidAppointmentTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := created
+			changed.ScheduledTime, changed.EndTime, changed.DurationMinutes = tt.start, tt.end, tt.duration
+			updated, err := service.UpdateAppointment(ctx, changed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateAppointment() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !updated.EndTime.Equal(tt.wantEnd) || updated.DurationMinutes != tt.wantDuration {
+				t.Errorf("UpdateAppointment() end = %v, duration = %d, want %v, %d", updated.EndTime, updated.DurationMinutes, tt.wantEnd, tt.wantDuration)
+			}
+			// Put the appointment back for the next case
+			if _, err := service.UpdateAppointment(ctx, created); err != nil {
+				t.Fatalf("restoring the appointment: %v", err)
+			}
+		})
+	}
+}
+
+// closedSchedule rejects every booking, as after the clinic shortened its hours
+type closedSchedule struct{ alwaysOpenSchedule }
+
+func (closedSchedule) CheckWorkingTime(ctx context.Context, clinicID, doctorID uint, start, end time.Time) error {
+	return schedule.ErrOutsideWorkingHours
+}
+
+func TestUpdateOutsideWorkingHours(t *testing.T) {
+	repo := newFakeAppointmentRepository()
+	procedureID := uint(5)
+	created, err := NewAppointmentService(repo, alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{}).
+		CreateAppointment(context.Background(), appointment.Appointment{ClinicID: 1, DoctorID: 1, PatientID: 1, ScheduledTime: baseTime, ProcedureID: &procedureID})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v", err)
+	}
+	service := NewAppointmentService(repo, closedSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, noConsents{}, noPlannedProcedures{})
+
+	noted := created
+	noted.Notes = "Bring previous x-rays"
+	if _, err := service.UpdateAppointment(context.Background(), noted); err != nil {
+		t.Errorf("changing only the notes: error = %v, want nil", err)
+	}
+	moved := created
+	moved.ScheduledTime = baseTime.Add(time.Hour)
+	moved.EndTime = time.Time{}
+	if _, err := service.UpdateAppointment(context.Background(), moved); !errors.Is(err, schedule.ErrOutsideWorkingHours) {
+		t.Errorf("moving the appointment: error = %v, want %v", err, schedule.ErrOutsideWorkingHours)
+	}
+}
+
+func TestCompleteSeriesOccurrence(t *testing.T) {
+	// Series occurrences have no procedure, so the consent gate has nothing to check
+	service := NewAppointmentService(newFakeAppointmentRepository(), alwaysOpenSchedule{}, &recordingSlotReleaser{}, staticAlerts{}, unsignedConsents{procedureIDs: []uint{4}}, noPlannedProcedures{})
+	ctx := context.Background()
+
+	series, err := service.CreateSeries(ctx, appointment.Series{DoctorID: 1, PatientID: 1, RRule: "FREQ=WEEKLY;COUNT=2", StartTime: baseTime, DurationMinutes: 30})
+	if err != nil {
+		t.Fatalf("CreateSeries() error = %v", err)
+	}
+	occurrence := series.Appointments[0]
+	for _, status := range []appointment.Status{appointment.StatusCheckedIn, appointment.StatusInChair, appointment.StatusCompleted} {
+		if _, err := service.TransitionStatus(ctx, occurrence.ID, status, 1, ""); err != nil {
+			t.Fatalf("TransitionStatus(%s) error = %v", status, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package attachmentService
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dental-clinic-system/models/attachment"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// DownloadLinkTTL is how long a signed download link stays valid
+const DownloadLinkTTL = 5 * time.Minute
+
+// maxFileNameLength is the longest file name kept for an attachment
+const maxFileNameLength = 200
+
+// sniffLength is how much of a file is inspected to detect its content type
+const sniffLength = 512
+
+// AttachmentRepository defines the attachment database operations
+type AttachmentRepository interface {
+	GetAttachments(ctx context.Context, clinicID, patientID uint, category attachment.Category) ([]attachment.Attachment, error)
+	GetAttachment(ctx context.Context, id uint) (attachment.Attachment, error)
+	CreateAttachment(ctx context.Context, a attachment.Attachment) (attachment.Attachment, error)
+	DeleteAttachment(ctx context.Context, id uint) error
+}
+
+// Storage keeps the attachment files, e.g. on the local filesystem or in an S3 bucket
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType, checksum string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// AttachmentService stores patients' X-rays, photos and scanned documents and hands out signed download links
+type AttachmentService struct {
+	attachmentRepository AttachmentRepository
+	storage              Storage
+	signingKey           []byte
+	maxBytes             int64
+	now                  func() time.Time
+}
+
+// NewAttachmentService creates a new instance of AttachmentService. Download links are signed with signingKey;
+// files larger than maxBytes are rejected, and attachment.DefaultMaxBytes is used when it is not positive.
+func NewAttachmentService(attachmentRepo AttachmentRepository, storage Storage, signingKey string, maxBytes int64) *AttachmentService {
+	if maxBytes <= 0 {
+		maxBytes = attachment.DefaultMaxBytes
+	}
+	return &AttachmentService{
+		attachmentRepository: attachmentRepo,
+		storage:              storage,
+		signingKey:           []byte(signingKey),
+		maxBytes:             maxBytes,
+		now:                  time.Now,
+	}
+}
+
+// MaxBytes returns the largest file size accepted
+func (s *AttachmentService) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Upload streams a file into storage and records it for the patient. The content type is detected
+// from the file itself and must be accepted by the category; the SHA-256 checksum is computed on the way.
+func (s *AttachmentService) Upload(ctx context.Context, upload attachment.Upload, r io.Reader) (attachment.Attachment, error) {
+	if !upload.Category.IsValid() {
+		return attachment.Attachment{}, fmt.Errorf("%w: unknown category %q", attachment.ErrAttachmentValidation, upload.Category)
+	}
+	fileName := cleanFileName(upload.FileName)
+	if fileName == "" {
+		return attachment.Attachment{}, fmt.Errorf("%w: file name is required", attachment.ErrAttachmentValidation)
+	}
+
+	// The upload is spooled to a temporary file so that its size and checksum are known before it is stored
+	spool, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return attachment.Attachment{}, err
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return attachment.Attachment{}, err
+	}
+	if size > s.maxBytes {
+		return attachment.Attachment{}, fmt.Errorf("%w: the limit is %d MB", attachment.ErrFileTooLarge, s.maxBytes>>20)
+	}
+	if size == 0 {
+		return attachment.Attachment{}, fmt.Errorf("%w: file is empty", attachment.ErrAttachmentValidation)
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := spool.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return attachment.Attachment{}, err
+	}
+	contentType := DetectContentType(head[:n])
+	if !upload.Category.Accepts(contentType) {
+		return attachment.Attachment{}, fmt.Errorf("%w: %s files can not be filed as %s, use one of %s",
+			attachment.ErrUnsupportedType, contentType, upload.Category, strings.Join(upload.Category.AllowedTypes(), ", "))
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return attachment.Attachment{}, err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	key := fmt.Sprintf("clinics/%d/patients/%d/%s", upload.ClinicID, upload.PatientID, uuid.NewString())
+	if err := s.storage.Put(ctx, key, spool, size, contentType, checksum); err != nil {
+		return attachment.Attachment{}, err
+	}
+
+	created, err := s.attachmentRepository.CreateAttachment(ctx, attachment.Attachment{
+		ClinicID:     upload.ClinicID,
+		PatientID:    upload.PatientID,
+		UploadedByID: upload.UploadedByID,
+		Category:     upload.Category,
+		Description:  strings.TrimSpace(upload.Description),
+		FileName:     fileName,
+		ContentType:  contentType,
+		SizeBytes:    size,
+		SHA256:       checksum,
+		StorageKey:   key,
+	})
+	if err != nil {
+		if deleteErr := s.storage.Delete(ctx, key); deleteErr != nil {
+			log.Error().
+				Str("operation", "Upload").
+				Err(deleteErr).
+				Str("key", key).
+				Msg("Failed to remove stored file of an attachment that could not be recorded")
+		}
+		return attachment.Attachment{}, err
+	}
+	return created, nil
+}
+
+// GetAttachments returns the patient's attachments, newest first, optionally of one category
+func (s *AttachmentService) GetAttachments(ctx context.Context, clinicID, patientID uint, category attachment.Category) ([]attachment.Attachment, error) {
+	if category != "" && !category.IsValid() {
+		return nil, fmt.Errorf("%w: unknown category %q", attachment.ErrAttachmentValidation, category)
+	}
+	return s.attachmentRepository.GetAttachments(ctx, clinicID, patientID, category)
+}
+
+// GetAttachment retrieves an attachment by its ID
+func (s *AttachmentService) GetAttachment(ctx context.Context, id uint) (attachment.Attachment, error) {
+	return s.attachmentRepository.GetAttachment(ctx, id)
+}
+
+// DeleteAttachment removes an attachment from the patient's record. The stored file is kept,
+// as patient records must be retained, but it can no longer be listed or downloaded.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, id uint) error {
+	return s.attachmentRepository.DeleteAttachment(ctx, id)
+}
+
+// DownloadLink returns a link to download the attachment that is valid for DownloadLinkTTL.
+// The caller must already have checked that the user may see the attachment.
+func (s *AttachmentService) DownloadLink(a attachment.Attachment) attachment.DownloadLink {
+	expiresAt := s.now().Add(DownloadLinkTTL).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return attachment.DownloadLink{
+		URL:       fmt.Sprintf("/attachments/%d/download?expires=%s&signature=%s", a.ID, expires, s.signature(a.ID, expires)),
+		ExpiresAt: expiresAt,
+	}
+}
+
+// OpenDownload checks a signed download link and opens the attachment's file. The caller must close the reader.
+func (s *AttachmentService) OpenDownload(ctx context.Context, id uint, expires, signature string) (attachment.Attachment, io.ReadCloser, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.signature(id, expires))) || s.now().Unix() > expiresAt {
+		return attachment.Attachment{}, nil, attachment.ErrInvalidSignature
+	}
+
+	a, err := s.attachmentRepository.GetAttachment(ctx, id)
+	if err != nil {
+		return attachment.Attachment{}, nil, err
+	}
+	file, err := s.storage.Get(ctx, a.StorageKey)
+	if err != nil {
+		return attachment.Attachment{}, nil, err
+	}
+	return a, file, nil
+}
+
+// signature is the HMAC-SHA256 over the attachment ID and the link's expiry time
+func (s *AttachmentService) signature(id uint, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "attachment-download:%d:%s", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DetectContentType identifies the type of a file from its first bytes. Besides the types the standard
+// library knows, it recognises DICOM, which X-ray machines export, and TIFF.
+func DetectContentType(head []byte) string {
+	switch {
+	case len(head) >= 132 && string(head[128:132]) == "DICM":
+		return attachment.TypeDICOM
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return attachment.TypeTIFF
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return contentType
+}
+
+// cleanFileName keeps the base name of an uploaded file without control characters
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > maxFileNameLength {
+		name = string(runes[len(runes)-maxFileNameLength:])
+	}
+	return name
+}
@@ -0,0 +1,270 @@
+package attachmentService
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"dental-clinic-system/models/attachment"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAttachmentRepository keeps attachments in memory
+type fakeAttachmentRepository struct {
+	attachments map[uint]attachment.Attachment
+	createErr   error
+}
+
+func (r *fakeAttachmentRepository) GetAttachments(ctx context.Context, clinicID, patientID uint, category attachment.Category) ([]attachment.Attachment, error) {
+	var found []attachment.Attachment
+	for _, a := range r.attachments {
+		if a.ClinicID == clinicID && a.PatientID == patientID && (category == "" || a.Category == category) {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeAttachmentRepository) GetAttachment(ctx context.Context, id uint) (attachment.Attachment, error) {
+	a, ok := r.attachments[id]
+	if !ok {
+		return attachment.Attachment{}, attachment.ErrAttachmentNotFound
+	}
+	return a, nil
+}
+
+func (r *fakeAttachmentRepository) CreateAttachment(ctx context.Context, a attachment.Attachment) (attachment.Attachment, error) {
+	if r.createErr != nil {
+		return attachment.Attachment{}, r.createErr
+	}
+	a.ID = uint(len(r.attachments) + 1)
+	r.attachments[a.ID] = a
+	return a, nil
+}
+
+func (r *fakeAttachmentRepository) DeleteAttachment(ctx context.Context, id uint) error {
+	if _, ok := r.attachments[id]; !ok {
+		return attachment.ErrAttachmentNotFound
+	}
+	delete(r.attachments, id)
+	return nil
+}
+
+// fakeStorage keeps stored files in memory and checks the checksum it is given, as S3 does
+type fakeStorage struct {
+	objects map[string][]byte
+}
+
+func (s *fakeStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType, checksum string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != size || hex.EncodeToString(sum[:]) != checksum {
+		return errors.New("stored content does not match its size and checksum")
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *fakeStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, attachment.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *fakeStorage) Delete(ctx context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+var (
+	pngFile = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 64)...)
+	pdfFile = []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\n")
+)
+
+func dicomFile() []byte {
+	data := make([]byte, 200)
+	copy(data[128:], "DICM")
+	return data
+}
+
+func newTestService(maxBytes int64) (*AttachmentService, *fakeAttachmentRepository, *fakeStorage) {
+	repo := &fakeAttachmentRepository{attachments: map[uint]attachment.Attachment{}}
+	store := &fakeStorage{objects: map[string][]byte{}}
+	service := NewAttachmentService(repo, store, "test-signing-key", maxBytes)
+	service.now = func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) }
+	return service, repo, store
+}
+
+func testUpload(category attachment.Category, fileName string) attachment.Upload {
+	return attachment.Upload{ClinicID: 3, PatientID: 7, UploadedByID: 11, Category: category, FileName: fileName}
+}
+
+func TestUploadStoresFileWithDetectedTypeAndChecksum(t *testing.T) {
+	service, _, store := newTestService(0)
+
+	created, err := service.Upload(context.Background(), testUpload(attachment.CategoryIntraoralPhoto, `C:\photos\upper arch.png`), bytes.NewReader(pngFile))
+	if err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+
+	sum := sha256.Sum256(pngFile)
+	if created.ContentType != attachment.TypePNG || created.SHA256 != hex.EncodeToString(sum[:]) || created.SizeBytes != int64(len(pngFile)) {
+		t.Errorf("got type %q, checksum %q, size %d", created.ContentType, created.SHA256, created.SizeBytes)
+	}
+	if created.FileName != "upper arch.png" {
+		t.Errorf("file name = %q, want the base name", created.FileName)
+	}
+	if !strings.HasPrefix(created.StorageKey, "clinics/3/patients/7/") {
+		t.Errorf("storage key %q is not scoped to the clinic and patient", created.StorageKey)
+	}
+	if !bytes.Equal(store.objects[created.StorageKey], pngFile) {
+		t.Error("stored file differs from the upload")
+	}
+}
+
+func TestUploadDetectsDicomXRays(t *testing.T) {
+	service, _, _ := newTestService(0)
+
+	created, err := service.Upload(context.Background(), testUpload(attachment.CategoryXRay, "pano.dcm"), bytes.NewReader(dicomFile()))
+	if err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+	if created.ContentType != attachment.TypeDICOM {
+		t.Errorf("content type = %q, want %q", created.ContentType, attachment.TypeDICOM)
+	}
+}
+
+func TestUploadRejectsTypeNotAcceptedByCategory(t *testing.T) {
+	service, repo, store := newTestService(0)
+
+	_, err := service.Upload(context.Background(), testUpload(attachment.CategoryIntraoralPhoto, "photo.jpg"), bytes.NewReader(pdfFile))
+	if !errors.Is(err, attachment.ErrUnsupportedType) {
+		t.Fatalf("err = %v, want ErrUnsupportedType", err)
+	}
+	if len(store.objects) != 0 || len(repo.attachments) != 0 {
+		t.Error("rejected file was stored")
+	}
+}
+
+func TestUploadRejectsFilesOverTheLimit(t *testing.T) {
+	service, _, store := newTestService(32)
+
+	_, err := service.Upload(context.Background(), testUpload(attachment.CategoryIntraoralPhoto, "photo.png"), bytes.NewReader(pngFile))
+	if !errors.Is(err, attachment.ErrFileTooLarge) {
+		t.Fatalf("err = %v, want ErrFileTooLarge", err)
+	}
+	if len(store.objects) != 0 {
+		t.Error("oversized file was stored")
+	}
+}
+
+func TestUploadValidatesCategoryAndContent(t *testing.T) {
+	service, _, _ := newTestService(0)
+
+	tests := map[string]struct {
+		upload attachment.Upload
+		data   []byte
+	}{
+		"unknown category": {testUpload("selfie", "photo.png"), pngFile},
+		"missing name":     {testUpload(attachment.CategoryIntraoralPhoto, ""), pngFile},
+		"empty file":       {testUpload(attachment.CategoryIntraoralPhoto, "photo.png"), nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.Upload(context.Background(), tt.upload, bytes.NewReader(tt.data))
+			if !errors.Is(err, attachment.ErrAttachmentValidation) {
+				t.Errorf("err = %v, want ErrAttachmentValidation", err)
+			}
+		})
+	}
+}
+
+func TestUploadRemovesStoredFileWhenRecordFails(t *testing.T) {
+	service, repo, store := newTestService(0)
+	repo.createErr = errors.New("database is down")
+
+	if _, err := service.Upload(context.Background(), testUpload(attachment.CategoryConsent, "consent.pdf"), bytes.NewReader(pdfFile)); err == nil {
+		t.Fatal("Upload succeeded without a record")
+	}
+	if len(store.objects) != 0 {
+		t.Error("stored file of the failed upload was left behind")
+	}
+}
+
+func TestDownloadLinkOpensOnlyWhileValid(t *testing.T) {
+	service, _, _ := newTestService(0)
+	ctx := context.Background()
+
+	created, err := service.Upload(ctx, testUpload(attachment.CategoryConsent, "consent.pdf"), bytes.NewReader(pdfFile))
+	if err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+
+	link := service.DownloadLink(created)
+	if want := service.now().Add(DownloadLinkTTL); !link.ExpiresAt.Equal(want) {
+		t.Errorf("link expires at %v, want %v", link.ExpiresAt, want)
+	}
+	parsed, err := url.Parse(link.URL)
+	if err != nil {
+		t.Fatalf("invalid link %q: %v", link.URL, err)
+	}
+	if parsed.Path != "/attachments/"+strconv.Itoa(int(created.ID))+"/download" {
+		t.Errorf("link path = %q", parsed.Path)
+	}
+	expires, signature := parsed.Query().Get("expires"), parsed.Query().Get("signature")
+
+	a, file, err := service.OpenDownload(ctx, created.ID, expires, signature)
+	if err != nil {
+		t.Fatalf("OpenDownload returned error: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if a.ID != created.ID || !bytes.Equal(data, pdfFile) {
+		t.Error("download does not serve the uploaded file")
+	}
+
+	if _, _, err := service.OpenDownload(ctx, created.ID+1, expires, signature); !errors.Is(err, attachment.ErrInvalidSignature) {
+		t.Errorf("link for another attachment: err = %v, want ErrInvalidSignature", err)
+	}
+	later := strconv.FormatInt(link.ExpiresAt.Add(time.Hour).Unix(), 10)
+	if _, _, err := service.OpenDownload(ctx, created.ID, later, signature); !errors.Is(err, attachment.ErrInvalidSignature) {
+		t.Errorf("extended expiry: err = %v, want ErrInvalidSignature", err)
+	}
+
+	service.now = func() time.Time { return link.ExpiresAt.Add(time.Second) }
+	if _, _, err := service.OpenDownload(ctx, created.ID, expires, signature); !errors.Is(err, attachment.ErrInvalidSignature) {
+		t.Errorf("expired link: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := map[string]struct {
+		head []byte
+		want string
+	}{
+		"png":   {pngFile, attachment.TypePNG},
+		"jpeg":  {[]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), attachment.TypeJPEG},
+		"pdf":   {pdfFile, attachment.TypePDF},
+		"tiff":  {[]byte("II*\x00\x08\x00\x00\x00"), attachment.TypeTIFF},
+		"dicom": {dicomFile(), attachment.TypeDICOM},
+		"text":  {[]byte("hello"), "text/plain"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := DetectContentType(tt.head); got != tt.want {
+				t.Errorf("DetectContentType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
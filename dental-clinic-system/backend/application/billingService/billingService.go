@@ -0,0 +1,398 @@
+package billingService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/validations"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// BillingRepository defines the invoice and payment database operations
+type BillingRepository interface {
+	GetInvoices(ctx context.Context, clinicID, patientID uint, statuses []billing.InvoiceStatus) ([]billing.Invoice, error)
+	GetInvoice(ctx context.Context, id uint) (billing.Invoice, error)
+	CreateInvoice(ctx context.Context, invoice billing.Invoice, actorID uint, at time.Time) (billing.Invoice, error)
+	RecordPayment(ctx context.Context, invoiceID uint, payment billing.Payment) (billing.Invoice, error)
+	VoidInvoice(ctx context.Context, invoiceID, actorID uint, at time.Time, reason string) (billing.Invoice, error)
+	GetPatientBalances(ctx context.Context, clinicID, patientID uint) ([]billing.Balance, error)
+	GetAuditTrail(ctx context.Context, invoiceID uint) ([]billing.AuditEntry, error)
+	GetInstallmentPlan(ctx context.Context, invoiceID uint) (billing.InstallmentPlan, error)
+	CreateInstallmentPlan(ctx context.Context, plan billing.InstallmentPlan, actorID uint, at time.Time) (billing.InstallmentPlan, error)
+	DeleteInstallmentPlan(ctx context.Context, invoiceID, actorID uint, at time.Time) error
+	GetInstallmentClinics(ctx context.Context) ([]clinic.Clinic, error)
+	MarkOverdueInstallments(ctx context.Context, clinicID uint, today string) (int64, error)
+	GetOverdueInstallments(ctx context.Context, clinicID uint, before string) ([]billing.OverdueInstallment, error)
+	ClaimInstallmentReminder(ctx context.Context, installmentID uint, previous, today string) (bool, error)
+	ReleaseInstallmentReminder(ctx context.Context, installmentID uint, previous, today string) error
+}
+
+// AppointmentRepository is used to look up the appointments being invoiced
+type AppointmentRepository interface {
+	GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error)
+}
+
+// TreatmentPlanRepository is used to look up the treatment plan items being invoiced
+type TreatmentPlanRepository interface {
+	GetPlanByItem(ctx context.Context, itemID uint) (treatment.Plan, error)
+}
+
+// PriceService prices invoice lines from the procedure catalogue
+type PriceService interface {
+	GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error)
+	GetPriceAt(ctx context.Context, procedureID uint, date string) (procedure.Price, error)
+}
+
+// ClinicRepository is used to resolve the time zone of a clinic
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// LineRequest describes one invoice line. A line is generated from a completed appointment,
+// a completed treatment plan item or a catalogue procedure; without any of them it is a
+// manual line that needs a description, unit price and VAT rate.
+type LineRequest struct {
+	AppointmentID   *uint  `json:"appointment_id"`
+	PlanItemID      *uint  `json:"plan_item_id"`
+	ProcedureID     *uint  `json:"procedure_id"`
+	Description     string `json:"description"`
+	Quantity        int    `json:"quantity"`
+	UnitPrice       int64  `json:"unit_price"`
+	VATRate         int    `json:"vat_rate"`
+	DiscountPercent int    `json:"discount_percent"`
+	DiscountAmount  int64  `json:"discount_amount"`
+}
+
+// InvoiceRequest describes an invoice to issue to a patient
+type InvoiceRequest struct {
+	PatientID uint          `json:"patient_id"`
+	Notes     string        `json:"notes"`
+	Lines     []LineRequest `json:"lines"`
+}
+
+// PaymentRequest describes money received for an invoice or paid back to the patient
+type PaymentRequest struct {
+	Method     billing.PaymentMethod `json:"method"`
+	Amount     int64                 `json:"amount"`
+	Reference  string                `json:"reference"`
+	Notes      string                `json:"notes"`
+	ReceivedAt *time.Time            `json:"received_at"`
+}
+
+// BillingService issues invoices, records payments and refunds and follows up installments
+type BillingService struct {
+	billingRepository     BillingRepository
+	appointmentRepository AppointmentRepository
+	planRepository        TreatmentPlanRepository
+	priceService          PriceService
+	clinicRepository      ClinicRepository
+	producer              InstallmentProducer
+	now                   func() time.Time
+}
+
+// NewBillingService creates a new instance of BillingService
+func NewBillingService(billingRepo BillingRepository, appointmentRepo AppointmentRepository, planRepo TreatmentPlanRepository, priceService PriceService, clinicRepo ClinicRepository, producer InstallmentProducer) *BillingService {
+	return &BillingService{
+		billingRepository:     billingRepo,
+		appointmentRepository: appointmentRepo,
+		planRepository:        planRepo,
+		priceService:          priceService,
+		clinicRepository:      clinicRepo,
+		producer:              producer,
+		now:                   time.Now,
+	}
+}
+
+// GetInvoices retrieves the invoices of a clinic, optionally for one patient or status
+func (s *BillingService) GetInvoices(ctx context.Context, clinicID, patientID uint, status billing.InvoiceStatus) ([]billing.Invoice, error) {
+	var statuses []billing.InvoiceStatus
+	if status != "" {
+		statuses = []billing.InvoiceStatus{status}
+	}
+	return s.resolveAll(s.billingRepository.GetInvoices(ctx, clinicID, patientID, statuses))
+}
+
+// GetUnpaidInvoices retrieves the invoices of a clinic that still have a balance
+func (s *BillingService) GetUnpaidInvoices(ctx context.Context, clinicID, patientID uint) ([]billing.Invoice, error) {
+	return s.resolveAll(s.billingRepository.GetInvoices(ctx, clinicID, patientID, billing.UnpaidStatuses))
+}
+
+// GetInvoice retrieves an invoice with its lines, payments and balance
+func (s *BillingService) GetInvoice(ctx context.Context, id uint) (billing.Invoice, error) {
+	invoice, err := s.billingRepository.GetInvoice(ctx, id)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	invoice.Resolve()
+	return invoice, nil
+}
+
+// GetPatientBalances returns what a patient has been invoiced and still owes, per currency
+func (s *BillingService) GetPatientBalances(ctx context.Context, clinicID, patientID uint) ([]billing.Balance, error) {
+	return s.billingRepository.GetPatientBalances(ctx, clinicID, patientID)
+}
+
+// GetAuditTrail retrieves every recorded change of an invoice
+func (s *BillingService) GetAuditTrail(ctx context.Context, invoiceID uint) ([]billing.AuditEntry, error) {
+	return s.billingRepository.GetAuditTrail(ctx, invoiceID)
+}
+
+// CreateInvoice prices the requested lines and issues an invoice dated today in the clinic's time zone.
+// Catalogue prices are those in effect on the day the treatment was carried out.
+func (s *BillingService) CreateInvoice(ctx context.Context, clinicID, issuedByID uint, req InvoiceRequest) (billing.Invoice, error) {
+	cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	loc := cln.Location()
+	now := s.now()
+	today := now.In(loc).Format(billing.DateLayout)
+
+	invoice := billing.Invoice{
+		ClinicID:   clinicID,
+		PatientID:  req.PatientID,
+		IssueDate:  today,
+		Status:     billing.InvoiceIssued,
+		Notes:      req.Notes,
+		IssuedByID: issuedByID,
+	}
+	billedAppointments, billedItems := map[uint]bool{}, map[uint]bool{}
+	for i, lineReq := range req.Lines {
+		line, currency, err := s.buildLine(ctx, clinicID, req.PatientID, loc, today, lineReq)
+		if err != nil {
+			log.Warn().
+				Str("operation", "CreateInvoice").
+				Err(err).
+				Uint("clinic_id", clinicID).
+				Int("line", i+1).
+				Msg("Invoice line could not be priced")
+			return billing.Invoice{}, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if (line.AppointmentID != nil && billedAppointments[*line.AppointmentID]) || (line.PlanItemID != nil && billedItems[*line.PlanItemID]) {
+			return billing.Invoice{}, fmt.Errorf("%w: line %d bills an appointment or plan item twice", billing.ErrInvoiceValidation, i+1)
+		}
+		if line.AppointmentID != nil {
+			billedAppointments[*line.AppointmentID] = true
+		}
+		if line.PlanItemID != nil {
+			billedItems[*line.PlanItemID] = true
+		}
+		if currency != "" {
+			if invoice.Currency != "" && invoice.Currency != currency {
+				return billing.Invoice{}, fmt.Errorf("line %d: %w", i+1, billing.ErrCurrencyMismatch)
+			}
+			invoice.Currency = currency
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	if err := validations.InvoiceValidation(&invoice); err != nil {
+		log.Warn().
+			Str("operation", "CreateInvoice").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Invoice validation failed")
+		return billing.Invoice{}, fmt.Errorf("%w: %s", billing.ErrInvoiceValidation, err.Error())
+	}
+	invoice.Calculate()
+
+	created, err := s.billingRepository.CreateInvoice(ctx, invoice, issuedByID, now)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	created.Resolve()
+	return created, nil
+}
+
+// RecordPayment books money received for an invoice
+func (s *BillingService) RecordPayment(ctx context.Context, invoiceID, recordedByID uint, req PaymentRequest) (billing.Invoice, error) {
+	return s.record(ctx, invoiceID, recordedByID, billing.KindPayment, req)
+}
+
+// RecordRefund books money paid back to the patient; a reason is required in the notes
+func (s *BillingService) RecordRefund(ctx context.Context, invoiceID, recordedByID uint, req PaymentRequest) (billing.Invoice, error) {
+	if strings.TrimSpace(req.Notes) == "" {
+		return billing.Invoice{}, fmt.Errorf("%w: notes must give the reason for the refund", billing.ErrPaymentValidation)
+	}
+	return s.record(ctx, invoiceID, recordedByID, billing.KindRefund, req)
+}
+
+// VoidInvoice cancels an invoice that has no payments
+func (s *BillingService) VoidInvoice(ctx context.Context, invoiceID, actorID uint, reason string) (billing.Invoice, error) {
+	if strings.TrimSpace(reason) == "" {
+		return billing.Invoice{}, fmt.Errorf("%w: a reason is required to void an invoice", billing.ErrInvoiceValidation)
+	}
+
+	invoice, err := s.billingRepository.VoidInvoice(ctx, invoiceID, actorID, s.now(), reason)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	invoice.Resolve()
+	return invoice, nil
+}
+
+func (s *BillingService) record(ctx context.Context, invoiceID, recordedByID uint, kind billing.PaymentKind, req PaymentRequest) (billing.Invoice, error) {
+	payment := billing.Payment{
+		Kind:         kind,
+		Method:       req.Method,
+		Amount:       req.Amount,
+		Reference:    req.Reference,
+		Notes:        req.Notes,
+		ReceivedAt:   s.now(),
+		RecordedByID: recordedByID,
+	}
+	if req.ReceivedAt != nil {
+		payment.ReceivedAt = *req.ReceivedAt
+	}
+
+	if err := validations.PaymentValidation(&payment); err != nil {
+		log.Warn().
+			Str("operation", "RecordPayment").
+			Err(err).
+			Uint("invoice_id", invoiceID).
+			Msg("Payment validation failed")
+		return billing.Invoice{}, fmt.Errorf("%w: %s", billing.ErrPaymentValidation, err.Error())
+	}
+
+	invoice, err := s.billingRepository.RecordPayment(ctx, invoiceID, payment)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	invoice.Resolve()
+	return invoice, nil
+}
+
+// buildLine turns a line request into a priced invoice line and returns the currency of its catalogue price
+func (s *BillingService) buildLine(ctx context.Context, clinicID, patientID uint, loc *time.Location, today string, req LineRequest) (billing.Line, string, error) {
+	sources := 0
+	for _, id := range []*uint{req.AppointmentID, req.PlanItemID, req.ProcedureID} {
+		if id != nil {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return billing.Line{}, "", fmt.Errorf("%w: give only one of appointment_id, plan_item_id or procedure_id", billing.ErrInvoiceValidation)
+	}
+
+	line := billing.Line{
+		Description:     strings.TrimSpace(req.Description),
+		Quantity:        req.Quantity,
+		DiscountPercent: req.DiscountPercent,
+		DiscountAmount:  req.DiscountAmount,
+		ServiceDate:     today,
+	}
+
+	var procedureID uint
+	var description string
+	switch {
+	case req.AppointmentID != nil:
+		appt, err := s.appointmentRepository.GetAppointment(ctx, *req.AppointmentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && appt.ClinicID != clinicID) {
+			return billing.Line{}, "", billing.ErrBillingSourceNotFound
+		}
+		if err != nil {
+			return billing.Line{}, "", err
+		}
+		if appt.PatientID != patientID {
+			return billing.Line{}, "", fmt.Errorf("%w: appointment %d belongs to another patient", billing.ErrInvoiceValidation, appt.ID)
+		}
+		if appt.Status != appointment.StatusCompleted {
+			return billing.Line{}, "", billing.ErrSourceNotBillable
+		}
+		if appt.ProcedureID == nil {
+			return billing.Line{}, "", fmt.Errorf("%w: appointment %d has no procedure to price", billing.ErrInvoiceValidation, appt.ID)
+		}
+		line.AppointmentID = &appt.ID
+		line.ServiceDate = appt.ScheduledTime.In(loc).Format(billing.DateLayout)
+		procedureID = *appt.ProcedureID
+		description = appt.Treatment
+
+	case req.PlanItemID != nil:
+		plan, err := s.planRepository.GetPlanByItem(ctx, *req.PlanItemID)
+		if errors.Is(err, treatment.ErrItemNotFound) || (err == nil && plan.ClinicID != clinicID) {
+			return billing.Line{}, "", billing.ErrBillingSourceNotFound
+		}
+		if err != nil {
+			return billing.Line{}, "", err
+		}
+		if plan.PatientID != patientID {
+			return billing.Line{}, "", fmt.Errorf("%w: treatment plan item %d belongs to another patient", billing.ErrInvoiceValidation, *req.PlanItemID)
+		}
+		plan.Resolve()
+		item, _ := plan.FindItem(*req.PlanItemID)
+		if item.Status != treatment.ItemCompleted {
+			return billing.Line{}, "", billing.ErrSourceNotBillable
+		}
+		line.PlanItemID = &item.ID
+		line.AppointmentID = item.AppointmentID
+		line.ServiceDate = item.Appointment.ScheduledTime.In(loc).Format(billing.DateLayout)
+		procedureID = item.ProcedureID
+		description = planItemDescription(item)
+
+	case req.ProcedureID != nil:
+		proc, err := s.priceService.GetProcedure(ctx, *req.ProcedureID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && proc.ClinicID != clinicID) {
+			return billing.Line{}, "", procedure.ErrProcedureNotFound
+		}
+		if err != nil {
+			return billing.Line{}, "", err
+		}
+		procedureID = proc.ID
+
+	default:
+		line.UnitPrice = req.UnitPrice
+		line.VATRate = req.VATRate
+		return line, "", nil
+	}
+
+	price, err := s.priceService.GetPriceAt(ctx, procedureID, line.ServiceDate)
+	if err != nil {
+		return billing.Line{}, "", err
+	}
+	line.ProcedureID = &price.ProcedureID
+	line.PriceListID = &price.PriceListID
+	line.Code = price.Code
+	line.UnitPrice = price.NetPrice
+	line.VATRate = price.VATRate
+	if line.Description == "" {
+		line.Description = description
+	}
+	if line.Description == "" {
+		line.Description = price.Name
+	}
+	return line, price.Currency, nil
+}
+
+func (s *BillingService) resolveAll(invoices []billing.Invoice, err error) ([]billing.Invoice, error) {
+	if err != nil {
+		return nil, err
+	}
+	for i := range invoices {
+		invoices[i].Resolve()
+	}
+	return invoices, nil
+}
+
+// planItemDescription names the procedure of a plan item with its tooth and surfaces
+func planItemDescription(item treatment.Item) string {
+	parts := []string{item.Procedure.Name}
+	if item.Tooth != nil {
+		parts = append(parts, fmt.Sprintf("%d", *item.Tooth))
+	}
+	if item.Surfaces != "" {
+		parts = append(parts, item.Surfaces)
+	}
+	if item.Description != "" {
+		parts = append(parts, "- "+item.Description)
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
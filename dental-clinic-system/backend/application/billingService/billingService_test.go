@@ -0,0 +1,391 @@
+package billingService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/treatment"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeBillingRepository keeps invoices in memory and applies payments with the model's rules
+type fakeBillingRepository struct {
+	invoices map[uint]billing.Invoice
+	payments []billing.Payment
+	plans    map[uint]billing.InstallmentPlan
+	overdue  []billing.OverdueInstallment
+	claimed  map[uint]string
+}
+
+func (r *fakeBillingRepository) GetInvoices(ctx context.Context, clinicID, patientID uint, statuses []billing.InvoiceStatus) ([]billing.Invoice, error) {
+	var invoices []billing.Invoice
+	for _, invoice := range r.invoices {
+		invoices = append(invoices, invoice)
+	}
+	return invoices, nil
+}
+
+func (r *fakeBillingRepository) GetInvoice(ctx context.Context, id uint) (billing.Invoice, error) {
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return billing.Invoice{}, billing.ErrInvoiceNotFound
+	}
+	return invoice, nil
+}
+
+func (r *fakeBillingRepository) CreateInvoice(ctx context.Context, invoice billing.Invoice, actorID uint, at time.Time) (billing.Invoice, error) {
+	if r.invoices == nil {
+		r.invoices = map[uint]billing.Invoice{}
+	}
+	invoice.ID = uint(len(r.invoices) + 1)
+	invoice.Number = fmt.Sprintf("%s-%06d", invoice.IssueDate[:4], invoice.ID)
+	r.invoices[invoice.ID] = invoice
+	return invoice, nil
+}
+
+func (r *fakeBillingRepository) RecordPayment(ctx context.Context, invoiceID uint, payment billing.Payment) (billing.Invoice, error) {
+	invoice, err := r.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	if err := invoice.Apply(payment.Kind, payment.Amount); err != nil {
+		return billing.Invoice{}, err
+	}
+	r.payments = append(r.payments, payment)
+	r.invoices[invoiceID] = invoice
+	if plan, ok := r.plans[invoiceID]; ok {
+		plan.Allocate(invoice.PatientPaid())
+		r.plans[invoiceID] = plan
+	}
+	return invoice, nil
+}
+
+func (r *fakeBillingRepository) VoidInvoice(ctx context.Context, invoiceID, actorID uint, at time.Time, reason string) (billing.Invoice, error) {
+	invoice, err := r.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return billing.Invoice{}, err
+	}
+	if invoice.PaidAmount != 0 {
+		return billing.Invoice{}, billing.ErrInvoiceHasPayments
+	}
+	invoice.Status = billing.InvoiceVoid
+	invoice.VoidReason = reason
+	r.invoices[invoiceID] = invoice
+	return invoice, nil
+}
+
+func (r *fakeBillingRepository) GetPatientBalances(ctx context.Context, clinicID, patientID uint) ([]billing.Balance, error) {
+	return nil, nil
+}
+
+func (r *fakeBillingRepository) GetAuditTrail(ctx context.Context, invoiceID uint) ([]billing.AuditEntry, error) {
+	return nil, nil
+}
+
+type fakeAppointmentRepository struct {
+	appointments map[uint]appointment.Appointment
+}
+
+func (r *fakeAppointmentRepository) GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error) {
+	appt, ok := r.appointments[id]
+	if !ok {
+		return appointment.Appointment{}, gorm.ErrRecordNotFound
+	}
+	return appt, nil
+}
+
+type fakePlanRepository struct {
+	plan treatment.Plan
+}
+
+func (r *fakePlanRepository) GetPlanByItem(ctx context.Context, itemID uint) (treatment.Plan, error) {
+	if _, ok := r.plan.FindItem(itemID); !ok {
+		return treatment.Plan{}, treatment.ErrItemNotFound
+	}
+	return r.plan, nil
+}
+
+// fakePriceService prices every procedure from a single price list, in TRY unless currencies says otherwise
+type fakePriceService struct {
+	procedures map[uint]procedure.Procedure
+	netPrices  map[uint]int64
+	currencies map[uint]string
+}
+
+func (s *fakePriceService) GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error) {
+	proc, ok := s.procedures[id]
+	if !ok {
+		return procedure.Procedure{}, gorm.ErrRecordNotFound
+	}
+	return proc, nil
+}
+
+func (s *fakePriceService) GetPriceAt(ctx context.Context, procedureID uint, date string) (procedure.Price, error) {
+	proc, ok := s.procedures[procedureID]
+	if !ok {
+		return procedure.Price{}, procedure.ErrPriceNotFound
+	}
+	currency := s.currencies[procedureID]
+	if currency == "" {
+		currency = procedure.DefaultCurrency
+	}
+	return procedure.Price{
+		ProcedureID: proc.ID,
+		Code:        proc.Code,
+		Name:        proc.Name,
+		Date:        date,
+		PriceListID: 1,
+		Currency:    currency,
+		NetPrice:    s.netPrices[procedureID],
+		VATRate:     proc.VATRate,
+	}, nil
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Timezone: "Europe/Istanbul"}, nil
+}
+
+func uintPtr(v uint) *uint { return &v }
+
+func newTestBillingService() (*BillingService, *fakeBillingRepository, *fakePriceService) {
+	procedureID := uint(7)
+	completed := appointment.Appointment{
+		ClinicID:      1,
+		PatientID:     10,
+		Status:        appointment.StatusCompleted,
+		ScheduledTime: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		ProcedureID:   &procedureID,
+		Treatment:     "Kompozit dolgu",
+	}
+	completed.ID = 100
+	booked := completed
+	booked.ID = 101
+	booked.Status = appointment.StatusBooked
+	otherPatient := completed
+	otherPatient.ID = 102
+	otherPatient.PatientID = 11
+	// Patient record 2 shares its ID with the user issuing the invoices in these tests
+	userNumbered := completed
+	userNumbered.ID = 104
+	userNumbered.PatientID = 2
+
+	itemAppt := completed
+	itemAppt.ID = 103
+	tooth := 36
+	plan := treatment.Plan{ClinicID: 1, PatientID: 10, Phases: []treatment.Phase{{Items: []treatment.Item{
+		{ProcedureID: 8, Procedure: procedure.Procedure{Name: "Kanal tedavisi"}, Tooth: &tooth, AppointmentID: &itemAppt.ID, Appointment: &itemAppt},
+		{ProcedureID: 8, Procedure: procedure.Procedure{Name: "Kanal tedavisi"}},
+	}}}}
+	plan.Phases[0].Items[0].ID = 50
+	plan.Phases[0].Items[1].ID = 51
+
+	dolgu := procedure.Procedure{ClinicID: 1, Code: "D-100", Name: "Dolgu", VATRate: 10}
+	dolgu.ID = 7
+	kanal := procedure.Procedure{ClinicID: 1, Code: "E-200", Name: "Kanal tedavisi", VATRate: 10}
+	kanal.ID = 8
+
+	repo := &fakeBillingRepository{}
+	prices := &fakePriceService{
+		procedures: map[uint]procedure.Procedure{7: dolgu, 8: kanal},
+		netPrices:  map[uint]int64{7: 150000, 8: 300000},
+	}
+	s := NewBillingService(
+		repo,
+		&fakeAppointmentRepository{appointments: map[uint]appointment.Appointment{100: completed, 101: booked, 102: otherPatient, 103: itemAppt, 104: userNumbered}},
+		&fakePlanRepository{plan: plan},
+		prices,
+		fakeClinicRepository{},
+		&fakeInstallmentProducer{},
+	)
+	s.now = func() time.Time { return time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC) }
+	return s, repo, prices
+}
+
+func TestCreateInvoice(t *testing.T) {
+	s, _, _ := newTestBillingService()
+	ctx := context.Background()
+
+	invoice, err := s.CreateInvoice(ctx, 1, 2, InvoiceRequest{
+		PatientID: 10,
+		Lines: []LineRequest{
+			{AppointmentID: uintPtr(100)},
+			{PlanItemID: uintPtr(50)},
+			{Description: "Diş taşı temizliği", UnitPrice: 50000, VATRate: 20, Quantity: 2, DiscountPercent: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+
+	if invoice.Number != "2026-000001" || invoice.IssueDate != "2026-03-05" || invoice.Currency != "TRY" {
+		t.Errorf("invoice header = %q %q %q", invoice.Number, invoice.IssueDate, invoice.Currency)
+	}
+	if len(invoice.Lines) != 3 {
+		t.Fatalf("len(Lines) = %d, want 3", len(invoice.Lines))
+	}
+
+	appt := invoice.Lines[0]
+	if appt.Code != "D-100" || appt.Description != "Kompozit dolgu" || appt.ServiceDate != "2026-03-02" || appt.Total != 165000 {
+		t.Errorf("appointment line = %+v", appt)
+	}
+	item := invoice.Lines[1]
+	if item.PlanItemID == nil || item.AppointmentID == nil || *item.AppointmentID != 103 || item.Description != "Kanal tedavisi 36" || item.Total != 330000 {
+		t.Errorf("plan item line = %+v", item)
+	}
+	manual := invoice.Lines[2]
+	if manual.DiscountAmount != 10000 || manual.NetAmount != 90000 || manual.VATAmount != 18000 || manual.Total != 108000 {
+		t.Errorf("manual line = %+v", manual)
+	}
+
+	if invoice.Subtotal != 550000 || invoice.DiscountTotal != 10000 || invoice.NetTotal != 540000 ||
+		invoice.VATTotal != 63000 || invoice.Total != 603000 || invoice.Balance != 603000 {
+		t.Errorf("totals = subtotal %d discount %d net %d vat %d total %d balance %d",
+			invoice.Subtotal, invoice.DiscountTotal, invoice.NetTotal, invoice.VATTotal, invoice.Total, invoice.Balance)
+	}
+}
+
+func TestCreateInvoiceMatchesPatientRecord(t *testing.T) {
+	s, _, _ := newTestBillingService()
+	ctx := context.Background()
+
+	// User 2 invoices patient record 10 for its completed appointment
+	invoice, err := s.CreateInvoice(ctx, 1, 2, InvoiceRequest{PatientID: 10, Lines: []LineRequest{{AppointmentID: uintPtr(100)}}})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	if invoice.PatientID != 10 || len(invoice.Lines) != 1 || *invoice.Lines[0].AppointmentID != 100 {
+		t.Errorf("invoice = patient %d, lines %+v", invoice.PatientID, invoice.Lines)
+	}
+
+	// The appointment of patient record 2 is not patient 10's, although user 2 issues the invoice
+	_, err = s.CreateInvoice(ctx, 1, 2, InvoiceRequest{PatientID: 10, Lines: []LineRequest{{AppointmentID: uintPtr(104)}}})
+	if !errors.Is(err, billing.ErrInvoiceValidation) {
+		t.Errorf("CreateInvoice() error = %v, want %v", err, billing.ErrInvoiceValidation)
+	}
+	if _, err := s.CreateInvoice(ctx, 1, 10, InvoiceRequest{PatientID: 2, Lines: []LineRequest{{AppointmentID: uintPtr(104)}}}); err != nil {
+		t.Errorf("CreateInvoice() for patient record 2 error = %v", err)
+	}
+}
+
+func TestCreateInvoiceRejectsLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		lines    []LineRequest
+		wantErr  error
+	}{
+		{name: "appointment not completed", lines: []LineRequest{{AppointmentID: uintPtr(101)}}, wantErr: billing.ErrSourceNotBillable},
+		{name: "appointment of another patient", lines: []LineRequest{{AppointmentID: uintPtr(102)}}, wantErr: billing.ErrInvoiceValidation},
+		{name: "unknown appointment", lines: []LineRequest{{AppointmentID: uintPtr(999)}}, wantErr: billing.ErrBillingSourceNotFound},
+		{name: "plan item not completed", lines: []LineRequest{{PlanItemID: uintPtr(51)}}, wantErr: billing.ErrSourceNotBillable},
+		{name: "billed twice in one invoice", lines: []LineRequest{{AppointmentID: uintPtr(103)}, {PlanItemID: uintPtr(50)}}, wantErr: billing.ErrInvoiceValidation},
+		{name: "two sources on one line", lines: []LineRequest{{AppointmentID: uintPtr(100), ProcedureID: uintPtr(7)}}, wantErr: billing.ErrInvoiceValidation},
+		{name: "manual line without description", lines: []LineRequest{{UnitPrice: 1000}}, wantErr: billing.ErrInvoiceValidation},
+		{name: "no lines", wantErr: billing.ErrInvoiceValidation},
+		{name: "currency mismatch", currency: "EUR", lines: []LineRequest{{ProcedureID: uintPtr(7)}, {ProcedureID: uintPtr(8)}}, wantErr: billing.ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, prices := newTestBillingService()
+			if tt.currency != "" {
+				prices.currencies = map[uint]string{8: tt.currency}
+			}
+
+			_, err := s.CreateInvoice(context.Background(), 1, 2, InvoiceRequest{PatientID: 10, Lines: tt.lines})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateInvoice() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPaymentsAndRefunds(t *testing.T) {
+	s, repo, _ := newTestBillingService()
+	ctx := context.Background()
+
+	invoice, err := s.CreateInvoice(ctx, 1, 2, InvoiceRequest{PatientID: 10, Lines: []LineRequest{{AppointmentID: uintPtr(100)}}})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+
+	steps := []struct {
+		name        string
+		refund      bool
+		req         PaymentRequest
+		wantErr     error
+		wantStatus  billing.InvoiceStatus
+		wantBalance int64
+	}{
+		{name: "unknown method", req: PaymentRequest{Method: "cheque", Amount: 1000}, wantErr: billing.ErrPaymentValidation},
+		{name: "zero amount", req: PaymentRequest{Method: billing.MethodCash}, wantErr: billing.ErrPaymentValidation},
+		{name: "partial payment", req: PaymentRequest{Method: billing.MethodCard, Amount: 65000}, wantStatus: billing.InvoicePartiallyPaid, wantBalance: 100000},
+		{name: "overpayment", req: PaymentRequest{Method: billing.MethodCash, Amount: 100001}, wantErr: billing.ErrOverpayment},
+		{name: "remaining balance", req: PaymentRequest{Method: billing.MethodBankTransfer, Amount: 100000, Reference: "EFT-1"}, wantStatus: billing.InvoicePaid, wantBalance: 0},
+		{name: "refund without reason", refund: true, req: PaymentRequest{Method: billing.MethodCash, Amount: 5000}, wantErr: billing.ErrPaymentValidation},
+		{name: "refund above paid", refund: true, req: PaymentRequest{Method: billing.MethodCash, Amount: 165001, Notes: "İptal"}, wantErr: billing.ErrRefundExceedsPaid},
+		{name: "partial refund", refund: true, req: PaymentRequest{Method: billing.MethodCash, Amount: 15000, Notes: "Fazla ödeme"}, wantStatus: billing.InvoicePartiallyPaid, wantBalance: 15000},
+	}
+
+	for _, step := range steps {
+		record := s.RecordPayment
+		if step.refund {
+			record = s.RecordRefund
+		}
+		updated, err := record(ctx, invoice.ID, 2, step.req)
+		if step.wantErr != nil {
+			if !errors.Is(err, step.wantErr) {
+				t.Fatalf("%s: error = %v, want %v", step.name, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		if updated.Status != step.wantStatus || updated.Balance != step.wantBalance {
+			t.Errorf("%s: status %s balance %d, want %s %d", step.name, updated.Status, updated.Balance, step.wantStatus, step.wantBalance)
+		}
+	}
+
+	if len(repo.payments) != 3 {
+		t.Errorf("recorded %d payments, want 3", len(repo.payments))
+	}
+	if _, err := s.VoidInvoice(ctx, invoice.ID, 2, "Hatalı fatura"); !errors.Is(err, billing.ErrInvoiceHasPayments) {
+		t.Errorf("VoidInvoice() with payments error = %v, want %v", err, billing.ErrInvoiceHasPayments)
+	}
+}
+
+func TestVoidInvoice(t *testing.T) {
+	s, _, _ := newTestBillingService()
+	ctx := context.Background()
+
+	invoice, err := s.CreateInvoice(ctx, 1, 2, InvoiceRequest{PatientID: 10, Lines: []LineRequest{{ProcedureID: uintPtr(7)}}})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+
+	if _, err := s.VoidInvoice(ctx, invoice.ID, 2, " "); !errors.Is(err, billing.ErrInvoiceValidation) {
+		t.Fatalf("VoidInvoice() without reason error = %v, want %v", err, billing.ErrInvoiceValidation)
+	}
+
+	voided, err := s.VoidInvoice(ctx, invoice.ID, 2, "Hasta vazgeçti")
+	if err != nil {
+		t.Fatalf("VoidInvoice() error = %v", err)
+	}
+	if voided.Status != billing.InvoiceVoid || voided.Balance != 0 {
+		t.Errorf("voided invoice status %s balance %d", voided.Status, voided.Balance)
+	}
+
+	if _, err := s.RecordPayment(ctx, invoice.ID, 2, PaymentRequest{Method: billing.MethodCash, Amount: 1000}); !errors.Is(err, billing.ErrInvoiceVoid) {
+		t.Errorf("RecordPayment() on void invoice error = %v, want %v", err, billing.ErrInvoiceVoid)
+	}
+}
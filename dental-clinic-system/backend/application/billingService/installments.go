@@ -0,0 +1,287 @@
+package billingService
+
+import (
+	"context"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/validations"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReminderDateLayout formats due dates shown in installment reminder emails
+const ReminderDateLayout = "02.01.2006"
+
+// InstallmentProducer publishes overdue installment reminders to the email pipeline
+type InstallmentProducer interface {
+	SendInstallmentReminder(email string, data map[string]string) error
+}
+
+// InstallmentRequest is one due date and amount of an installment plan
+type InstallmentRequest struct {
+	DueDate string `json:"due_date"`
+	Amount  int64  `json:"amount"`
+}
+
+// InstallmentPlanRequest describes how the balance of an invoice is paid. Either the installments
+// are listed one by one, or the balance is split into Count monthly installments from FirstDueDate.
+type InstallmentPlanRequest struct {
+	Installments []InstallmentRequest `json:"installments"`
+	Count        int                  `json:"count"`
+	FirstDueDate string               `json:"first_due_date"`
+}
+
+// GetInstallmentPlan retrieves the installment plan of an invoice
+func (s *BillingService) GetInstallmentPlan(ctx context.Context, invoiceID uint) (billing.InstallmentPlan, error) {
+	plan, err := s.billingRepository.GetInstallmentPlan(ctx, invoiceID)
+	if err != nil {
+		return billing.InstallmentPlan{}, err
+	}
+	plan.Resolve()
+	return plan, nil
+}
+
+// CreateInstallmentPlan spreads the patient's outstanding balance of an invoice over dated installments
+func (s *BillingService) CreateInstallmentPlan(ctx context.Context, invoiceID, actorID uint, req InstallmentPlanRequest) (billing.InstallmentPlan, error) {
+	invoice, err := s.billingRepository.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return billing.InstallmentPlan{}, err
+	}
+	if invoice.Status == billing.InvoiceVoid {
+		return billing.InstallmentPlan{}, billing.ErrInvoiceVoid
+	}
+	invoice.Resolve()
+	balance := invoice.PatientBalance
+	if balance <= 0 {
+		return billing.InstallmentPlan{}, billing.ErrInvoiceSettled
+	}
+
+	cln, err := s.clinicRepository.GetClinic(ctx, invoice.ClinicID)
+	if err != nil {
+		return billing.InstallmentPlan{}, err
+	}
+	now := s.now()
+	today := now.In(cln.Location()).Format(billing.DateLayout)
+
+	plan := billing.InstallmentPlan{InvoiceID: invoice.ID, CreatedByID: actorID}
+	switch {
+	case len(req.Installments) > 0 && req.Count > 0:
+		return billing.InstallmentPlan{}, fmt.Errorf("%w: give either installments or count, not both", billing.ErrInstallmentValidation)
+	case req.Count > 0:
+		plan.Installments, err = billing.SplitInstallments(balance, req.Count, req.FirstDueDate)
+		if err != nil {
+			return billing.InstallmentPlan{}, fmt.Errorf("%w: %s", billing.ErrInstallmentValidation, err.Error())
+		}
+	default:
+		for _, inst := range req.Installments {
+			plan.Installments = append(plan.Installments, billing.Installment{DueDate: inst.DueDate, Amount: inst.Amount})
+		}
+	}
+
+	if err := validations.InstallmentPlanValidation(&plan, today); err != nil {
+		log.Warn().
+			Str("operation", "CreateInstallmentPlan").
+			Err(err).
+			Uint("invoice_id", invoiceID).
+			Msg("Installment plan validation failed")
+		return billing.InstallmentPlan{}, fmt.Errorf("%w: %s", billing.ErrInstallmentValidation, err.Error())
+	}
+	if plan.Total != balance {
+		return billing.InstallmentPlan{}, fmt.Errorf("%w: installments add up to %d but the invoice balance is %d", billing.ErrInstallmentValidation, plan.Total, balance)
+	}
+
+	created, err := s.billingRepository.CreateInstallmentPlan(ctx, plan, actorID, now)
+	if err != nil {
+		return billing.InstallmentPlan{}, err
+	}
+	created.Resolve()
+	return created, nil
+}
+
+// DeleteInstallmentPlan removes the installment plan of an invoice, e.g. to agree on a new one
+func (s *BillingService) DeleteInstallmentPlan(ctx context.Context, invoiceID, actorID uint) error {
+	return s.billingRepository.DeleteInstallmentPlan(ctx, invoiceID, actorID, s.now())
+}
+
+// ProcessOverdueInstallments flags the installments that were due before today in each clinic's time zone
+// and reminds the patients. Installments that stay overdue are reminded again every OverdueReminderIntervalDays.
+func (s *BillingService) ProcessOverdueInstallments(ctx context.Context) error {
+	clinics, err := s.billingRepository.GetInstallmentClinics(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	var marked int64
+	sent := 0
+	for _, cln := range clinics {
+		today := now.In(cln.Location()).Format(billing.DateLayout)
+
+		count, err := s.billingRepository.MarkOverdueInstallments(ctx, cln.ID, today)
+		if err != nil {
+			return err
+		}
+		marked += count
+
+		overdue, err := s.billingRepository.GetOverdueInstallments(ctx, cln.ID, today)
+		if err != nil {
+			return err
+		}
+		for _, inst := range overdue {
+			if !inst.ReminderDue(today) {
+				continue
+			}
+			ok, err := s.sendInstallmentReminder(ctx, inst, today)
+			if err != nil {
+				return err
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	log.Info().
+		Str("operation", "ProcessOverdueInstallments").
+		Int64("marked", marked).
+		Int("reminders", sent).
+		Msgf("Marked %d installments overdue and sent %d reminders", marked, sent)
+
+	return nil
+}
+
+// sendInstallmentReminder claims today's reminder before publishing it and releases the claim if publishing fails
+func (s *BillingService) sendInstallmentReminder(ctx context.Context, inst billing.OverdueInstallment, today string) (bool, error) {
+	if inst.PatientEmail == "" {
+		log.Warn().
+			Str("operation", "ProcessOverdueInstallments").
+			Uint("installment_id", inst.InstallmentID).
+			Msg("Patient has no email address, skipping installment reminder")
+		return false, nil
+	}
+
+	claimed, err := s.billingRepository.ClaimInstallmentReminder(ctx, inst.InstallmentID, inst.LastReminderDate, today)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := s.producer.SendInstallmentReminder(inst.PatientEmail, InstallmentReminderData(inst, today)); err != nil {
+		log.Error().
+			Str("operation", "ProcessOverdueInstallments").
+			Err(err).
+			Uint("installment_id", inst.InstallmentID).
+			Msg("Failed to publish installment reminder")
+		if releaseErr := s.billingRepository.ReleaseInstallmentReminder(ctx, inst.InstallmentID, inst.LastReminderDate, today); releaseErr != nil {
+			return false, releaseErr
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// InstallmentReminderData builds the template data of an overdue installment reminder email
+func InstallmentReminderData(inst billing.OverdueInstallment, today string) map[string]string {
+	dueDate := inst.DueDate
+	if due, err := time.Parse(billing.DateLayout, inst.DueDate); err == nil {
+		dueDate = due.Format(ReminderDateLayout)
+	}
+	return map[string]string{
+		"clinic_name":    inst.ClinicName,
+		"clinic_phone":   inst.ClinicPhone,
+		"patient_name":   inst.PatientName,
+		"invoice_number": inst.InvoiceNumber,
+		"installment":    fmt.Sprintf("%d/%d", inst.Sequence, inst.Installments),
+		"due_date":       dueDate,
+		"days_overdue":   fmt.Sprintf("%d", daysBetween(inst.DueDate, today)),
+		"amount_due":     billing.FormatAmount(inst.Balance(), inst.Currency),
+	}
+}
+
+// GetOverdueReport lists the clinic's installments that were due before asOf and are still unpaid,
+// grouped by patient and aged in 30 day buckets. An empty asOf means today in the clinic's time zone.
+func (s *BillingService) GetOverdueReport(ctx context.Context, clinicID uint, asOf string) (billing.OverdueReport, error) {
+	if asOf == "" {
+		cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+		if err != nil {
+			return billing.OverdueReport{}, err
+		}
+		asOf = s.now().In(cln.Location()).Format(billing.DateLayout)
+	} else if _, err := time.Parse(billing.DateLayout, asOf); err != nil {
+		return billing.OverdueReport{}, fmt.Errorf("%w: as_of must be in YYYY-MM-DD format", billing.ErrInstallmentValidation)
+	}
+
+	overdue, err := s.billingRepository.GetOverdueInstallments(ctx, clinicID, asOf)
+	if err != nil {
+		return billing.OverdueReport{}, err
+	}
+
+	report := billing.OverdueReport{ClinicID: clinicID, AsOf: asOf, Totals: []billing.OverdueTotal{}, Patients: []billing.OverduePatient{}}
+	type key struct {
+		patientID uint
+		currency  string
+	}
+	patients := map[key]*billing.OverduePatient{}
+	totals := map[string]*billing.OverdueTotal{}
+	var order []key
+	for _, inst := range overdue {
+		days := daysBetween(inst.DueDate, asOf)
+		amount := inst.Balance()
+
+		k := key{inst.PatientID, inst.Currency}
+		row, ok := patients[k]
+		if !ok {
+			row = &billing.OverduePatient{
+				PatientID:     inst.PatientID,
+				PatientName:   inst.PatientName,
+				PatientEmail:  inst.PatientEmail,
+				PatientPhone:  inst.PatientPhone,
+				Currency:      inst.Currency,
+				OldestDueDate: inst.DueDate,
+				DaysOverdue:   days,
+			}
+			patients[k] = row
+			order = append(order, k)
+		}
+		row.Installments++
+		row.Overdue += amount
+		row.Add(days, amount)
+
+		total, ok := totals[inst.Currency]
+		if !ok {
+			total = &billing.OverdueTotal{Currency: inst.Currency}
+			totals[inst.Currency] = total
+		}
+		if row.Installments == 1 {
+			total.Patients++
+		}
+		total.Installments++
+		total.Overdue += amount
+		total.Add(days, amount)
+	}
+
+	// Installments come oldest first, so patients are already ordered from most to least overdue
+	for _, k := range order {
+		report.Patients = append(report.Patients, *patients[k])
+	}
+	for _, total := range totals {
+		report.Totals = append(report.Totals, *total)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool {
+		return report.Totals[i].Currency < report.Totals[j].Currency
+	})
+
+	return report, nil
+}
+
+// daysBetween counts the days from one date to a later one
+func daysBetween(from, to string) int {
+	start, err1 := time.Parse(billing.DateLayout, from)
+	end, err2 := time.Parse(billing.DateLayout, to)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return int(end.Sub(start).Hours() / 24)
+}
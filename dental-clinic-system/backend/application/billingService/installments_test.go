@@ -0,0 +1,343 @@
+package billingService
+
+import (
+	"context"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func (r *fakeBillingRepository) GetInstallmentPlan(ctx context.Context, invoiceID uint) (billing.InstallmentPlan, error) {
+	plan, ok := r.plans[invoiceID]
+	if !ok {
+		return billing.InstallmentPlan{}, billing.ErrInstallmentPlanNotFound
+	}
+	return plan, nil
+}
+
+func (r *fakeBillingRepository) CreateInstallmentPlan(ctx context.Context, plan billing.InstallmentPlan, actorID uint, at time.Time) (billing.InstallmentPlan, error) {
+	if _, ok := r.plans[plan.InvoiceID]; ok {
+		return billing.InstallmentPlan{}, billing.ErrInstallmentPlanExists
+	}
+	if r.plans == nil {
+		r.plans = map[uint]billing.InstallmentPlan{}
+	}
+	invoice := r.invoices[plan.InvoiceID]
+	plan.DownPayment = invoice.PatientPaid()
+	plan.Currency = invoice.Currency
+	r.plans[plan.InvoiceID] = plan
+	return plan, nil
+}
+
+func (r *fakeBillingRepository) DeleteInstallmentPlan(ctx context.Context, invoiceID, actorID uint, at time.Time) error {
+	if _, ok := r.plans[invoiceID]; !ok {
+		return billing.ErrInstallmentPlanNotFound
+	}
+	delete(r.plans, invoiceID)
+	return nil
+}
+
+func (r *fakeBillingRepository) GetInstallmentClinics(ctx context.Context) ([]clinic.Clinic, error) {
+	cln := clinic.Clinic{Timezone: "Europe/Istanbul"}
+	cln.ID = 1
+	return []clinic.Clinic{cln}, nil
+}
+
+func (r *fakeBillingRepository) MarkOverdueInstallments(ctx context.Context, clinicID uint, today string) (int64, error) {
+	var marked int64
+	for i := range r.overdue {
+		if r.overdue[i].Status == billing.InstallmentPending && r.overdue[i].DueDate < today {
+			r.overdue[i].Status = billing.InstallmentOverdue
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (r *fakeBillingRepository) GetOverdueInstallments(ctx context.Context, clinicID uint, before string) ([]billing.OverdueInstallment, error) {
+	var overdue []billing.OverdueInstallment
+	for _, inst := range r.overdue {
+		if inst.DueDate < before {
+			if date, ok := r.claimed[inst.InstallmentID]; ok {
+				inst.LastReminderDate = date
+			}
+			overdue = append(overdue, inst)
+		}
+	}
+	return overdue, nil
+}
+
+func (r *fakeBillingRepository) ClaimInstallmentReminder(ctx context.Context, installmentID uint, previous, today string) (bool, error) {
+	if r.claimed == nil {
+		r.claimed = map[uint]string{}
+	}
+	if r.claimed[installmentID] != previous {
+		return false, nil
+	}
+	r.claimed[installmentID] = today
+	return true, nil
+}
+
+func (r *fakeBillingRepository) ReleaseInstallmentReminder(ctx context.Context, installmentID uint, previous, today string) error {
+	r.claimed[installmentID] = previous
+	return nil
+}
+
+type fakeInstallmentProducer struct {
+	sent []map[string]string
+	fail bool
+}
+
+func (p *fakeInstallmentProducer) SendInstallmentReminder(email string, data map[string]string) error {
+	if p.fail {
+		return errors.New("broker unavailable")
+	}
+	p.sent = append(p.sent, data)
+	return nil
+}
+
+func TestCreateInstallmentPlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       InstallmentPlanRequest
+		wantErr   error
+		wantDates []string
+		wantSums  []int64
+	}{
+		{
+			name:      "split monthly keeps month ends",
+			req:       InstallmentPlanRequest{Count: 3, FirstDueDate: "2026-03-31"},
+			wantDates: []string{"2026-03-31", "2026-04-30", "2026-05-31"},
+			wantSums:  []int64{48334, 48333, 48333},
+		},
+		{
+			name:      "explicit installments",
+			req:       InstallmentPlanRequest{Installments: []InstallmentRequest{{DueDate: "2026-04-01", Amount: 100000}, {DueDate: "2026-06-01", Amount: 45000}}},
+			wantDates: []string{"2026-04-01", "2026-06-01"},
+			wantSums:  []int64{100000, 45000},
+		},
+		{
+			name:    "amounts do not add up to the balance",
+			req:     InstallmentPlanRequest{Installments: []InstallmentRequest{{DueDate: "2026-04-01", Amount: 100000}}},
+			wantErr: billing.ErrInstallmentValidation,
+		},
+		{
+			name:    "first installment in the past",
+			req:     InstallmentPlanRequest{Count: 2, FirstDueDate: "2026-03-01"},
+			wantErr: billing.ErrInstallmentValidation,
+		},
+		{
+			name:    "due dates out of order",
+			req:     InstallmentPlanRequest{Installments: []InstallmentRequest{{DueDate: "2026-05-01", Amount: 100000}, {DueDate: "2026-04-01", Amount: 45000}}},
+			wantErr: billing.ErrInstallmentValidation,
+		},
+		{
+			name:    "both list and count",
+			req:     InstallmentPlanRequest{Count: 2, FirstDueDate: "2026-04-01", Installments: []InstallmentRequest{{DueDate: "2026-04-01", Amount: 145000}}},
+			wantErr: billing.ErrInstallmentValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := newTestBillingService()
+			ctx := context.Background()
+			invoice, err := s.CreateInvoice(ctx, 1, 2, InvoiceRequest{PatientID: 10, Lines: []LineRequest{{AppointmentID: uintPtr(100)}}})
+			if err != nil {
+				t.Fatalf("CreateInvoice() error = %v", err)
+			}
+			// A down payment before the plan is agreed is not part of the installments
+			if _, err := s.RecordPayment(ctx, invoice.ID, 2, PaymentRequest{Method: billing.MethodCash, Amount: 20000}); err != nil {
+				t.Fatalf("RecordPayment() error = %v", err)
+			}
+
+			plan, err := s.CreateInstallmentPlan(ctx, invoice.ID, 2, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateInstallmentPlan() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateInstallmentPlan() error = %v", err)
+			}
+
+			var dates []string
+			var sums []int64
+			for i, inst := range plan.Installments {
+				if inst.Sequence != i+1 || inst.Status != billing.InstallmentPending {
+					t.Errorf("installment %d sequence %d status %s", i+1, inst.Sequence, inst.Status)
+				}
+				dates = append(dates, inst.DueDate)
+				sums = append(sums, inst.Amount)
+			}
+			if !reflect.DeepEqual(dates, tt.wantDates) || !reflect.DeepEqual(sums, tt.wantSums) {
+				t.Errorf("installments = %v %v, want %v %v", dates, sums, tt.wantDates, tt.wantSums)
+			}
+			if plan.DownPayment != 20000 || plan.Total != 145000 {
+				t.Errorf("plan down payment %d total %d", plan.DownPayment, plan.Total)
+			}
+		})
+	}
+}
+
+func TestInstallmentAllocation(t *testing.T) {
+	s, repo, _ := newTestBillingService()
+	ctx := context.Background()
+	invoice, err := s.CreateInvoice(ctx, 1, 2, InvoiceRequest{PatientID: 10, Lines: []LineRequest{{AppointmentID: uintPtr(100)}}})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	if _, err := s.CreateInstallmentPlan(ctx, invoice.ID, 2, InstallmentPlanRequest{Count: 3, FirstDueDate: "2026-04-01"}); err != nil {
+		t.Fatalf("CreateInstallmentPlan() error = %v", err)
+	}
+	if _, err := s.CreateInstallmentPlan(ctx, invoice.ID, 2, InstallmentPlanRequest{Count: 2, FirstDueDate: "2026-04-01"}); !errors.Is(err, billing.ErrInstallmentPlanExists) {
+		t.Errorf("second CreateInstallmentPlan() error = %v, want %v", err, billing.ErrInstallmentPlanExists)
+	}
+
+	steps := []struct {
+		refund     bool
+		amount     int64
+		wantPaid   []int64
+		wantStatus []billing.InstallmentStatus
+	}{
+		{amount: 70000, wantPaid: []int64{55000, 15000, 0}, wantStatus: []billing.InstallmentStatus{billing.InstallmentPaid, billing.InstallmentPending, billing.InstallmentPending}},
+		{amount: 40000, wantPaid: []int64{55000, 55000, 0}, wantStatus: []billing.InstallmentStatus{billing.InstallmentPaid, billing.InstallmentPaid, billing.InstallmentPending}},
+		{refund: true, amount: 10000, wantPaid: []int64{55000, 45000, 0}, wantStatus: []billing.InstallmentStatus{billing.InstallmentPaid, billing.InstallmentPending, billing.InstallmentPending}},
+	}
+	for i, step := range steps {
+		record := s.RecordPayment
+		req := PaymentRequest{Method: billing.MethodCard, Amount: step.amount}
+		if step.refund {
+			record = s.RecordRefund
+			req.Notes = "Yanlış tutar"
+		}
+		if _, err := record(ctx, invoice.ID, 2, req); err != nil {
+			t.Fatalf("step %d: error = %v", i+1, err)
+		}
+
+		plan := repo.plans[invoice.ID]
+		for j, inst := range plan.Installments {
+			if inst.PaidAmount != step.wantPaid[j] || inst.Status != step.wantStatus[j] {
+				t.Errorf("step %d installment %d: paid %d status %s, want %d %s", i+1, j+1, inst.PaidAmount, inst.Status, step.wantPaid[j], step.wantStatus[j])
+			}
+		}
+	}
+
+	if err := s.DeleteInstallmentPlan(ctx, invoice.ID, 2); err != nil {
+		t.Fatalf("DeleteInstallmentPlan() error = %v", err)
+	}
+	if _, err := s.GetInstallmentPlan(ctx, invoice.ID); !errors.Is(err, billing.ErrInstallmentPlanNotFound) {
+		t.Errorf("GetInstallmentPlan() after delete error = %v", err)
+	}
+}
+
+func TestProcessOverdueInstallments(t *testing.T) {
+	s, repo, _ := newTestBillingService()
+	producer := &fakeInstallmentProducer{}
+	s.producer = producer
+	repo.overdue = []billing.OverdueInstallment{
+		{InstallmentID: 1, PatientID: 10, Sequence: 1, Installments: 3, DueDate: "2026-02-01", Amount: 50000, PaidAmount: 20000, Status: billing.InstallmentOverdue,
+			InvoiceNumber: "2026-000001", Currency: "TRY", PatientName: "Ayşe Yılmaz", PatientEmail: "ayse@example.com", ClinicName: "Gülüş Diş"},
+		{InstallmentID: 2, PatientID: 10, Sequence: 2, Installments: 3, DueDate: "2026-03-04", Amount: 50000, Status: billing.InstallmentPending,
+			Currency: "TRY", PatientEmail: "ayse@example.com"},
+		{InstallmentID: 3, PatientID: 11, Sequence: 1, Installments: 1, DueDate: "2026-03-05", Amount: 80000, Status: billing.InstallmentPending,
+			Currency: "TRY", PatientEmail: "mehmet@example.com"},
+		{InstallmentID: 4, PatientID: 12, Sequence: 1, Installments: 1, DueDate: "2025-11-20", Amount: 30000, Status: billing.InstallmentOverdue,
+			Currency: "TRY"},
+	}
+	ctx := context.Background()
+
+	// 2026-03-05 15:00 in Istanbul
+	s.now = func() time.Time { return time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC) }
+	if err := s.ProcessOverdueInstallments(ctx); err != nil {
+		t.Fatalf("ProcessOverdueInstallments() error = %v", err)
+	}
+	if repo.overdue[1].Status != billing.InstallmentOverdue || repo.overdue[2].Status != billing.InstallmentPending {
+		t.Errorf("statuses after run = %s %s", repo.overdue[1].Status, repo.overdue[2].Status)
+	}
+	if len(producer.sent) != 2 {
+		t.Fatalf("sent %d reminders, want 2", len(producer.sent))
+	}
+	want := map[string]string{
+		"clinic_name":    "Gülüş Diş",
+		"clinic_phone":   "",
+		"patient_name":   "Ayşe Yılmaz",
+		"invoice_number": "2026-000001",
+		"installment":    "1/3",
+		"due_date":       "01.02.2026",
+		"days_overdue":   "32",
+		"amount_due":     "300,00 TRY",
+	}
+	if !reflect.DeepEqual(producer.sent[0], want) {
+		t.Errorf("reminder data = %v, want %v", producer.sent[0], want)
+	}
+
+	// The same day again and the next days send nothing new until the reminder interval has passed
+	if err := s.ProcessOverdueInstallments(ctx); err != nil {
+		t.Fatalf("second ProcessOverdueInstallments() error = %v", err)
+	}
+	s.now = func() time.Time { return time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC) }
+	if err := s.ProcessOverdueInstallments(ctx); err != nil {
+		t.Fatalf("ProcessOverdueInstallments() error = %v", err)
+	}
+	if len(producer.sent) != 3 {
+		t.Fatalf("sent %d reminders after 6 days, want 3", len(producer.sent))
+	}
+
+	// A failed publish is released and retried on the next run
+	producer.fail = true
+	s.now = func() time.Time { return time.Date(2026, 3, 12, 12, 0, 0, 0, time.UTC) }
+	if err := s.ProcessOverdueInstallments(ctx); err != nil {
+		t.Fatalf("ProcessOverdueInstallments() error = %v", err)
+	}
+	producer.fail = false
+	if err := s.ProcessOverdueInstallments(ctx); err != nil {
+		t.Fatalf("ProcessOverdueInstallments() error = %v", err)
+	}
+	if len(producer.sent) != 5 || repo.claimed[1] != "2026-03-12" {
+		t.Errorf("sent %d reminders, last reminder of installment 1 on %q", len(producer.sent), repo.claimed[1])
+	}
+}
+
+func TestGetOverdueReport(t *testing.T) {
+	s, repo, _ := newTestBillingService()
+	repo.overdue = []billing.OverdueInstallment{
+		{InstallmentID: 4, PatientID: 12, DueDate: "2025-11-20", Amount: 30000, Currency: "TRY", PatientName: "Can"},
+		{InstallmentID: 1, PatientID: 10, DueDate: "2026-02-01", Amount: 50000, PaidAmount: 20000, Currency: "TRY", PatientName: "Ayşe"},
+		{InstallmentID: 2, PatientID: 10, DueDate: "2026-03-04", Amount: 50000, Currency: "TRY", PatientName: "Ayşe"},
+		{InstallmentID: 5, PatientID: 10, DueDate: "2026-03-01", Amount: 10000, Currency: "EUR", PatientName: "Ayşe"},
+	}
+
+	if _, err := s.GetOverdueReport(context.Background(), 1, "05.03.2026"); !errors.Is(err, billing.ErrInstallmentValidation) {
+		t.Fatalf("GetOverdueReport() with a bad date error = %v", err)
+	}
+
+	report, err := s.GetOverdueReport(context.Background(), 1, "")
+	if err != nil {
+		t.Fatalf("GetOverdueReport() error = %v", err)
+	}
+	if report.AsOf != "2026-03-05" || len(report.Patients) != 3 {
+		t.Fatalf("report as of %s with %d rows", report.AsOf, len(report.Patients))
+	}
+
+	can, ayse := report.Patients[0], report.Patients[1]
+	if can.PatientID != 12 || can.DaysOverdue != 105 || can.Over90 != 30000 {
+		t.Errorf("first row = %+v", can)
+	}
+	if ayse.PatientID != 10 || ayse.Currency != "TRY" || ayse.Installments != 2 || ayse.Overdue != 80000 ||
+		ayse.OldestDueDate != "2026-02-01" || ayse.Days1To30 != 50000 || ayse.Days31To60 != 30000 {
+		t.Errorf("second row = %+v", ayse)
+	}
+
+	wantTotals := []billing.OverdueTotal{
+		{Currency: "EUR", Patients: 1, Installments: 1, Overdue: 10000, AgingBuckets: billing.AgingBuckets{Days1To30: 10000}},
+		{Currency: "TRY", Patients: 2, Installments: 3, Overdue: 110000, AgingBuckets: billing.AgingBuckets{Days1To30: 50000, Days31To60: 30000, Over90: 30000}},
+	}
+	if !reflect.DeepEqual(report.Totals, wantTotals) {
+		t.Errorf("totals = %+v, want %+v", report.Totals, wantTotals)
+	}
+}
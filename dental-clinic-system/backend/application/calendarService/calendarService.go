@@ -0,0 +1,137 @@
+package calendarService
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/calendar"
+	"dental-clinic-system/models/clinic"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// feedTokenBytes is the amount of random data in a feed token
+const feedTokenBytes = 32
+
+// CalendarRepository defines the feed token database operations
+type CalendarRepository interface {
+	CreateFeedToken(ctx context.Context, token calendar.FeedToken) (calendar.FeedToken, error)
+	GetFeedTokens(ctx context.Context, doctorID uint) ([]calendar.FeedToken, error)
+	GetFeedToken(ctx context.Context, id uint) (calendar.FeedToken, error)
+	GetActiveFeedTokenByHash(ctx context.Context, tokenHash string) (calendar.FeedToken, error)
+	TouchFeedToken(ctx context.Context, id uint, usedAt time.Time) error
+	RevokeFeedToken(ctx context.Context, id uint, revokedAt time.Time) error
+}
+
+// AppointmentRepository defines the appointment lookups needed for calendar feeds
+type AppointmentRepository interface {
+	GetDoctorAppointments(ctx context.Context, doctorID uint) ([]appointment.Appointment, error)
+}
+
+// ClinicRepository defines the clinic lookups needed for calendar feeds
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// CalendarService builds iCalendar feeds and manages the tokens that grant access to them
+type CalendarService struct {
+	calendarRepository    CalendarRepository
+	appointmentRepository AppointmentRepository
+	clinicRepository      ClinicRepository
+}
+
+// NewCalendarService creates a new instance of CalendarService
+func NewCalendarService(calendarRepo CalendarRepository, appointmentRepo AppointmentRepository, clinicRepo ClinicRepository) *CalendarService {
+	return &CalendarService{
+		calendarRepository:    calendarRepo,
+		appointmentRepository: appointmentRepo,
+		clinicRepository:      clinicRepo,
+	}
+}
+
+// CreateFeedToken issues a new feed token for a doctor and returns the stored token with its plain value.
+// The plain value is not stored and cannot be retrieved again.
+func (s *CalendarService) CreateFeedToken(ctx context.Context, clinicID, doctorID, createdByID uint) (calendar.FeedToken, string, error) {
+	raw := make([]byte, feedTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return calendar.FeedToken{}, "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(raw)
+
+	token, err := s.calendarRepository.CreateFeedToken(ctx, calendar.FeedToken{
+		ClinicID:    clinicID,
+		DoctorID:    doctorID,
+		TokenHash:   hashFeedToken(value),
+		CreatedByID: createdByID,
+	})
+	if err != nil {
+		return calendar.FeedToken{}, "", err
+	}
+
+	return token, value, nil
+}
+
+// GetFeedTokens retrieves the feed tokens of a doctor
+func (s *CalendarService) GetFeedTokens(ctx context.Context, doctorID uint) ([]calendar.FeedToken, error) {
+	return s.calendarRepository.GetFeedTokens(ctx, doctorID)
+}
+
+// GetFeedToken retrieves a feed token by its ID
+func (s *CalendarService) GetFeedToken(ctx context.Context, id uint) (calendar.FeedToken, error) {
+	return s.calendarRepository.GetFeedToken(ctx, id)
+}
+
+// RevokeFeedToken stops a feed token from working; other tokens and the user's credentials are unaffected
+func (s *CalendarService) RevokeFeedToken(ctx context.Context, id uint) error {
+	return s.calendarRepository.RevokeFeedToken(ctx, id, time.Now())
+}
+
+// GetDoctorFeed renders the calendar of the doctor the token belongs to.
+// It returns calendar.ErrFeedTokenNotFound for unknown or revoked tokens.
+func (s *CalendarService) GetDoctorFeed(ctx context.Context, tokenValue string) ([]byte, error) {
+	token, err := s.calendarRepository.GetActiveFeedTokenByHash(ctx, hashFeedToken(tokenValue))
+	if err != nil {
+		return nil, err
+	}
+
+	cln, err := s.clinicRepository.GetClinic(ctx, token.ClinicID)
+	if err != nil {
+		return nil, err
+	}
+
+	appointments, err := s.appointmentRepository.GetDoctorAppointments(ctx, token.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.calendarRepository.TouchFeedToken(ctx, token.ID, time.Now()); err != nil {
+		log.Warn().
+			Str("operation", "GetDoctorFeed").
+			Err(err).
+			Uint("token_id", token.ID).
+			Msg("Failed to record calendar feed usage")
+	}
+
+	name := cln.Name
+	if len(appointments) > 0 {
+		if doctor := appointments[0].Doctor; doctor.FirstName != "" || doctor.LastName != "" {
+			name = "Dr. " + doctor.FirstName + " " + doctor.LastName + " - " + cln.Name
+		}
+	}
+
+	return BuildCalendar(name, cln.Location(), appointments), nil
+}
+
+// GetAppointmentCalendar renders a single appointment as an iCalendar document in its clinic's time zone
+func (s *CalendarService) GetAppointmentCalendar(appt appointment.Appointment) []byte {
+	return BuildCalendar("", appt.Clinic.Location(), []appointment.Appointment{appt})
+}
+
+func hashFeedToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
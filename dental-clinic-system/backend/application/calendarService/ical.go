@@ -0,0 +1,221 @@
+package calendarService
+
+import (
+	"dental-clinic-system/models/appointment"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar settings
+const (
+	ProductID   = "-//I-Dentist//Dental Clinic System//TR"
+	UIDDomain   = "i-dentist"
+	ContentType = "text/calendar; charset=utf-8"
+
+	icalLocalLayout = "20060102T150405"
+	icalUTCLayout   = "20060102T150405Z"
+	maxLineOctets   = 75
+)
+
+// EventUID returns the stable UID of an appointment event, so calendar clients update instead of duplicating it
+func EventUID(appt appointment.Appointment) string {
+	return fmt.Sprintf("appointment-%d@%s", appt.ID, UIDDomain)
+}
+
+// BuildCalendar renders the appointments as an iCalendar document with times in the given location
+func BuildCalendar(name string, loc *time.Location, appointments []appointment.Appointment) []byte {
+	w := &icalWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", ProductID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	if name != "" {
+		w.text("X-WR-CALNAME", name)
+	}
+	w.line("X-WR-TIMEZONE", loc.String())
+
+	writeTimezone(w, loc, appointments)
+	for _, appt := range appointments {
+		writeEvent(w, loc, appt)
+	}
+
+	w.line("END", "VCALENDAR")
+	return []byte(w.b.String())
+}
+
+func writeEvent(w *icalWriter, loc *time.Location, appt appointment.Appointment) {
+	end := appt.EndTime
+	if end.IsZero() {
+		end = appt.ScheduledTime.Add(appointment.DefaultDuration)
+	}
+	modified := appt.UpdatedAt
+	if modified.IsZero() {
+		modified = appt.CreatedAt
+	}
+
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", EventUID(appt))
+	w.line("DTSTAMP", modified.UTC().Format(icalUTCLayout))
+	w.line("LAST-MODIFIED", modified.UTC().Format(icalUTCLayout))
+	if appt.Revision > 0 {
+		w.line("SEQUENCE", fmt.Sprint(appt.Revision))
+	}
+	w.line("DTSTART;TZID="+loc.String(), appt.ScheduledTime.In(loc).Format(icalLocalLayout))
+	w.line("DTEND;TZID="+loc.String(), end.In(loc).Format(icalLocalLayout))
+	w.text("SUMMARY", eventSummary(appt))
+	if description := eventDescription(appt); description != "" {
+		w.text("DESCRIPTION", description)
+	}
+	if location := strings.TrimSpace(strings.Join(nonEmpty(appt.Clinic.Name, appt.Clinic.Address), ", ")); location != "" {
+		w.text("LOCATION", location)
+	}
+	if appt.Status == appointment.StatusCancelled {
+		w.line("STATUS", "CANCELLED")
+	} else {
+		w.line("STATUS", "CONFIRMED")
+	}
+	w.line("TRANSP", "OPAQUE")
+	w.line("END", "VEVENT")
+}
+
+func eventSummary(appt appointment.Appointment) string {
+	summary := appt.Treatment
+	if summary == "" {
+		summary = "Randevu"
+	}
+	if patient := strings.TrimSpace(appt.Patient.Name); patient != "" {
+		summary += " - " + patient
+	}
+	return summary
+}
+
+func eventDescription(appt appointment.Appointment) string {
+	var lines []string
+	if doctor := strings.TrimSpace(appt.Doctor.FirstName + " " + appt.Doctor.LastName); doctor != "" {
+		lines = append(lines, "Doktor: "+doctor)
+	}
+	if appt.Status == appointment.StatusCancelled && appt.CancellationReason != "" {
+		lines = append(lines, "İptal nedeni: "+appt.CancellationReason)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeTimezone writes a VTIMEZONE block with every offset change of the location in the years covered by the events.
+// Explicit observances are used instead of recurrence rules because Go does not expose the zone rules themselves.
+func writeTimezone(w *icalWriter, loc *time.Location, appointments []appointment.Appointment) {
+	first, last := time.Now(), time.Now()
+	for i, appt := range appointments {
+		if i == 0 || appt.ScheduledTime.Before(first) {
+			first = appt.ScheduledTime
+		}
+		if i == 0 || appt.ScheduledTime.After(last) {
+			last = appt.ScheduledTime
+		}
+	}
+	from := time.Date(first.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	to := time.Date(last.In(loc).Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", loc.String())
+
+	abbreviation, offset := from.Zone()
+	writeObservance(w, from.IsDST(), from, offset, offset, abbreviation)
+
+	current := from
+	for {
+		_, end := current.ZoneBounds()
+		if end.IsZero() || !end.Before(to) {
+			break
+		}
+		next := end.In(loc)
+		nextAbbreviation, nextOffset := next.Zone()
+		if nextOffset != offset {
+			writeObservance(w, next.IsDST(), next, offset, nextOffset, nextAbbreviation)
+		}
+		offset = nextOffset
+		current = next
+	}
+
+	w.line("END", "VTIMEZONE")
+}
+
+// writeObservance writes a STANDARD or DAYLIGHT component starting at the given instant.
+// DTSTART is the wall clock time before the change, as required by RFC 5545.
+func writeObservance(w *icalWriter, daylight bool, start time.Time, offsetFrom, offsetTo int, abbreviation string) {
+	component := "STANDARD"
+	if daylight {
+		component = "DAYLIGHT"
+	}
+	w.line("BEGIN", component)
+	w.line("DTSTART", start.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(icalLocalLayout))
+	w.line("TZOFFSETFROM", formatOffset(offsetFrom))
+	w.line("TZOFFSETTO", formatOffset(offsetTo))
+	if abbreviation != "" {
+		w.text("TZNAME", abbreviation)
+	}
+	w.line("END", component)
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	formatted := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		formatted += fmt.Sprintf("%02d", seconds%60)
+	}
+	return formatted
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// icalWriter builds content lines with CRLF endings and folds lines longer than 75 octets
+type icalWriter struct {
+	b strings.Builder
+}
+
+func (w *icalWriter) line(name, value string) {
+	content := name + ":" + value
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.b.WriteString(content[:cut])
+		w.b.WriteString("\r\n ")
+		content = content[cut:]
+		// The leading space of a continuation line counts towards its length
+		limit = maxLineOctets - 1
+	}
+	w.b.WriteString(content)
+	w.b.WriteString("\r\n")
+}
+
+func (w *icalWriter) text(name, value string) {
+	w.line(name, escapeText(value))
+}
+
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,112 @@
+package calendarService
+
+import (
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/clinic"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildCalendar(t *testing.T) {
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start := time.Date(2025, 3, 3, 7, 0, 0, 0, time.UTC)
+
+	newAppointment := func(id uint, status appointment.Status) appointment.Appointment {
+		appt := appointment.Appointment{
+			ScheduledTime: start,
+			EndTime:       start.Add(45 * time.Minute),
+			Status:        status,
+			Treatment:     "Kanal tedavisi; kontrol",
+			Clinic:        clinic.Clinic{Name: "Gülüş Diş Kliniği", Address: "Bağdat Caddesi No: 1, Kadıköy, İstanbul"},
+		}
+		appt.ID = id
+		appt.CreatedAt = start.Add(-48 * time.Hour)
+		appt.UpdatedAt = appt.CreatedAt
+		return appt
+	}
+
+	tests := []struct {
+		name         string
+		loc          *time.Location
+		appointments []appointment.Appointment
+		want         []string
+		notWant      []string
+	}{
+		{
+			name:         "Fixed offset zone",
+			loc:          istanbul,
+			appointments: []appointment.Appointment{newAppointment(7, appointment.StatusBooked)},
+			want: []string{
+				"UID:appointment-7@" + UIDDomain,
+				"DTSTART;TZID=Europe/Istanbul:20250303T100000",
+				"DTEND;TZID=Europe/Istanbul:20250303T104500",
+				"STATUS:CONFIRMED",
+				"SUMMARY:Kanal tedavisi\\; kontrol",
+				"BEGIN:STANDARD\r\nDTSTART:20250101T000000\r\nTZOFFSETFROM:+0300\r\nTZOFFSETTO:+0300",
+			},
+			notWant: []string{"BEGIN:DAYLIGHT", "SEQUENCE:"},
+		},
+		{
+			name:         "Daylight saving zone",
+			loc:          berlin,
+			appointments: []appointment.Appointment{newAppointment(8, appointment.StatusConfirmed)},
+			want: []string{
+				"DTSTART;TZID=Europe/Berlin:20250303T080000",
+				"BEGIN:DAYLIGHT\r\nDTSTART:20250330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST",
+				"BEGIN:STANDARD\r\nDTSTART:20251026T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET",
+			},
+		},
+		{
+			name:         "Cancelled appointment",
+			loc:          istanbul,
+			appointments: []appointment.Appointment{newAppointment(9, appointment.StatusCancelled)},
+			want:         []string{"UID:appointment-9@" + UIDDomain, "STATUS:CANCELLED"},
+			notWant:      []string{"STATUS:CONFIRMED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := string(BuildCalendar("Takvim", tt.loc, tt.appointments))
+
+			for _, line := range strings.Split(strings.TrimSuffix(output, "\r\n"), "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Errorf("line longer than %d octets: %q", maxLineOctets, line)
+				}
+			}
+
+			unfolded := strings.ReplaceAll(output, "\r\n ", "")
+			for _, want := range tt.want {
+				if !strings.Contains(unfolded, want) {
+					t.Errorf("calendar does not contain %q:\n%s", want, unfolded)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(unfolded, notWant) {
+					t.Errorf("calendar unexpectedly contains %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildCalendarSequence(t *testing.T) {
+	appt := appointment.Appointment{ScheduledTime: time.Date(2025, 3, 3, 7, 0, 0, 0, time.UTC)}
+	appt.ID = 3
+	appt.CreatedAt = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	appt.UpdatedAt = appt.CreatedAt.Add(90 * time.Second)
+	appt.Revision = 2
+
+	output := string(BuildCalendar("", time.UTC, []appointment.Appointment{appt}))
+	if !strings.Contains(output, "SEQUENCE:2\r\n") {
+		t.Errorf("rescheduled appointment must carry its revision as SEQUENCE:\n%s", output)
+	}
+	if again := string(BuildCalendar("", time.UTC, []appointment.Appointment{appt})); again != output {
+		t.Errorf("an unchanged appointment must be published identically on every fetch:\n%s\n%s", output, again)
+	}
+	if !strings.Contains(output, "UID:appointment-3@"+UIDDomain+"\r\n") {
+		t.Errorf("UID must not change when the appointment is updated:\n%s", output)
+	}
+}
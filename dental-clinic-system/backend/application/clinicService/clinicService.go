@@ -5,6 +5,7 @@ import (
 	"dental-clinic-system/models/clinic"
 	"dental-clinic-system/validations"
 	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 
@@ -19,6 +20,7 @@ type ClinicRepository interface {
 	UpdateClinic(ctx context.Context, cln clinic.Clinic) (clinic.Clinic, error)
 	DeleteClinic(ctx context.Context, id uint) error
 	CheckClinicExist(ctx context.Context, cln clinic.Clinic) (bool, error)
+	UpdateLogo(ctx context.Context, id uint, data []byte, contentType string) error
 }
 
 // ClinicService handles clinic-related business logic
@@ -193,6 +195,49 @@ func (s *ClinicService) UpdateClinic(ctx context.Context, cln clinic.Clinic) (cl
 	return updatedCln, nil
 }
 
+// UpdateLogo validates and stores the logo printed on the clinic's documents
+func (s *ClinicService) UpdateLogo(ctx context.Context, id uint, data []byte) error {
+	contentType, err := validations.ClinicLogoValidation(data)
+	if err != nil {
+		log.Warn().
+			Str("operation", "UpdateLogo").
+			Err(err).
+			Uint("clinic_id", id).
+			Msg("Clinic logo validation failed")
+		return fmt.Errorf("%w: %s", clinic.ErrInvalidLogo, err.Error())
+	}
+
+	if err := s.clinicRepository.UpdateLogo(ctx, id, data, contentType); err != nil {
+		if errors.Is(err, clinic.ErrClinicNotFound) {
+			return err
+		}
+		return clinic.ErrClinicUpdate
+	}
+
+	log.Info().
+		Str("operation", "UpdateLogo").
+		Uint("clinic_id", id).
+		Str("content_type", contentType).
+		Msg("Clinic logo updated successfully")
+	return nil
+}
+
+// DeleteLogo removes the clinic's logo from its documents
+func (s *ClinicService) DeleteLogo(ctx context.Context, id uint) error {
+	if err := s.clinicRepository.UpdateLogo(ctx, id, nil, ""); err != nil {
+		if errors.Is(err, clinic.ErrClinicNotFound) {
+			return err
+		}
+		return clinic.ErrClinicUpdate
+	}
+
+	log.Info().
+		Str("operation", "DeleteLogo").
+		Uint("clinic_id", id).
+		Msg("Clinic logo removed successfully")
+	return nil
+}
+
 // DeleteClinic deletes a clinic by its ID after existence check
 func (s *ClinicService) DeleteClinic(ctx context.Context, id uint) error {
 	log.Info().
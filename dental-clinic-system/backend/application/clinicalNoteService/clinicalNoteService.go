@@ -0,0 +1,259 @@
+package clinicalNoteService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/clinicalnote"
+	"dental-clinic-system/validations"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTitle is used for notes written without a template and without a title
+const DefaultTitle = "Clinical note"
+
+// ClinicalNoteRepository defines the clinical note database operations
+type ClinicalNoteRepository interface {
+	GetTemplates(ctx context.Context, clinicID uint) ([]clinicalnote.Template, error)
+	GetTemplate(ctx context.Context, id uint) (clinicalnote.Template, error)
+	CreateTemplates(ctx context.Context, templates []clinicalnote.Template) ([]clinicalnote.Template, error)
+	ReplaceTemplate(ctx context.Context, template clinicalnote.Template) (clinicalnote.Template, error)
+	GetNotes(ctx context.Context, clinicID, patientID, appointmentID uint) ([]clinicalnote.Note, error)
+	GetNote(ctx context.Context, id uint) (clinicalnote.Note, error)
+	CreateNote(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error)
+	ReplaceDraft(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error)
+	SignNote(ctx context.Context, id, signerID uint, at time.Time) (clinicalnote.Note, error)
+	DeleteDraft(ctx context.Context, id uint) error
+	CreateAddendum(ctx context.Context, addendum clinicalnote.Addendum) (clinicalnote.Addendum, error)
+}
+
+// AppointmentRepository is used to find the appointment a note documents
+type AppointmentRepository interface {
+	GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error)
+}
+
+// ClinicalNoteService manages note templates and the clinical notes written about appointments
+type ClinicalNoteService struct {
+	clinicalNoteRepository ClinicalNoteRepository
+	appointmentRepository  AppointmentRepository
+	now                    func() time.Time
+}
+
+// NewClinicalNoteService creates a new instance of ClinicalNoteService
+func NewClinicalNoteService(clinicalNoteRepo ClinicalNoteRepository, appointmentRepo AppointmentRepository) *ClinicalNoteService {
+	return &ClinicalNoteService{
+		clinicalNoteRepository: clinicalNoteRepo,
+		appointmentRepository:  appointmentRepo,
+		now:                    time.Now,
+	}
+}
+
+// GetTemplates returns the clinic's templates. A clinic without templates gets the default
+// SOAP, extraction and root canal templates, which it can then adapt.
+func (s *ClinicalNoteService) GetTemplates(ctx context.Context, clinicID uint) ([]clinicalnote.Template, error) {
+	templates, err := s.clinicalNoteRepository.GetTemplates(ctx, clinicID)
+	if err != nil || len(templates) > 0 {
+		return templates, err
+	}
+
+	defaults := clinicalnote.DefaultTemplates()
+	for i := range defaults {
+		defaults[i].ClinicID = clinicID
+		for j := range defaults[i].Sections {
+			defaults[i].Sections[j].Position = j
+		}
+	}
+	return s.clinicalNoteRepository.CreateTemplates(ctx, defaults)
+}
+
+// GetTemplate retrieves a template by its ID
+func (s *ClinicalNoteService) GetTemplate(ctx context.Context, id uint) (clinicalnote.Template, error) {
+	return s.clinicalNoteRepository.GetTemplate(ctx, id)
+}
+
+// CreateTemplate validates and stores a new template
+func (s *ClinicalNoteService) CreateTemplate(ctx context.Context, template clinicalnote.Template) (clinicalnote.Template, error) {
+	if err := validations.ClinicalNoteTemplateValidation(&template); err != nil {
+		return clinicalnote.Template{}, fmt.Errorf("%w: %s", clinicalnote.ErrTemplateValidation, err.Error())
+	}
+	template.ID = 0
+	template.Active = true
+	for i := range template.Sections {
+		template.Sections[i].ID = 0
+	}
+
+	created, err := s.clinicalNoteRepository.CreateTemplates(ctx, []clinicalnote.Template{template})
+	if err != nil {
+		return clinicalnote.Template{}, err
+	}
+	return created[0], nil
+}
+
+// UpdateTemplate validates and saves changes to a template. Notes already written with it keep their sections.
+func (s *ClinicalNoteService) UpdateTemplate(ctx context.Context, template clinicalnote.Template) (clinicalnote.Template, error) {
+	if err := validations.ClinicalNoteTemplateValidation(&template); err != nil {
+		return clinicalnote.Template{}, fmt.Errorf("%w: %s", clinicalnote.ErrTemplateValidation, err.Error())
+	}
+	return s.clinicalNoteRepository.ReplaceTemplate(ctx, template)
+}
+
+// GetNotes returns the clinic's notes, newest first, optionally for one patient or appointment
+func (s *ClinicalNoteService) GetNotes(ctx context.Context, clinicID, patientID, appointmentID uint) ([]clinicalnote.Note, error) {
+	return s.clinicalNoteRepository.GetNotes(ctx, clinicID, patientID, appointmentID)
+}
+
+// GetNote retrieves a note with its sections and addenda
+func (s *ClinicalNoteService) GetNote(ctx context.Context, id uint) (clinicalnote.Note, error) {
+	return s.clinicalNoteRepository.GetNote(ctx, id)
+}
+
+// CreateNote starts a draft note for an appointment of the note's clinic. With a template the note gets
+// the template's sections and the given sections only fill in their bodies; without one the given sections are used.
+func (s *ClinicalNoteService) CreateNote(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error) {
+	appt, err := s.appointmentRepository.GetAppointment(ctx, note.AppointmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && appt.ClinicID != note.ClinicID) {
+		return clinicalnote.Note{}, clinicalnote.ErrAppointmentNotFound
+	}
+	if err != nil {
+		return clinicalnote.Note{}, err
+	}
+	if appt.Status == appointment.StatusCancelled || appt.Status == appointment.StatusNoShow {
+		return clinicalnote.Note{}, clinicalnote.ErrAppointmentNotDocumentable
+	}
+	note.PatientID = appt.PatientID
+
+	if note.TemplateID != nil {
+		template, err := s.clinicalNoteRepository.GetTemplate(ctx, *note.TemplateID)
+		if errors.Is(err, clinicalnote.ErrTemplateNotFound) || (err == nil && template.ClinicID != note.ClinicID) {
+			return clinicalnote.Note{}, clinicalnote.ErrTemplateNotFound
+		}
+		if err != nil {
+			return clinicalnote.Note{}, err
+		}
+		if !template.Active {
+			return clinicalnote.Note{}, fmt.Errorf("%w: template %q is no longer in use", clinicalnote.ErrNoteValidation, template.Name)
+		}
+
+		structure := make([]clinicalnote.Section, 0, len(template.Sections))
+		for _, section := range template.Sections {
+			structure = append(structure, clinicalnote.Section{Key: section.Key, Title: section.Title, Required: section.Required})
+		}
+		if note.Sections, err = fillSections(structure, note.Sections); err != nil {
+			return clinicalnote.Note{}, err
+		}
+		if strings.TrimSpace(note.Title) == "" {
+			note.Title = template.Name
+		}
+	}
+	if strings.TrimSpace(note.Title) == "" {
+		note.Title = DefaultTitle
+	}
+
+	if err := validations.ClinicalNoteValidation(&note); err != nil {
+		return clinicalnote.Note{}, fmt.Errorf("%w: %s", clinicalnote.ErrNoteValidation, err.Error())
+	}
+
+	note.ID = 0
+	note.Status = clinicalnote.StatusDraft
+	note.ContentHash = ""
+	note.SignedAt = nil
+	note.Addenda = nil
+	for i := range note.Sections {
+		note.Sections[i].ID = 0
+	}
+
+	return s.clinicalNoteRepository.CreateNote(ctx, note)
+}
+
+// UpdateNote changes the title and section bodies of a draft. Only the author may edit a draft;
+// sections of a templated note keep the template's structure.
+func (s *ClinicalNoteService) UpdateNote(ctx context.Context, id, editorID uint, changes clinicalnote.Note) (clinicalnote.Note, error) {
+	note, err := s.clinicalNoteRepository.GetNote(ctx, id)
+	if err != nil {
+		return clinicalnote.Note{}, err
+	}
+	if note.Status == clinicalnote.StatusSigned {
+		return clinicalnote.Note{}, clinicalnote.ErrNoteSigned
+	}
+	if note.AuthorID != editorID {
+		return clinicalnote.Note{}, clinicalnote.ErrNotAuthor
+	}
+
+	if strings.TrimSpace(changes.Title) != "" {
+		note.Title = changes.Title
+	}
+	switch {
+	case note.TemplateID != nil:
+		if note.Sections, err = fillSections(note.Sections, changes.Sections); err != nil {
+			return clinicalnote.Note{}, err
+		}
+	case changes.Sections != nil:
+		note.Sections = changes.Sections
+	}
+
+	if err := validations.ClinicalNoteValidation(&note); err != nil {
+		return clinicalnote.Note{}, fmt.Errorf("%w: %s", clinicalnote.ErrNoteValidation, err.Error())
+	}
+
+	return s.clinicalNoteRepository.ReplaceDraft(ctx, note)
+}
+
+// SignNote signs a draft for its author once every required section is filled in. A signed note never changes again.
+func (s *ClinicalNoteService) SignNote(ctx context.Context, id, signerID uint) (clinicalnote.Note, error) {
+	return s.clinicalNoteRepository.SignNote(ctx, id, signerID, s.now())
+}
+
+// DeleteNote discards a draft; only its author may do so and signed notes are kept forever
+func (s *ClinicalNoteService) DeleteNote(ctx context.Context, id, userID uint) error {
+	note, err := s.clinicalNoteRepository.GetNote(ctx, id)
+	if err != nil {
+		return err
+	}
+	if note.Status == clinicalnote.StatusSigned {
+		return clinicalnote.ErrNoteSigned
+	}
+	if note.AuthorID != userID {
+		return clinicalnote.ErrNotAuthor
+	}
+	return s.clinicalNoteRepository.DeleteDraft(ctx, id)
+}
+
+// AddAddendum appends a correction or later observation to a signed note
+func (s *ClinicalNoteService) AddAddendum(ctx context.Context, noteID, authorID uint, body string) (clinicalnote.Addendum, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return clinicalnote.Addendum{}, fmt.Errorf("%w: addendum text is required", clinicalnote.ErrNoteValidation)
+	}
+
+	addendum := clinicalnote.Addendum{
+		NoteID:   noteID,
+		AuthorID: authorID,
+		Body:     body,
+	}
+	addendum.CreatedAt = s.now()
+	addendum.ContentHash = addendum.Hash(addendum.CreatedAt)
+
+	return s.clinicalNoteRepository.CreateAddendum(ctx, addendum)
+}
+
+// fillSections copies the bodies of the given sections into the sections of a fixed structure by key
+func fillSections(structure, given []clinicalnote.Section) ([]clinicalnote.Section, error) {
+	index := map[string]int{}
+	for i, section := range structure {
+		index[section.Key] = i
+	}
+
+	filled := append([]clinicalnote.Section(nil), structure...)
+	for _, section := range given {
+		i, ok := index[section.Key]
+		if !ok {
+			return nil, fmt.Errorf("%w: template has no section %q", clinicalnote.ErrNoteValidation, section.Key)
+		}
+		filled[i].Body = section.Body
+	}
+	return filled, nil
+}
@@ -0,0 +1,328 @@
+package clinicalNoteService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/clinicalnote"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeClinicalNoteRepository keeps templates and notes in memory and enforces the draft and signing rules like the real repository
+type fakeClinicalNoteRepository struct {
+	templates []clinicalnote.Template
+	notes     map[uint]clinicalnote.Note
+	nextID    uint
+}
+
+func newFakeClinicalNoteRepository() *fakeClinicalNoteRepository {
+	return &fakeClinicalNoteRepository{notes: map[uint]clinicalnote.Note{}}
+}
+
+func (r *fakeClinicalNoteRepository) id() uint {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *fakeClinicalNoteRepository) GetTemplates(ctx context.Context, clinicID uint) ([]clinicalnote.Template, error) {
+	var templates []clinicalnote.Template
+	for _, template := range r.templates {
+		if template.ClinicID == clinicID {
+			templates = append(templates, template)
+		}
+	}
+	return templates, nil
+}
+
+func (r *fakeClinicalNoteRepository) GetTemplate(ctx context.Context, id uint) (clinicalnote.Template, error) {
+	for _, template := range r.templates {
+		if template.ID == id {
+			return template, nil
+		}
+	}
+	return clinicalnote.Template{}, clinicalnote.ErrTemplateNotFound
+}
+
+func (r *fakeClinicalNoteRepository) CreateTemplates(ctx context.Context, templates []clinicalnote.Template) ([]clinicalnote.Template, error) {
+	for i := range templates {
+		templates[i].ID = r.id()
+		r.templates = append(r.templates, templates[i])
+	}
+	return templates, nil
+}
+
+func (r *fakeClinicalNoteRepository) ReplaceTemplate(ctx context.Context, template clinicalnote.Template) (clinicalnote.Template, error) {
+	for i := range r.templates {
+		if r.templates[i].ID == template.ID {
+			r.templates[i] = template
+			return template, nil
+		}
+	}
+	return clinicalnote.Template{}, clinicalnote.ErrTemplateNotFound
+}
+
+func (r *fakeClinicalNoteRepository) GetNotes(ctx context.Context, clinicID, patientID, appointmentID uint) ([]clinicalnote.Note, error) {
+	var notes []clinicalnote.Note
+	for _, note := range r.notes {
+		if note.ClinicID == clinicID && (patientID == 0 || note.PatientID == patientID) && (appointmentID == 0 || note.AppointmentID == appointmentID) {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+func (r *fakeClinicalNoteRepository) GetNote(ctx context.Context, id uint) (clinicalnote.Note, error) {
+	note, ok := r.notes[id]
+	if !ok {
+		return clinicalnote.Note{}, clinicalnote.ErrNoteNotFound
+	}
+	note.Sections = append([]clinicalnote.Section(nil), note.Sections...)
+	return note, nil
+}
+
+func (r *fakeClinicalNoteRepository) CreateNote(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error) {
+	note.ID = r.id()
+	r.notes[note.ID] = note
+	return note, nil
+}
+
+func (r *fakeClinicalNoteRepository) ReplaceDraft(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error) {
+	if r.notes[note.ID].Status != clinicalnote.StatusDraft {
+		return clinicalnote.Note{}, clinicalnote.ErrNoteSigned
+	}
+	r.notes[note.ID] = note
+	return note, nil
+}
+
+func (r *fakeClinicalNoteRepository) SignNote(ctx context.Context, id, signerID uint, at time.Time) (clinicalnote.Note, error) {
+	note, err := r.GetNote(ctx, id)
+	if err != nil {
+		return clinicalnote.Note{}, err
+	}
+	if err := note.CanSign(signerID); err != nil {
+		return clinicalnote.Note{}, err
+	}
+	note.Status = clinicalnote.StatusSigned
+	note.SignedAt = &at
+	note.ContentHash = note.Hash()
+	r.notes[id] = note
+	return note, nil
+}
+
+func (r *fakeClinicalNoteRepository) DeleteDraft(ctx context.Context, id uint) error {
+	if r.notes[id].Status != clinicalnote.StatusDraft {
+		return clinicalnote.ErrNoteSigned
+	}
+	delete(r.notes, id)
+	return nil
+}
+
+func (r *fakeClinicalNoteRepository) CreateAddendum(ctx context.Context, addendum clinicalnote.Addendum) (clinicalnote.Addendum, error) {
+	note, err := r.GetNote(ctx, addendum.NoteID)
+	if err != nil {
+		return clinicalnote.Addendum{}, err
+	}
+	if note.Status != clinicalnote.StatusSigned {
+		return clinicalnote.Addendum{}, clinicalnote.ErrNoteNotSigned
+	}
+	addendum.ID = r.id()
+	note.Addenda = append(note.Addenda, addendum)
+	r.notes[note.ID] = note
+	return addendum, nil
+}
+
+// fakeAppointmentRepository serves fixed appointments
+type fakeAppointmentRepository map[uint]appointment.Appointment
+
+func (r fakeAppointmentRepository) GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error) {
+	appt, ok := r[id]
+	if !ok {
+		return appointment.Appointment{}, gorm.ErrRecordNotFound
+	}
+	return appt, nil
+}
+
+const (
+	doctorID    = 10
+	assistantID = 11
+)
+
+var signedAt = time.Date(2025, time.March, 3, 11, 0, 0, 0, time.UTC)
+
+func newTestService() (*ClinicalNoteService, *fakeClinicalNoteRepository) {
+	repo := newFakeClinicalNoteRepository()
+	appointments := fakeAppointmentRepository{
+		1: {Model: gorm.Model{ID: 1}, ClinicID: 1, PatientID: 5, Status: appointment.StatusInChair},
+		2: {Model: gorm.Model{ID: 2}, ClinicID: 1, PatientID: 5, Status: appointment.StatusCancelled},
+		3: {Model: gorm.Model{ID: 3}, ClinicID: 2, PatientID: 6, Status: appointment.StatusInChair},
+	}
+	service := NewClinicalNoteService(repo, appointments)
+	service.now = func() time.Time { return signedAt }
+	return service, repo
+}
+
+func soapTemplateID(t *testing.T, service *ClinicalNoteService) uint {
+	templates, err := service.GetTemplates(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetTemplates() error = %v", err)
+	}
+	for _, template := range templates {
+		if template.Name == "SOAP" {
+			return template.ID
+		}
+	}
+	t.Fatalf("GetTemplates() = %+v, want the default SOAP template", templates)
+	return 0
+}
+
+func TestGetTemplatesSeedsDefaultsOnce(t *testing.T) {
+	service, repo := newTestService()
+
+	first, err := service.GetTemplates(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetTemplates() error = %v", err)
+	}
+	second, err := service.GetTemplates(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetTemplates() error = %v", err)
+	}
+
+	if len(first) != 3 || len(second) != 3 || len(repo.templates) != 3 {
+		t.Errorf("got %d then %d templates with %d stored, want the 3 defaults created once", len(first), len(second), len(repo.templates))
+	}
+	for _, template := range first {
+		if template.ClinicID != 1 {
+			t.Errorf("template %q clinic = %d, want 1", template.Name, template.ClinicID)
+		}
+	}
+}
+
+func TestCreateNote(t *testing.T) {
+	tests := []struct {
+		name          string
+		appointmentID uint
+		sections      []clinicalnote.Section
+		wantErr       error
+	}{
+		{
+			name:          "SOAP note for an appointment in the chair",
+			appointmentID: 1,
+			sections:      []clinicalnote.Section{{Key: "subjective", Body: "Pain on 36 when chewing"}},
+		},
+		{
+			name:          "Unknown template section",
+			appointmentID: 1,
+			sections:      []clinicalnote.Section{{Key: "history", Body: "None"}},
+			wantErr:       clinicalnote.ErrNoteValidation,
+		},
+		{
+			name:          "Cancelled appointment",
+			appointmentID: 2,
+			wantErr:       clinicalnote.ErrAppointmentNotDocumentable,
+		},
+		{
+			name:          "Appointment of another clinic",
+			appointmentID: 3,
+			wantErr:       clinicalnote.ErrAppointmentNotFound,
+		},
+		{
+			name:          "Missing appointment",
+			appointmentID: 99,
+			wantErr:       clinicalnote.ErrAppointmentNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService()
+			templateID := soapTemplateID(t, service)
+
+			note, err := service.CreateNote(context.Background(), clinicalnote.Note{
+				ClinicID:      1,
+				AppointmentID: tt.appointmentID,
+				AuthorID:      doctorID,
+				TemplateID:    &templateID,
+				Sections:      tt.sections,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateNote() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if note.PatientID != 5 || note.Title != "SOAP" || note.Status != clinicalnote.StatusDraft {
+				t.Errorf("note = patient %d, title %q, status %s, want patient 5, SOAP draft", note.PatientID, note.Title, note.Status)
+			}
+			if len(note.Sections) != 4 || note.Sections[0].Body != "Pain on 36 when chewing" || !note.Sections[3].Required {
+				t.Errorf("sections = %+v, want the 4 SOAP sections with the subjective body filled in", note.Sections)
+			}
+		})
+	}
+}
+
+func TestNoteSigningLifecycle(t *testing.T) {
+	service, _ := newTestService()
+	ctx := context.Background()
+	templateID := soapTemplateID(t, service)
+
+	note, err := service.CreateNote(ctx, clinicalnote.Note{ClinicID: 1, AppointmentID: 1, AuthorID: doctorID, TemplateID: &templateID})
+	if err != nil {
+		t.Fatalf("CreateNote() error = %v", err)
+	}
+
+	if _, err := service.SignNote(ctx, note.ID, doctorID); !errors.Is(err, clinicalnote.ErrNoteValidation) {
+		t.Errorf("SignNote() with empty sections error = %v, want ErrNoteValidation", err)
+	}
+	if _, err := service.AddAddendum(ctx, note.ID, doctorID, "Forgot to mention bruxism"); !errors.Is(err, clinicalnote.ErrNoteNotSigned) {
+		t.Errorf("AddAddendum() to a draft error = %v, want ErrNoteNotSigned", err)
+	}
+
+	complete := []clinicalnote.Section{
+		{Key: "subjective", Body: "Pain on 36"},
+		{Key: "objective", Body: "Deep caries 36 MO"},
+		{Key: "assessment", Body: "Reversible pulpitis"},
+		{Key: "plan", Body: "Composite filling"},
+	}
+	if _, err := service.UpdateNote(ctx, note.ID, assistantID, clinicalnote.Note{Sections: complete}); !errors.Is(err, clinicalnote.ErrNotAuthor) {
+		t.Errorf("UpdateNote() by another user error = %v, want ErrNotAuthor", err)
+	}
+	if _, err := service.UpdateNote(ctx, note.ID, doctorID, clinicalnote.Note{Sections: complete}); err != nil {
+		t.Fatalf("UpdateNote() error = %v", err)
+	}
+	if _, err := service.SignNote(ctx, note.ID, assistantID); !errors.Is(err, clinicalnote.ErrNotAuthor) {
+		t.Errorf("SignNote() by another user error = %v, want ErrNotAuthor", err)
+	}
+
+	signed, err := service.SignNote(ctx, note.ID, doctorID)
+	if err != nil {
+		t.Fatalf("SignNote() error = %v", err)
+	}
+	if signed.Status != clinicalnote.StatusSigned || signed.SignedAt == nil || signed.ContentHash != signed.Hash() {
+		t.Errorf("signed note = status %s, signed at %v, hash %q, want a signed note with its content hash", signed.Status, signed.SignedAt, signed.ContentHash)
+	}
+
+	if _, err := service.UpdateNote(ctx, note.ID, doctorID, clinicalnote.Note{Title: "Changed"}); !errors.Is(err, clinicalnote.ErrNoteSigned) {
+		t.Errorf("UpdateNote() after signing error = %v, want ErrNoteSigned", err)
+	}
+	if err := service.DeleteNote(ctx, note.ID, doctorID); !errors.Is(err, clinicalnote.ErrNoteSigned) {
+		t.Errorf("DeleteNote() after signing error = %v, want ErrNoteSigned", err)
+	}
+	if _, err := service.SignNote(ctx, note.ID, doctorID); !errors.Is(err, clinicalnote.ErrNoteSigned) {
+		t.Errorf("SignNote() twice error = %v, want ErrNoteSigned", err)
+	}
+
+	addendum, err := service.AddAddendum(ctx, note.ID, doctorID, " Tooth is 37, not 36 ")
+	if err != nil {
+		t.Fatalf("AddAddendum() error = %v", err)
+	}
+	if addendum.Body != "Tooth is 37, not 36" || addendum.ContentHash == "" || !addendum.CreatedAt.Equal(signedAt) {
+		t.Errorf("addendum = %+v, want the trimmed body with its hash and time", addendum)
+	}
+	if _, err := service.AddAddendum(ctx, note.ID, doctorID, "  "); !errors.Is(err, clinicalnote.ErrNoteValidation) {
+		t.Errorf("AddAddendum() without text error = %v, want ErrNoteValidation", err)
+	}
+}
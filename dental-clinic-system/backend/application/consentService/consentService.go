@@ -0,0 +1,302 @@
+package consentService
+
+import (
+	"bytes"
+	"context"
+	"dental-clinic-system/infrastructure/signature"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/document"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/validations"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ConsentRepository defines the consent template and form database operations
+type ConsentRepository interface {
+	GetTemplates(ctx context.Context, clinicID uint) ([]consent.Template, error)
+	GetTemplate(ctx context.Context, id uint) (consent.Template, error)
+	CreateTemplate(ctx context.Context, template consent.Template, createdByID uint) (consent.Template, error)
+	UpdateTemplate(ctx context.Context, template consent.Template, editorID uint) (consent.Template, error)
+	GetTemplateVersions(ctx context.Context, templateID uint) ([]consent.TemplateVersion, error)
+	GetRequiredTemplates(ctx context.Context, clinicID, procedureID uint) ([]consent.Template, error)
+	GetSignedForms(ctx context.Context, patientID, procedureID uint) ([]consent.Form, error)
+	GetForms(ctx context.Context, clinicID, patientID uint) ([]consent.Form, error)
+	GetForm(ctx context.Context, id uint) (consent.Form, error)
+	CreateForm(ctx context.Context, form consent.Form) (consent.Form, error)
+	SignForm(ctx context.Context, form consent.Form) (consent.Form, error)
+	DeletePendingForm(ctx context.Context, id uint) error
+}
+
+// ProcedureRepository is used to check the procedures consents are given for
+type ProcedureRepository interface {
+	GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error)
+}
+
+// DocumentRenderer renders signed consent forms as PDF files
+type DocumentRenderer interface {
+	ConsentPDF(ctx context.Context, form consent.Form, signaturePNG []byte) (document.Document, error)
+}
+
+// AttachmentService keeps the signed PDF files with the patient's attachments
+type AttachmentService interface {
+	Upload(ctx context.Context, upload attachment.Upload, r io.Reader) (attachment.Attachment, error)
+	GetAttachment(ctx context.Context, id uint) (attachment.Attachment, error)
+	DeleteAttachment(ctx context.Context, id uint) error
+	DownloadLink(a attachment.Attachment) attachment.DownloadLink
+}
+
+// ConsentService manages consent templates and the forms patients sign before procedures
+type ConsentService struct {
+	consentRepository   ConsentRepository
+	procedureRepository ProcedureRepository
+	documentRenderer    DocumentRenderer
+	attachmentService   AttachmentService
+	now                 func() time.Time
+}
+
+// NewConsentService creates a new instance of ConsentService
+func NewConsentService(consentRepo ConsentRepository, procedureRepo ProcedureRepository, documentRenderer DocumentRenderer, attachmentService AttachmentService) *ConsentService {
+	return &ConsentService{
+		consentRepository:   consentRepo,
+		procedureRepository: procedureRepo,
+		documentRenderer:    documentRenderer,
+		attachmentService:   attachmentService,
+		now:                 time.Now,
+	}
+}
+
+// GetTemplates returns the clinic's consent templates
+func (s *ConsentService) GetTemplates(ctx context.Context, clinicID uint) ([]consent.Template, error) {
+	return s.consentRepository.GetTemplates(ctx, clinicID)
+}
+
+// GetTemplate retrieves a template by its ID
+func (s *ConsentService) GetTemplate(ctx context.Context, id uint) (consent.Template, error) {
+	return s.consentRepository.GetTemplate(ctx, id)
+}
+
+// CreateTemplate validates and stores a new template as its first version
+func (s *ConsentService) CreateTemplate(ctx context.Context, template consent.Template, createdByID uint) (consent.Template, error) {
+	if err := s.validateTemplate(ctx, &template); err != nil {
+		return consent.Template{}, err
+	}
+	template.ID = 0
+	template.Active = true
+	return s.consentRepository.CreateTemplate(ctx, template, createdByID)
+}
+
+// UpdateTemplate validates and saves changes to a template. A changed title or body becomes a new version;
+// forms already prepared keep the text they were prepared with.
+func (s *ConsentService) UpdateTemplate(ctx context.Context, template consent.Template, editorID uint) (consent.Template, error) {
+	if err := s.validateTemplate(ctx, &template); err != nil {
+		return consent.Template{}, err
+	}
+	return s.consentRepository.UpdateTemplate(ctx, template, editorID)
+}
+
+// GetTemplateVersions returns every version of a template, newest first
+func (s *ConsentService) GetTemplateVersions(ctx context.Context, templateID uint) ([]consent.TemplateVersion, error) {
+	return s.consentRepository.GetTemplateVersions(ctx, templateID)
+}
+
+// GetForms returns the patient's consent forms, newest first
+func (s *ConsentService) GetForms(ctx context.Context, clinicID, patientID uint) ([]consent.Form, error) {
+	return s.consentRepository.GetForms(ctx, clinicID, patientID)
+}
+
+// GetForm retrieves a consent form by its ID
+func (s *ConsentService) GetForm(ctx context.Context, id uint) (consent.Form, error) {
+	return s.consentRepository.GetForm(ctx, id)
+}
+
+// PrepareForm creates a form for the patient and procedure from the current version of an active template
+func (s *ConsentService) PrepareForm(ctx context.Context, form consent.Form) (consent.Form, error) {
+	if form.PatientID == 0 || form.TemplateID == 0 || form.ProcedureID == 0 {
+		return consent.Form{}, fmt.Errorf("%w: patient, template and procedure are required", consent.ErrFormValidation)
+	}
+
+	template, err := s.consentRepository.GetTemplate(ctx, form.TemplateID)
+	if errors.Is(err, consent.ErrTemplateNotFound) || (err == nil && template.ClinicID != form.ClinicID) {
+		return consent.Form{}, consent.ErrTemplateNotFound
+	}
+	if err != nil {
+		return consent.Form{}, err
+	}
+	if !template.Active {
+		return consent.Form{}, fmt.Errorf("%w: template %q is no longer in use", consent.ErrFormValidation, template.Name)
+	}
+
+	proc, err := s.clinicProcedure(ctx, form.ClinicID, form.ProcedureID)
+	if err != nil {
+		return consent.Form{}, err
+	}
+
+	return s.consentRepository.CreateForm(ctx, consent.Form{
+		ClinicID:      form.ClinicID,
+		PatientID:     form.PatientID,
+		ProcedureID:   proc.ID,
+		ProcedureName: proc.Name,
+		TemplateID:    template.ID,
+		Version:       template.Version,
+		Title:         template.Title,
+		Body:          template.Body,
+		PreparedByID:  form.PreparedByID,
+		Status:        consent.StatusPending,
+	})
+}
+
+// SignForm captures the signature of a pending form, witnessed by a staff user, and freezes the signed
+// form as a PDF attachment of the patient. The form's content hash and the PDF's SHA-256 are recorded.
+func (s *ConsentService) SignForm(ctx context.Context, id uint, req consent.SignRequest, witnessID uint, ip string) (consent.Form, error) {
+	if err := validations.ConsentSignatureValidation(&req); err != nil {
+		return consent.Form{}, fmt.Errorf("%w: %s", consent.ErrFormValidation, err.Error())
+	}
+	contentType, image, err := signature.DecodeDataURL(req.Signature)
+	if err != nil {
+		return consent.Form{}, fmt.Errorf("%w: %s", consent.ErrFormValidation, err.Error())
+	}
+	signaturePNG, err := signature.ToPNG(contentType, image)
+	if err != nil {
+		return consent.Form{}, fmt.Errorf("%w: %s", consent.ErrFormValidation, err.Error())
+	}
+
+	form, err := s.consentRepository.GetForm(ctx, id)
+	if err != nil {
+		return consent.Form{}, err
+	}
+	if form.Status == consent.StatusSigned {
+		return consent.Form{}, consent.ErrFormSigned
+	}
+
+	signedAt := s.now()
+	form.SignerName = req.SignerName
+	form.SignatureType = contentType
+	form.Signature = image
+	form.SignedAt = &signedAt
+	form.SignedIP = ip
+	form.WitnessID = &witnessID
+	form.ContentHash = form.Hash()
+
+	doc, err := s.documentRenderer.ConsentPDF(ctx, form, signaturePNG)
+	if err != nil {
+		return consent.Form{}, err
+	}
+	stored, err := s.attachmentService.Upload(ctx, attachment.Upload{
+		ClinicID:     form.ClinicID,
+		PatientID:    form.PatientID,
+		UploadedByID: witnessID,
+		Category:     attachment.CategoryConsent,
+		Description:  form.Title,
+		FileName:     doc.FileName,
+	}, bytes.NewReader(doc.Content))
+	if err != nil {
+		return consent.Form{}, err
+	}
+	form.AttachmentID = &stored.ID
+	form.DocumentHash = stored.SHA256
+
+	signed, err := s.consentRepository.SignForm(ctx, form)
+	if err != nil {
+		if deleteErr := s.attachmentService.DeleteAttachment(ctx, stored.ID); deleteErr != nil {
+			log.Error().
+				Str("operation", "SignForm").
+				Err(deleteErr).
+				Uint("attachment_id", stored.ID).
+				Msg("Failed to remove the PDF of a consent form that could not be signed")
+		}
+		return consent.Form{}, err
+	}
+	return signed, nil
+}
+
+// DeleteForm removes a form that has not been signed
+func (s *ConsentService) DeleteForm(ctx context.Context, id uint) error {
+	return s.consentRepository.DeletePendingForm(ctx, id)
+}
+
+// DocumentLink returns a short-lived link to the signed PDF of a form
+func (s *ConsentService) DocumentLink(ctx context.Context, form consent.Form) (attachment.DownloadLink, error) {
+	if form.Status != consent.StatusSigned || form.AttachmentID == nil {
+		return attachment.DownloadLink{}, consent.ErrFormNotSigned
+	}
+	stored, err := s.attachmentService.GetAttachment(ctx, *form.AttachmentID)
+	if err != nil {
+		return attachment.DownloadLink{}, err
+	}
+	return s.attachmentService.DownloadLink(stored), nil
+}
+
+// RequiredConsents lists the consents the clinic requires before the procedure is started,
+// and the patient's signed form for each of them if there is one
+func (s *ConsentService) RequiredConsents(ctx context.Context, clinicID, patientID, procedureID uint) ([]consent.Requirement, error) {
+	templates, err := s.consentRepository.GetRequiredTemplates(ctx, clinicID, procedureID)
+	if err != nil || len(templates) == 0 {
+		return []consent.Requirement{}, err
+	}
+	forms, err := s.consentRepository.GetSignedForms(ctx, patientID, procedureID)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := make([]consent.Requirement, len(templates))
+	for i, template := range templates {
+		requirements[i] = consent.Requirement{TemplateID: template.ID, Name: template.Name}
+		for _, form := range forms {
+			if form.TemplateID == template.ID && form.ClinicID == clinicID {
+				formID := form.ID
+				requirements[i].Signed = true
+				requirements[i].FormID = &formID
+				break
+			}
+		}
+	}
+	return requirements, nil
+}
+
+// CheckConsents returns a MissingConsentError when the patient has not signed every consent the procedure requires
+func (s *ConsentService) CheckConsents(ctx context.Context, clinicID, patientID, procedureID uint) error {
+	requirements, err := s.RequiredConsents(ctx, clinicID, patientID, procedureID)
+	if err != nil {
+		return err
+	}
+
+	var missing []consent.Requirement
+	for _, requirement := range requirements {
+		if !requirement.Signed {
+			missing = append(missing, requirement)
+		}
+	}
+	if len(missing) > 0 {
+		return &consent.MissingConsentError{ProcedureID: procedureID, Missing: missing}
+	}
+	return nil
+}
+
+// validateTemplate checks a template and that its procedures belong to its clinic
+func (s *ConsentService) validateTemplate(ctx context.Context, template *consent.Template) error {
+	if err := validations.ConsentTemplateValidation(template); err != nil {
+		return fmt.Errorf("%w: %s", consent.ErrTemplateValidation, err.Error())
+	}
+	for _, p := range template.Procedures {
+		if _, err := s.clinicProcedure(ctx, template.ClinicID, p.ProcedureID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clinicProcedure retrieves a procedure of the clinic
+func (s *ConsentService) clinicProcedure(ctx context.Context, clinicID, procedureID uint) (procedure.Procedure, error) {
+	proc, err := s.procedureRepository.GetProcedure(ctx, procedureID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && proc.ClinicID != clinicID) {
+		return procedure.Procedure{}, fmt.Errorf("%w: %d", consent.ErrProcedureNotFound, procedureID)
+	}
+	return proc, err
+}
@@ -0,0 +1,499 @@
+package consentService
+
+import (
+	"bytes"
+	"context"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/document"
+	"dental-clinic-system/models/procedure"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeConsentRepository keeps templates and forms in memory
+type fakeConsentRepository struct {
+	templates map[uint]consent.Template
+	forms     map[uint]consent.Form
+	signErr   error
+}
+
+func (r *fakeConsentRepository) GetTemplates(ctx context.Context, clinicID uint) ([]consent.Template, error) {
+	var found []consent.Template
+	for _, template := range r.templates {
+		if template.ClinicID == clinicID {
+			found = append(found, template)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeConsentRepository) GetTemplate(ctx context.Context, id uint) (consent.Template, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return consent.Template{}, consent.ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+func (r *fakeConsentRepository) CreateTemplate(ctx context.Context, template consent.Template, createdByID uint) (consent.Template, error) {
+	template.ID = uint(len(r.templates) + 1)
+	template.Version = 1
+	r.templates[template.ID] = template
+	return template, nil
+}
+
+func (r *fakeConsentRepository) UpdateTemplate(ctx context.Context, template consent.Template, editorID uint) (consent.Template, error) {
+	current, ok := r.templates[template.ID]
+	if !ok {
+		return consent.Template{}, consent.ErrTemplateNotFound
+	}
+	template.Version = current.Version
+	if current.Title != template.Title || current.Body != template.Body {
+		template.Version++
+	}
+	r.templates[template.ID] = template
+	return template, nil
+}
+
+func (r *fakeConsentRepository) GetTemplateVersions(ctx context.Context, templateID uint) ([]consent.TemplateVersion, error) {
+	return nil, nil
+}
+
+func (r *fakeConsentRepository) GetRequiredTemplates(ctx context.Context, clinicID, procedureID uint) ([]consent.Template, error) {
+	var found []consent.Template
+	for id := uint(1); id <= uint(len(r.templates)); id++ {
+		template := r.templates[id]
+		if template.ClinicID != clinicID || !template.Active {
+			continue
+		}
+		for _, p := range template.Procedures {
+			if p.ProcedureID == procedureID {
+				found = append(found, template)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeConsentRepository) GetSignedForms(ctx context.Context, patientID, procedureID uint) ([]consent.Form, error) {
+	var found []consent.Form
+	for _, form := range r.forms {
+		if form.PatientID == patientID && form.ProcedureID == procedureID && form.Status == consent.StatusSigned {
+			found = append(found, form)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeConsentRepository) GetForms(ctx context.Context, clinicID, patientID uint) ([]consent.Form, error) {
+	var found []consent.Form
+	for _, form := range r.forms {
+		if form.ClinicID == clinicID && form.PatientID == patientID {
+			found = append(found, form)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeConsentRepository) GetForm(ctx context.Context, id uint) (consent.Form, error) {
+	form, ok := r.forms[id]
+	if !ok {
+		return consent.Form{}, consent.ErrFormNotFound
+	}
+	return form, nil
+}
+
+func (r *fakeConsentRepository) CreateForm(ctx context.Context, form consent.Form) (consent.Form, error) {
+	form.ID = uint(len(r.forms) + 1)
+	r.forms[form.ID] = form
+	return form, nil
+}
+
+func (r *fakeConsentRepository) SignForm(ctx context.Context, form consent.Form) (consent.Form, error) {
+	if r.signErr != nil {
+		return consent.Form{}, r.signErr
+	}
+	if r.forms[form.ID].Status != consent.StatusPending {
+		return consent.Form{}, consent.ErrFormSigned
+	}
+	form.Status = consent.StatusSigned
+	r.forms[form.ID] = form
+	return form, nil
+}
+
+func (r *fakeConsentRepository) DeletePendingForm(ctx context.Context, id uint) error {
+	form, ok := r.forms[id]
+	if !ok {
+		return consent.ErrFormNotFound
+	}
+	if form.Status != consent.StatusPending {
+		return consent.ErrFormSigned
+	}
+	delete(r.forms, id)
+	return nil
+}
+
+// fakeProcedureRepository returns fixed procedures
+type fakeProcedureRepository struct {
+	procedures map[uint]procedure.Procedure
+}
+
+func (r *fakeProcedureRepository) GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error) {
+	proc, ok := r.procedures[id]
+	if !ok {
+		return procedure.Procedure{}, gorm.ErrRecordNotFound
+	}
+	return proc, nil
+}
+
+// fakeRenderer records the forms it rendered
+type fakeRenderer struct {
+	forms      []consent.Form
+	signatures [][]byte
+}
+
+func (r *fakeRenderer) ConsentPDF(ctx context.Context, form consent.Form, signaturePNG []byte) (document.Document, error) {
+	r.forms = append(r.forms, form)
+	r.signatures = append(r.signatures, signaturePNG)
+	return document.Document{FileName: "onam-formu.pdf", Content: []byte("%PDF-1.4")}, nil
+}
+
+// fakeAttachmentService records uploaded files
+type fakeAttachmentService struct {
+	attachments map[uint]attachment.Attachment
+	deleted     []uint
+}
+
+func (s *fakeAttachmentService) Upload(ctx context.Context, upload attachment.Upload, r io.Reader) (attachment.Attachment, error) {
+	if _, err := io.ReadAll(r); err != nil {
+		return attachment.Attachment{}, err
+	}
+	a := attachment.Attachment{
+		ClinicID:  upload.ClinicID,
+		PatientID: upload.PatientID,
+		Category:  upload.Category,
+		FileName:  upload.FileName,
+		SHA256:    "pdf-sha256",
+	}
+	a.ID = uint(len(s.attachments) + 1)
+	s.attachments[a.ID] = a
+	return a, nil
+}
+
+func (s *fakeAttachmentService) GetAttachment(ctx context.Context, id uint) (attachment.Attachment, error) {
+	a, ok := s.attachments[id]
+	if !ok {
+		return attachment.Attachment{}, attachment.ErrAttachmentNotFound
+	}
+	return a, nil
+}
+
+func (s *fakeAttachmentService) DeleteAttachment(ctx context.Context, id uint) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func (s *fakeAttachmentService) DownloadLink(a attachment.Attachment) attachment.DownloadLink {
+	return attachment.DownloadLink{URL: "/attachments/download"}
+}
+
+var signedAt = time.Date(2026, 3, 5, 7, 30, 0, 0, time.UTC)
+
+func newTestService() (*ConsentService, *fakeConsentRepository, *fakeRenderer, *fakeAttachmentService) {
+	repo := &fakeConsentRepository{
+		templates: map[uint]consent.Template{
+			1: {ClinicID: 1, Name: "Extraction", Active: true, Version: 2, Title: "Diş Çekimi Onam Formu", Body: "Riskler anlatıldı.",
+				Procedures: []consent.TemplateProcedure{{ProcedureID: 5}}},
+			2: {ClinicID: 1, Name: "Anaesthesia", Active: true, Version: 1, Title: "Anestezi Onam Formu", Body: "Lokal anestezi uygulanacak.",
+				Procedures: []consent.TemplateProcedure{{ProcedureID: 5}, {ProcedureID: 6}}},
+			3: {ClinicID: 1, Name: "Retired", Active: false, Version: 1, Title: "Eski Form", Body: "Eski metin.",
+				Procedures: []consent.TemplateProcedure{{ProcedureID: 5}}},
+			4: {ClinicID: 2, Name: "Other clinic", Active: true, Version: 1, Title: "Başka Klinik", Body: "Metin."},
+		},
+		forms: map[uint]consent.Form{},
+	}
+	for id, template := range repo.templates {
+		template.ID = id
+		repo.templates[id] = template
+	}
+	procedures := &fakeProcedureRepository{procedures: map[uint]procedure.Procedure{
+		5: {Name: "Diş çekimi", ClinicID: 1},
+		6: {Name: "Dolgu", ClinicID: 1},
+		7: {Name: "Başka klinik", ClinicID: 2},
+	}}
+	for id, proc := range procedures.procedures {
+		proc.ID = id
+		procedures.procedures[id] = proc
+	}
+	renderer := &fakeRenderer{}
+	attachments := &fakeAttachmentService{attachments: map[uint]attachment.Attachment{}}
+
+	s := NewConsentService(repo, procedures, renderer, attachments)
+	s.now = func() time.Time { return signedAt }
+	return s, repo, renderer, attachments
+}
+
+func pngSignature(t *testing.T) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	img.Set(5, 5, color.Black)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode signature: %v", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func svgSignature() string {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="100"><path d="M10 80 C 40 10, 65 10, 95 80 S 150 150, 180 80" stroke="black" stroke-width="3" fill="none"/></svg>`
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg))
+}
+
+func prepare(t *testing.T, s *ConsentService, templateID, procedureID uint) consent.Form {
+	t.Helper()
+	form, err := s.PrepareForm(context.Background(), consent.Form{ClinicID: 1, PatientID: 7, TemplateID: templateID, ProcedureID: procedureID, PreparedByID: 3})
+	if err != nil {
+		t.Fatalf("PrepareForm() error = %v", err)
+	}
+	return form
+}
+
+func TestPrepareForm(t *testing.T) {
+	s, repo, _, _ := newTestService()
+
+	form := prepare(t, s, 1, 5)
+	if form.Status != consent.StatusPending || form.Version != 2 || form.Title != "Diş Çekimi Onam Formu" ||
+		form.Body != "Riskler anlatıldı." || form.ProcedureName != "Diş çekimi" {
+		t.Errorf("form = %+v", form)
+	}
+
+	// Later changes to the template do not change forms that were already prepared
+	template := repo.templates[1]
+	template.Body = "Yeni metin."
+	if _, err := s.UpdateTemplate(context.Background(), template, 3); err != nil {
+		t.Fatalf("UpdateTemplate() error = %v", err)
+	}
+	if stored, _ := s.GetForm(context.Background(), form.ID); stored.Body != "Riskler anlatıldı." || stored.Version != 2 {
+		t.Errorf("prepared form changed with its template: %+v", stored)
+	}
+
+	tests := []struct {
+		name        string
+		templateID  uint
+		procedureID uint
+		want        error
+	}{
+		{"missing template", 0, 5, consent.ErrFormValidation},
+		{"unknown template", 99, 5, consent.ErrTemplateNotFound},
+		{"other clinic's template", 4, 5, consent.ErrTemplateNotFound},
+		{"retired template", 3, 5, consent.ErrFormValidation},
+		{"unknown procedure", 1, 99, consent.ErrProcedureNotFound},
+		{"other clinic's procedure", 1, 7, consent.ErrProcedureNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.PrepareForm(context.Background(), consent.Form{ClinicID: 1, PatientID: 7, TemplateID: tt.templateID, ProcedureID: tt.procedureID})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("PrepareForm() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignForm(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature func(t *testing.T) string
+		wantType  string
+	}{
+		{"png", pngSignature, "image/png"},
+		{"svg", func(t *testing.T) string { return svgSignature() }, "image/svg+xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, renderer, attachments := newTestService()
+			form := prepare(t, s, 1, 5)
+
+			signed, err := s.SignForm(context.Background(), form.ID, consent.SignRequest{SignerName: " Ayşe Yılmaz ", Signature: tt.signature(t)}, 3, "203.0.113.7")
+			if err != nil {
+				t.Fatalf("SignForm() error = %v", err)
+			}
+			if signed.Status != consent.StatusSigned || signed.SignerName != "Ayşe Yılmaz" || signed.SignatureType != tt.wantType ||
+				signed.SignedIP != "203.0.113.7" || signed.WitnessID == nil || *signed.WitnessID != 3 ||
+				signed.SignedAt == nil || !signed.SignedAt.Equal(signedAt) {
+				t.Errorf("signed form = %+v", signed)
+			}
+			if signed.ContentHash == "" || signed.ContentHash != signed.Hash() {
+				t.Errorf("content hash = %q, want %q", signed.ContentHash, signed.Hash())
+			}
+			if signed.AttachmentID == nil || signed.DocumentHash != "pdf-sha256" {
+				t.Errorf("signed PDF is not recorded: %+v", signed)
+			}
+
+			stored := attachments.attachments[*signed.AttachmentID]
+			if stored.Category != attachment.CategoryConsent || stored.PatientID != 7 {
+				t.Errorf("attachment = %+v", stored)
+			}
+			if len(renderer.signatures) != 1 {
+				t.Fatalf("rendered %d documents, want 1", len(renderer.signatures))
+			}
+			if _, err := png.Decode(bytes.NewReader(renderer.signatures[0])); err != nil {
+				t.Errorf("signature on the PDF is not a PNG: %v", err)
+			}
+			if renderer.forms[0].ContentHash != signed.ContentHash {
+				t.Error("PDF shows another content hash than the form")
+			}
+
+			link, err := s.DocumentLink(context.Background(), signed)
+			if err != nil || link.URL == "" {
+				t.Errorf("DocumentLink() = %+v, %v", link, err)
+			}
+		})
+	}
+}
+
+func TestSignForm_Rejected(t *testing.T) {
+	s, repo, _, attachments := newTestService()
+	form := prepare(t, s, 1, 5)
+
+	tests := []struct {
+		name string
+		req  consent.SignRequest
+		want error
+	}{
+		{"no signer", consent.SignRequest{Signature: pngSignature(t)}, consent.ErrFormValidation},
+		{"no signature", consent.SignRequest{SignerName: "Ayşe Yılmaz"}, consent.ErrFormValidation},
+		{"not a data URL", consent.SignRequest{SignerName: "Ayşe Yılmaz", Signature: "signature.png"}, consent.ErrFormValidation},
+		{"unsupported type", consent.SignRequest{SignerName: "Ayşe Yılmaz", Signature: "data:image/gif;base64,R0lGODlh"}, consent.ErrFormValidation},
+		{"broken png", consent.SignRequest{SignerName: "Ayşe Yılmaz", Signature: "data:image/png;base64,aGVsbG8="}, consent.ErrFormValidation},
+		{"blank svg", consent.SignRequest{SignerName: "Ayşe Yılmaz",
+			Signature: "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(`<svg width="100" height="50"></svg>`))}, consent.ErrFormValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.SignForm(context.Background(), form.ID, tt.req, 3, "203.0.113.7"); !errors.Is(err, tt.want) {
+				t.Errorf("SignForm() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	req := consent.SignRequest{SignerName: "Ayşe Yılmaz", Signature: pngSignature(t)}
+	if _, err := s.SignForm(context.Background(), 99, req, 3, ""); !errors.Is(err, consent.ErrFormNotFound) {
+		t.Errorf("SignForm() error = %v, want %v", err, consent.ErrFormNotFound)
+	}
+
+	// A PDF stored for a signature that could not be recorded is removed again
+	repo.signErr = errors.New("database is down")
+	if _, err := s.SignForm(context.Background(), form.ID, req, 3, ""); err == nil {
+		t.Fatal("SignForm() succeeded while the form could not be stored")
+	}
+	if len(attachments.deleted) != 1 {
+		t.Errorf("deleted attachments = %v, want the uploaded PDF", attachments.deleted)
+	}
+	repo.signErr = nil
+
+	if _, err := s.SignForm(context.Background(), form.ID, req, 3, ""); err != nil {
+		t.Fatalf("SignForm() error = %v", err)
+	}
+	if _, err := s.SignForm(context.Background(), form.ID, req, 3, ""); !errors.Is(err, consent.ErrFormSigned) {
+		t.Errorf("second SignForm() error = %v, want %v", err, consent.ErrFormSigned)
+	}
+	if err := s.DeleteForm(context.Background(), form.ID); !errors.Is(err, consent.ErrFormSigned) {
+		t.Errorf("DeleteForm() error = %v, want %v", err, consent.ErrFormSigned)
+	}
+}
+
+func TestDocumentLink_Unsigned(t *testing.T) {
+	s, _, _, _ := newTestService()
+	form := prepare(t, s, 1, 5)
+
+	if _, err := s.DocumentLink(context.Background(), form); !errors.Is(err, consent.ErrFormNotSigned) {
+		t.Errorf("DocumentLink() error = %v, want %v", err, consent.ErrFormNotSigned)
+	}
+}
+
+func TestCheckConsents(t *testing.T) {
+	s, _, _, _ := newTestService()
+	ctx := context.Background()
+
+	err := s.CheckConsents(ctx, 1, 7, 5)
+	var missing *consent.MissingConsentError
+	if !errors.As(err, &missing) || !errors.Is(err, consent.ErrConsentRequired) {
+		t.Fatalf("CheckConsents() error = %v, want a MissingConsentError", err)
+	}
+	// The retired template is no longer required
+	if len(missing.Missing) != 2 || missing.Missing[0].TemplateID != 1 || missing.Missing[1].TemplateID != 2 {
+		t.Errorf("missing consents = %+v", missing.Missing)
+	}
+
+	req := consent.SignRequest{SignerName: "Ayşe Yılmaz", Signature: pngSignature(t)}
+	extraction := prepare(t, s, 1, 5)
+	if _, err := s.SignForm(ctx, extraction.ID, req, 3, ""); err != nil {
+		t.Fatalf("SignForm() error = %v", err)
+	}
+	// A form signed for another procedure does not count
+	anaesthesia := prepare(t, s, 2, 6)
+	if _, err := s.SignForm(ctx, anaesthesia.ID, req, 3, ""); err != nil {
+		t.Fatalf("SignForm() error = %v", err)
+	}
+
+	requirements, err := s.RequiredConsents(ctx, 1, 7, 5)
+	if err != nil {
+		t.Fatalf("RequiredConsents() error = %v", err)
+	}
+	if len(requirements) != 2 || !requirements[0].Signed || requirements[0].FormID == nil ||
+		*requirements[0].FormID != extraction.ID || requirements[1].Signed {
+		t.Errorf("requirements = %+v", requirements)
+	}
+	if err := s.CheckConsents(ctx, 1, 7, 5); !errors.As(err, &missing) || len(missing.Missing) != 1 {
+		t.Errorf("CheckConsents() error = %v, want the anaesthesia consent missing", err)
+	}
+	if err := s.CheckConsents(ctx, 1, 7, 6); err != nil {
+		t.Errorf("CheckConsents() error = %v, want nil", err)
+	}
+
+	// Procedures without consent templates can always be started
+	if err := s.CheckConsents(ctx, 1, 7, 7); err != nil {
+		t.Errorf("CheckConsents() error = %v, want nil", err)
+	}
+}
+
+func TestCreateTemplate(t *testing.T) {
+	s, _, _, _ := newTestService()
+
+	created, err := s.CreateTemplate(context.Background(), consent.Template{
+		ClinicID:   1,
+		Name:       "Implant",
+		Title:      "İmplant Onam Formu",
+		Body:       "Metin.",
+		Procedures: []consent.TemplateProcedure{{ProcedureID: 5}, {ProcedureID: 5}},
+	}, 3)
+	if err != nil {
+		t.Fatalf("CreateTemplate() error = %v", err)
+	}
+	if created.Version != 1 || len(created.Procedures) != 1 {
+		t.Errorf("template = %+v", created)
+	}
+
+	_, err = s.CreateTemplate(context.Background(), consent.Template{
+		ClinicID: 1, Name: "Implant", Title: "İmplant", Body: "Metin.",
+		Procedures: []consent.TemplateProcedure{{ProcedureID: 7}},
+	}, 3)
+	if !errors.Is(err, consent.ErrProcedureNotFound) {
+		t.Errorf("CreateTemplate() error = %v, want %v", err, consent.ErrProcedureNotFound)
+	}
+
+	if _, err := s.CreateTemplate(context.Background(), consent.Template{ClinicID: 1, Name: "Empty"}, 3); !errors.Is(err, consent.ErrTemplateValidation) {
+		t.Errorf("CreateTemplate() error = %v, want %v", err, consent.ErrTemplateValidation)
+	}
+}
@@ -0,0 +1,683 @@
+package documentService
+
+import (
+	"bytes"
+	"context"
+	"dental-clinic-system/infrastructure/pdf"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/document"
+	"dental-clinic-system/models/odontogram"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Templates of the documents, rendered with the shared header and footer in layout.html
+const (
+	layoutTemplate        = "layout.html"
+	invoiceTemplate       = "invoice.html"
+	treatmentPlanTemplate = "treatment_plan.html"
+	visitSummaryTemplate  = "visit_summary.html"
+	consentTemplate       = "consent.html"
+)
+
+// Display layouts of dates printed on documents
+const (
+	displayDate     = "02.01.2006"
+	displayTime     = "15:04"
+	displayDateTime = "02.01.2006 15:04"
+)
+
+// InvoiceRepository is used to read invoices with their lines
+type InvoiceRepository interface {
+	GetInvoice(ctx context.Context, id uint) (billing.Invoice, error)
+}
+
+// PlanRepository is used to read treatment plans with their phases and items
+type PlanRepository interface {
+	GetPlan(ctx context.Context, id uint) (treatment.Plan, error)
+}
+
+// AppointmentRepository is used to read appointments with their patient and doctor
+type AppointmentRepository interface {
+	GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error)
+}
+
+// ClinicRepository is used to read the clinic name, address and logo printed on documents
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// PatientRepository is used to read the patient an invoice or plan is for
+type PatientRepository interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// UserRepository is used to read the doctor of a treatment plan
+type UserRepository interface {
+	GetUser(ctx context.Context, id uint) (user.User, error)
+}
+
+// FindingRepository is used to read the chart findings recorded during a visit
+type FindingRepository interface {
+	GetAppointmentFindings(ctx context.Context, appointmentID uint) ([]odontogram.Finding, error)
+}
+
+// DocumentProducer publishes documents to be emailed as attachments
+type DocumentProducer interface {
+	SendDocument(email string, data map[string]string, fileName, contentType string, content []byte) error
+}
+
+// DocumentService renders patient documents as PDF files and emails them
+type DocumentService struct {
+	invoiceRepository     InvoiceRepository
+	planRepository        PlanRepository
+	appointmentRepository AppointmentRepository
+	clinicRepository      ClinicRepository
+	patientRepository     PatientRepository
+	userRepository        UserRepository
+	findingRepository     FindingRepository
+	producer              DocumentProducer
+	templateDir           string
+	now                   func() time.Time
+}
+
+// NewDocumentService creates a new instance of DocumentService reading its templates from templateDir
+func NewDocumentService(invoiceRepo InvoiceRepository, planRepo PlanRepository, appointmentRepo AppointmentRepository, clinicRepo ClinicRepository,
+	patientRepo PatientRepository, userRepo UserRepository, findingRepo FindingRepository, producer DocumentProducer, templateDir string) *DocumentService {
+	return &DocumentService{
+		invoiceRepository:     invoiceRepo,
+		planRepository:        planRepo,
+		appointmentRepository: appointmentRepo,
+		clinicRepository:      clinicRepo,
+		patientRepository:     patientRepo,
+		userRepository:        userRepo,
+		findingRepository:     findingRepo,
+		producer:              producer,
+		templateDir:           templateDir,
+		now:                   time.Now,
+	}
+}
+
+// clinicView is the letterhead printed at the top of every document
+type clinicView struct {
+	Name      string
+	Address   string
+	Phone     string
+	Email     string
+	TaxOffice string
+	TaxNumber string
+	HasLogo   bool
+}
+
+type invoiceView struct {
+	Clinic            clinicView
+	Printed           string
+	Number            string
+	IssueDate         string
+	Status            string
+	Void              bool
+	PatientName       string
+	PatientNationalID string
+	Lines             []invoiceLineView
+	Subtotal          string
+	Discount          string
+	VAT               string
+	Total             string
+	Paid              string
+	Balance           string
+	InsurerShare      string
+	PatientShare      string
+	PatientBalance    string
+	Notes             string
+}
+
+type invoiceLineView struct {
+	Position    int
+	Code        string
+	Description string
+	ServiceDate string
+	Quantity    int
+	UnitPrice   string
+	Discount    string
+	VATRate     int
+	Total       string
+}
+
+type planView struct {
+	Clinic      clinicView
+	Printed     string
+	Title       string
+	Date        string
+	Status      string
+	DoctorName  string
+	PatientName string
+	Phases      []planPhaseView
+	Total       string
+	Notes       string
+}
+
+type planPhaseView struct {
+	Position int
+	Name     string
+	Items    []planItemView
+	Total    string
+}
+
+type planItemView struct {
+	Procedure   string
+	Code        string
+	Tooth       string
+	Description string
+	Price       string
+}
+
+type visitView struct {
+	Clinic      clinicView
+	Printed     string
+	Date        string
+	Time        string
+	DoctorName  string
+	PatientName string
+	Treatment   string
+	Notes       string
+	Findings    []findingView
+}
+
+type consentView struct {
+	Clinic            clinicView
+	Printed           string
+	Title             string
+	PatientName       string
+	PatientNationalID string
+	Procedure         string
+	Version           int
+	Paragraphs        []string
+	SignerName        string
+	SignedAt          string
+	SignedIP          string
+	WitnessName       string
+	HasSignature      bool
+	ContentHash       string
+}
+
+type findingView struct {
+	Tooth     int
+	Condition string
+	Surfaces  string
+	Notes     string
+}
+
+// invoiceStatusLabels are the Turkish names of invoice statuses
+var invoiceStatusLabels = map[billing.InvoiceStatus]string{
+	billing.InvoiceIssued:        "Düzenlendi",
+	billing.InvoicePartiallyPaid: "Kısmen ödendi",
+	billing.InvoicePaid:          "Ödendi",
+	billing.InvoiceVoid:          "İptal edildi",
+}
+
+// planStatusLabels are the Turkish names of treatment plan statuses
+var planStatusLabels = map[treatment.PlanStatus]string{
+	treatment.PlanDraft:      "Taslak",
+	treatment.PlanPresented:  "Sunuldu",
+	treatment.PlanAccepted:   "Kabul edildi",
+	treatment.PlanDeclined:   "Reddedildi",
+	treatment.PlanInProgress: "Devam ediyor",
+	treatment.PlanCompleted:  "Tamamlandı",
+}
+
+// conditionLabels are the Turkish names of chart conditions
+var conditionLabels = map[odontogram.Condition]string{
+	odontogram.ConditionCaries:    "Çürük",
+	odontogram.ConditionFilling:   "Dolgu",
+	odontogram.ConditionCrown:     "Kron",
+	odontogram.ConditionImplant:   "İmplant",
+	odontogram.ConditionMissing:   "Eksik diş",
+	odontogram.ConditionRootCanal: "Kanal tedavisi",
+}
+
+// InvoicePDF renders an invoice
+func (s *DocumentService) InvoicePDF(ctx context.Context, invoiceID uint) (document.Document, error) {
+	invoice, err := s.invoiceRepository.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	invoice.Resolve()
+
+	cln, err := s.clinicRepository.GetClinic(ctx, invoice.ClinicID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	pt, err := s.patientRepository.GetPatient(ctx, invoice.PatientID)
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	view := invoiceView{
+		Clinic:            letterhead(cln),
+		Printed:           s.now().In(cln.Location()).Format(displayDateTime),
+		Number:            invoice.Number,
+		IssueDate:         displayDay(invoice.IssueDate),
+		Status:            invoiceStatusLabels[invoice.Status],
+		Void:              invoice.Status == billing.InvoiceVoid,
+		PatientName:       pt.Name,
+		PatientNationalID: pt.NationalID,
+		Subtotal:          billing.FormatAmount(invoice.Subtotal, invoice.Currency),
+		Discount:          optionalAmount(invoice.DiscountTotal, invoice.Currency),
+		VAT:               billing.FormatAmount(invoice.VATTotal, invoice.Currency),
+		Total:             billing.FormatAmount(invoice.Total, invoice.Currency),
+		Paid:              billing.FormatAmount(invoice.PaidAmount, invoice.Currency),
+		Balance:           billing.FormatAmount(invoice.Balance, invoice.Currency),
+		InsurerShare:      optionalAmount(invoice.InsurerShare, invoice.Currency),
+		PatientShare:      billing.FormatAmount(invoice.PatientShare, invoice.Currency),
+		PatientBalance:    billing.FormatAmount(invoice.PatientBalance, invoice.Currency),
+		Notes:             invoice.Notes,
+	}
+	for _, line := range invoice.Lines {
+		view.Lines = append(view.Lines, invoiceLineView{
+			Position:    line.Position,
+			Code:        line.Code,
+			Description: line.Description,
+			ServiceDate: displayDay(line.ServiceDate),
+			Quantity:    line.Quantity,
+			UnitPrice:   billing.FormatAmount(line.UnitPrice, invoice.Currency),
+			Discount:    optionalAmount(line.DiscountAmount, invoice.Currency),
+			VATRate:     line.VATRate,
+			Total:       billing.FormatAmount(line.Total, invoice.Currency),
+		})
+	}
+
+	title := "Fatura " + invoice.Number
+	content, err := s.render(invoiceTemplate, view, cln, title, nil)
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	return document.Document{
+		Kind:        document.KindInvoice,
+		ClinicID:    invoice.ClinicID,
+		ClinicName:  cln.Name,
+		PatientName: pt.Name,
+		Title:       title,
+		FileName:    fmt.Sprintf("fatura-%s.pdf", fileNamePart(invoice.Number)),
+		ContentType: document.ContentTypePDF,
+		Content:     content,
+		Recipient:   pt.Email(),
+	}, nil
+}
+
+// TreatmentPlanPDF renders the cost estimate of a treatment plan
+func (s *DocumentService) TreatmentPlanPDF(ctx context.Context, planID uint) (document.Document, error) {
+	plan, err := s.planRepository.GetPlan(ctx, planID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	plan.Resolve()
+
+	cln, err := s.clinicRepository.GetClinic(ctx, plan.ClinicID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	pt, err := s.patientRepository.GetPatient(ctx, plan.PatientID)
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	var doctorName string
+	if plan.DoctorID != 0 {
+		doctor, err := s.userRepository.GetUser(ctx, plan.DoctorID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return document.Document{}, err
+		}
+		doctorName = fullName(doctor.FirstName, doctor.LastName)
+	}
+
+	date := plan.CreatedAt
+	if plan.PresentedAt != nil {
+		date = *plan.PresentedAt
+	}
+	view := planView{
+		Clinic:      letterhead(cln),
+		Printed:     s.now().In(cln.Location()).Format(displayDateTime),
+		Title:       plan.Title,
+		Date:        date.In(cln.Location()).Format(displayDate),
+		Status:      planStatusLabels[plan.Status],
+		DoctorName:  doctorName,
+		PatientName: pt.Name,
+		Total:       billing.FormatAmount(plan.EstimatedTotal, plan.Currency),
+		Notes:       plan.Notes,
+	}
+	for i, phase := range plan.Phases {
+		phaseView := planPhaseView{
+			Position: i + 1,
+			Name:     phase.Name,
+			Total:    billing.FormatAmount(phase.EstimatedTotal, plan.Currency),
+		}
+		for _, item := range phase.Items {
+			phaseView.Items = append(phaseView.Items, planItemView{
+				Procedure:   item.Procedure.Name,
+				Code:        item.Procedure.Code,
+				Tooth:       toothLabel(item.Tooth, item.Surfaces),
+				Description: item.Description,
+				Price:       billing.FormatAmount(item.EstimatedPrice, plan.Currency),
+			})
+		}
+		view.Phases = append(view.Phases, phaseView)
+	}
+
+	title := "Tedavi Planı Tahmini - " + plan.Title
+	content, err := s.render(treatmentPlanTemplate, view, cln, title, nil)
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	return document.Document{
+		Kind:        document.KindTreatmentPlan,
+		ClinicID:    plan.ClinicID,
+		ClinicName:  cln.Name,
+		PatientName: pt.Name,
+		Title:       title,
+		FileName:    fmt.Sprintf("tedavi-plani-%d.pdf", plan.ID),
+		ContentType: document.ContentTypePDF,
+		Content:     content,
+		Recipient:   pt.Email(),
+	}, nil
+}
+
+// VisitSummaryPDF renders the summary of an appointment with the findings charted during it
+func (s *DocumentService) VisitSummaryPDF(ctx context.Context, appointmentID uint) (document.Document, error) {
+	appt, err := s.appointmentRepository.GetAppointment(ctx, appointmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return document.Document{}, document.ErrAppointmentNotFound
+	}
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	cln, err := s.clinicRepository.GetClinic(ctx, appt.ClinicID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	findings, err := s.findingRepository.GetAppointmentFindings(ctx, appt.ID)
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	loc := cln.Location()
+	patientName := strings.TrimSpace(appt.Patient.Name)
+	view := visitView{
+		Clinic:      letterhead(cln),
+		Printed:     s.now().In(loc).Format(displayDateTime),
+		Date:        appt.ScheduledTime.In(loc).Format(displayDate),
+		Time:        appt.ScheduledTime.In(loc).Format(displayTime),
+		DoctorName:  fullName(appt.Doctor.FirstName, appt.Doctor.LastName),
+		PatientName: patientName,
+		Treatment:   appt.Treatment,
+		Notes:       appt.Notes,
+	}
+	for _, finding := range findings {
+		label, ok := conditionLabels[finding.Condition]
+		if !ok {
+			label = string(finding.Condition)
+		}
+		view.Findings = append(view.Findings, findingView{
+			Tooth:     finding.Tooth,
+			Condition: label,
+			Surfaces:  finding.Surfaces,
+			Notes:     finding.Notes,
+		})
+	}
+
+	title := "Muayene Özeti - " + view.Date
+	content, err := s.render(visitSummaryTemplate, view, cln, title, nil)
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	return document.Document{
+		Kind:        document.KindVisitSummary,
+		ClinicID:    appt.ClinicID,
+		ClinicName:  cln.Name,
+		PatientName: patientName,
+		Title:       title,
+		FileName:    fmt.Sprintf("muayene-ozeti-%d.pdf", appt.ID),
+		ContentType: document.ContentTypePDF,
+		Content:     content,
+		Recipient:   appt.Patient.Email(),
+	}, nil
+}
+
+// ConsentPDF renders a signed consent form with the signature drawn on it. The signature must be a PNG image.
+func (s *DocumentService) ConsentPDF(ctx context.Context, form consent.Form, signaturePNG []byte) (document.Document, error) {
+	cln, err := s.clinicRepository.GetClinic(ctx, form.ClinicID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	pt, err := s.patientRepository.GetPatient(ctx, form.PatientID)
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	var witnessName string
+	if form.WitnessID != nil {
+		witness, err := s.userRepository.GetUser(ctx, *form.WitnessID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return document.Document{}, err
+		}
+		witnessName = fullName(witness.FirstName, witness.LastName)
+	}
+
+	var signedAt string
+	if form.SignedAt != nil {
+		signedAt = form.SignedAt.In(cln.Location()).Format(displayDateTime)
+	}
+	view := consentView{
+		Clinic:            letterhead(cln),
+		Printed:           s.now().In(cln.Location()).Format(displayDateTime),
+		Title:             form.Title,
+		PatientName:       pt.Name,
+		PatientNationalID: pt.NationalID,
+		Procedure:         form.ProcedureName,
+		Version:           form.Version,
+		Paragraphs:        paragraphs(form.Body),
+		SignerName:        form.SignerName,
+		SignedAt:          signedAt,
+		SignedIP:          form.SignedIP,
+		WitnessName:       witnessName,
+		HasSignature:      len(signaturePNG) > 0,
+		ContentHash:       form.ContentHash,
+	}
+
+	content, err := s.render(consentTemplate, view, cln, form.Title, map[string][]byte{"signature": signaturePNG})
+	if err != nil {
+		return document.Document{}, err
+	}
+
+	return document.Document{
+		Kind:        document.KindConsent,
+		ClinicID:    form.ClinicID,
+		ClinicName:  cln.Name,
+		PatientName: pt.Name,
+		Title:       form.Title,
+		FileName:    fmt.Sprintf("onam-formu-%d.pdf", form.ID),
+		ContentType: document.ContentTypePDF,
+		Content:     content,
+		Recipient:   pt.Email(),
+	}, nil
+}
+
+// EmailDocument sends a rendered document as an email attachment, to the given address or to the
+// patient's address on file, and returns the address it was sent to
+func (s *DocumentService) EmailDocument(ctx context.Context, doc document.Document, to string) (string, error) {
+	recipient := strings.TrimSpace(to)
+	if recipient == "" {
+		recipient = doc.Recipient
+	}
+	if recipient == "" {
+		return "", document.ErrNoRecipient
+	}
+	if _, err := mail.ParseAddress(recipient); err != nil {
+		return "", fmt.Errorf("%w: %s", document.ErrInvalidRecipient, recipient)
+	}
+	if len(doc.Content) > document.MaxAttachmentBytes {
+		return "", document.ErrAttachmentTooBig
+	}
+
+	data := map[string]string{
+		"patient_name":   doc.PatientName,
+		"clinic_name":    doc.ClinicName,
+		"document_title": doc.Title,
+	}
+	if cln, err := s.clinicRepository.GetClinic(ctx, doc.ClinicID); err == nil {
+		data["clinic_phone"] = cln.PhoneNumber
+	}
+
+	if err := s.producer.SendDocument(recipient, data, doc.FileName, doc.ContentType, doc.Content); err != nil {
+		log.Error().
+			Str("operation", "EmailDocument").
+			Err(err).
+			Str("kind", string(doc.Kind)).
+			Uint("clinic_id", doc.ClinicID).
+			Msg("Failed to publish document email")
+		return "", err
+	}
+
+	log.Info().
+		Str("operation", "EmailDocument").
+		Str("kind", string(doc.Kind)).
+		Uint("clinic_id", doc.ClinicID).
+		Int("size", len(doc.Content)).
+		Msg("Document email published")
+
+	return recipient, nil
+}
+
+// render executes a document template and lays the resulting HTML out as a PDF. Images other than the
+// clinic logo are given by name. Templates are parsed on every call, like the email templates, so they
+// can be edited without a rebuild.
+func (s *DocumentService) render(file string, view interface{}, cln clinic.Clinic, title string, images map[string][]byte) ([]byte, error) {
+	tmpl, err := template.ParseFiles(filepath.Join(s.templateDir, layoutTemplate), filepath.Join(s.templateDir, file))
+	if err != nil {
+		log.Error().
+			Str("operation", "render").
+			Err(err).
+			Str("template", file).
+			Msg("Failed to parse document template")
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&html, file, view); err != nil {
+		log.Error().
+			Str("operation", "render").
+			Err(err).
+			Str("template", file).
+			Msg("Failed to execute document template")
+		return nil, err
+	}
+
+	if images == nil {
+		images = map[string][]byte{}
+	}
+	if cln.HasLogo() {
+		images["logo"] = cln.Logo
+	}
+	content, err := pdf.Render(html.Bytes(), pdf.Options{Title: title, Images: images, CreatedAt: s.now()})
+	if err != nil {
+		log.Error().
+			Str("operation", "render").
+			Err(err).
+			Str("template", file).
+			Uint("clinic_id", cln.ID).
+			Msg("Failed to render document")
+		return nil, err
+	}
+	return content, nil
+}
+
+func letterhead(cln clinic.Clinic) clinicView {
+	return clinicView{
+		Name:      cln.Name,
+		Address:   strings.Join(nonEmpty(cln.Address, strings.Join(nonEmpty(cln.District, cln.City), " / ")), ", "),
+		Phone:     cln.PhoneNumber,
+		Email:     cln.Email,
+		TaxOffice: cln.TaxOffice,
+		TaxNumber: cln.TaxNumber,
+		HasLogo:   cln.HasLogo(),
+	}
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func fullName(first, last string) string {
+	return strings.TrimSpace(first + " " + last)
+}
+
+// displayDay converts a YYYY-MM-DD date to the day.month.year form used in Turkey
+func displayDay(date string) string {
+	if d, err := time.Parse(billing.DateLayout, date); err == nil {
+		return d.Format(displayDate)
+	}
+	return date
+}
+
+// paragraphs splits text into the paragraphs separated by blank lines
+func paragraphs(text string) []string {
+	var result []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			result = append(result, paragraph)
+		}
+	}
+	return result
+}
+
+// optionalAmount formats an amount, or returns an empty string for zero so templates can leave it out
+func optionalAmount(minor int64, currency string) string {
+	if minor == 0 {
+		return ""
+	}
+	return billing.FormatAmount(minor, currency)
+}
+
+// toothLabel prints an FDI tooth number with its surfaces; items without a tooth apply to the whole mouth
+func toothLabel(tooth *int, surfaces string) string {
+	if tooth == nil {
+		return "Tüm ağız"
+	}
+	if surfaces != "" {
+		return fmt.Sprintf("%d (%s)", *tooth, surfaces)
+	}
+	return fmt.Sprintf("%d", *tooth)
+}
+
+// fileNamePart keeps the characters of an invoice number that are safe in a file name
+func fileNamePart(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, value)
+}
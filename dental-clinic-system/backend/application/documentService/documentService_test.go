@@ -0,0 +1,589 @@
+package documentService
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/document"
+	"dental-clinic-system/models/odontogram"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+	"gorm.io/gorm"
+)
+
+const testTemplateDir = "../../templates/documents"
+
+type fakeInvoiceRepository struct {
+	invoices map[uint]billing.Invoice
+}
+
+func (r *fakeInvoiceRepository) GetInvoice(ctx context.Context, id uint) (billing.Invoice, error) {
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return billing.Invoice{}, billing.ErrInvoiceNotFound
+	}
+	return invoice, nil
+}
+
+type fakePlanRepository struct {
+	plans map[uint]treatment.Plan
+}
+
+func (r *fakePlanRepository) GetPlan(ctx context.Context, id uint) (treatment.Plan, error) {
+	plan, ok := r.plans[id]
+	if !ok {
+		return treatment.Plan{}, treatment.ErrPlanNotFound
+	}
+	return plan, nil
+}
+
+type fakeAppointmentRepository struct {
+	appointments map[uint]appointment.Appointment
+}
+
+func (r *fakeAppointmentRepository) GetAppointment(ctx context.Context, id uint) (appointment.Appointment, error) {
+	appt, ok := r.appointments[id]
+	if !ok {
+		return appointment.Appointment{}, gorm.ErrRecordNotFound
+	}
+	return appt, nil
+}
+
+type fakeClinicRepository struct {
+	clinic clinic.Clinic
+}
+
+func (r *fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	if id != r.clinic.ID {
+		return clinic.Clinic{}, clinic.ErrClinicNotFound
+	}
+	return r.clinic, nil
+}
+
+type fakePatientRepository struct {
+	patients map[uint]patient.Patient
+}
+
+func (r *fakePatientRepository) GetPatient(ctx context.Context, id uint) (patient.Patient, error) {
+	pat, ok := r.patients[id]
+	if !ok {
+		return patient.Patient{}, gorm.ErrRecordNotFound
+	}
+	return pat, nil
+}
+
+type fakeUserRepository struct {
+	users map[uint]user.User
+}
+
+func (r *fakeUserRepository) GetUser(ctx context.Context, id uint) (user.User, error) {
+	usr, ok := r.users[id]
+	if !ok {
+		return user.User{}, gorm.ErrRecordNotFound
+	}
+	return usr, nil
+}
+
+type fakeFindingRepository struct {
+	findings []odontogram.Finding
+}
+
+func (r *fakeFindingRepository) GetAppointmentFindings(ctx context.Context, appointmentID uint) ([]odontogram.Finding, error) {
+	var findings []odontogram.Finding
+	for _, finding := range r.findings {
+		if finding.AppointmentID != nil && *finding.AppointmentID == appointmentID {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+type sentDocument struct {
+	to       string
+	data     map[string]string
+	fileName string
+	content  []byte
+}
+
+type fakeProducer struct {
+	sent []sentDocument
+	err  error
+}
+
+func (p *fakeProducer) SendDocument(email string, data map[string]string, fileName, contentType string, content []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.sent = append(p.sent, sentDocument{to: email, data: data, fileName: fileName, content: content})
+	return nil
+}
+
+func testClinic() clinic.Clinic {
+	cln := clinic.Clinic{
+		Name:        "Gülüş Ağız ve Diş Sağlığı",
+		Address:     "Bağdat Cad. No:12",
+		PhoneNumber: "2161234567",
+		Email:       "info@gulus.example",
+		TaxNumber:   "1234567890",
+		TaxOffice:   "Kadıköy",
+		District:    "Kadıköy",
+		City:        "İstanbul",
+	}
+	cln.ID = 1
+	return cln
+}
+
+func testLogo(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 120, 40))); err != nil {
+		t.Fatalf("failed to encode logo: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func testInvoice(lines int) billing.Invoice {
+	invoice := billing.Invoice{
+		ClinicID:   1,
+		PatientID:  7,
+		Number:     "2026/000042",
+		IssueDate:  "2026-03-05",
+		Currency:   "TRY",
+		Status:     billing.InvoicePartiallyPaid,
+		PaidAmount: 50000,
+		Notes:      "Kontrol randevusu iki hafta sonra.",
+	}
+	invoice.ID = 42
+	for i := 0; i < lines; i++ {
+		invoice.Lines = append(invoice.Lines, billing.Line{
+			Code:            "D2391",
+			Description:     fmt.Sprintf("Kompozit dolgu %d", i+1),
+			ServiceDate:     "2026-03-05",
+			Quantity:        1,
+			UnitPrice:       125000,
+			DiscountPercent: 10,
+			VATRate:         10,
+		})
+	}
+	invoice.Calculate()
+	return invoice
+}
+
+// insuredInvoice is testInvoice with 1.500,00 TRY of its 2.475,00 TRY claimed from the patient's insurer
+func insuredInvoice() billing.Invoice {
+	invoice := testInvoice(2)
+	invoice.ID = 44
+	invoice.InsurerShare = 150000
+	return invoice
+}
+
+func testPlan() treatment.Plan {
+	tooth := 36
+	plan := treatment.Plan{
+		ClinicID:  1,
+		PatientID: 7,
+		DoctorID:  3,
+		Title:     "Protetik rehabilitasyon",
+		Currency:  "TRY",
+		Status:    treatment.PlanPresented,
+		Phases: []treatment.Phase{
+			{Name: "Acil tedavi", Items: []treatment.Item{
+				{Procedure: procedure.Procedure{Name: "Kanal tedavisi", Code: "D3330"}, Tooth: &tooth, EstimatedPrice: 400000},
+			}},
+			{Name: "Hijyen", Items: []treatment.Item{
+				{Procedure: procedure.Procedure{Name: "Diş taşı temizliği"}, EstimatedPrice: 150000},
+			}},
+		},
+	}
+	plan.ID = 5
+	plan.CreatedAt = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	return plan
+}
+
+func testAppointment() appointment.Appointment {
+	appt := appointment.Appointment{
+		ClinicID:      1,
+		PatientID:     20,
+		Patient:       patient.Patient{Name: "Ayşe Yılmaz", ContactInfo: "ayse@example.com"},
+		DoctorID:      3,
+		Doctor:        user.User{FirstName: "Işıl", LastName: "Şahin"},
+		ScheduledTime: time.Date(2026, 3, 5, 7, 30, 0, 0, time.UTC),
+		Status:        appointment.StatusCompleted,
+		Treatment:     "Muayene ve dolgu",
+		Notes:         "Diş ipi kullanımı önerildi.",
+	}
+	appt.ID = 9
+	return appt
+}
+
+func newTestService(cln clinic.Clinic, producer *fakeProducer) *DocumentService {
+	apptID := uint(9)
+	s := NewDocumentService(
+		&fakeInvoiceRepository{invoices: map[uint]billing.Invoice{42: testInvoice(2), 43: testInvoice(60), 44: insuredInvoice()}},
+		&fakePlanRepository{plans: map[uint]treatment.Plan{5: testPlan()}},
+		&fakeAppointmentRepository{appointments: map[uint]appointment.Appointment{9: testAppointment()}},
+		&fakeClinicRepository{clinic: cln},
+		&fakePatientRepository{patients: map[uint]patient.Patient{
+			7: {NationalID: "10000000146", Name: "Ayşe Yılmaz", ContactInfo: "ayse@example.com", ClinicID: 1},
+		}},
+		&fakeUserRepository{users: map[uint]user.User{3: {FirstName: "Işıl", LastName: "Şahin"}}},
+		&fakeFindingRepository{findings: []odontogram.Finding{
+			{Tooth: 16, Condition: odontogram.ConditionCaries, Surfaces: "MO", AppointmentID: &apptID},
+			{Tooth: 36, Condition: odontogram.ConditionRootCanal, AppointmentID: &apptID},
+		}},
+		producer,
+		testTemplateDir,
+	)
+	s.now = func() time.Time { return time.Date(2026, 3, 6, 10, 0, 0, 0, time.UTC) }
+	return s
+}
+
+var (
+	objectPattern  = regexp.MustCompile(`(?s)(\d+) 0 obj\n<<(.*?)>>\nstream\n`)
+	xrefPattern    = regexp.MustCompile(`(?s)xref\n0 (\d+)\n(.*?)trailer`)
+	startxrefRegex = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+)
+
+// pdfText checks the file structure and returns the text drawn on each page, decoded from Windows-1254
+func pdfText(t *testing.T, data []byte) []string {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing PDF header")
+	}
+
+	match := startxrefRegex.FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("missing startxref trailer")
+	}
+	xrefOffset, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(data[xrefOffset:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	xref := xrefPattern.FindSubmatch(data[xrefOffset:])
+	for i, entry := range strings.Split(strings.TrimSpace(string(xref[2])), "\n")[1:] {
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", i+1, data[offset:offset+10])
+		}
+	}
+
+	var pages []string
+	for _, m := range objectPattern.FindAllSubmatchIndex(data, -1) {
+		dict := string(data[m[4]:m[5]])
+		if strings.Contains(dict, "/Subtype /Image") {
+			continue
+		}
+		length, _ := strconv.Atoi(regexp.MustCompile(`/Length (\d+)`).FindStringSubmatch(dict)[1])
+		zr, err := zlib.NewReader(bytes.NewReader(data[m[1] : m[1]+length]))
+		if err != nil {
+			t.Fatalf("content stream is not compressed: %v", err)
+		}
+		raw, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("failed to decompress content stream: %v", err)
+		}
+		text, _ := charmap.Windows1254.NewDecoder().Bytes(raw)
+		pages = append(pages, string(text))
+	}
+	return pages
+}
+
+func assertContains(t *testing.T, page string, texts ...string) {
+	t.Helper()
+	for _, text := range texts {
+		if !strings.Contains(page, "("+text+")") && !strings.Contains(page, text) {
+			t.Errorf("page does not contain %q", text)
+		}
+	}
+}
+
+func TestInvoicePDF(t *testing.T) {
+	s := newTestService(testClinic(), &fakeProducer{})
+
+	doc, err := s.InvoicePDF(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("InvoicePDF() error = %v", err)
+	}
+	if doc.FileName != "fatura-2026-000042.pdf" || doc.ContentType != document.ContentTypePDF {
+		t.Errorf("file = %s %s", doc.FileName, doc.ContentType)
+	}
+	if doc.ClinicID != 1 || doc.Recipient != "ayse@example.com" || doc.PatientName != "Ayşe Yılmaz" {
+		t.Errorf("document = %+v", doc)
+	}
+
+	pages := pdfText(t, doc.Content)
+	if len(pages) != 1 {
+		t.Fatalf("pages = %d, want 1", len(pages))
+	}
+	assertContains(t, pages[0],
+		"Gülüş Ağız ve Diş Sağlığı",
+		"Bağdat Cad. No:12, Kadıköy / İstanbul",
+		"T.C. Kimlik No: 10000000146",
+		"Ayşe Yılmaz",
+		"2026/000042",
+		"05.03.2026",
+		"Kısmen ödendi",
+		"Kompozit dolgu 2",
+		"1.250,00 TRY",
+		"2.475,00 TRY",
+		"1.975,00 TRY",
+		"Kontrol randevusu iki hafta sonra.",
+		"Sayfa 1 / 1",
+	)
+	if strings.Contains(string(doc.Content), "/Subtype /Image") {
+		t.Error("document without a clinic logo contains an image")
+	}
+	if strings.Contains(pages[0], "Sigorta Pay") {
+		t.Error("invoice without an insurance claim shows an insurer share")
+	}
+}
+
+func TestInvoicePDF_InsuranceShare(t *testing.T) {
+	s := newTestService(testClinic(), &fakeProducer{})
+
+	doc, err := s.InvoicePDF(context.Background(), 44)
+	if err != nil {
+		t.Fatalf("InvoicePDF() error = %v", err)
+	}
+	pages := pdfText(t, doc.Content)
+	assertContains(t, pages[0],
+		"Sigorta Payı", "1.500,00 TRY",
+		"Hasta Payı", "975,00 TRY",
+		"Hasta Kalanı", "475,00 TRY",
+	)
+}
+
+func TestInvoicePDF_Logo(t *testing.T) {
+	cln := testClinic()
+	cln.Logo = testLogo(t)
+	cln.LogoContentType = "image/png"
+	s := newTestService(cln, &fakeProducer{})
+
+	doc, err := s.InvoicePDF(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("InvoicePDF() error = %v", err)
+	}
+	if !bytes.Contains(doc.Content, []byte("/Subtype /Image /Width 120 /Height 40")) {
+		t.Error("logo image is not embedded")
+	}
+	pages := pdfText(t, doc.Content)
+	assertContains(t, pages[0], "/Im1 Do")
+}
+
+func TestInvoicePDF_PageBreaks(t *testing.T) {
+	s := newTestService(testClinic(), &fakeProducer{})
+
+	doc, err := s.InvoicePDF(context.Background(), 43)
+	if err != nil {
+		t.Fatalf("InvoicePDF() error = %v", err)
+	}
+	pages := pdfText(t, doc.Content)
+	if len(pages) < 2 {
+		t.Fatalf("pages = %d, want the lines to continue on a second page", len(pages))
+	}
+	// The table header is repeated at the top of the page the lines continue on
+	assertContains(t, pages[1], "Açıklama", "Birim Fiyat", "Kompozit dolgu")
+	assertContains(t, pages[0], fmt.Sprintf("Sayfa 1 / %d", len(pages)))
+	assertContains(t, pages[len(pages)-1], fmt.Sprintf("Sayfa %d / %d", len(pages), len(pages)))
+	if !strings.HasPrefix(pages[1], "0.930 g") {
+		t.Errorf("second page does not start with the table header")
+	}
+}
+
+func TestInvoicePDF_NotFound(t *testing.T) {
+	s := newTestService(testClinic(), &fakeProducer{})
+
+	if _, err := s.InvoicePDF(context.Background(), 99); !errors.Is(err, billing.ErrInvoiceNotFound) {
+		t.Errorf("InvoicePDF() error = %v, want %v", err, billing.ErrInvoiceNotFound)
+	}
+}
+
+func TestTreatmentPlanPDF(t *testing.T) {
+	s := newTestService(testClinic(), &fakeProducer{})
+
+	doc, err := s.TreatmentPlanPDF(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("TreatmentPlanPDF() error = %v", err)
+	}
+	if doc.FileName != "tedavi-plani-5.pdf" || doc.Title != "Tedavi Planı Tahmini - Protetik rehabilitasyon" {
+		t.Errorf("document = %s %q", doc.FileName, doc.Title)
+	}
+
+	pages := pdfText(t, doc.Content)
+	assertContains(t, pages[0],
+		"Tedavi Planı ve Fiyat Tahmini",
+		"Işıl Şahin",
+		"Sunuldu",
+		"1. Acil tedavi",
+		"2. Hijyen",
+		"Kanal tedavisi",
+		"Tüm ağız",
+		"4.000,00 TRY",
+		"5.500,00 TRY",
+	)
+	if !strings.Contains(string(doc.Content), "<FEFF") {
+		t.Error("document title is not stored as a text string")
+	}
+
+	if _, err := s.TreatmentPlanPDF(context.Background(), 99); !errors.Is(err, treatment.ErrPlanNotFound) {
+		t.Errorf("TreatmentPlanPDF() error = %v, want %v", err, treatment.ErrPlanNotFound)
+	}
+}
+
+func TestVisitSummaryPDF(t *testing.T) {
+	s := newTestService(testClinic(), &fakeProducer{})
+
+	doc, err := s.VisitSummaryPDF(context.Background(), 9)
+	if err != nil {
+		t.Fatalf("VisitSummaryPDF() error = %v", err)
+	}
+	if doc.Recipient != "ayse@example.com" || doc.FileName != "muayene-ozeti-9.pdf" {
+		t.Errorf("document = %+v", doc)
+	}
+
+	pages := pdfText(t, doc.Content)
+	// The appointment is at 07:30 UTC, which is 10:30 in the clinic's time zone
+	assertContains(t, pages[0],
+		"Muayene Özeti",
+		"05.03.2026",
+		"10:30",
+		"Işıl Şahin",
+		"Muayene ve dolgu",
+		"Çürük",
+		"Kanal tedavisi",
+		"Diş ipi kullanımı önerildi.",
+	)
+
+	if _, err := s.VisitSummaryPDF(context.Background(), 99); !errors.Is(err, document.ErrAppointmentNotFound) {
+		t.Errorf("VisitSummaryPDF() error = %v, want %v", err, document.ErrAppointmentNotFound)
+	}
+}
+
+func TestConsentPDF(t *testing.T) {
+	s := newTestService(testClinic(), &fakeProducer{})
+
+	signedAt := time.Date(2026, 3, 5, 7, 30, 0, 0, time.UTC)
+	witnessID := uint(3)
+	form := consent.Form{
+		ClinicID:      1,
+		PatientID:     7,
+		ProcedureName: "Diş çekimi",
+		Version:       2,
+		Title:         "Diş Çekimi Onam Formu",
+		Body:          "İşlemin riskleri anlatıldı.\n\nSorularım yanıtlandı.",
+		SignerName:    "Ayşe Yılmaz",
+		SignedAt:      &signedAt,
+		SignedIP:      "203.0.113.7",
+		WitnessID:     &witnessID,
+		ContentHash:   "abc123",
+	}
+	form.ID = 4
+
+	doc, err := s.ConsentPDF(context.Background(), form, testLogo(t))
+	if err != nil {
+		t.Fatalf("ConsentPDF() error = %v", err)
+	}
+	if doc.Kind != document.KindConsent || doc.FileName != "onam-formu-4.pdf" {
+		t.Errorf("document = %+v", doc)
+	}
+	if !bytes.Contains(doc.Content, []byte("/Subtype /Image /Width 120 /Height 40")) {
+		t.Error("signature image is not embedded")
+	}
+
+	pages := pdfText(t, doc.Content)
+	// The form was signed at 07:30 UTC, which is 10:30 in the clinic's time zone
+	assertContains(t, pages[0],
+		"Diş Çekimi Onam Formu",
+		"10000000146",
+		"Diş çekimi",
+		"İşlemin riskleri anlatıldı.",
+		"Sorularım yanıtlandı.",
+		"05.03.2026 10:30",
+		"203.0.113.7",
+		"Işıl Şahin",
+		"abc123",
+	)
+}
+
+func TestEmailDocument(t *testing.T) {
+	doc := document.Document{
+		Kind:        document.KindInvoice,
+		ClinicID:    1,
+		ClinicName:  "Gülüş",
+		PatientName: "Ayşe Yılmaz",
+		Title:       "Fatura 2026/000042",
+		FileName:    "fatura-2026-000042.pdf",
+		ContentType: document.ContentTypePDF,
+		Content:     []byte("%PDF-1.4"),
+		Recipient:   "ayse@example.com",
+	}
+	noRecipient := doc
+	noRecipient.Recipient = ""
+	tooBig := doc
+	tooBig.Content = make([]byte, document.MaxAttachmentBytes+1)
+
+	tests := []struct {
+		name        string
+		doc         document.Document
+		to          string
+		producerErr error
+		wantTo      string
+		wantErr     error
+	}{
+		{name: "Address on file", doc: doc, wantTo: "ayse@example.com"},
+		{name: "Address in request", doc: doc, to: " muhasebe@example.com ", wantTo: "muhasebe@example.com"},
+		{name: "No address", doc: noRecipient, wantErr: document.ErrNoRecipient},
+		{name: "Invalid address", doc: doc, to: "not-an-email", wantErr: document.ErrInvalidRecipient},
+		{name: "Too large", doc: tooBig, wantErr: document.ErrAttachmentTooBig},
+		{name: "Producer failure", doc: doc, producerErr: errors.New("broker down"), wantErr: errors.New("broker down")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &fakeProducer{err: tt.producerErr}
+			s := newTestService(testClinic(), producer)
+
+			to, err := s.EmailDocument(context.Background(), tt.doc, tt.to)
+			if tt.wantErr != nil {
+				if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+					t.Fatalf("EmailDocument() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(producer.sent) != 0 {
+					t.Errorf("document was published despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EmailDocument() error = %v", err)
+			}
+			if to != tt.wantTo || len(producer.sent) != 1 {
+				t.Fatalf("sent to %q, %d messages", to, len(producer.sent))
+			}
+			sent := producer.sent[0]
+			if sent.to != tt.wantTo || sent.fileName != "fatura-2026-000042.pdf" || !bytes.Equal(sent.content, doc.Content) {
+				t.Errorf("sent = %+v", sent)
+			}
+			if sent.data["document_title"] != "Fatura 2026/000042" || sent.data["clinic_phone"] != "2161234567" {
+				t.Errorf("data = %v", sent.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package eInvoiceService
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/einvoice"
+	"dental-clinic-system/models/patient"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaxExportDays limits how many days one batch export can cover
+const MaxExportDays = 366
+
+// InvoiceRepository defines the invoice lookups needed to build e-invoices
+type InvoiceRepository interface {
+	GetInvoice(ctx context.Context, id uint) (billing.Invoice, error)
+	GetInvoicesIssuedBetween(ctx context.Context, clinicID uint, from, to string) ([]billing.Invoice, error)
+}
+
+// ClinicRepository is used to read the issuing clinic's tax details
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// PatientRepository is used to read the invoiced patient's TCKN and name
+type PatientRepository interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+}
+
+// SubmissionRepository keeps track of the invoices sent to the integrator
+type SubmissionRepository interface {
+	GetSubmission(ctx context.Context, invoiceID uint) (einvoice.Submission, error)
+	ClaimSubmission(ctx context.Context, submission einvoice.Submission) (einvoice.Submission, error)
+	CompleteSubmission(ctx context.Context, submission einvoice.Submission) (einvoice.Submission, error)
+	ReleaseSubmission(ctx context.Context, id uint) error
+}
+
+// Submitter sends documents to a GİB integrator
+type Submitter interface {
+	Submit(ctx context.Context, doc einvoice.Document) (einvoice.SubmissionResult, error)
+}
+
+// EInvoiceService exports invoices as UBL-TR e-invoices and submits them to the integrator
+type EInvoiceService struct {
+	invoiceRepository    InvoiceRepository
+	clinicRepository     ClinicRepository
+	patientRepository    PatientRepository
+	submissionRepository SubmissionRepository
+	submitter            Submitter
+	now                  func() time.Time
+}
+
+// NewEInvoiceService creates a new instance of EInvoiceService
+func NewEInvoiceService(invoiceRepo InvoiceRepository, clinicRepo ClinicRepository, patientRepo PatientRepository, submissionRepo SubmissionRepository, submitter Submitter) *EInvoiceService {
+	return &EInvoiceService{
+		invoiceRepository:    invoiceRepo,
+		clinicRepository:     clinicRepo,
+		patientRepository:    patientRepo,
+		submissionRepository: submissionRepo,
+		submitter:            submitter,
+		now:                  time.Now,
+	}
+}
+
+// ExportInvoice builds the UBL-TR document of an invoice
+func (s *EInvoiceService) ExportInvoice(ctx context.Context, invoiceID uint) (einvoice.Document, error) {
+	invoice, err := s.invoiceRepository.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return einvoice.Document{}, err
+	}
+	cln, err := s.clinicRepository.GetClinic(ctx, invoice.ClinicID)
+	if err != nil {
+		return einvoice.Document{}, err
+	}
+	return s.build(ctx, invoice, cln)
+}
+
+// ExportRange builds the documents of every live invoice a clinic issued between two dates
+// inclusive and packs them into a zip archive
+func (s *EInvoiceService) ExportRange(ctx context.Context, clinicID uint, from, to string) ([]byte, int, error) {
+	start, err1 := time.Parse(billing.DateLayout, from)
+	end, err2 := time.Parse(billing.DateLayout, to)
+	switch {
+	case err1 != nil || err2 != nil:
+		return nil, 0, fmt.Errorf("%w: from and to must be in YYYY-MM-DD format", einvoice.ErrInvalidRange)
+	case end.Before(start):
+		return nil, 0, fmt.Errorf("%w: to can not be before from", einvoice.ErrInvalidRange)
+	case end.Sub(start) > MaxExportDays*24*time.Hour:
+		return nil, 0, fmt.Errorf("%w: a batch can cover at most %d days", einvoice.ErrInvalidRange, MaxExportDays)
+	}
+
+	cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+	if err != nil {
+		return nil, 0, err
+	}
+	invoices, err := s.invoiceRepository.GetInvoicesIssuedBetween(ctx, clinicID, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, invoice := range invoices {
+		doc, err := s.build(ctx, invoice, cln)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invoice %s: %w", invoice.Number, err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     doc.FileName(),
+			Method:   zip.Deflate,
+			Modified: s.now(),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := w.Write(doc.XML); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	log.Info().
+		Str("operation", "ExportRange").
+		Uint("clinic_id", clinicID).
+		Str("from", from).
+		Str("to", to).
+		Int("count", len(invoices)).
+		Msg("E-invoices exported")
+
+	return archive.Bytes(), len(invoices), nil
+}
+
+// GetSubmission retrieves the integrator submission of an invoice
+func (s *EInvoiceService) GetSubmission(ctx context.Context, invoiceID uint) (einvoice.Submission, error) {
+	return s.submissionRepository.GetSubmission(ctx, invoiceID)
+}
+
+// SubmitInvoice sends the e-invoice of an invoice to the integrator once. The submission is claimed
+// before sending and released again if the integrator can not be reached.
+func (s *EInvoiceService) SubmitInvoice(ctx context.Context, invoiceID, submittedByID uint) (einvoice.Submission, error) {
+	doc, err := s.ExportInvoice(ctx, invoiceID)
+	if err != nil {
+		return einvoice.Submission{}, err
+	}
+
+	submission, err := s.submissionRepository.ClaimSubmission(ctx, einvoice.Submission{
+		InvoiceID:     doc.InvoiceID,
+		ClinicID:      doc.ClinicID,
+		DocumentID:    doc.ID,
+		UUID:          doc.UUID,
+		Profile:       doc.Profile,
+		SubmittedAt:   s.now(),
+		SubmittedByID: submittedByID,
+	})
+	if err != nil {
+		return einvoice.Submission{}, err
+	}
+
+	result, err := s.submitter.Submit(ctx, doc)
+	if err != nil {
+		log.Error().
+			Str("operation", "SubmitInvoice").
+			Err(err).
+			Uint("invoice_id", invoiceID).
+			Msg("Failed to submit e-invoice")
+		if releaseErr := s.submissionRepository.ReleaseSubmission(ctx, submission.ID); releaseErr != nil {
+			return einvoice.Submission{}, releaseErr
+		}
+		return einvoice.Submission{}, err
+	}
+
+	submission.Status = result.Status
+	submission.Reference = result.Reference
+	submission.Message = result.Message
+	return s.submissionRepository.CompleteSubmission(ctx, submission)
+}
+
+func (s *EInvoiceService) build(ctx context.Context, invoice billing.Invoice, cln clinic.Clinic) (einvoice.Document, error) {
+	pat, err := s.patientRepository.GetPatient(ctx, invoice.PatientID)
+	if err != nil {
+		return einvoice.Document{}, err
+	}
+	return BuildDocument(invoice, cln, pat)
+}
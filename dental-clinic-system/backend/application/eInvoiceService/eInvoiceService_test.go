@@ -0,0 +1,477 @@
+package eInvoiceService
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/einvoice"
+	"dental-clinic-system/models/patient"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type fakeInvoiceRepository struct {
+	invoices map[uint]billing.Invoice
+}
+
+func (r *fakeInvoiceRepository) GetInvoice(ctx context.Context, id uint) (billing.Invoice, error) {
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return billing.Invoice{}, billing.ErrInvoiceNotFound
+	}
+	return invoice, nil
+}
+
+func (r *fakeInvoiceRepository) GetInvoicesIssuedBetween(ctx context.Context, clinicID uint, from, to string) ([]billing.Invoice, error) {
+	var invoices []billing.Invoice
+	for id := uint(1); id <= uint(len(r.invoices)); id++ {
+		invoice := r.invoices[id]
+		if invoice.ClinicID == clinicID && invoice.Status != billing.InvoiceVoid && invoice.IssueDate >= from && invoice.IssueDate <= to {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+type fakeClinicRepository struct {
+	clinic clinic.Clinic
+}
+
+func (r *fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	if id != r.clinic.ID {
+		return clinic.Clinic{}, gorm.ErrRecordNotFound
+	}
+	return r.clinic, nil
+}
+
+type fakePatientRepository struct {
+	patients map[uint]patient.Patient
+}
+
+func (r *fakePatientRepository) GetPatient(ctx context.Context, id uint) (patient.Patient, error) {
+	pat, ok := r.patients[id]
+	if !ok {
+		return patient.Patient{}, gorm.ErrRecordNotFound
+	}
+	return pat, nil
+}
+
+type fakeSubmissionRepository struct {
+	submissions map[uint]einvoice.Submission
+	nextID      uint
+}
+
+func (r *fakeSubmissionRepository) GetSubmission(ctx context.Context, invoiceID uint) (einvoice.Submission, error) {
+	submission, ok := r.submissions[invoiceID]
+	if !ok {
+		return einvoice.Submission{}, einvoice.ErrSubmissionNotFound
+	}
+	return submission, nil
+}
+
+func (r *fakeSubmissionRepository) ClaimSubmission(ctx context.Context, submission einvoice.Submission) (einvoice.Submission, error) {
+	if existing, ok := r.submissions[submission.InvoiceID]; ok && existing.Status != einvoice.SubmissionRejected {
+		return einvoice.Submission{}, einvoice.ErrAlreadySubmitted
+	}
+	r.nextID++
+	submission.ID = r.nextID
+	submission.Status = einvoice.SubmissionPending
+	r.submissions[submission.InvoiceID] = submission
+	return submission, nil
+}
+
+func (r *fakeSubmissionRepository) CompleteSubmission(ctx context.Context, submission einvoice.Submission) (einvoice.Submission, error) {
+	r.submissions[submission.InvoiceID] = submission
+	return submission, nil
+}
+
+func (r *fakeSubmissionRepository) ReleaseSubmission(ctx context.Context, id uint) error {
+	for invoiceID, submission := range r.submissions {
+		if submission.ID == id {
+			delete(r.submissions, invoiceID)
+		}
+	}
+	return nil
+}
+
+type fakeSubmitter struct {
+	result einvoice.SubmissionResult
+	err    error
+	sent   []string
+}
+
+func (s *fakeSubmitter) Submit(ctx context.Context, doc einvoice.Document) (einvoice.SubmissionResult, error) {
+	if s.err != nil {
+		return einvoice.SubmissionResult{}, s.err
+	}
+	s.sent = append(s.sent, doc.ID)
+	return s.result, nil
+}
+
+func testClinic() clinic.Clinic {
+	cln := clinic.Clinic{
+		Name:        "Gülüş Ağız ve Diş Sağlığı",
+		Address:     "Bağdat Cad. No:12",
+		PhoneNumber: "02161234567",
+		Email:       "info@gulus.example",
+		TaxNumber:   "1234567890",
+		TaxOffice:   "Kadıköy",
+		District:    "Kadıköy",
+		City:        "İstanbul",
+	}
+	cln.ID = 1
+	return cln
+}
+
+// testInvoice has a discounted 20% line and a 0% line
+func testInvoice(id uint, number, issueDate string) billing.Invoice {
+	invoice := billing.Invoice{
+		ClinicID:      1,
+		PatientID:     7,
+		Number:        number,
+		IssueDate:     issueDate,
+		Currency:      "TRY",
+		Status:        billing.InvoiceIssued,
+		Subtotal:      350000,
+		DiscountTotal: 25000,
+		NetTotal:      325000,
+		VATTotal:      45000,
+		Total:         370000,
+		Lines: []billing.Line{
+			{Position: 1, Code: "D2740", Description: "Zirkonyum kron", Quantity: 1, UnitPrice: 250000,
+				DiscountPercent: 10, DiscountAmount: 25000, VATRate: 20, NetAmount: 225000, VATAmount: 45000, Total: 270000},
+			{Position: 2, Code: "D1110", Description: "Diş taşı temizliği", Quantity: 2, UnitPrice: 50000,
+				VATRate: 0, NetAmount: 100000, VATAmount: 0, Total: 100000},
+		},
+	}
+	invoice.ID = id
+	return invoice
+}
+
+func newTestEInvoiceService() (*EInvoiceService, *fakeInvoiceRepository, *fakeSubmissionRepository, *fakeSubmitter) {
+	invoices := &fakeInvoiceRepository{invoices: map[uint]billing.Invoice{
+		1: testInvoice(1, "2026-000001", "2026-03-02"),
+		2: testInvoice(2, "2026-000002", "2026-03-15"),
+		3: testInvoice(3, "2026-000003", "2026-04-01"),
+	}}
+	patients := &fakePatientRepository{patients: map[uint]patient.Patient{
+		7: {NationalID: "10000000146", Name: "Ayşe Nur Yılmaz", ClinicID: 1},
+	}}
+	submissions := &fakeSubmissionRepository{submissions: map[uint]einvoice.Submission{}}
+	submitter := &fakeSubmitter{result: einvoice.SubmissionResult{Status: einvoice.SubmissionAccepted, Reference: "REF-1"}}
+	s := NewEInvoiceService(invoices, &fakeClinicRepository{clinic: testClinic()}, patients, submissions, submitter)
+	s.now = func() time.Time { return time.Date(2026, 4, 10, 9, 0, 0, 0, time.UTC) }
+	return s, invoices, submissions, submitter
+}
+
+// parsedInvoice reads back the parts of a UBL document the tests check, by namespace
+type parsedInvoice struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:specification:ubl:schema:xsd:Invoice-2 Invoice"`
+	CustomizationID string   `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CustomizationID"`
+	ProfileID       string   `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ProfileID"`
+	ID              string   `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	UUID            string   `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 UUID"`
+	Customer        struct {
+		ID struct {
+			SchemeID string `xml:"schemeID,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"Party>PartyIdentification>ID"`
+		First  string `xml:"Party>Person>FirstName"`
+		Family string `xml:"Party>Person>FamilyName"`
+	} `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AccountingCustomerParty"`
+	TaxTotal struct {
+		TaxAmount string `xml:"TaxAmount"`
+		Subtotals []struct {
+			Taxable   string `xml:"TaxableAmount"`
+			Tax       string `xml:"TaxAmount"`
+			Percent   int    `xml:"Percent"`
+			Exemption string `xml:"TaxCategory>TaxExemptionReasonCode"`
+		} `xml:"TaxSubtotal"`
+	} `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxTotal"`
+	Totals struct {
+		LineExtension string `xml:"LineExtensionAmount"`
+		TaxInclusive  string `xml:"TaxInclusiveAmount"`
+		Allowance     string `xml:"AllowanceTotalAmount"`
+		Payable       string `xml:"PayableAmount"`
+	} `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 LegalMonetaryTotal"`
+	Lines []struct {
+		ID        int    `xml:"ID"`
+		Net       string `xml:"LineExtensionAmount"`
+		Allowance string `xml:"AllowanceCharge>Amount"`
+		Price     string `xml:"Price>PriceAmount"`
+	} `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoiceLine"`
+}
+
+func TestBuildDocument(t *testing.T) {
+	s, _, _, _ := newTestEInvoiceService()
+	doc, err := s.ExportInvoice(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("ExportInvoice() error = %v", err)
+	}
+
+	var parsed parsedInvoice
+	if err := xml.Unmarshal(doc.XML, &parsed); err != nil {
+		t.Fatalf("document is not well formed: %v", err)
+	}
+
+	if doc.ID != "DNT2026000000001" || parsed.ID != doc.ID {
+		t.Errorf("ID = %q / %q, want DNT2026000000001", doc.ID, parsed.ID)
+	}
+	if parsed.CustomizationID != "TR1.2" || parsed.ProfileID != einvoice.ProfileEArchive {
+		t.Errorf("customization/profile = %q/%q", parsed.CustomizationID, parsed.ProfileID)
+	}
+	if parsed.UUID != doc.UUID || doc.UUID != DocumentUUID(1, "2026-000001") {
+		t.Errorf("UUID = %q, want the stable ETTN of the invoice", parsed.UUID)
+	}
+	again, _ := s.ExportInvoice(context.Background(), 1)
+	if !bytes.Equal(again.XML, doc.XML) {
+		t.Error("exporting the same invoice twice produced different documents")
+	}
+
+	if parsed.Customer.ID.Value != "10000000146" || parsed.Customer.ID.SchemeID != "TCKN" {
+		t.Errorf("customer = %+v, want the patient's TCKN", parsed.Customer.ID)
+	}
+	if parsed.Customer.First != "Ayşe Nur" || parsed.Customer.Family != "Yılmaz" {
+		t.Errorf("customer name = %q %q", parsed.Customer.First, parsed.Customer.Family)
+	}
+
+	if parsed.TaxTotal.TaxAmount != "450.00" || len(parsed.TaxTotal.Subtotals) != 2 {
+		t.Fatalf("tax total = %q with %d subtotals, want 450.00 with 2", parsed.TaxTotal.TaxAmount, len(parsed.TaxTotal.Subtotals))
+	}
+	zero, twenty := parsed.TaxTotal.Subtotals[0], parsed.TaxTotal.Subtotals[1]
+	if zero.Percent != 0 || zero.Taxable != "1000.00" || zero.Tax != "0.00" || zero.Exemption != "351" {
+		t.Errorf("0%% subtotal = %+v", zero)
+	}
+	if twenty.Percent != 20 || twenty.Taxable != "2250.00" || twenty.Tax != "450.00" || twenty.Exemption != "" {
+		t.Errorf("20%% subtotal = %+v", twenty)
+	}
+
+	totals := parsed.Totals
+	if totals.LineExtension != "3250.00" || totals.TaxInclusive != "3700.00" || totals.Allowance != "250.00" || totals.Payable != "3700.00" {
+		t.Errorf("monetary totals = %+v", totals)
+	}
+	if len(parsed.Lines) != 2 || parsed.Lines[0].Allowance != "250.00" || parsed.Lines[1].Price != "500.00" {
+		t.Errorf("lines = %+v", parsed.Lines)
+	}
+
+	validateSchema(t, doc)
+}
+
+func TestBuildDocumentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		invoice func(billing.Invoice) billing.Invoice
+		clinic  func(clinic.Clinic) clinic.Clinic
+		wantErr error
+	}{
+		{
+			name:    "void invoice",
+			invoice: func(i billing.Invoice) billing.Invoice { i.Status = billing.InvoiceVoid; return i },
+			wantErr: einvoice.ErrInvoiceVoid,
+		},
+		{
+			name:    "clinic without tax number",
+			clinic:  func(c clinic.Clinic) clinic.Clinic { c.TaxNumber = ""; return c },
+			wantErr: einvoice.ErrNotConfigured,
+		},
+		{
+			name:    "clinic without city",
+			clinic:  func(c clinic.Clinic) clinic.Clinic { c.City = ""; return c },
+			wantErr: einvoice.ErrNotConfigured,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice, cln := testInvoice(1, "2026-000001", "2026-03-02"), testClinic()
+			if tt.invoice != nil {
+				invoice = tt.invoice(invoice)
+			}
+			if tt.clinic != nil {
+				cln = tt.clinic(cln)
+			}
+			if _, err := BuildDocument(invoice, cln, patient.Patient{}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("BuildDocument() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildDocumentAnonymousPatient(t *testing.T) {
+	cln := testClinic()
+	cln.EInvoicePrefix = "GLS"
+	doc, err := BuildDocument(testInvoice(1, "2026-000042", "2026-03-02"), cln, patient.Patient{Name: "Mehmet"})
+	if err != nil {
+		t.Fatalf("BuildDocument() error = %v", err)
+	}
+	var parsed parsedInvoice
+	if err := xml.Unmarshal(doc.XML, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != "GLS2026000000042" {
+		t.Errorf("ID = %q, want the clinic's prefix", doc.ID)
+	}
+	if parsed.Customer.ID.Value != einvoice.AnonymousTCKN || parsed.Customer.Family != "-" {
+		t.Errorf("customer = %+v, want the anonymous TCKN", parsed.Customer)
+	}
+	validateSchema(t, doc)
+}
+
+func TestDocumentID(t *testing.T) {
+	tests := []struct {
+		prefix, number, want string
+		wantErr              bool
+	}{
+		{"", "2026-000042", "DNT2026000000042", false},
+		{"ABC", "2025-123456", "ABC2025000123456", false},
+		{"", "42", "", true},
+		{"", "2026-000000", "", true},
+		{"", "26-000001", "", true},
+	}
+	for _, tt := range tests {
+		got, err := DocumentID(tt.prefix, tt.number)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("DocumentID(%q, %q) = %q, %v; want %q", tt.prefix, tt.number, got, err, tt.want)
+		}
+	}
+}
+
+func TestExportRange(t *testing.T) {
+	s, invoices, _, _ := newTestEInvoiceService()
+	voided := invoices.invoices[2]
+	voided.Status = billing.InvoiceVoid
+	invoices.invoices[2] = voided
+
+	archive, count, err := s.ExportRange(context.Background(), 1, "2026-03-01", "2026-03-31")
+	if err != nil {
+		t.Fatalf("ExportRange() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1 (void and out of range invoices are skipped)", count)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("archive is not a zip: %v", err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != "DNT2026000000001.xml" {
+		t.Fatalf("archive entries = %v", zr.File)
+	}
+	f, _ := zr.File[0].Open()
+	content, _ := io.ReadAll(f)
+	f.Close()
+	doc, _ := s.ExportInvoice(context.Background(), 1)
+	if !bytes.Equal(content, doc.XML) {
+		t.Error("archived document differs from the single export")
+	}
+
+	for _, r := range [][2]string{{"2026-03-31", "2026-03-01"}, {"2026-03", "2026-04-01"}, {"2025-01-01", "2026-03-01"}} {
+		if _, _, err := s.ExportRange(context.Background(), 1, r[0], r[1]); !errors.Is(err, einvoice.ErrInvalidRange) {
+			t.Errorf("ExportRange(%s, %s) error = %v, want ErrInvalidRange", r[0], r[1], err)
+		}
+	}
+}
+
+func TestSubmitInvoice(t *testing.T) {
+	s, _, submissions, submitter := newTestEInvoiceService()
+	ctx := context.Background()
+
+	submitter.err = errors.New("integrator unavailable")
+	if _, err := s.SubmitInvoice(ctx, 1, 5); err == nil {
+		t.Fatal("SubmitInvoice() succeeded while the integrator was down")
+	}
+	if _, err := s.GetSubmission(ctx, 1); !errors.Is(err, einvoice.ErrSubmissionNotFound) {
+		t.Fatalf("failed submission was not released: %v", err)
+	}
+
+	submitter.err = nil
+	submission, err := s.SubmitInvoice(ctx, 1, 5)
+	if err != nil {
+		t.Fatalf("SubmitInvoice() error = %v", err)
+	}
+	if submission.Status != einvoice.SubmissionAccepted || submission.Reference != "REF-1" || submission.DocumentID != "DNT2026000000001" || submission.SubmittedByID != 5 {
+		t.Errorf("submission = %+v", submission)
+	}
+	if _, err := s.SubmitInvoice(ctx, 1, 5); !errors.Is(err, einvoice.ErrAlreadySubmitted) {
+		t.Errorf("second SubmitInvoice() error = %v, want ErrAlreadySubmitted", err)
+	}
+	if len(submitter.sent) != 1 {
+		t.Errorf("integrator received %d documents, want 1", len(submitter.sent))
+	}
+
+	// A rejected document can be corrected and sent again
+	submitter.result = einvoice.SubmissionResult{Status: einvoice.SubmissionRejected, Message: "Alıcı TCKN hatalı"}
+	if rejected, err := s.SubmitInvoice(ctx, 2, 5); err != nil || rejected.Status != einvoice.SubmissionRejected {
+		t.Fatalf("SubmitInvoice() = %+v, %v", rejected, err)
+	}
+	submitter.result = einvoice.SubmissionResult{Status: einvoice.SubmissionAccepted}
+	if resent, err := s.SubmitInvoice(ctx, 2, 5); err != nil || resent.Status != einvoice.SubmissionAccepted {
+		t.Errorf("resubmitting a rejected invoice = %+v, %v", resent, err)
+	}
+	if len(submissions.submissions) != 2 {
+		t.Errorf("submissions = %d, want 2", len(submissions.submissions))
+	}
+}
+
+// schemaDirVariable names the environment variable pointing at the UBL-TR 1.2 schema package, unzipped
+// and unmodified. Without it the package is looked for in testdata/ubl-tr-1.2. UBL-TR 1.2 uses the
+// OASIS UBL 2.1 schemas as they are, so either the GİB package or the OASIS one can be used.
+const schemaDirVariable = "UBLTR_SCHEMA_DIR"
+
+// validateSchema checks a document against the UBL-TR 1.2 invoice schema with xmllint. It runs as a
+// subtest that is skipped when xmllint or the schema package is missing, except in CI where it fails.
+func validateSchema(t *testing.T, doc einvoice.Document) {
+	t.Helper()
+	t.Run("UBL-TR 1.2 schema", func(t *testing.T) {
+		missing := t.Skipf
+		if os.Getenv("CI") != "" {
+			missing = t.Fatalf
+		}
+		xmllint, err := exec.LookPath("xmllint")
+		if err != nil {
+			missing("xmllint not found; install libxml2-utils to validate e-invoices")
+		}
+		dir := os.Getenv(schemaDirVariable)
+		if dir == "" {
+			dir = filepath.Join("testdata", "ubl-tr-1.2")
+		}
+		schema := findFile(dir, "UBL-Invoice-2.1.xsd")
+		if schema == "" {
+			missing("UBL-Invoice-2.1.xsd not found in %s; unzip the UBL-TR 1.2 schema package there or set %s", dir, schemaDirVariable)
+		}
+
+		path := filepath.Join(t.TempDir(), doc.FileName())
+		if err := os.WriteFile(path, doc.XML, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(xmllint, "--noout", "--nonet", "--schema", schema, path).CombinedOutput()
+		if err != nil {
+			t.Errorf("document does not validate against UBL-TR 1.2:\n%s", out)
+		}
+	})
+}
+
+// findFile returns the path of the first file with the given name below dir, or "" if there is none
+func findFile(dir, name string) string {
+	var found string
+	_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || found != "" {
+			return filepath.SkipAll
+		}
+		if !entry.IsDir() && entry.Name() == name {
+			found = path
+		}
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,397 @@
+package eInvoiceService
+
+import (
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/einvoice"
+	"dental-clinic-system/models/patient"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	nsInvoice = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	nsCAC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	nsCBC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+
+	// vatTaxTypeCode is the GİB code of KDV
+	vatTaxTypeCode = "0015"
+	// vatExemptionCode is used on 0% lines that are not covered by a specific exemption
+	vatExemptionCode   = "351"
+	vatExemptionReason = "KDV - İstisna Olmayan Diğer"
+	// unitCodePiece is the UN/ECE unit code for "adet"
+	unitCodePiece = "C62"
+	countryName   = "Türkiye"
+)
+
+// documentNamespace seeds the UUIDs of e-invoices, so an invoice keeps its UUID across exports
+var documentNamespace = uuid.NewSHA1(uuid.NameSpaceOID, []byte("dental-clinic-system/e-invoice"))
+
+// The ubl types mirror the UBL-TR 1.2 elements in schema order. Element names carry their
+// namespace prefix so the document is written with the cac and cbc prefixes GİB expects.
+
+type ublInvoice struct {
+	XMLName                     xml.Name               `xml:"Invoice"`
+	Xmlns                       string                 `xml:"xmlns,attr"`
+	XmlnsCAC                    string                 `xml:"xmlns:cac,attr"`
+	XmlnsCBC                    string                 `xml:"xmlns:cbc,attr"`
+	UBLVersionID                string                 `xml:"cbc:UBLVersionID"`
+	CustomizationID             string                 `xml:"cbc:CustomizationID"`
+	ProfileID                   string                 `xml:"cbc:ProfileID"`
+	ID                          string                 `xml:"cbc:ID"`
+	CopyIndicator               bool                   `xml:"cbc:CopyIndicator"`
+	UUID                        string                 `xml:"cbc:UUID"`
+	IssueDate                   string                 `xml:"cbc:IssueDate"`
+	InvoiceTypeCode             string                 `xml:"cbc:InvoiceTypeCode"`
+	Notes                       []string               `xml:"cbc:Note"`
+	DocumentCurrencyCode        string                 `xml:"cbc:DocumentCurrencyCode"`
+	LineCountNumeric            int                    `xml:"cbc:LineCountNumeric"`
+	AdditionalDocumentReference []ublDocumentReference `xml:"cac:AdditionalDocumentReference"`
+	Signature                   ublSignature           `xml:"cac:Signature"`
+	AccountingSupplierParty     ublPartyRole           `xml:"cac:AccountingSupplierParty"`
+	AccountingCustomerParty     ublPartyRole           `xml:"cac:AccountingCustomerParty"`
+	TaxTotal                    ublTaxTotal            `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal          ublMonetaryTotal       `xml:"cac:LegalMonetaryTotal"`
+	InvoiceLines                []ublInvoiceLine       `xml:"cac:InvoiceLine"`
+}
+
+type ublIdentifier struct {
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type ublAmount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type ublQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    int    `xml:",chardata"`
+}
+
+type ublDocumentReference struct {
+	ID               string `xml:"cbc:ID"`
+	IssueDate        string `xml:"cbc:IssueDate"`
+	DocumentTypeCode string `xml:"cbc:DocumentTypeCode"`
+}
+
+type ublSignature struct {
+	ID                         ublIdentifier              `xml:"cbc:ID"`
+	SignatoryParty             ublParty                   `xml:"cac:SignatoryParty"`
+	DigitalSignatureAttachment ublSignatureAttachmentLink `xml:"cac:DigitalSignatureAttachment"`
+}
+
+type ublSignatureAttachmentLink struct {
+	URI string `xml:"cac:ExternalReference>cbc:URI"`
+}
+
+type ublPartyRole struct {
+	Party ublParty `xml:"cac:Party"`
+}
+
+type ublParty struct {
+	PartyIdentification ublIdentifier      `xml:"cac:PartyIdentification>cbc:ID"`
+	PartyName           *ublPartyName      `xml:"cac:PartyName,omitempty"`
+	PostalAddress       ublAddress         `xml:"cac:PostalAddress"`
+	PartyTaxScheme      *ublPartyTaxScheme `xml:"cac:PartyTaxScheme,omitempty"`
+	Contact             *ublContact        `xml:"cac:Contact,omitempty"`
+	Person              *ublPerson         `xml:"cac:Person,omitempty"`
+}
+
+type ublPartyName struct {
+	Name string `xml:"cbc:Name"`
+}
+
+type ublAddress struct {
+	StreetName          string `xml:"cbc:StreetName,omitempty"`
+	CitySubdivisionName string `xml:"cbc:CitySubdivisionName"`
+	CityName            string `xml:"cbc:CityName"`
+	Country             string `xml:"cac:Country>cbc:Name"`
+}
+
+type ublPartyTaxScheme struct {
+	TaxSchemeName string `xml:"cac:TaxScheme>cbc:Name"`
+}
+
+type ublContact struct {
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+type ublPerson struct {
+	FirstName  string `xml:"cbc:FirstName"`
+	FamilyName string `xml:"cbc:FamilyName"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount    ublAmount        `xml:"cbc:TaxAmount"`
+	TaxSubtotals []ublTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount ublAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmount      `xml:"cbc:TaxAmount"`
+	Percent       int            `xml:"cbc:Percent"`
+	TaxCategory   ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxCategory struct {
+	TaxExemptionReasonCode string       `xml:"cbc:TaxExemptionReasonCode,omitempty"`
+	TaxExemptionReason     string       `xml:"cbc:TaxExemptionReason,omitempty"`
+	TaxScheme              ublTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type ublTaxScheme struct {
+	Name        string `xml:"cbc:Name"`
+	TaxTypeCode string `xml:"cbc:TaxTypeCode"`
+}
+
+type ublMonetaryTotal struct {
+	LineExtensionAmount  ublAmount `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount   ublAmount `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount   ublAmount `xml:"cbc:TaxInclusiveAmount"`
+	AllowanceTotalAmount ublAmount `xml:"cbc:AllowanceTotalAmount"`
+	PayableAmount        ublAmount `xml:"cbc:PayableAmount"`
+}
+
+type ublInvoiceLine struct {
+	ID                  int                 `xml:"cbc:ID"`
+	InvoicedQuantity    ublQuantity         `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount ublAmount           `xml:"cbc:LineExtensionAmount"`
+	AllowanceCharge     *ublAllowanceCharge `xml:"cac:AllowanceCharge,omitempty"`
+	TaxTotal            ublTaxTotal         `xml:"cac:TaxTotal"`
+	Item                ublItem             `xml:"cac:Item"`
+	PriceAmount         ublAmount           `xml:"cac:Price>cbc:PriceAmount"`
+}
+
+type ublAllowanceCharge struct {
+	ChargeIndicator         bool      `xml:"cbc:ChargeIndicator"`
+	MultiplierFactorNumeric string    `xml:"cbc:MultiplierFactorNumeric,omitempty"`
+	Amount                  ublAmount `xml:"cbc:Amount"`
+	BaseAmount              ublAmount `xml:"cbc:BaseAmount"`
+}
+
+type ublItem struct {
+	Name                      string                 `xml:"cbc:Name"`
+	SellersItemIdentification *ublItemIdentification `xml:"cac:SellersItemIdentification,omitempty"`
+}
+
+type ublItemIdentification struct {
+	ID string `xml:"cbc:ID"`
+}
+
+// DocumentID turns an invoice number like "2026-000042" into the 16 character GİB document ID,
+// a 3 character prefix, the year and a 9 digit sequence: "DNT2026000000042"
+func DocumentID(prefix, number string) (string, error) {
+	year, sequence, ok := strings.Cut(number, "-")
+	n, err := strconv.Atoi(sequence)
+	if !ok || len(year) != 4 || err != nil || n <= 0 || n > 999999999 {
+		return "", fmt.Errorf("invoice number %q can not be turned into an e-invoice ID", number)
+	}
+	if prefix == "" {
+		prefix = einvoice.DefaultPrefix
+	}
+	return fmt.Sprintf("%s%s%09d", prefix, year, n), nil
+}
+
+// DocumentUUID is the ETTN of an invoice; it is derived from the clinic and invoice number so it never changes
+func DocumentUUID(clinicID uint, number string) string {
+	return uuid.NewSHA1(documentNamespace, []byte(fmt.Sprintf("%d/%s", clinicID, number))).String()
+}
+
+// BuildDocument renders an invoice as a UBL-TR 1.2 e-Arşiv invoice issued by the clinic to the patient
+func BuildDocument(invoice billing.Invoice, cln clinic.Clinic, pat patient.Patient) (einvoice.Document, error) {
+	if invoice.Status == billing.InvoiceVoid {
+		return einvoice.Document{}, einvoice.ErrInvoiceVoid
+	}
+	if cln.TaxNumber == "" || cln.TaxOffice == "" || cln.District == "" || cln.City == "" {
+		return einvoice.Document{}, einvoice.ErrNotConfigured
+	}
+
+	id, err := DocumentID(cln.EInvoicePrefix, invoice.Number)
+	if err != nil {
+		return einvoice.Document{}, err
+	}
+	doc := einvoice.Document{
+		InvoiceID: invoice.ID,
+		ClinicID:  invoice.ClinicID,
+		ID:        id,
+		UUID:      DocumentUUID(invoice.ClinicID, invoice.Number),
+		Profile:   einvoice.ProfileEArchive,
+		IssueDate: invoice.IssueDate,
+	}
+	currency := invoice.Currency
+
+	supplier := ublParty{
+		PartyIdentification: ublIdentifier{SchemeID: taxScheme(cln.TaxNumber), Value: cln.TaxNumber},
+		PartyName:           &ublPartyName{Name: cln.Name},
+		PostalAddress: ublAddress{
+			StreetName:          cln.Address,
+			CitySubdivisionName: cln.District,
+			CityName:            cln.City,
+			Country:             countryName,
+		},
+		PartyTaxScheme: &ublPartyTaxScheme{TaxSchemeName: cln.TaxOffice},
+		Contact:        &ublContact{Telephone: cln.PhoneNumber, ElectronicMail: cln.Email},
+	}
+
+	// Patients have no postal address on file; the clinic's district and city are used instead
+	tckn := strings.TrimSpace(pat.NationalID)
+	if tckn == "" {
+		tckn = einvoice.AnonymousTCKN
+	}
+	firstName, familyName := splitName(pat.Name)
+	customer := ublParty{
+		PartyIdentification: ublIdentifier{SchemeID: "TCKN", Value: tckn},
+		PostalAddress: ublAddress{
+			CitySubdivisionName: cln.District,
+			CityName:            cln.City,
+			Country:             countryName,
+		},
+		Person: &ublPerson{FirstName: firstName, FamilyName: familyName},
+	}
+
+	ubl := ublInvoice{
+		Xmlns:                nsInvoice,
+		XmlnsCAC:             nsCAC,
+		XmlnsCBC:             nsCBC,
+		UBLVersionID:         einvoice.UBLVersionID,
+		CustomizationID:      einvoice.CustomizationID,
+		ProfileID:            doc.Profile,
+		ID:                   doc.ID,
+		UUID:                 doc.UUID,
+		IssueDate:            invoice.IssueDate,
+		InvoiceTypeCode:      einvoice.InvoiceTypeSales,
+		DocumentCurrencyCode: currency,
+		LineCountNumeric:     len(invoice.Lines),
+		AdditionalDocumentReference: []ublDocumentReference{
+			{ID: "ELEKTRONIK", IssueDate: invoice.IssueDate, DocumentTypeCode: "SEND_TYPE"},
+		},
+		Signature: ublSignature{
+			ID: ublIdentifier{SchemeID: "VKN_TCKN", Value: cln.TaxNumber},
+			SignatoryParty: ublParty{
+				PartyIdentification: supplier.PartyIdentification,
+				PostalAddress:       supplier.PostalAddress,
+			},
+			DigitalSignatureAttachment: ublSignatureAttachmentLink{URI: "#Signature_" + doc.ID},
+		},
+		AccountingSupplierParty: ublPartyRole{Party: supplier},
+		AccountingCustomerParty: ublPartyRole{Party: customer},
+		TaxTotal:                taxTotal(invoice.Lines, currency),
+		LegalMonetaryTotal: ublMonetaryTotal{
+			LineExtensionAmount:  amount(invoice.NetTotal, currency),
+			TaxExclusiveAmount:   amount(invoice.NetTotal, currency),
+			TaxInclusiveAmount:   amount(invoice.Total, currency),
+			AllowanceTotalAmount: amount(invoice.DiscountTotal, currency),
+			PayableAmount:        amount(invoice.Total, currency),
+		},
+	}
+	if invoice.Notes != "" {
+		ubl.Notes = append(ubl.Notes, invoice.Notes)
+	}
+	ubl.Notes = append(ubl.Notes, "Fatura No: "+invoice.Number)
+
+	for _, line := range invoice.Lines {
+		ublLine := ublInvoiceLine{
+			ID:                  line.Position,
+			InvoicedQuantity:    ublQuantity{UnitCode: unitCodePiece, Value: line.Quantity},
+			LineExtensionAmount: amount(line.NetAmount, currency),
+			TaxTotal:            taxTotal([]billing.Line{line}, currency),
+			Item:                ublItem{Name: line.Description},
+			PriceAmount:         amount(line.UnitPrice, currency),
+		}
+		if line.Code != "" {
+			ublLine.Item.SellersItemIdentification = &ublItemIdentification{ID: line.Code}
+		}
+		if line.DiscountAmount > 0 {
+			gross := line.UnitPrice * int64(line.Quantity)
+			ublLine.AllowanceCharge = &ublAllowanceCharge{
+				Amount:     amount(line.DiscountAmount, currency),
+				BaseAmount: amount(gross, currency),
+			}
+			if line.DiscountPercent > 0 {
+				ublLine.AllowanceCharge.MultiplierFactorNumeric = strconv.FormatFloat(float64(line.DiscountPercent)/100, 'f', -1, 64)
+			}
+		}
+		ubl.InvoiceLines = append(ubl.InvoiceLines, ublLine)
+	}
+
+	encoded, err := xml.MarshalIndent(ubl, "", "  ")
+	if err != nil {
+		return einvoice.Document{}, err
+	}
+	doc.XML = append([]byte(xml.Header), encoded...)
+	return doc, nil
+}
+
+// taxTotal sums the VAT of the lines with one subtotal per rate
+func taxTotal(lines []billing.Line, currency string) ublTaxTotal {
+	type subtotal struct{ taxable, tax int64 }
+	byRate := map[int]*subtotal{}
+	var rates []int
+	var total int64
+	for _, line := range lines {
+		sub, ok := byRate[line.VATRate]
+		if !ok {
+			sub = &subtotal{}
+			byRate[line.VATRate] = sub
+			rates = append(rates, line.VATRate)
+		}
+		sub.taxable += line.NetAmount
+		sub.tax += line.VATAmount
+		total += line.VATAmount
+	}
+	sort.Ints(rates)
+
+	result := ublTaxTotal{TaxAmount: amount(total, currency)}
+	for _, rate := range rates {
+		category := ublTaxCategory{TaxScheme: ublTaxScheme{Name: "KDV", TaxTypeCode: vatTaxTypeCode}}
+		if rate == 0 {
+			category.TaxExemptionReasonCode = vatExemptionCode
+			category.TaxExemptionReason = vatExemptionReason
+		}
+		result.TaxSubtotals = append(result.TaxSubtotals, ublTaxSubtotal{
+			TaxableAmount: amount(byRate[rate].taxable, currency),
+			TaxAmount:     amount(byRate[rate].tax, currency),
+			Percent:       rate,
+			TaxCategory:   category,
+		})
+	}
+	return result
+}
+
+// amount writes minor units as a decimal amount, e.g. 125050 as "1250.50"
+func amount(minor int64, currency string) ublAmount {
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return ublAmount{CurrencyID: currency, Value: fmt.Sprintf("%s%d.%02d", sign, minor/100, minor%100)}
+}
+
+// taxScheme tells a VKN from the TCKN of a sole proprietor
+func taxScheme(taxNumber string) string {
+	if len(taxNumber) == 11 {
+		return "TCKN"
+	}
+	return "VKN"
+}
+
+// splitName splits a full name into first names and the family name
+func splitName(name string) (string, string) {
+	fields := strings.Fields(name)
+	switch len(fields) {
+	case 0:
+		return "-", "-"
+	case 1:
+		return fields[0], "-"
+	}
+	return strings.Join(fields[:len(fields)-1], " "), fields[len(fields)-1]
+}
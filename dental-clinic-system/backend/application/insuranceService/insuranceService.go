@@ -0,0 +1,320 @@
+package insuranceService
+
+import (
+	"context"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/insurance"
+	"dental-clinic-system/validations"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// InsuranceRepository defines the insurance provider, policy and claim database operations
+type InsuranceRepository interface {
+	GetProviders(ctx context.Context, clinicID uint) ([]insurance.Provider, error)
+	GetProvider(ctx context.Context, id uint) (insurance.Provider, error)
+	CreateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error)
+	UpdateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error)
+	GetPolicies(ctx context.Context, clinicID, patientID uint) ([]insurance.Policy, error)
+	GetPolicy(ctx context.Context, id uint) (insurance.Policy, error)
+	CreatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error)
+	UpdatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error)
+	GetUsage(ctx context.Context, policyID uint, from, to string) (insurance.Usage, error)
+	GetClaims(ctx context.Context, clinicID, patientID uint, status insurance.ClaimStatus) ([]insurance.Claim, error)
+	GetClaim(ctx context.Context, id uint) (insurance.Claim, error)
+	CreateClaim(ctx context.Context, claim insurance.Claim) (insurance.Claim, error)
+	DecideClaim(ctx context.Context, claimID uint, decision insurance.Decision, actorID uint, at time.Time) (insurance.Claim, error)
+	PayClaim(ctx context.Context, claimID uint, payment billing.Payment) (insurance.Claim, error)
+}
+
+// BillingRepository is used to look up the invoices being claimed
+type BillingRepository interface {
+	GetInvoice(ctx context.Context, id uint) (billing.Invoice, error)
+}
+
+// ClinicRepository is used to resolve the time zone of a clinic
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// ClaimRequest asks for an invoice to be claimed under one of the patient's policies
+type ClaimRequest struct {
+	PolicyID  uint   `json:"policy_id"`
+	Reference string `json:"reference"`
+	Notes     string `json:"notes"`
+}
+
+// ClaimPaymentRequest records that the insurer paid an approved claim
+type ClaimPaymentRequest struct {
+	Reference  string     `json:"reference"`
+	Notes      string     `json:"notes"`
+	ReceivedAt *time.Time `json:"received_at"`
+}
+
+// InsuranceService manages insurance providers and patient policies and takes invoices through the claims workflow
+type InsuranceService struct {
+	insuranceRepository InsuranceRepository
+	billingRepository   BillingRepository
+	clinicRepository    ClinicRepository
+	now                 func() time.Time
+}
+
+// NewInsuranceService creates a new instance of InsuranceService
+func NewInsuranceService(insuranceRepo InsuranceRepository, billingRepo BillingRepository, clinicRepo ClinicRepository) *InsuranceService {
+	return &InsuranceService{
+		insuranceRepository: insuranceRepo,
+		billingRepository:   billingRepo,
+		clinicRepository:    clinicRepo,
+		now:                 time.Now,
+	}
+}
+
+// GetProviders retrieves the insurance providers of a clinic
+func (s *InsuranceService) GetProviders(ctx context.Context, clinicID uint) ([]insurance.Provider, error) {
+	return s.insuranceRepository.GetProviders(ctx, clinicID)
+}
+
+// GetProvider retrieves an insurance provider by its ID
+func (s *InsuranceService) GetProvider(ctx context.Context, id uint) (insurance.Provider, error) {
+	return s.insuranceRepository.GetProvider(ctx, id)
+}
+
+// CreateProvider validates and stores a new, active insurance provider
+func (s *InsuranceService) CreateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error) {
+	if err := validations.InsuranceProviderValidation(&provider); err != nil {
+		return insurance.Provider{}, fmt.Errorf("%w: %s", insurance.ErrProviderValidation, err.Error())
+	}
+	provider.Active = true
+	return s.insuranceRepository.CreateProvider(ctx, provider)
+}
+
+// UpdateProvider validates and stores changes to an insurance provider
+func (s *InsuranceService) UpdateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error) {
+	if err := validations.InsuranceProviderValidation(&provider); err != nil {
+		return insurance.Provider{}, fmt.Errorf("%w: %s", insurance.ErrProviderValidation, err.Error())
+	}
+	return s.insuranceRepository.UpdateProvider(ctx, provider)
+}
+
+// GetPolicies retrieves the insurance policies of a patient
+func (s *InsuranceService) GetPolicies(ctx context.Context, clinicID, patientID uint) ([]insurance.Policy, error) {
+	return s.insuranceRepository.GetPolicies(ctx, clinicID, patientID)
+}
+
+// GetPolicy retrieves an insurance policy by its ID
+func (s *InsuranceService) GetPolicy(ctx context.Context, id uint) (insurance.Policy, error) {
+	return s.insuranceRepository.GetPolicy(ctx, id)
+}
+
+// CreatePolicy validates and stores a new, active policy with one of the clinic's active providers
+func (s *InsuranceService) CreatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error) {
+	if err := validations.InsurancePolicyValidation(&policy); err != nil {
+		return insurance.Policy{}, fmt.Errorf("%w: %s", insurance.ErrPolicyValidation, err.Error())
+	}
+
+	provider, err := s.clinicProvider(ctx, policy.ClinicID, policy.ProviderID)
+	if err != nil {
+		return insurance.Policy{}, err
+	}
+	if !provider.Active {
+		return insurance.Policy{}, insurance.ErrProviderInactive
+	}
+
+	policy.Active = true
+	return s.insuranceRepository.CreatePolicy(ctx, policy)
+}
+
+// UpdatePolicy validates and stores changes to a policy. Claims already submitted keep the amounts
+// they were submitted with.
+func (s *InsuranceService) UpdatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error) {
+	if err := validations.InsurancePolicyValidation(&policy); err != nil {
+		return insurance.Policy{}, fmt.Errorf("%w: %s", insurance.ErrPolicyValidation, err.Error())
+	}
+
+	if _, err := s.clinicProvider(ctx, policy.ClinicID, policy.ProviderID); err != nil {
+		return insurance.Policy{}, err
+	}
+
+	return s.insuranceRepository.UpdatePolicy(ctx, policy)
+}
+
+// GetCoverage reports, for each policy of a patient in force on date (YYYY-MM-DD, today in the clinic's
+// time zone when empty), how much of the annual limit is left in that policy year. When amount is given
+// the insurer's and the patient's share of a treatment costing that much are estimated.
+func (s *InsuranceService) GetCoverage(ctx context.Context, clinicID, patientID uint, date string, amount int64) ([]insurance.Coverage, error) {
+	if date == "" {
+		cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+		if err != nil {
+			return nil, err
+		}
+		date = s.now().In(cln.Location()).Format(insurance.DateLayout)
+	}
+	if _, err := time.Parse(insurance.DateLayout, date); err != nil {
+		return nil, fmt.Errorf("%w: date must be in YYYY-MM-DD format", insurance.ErrPolicyValidation)
+	}
+	if amount < 0 {
+		return nil, fmt.Errorf("%w: amount can not be negative", insurance.ErrPolicyValidation)
+	}
+
+	policies, err := s.insuranceRepository.GetPolicies(ctx, clinicID, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	coverages := []insurance.Coverage{}
+	for _, policy := range policies {
+		if !policy.ValidOn(date) {
+			continue
+		}
+
+		yearStart, yearEnd, err := policy.PolicyYear(date)
+		if err != nil {
+			return nil, err
+		}
+		usage, err := s.insuranceRepository.GetUsage(ctx, policy.ID, yearStart, yearEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		coverage := insurance.Coverage{
+			PolicyID:        policy.ID,
+			PolicyNumber:    policy.PolicyNumber,
+			ProviderID:      policy.ProviderID,
+			ProviderName:    policy.Provider.Name,
+			CoveragePercent: policy.CoveragePercent,
+			Currency:        policy.Currency,
+			YearStart:       yearStart,
+			YearEnd:         yearEnd,
+			AnnualLimit:     policy.AnnualLimit,
+			Used:            usage.Used,
+			Pending:         usage.Pending,
+		}
+		if policy.AnnualLimit > 0 {
+			remaining := max(policy.AnnualLimit-usage.Total(), 0)
+			coverage.Remaining = &remaining
+		}
+		if amount > 0 {
+			insurerShare := policy.Share(amount, usage.Total())
+			patientShare := amount - insurerShare
+			coverage.EstimatedInsurerShare = &insurerShare
+			coverage.EstimatedPatientShare = &patientShare
+		}
+		coverages = append(coverages, coverage)
+	}
+
+	return coverages, nil
+}
+
+// GetClaims retrieves the claims of a clinic, optionally for one patient or status
+func (s *InsuranceService) GetClaims(ctx context.Context, clinicID, patientID uint, status insurance.ClaimStatus) ([]insurance.Claim, error) {
+	if status != "" && !status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", insurance.ErrClaimValidation, status)
+	}
+	return s.insuranceRepository.GetClaims(ctx, clinicID, patientID, status)
+}
+
+// GetClaim retrieves a claim by its ID
+func (s *InsuranceService) GetClaim(ctx context.Context, id uint) (insurance.Claim, error) {
+	return s.insuranceRepository.GetClaim(ctx, id)
+}
+
+// SubmitClaim claims the insurer's share of an invoice under one of the patient's policies.
+// The insurer's share is taken off the patient's share of the invoice until the insurer decides.
+func (s *InsuranceService) SubmitClaim(ctx context.Context, clinicID, invoiceID, actorID uint, req ClaimRequest) (insurance.Claim, error) {
+	if req.PolicyID == 0 {
+		return insurance.Claim{}, fmt.Errorf("%w: policy is required", insurance.ErrClaimValidation)
+	}
+
+	invoice, err := s.billingRepository.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return insurance.Claim{}, err
+	}
+	if invoice.ClinicID != clinicID {
+		return insurance.Claim{}, billing.ErrInvoiceNotFound
+	}
+
+	policy, err := s.insuranceRepository.GetPolicy(ctx, req.PolicyID)
+	if err != nil {
+		return insurance.Claim{}, err
+	}
+	if policy.ClinicID != clinicID {
+		return insurance.Claim{}, insurance.ErrPolicyNotFound
+	}
+	if !policy.Provider.Active {
+		return insurance.Claim{}, insurance.ErrProviderInactive
+	}
+
+	claim, err := s.insuranceRepository.CreateClaim(ctx, insurance.Claim{
+		InvoiceID:     invoice.ID,
+		PolicyID:      policy.ID,
+		Reference:     req.Reference,
+		Notes:         req.Notes,
+		SubmittedAt:   s.now(),
+		SubmittedByID: actorID,
+	})
+	if err != nil {
+		return insurance.Claim{}, err
+	}
+
+	log.Info().
+		Str("operation", "SubmitClaim").
+		Uint("claim_id", claim.ID).
+		Uint("invoice_id", invoice.ID).
+		Str("provider", policy.Provider.Name).
+		Msg("Invoice claimed from insurer")
+
+	return claim, nil
+}
+
+// DecideClaim records whether the insurer approved, partially approved or rejected a submitted claim
+func (s *InsuranceService) DecideClaim(ctx context.Context, claimID, actorID uint, decision insurance.Decision) (insurance.Claim, error) {
+	claim, err := s.insuranceRepository.GetClaim(ctx, claimID)
+	if err != nil {
+		return insurance.Claim{}, err
+	}
+	if claim.Status != insurance.ClaimSubmitted {
+		return insurance.Claim{}, insurance.ErrClaimNotSubmitted
+	}
+
+	if err := validations.ClaimDecisionValidation(&decision, claim.ClaimedAmount); err != nil {
+		log.Warn().
+			Str("operation", "DecideClaim").
+			Err(err).
+			Uint("claim_id", claimID).
+			Msg("Claim decision validation failed")
+		return insurance.Claim{}, fmt.Errorf("%w: %s", insurance.ErrClaimValidation, err.Error())
+	}
+
+	return s.insuranceRepository.DecideClaim(ctx, claimID, decision, actorID, s.now())
+}
+
+// PayClaim books the insurer's payment of the approved amount of a claim against its invoice
+func (s *InsuranceService) PayClaim(ctx context.Context, claimID, recordedByID uint, req ClaimPaymentRequest) (insurance.Claim, error) {
+	payment := billing.Payment{
+		Reference:    req.Reference,
+		Notes:        req.Notes,
+		ReceivedAt:   s.now(),
+		RecordedByID: recordedByID,
+	}
+	if req.ReceivedAt != nil {
+		payment.ReceivedAt = *req.ReceivedAt
+	}
+
+	return s.insuranceRepository.PayClaim(ctx, claimID, payment)
+}
+
+// clinicProvider loads a provider and checks it belongs to the clinic
+func (s *InsuranceService) clinicProvider(ctx context.Context, clinicID, providerID uint) (insurance.Provider, error) {
+	provider, err := s.insuranceRepository.GetProvider(ctx, providerID)
+	if errors.Is(err, insurance.ErrProviderNotFound) || (err == nil && provider.ClinicID != clinicID) {
+		return insurance.Provider{}, fmt.Errorf("%w: provider %d not found", insurance.ErrPolicyValidation, providerID)
+	}
+	if err != nil {
+		return insurance.Provider{}, err
+	}
+	return provider, nil
+}
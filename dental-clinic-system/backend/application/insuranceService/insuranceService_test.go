@@ -0,0 +1,455 @@
+package insuranceService
+
+import (
+	"context"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/insurance"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeInsuranceRepository keeps policies, claims and the claimed invoices in memory and applies
+// claims to the invoices the way the database repository does
+type fakeInsuranceRepository struct {
+	providers map[uint]insurance.Provider
+	policies  map[uint]insurance.Policy
+	claims    map[uint]insurance.Claim
+	invoices  map[uint]billing.Invoice
+	payments  []billing.Payment
+}
+
+func (r *fakeInsuranceRepository) GetProviders(ctx context.Context, clinicID uint) ([]insurance.Provider, error) {
+	return nil, nil
+}
+
+func (r *fakeInsuranceRepository) GetProvider(ctx context.Context, id uint) (insurance.Provider, error) {
+	provider, ok := r.providers[id]
+	if !ok {
+		return insurance.Provider{}, insurance.ErrProviderNotFound
+	}
+	return provider, nil
+}
+
+func (r *fakeInsuranceRepository) CreateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error) {
+	return provider, nil
+}
+
+func (r *fakeInsuranceRepository) UpdateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error) {
+	return provider, nil
+}
+
+func (r *fakeInsuranceRepository) GetPolicies(ctx context.Context, clinicID, patientID uint) ([]insurance.Policy, error) {
+	var policies []insurance.Policy
+	for id := uint(1); id <= uint(len(r.policies)); id++ {
+		if policy := r.policies[id]; policy.ClinicID == clinicID && policy.PatientID == patientID {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+func (r *fakeInsuranceRepository) GetPolicy(ctx context.Context, id uint) (insurance.Policy, error) {
+	policy, ok := r.policies[id]
+	if !ok {
+		return insurance.Policy{}, insurance.ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (r *fakeInsuranceRepository) CreatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error) {
+	policy.ID = uint(len(r.policies) + 1)
+	policy.Provider = r.providers[policy.ProviderID]
+	r.policies[policy.ID] = policy
+	return policy, nil
+}
+
+func (r *fakeInsuranceRepository) UpdatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error) {
+	r.policies[policy.ID] = policy
+	return policy, nil
+}
+
+func (r *fakeInsuranceRepository) GetUsage(ctx context.Context, policyID uint, from, to string) (insurance.Usage, error) {
+	var usage insurance.Usage
+	for _, claim := range r.claims {
+		if claim.PolicyID != policyID || claim.ServiceDate < from || claim.ServiceDate > to {
+			continue
+		}
+		if claim.Status == insurance.ClaimSubmitted {
+			usage.Pending += claim.ClaimedAmount
+		} else {
+			usage.Used += claim.CoveredAmount()
+		}
+	}
+	return usage, nil
+}
+
+func (r *fakeInsuranceRepository) GetClaims(ctx context.Context, clinicID, patientID uint, status insurance.ClaimStatus) ([]insurance.Claim, error) {
+	return nil, nil
+}
+
+func (r *fakeInsuranceRepository) GetClaim(ctx context.Context, id uint) (insurance.Claim, error) {
+	claim, ok := r.claims[id]
+	if !ok {
+		return insurance.Claim{}, insurance.ErrClaimNotFound
+	}
+	return claim, nil
+}
+
+func (r *fakeInsuranceRepository) CreateClaim(ctx context.Context, claim insurance.Claim) (insurance.Claim, error) {
+	policy := r.policies[claim.PolicyID]
+	invoice := r.invoices[claim.InvoiceID]
+	if !policy.ValidOn(invoice.IssueDate) {
+		return insurance.Claim{}, insurance.ErrPolicyNotValid
+	}
+	for _, existing := range r.claims {
+		if existing.InvoiceID == invoice.ID && existing.Status != insurance.ClaimRejected {
+			return insurance.Claim{}, insurance.ErrClaimExists
+		}
+	}
+
+	yearStart, yearEnd, err := policy.PolicyYear(invoice.IssueDate)
+	if err != nil {
+		return insurance.Claim{}, err
+	}
+	usage, _ := r.GetUsage(ctx, policy.ID, yearStart, yearEnd)
+	share := min(policy.Share(invoice.Total, usage.Total()), invoice.Total-invoice.PatientPaid())
+	if share <= 0 {
+		return insurance.Claim{}, insurance.ErrNothingToClaim
+	}
+
+	claim.ID = uint(len(r.claims) + 1)
+	claim.ClinicID = invoice.ClinicID
+	claim.PatientID = invoice.PatientID
+	claim.Status = insurance.ClaimSubmitted
+	claim.ServiceDate = invoice.IssueDate
+	claim.ClaimedAmount = share
+	claim.Policy = policy
+	r.claims[claim.ID] = claim
+	invoice.InsurerShare = claim.CoveredAmount()
+	r.invoices[invoice.ID] = invoice
+	return claim, nil
+}
+
+func (r *fakeInsuranceRepository) DecideClaim(ctx context.Context, claimID uint, decision insurance.Decision, actorID uint, at time.Time) (insurance.Claim, error) {
+	claim := r.claims[claimID]
+	if err := claim.Decide(decision, actorID, at); err != nil {
+		return insurance.Claim{}, err
+	}
+	r.claims[claimID] = claim
+	invoice := r.invoices[claim.InvoiceID]
+	invoice.InsurerShare = claim.CoveredAmount()
+	r.invoices[invoice.ID] = invoice
+	return claim, nil
+}
+
+func (r *fakeInsuranceRepository) PayClaim(ctx context.Context, claimID uint, payment billing.Payment) (insurance.Claim, error) {
+	claim := r.claims[claimID]
+	if claim.Status != insurance.ClaimApproved && claim.Status != insurance.ClaimPartiallyApproved {
+		return insurance.Claim{}, insurance.ErrClaimNotApproved
+	}
+	invoice := r.invoices[claim.InvoiceID]
+	if err := invoice.ApplyInsurer(billing.KindPayment, claim.ApprovedAmount); err != nil {
+		return insurance.Claim{}, err
+	}
+	r.invoices[invoice.ID] = invoice
+	payment.Amount = claim.ApprovedAmount
+	r.payments = append(r.payments, payment)
+	claim.Status = insurance.ClaimPaid
+	claim.PaidAmount = payment.Amount
+	r.claims[claimID] = claim
+	return claim, nil
+}
+
+type fakeBillingRepository struct {
+	invoices map[uint]billing.Invoice
+}
+
+func (r fakeBillingRepository) GetInvoice(ctx context.Context, id uint) (billing.Invoice, error) {
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return billing.Invoice{}, billing.ErrInvoiceNotFound
+	}
+	return invoice, nil
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Timezone: "Europe/Istanbul"}, nil
+}
+
+// newTestService sets up clinic 1 with an active and an inactive provider and two invoices of patient 7:
+// invoice 1 of 10.000,00 TRY issued on 2026-05-10 and invoice 2 of 2.000,00 TRY issued on 2026-06-01
+func newTestService() (*InsuranceService, *fakeInsuranceRepository) {
+	invoices := map[uint]billing.Invoice{}
+	for id, issued := range map[uint]struct {
+		date  string
+		total int64
+	}{1: {"2026-05-10", 1000000}, 2: {"2026-06-01", 200000}} {
+		invoice := billing.Invoice{ClinicID: 1, PatientID: 7, IssueDate: issued.date, Currency: "TRY", Status: billing.InvoiceIssued, Total: issued.total}
+		invoice.ID = id
+		invoices[id] = invoice
+	}
+
+	active := insurance.Provider{ClinicID: 1, Name: "Anadolu Sigorta", Active: true}
+	active.ID = 1
+	inactive := insurance.Provider{ClinicID: 1, Name: "Eski Sigorta"}
+	inactive.ID = 2
+	repo := &fakeInsuranceRepository{
+		providers: map[uint]insurance.Provider{1: active, 2: inactive},
+		policies:  map[uint]insurance.Policy{},
+		claims:    map[uint]insurance.Claim{},
+		invoices:  invoices,
+	}
+
+	service := NewInsuranceService(repo, fakeBillingRepository{invoices: invoices}, fakeClinicRepository{})
+	service.now = func() time.Time {
+		return time.Date(2026, time.June, 15, 9, 0, 0, 0, time.UTC)
+	}
+	return service, repo
+}
+
+func createPolicy(t *testing.T, service *InsuranceService, coverage int, limit int64, validFrom string) insurance.Policy {
+	t.Helper()
+	policy, err := service.CreatePolicy(context.Background(), insurance.Policy{
+		ClinicID:        1,
+		PatientID:       7,
+		ProviderID:      1,
+		PolicyNumber:    "POL-1",
+		CoveragePercent: coverage,
+		AnnualLimit:     limit,
+		ValidFrom:       validFrom,
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy() error = %v", err)
+	}
+	return policy
+}
+
+func TestCreatePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		providerID uint
+		clinicID   uint
+		wantErr    error
+	}{
+		{name: "Active provider", providerID: 1, clinicID: 1},
+		{name: "Inactive provider", providerID: 2, clinicID: 1, wantErr: insurance.ErrProviderInactive},
+		{name: "Unknown provider", providerID: 9, clinicID: 1, wantErr: insurance.ErrPolicyValidation},
+		{name: "Provider of another clinic", providerID: 1, clinicID: 2, wantErr: insurance.ErrPolicyValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService()
+			policy, err := service.CreatePolicy(context.Background(), insurance.Policy{
+				ClinicID:        tt.clinicID,
+				PatientID:       7,
+				ProviderID:      tt.providerID,
+				PolicyNumber:    "POL-1",
+				CoveragePercent: 80,
+				ValidFrom:       "2026-01-01",
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreatePolicy() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !policy.Active {
+				t.Errorf("new policy is not active")
+			}
+		})
+	}
+}
+
+func TestClaimWorkflowSplitsInvoice(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newTestService()
+	policy := createPolicy(t, service, 80, 1200000, "2026-03-01")
+
+	claim, err := service.SubmitClaim(ctx, 1, 1, 5, ClaimRequest{PolicyID: policy.ID})
+	if err != nil {
+		t.Fatalf("SubmitClaim() error = %v", err)
+	}
+	if claim.Status != insurance.ClaimSubmitted || claim.ClaimedAmount != 800000 {
+		t.Fatalf("claim status %s amount %d, want submitted 800000", claim.Status, claim.ClaimedAmount)
+	}
+	if _, err := service.SubmitClaim(ctx, 1, 1, 5, ClaimRequest{PolicyID: policy.ID}); !errors.Is(err, insurance.ErrClaimExists) {
+		t.Errorf("second SubmitClaim() error = %v, want %v", err, insurance.ErrClaimExists)
+	}
+
+	if _, err := service.DecideClaim(ctx, claim.ID, 6, insurance.Decision{Status: insurance.ClaimPartiallyApproved, ApprovedAmount: 900000}); !errors.Is(err, insurance.ErrClaimValidation) {
+		t.Fatalf("DecideClaim() above the claimed amount error = %v, want %v", err, insurance.ErrClaimValidation)
+	}
+	decided, err := service.DecideClaim(ctx, claim.ID, 6, insurance.Decision{Status: insurance.ClaimPartiallyApproved, ApprovedAmount: 600000, Reference: "PRV-77"})
+	if err != nil {
+		t.Fatalf("DecideClaim() error = %v", err)
+	}
+	if decided.Reference != "PRV-77" || decided.DecidedByID == nil || *decided.DecidedByID != 6 {
+		t.Errorf("decided claim reference %q decided by %v", decided.Reference, decided.DecidedByID)
+	}
+	if _, err := service.DecideClaim(ctx, claim.ID, 6, insurance.Decision{Status: insurance.ClaimApproved}); !errors.Is(err, insurance.ErrClaimNotSubmitted) {
+		t.Errorf("second DecideClaim() error = %v, want %v", err, insurance.ErrClaimNotSubmitted)
+	}
+
+	invoice := repo.invoices[1]
+	invoice.Resolve()
+	if invoice.InsurerShare != 600000 || invoice.PatientShare != 400000 {
+		t.Fatalf("insurer share %d patient share %d, want 600000 and 400000", invoice.InsurerShare, invoice.PatientShare)
+	}
+	if err := invoice.Apply(billing.KindPayment, 400001); !errors.Is(err, billing.ErrOverpayment) {
+		t.Errorf("patient paying more than their share error = %v, want %v", err, billing.ErrOverpayment)
+	}
+
+	paid, err := service.PayClaim(ctx, claim.ID, 6, ClaimPaymentRequest{Reference: "EFT-1"})
+	if err != nil {
+		t.Fatalf("PayClaim() error = %v", err)
+	}
+	if paid.Status != insurance.ClaimPaid || paid.PaidAmount != 600000 {
+		t.Errorf("paid claim status %s amount %d", paid.Status, paid.PaidAmount)
+	}
+	if payment := repo.payments[0]; payment.Reference != "EFT-1" || payment.RecordedByID != 6 || !payment.ReceivedAt.Equal(service.now()) {
+		t.Errorf("insurer payment %+v", payment)
+	}
+
+	invoice = repo.invoices[1]
+	if invoice.Status != billing.InvoicePartiallyPaid || invoice.InsurerBalance != 0 || invoice.PatientBalance != 400000 {
+		t.Errorf("invoice status %s insurer balance %d patient balance %d", invoice.Status, invoice.InsurerBalance, invoice.PatientBalance)
+	}
+	if _, err := service.PayClaim(ctx, claim.ID, 6, ClaimPaymentRequest{}); !errors.Is(err, insurance.ErrClaimNotApproved) {
+		t.Errorf("second PayClaim() error = %v, want %v", err, insurance.ErrClaimNotApproved)
+	}
+}
+
+func TestRejectedClaimFallsBackToPatient(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newTestService()
+	policy := createPolicy(t, service, 50, 0, "2026-01-01")
+
+	claim, err := service.SubmitClaim(ctx, 1, 2, 5, ClaimRequest{PolicyID: policy.ID})
+	if err != nil {
+		t.Fatalf("SubmitClaim() error = %v", err)
+	}
+	if repo.invoices[2].InsurerShare != 100000 {
+		t.Fatalf("insurer share while submitted = %d, want 100000", repo.invoices[2].InsurerShare)
+	}
+
+	if _, err := service.DecideClaim(ctx, claim.ID, 6, insurance.Decision{Status: insurance.ClaimRejected}); !errors.Is(err, insurance.ErrClaimValidation) {
+		t.Fatalf("DecideClaim() without a reason error = %v, want %v", err, insurance.ErrClaimValidation)
+	}
+	if _, err := service.DecideClaim(ctx, claim.ID, 6, insurance.Decision{Status: insurance.ClaimRejected, RejectionReason: "Not covered"}); err != nil {
+		t.Fatalf("DecideClaim() error = %v", err)
+	}
+
+	invoice := repo.invoices[2]
+	invoice.Resolve()
+	if invoice.InsurerShare != 0 || invoice.PatientBalance != 200000 {
+		t.Errorf("insurer share %d patient balance %d after rejection", invoice.InsurerShare, invoice.PatientBalance)
+	}
+
+	if _, err := service.SubmitClaim(ctx, 1, 2, 5, ClaimRequest{PolicyID: policy.ID}); err != nil {
+		t.Errorf("resubmitting a rejected invoice error = %v", err)
+	}
+}
+
+func TestSubmitClaimChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		clinicID  uint
+		invoiceID uint
+		validFrom string
+		wantErr   error
+	}{
+		{name: "Invoice of another clinic", clinicID: 2, invoiceID: 1, validFrom: "2026-01-01", wantErr: billing.ErrInvoiceNotFound},
+		{name: "Unknown invoice", clinicID: 1, invoiceID: 9, validFrom: "2026-01-01", wantErr: billing.ErrInvoiceNotFound},
+		{name: "Policy starts after the invoice", clinicID: 1, invoiceID: 1, validFrom: "2026-05-11", wantErr: insurance.ErrPolicyNotValid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService()
+			policy := createPolicy(t, service, 80, 0, tt.validFrom)
+			_, err := service.SubmitClaim(context.Background(), tt.clinicID, tt.invoiceID, 5, ClaimRequest{PolicyID: policy.ID})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SubmitClaim() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetCoverage(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newTestService()
+	policy := createPolicy(t, service, 80, 1000000, "2025-06-01")
+
+	// Invoice 1 (2026-05-10) falls in the policy year that started on 2025-06-01, invoice 2 (2026-06-01) in the next one
+	first, err := service.SubmitClaim(ctx, 1, 1, 5, ClaimRequest{PolicyID: policy.ID})
+	if err != nil {
+		t.Fatalf("SubmitClaim() error = %v", err)
+	}
+	if _, err := service.DecideClaim(ctx, first.ID, 6, insurance.Decision{Status: insurance.ClaimApproved}); err != nil {
+		t.Fatalf("DecideClaim() error = %v", err)
+	}
+	if _, err := service.SubmitClaim(ctx, 1, 2, 5, ClaimRequest{PolicyID: policy.ID}); err != nil {
+		t.Fatalf("SubmitClaim() error = %v", err)
+	}
+
+	expired := insurance.Policy{ClinicID: 1, PatientID: 7, ProviderID: 1, PolicyNumber: "OLD", CoveragePercent: 100, ValidFrom: "2024-01-01", ValidTo: "2024-12-31", Active: true}
+	expired.ID = uint(len(repo.policies) + 1)
+	repo.policies[expired.ID] = expired
+
+	tests := []struct {
+		name          string
+		date          string
+		amount        int64
+		wantYearStart string
+		wantUsed      int64
+		wantPending   int64
+		wantRemaining int64
+		wantInsurer   int64
+	}{
+		{
+			name:          "Today is in the second policy year",
+			amount:        500000,
+			wantYearStart: "2026-06-01",
+			wantPending:   160000,
+			wantRemaining: 840000,
+			wantInsurer:   400000,
+		},
+		{
+			name:          "Limit nearly used up in the first policy year",
+			date:          "2026-05-20",
+			amount:        500000,
+			wantYearStart: "2025-06-01",
+			wantUsed:      800000,
+			wantRemaining: 200000,
+			wantInsurer:   200000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coverages, err := service.GetCoverage(ctx, 1, 7, tt.date, tt.amount)
+			if err != nil {
+				t.Fatalf("GetCoverage() error = %v", err)
+			}
+			if len(coverages) != 1 {
+				t.Fatalf("got %d coverages, want only the policy in force", len(coverages))
+			}
+			coverage := coverages[0]
+			if coverage.YearStart != tt.wantYearStart || coverage.Used != tt.wantUsed || coverage.Pending != tt.wantPending {
+				t.Errorf("year %s used %d pending %d, want %s %d %d", coverage.YearStart, coverage.Used, coverage.Pending, tt.wantYearStart, tt.wantUsed, tt.wantPending)
+			}
+			if coverage.Remaining == nil || *coverage.Remaining != tt.wantRemaining {
+				t.Errorf("remaining = %v, want %d", coverage.Remaining, tt.wantRemaining)
+			}
+			if *coverage.EstimatedInsurerShare != tt.wantInsurer || *coverage.EstimatedPatientShare != tt.amount-tt.wantInsurer {
+				t.Errorf("estimated insurer share %d patient share %d", *coverage.EstimatedInsurerShare, *coverage.EstimatedPatientShare)
+			}
+		})
+	}
+
+	if _, err := service.GetCoverage(ctx, 1, 7, "15.06.2026", 0); !errors.Is(err, insurance.ErrPolicyValidation) {
+		t.Errorf("GetCoverage() with an invalid date error = %v, want %v", err, insurance.ErrPolicyValidation)
+	}
+}
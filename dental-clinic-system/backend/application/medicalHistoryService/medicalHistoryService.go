@@ -0,0 +1,223 @@
+package medicalHistoryService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/schedule"
+	"dental-clinic-system/validations"
+	"fmt"
+	"time"
+)
+
+// MedicalHistoryRepository defines the medical history database operations
+type MedicalHistoryRepository interface {
+	GetRecords(ctx context.Context, patientIDs []uint) (map[uint]medical.Record, error)
+	GetAllergy(ctx context.Context, id uint) (medical.Allergy, error)
+	CreateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error)
+	UpdateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error)
+	DeleteAllergy(ctx context.Context, id uint) error
+	GetMedication(ctx context.Context, id uint) (medical.Medication, error)
+	CreateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error)
+	UpdateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error)
+	DeleteMedication(ctx context.Context, id uint) error
+	GetCondition(ctx context.Context, id uint) (medical.Condition, error)
+	CreateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error)
+	UpdateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error)
+	DeleteCondition(ctx context.Context, id uint) error
+	GetQuestionnaires(ctx context.Context, patientID uint) ([]medical.Questionnaire, error)
+	GetQuestionnaire(ctx context.Context, id uint) (medical.Questionnaire, error)
+	CreateQuestionnaire(ctx context.Context, questionnaire medical.Questionnaire) (medical.Questionnaire, error)
+}
+
+// ClinicRepository is used to resolve the time zone of a clinic
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// MedicalHistoryService keeps the structured medical history of patients and derives the alerts shown before treatment
+type MedicalHistoryService struct {
+	medicalHistoryRepository MedicalHistoryRepository
+	clinicRepository         ClinicRepository
+	now                      func() time.Time
+}
+
+// NewMedicalHistoryService creates a new instance of MedicalHistoryService
+func NewMedicalHistoryService(medicalHistoryRepo MedicalHistoryRepository, clinicRepo ClinicRepository) *MedicalHistoryService {
+	return &MedicalHistoryService{
+		medicalHistoryRepository: medicalHistoryRepo,
+		clinicRepository:         clinicRepo,
+		now:                      time.Now,
+	}
+}
+
+// GetQuestions returns the questions of the medical questionnaire
+func (s *MedicalHistoryService) GetQuestions() []medical.Question {
+	return medical.Questions
+}
+
+// GetRecord returns the patient's medical history with the alerts that apply today in the clinic
+func (s *MedicalHistoryService) GetRecord(ctx context.Context, clinicID, patientID uint) (medical.Record, error) {
+	today, err := s.today(ctx, clinicID)
+	if err != nil {
+		return medical.Record{}, err
+	}
+
+	records, err := s.medicalHistoryRepository.GetRecords(ctx, []uint{patientID})
+	if err != nil {
+		return medical.Record{}, err
+	}
+
+	record := records[patientID]
+	record.Alerts = medical.BuildAlerts(record, today)
+	return record, nil
+}
+
+// GetAlerts returns the alerts of each patient that apply today in the clinic
+func (s *MedicalHistoryService) GetAlerts(ctx context.Context, clinicID uint, patientIDs []uint) (map[uint][]medical.Alert, error) {
+	alerts := map[uint][]medical.Alert{}
+	if len(patientIDs) == 0 {
+		return alerts, nil
+	}
+
+	today, err := s.today(ctx, clinicID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.medicalHistoryRepository.GetRecords(ctx, patientIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for patientID, record := range records {
+		alerts[patientID] = medical.BuildAlerts(record, today)
+	}
+	return alerts, nil
+}
+
+// GetAllergy retrieves an allergy by its ID
+func (s *MedicalHistoryService) GetAllergy(ctx context.Context, id uint) (medical.Allergy, error) {
+	return s.medicalHistoryRepository.GetAllergy(ctx, id)
+}
+
+// CreateAllergy validates and records an allergy
+func (s *MedicalHistoryService) CreateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error) {
+	if err := validations.AllergyValidation(&allergy); err != nil {
+		return medical.Allergy{}, fmt.Errorf("%w: %s", medical.ErrMedicalValidation, err.Error())
+	}
+	allergy.ID = 0
+	return s.medicalHistoryRepository.CreateAllergy(ctx, allergy)
+}
+
+// UpdateAllergy validates and saves changes to an allergy
+func (s *MedicalHistoryService) UpdateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error) {
+	if err := validations.AllergyValidation(&allergy); err != nil {
+		return medical.Allergy{}, fmt.Errorf("%w: %s", medical.ErrMedicalValidation, err.Error())
+	}
+	return s.medicalHistoryRepository.UpdateAllergy(ctx, allergy)
+}
+
+// DeleteAllergy removes an allergy recorded by mistake
+func (s *MedicalHistoryService) DeleteAllergy(ctx context.Context, id uint) error {
+	return s.medicalHistoryRepository.DeleteAllergy(ctx, id)
+}
+
+// GetMedication retrieves a medication by its ID
+func (s *MedicalHistoryService) GetMedication(ctx context.Context, id uint) (medical.Medication, error) {
+	return s.medicalHistoryRepository.GetMedication(ctx, id)
+}
+
+// CreateMedication validates and records a medication
+func (s *MedicalHistoryService) CreateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error) {
+	if err := validations.MedicationValidation(&medication); err != nil {
+		return medical.Medication{}, fmt.Errorf("%w: %s", medical.ErrMedicalValidation, err.Error())
+	}
+	medication.ID = 0
+	return s.medicalHistoryRepository.CreateMedication(ctx, medication)
+}
+
+// UpdateMedication validates and saves changes to a medication
+func (s *MedicalHistoryService) UpdateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error) {
+	if err := validations.MedicationValidation(&medication); err != nil {
+		return medical.Medication{}, fmt.Errorf("%w: %s", medical.ErrMedicalValidation, err.Error())
+	}
+	return s.medicalHistoryRepository.UpdateMedication(ctx, medication)
+}
+
+// DeleteMedication removes a medication recorded by mistake
+func (s *MedicalHistoryService) DeleteMedication(ctx context.Context, id uint) error {
+	return s.medicalHistoryRepository.DeleteMedication(ctx, id)
+}
+
+// GetCondition retrieves a condition by its ID
+func (s *MedicalHistoryService) GetCondition(ctx context.Context, id uint) (medical.Condition, error) {
+	return s.medicalHistoryRepository.GetCondition(ctx, id)
+}
+
+// CreateCondition validates and records a systemic condition
+func (s *MedicalHistoryService) CreateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error) {
+	if err := validations.ConditionValidation(&condition); err != nil {
+		return medical.Condition{}, fmt.Errorf("%w: %s", medical.ErrMedicalValidation, err.Error())
+	}
+	condition.ID = 0
+	return s.medicalHistoryRepository.CreateCondition(ctx, condition)
+}
+
+// UpdateCondition validates and saves changes to a condition
+func (s *MedicalHistoryService) UpdateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error) {
+	if err := validations.ConditionValidation(&condition); err != nil {
+		return medical.Condition{}, fmt.Errorf("%w: %s", medical.ErrMedicalValidation, err.Error())
+	}
+	return s.medicalHistoryRepository.UpdateCondition(ctx, condition)
+}
+
+// DeleteCondition removes a condition recorded by mistake
+func (s *MedicalHistoryService) DeleteCondition(ctx context.Context, id uint) error {
+	return s.medicalHistoryRepository.DeleteCondition(ctx, id)
+}
+
+// GetQuestionnaires returns every questionnaire the patient filled in, newest first
+func (s *MedicalHistoryService) GetQuestionnaires(ctx context.Context, patientID uint) ([]medical.Questionnaire, error) {
+	return s.medicalHistoryRepository.GetQuestionnaires(ctx, patientID)
+}
+
+// GetQuestionnaire retrieves a questionnaire with its answers
+func (s *MedicalHistoryService) GetQuestionnaire(ctx context.Context, id uint) (medical.Questionnaire, error) {
+	return s.medicalHistoryRepository.GetQuestionnaire(ctx, id)
+}
+
+// SubmitQuestionnaire records a filled in questionnaire. It is dated today in the clinic unless a
+// past date is given, e.g. when a paper form is entered later.
+func (s *MedicalHistoryService) SubmitQuestionnaire(ctx context.Context, questionnaire medical.Questionnaire) (medical.Questionnaire, error) {
+	today, err := s.today(ctx, questionnaire.ClinicID)
+	if err != nil {
+		return medical.Questionnaire{}, err
+	}
+	if questionnaire.AnsweredOn == "" {
+		questionnaire.AnsweredOn = today
+	}
+
+	if err := validations.QuestionnaireValidation(&questionnaire); err != nil {
+		return medical.Questionnaire{}, fmt.Errorf("%w: %s", medical.ErrMedicalValidation, err.Error())
+	}
+	if questionnaire.AnsweredOn > today {
+		return medical.Questionnaire{}, fmt.Errorf("%w: answered_on can not be in the future", medical.ErrMedicalValidation)
+	}
+
+	questionnaire.ID = 0
+	for i := range questionnaire.Answers {
+		questionnaire.Answers[i].ID = 0
+		questionnaire.Answers[i].QuestionnaireID = 0
+	}
+	return s.medicalHistoryRepository.CreateQuestionnaire(ctx, questionnaire)
+}
+
+// today returns the current date in the clinic's time zone
+func (s *MedicalHistoryService) today(ctx context.Context, clinicID uint) (string, error) {
+	cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+	if err != nil {
+		return "", err
+	}
+	return s.now().In(cln.Location()).Format(schedule.DateLayout), nil
+}
@@ -0,0 +1,278 @@
+package medicalHistoryService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/medical"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeMedicalHistoryRepository serves a fixed medical record per patient and stores submitted questionnaires
+type fakeMedicalHistoryRepository struct {
+	records        map[uint]medical.Record
+	questionnaires []medical.Questionnaire
+}
+
+func (r *fakeMedicalHistoryRepository) GetRecords(ctx context.Context, patientIDs []uint) (map[uint]medical.Record, error) {
+	records := map[uint]medical.Record{}
+	for _, id := range patientIDs {
+		record := r.records[id]
+		record.PatientID = id
+		records[id] = record
+	}
+	return records, nil
+}
+
+func (r *fakeMedicalHistoryRepository) GetAllergy(ctx context.Context, id uint) (medical.Allergy, error) {
+	return medical.Allergy{}, medical.ErrAllergyNotFound
+}
+
+func (r *fakeMedicalHistoryRepository) CreateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error) {
+	allergy.ID = 1
+	return allergy, nil
+}
+
+func (r *fakeMedicalHistoryRepository) UpdateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error) {
+	return allergy, nil
+}
+
+func (r *fakeMedicalHistoryRepository) DeleteAllergy(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *fakeMedicalHistoryRepository) GetMedication(ctx context.Context, id uint) (medical.Medication, error) {
+	return medical.Medication{}, medical.ErrMedicationNotFound
+}
+
+func (r *fakeMedicalHistoryRepository) CreateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error) {
+	medication.ID = 1
+	return medication, nil
+}
+
+func (r *fakeMedicalHistoryRepository) UpdateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error) {
+	return medication, nil
+}
+
+func (r *fakeMedicalHistoryRepository) DeleteMedication(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *fakeMedicalHistoryRepository) GetCondition(ctx context.Context, id uint) (medical.Condition, error) {
+	return medical.Condition{}, medical.ErrConditionNotFound
+}
+
+func (r *fakeMedicalHistoryRepository) CreateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error) {
+	condition.ID = 1
+	return condition, nil
+}
+
+func (r *fakeMedicalHistoryRepository) UpdateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error) {
+	return condition, nil
+}
+
+func (r *fakeMedicalHistoryRepository) DeleteCondition(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *fakeMedicalHistoryRepository) GetQuestionnaires(ctx context.Context, patientID uint) ([]medical.Questionnaire, error) {
+	return r.questionnaires, nil
+}
+
+func (r *fakeMedicalHistoryRepository) GetQuestionnaire(ctx context.Context, id uint) (medical.Questionnaire, error) {
+	return medical.Questionnaire{}, medical.ErrQuestionnaireNotFound
+}
+
+func (r *fakeMedicalHistoryRepository) CreateQuestionnaire(ctx context.Context, questionnaire medical.Questionnaire) (medical.Questionnaire, error) {
+	questionnaire.ID = uint(len(r.questionnaires) + 1)
+	r.questionnaires = append(r.questionnaires, questionnaire)
+	return questionnaire, nil
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Timezone: "Europe/Istanbul"}, nil
+}
+
+// 22:30 UTC on 1 March is already 2 March in Istanbul
+var now = time.Date(2025, time.March, 1, 22, 30, 0, 0, time.UTC)
+
+func newTestService(records map[uint]medical.Record) (*MedicalHistoryService, *fakeMedicalHistoryRepository) {
+	repo := &fakeMedicalHistoryRepository{records: records}
+	service := NewMedicalHistoryService(repo, fakeClinicRepository{})
+	service.now = func() time.Time { return now }
+	return service, repo
+}
+
+func model(id uint) gorm.Model {
+	return gorm.Model{ID: id}
+}
+
+func answers(yes ...string) []medical.QuestionnaireAnswer {
+	var answers []medical.QuestionnaireAnswer
+	for _, question := range medical.Questions {
+		answer := medical.QuestionnaireAnswer{Question: question.Key}
+		for _, key := range yes {
+			if key == question.Key {
+				answer.Answer = true
+			}
+		}
+		answers = append(answers, answer)
+	}
+	return answers
+}
+
+func TestGetRecordAlerts(t *testing.T) {
+	tests := []struct {
+		name       string
+		record     medical.Record
+		wantAlerts []medical.Alert
+	}{
+		{
+			name: "Nothing on file",
+			wantAlerts: []medical.Alert{
+				{Level: medical.AlertInfo, Kind: medical.AlertKindQuestionnaire, Message: "No medical questionnaire on file"},
+			},
+		},
+		{
+			name: "Critical flags come first",
+			record: medical.Record{
+				Allergies: []medical.Allergy{
+					{Model: model(1), Allergen: "Latex", Severity: medical.SeverityMild},
+					{Model: model(2), Allergen: "Penicillin", Severity: medical.SeverityLifeThreatening, Reaction: "anaphylaxis"},
+				},
+				Medications: []medical.Medication{
+					{Model: model(3), Name: "Warfarin", Class: medical.ClassAnticoagulant, Dosage: "5 mg"},
+				},
+				Conditions: []medical.Condition{
+					{Model: model(4), Code: medical.ConditionDiabetes},
+					{Model: model(5), Code: medical.ConditionPregnancy},
+				},
+				Questionnaire: &medical.Questionnaire{Model: model(6), AnsweredOn: "2025-01-10", Answers: answers("smoking")},
+			},
+			wantAlerts: []medical.Alert{
+				{Level: medical.AlertCritical, Kind: medical.AlertKindAllergy, SourceID: 2, Message: "Allergic to Penicillin (life threatening): anaphylaxis"},
+				{Level: medical.AlertCritical, Kind: medical.AlertKindMedication, SourceID: 3, Message: "Takes Warfarin 5 mg (bleeding risk)"},
+				{Level: medical.AlertCritical, Kind: medical.AlertKindCondition, SourceID: 5, Message: "Condition: pregnancy"},
+				{Level: medical.AlertWarning, Kind: medical.AlertKindAllergy, SourceID: 1, Message: "Allergic to Latex (mild)"},
+				{Level: medical.AlertWarning, Kind: medical.AlertKindCondition, SourceID: 4, Message: "Condition: diabetes"},
+				{Level: medical.AlertInfo, Kind: medical.AlertKindQuestionnaire, SourceID: 6, Message: "Answered yes: Do you smoke?"},
+			},
+		},
+		{
+			name: "Stopped medications and resolved conditions are ignored",
+			record: medical.Record{
+				Medications: []medical.Medication{
+					{Model: model(1), Name: "Clopidogrel", Class: medical.ClassAntiplatelet, StartDate: "2024-01-01", EndDate: "2025-03-01"},
+					{Model: model(2), Name: "Alendronate", Class: medical.ClassBisphosphonate, StartDate: "2025-03-02"},
+				},
+				Conditions: []medical.Condition{
+					{Model: model(3), Code: medical.ConditionPregnancy, ResolvedOn: "2025-03-02"},
+				},
+				Questionnaire: &medical.Questionnaire{Model: model(4), AnsweredOn: "2025-03-02", Answers: answers()},
+			},
+			wantAlerts: []medical.Alert{
+				{Level: medical.AlertWarning, Kind: medical.AlertKindMedication, SourceID: 2, Message: "Takes Alendronate (osteonecrosis risk after extractions)"},
+			},
+		},
+		{
+			name: "Outdated questionnaire",
+			record: medical.Record{
+				Questionnaire: &medical.Questionnaire{Model: model(1), AnsweredOn: "2024-03-02", Answers: answers("bleeding_problems"), Notes: "Penisilin alerjisi"},
+			},
+			wantAlerts: []medical.Alert{
+				{Level: medical.AlertCritical, Kind: medical.AlertKindQuestionnaire, SourceID: 1, Message: "Answered yes: Do you bleed for a long time after a cut or a tooth extraction?"},
+				{Level: medical.AlertInfo, Kind: medical.AlertKindQuestionnaire, SourceID: 1, Message: "Questionnaire notes: Penisilin alerjisi"},
+				{Level: medical.AlertInfo, Kind: medical.AlertKindQuestionnaire, SourceID: 1, Message: "Medical questionnaire from 2024-03-02 is older than 12 months"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(map[uint]medical.Record{7: tt.record})
+
+			record, err := service.GetRecord(context.Background(), 1, 7)
+			if err != nil {
+				t.Fatalf("GetRecord() error = %v", err)
+			}
+			if !reflect.DeepEqual(record.Alerts, tt.wantAlerts) {
+				t.Errorf("Alerts =\n%+v\nwant\n%+v", record.Alerts, tt.wantAlerts)
+			}
+		})
+	}
+}
+
+func TestGetAlertsReturnsEveryPatient(t *testing.T) {
+	service, _ := newTestService(map[uint]medical.Record{
+		1: {Allergies: []medical.Allergy{{Model: model(1), Allergen: "Lidocaine", Severity: medical.SeveritySevere}}},
+	})
+
+	alerts, err := service.GetAlerts(context.Background(), 1, []uint{1, 2})
+	if err != nil {
+		t.Fatalf("GetAlerts() error = %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("got alerts for %d patients, want 2", len(alerts))
+	}
+	if alerts[1][0].Level != medical.AlertCritical || alerts[1][0].Kind != medical.AlertKindAllergy {
+		t.Errorf("first alert of patient 1 = %+v, want critical allergy", alerts[1][0])
+	}
+	if len(alerts[2]) != 1 || alerts[2][0].Kind != medical.AlertKindQuestionnaire {
+		t.Errorf("alerts of patient 2 = %+v, want only the missing questionnaire", alerts[2])
+	}
+}
+
+func TestSubmitQuestionnaire(t *testing.T) {
+	tests := []struct {
+		name           string
+		answeredOn     string
+		wantAnsweredOn string
+		wantErr        error
+	}{
+		{
+			name:           "Dated today in the clinic",
+			wantAnsweredOn: "2025-03-02",
+		},
+		{
+			name:           "Paper form entered later",
+			answeredOn:     "2025-02-20",
+			wantAnsweredOn: "2025-02-20",
+		},
+		{
+			name:       "Future date",
+			answeredOn: "2025-03-03",
+			wantErr:    medical.ErrMedicalValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo := newTestService(nil)
+
+			questionnaire, err := service.SubmitQuestionnaire(context.Background(), medical.Questionnaire{
+				ClinicID:   1,
+				PatientID:  7,
+				AnsweredOn: tt.answeredOn,
+				Answers:    answers("diabetes"),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SubmitQuestionnaire() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.questionnaires) != 0 {
+					t.Errorf("rejected questionnaire was stored")
+				}
+				return
+			}
+			if questionnaire.AnsweredOn != tt.wantAnsweredOn {
+				t.Errorf("AnsweredOn = %s, want %s", questionnaire.AnsweredOn, tt.wantAnsweredOn)
+			}
+		})
+	}
+}
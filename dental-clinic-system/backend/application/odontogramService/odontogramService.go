@@ -0,0 +1,107 @@
+package odontogramService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/odontogram"
+	"dental-clinic-system/models/schedule"
+	"dental-clinic-system/validations"
+	"fmt"
+	"time"
+)
+
+// OdontogramRepository defines the odontogram database operations
+type OdontogramRepository interface {
+	GetFindingsAt(ctx context.Context, patientID uint, at time.Time) ([]odontogram.Finding, error)
+	GetFindingHistory(ctx context.Context, patientID uint) ([]odontogram.Finding, error)
+	GetFinding(ctx context.Context, id uint) (odontogram.Finding, error)
+	CreateFinding(ctx context.Context, finding odontogram.Finding) (odontogram.Finding, error)
+	RemoveFinding(ctx context.Context, id, removedByID uint, at time.Time, reason string) (odontogram.Finding, error)
+}
+
+// ClinicRepository is used to resolve the time zone of a clinic
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// OdontogramService records tooth findings and rebuilds patient charts at any point in time
+type OdontogramService struct {
+	odontogramRepository OdontogramRepository
+	clinicRepository     ClinicRepository
+	now                  func() time.Time
+}
+
+// NewOdontogramService creates a new instance of OdontogramService
+func NewOdontogramService(odontogramRepo OdontogramRepository, clinicRepo ClinicRepository) *OdontogramService {
+	return &OdontogramService{
+		odontogramRepository: odontogramRepo,
+		clinicRepository:     clinicRepo,
+		now:                  time.Now,
+	}
+}
+
+// GetChart returns the patient's chart as it was at the end of date (YYYY-MM-DD in clinic local time)
+// or at an RFC3339 instant. An empty date returns the current chart.
+func (s *OdontogramService) GetChart(ctx context.Context, clinicID, patientID uint, date string) (odontogram.Chart, error) {
+	at, err := s.chartTime(ctx, clinicID, date)
+	if err != nil {
+		return odontogram.Chart{}, err
+	}
+
+	findings, err := s.odontogramRepository.GetFindingsAt(ctx, patientID, at)
+	if err != nil {
+		return odontogram.Chart{}, err
+	}
+
+	return odontogram.BuildChart(patientID, at, findings), nil
+}
+
+// GetHistory returns every finding ever charted for the patient, including removed and superseded ones
+func (s *OdontogramService) GetHistory(ctx context.Context, patientID uint) ([]odontogram.Finding, error) {
+	return s.odontogramRepository.GetFindingHistory(ctx, patientID)
+}
+
+// GetFinding retrieves a finding by its ID
+func (s *OdontogramService) GetFinding(ctx context.Context, id uint) (odontogram.Finding, error) {
+	return s.odontogramRepository.GetFinding(ctx, id)
+}
+
+// RecordFinding validates and charts a finding, replacing the findings listed in its Supersedes
+func (s *OdontogramService) RecordFinding(ctx context.Context, finding odontogram.Finding) (odontogram.Finding, error) {
+	if err := validations.OdontogramFindingValidation(&finding); err != nil {
+		return odontogram.Finding{}, fmt.Errorf("%w: %s", odontogram.ErrFindingValidation, err.Error())
+	}
+
+	finding.ID = 0
+	finding.RecordedAt = s.now()
+	finding.RemovedAt = nil
+	finding.RemovedByID = nil
+	finding.RemovalReason = ""
+	finding.SupersededByID = nil
+
+	return s.odontogramRepository.CreateFinding(ctx, finding)
+}
+
+// RemoveFinding takes a finding off the chart, e.g. when it was charted by mistake
+func (s *OdontogramService) RemoveFinding(ctx context.Context, id, removedByID uint, reason string) (odontogram.Finding, error) {
+	return s.odontogramRepository.RemoveFinding(ctx, id, removedByID, s.now(), reason)
+}
+
+func (s *OdontogramService) chartTime(ctx context.Context, clinicID uint, date string) (time.Time, error) {
+	if date == "" {
+		return s.now(), nil
+	}
+	if at, err := time.Parse(time.RFC3339, date); err == nil {
+		return at, nil
+	}
+
+	cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := time.ParseInLocation(schedule.DateLayout, date, cln.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date must be YYYY-MM-DD or RFC3339", odontogram.ErrFindingValidation)
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
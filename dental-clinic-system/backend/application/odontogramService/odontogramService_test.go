@@ -0,0 +1,164 @@
+package odontogramService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/odontogram"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeOdontogramRepository keeps findings in memory and supersedes them like the real repository
+type fakeOdontogramRepository struct {
+	findings []odontogram.Finding
+}
+
+func (r *fakeOdontogramRepository) GetFindingsAt(ctx context.Context, patientID uint, at time.Time) ([]odontogram.Finding, error) {
+	var findings []odontogram.Finding
+	for _, finding := range r.findings {
+		if finding.PatientID == patientID && finding.ActiveAt(at) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+func (r *fakeOdontogramRepository) GetFindingHistory(ctx context.Context, patientID uint) ([]odontogram.Finding, error) {
+	return r.findings, nil
+}
+
+func (r *fakeOdontogramRepository) GetFinding(ctx context.Context, id uint) (odontogram.Finding, error) {
+	for _, finding := range r.findings {
+		if finding.ID == id {
+			return finding, nil
+		}
+	}
+	return odontogram.Finding{}, odontogram.ErrFindingNotFound
+}
+
+func (r *fakeOdontogramRepository) CreateFinding(ctx context.Context, finding odontogram.Finding) (odontogram.Finding, error) {
+	superseded := map[uint]bool{}
+	for _, id := range finding.Supersedes {
+		superseded[id] = true
+	}
+	for _, existing := range r.findings {
+		if existing.RemovedAt == nil && !superseded[existing.ID] && existing.Overlaps(finding) {
+			return odontogram.Finding{}, odontogram.ErrFindingConflict
+		}
+	}
+
+	finding.ID = uint(len(r.findings) + 1)
+	for i := range r.findings {
+		if superseded[r.findings[i].ID] {
+			removedAt := finding.RecordedAt
+			r.findings[i].RemovedAt = &removedAt
+			r.findings[i].SupersededByID = &finding.ID
+		}
+	}
+	r.findings = append(r.findings, finding)
+	return finding, nil
+}
+
+func (r *fakeOdontogramRepository) RemoveFinding(ctx context.Context, id, removedByID uint, at time.Time, reason string) (odontogram.Finding, error) {
+	for i := range r.findings {
+		if r.findings[i].ID == id {
+			if r.findings[i].RemovedAt != nil {
+				return odontogram.Finding{}, odontogram.ErrFindingNotRemovable
+			}
+			r.findings[i].RemovedAt = &at
+			r.findings[i].RemovalReason = reason
+			return r.findings[i], nil
+		}
+	}
+	return odontogram.Finding{}, odontogram.ErrFindingNotFound
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Timezone: "Europe/Istanbul"}, nil
+}
+
+func TestChartHistory(t *testing.T) {
+	repository := &fakeOdontogramRepository{}
+	service := NewOdontogramService(repository, fakeClinicRepository{})
+	ctx := context.Background()
+
+	// 2025-03-03 10:00 and 2025-03-10 10:00 in Istanbul
+	firstVisit := time.Date(2025, time.March, 3, 7, 0, 0, 0, time.UTC)
+	secondVisit := time.Date(2025, time.March, 10, 7, 0, 0, 0, time.UTC)
+
+	service.now = func() time.Time { return firstVisit }
+	caries, err := service.RecordFinding(ctx, odontogram.Finding{PatientID: 1, Tooth: 36, Condition: odontogram.ConditionCaries, Surfaces: "OM"})
+	if err != nil {
+		t.Fatalf("RecordFinding() error = %v", err)
+	}
+	if _, err := service.RecordFinding(ctx, odontogram.Finding{PatientID: 1, Tooth: 18, Condition: odontogram.ConditionMissing}); err != nil {
+		t.Fatalf("RecordFinding() error = %v", err)
+	}
+	if _, err := service.RecordFinding(ctx, odontogram.Finding{PatientID: 1, Tooth: 36, Condition: odontogram.ConditionCaries, Surfaces: "D"}); err != nil {
+		t.Fatalf("RecordFinding() on another surface error = %v", err)
+	}
+	if _, err := service.RecordFinding(ctx, odontogram.Finding{PatientID: 1, Tooth: 36, Condition: odontogram.ConditionCaries, Surfaces: "O"}); !errors.Is(err, odontogram.ErrFindingConflict) {
+		t.Errorf("RecordFinding() on a charted surface error = %v, want ErrFindingConflict", err)
+	}
+
+	service.now = func() time.Time { return secondVisit }
+	if _, err := service.RecordFinding(ctx, odontogram.Finding{PatientID: 1, Tooth: 36, Condition: odontogram.ConditionFilling, Surfaces: "MO", Supersedes: []uint{caries.ID}}); err != nil {
+		t.Fatalf("RecordFinding() superseding the caries error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		date string
+		want map[int][]odontogram.Condition
+	}{
+		{name: "Before the first visit", date: "2025-03-02", want: map[int][]odontogram.Condition{}},
+		{
+			name: "After the first visit",
+			date: "2025-03-03",
+			want: map[int][]odontogram.Condition{18: {odontogram.ConditionMissing}, 36: {odontogram.ConditionCaries, odontogram.ConditionCaries}},
+		},
+		{
+			name: "After the filling",
+			date: "2025-03-10",
+			want: map[int][]odontogram.Condition{18: {odontogram.ConditionMissing}, 36: {odontogram.ConditionCaries, odontogram.ConditionFilling}},
+		},
+		{
+			name: "Current chart",
+			want: map[int][]odontogram.Condition{18: {odontogram.ConditionMissing}, 36: {odontogram.ConditionCaries, odontogram.ConditionFilling}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart, err := service.GetChart(ctx, 1, 1, tt.date)
+			if err != nil {
+				t.Fatalf("GetChart() error = %v", err)
+			}
+			if len(chart.Teeth) != len(tt.want) {
+				t.Fatalf("GetChart() has %d teeth, want %d", len(chart.Teeth), len(tt.want))
+			}
+			for _, tooth := range chart.Teeth {
+				want := tt.want[tooth.Tooth]
+				if len(tooth.Findings) != len(want) || tooth.Dentition != odontogram.DentitionPermanent {
+					t.Fatalf("tooth %d = %+v, want conditions %v", tooth.Tooth, tooth.Findings, want)
+				}
+				for i, finding := range tooth.Findings {
+					if finding.Condition != want[i] {
+						t.Errorf("tooth %d finding %d = %s, want %s", tooth.Tooth, i, finding.Condition, want[i])
+					}
+				}
+			}
+		})
+	}
+
+	history, err := service.GetHistory(ctx, 1)
+	if err != nil || len(history) != 4 {
+		t.Errorf("GetHistory() = %d findings, %v, want all 4", len(history), err)
+	}
+	if _, err := service.GetChart(ctx, 1, 1, "10.03.2025"); !errors.Is(err, odontogram.ErrFindingValidation) {
+		t.Errorf("GetChart() error = %v, want ErrFindingValidation for a malformed date", err)
+	}
+}
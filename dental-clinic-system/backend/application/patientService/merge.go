@@ -0,0 +1,118 @@
+package patientService
+
+import (
+	"context"
+	"dental-clinic-system/models/patient"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// maxDuplicateCandidates is the most candidate pairs scored in one duplicate search
+const maxDuplicateCandidates = 1000
+
+// maxRedirects is the longest chain of merges followed when resolving a patient
+const maxRedirects = 10
+
+// FindDuplicates returns the pairs of the clinic's patients that score at least minScore as the same
+// person, best first. A zero minScore uses patient.DefaultDuplicateScore.
+func (s *patientService) FindDuplicates(ctx context.Context, clinicID uint, minScore float64) ([]patient.Duplicate, error) {
+	if minScore == 0 {
+		minScore = patient.DefaultDuplicateScore
+	}
+	if minScore < 0 || minScore > 1 {
+		return nil, fmt.Errorf("%w: min_score must be between 0 and 1", patient.ErrMergeValidation)
+	}
+
+	candidates, err := s.patientRepository.GetDuplicateCandidates(ctx, clinicID, maxDuplicateCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := []patient.Duplicate{}
+	for _, pair := range candidates {
+		if d := patient.ScoreDuplicate(pair[0], pair[1]); d.Score >= minScore {
+			duplicates = append(duplicates, d)
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Score > duplicates[j].Score
+	})
+	return duplicates, nil
+}
+
+// MergePatients merges the duplicate into the surviving patient, moving its appointments, charts,
+// invoices, attachments and other records onto the survivor
+func (s *patientService) MergePatients(ctx context.Context, clinicID uint, req patient.MergeRequest, mergedByID uint) (patient.Merge, error) {
+	if req.SurvivorID == 0 || req.MergedID == 0 {
+		return patient.Merge{}, fmt.Errorf("%w: survivor_id and merged_id are required", patient.ErrMergeValidation)
+	}
+	if req.SurvivorID == req.MergedID {
+		return patient.Merge{}, fmt.Errorf("%w: a patient can not be merged into itself", patient.ErrMergeValidation)
+	}
+
+	return s.patientRepository.MergePatients(ctx, patient.Merge{
+		ClinicID:   clinicID,
+		SurvivorID: req.SurvivorID,
+		MergedID:   req.MergedID,
+		MergedByID: mergedByID,
+	})
+}
+
+// UndoMerge restores the merged patient with the records that were moved from it,
+// as long as the merge is younger than patient.MergeUndoWindow
+func (s *patientService) UndoMerge(ctx context.Context, id, undoneByID uint) (patient.Merge, error) {
+	merge, err := s.patientRepository.GetMerge(ctx, id)
+	if err != nil {
+		return patient.Merge{}, err
+	}
+	if merge.UndoneAt != nil {
+		return patient.Merge{}, patient.ErrMergeUndone
+	}
+	now := s.now()
+	if now.After(merge.UndoableUntil()) {
+		return patient.Merge{}, patient.ErrMergeExpired
+	}
+	return s.patientRepository.UndoMerge(ctx, id, undoneByID, now)
+}
+
+// GetMerges returns the clinic's merges, newest first
+func (s *patientService) GetMerges(ctx context.Context, clinicID uint) ([]patient.Merge, error) {
+	return s.patientRepository.GetMerges(ctx, clinicID)
+}
+
+// GetMerge retrieves a merge with the records it moved
+func (s *patientService) GetMerge(ctx context.Context, id uint) (patient.Merge, error) {
+	return s.patientRepository.GetMerge(ctx, id)
+}
+
+// ResolvePatient follows the merges of a patient of the clinic to the patient that holds its records now
+func (s *patientService) ResolvePatient(ctx context.Context, clinicID, id uint) (patient.Redirect, error) {
+	redirect := patient.Redirect{PatientID: id, CurrentID: id}
+	for i := 0; i < maxRedirects; i++ {
+		merge, err := s.patientRepository.GetActiveMerge(ctx, redirect.CurrentID)
+		if errors.Is(err, patient.ErrMergeNotFound) {
+			break
+		}
+		if err != nil {
+			return patient.Redirect{}, err
+		}
+		if merge.ClinicID != clinicID {
+			return patient.Redirect{}, patient.ErrPatientNotFound
+		}
+		redirect.CurrentID = merge.SurvivorID
+		redirect.Merged = true
+		redirect.MergeIDs = append(redirect.MergeIDs, merge.ID)
+	}
+
+	current, err := s.patientRepository.GetPatient(ctx, redirect.CurrentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && current.ClinicID != clinicID) {
+		return patient.Redirect{}, patient.ErrPatientNotFound
+	}
+	if err != nil {
+		return patient.Redirect{}, err
+	}
+	return redirect, nil
+}
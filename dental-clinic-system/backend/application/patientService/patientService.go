@@ -3,23 +3,33 @@ package patientService
 import (
 	"context"
 	"dental-clinic-system/models/patient"
+	"time"
 )
 
 type PatientRepository interface {
 	GetPatients(ctx context.Context, ClinicID uint) ([]patient.Patient, error)
 	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+	SearchPatients(ctx context.Context, query patient.SearchQuery) (patient.SearchPage, error)
 	CreatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error)
 	UpdatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error)
 	DeletePatient(ctx context.Context, id uint) error
+	GetDuplicateCandidates(ctx context.Context, clinicID uint, limit int) ([][2]patient.Patient, error)
+	MergePatients(ctx context.Context, merge patient.Merge) (patient.Merge, error)
+	UndoMerge(ctx context.Context, id, undoneByID uint, undoneAt time.Time) (patient.Merge, error)
+	GetMerges(ctx context.Context, clinicID uint) ([]patient.Merge, error)
+	GetMerge(ctx context.Context, id uint) (patient.Merge, error)
+	GetActiveMerge(ctx context.Context, mergedID uint) (patient.Merge, error)
 }
 
 type patientService struct {
 	patientRepository PatientRepository
+	now               func() time.Time
 }
 
 func NewPatientService(patientRepository PatientRepository) *patientService {
 	return &patientService{
 		patientRepository: patientRepository,
+		now:               time.Now,
 	}
 }
 
@@ -31,6 +41,14 @@ func (s *patientService) GetPatient(ctx context.Context, id uint) (patient.Patie
 	return s.patientRepository.GetPatient(ctx, id)
 }
 
+// SearchPatients returns a page of the clinic's patients matching the query, best matches first
+func (s *patientService) SearchPatients(ctx context.Context, query patient.SearchQuery) (patient.SearchPage, error) {
+	if err := query.Normalize(); err != nil {
+		return patient.SearchPage{}, err
+	}
+	return s.patientRepository.SearchPatients(ctx, query)
+}
+
 func (s *patientService) CreatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error) {
 	return s.patientRepository.CreatePatient(ctx, patient)
 }
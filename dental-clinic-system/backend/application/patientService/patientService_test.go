@@ -0,0 +1,379 @@
+package patientService
+
+import (
+	"context"
+	"dental-clinic-system/models/patient"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakePatientRepository keeps patients and merges in memory and records the searches it receives
+type fakePatientRepository struct {
+	patients   map[uint]patient.Patient
+	merges     map[uint]patient.Merge
+	candidates [][2]patient.Patient
+	searches   []patient.SearchQuery
+	undone     []uint
+}
+
+func (r *fakePatientRepository) GetPatients(ctx context.Context, clinicID uint) ([]patient.Patient, error) {
+	return nil, nil
+}
+
+func (r *fakePatientRepository) GetPatient(ctx context.Context, id uint) (patient.Patient, error) {
+	p, ok := r.patients[id]
+	if !ok {
+		return patient.Patient{}, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (r *fakePatientRepository) SearchPatients(ctx context.Context, query patient.SearchQuery) (patient.SearchPage, error) {
+	r.searches = append(r.searches, query)
+	return patient.SearchPage{Page: query.Page, PageSize: query.PageSize}, nil
+}
+
+func (r *fakePatientRepository) CreatePatient(ctx context.Context, p patient.Patient) (patient.Patient, error) {
+	return p, nil
+}
+
+func (r *fakePatientRepository) UpdatePatient(ctx context.Context, p patient.Patient) (patient.Patient, error) {
+	return p, nil
+}
+
+func (r *fakePatientRepository) DeletePatient(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *fakePatientRepository) GetDuplicateCandidates(ctx context.Context, clinicID uint, limit int) ([][2]patient.Patient, error) {
+	return r.candidates, nil
+}
+
+func (r *fakePatientRepository) MergePatients(ctx context.Context, merge patient.Merge) (patient.Merge, error) {
+	survivor, okSurvivor := r.patients[merge.SurvivorID]
+	merged, okMerged := r.patients[merge.MergedID]
+	if !okSurvivor || !okMerged || survivor.ClinicID != merge.ClinicID || merged.ClinicID != merge.ClinicID {
+		return patient.Merge{}, patient.ErrPatientNotFound
+	}
+	delete(r.patients, merge.MergedID)
+	merge.ID = uint(len(r.merges) + 1)
+	merge.CreatedAt = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	r.merges[merge.ID] = merge
+	return merge, nil
+}
+
+func (r *fakePatientRepository) UndoMerge(ctx context.Context, id, undoneByID uint, undoneAt time.Time) (patient.Merge, error) {
+	merge := r.merges[id]
+	merge.UndoneAt = &undoneAt
+	merge.UndoneByID = &undoneByID
+	r.merges[id] = merge
+	r.undone = append(r.undone, id)
+	return merge, nil
+}
+
+func (r *fakePatientRepository) GetMerges(ctx context.Context, clinicID uint) ([]patient.Merge, error) {
+	return nil, nil
+}
+
+func (r *fakePatientRepository) GetMerge(ctx context.Context, id uint) (patient.Merge, error) {
+	merge, ok := r.merges[id]
+	if !ok {
+		return patient.Merge{}, patient.ErrMergeNotFound
+	}
+	return merge, nil
+}
+
+func (r *fakePatientRepository) GetActiveMerge(ctx context.Context, mergedID uint) (patient.Merge, error) {
+	for _, merge := range r.merges {
+		if merge.MergedID == mergedID && merge.UndoneAt == nil {
+			return merge, nil
+		}
+	}
+	return patient.Merge{}, patient.ErrMergeNotFound
+}
+
+func testPatient(id, clinicID uint, nationalID, name, birthDate, contactInfo string) patient.Patient {
+	p := patient.Patient{NationalID: nationalID, Name: name, BirthDate: birthDate, ContactInfo: contactInfo, ClinicID: clinicID}
+	p.ID = id
+	return p
+}
+
+func newMergeTestService() (*patientService, *fakePatientRepository) {
+	repo := &fakePatientRepository{
+		patients: map[uint]patient.Patient{
+			1: testPatient(1, 1, "10000000146", "Şükrü Öztürk", "1985-03-12", "0532 123 45 67"),
+			2: testPatient(2, 1, "10000000164", "Sukru Ozturk", "12.03.1985", "+90 532 123 45 67"),
+			3: testPatient(3, 1, "20000000000", "Mehmet Öz", "1990-01-01", "m@example.com"),
+			4: testPatient(4, 2, "30000000000", "Başka Klinik", "", ""),
+		},
+		merges: map[uint]patient.Merge{},
+	}
+	s := NewPatientService(repo)
+	s.now = func() time.Time { return time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC) }
+	return s, repo
+}
+
+func TestSearchPatients(t *testing.T) {
+	repo := &fakePatientRepository{}
+	s := NewPatientService(repo)
+
+	page, err := s.SearchPatients(context.Background(), patient.SearchQuery{ClinicID: 1, Query: "  şükrü "})
+	if err != nil {
+		t.Fatalf("SearchPatients() error = %v", err)
+	}
+	if page.Page != 1 || page.PageSize != patient.DefaultSearchPageSize {
+		t.Errorf("page = %+v, want the first page of the default size", page)
+	}
+	if got := repo.searches[0]; got.ClinicID != 1 || got.Query != "şükrü" {
+		t.Errorf("search = %+v", got)
+	}
+
+	tests := []struct {
+		name  string
+		query patient.SearchQuery
+	}{
+		{"empty", patient.SearchQuery{ClinicID: 1}},
+		{"one letter", patient.SearchQuery{ClinicID: 1, Query: "Ş"}},
+		{"punctuation only", patient.SearchQuery{ClinicID: 1, Query: "-- .."}},
+		{"page too large", patient.SearchQuery{ClinicID: 1, Query: "ali", PageSize: patient.MaxSearchPageSize + 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.SearchPatients(context.Background(), tt.query); !errors.Is(err, patient.ErrInvalidSearch) {
+				t.Errorf("SearchPatients() error = %v, want %v", err, patient.ErrInvalidSearch)
+			}
+		})
+	}
+	if len(repo.searches) != 1 {
+		t.Errorf("invalid searches reached the repository: %+v", repo.searches)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Şükrü Öztürk", "sukru ozturk"},
+		{"ŞÜKRÜ ÖZTÜRK", "sukru ozturk"},
+		{"sukru ozturk", "sukru ozturk"},
+		{"IŞIK Çağlar", "isik caglar"},
+		{"İpek Gül", "ipek gul"},
+		// "İ" typed as "I" with a combining dot above
+		{"I\u0307pek", "ipek"},
+		{"Hâkim-Yılmaz,  Ali", "hakim yilmaz ali"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := patient.Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  patient.SearchTerms
+	}{
+		{"Şükrü Öztürk", patient.SearchTerms{Name: "sukru ozturk", Words: []string{"sukru", "ozturk"}}},
+		{"1000", patient.SearchTerms{Name: "1000", Words: []string{"1000"}, NationalID: "1000", Phone: "1000"}},
+		{"123", patient.SearchTerms{Name: "123", Words: []string{"123"}, NationalID: "123"}},
+		{"0532 123", patient.SearchTerms{Name: "0532 123", Words: []string{"0532", "123"}, NationalID: "0532123", Phone: "532123"}},
+		{"+90 (532) 123 45 67", patient.SearchTerms{Name: "90 532 123 45 67", Words: []string{"90", "532", "123", "45", "67"},
+			NationalID: "905321234567", Phone: "5321234567"}},
+		{"12.03.1985", patient.SearchTerms{Name: "12 03 1985", Words: []string{"12", "03", "1985"}, BirthDate: "1985-03-12"}},
+		{"1985-03-12", patient.SearchTerms{Name: "1985 03 12", Words: []string{"1985", "03", "12"}, BirthDate: "1985-03-12",
+			NationalID: "19850312", Phone: "19850312"}},
+	}
+	for _, tt := range tests {
+		if got := (patient.SearchQuery{Query: tt.query}).Terms(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSetSearchFields(t *testing.T) {
+	p := patient.Patient{Name: "Şükrü Öztürk", ContactInfo: "0 (532) 123 45 67", BirthDate: "12/03/1985"}
+	p.SetSearchFields()
+	if p.SearchName != "sukru ozturk" || p.SearchPhone != "5321234567" || p.SearchBirthDate != "1985-03-12" {
+		t.Errorf("search fields = %q, %q, %q", p.SearchName, p.SearchPhone, p.SearchBirthDate)
+	}
+
+	p = patient.Patient{Name: "Ayşe", ContactInfo: "ayse@example.com", BirthDate: "unknown"}
+	p.SetSearchFields()
+	if p.SearchPhone != "" || p.SearchBirthDate != "" {
+		t.Errorf("search fields = %q, %q, want no phone or birth date", p.SearchPhone, p.SearchBirthDate)
+	}
+}
+
+func TestScoreDuplicate(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        patient.Patient
+		wantScore   float64
+		wantReasons []string
+	}{
+		{
+			"same person typed differently",
+			testPatient(1, 1, "10000000146", "Şükrü Öztürk", "1985-03-12", "0532 123 45 67"),
+			testPatient(2, 1, "10000000164", "SUKRU OZTURK", "12.03.1985", "+90 532 123 45 67"),
+			1,
+			[]string{patient.ReasonSimilarNationalID, patient.ReasonPhone, patient.ReasonBirthDate, patient.ReasonName},
+		},
+		{
+			"swapped national ID digits and birth date",
+			testPatient(1, 1, "10000000146", "Ali Veli", "1990-05-01", ""),
+			testPatient(2, 1, "10000000416", "Can Öz", "1990-05-01", ""),
+			0.3,
+			[]string{patient.ReasonSimilarNationalID, patient.ReasonBirthDate},
+		},
+		{
+			"same name and birth date",
+			testPatient(1, 1, "10000000146", "Ayşe Kaya", "1990-05-01", "ayse@example.com"),
+			testPatient(2, 1, "20000000000", "Ayse Kaya", "01/05/1990", "0555 000 00 00"),
+			0.6,
+			[]string{patient.ReasonBirthDate, patient.ReasonName},
+		},
+		{
+			"family sharing a phone",
+			testPatient(1, 1, "10000000146", "Ayşe Kaya", "1990-05-01", "0555 000 00 00"),
+			testPatient(2, 1, "20000000000", "Mehmet Kaya", "2015-09-09", "0555 000 00 00"),
+			0.418,
+			[]string{patient.ReasonPhone},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := patient.ScoreDuplicate(tt.a, tt.b)
+			if d.Score != tt.wantScore || !reflect.DeepEqual(d.Reasons, tt.wantReasons) {
+				t.Errorf("ScoreDuplicate() = %v %v, want %v %v", d.Score, d.Reasons, tt.wantScore, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	s, repo := newMergeTestService()
+	repo.candidates = [][2]patient.Patient{
+		{repo.patients[1], repo.patients[3]},
+		{repo.patients[1], repo.patients[2]},
+	}
+
+	duplicates, err := s.FindDuplicates(context.Background(), 1, 0)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(duplicates) != 1 || duplicates[0].First.ID != 1 || duplicates[0].Second.ID != 2 {
+		t.Errorf("duplicates = %+v", duplicates)
+	}
+
+	if duplicates, _ := s.FindDuplicates(context.Background(), 1, 0.01); len(duplicates) != 2 || duplicates[0].Second.ID != 2 {
+		t.Errorf("duplicates = %+v, want both pairs, best first", duplicates)
+	}
+	if _, err := s.FindDuplicates(context.Background(), 1, 1.5); !errors.Is(err, patient.ErrMergeValidation) {
+		t.Errorf("FindDuplicates() error = %v, want %v", err, patient.ErrMergeValidation)
+	}
+}
+
+func TestMergePatients(t *testing.T) {
+	s, _ := newMergeTestService()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		req  patient.MergeRequest
+		want error
+	}{
+		{"missing survivor", patient.MergeRequest{MergedID: 2}, patient.ErrMergeValidation},
+		{"into itself", patient.MergeRequest{SurvivorID: 1, MergedID: 1}, patient.ErrMergeValidation},
+		{"other clinic's patient", patient.MergeRequest{SurvivorID: 1, MergedID: 4}, patient.ErrPatientNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.MergePatients(ctx, 1, tt.req, 9); !errors.Is(err, tt.want) {
+				t.Errorf("MergePatients() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	merge, err := s.MergePatients(ctx, 1, patient.MergeRequest{SurvivorID: 1, MergedID: 2}, 9)
+	if err != nil {
+		t.Fatalf("MergePatients() error = %v", err)
+	}
+	if merge.ClinicID != 1 || merge.SurvivorID != 1 || merge.MergedID != 2 || merge.MergedByID != 9 {
+		t.Errorf("merge = %+v", merge)
+	}
+
+	// The merged patient redirects to the survivor
+	redirect, err := s.ResolvePatient(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("ResolvePatient() error = %v", err)
+	}
+	if !redirect.Merged || redirect.CurrentID != 1 || !reflect.DeepEqual(redirect.MergeIDs, []uint{merge.ID}) {
+		t.Errorf("redirect = %+v", redirect)
+	}
+
+	// Merges are followed through to the last survivor
+	second, err := s.MergePatients(ctx, 1, patient.MergeRequest{SurvivorID: 3, MergedID: 1}, 9)
+	if err != nil {
+		t.Fatalf("MergePatients() error = %v", err)
+	}
+	if redirect, _ := s.ResolvePatient(ctx, 1, 2); redirect.CurrentID != 3 || len(redirect.MergeIDs) != 2 {
+		t.Errorf("redirect = %+v, want patient 3 through both merges", redirect)
+	}
+	if redirect, _ := s.ResolvePatient(ctx, 1, 3); redirect.Merged || redirect.CurrentID != 3 {
+		t.Errorf("redirect = %+v, want the patient itself", redirect)
+	}
+	if _, err := s.ResolvePatient(ctx, 2, 2); !errors.Is(err, patient.ErrPatientNotFound) {
+		t.Errorf("ResolvePatient() from another clinic error = %v, want %v", err, patient.ErrPatientNotFound)
+	}
+	if _, err := s.ResolvePatient(ctx, 1, 99); !errors.Is(err, patient.ErrPatientNotFound) {
+		t.Errorf("ResolvePatient() error = %v, want %v", err, patient.ErrPatientNotFound)
+	}
+
+	if _, err := s.UndoMerge(ctx, second.ID, 9); err != nil {
+		t.Fatalf("UndoMerge() error = %v", err)
+	}
+}
+
+func TestUndoMerge(t *testing.T) {
+	s, repo := newMergeTestService()
+	ctx := context.Background()
+
+	merge, err := s.MergePatients(ctx, 1, patient.MergeRequest{SurvivorID: 1, MergedID: 2}, 9)
+	if err != nil {
+		t.Fatalf("MergePatients() error = %v", err)
+	}
+
+	undone, err := s.UndoMerge(ctx, merge.ID, 7)
+	if err != nil {
+		t.Fatalf("UndoMerge() error = %v", err)
+	}
+	if undone.UndoneAt == nil || !undone.UndoneAt.Equal(s.now()) || undone.UndoneByID == nil || *undone.UndoneByID != 7 {
+		t.Errorf("undone merge = %+v", undone)
+	}
+	if _, err := s.UndoMerge(ctx, merge.ID, 7); !errors.Is(err, patient.ErrMergeUndone) {
+		t.Errorf("second UndoMerge() error = %v, want %v", err, patient.ErrMergeUndone)
+	}
+	if _, err := s.UndoMerge(ctx, 99, 7); !errors.Is(err, patient.ErrMergeNotFound) {
+		t.Errorf("UndoMerge() error = %v, want %v", err, patient.ErrMergeNotFound)
+	}
+
+	// Merges older than the retention window are final
+	expired, err := s.MergePatients(ctx, 1, patient.MergeRequest{SurvivorID: 1, MergedID: 3}, 9)
+	if err != nil {
+		t.Fatalf("MergePatients() error = %v", err)
+	}
+	s.now = func() time.Time { return expired.CreatedAt.Add(patient.MergeUndoWindow + time.Minute) }
+	if _, err := s.UndoMerge(ctx, expired.ID, 7); !errors.Is(err, patient.ErrMergeExpired) {
+		t.Errorf("UndoMerge() error = %v, want %v", err, patient.ErrMergeExpired)
+	}
+	if len(repo.undone) != 1 {
+		t.Errorf("undone merges = %v, want only the first", repo.undone)
+	}
+}
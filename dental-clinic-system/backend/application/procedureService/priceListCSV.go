@@ -0,0 +1,182 @@
+package procedureService
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// priceListColumns maps accepted CSV header names, English or Turkish, to price list columns
+var priceListColumns = map[string]string{
+	"code":             "code",
+	"kod":              "code",
+	"name":             "name",
+	"ad":               "name",
+	"işlem":            "name",
+	"price":            "price",
+	"fiyat":            "price",
+	"duration_minutes": "duration_minutes",
+	"duration":         "duration_minutes",
+	"süre":             "duration_minutes",
+	"vat_rate":         "vat_rate",
+	"vat":              "vat_rate",
+	"kdv":              "vat_rate",
+}
+
+// priceListRow is one line of an imported price list
+type priceListRow struct {
+	Line            int
+	Code            string
+	Name            string
+	Price           int64
+	DurationMinutes *int
+	VATRate         *int
+}
+
+// parsePriceListCSV reads a price list with a header row. The code and price columns are required;
+// name, duration_minutes and vat_rate are optional. Both comma and semicolon separated files are
+// accepted, as spreadsheets in a Turkish locale export with semicolons.
+func parsePriceListCSV(r io.Reader) ([]priceListRow, error) {
+	buffered := bufio.NewReader(r)
+	firstLine, err := buffered.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if newline := bytes.IndexByte(firstLine, '\n'); newline >= 0 {
+		firstLine = firstLine[:newline]
+	}
+
+	reader := csv.NewReader(buffered)
+	if bytes.Count(firstLine, []byte{';'}) > bytes.Count(firstLine, []byte{','}) {
+		reader.Comma = ';'
+	}
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if column, ok := priceListColumns[name]; ok {
+			columns[column] = i
+		}
+	}
+	for _, required := range []string{"code", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("header has no %s column", required)
+		}
+	}
+
+	var rows []priceListRow
+	var problems []string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		row := priceListRow{Line: line, Code: field("code"), Name: field("name")}
+		if row.Code == "" {
+			problems = append(problems, fmt.Sprintf("line %d: code is required", line))
+			continue
+		}
+		if row.Price, err = parseMinorUnits(field("price")); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %s", line, err))
+			continue
+		}
+		if row.DurationMinutes, err = parseOptionalInt(field("duration_minutes")); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: duration: %s", line, err))
+			continue
+		}
+		if row.VATRate, err = parseOptionalInt(strings.TrimPrefix(field("vat_rate"), "%")); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: vat rate: %s", line, err))
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file has no prices")
+	}
+	return rows, nil
+}
+
+// parseMinorUnits converts a decimal amount such as "1.250,50", "1,250.50" or "1250" to minor units.
+// When both separators appear the last one is the decimal separator; a single separator followed by
+// exactly three digits is read as a thousands separator.
+func parseMinorUnits(value string) (int64, error) {
+	cleaned := strings.NewReplacer(" ", "", "\u00a0", "", "₺", "", "TL", "", "TRY", "").Replace(value)
+	if cleaned == "" {
+		return 0, errors.New("price is required")
+	}
+
+	decimal := -1
+	lastDot, lastComma := strings.LastIndex(cleaned, "."), strings.LastIndex(cleaned, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimal = max(lastDot, lastComma)
+	case lastDot >= 0 || lastComma >= 0:
+		separator := max(lastDot, lastComma)
+		if strings.Count(cleaned, cleaned[separator:separator+1]) == 1 && len(cleaned)-separator-1 != 3 {
+			decimal = separator
+		}
+	}
+
+	whole, fraction := cleaned, ""
+	if decimal >= 0 {
+		whole, fraction = cleaned[:decimal], cleaned[decimal+1:]
+	}
+	whole = strings.NewReplacer(".", "", ",", "").Replace(whole)
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("price %q has more than two decimals", value)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+	if whole == "" {
+		whole = "0"
+	}
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || strings.ContainsAny(whole+fraction, "+-") {
+		return 0, fmt.Errorf("price %q is not a valid amount", value)
+	}
+	return amount, nil
+}
+
+func parseOptionalInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a whole number", value)
+	}
+	return &parsed, nil
+}
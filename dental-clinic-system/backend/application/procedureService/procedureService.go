@@ -2,7 +2,16 @@ package procedureService
 
 import (
 	"context"
+	"dental-clinic-system/models/clinic"
 	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/validations"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
 )
 
 type ProcedureRepository interface {
@@ -11,15 +20,29 @@ type ProcedureRepository interface {
 	CreateProcedure(ctx context.Context, procedure procedure.Procedure) (procedure.Procedure, error)
 	UpdateProcedure(ctx context.Context, procedure procedure.Procedure) (procedure.Procedure, error)
 	DeleteProcedure(ctx context.Context, id uint) error
+	GetPriceLists(ctx context.Context, clinicID uint) ([]procedure.PriceList, error)
+	GetPriceList(ctx context.Context, id uint) (procedure.PriceList, error)
+	CreatePriceList(ctx context.Context, list procedure.PriceList, procedures []procedure.Procedure) (procedure.PriceList, error)
+	DeletePriceList(ctx context.Context, id uint, today string) error
+	GetPriceEntry(ctx context.Context, procedureID uint, date string) (procedure.PriceList, procedure.PriceListEntry, error)
+}
+
+// ClinicRepository is used to resolve the time zone of a clinic
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
 }
 
 type procedureService struct {
 	procedureRepository ProcedureRepository
+	clinicRepository    ClinicRepository
+	now                 func() time.Time
 }
 
-func NewProcedureService(procedureRepository ProcedureRepository) *procedureService {
+func NewProcedureService(procedureRepository ProcedureRepository, clinicRepository ClinicRepository) *procedureService {
 	return &procedureService{
 		procedureRepository: procedureRepository,
+		clinicRepository:    clinicRepository,
+		now:                 time.Now,
 	}
 }
 
@@ -31,14 +54,188 @@ func (s *procedureService) GetProcedure(ctx context.Context, id uint) (procedure
 	return s.procedureRepository.GetProcedure(ctx, id)
 }
 
-func (s *procedureService) CreateProcedure(ctx context.Context, procedure procedure.Procedure) (procedure.Procedure, error) {
-	return s.procedureRepository.CreateProcedure(ctx, procedure)
+func (s *procedureService) CreateProcedure(ctx context.Context, proc procedure.Procedure) (procedure.Procedure, error) {
+	if err := validations.ProcedureValidation(&proc); err != nil {
+		log.Warn().
+			Str("operation", "CreateProcedure").
+			Err(err).
+			Uint("clinic_id", proc.ClinicID).
+			Msg("Procedure validation failed")
+		return procedure.Procedure{}, fmt.Errorf("%w: %s", procedure.ErrProcedureValidation, err.Error())
+	}
+	return s.procedureRepository.CreateProcedure(ctx, proc)
 }
 
-func (s *procedureService) UpdateProcedure(ctx context.Context, procedure procedure.Procedure) (procedure.Procedure, error) {
-	return s.procedureRepository.UpdateProcedure(ctx, procedure)
+func (s *procedureService) UpdateProcedure(ctx context.Context, proc procedure.Procedure) (procedure.Procedure, error) {
+	if err := validations.ProcedureValidation(&proc); err != nil {
+		log.Warn().
+			Str("operation", "UpdateProcedure").
+			Err(err).
+			Uint("procedure_id", proc.ID).
+			Msg("Procedure validation failed")
+		return procedure.Procedure{}, fmt.Errorf("%w: %s", procedure.ErrProcedureValidation, err.Error())
+	}
+	return s.procedureRepository.UpdateProcedure(ctx, proc)
 }
 
 func (s *procedureService) DeleteProcedure(ctx context.Context, id uint) error {
 	return s.procedureRepository.DeleteProcedure(ctx, id)
 }
+
+// GetPriceLists retrieves the price list versions of a clinic, newest first
+func (s *procedureService) GetPriceLists(ctx context.Context, clinicID uint) ([]procedure.PriceList, error) {
+	return s.procedureRepository.GetPriceLists(ctx, clinicID)
+}
+
+// GetPriceList retrieves a price list with its prices
+func (s *procedureService) GetPriceList(ctx context.Context, id uint) (procedure.PriceList, error) {
+	return s.procedureRepository.GetPriceList(ctx, id)
+}
+
+// CreatePriceList validates and creates a new price list version. Lists can only take effect from
+// today onwards so prices that were already charged are never changed.
+func (s *procedureService) CreatePriceList(ctx context.Context, list procedure.PriceList) (procedure.PriceList, error) {
+	if err := s.validatePriceList(ctx, &list); err != nil {
+		return procedure.PriceList{}, err
+	}
+	return s.procedureRepository.CreatePriceList(ctx, list, nil)
+}
+
+// ImportPriceList creates a price list version from a CSV file. Procedures are matched by code;
+// unknown codes create new procedures, which then need a name. Durations and VAT rates given in
+// the file are applied to the procedures.
+func (s *procedureService) ImportPriceList(ctx context.Context, list procedure.PriceList, file io.Reader) (procedure.PriceList, error) {
+	rows, err := parsePriceListCSV(file)
+	if err != nil {
+		log.Warn().
+			Str("operation", "ImportPriceList").
+			Err(err).
+			Uint("clinic_id", list.ClinicID).
+			Msg("Price list file could not be read")
+		return procedure.PriceList{}, fmt.Errorf("%w: %s", procedure.ErrPriceListValidation, err.Error())
+	}
+
+	existing, err := s.procedureRepository.GetProcedures(ctx, list.ClinicID)
+	if err != nil {
+		return procedure.PriceList{}, err
+	}
+	byCode := make(map[string]procedure.Procedure, len(existing))
+	for _, proc := range existing {
+		if proc.Code != "" {
+			byCode[proc.Code] = proc
+		}
+	}
+
+	var procedures []procedure.Procedure
+	list.Entries = make([]procedure.PriceListEntry, 0, len(rows))
+	for _, row := range rows {
+		proc, known := byCode[row.Code]
+		changed := !known
+		if !known {
+			proc = procedure.Procedure{Code: row.Code, Name: row.Name, ClinicID: list.ClinicID}
+		}
+		if row.DurationMinutes != nil && *row.DurationMinutes != proc.DefaultDurationMinutes {
+			proc.DefaultDurationMinutes = *row.DurationMinutes
+			changed = true
+		}
+		if row.VATRate != nil && *row.VATRate != proc.VATRate {
+			proc.VATRate = *row.VATRate
+			changed = true
+		}
+		if changed {
+			if err := validations.ProcedureValidation(&proc); err != nil {
+				return procedure.PriceList{}, fmt.Errorf("%w: line %d: %s", procedure.ErrPriceListValidation, row.Line, err.Error())
+			}
+			procedures = append(procedures, proc)
+		}
+		list.Entries = append(list.Entries, procedure.PriceListEntry{Code: row.Code, Price: row.Price})
+	}
+
+	if err := s.validatePriceList(ctx, &list); err != nil {
+		return procedure.PriceList{}, err
+	}
+	return s.procedureRepository.CreatePriceList(ctx, list, procedures)
+}
+
+// DeletePriceList deletes a price list version that has not taken effect yet
+func (s *procedureService) DeletePriceList(ctx context.Context, id uint) error {
+	list, err := s.procedureRepository.GetPriceList(ctx, id)
+	if err != nil {
+		return err
+	}
+	today, err := s.clinicDate(ctx, list.ClinicID, s.now())
+	if err != nil {
+		return err
+	}
+	return s.procedureRepository.DeletePriceList(ctx, id, today)
+}
+
+// GetPriceAt returns the price of a procedure on a date (YYYY-MM-DD), today in the clinic's
+// time zone when the date is empty, with the VAT of the procedure added
+func (s *procedureService) GetPriceAt(ctx context.Context, procedureID uint, date string) (procedure.Price, error) {
+	proc, err := s.procedureRepository.GetProcedure(ctx, procedureID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return procedure.Price{}, procedure.ErrProcedureNotFound
+	}
+	if err != nil {
+		return procedure.Price{}, err
+	}
+
+	if date == "" {
+		if date, err = s.clinicDate(ctx, proc.ClinicID, s.now()); err != nil {
+			return procedure.Price{}, err
+		}
+	} else if _, err := time.Parse(procedure.DateLayout, date); err != nil {
+		return procedure.Price{}, fmt.Errorf("%w: date must be in YYYY-MM-DD format", procedure.ErrPriceListValidation)
+	}
+
+	list, entry, err := s.procedureRepository.GetPriceEntry(ctx, procedureID, date)
+	if err != nil {
+		return procedure.Price{}, err
+	}
+
+	vat := procedure.VATAmount(entry.Price, proc.VATRate)
+	return procedure.Price{
+		ProcedureID:   proc.ID,
+		Code:          proc.Code,
+		Name:          proc.Name,
+		Date:          date,
+		PriceListID:   list.ID,
+		EffectiveFrom: list.EffectiveFrom,
+		Currency:      list.Currency,
+		NetPrice:      entry.Price,
+		VATRate:       proc.VATRate,
+		VATAmount:     vat,
+		GrossPrice:    entry.Price + vat,
+	}, nil
+}
+
+// validatePriceList checks the list and that it does not take effect in the past
+func (s *procedureService) validatePriceList(ctx context.Context, list *procedure.PriceList) error {
+	if err := validations.PriceListValidation(list); err != nil {
+		log.Warn().
+			Str("operation", "CreatePriceList").
+			Err(err).
+			Uint("clinic_id", list.ClinicID).
+			Msg("Price list validation failed")
+		return fmt.Errorf("%w: %s", procedure.ErrPriceListValidation, err.Error())
+	}
+
+	today, err := s.clinicDate(ctx, list.ClinicID, s.now())
+	if err != nil {
+		return err
+	}
+	if list.EffectiveFrom < today {
+		return fmt.Errorf("%w: effective_from can not be before today (%s)", procedure.ErrPriceListValidation, today)
+	}
+	return nil
+}
+
+// clinicDate formats a time as a date in the clinic's time zone
+func (s *procedureService) clinicDate(ctx context.Context, clinicID uint, t time.Time) (string, error) {
+	cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+	if err != nil {
+		return "", err
+	}
+	return t.In(cln.Location()).Format(procedure.DateLayout), nil
+}
@@ -0,0 +1,295 @@
+package procedureService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/procedure"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeProcedureRepository keeps procedures and price lists in memory
+type fakeProcedureRepository struct {
+	procedures []procedure.Procedure
+	lists      []procedure.PriceList
+	saved      []procedure.Procedure
+}
+
+func (r *fakeProcedureRepository) GetProcedures(ctx context.Context, clinicID uint) ([]procedure.Procedure, error) {
+	var procs []procedure.Procedure
+	for _, proc := range r.procedures {
+		if proc.ClinicID == clinicID {
+			procs = append(procs, proc)
+		}
+	}
+	return procs, nil
+}
+
+func (r *fakeProcedureRepository) GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error) {
+	for _, proc := range r.procedures {
+		if proc.ID == id {
+			return proc, nil
+		}
+	}
+	return procedure.Procedure{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeProcedureRepository) CreateProcedure(ctx context.Context, proc procedure.Procedure) (procedure.Procedure, error) {
+	return proc, nil
+}
+
+func (r *fakeProcedureRepository) UpdateProcedure(ctx context.Context, proc procedure.Procedure) (procedure.Procedure, error) {
+	return proc, nil
+}
+
+func (r *fakeProcedureRepository) DeleteProcedure(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *fakeProcedureRepository) GetPriceLists(ctx context.Context, clinicID uint) ([]procedure.PriceList, error) {
+	return r.lists, nil
+}
+
+func (r *fakeProcedureRepository) GetPriceList(ctx context.Context, id uint) (procedure.PriceList, error) {
+	for _, list := range r.lists {
+		if list.ID == id {
+			return list, nil
+		}
+	}
+	return procedure.PriceList{}, procedure.ErrPriceListNotFound
+}
+
+func (r *fakeProcedureRepository) CreatePriceList(ctx context.Context, list procedure.PriceList, procedures []procedure.Procedure) (procedure.PriceList, error) {
+	r.saved = procedures
+	list.ID = uint(len(r.lists) + 1)
+	r.lists = append(r.lists, list)
+	return list, nil
+}
+
+func (r *fakeProcedureRepository) DeletePriceList(ctx context.Context, id uint, today string) error {
+	return nil
+}
+
+// GetPriceEntry picks the newest list effective on the date that prices the procedure
+func (r *fakeProcedureRepository) GetPriceEntry(ctx context.Context, procedureID uint, date string) (procedure.PriceList, procedure.PriceListEntry, error) {
+	var found procedure.PriceList
+	var foundEntry procedure.PriceListEntry
+	for _, list := range r.lists {
+		if list.EffectiveFrom > date || list.EffectiveFrom <= found.EffectiveFrom {
+			continue
+		}
+		for _, entry := range list.Entries {
+			if entry.ProcedureID == procedureID {
+				found, foundEntry = list, entry
+			}
+		}
+	}
+	if found.ID == 0 {
+		return procedure.PriceList{}, procedure.PriceListEntry{}, procedure.ErrPriceNotFound
+	}
+	return found, foundEntry, nil
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Timezone: "Europe/Istanbul"}, nil
+}
+
+func newTestService(repository *fakeProcedureRepository, now time.Time) *procedureService {
+	service := NewProcedureService(repository, fakeClinicRepository{})
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestGetPriceAt(t *testing.T) {
+	repository := &fakeProcedureRepository{
+		procedures: []procedure.Procedure{{Model: gorm.Model{ID: 7}, ClinicID: 1, Code: "401", Name: "Kompozit dolgu", VATRate: 10}},
+		lists: []procedure.PriceList{
+			{Model: gorm.Model{ID: 1}, ClinicID: 1, Currency: "TRY", EffectiveFrom: "2024-01-01", Entries: []procedure.PriceListEntry{{ProcedureID: 7, Price: 150000}}},
+			{Model: gorm.Model{ID: 2}, ClinicID: 1, Currency: "TRY", EffectiveFrom: "2025-01-01", Entries: []procedure.PriceListEntry{{ProcedureID: 7, Price: 199995}}},
+		},
+	}
+	// 23:30 UTC on New Year's Eve is already 1 January in Istanbul
+	service := newTestService(repository, time.Date(2024, time.December, 31, 23, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		name      string
+		date      string
+		wantList  uint
+		wantNet   int64
+		wantVAT   int64
+		wantError error
+	}{
+		{name: "Old list on its first day", date: "2024-01-01", wantList: 1, wantNet: 150000, wantVAT: 15000},
+		{name: "Old list before the new one takes effect", date: "2024-12-31", wantList: 1, wantNet: 150000, wantVAT: 15000},
+		{name: "New list, VAT rounded half up", date: "2025-06-30", wantList: 2, wantNet: 199995, wantVAT: 20000},
+		{name: "Today in the clinic's time zone", date: "", wantList: 2, wantNet: 199995, wantVAT: 20000},
+		{name: "Before any list", date: "2023-12-31", wantError: procedure.ErrPriceNotFound},
+		{name: "Invalid date", date: "31.12.2024", wantError: procedure.ErrPriceListValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := service.GetPriceAt(context.Background(), 7, tt.date)
+			if tt.wantError != nil {
+				if !errors.Is(err, tt.wantError) {
+					t.Fatalf("GetPriceAt() error = %v, want %v", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPriceAt() error = %v", err)
+			}
+			if price.PriceListID != tt.wantList || price.NetPrice != tt.wantNet || price.VATAmount != tt.wantVAT || price.GrossPrice != tt.wantNet+tt.wantVAT {
+				t.Errorf("GetPriceAt() = %+v, want list %d, net %d, vat %d", price, tt.wantList, tt.wantNet, tt.wantVAT)
+			}
+		})
+	}
+
+	if _, err := service.GetPriceAt(context.Background(), 99, ""); !errors.Is(err, procedure.ErrProcedureNotFound) {
+		t.Errorf("GetPriceAt() for an unknown procedure error = %v, want ErrProcedureNotFound", err)
+	}
+}
+
+func TestParseMinorUnits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "1250", want: 125000},
+		{value: "1250,5", want: 125050},
+		{value: "1250.50", want: 125050},
+		{value: "1.250,50", want: 125050},
+		{value: "1,250.50", want: 125050},
+		{value: "1.250", want: 125000},
+		{value: "1.250.000", want: 125000000},
+		{value: "₺ 1.250,00", want: 125000},
+		{value: "0,99 TL", want: 99},
+		{value: "12,345", want: 1234500},
+		{value: "", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "1,2345", wantErr: true},
+		{value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseMinorUnits(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMinorUnits(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseMinorUnits(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePriceListCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []priceListRow
+		wantErr string
+	}{
+		{
+			name: "Turkish spreadsheet export",
+			file: "\ufeffKod;İşlem;Fiyat;KDV\n401;Kompozit dolgu;1.250,00;%10\n\n402;Amalgam dolgu;900;10\n",
+			want: []priceListRow{
+				{Line: 2, Code: "401", Name: "Kompozit dolgu", Price: 125000},
+				{Line: 4, Code: "402", Name: "Amalgam dolgu", Price: 90000},
+			},
+		},
+		{
+			name: "Comma separated with duration",
+			file: "code,price,duration_minutes\nD2391,\"1,500.00\",45\n",
+			want: []priceListRow{{Line: 2, Code: "D2391", Price: 150000}},
+		},
+		{name: "Missing price column", file: "code;name\n401;Dolgu\n", wantErr: "no price column"},
+		{name: "Only a header", file: "code;price\n", wantErr: "no prices"},
+		{name: "Row errors are reported together", file: "code;price\n;100\n401;abc\n", wantErr: "line 2: code is required; line 3:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parsePriceListCSV(strings.NewReader(tt.file))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parsePriceListCSV() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePriceListCSV() error = %v", err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("parsePriceListCSV() returned %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := rows[i]
+				if got.Line != want.Line || got.Code != want.Code || got.Name != want.Name || got.Price != want.Price {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportPriceList(t *testing.T) {
+	today := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	existing := []procedure.Procedure{{Model: gorm.Model{ID: 7}, ClinicID: 1, Code: "401", Name: "Kompozit dolgu", VATRate: 10}}
+
+	t.Run("Creates unknown procedures and updates changed ones", func(t *testing.T) {
+		repository := &fakeProcedureRepository{procedures: existing}
+		service := newTestService(repository, today)
+
+		file := "code;name;price;duration_minutes;vat_rate\n401;;1500;45;10\n402;Amalgam dolgu;900;;20\n"
+		list, err := service.ImportPriceList(context.Background(), procedure.PriceList{ClinicID: 1, EffectiveFrom: "2025-04-01"}, strings.NewReader(file))
+		if err != nil {
+			t.Fatalf("ImportPriceList() error = %v", err)
+		}
+		if list.Currency != procedure.DefaultCurrency || len(list.Entries) != 2 || list.Entries[1].Code != "402" || list.Entries[1].Price != 90000 {
+			t.Errorf("ImportPriceList() = %+v", list)
+		}
+		if len(repository.saved) != 2 {
+			t.Fatalf("saved %d procedures, want 2", len(repository.saved))
+		}
+		if updated := repository.saved[0]; updated.ID != 7 || updated.DefaultDurationMinutes != 45 || updated.Name != "Kompozit dolgu" {
+			t.Errorf("updated procedure = %+v", updated)
+		}
+		if created := repository.saved[1]; created.ID != 0 || created.Name != "Amalgam dolgu" || created.VATRate != 20 {
+			t.Errorf("created procedure = %+v", created)
+		}
+	})
+
+	tests := []struct {
+		name          string
+		effectiveFrom string
+		file          string
+	}{
+		{name: "Unknown code without a name", effectiveFrom: "2025-04-01", file: "code;price\n999;100\n"},
+		{name: "Takes effect in the past", effectiveFrom: "2025-02-28", file: "code;price\n401;100\n"},
+		{name: "Duplicate code", effectiveFrom: "2025-04-01", file: "code;price\n401;100\n401;200\n"},
+		{name: "Invalid VAT rate", effectiveFrom: "2025-04-01", file: "code;price;kdv\n401;100;120\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeProcedureRepository{procedures: existing}
+			service := newTestService(repository, today)
+
+			_, err := service.ImportPriceList(context.Background(), procedure.PriceList{ClinicID: 1, EffectiveFrom: tt.effectiveFrom}, strings.NewReader(tt.file))
+			if !errors.Is(err, procedure.ErrPriceListValidation) {
+				t.Errorf("ImportPriceList() error = %v, want ErrPriceListValidation", err)
+			}
+			if len(repository.lists) != 0 {
+				t.Errorf("ImportPriceList() stored a list despite the error")
+			}
+		})
+	}
+}
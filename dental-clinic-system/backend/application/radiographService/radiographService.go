@@ -0,0 +1,273 @@
+package radiographService
+
+import (
+	"bytes"
+	"context"
+	"dental-clinic-system/infrastructure/dicom"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/odontogram"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/radiograph"
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// PreviewSize is the longest side, in pixels, of generated previews
+const PreviewSize = 512
+
+// dicomDateLayout is the layout of DICOM DA values
+const dicomDateLayout = "20060102"
+
+// RadiographRepository defines the radiograph database operations
+type RadiographRepository interface {
+	GetImages(ctx context.Context, clinicID, patientID uint, filter radiograph.Filter) ([]radiograph.Image, error)
+	GetImage(ctx context.Context, id uint) (radiograph.Image, error)
+	GetPreview(ctx context.Context, id uint) ([]byte, error)
+	ImageExists(ctx context.Context, clinicID uint, sopInstanceUID string) (bool, error)
+	CreateImage(ctx context.Context, image radiograph.Image) (radiograph.Image, error)
+}
+
+// PatientRepository is used to find the patient a DICOM file belongs to
+type PatientRepository interface {
+	GetPatient(ctx context.Context, id uint) (patient.Patient, error)
+	GetPatientByNationalID(ctx context.Context, clinicID uint, nationalID string) (patient.Patient, error)
+}
+
+// AttachmentService stores the DICOM files as X-ray attachments
+type AttachmentService interface {
+	MaxBytes() int64
+	Upload(ctx context.Context, upload attachment.Upload, r io.Reader) (attachment.Attachment, error)
+	DeleteAttachment(ctx context.Context, id uint) error
+}
+
+// IngestRequest describes a DICOM file being uploaded. Without a PatientID the patient is found by the file's patient ID.
+type IngestRequest struct {
+	ClinicID     uint
+	PatientID    uint
+	UploadedByID uint
+	FileName     string
+}
+
+// RadiographService ingests DICOM radiographs and lists them on the patient's timeline
+type RadiographService struct {
+	radiographRepository RadiographRepository
+	patientRepository    PatientRepository
+	attachmentService    AttachmentService
+	now                  func() time.Time
+}
+
+// NewRadiographService creates a new instance of RadiographService
+func NewRadiographService(radiographRepo RadiographRepository, patientRepo PatientRepository, attachmentService AttachmentService) *RadiographService {
+	return &RadiographService{
+		radiographRepository: radiographRepo,
+		patientRepository:    patientRepo,
+		attachmentService:    attachmentService,
+		now:                  time.Now,
+	}
+}
+
+// Ingest reads a DICOM file, matches it to a patient by national ID, stores it as an X-ray attachment
+// and indexes its header together with a preview of its pixel data
+func (s *RadiographService) Ingest(ctx context.Context, req IngestRequest, r io.Reader) (radiograph.Image, error) {
+	maxBytes := s.attachmentService.MaxBytes()
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return radiograph.Image{}, err
+	}
+	if int64(len(data)) > maxBytes {
+		return radiograph.Image{}, fmt.Errorf("%w: the limit is %d MB", attachment.ErrFileTooLarge, maxBytes>>20)
+	}
+
+	header, err := dicom.Parse(data)
+	if err != nil {
+		return radiograph.Image{}, fmt.Errorf("%w: %s", radiograph.ErrInvalidDicom, err.Error())
+	}
+
+	if header.SOPInstanceUID != "" {
+		exists, err := s.radiographRepository.ImageExists(ctx, req.ClinicID, header.SOPInstanceUID)
+		if err != nil {
+			return radiograph.Image{}, err
+		}
+		if exists {
+			return radiograph.Image{}, radiograph.ErrDuplicateImage
+		}
+	}
+
+	pt, err := s.matchPatient(ctx, req, header.PatientID)
+	if err != nil {
+		return radiograph.Image{}, err
+	}
+
+	description := header.StudyDescription
+	if description == "" {
+		description = header.SeriesDescription
+	}
+	stored, err := s.attachmentService.Upload(ctx, attachment.Upload{
+		ClinicID:     req.ClinicID,
+		PatientID:    pt.ID,
+		UploadedByID: req.UploadedByID,
+		Category:     attachment.CategoryXRay,
+		Description:  description,
+		FileName:     req.FileName,
+	}, bytes.NewReader(data))
+	if err != nil {
+		return radiograph.Image{}, err
+	}
+
+	image := radiograph.Image{
+		ClinicID:          req.ClinicID,
+		PatientID:         pt.ID,
+		AttachmentID:      stored.ID,
+		UploadedByID:      req.UploadedByID,
+		StudyDate:         s.studyDate(header.StudyDate),
+		Modality:          strings.ToUpper(header.Modality),
+		StudyInstanceUID:  header.StudyInstanceUID,
+		SeriesInstanceUID: header.SeriesInstanceUID,
+		SOPInstanceUID:    header.SOPInstanceUID,
+		Description:       description,
+		BodyPart:          header.BodyPartExamined,
+		Region:            regionOf(header.AnatomicRegions),
+		Teeth:             teethOf(header.AnatomicRegions, header.AnatomicStructures),
+		DicomPatientID:    header.PatientID,
+		DicomPatientName:  header.PatientName,
+		Rows:              header.Image.Rows,
+		Columns:           header.Image.Columns,
+	}
+
+	preview, err := dicom.Preview(header.Image, PreviewSize)
+	switch {
+	case err == nil:
+		image.Preview = preview
+		image.HasPreview = true
+	case !errors.Is(err, dicom.ErrNoPreview):
+		log.Warn().
+			Str("operation", "Ingest").
+			Err(err).
+			Uint("attachment_id", stored.ID).
+			Msg("Failed to render radiograph preview")
+	}
+
+	created, err := s.radiographRepository.CreateImage(ctx, image)
+	if err != nil {
+		if deleteErr := s.attachmentService.DeleteAttachment(ctx, stored.ID); deleteErr != nil {
+			log.Error().
+				Str("operation", "Ingest").
+				Err(deleteErr).
+				Uint("attachment_id", stored.ID).
+				Msg("Failed to remove attachment of a radiograph that could not be indexed")
+		}
+		return radiograph.Image{}, err
+	}
+	return created, nil
+}
+
+// GetTimeline returns the patient's radiograph studies, newest first
+func (s *RadiographService) GetTimeline(ctx context.Context, clinicID, patientID uint, filter radiograph.Filter) ([]radiograph.Study, error) {
+	filter.Modality = strings.ToUpper(strings.TrimSpace(filter.Modality))
+	for _, date := range []string{filter.From, filter.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(schedule.DateLayout, date); err != nil {
+			return nil, fmt.Errorf("%w: dates must be formatted as %s", radiograph.ErrRadiographValidation, schedule.DateLayout)
+		}
+	}
+
+	images, err := s.radiographRepository.GetImages(ctx, clinicID, patientID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return radiograph.Timeline(images), nil
+}
+
+// GetImage retrieves an ingested radiograph by its ID
+func (s *RadiographService) GetImage(ctx context.Context, id uint) (radiograph.Image, error) {
+	return s.radiographRepository.GetImage(ctx, id)
+}
+
+// GetPreview returns the PNG preview of a radiograph
+func (s *RadiographService) GetPreview(ctx context.Context, id uint) ([]byte, error) {
+	return s.radiographRepository.GetPreview(ctx, id)
+}
+
+// matchPatient finds the patient of a DICOM file. A file uploaded for a given patient must carry
+// that patient's national ID or none; otherwise the clinic's patient with the file's ID is used.
+func (s *RadiographService) matchPatient(ctx context.Context, req IngestRequest, dicomPatientID string) (patient.Patient, error) {
+	dicomPatientID = strings.TrimSpace(dicomPatientID)
+
+	if req.PatientID != 0 {
+		pt, err := s.patientRepository.GetPatient(ctx, req.PatientID)
+		if err != nil {
+			return patient.Patient{}, err
+		}
+		if dicomPatientID != "" && dicomPatientID != strings.TrimSpace(pt.NationalID) {
+			return patient.Patient{}, fmt.Errorf("%w: the file is for patient ID %q", radiograph.ErrPatientMismatch, dicomPatientID)
+		}
+		return pt, nil
+	}
+
+	if dicomPatientID == "" {
+		return patient.Patient{}, fmt.Errorf("%w: the file has no patient ID, upload it for the patient instead", radiograph.ErrPatientNotMatched)
+	}
+	pt, err := s.patientRepository.GetPatientByNationalID(ctx, req.ClinicID, dicomPatientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return patient.Patient{}, fmt.Errorf("%w: %q", radiograph.ErrPatientNotMatched, dicomPatientID)
+	}
+	return pt, err
+}
+
+// studyDate converts a DICOM date, falling back to today when the file has no valid date
+func (s *RadiographService) studyDate(value string) string {
+	if date, err := time.Parse(dicomDateLayout, value); err == nil {
+		return date.Format(schedule.DateLayout)
+	}
+	return s.now().Format(schedule.DateLayout)
+}
+
+// regionOf names the anatomic regions of a file
+func regionOf(regions []dicom.Code) string {
+	var names []string
+	for _, code := range regions {
+		name := code.Meaning
+		if name == "" {
+			name = code.Value
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// teethOf collects the FDI tooth numbers coded in a file's anatomic regions and structures, in ascending order
+func teethOf(codes ...[]dicom.Code) string {
+	seen := map[int]bool{}
+	var teeth []int
+	for _, list := range codes {
+		for _, code := range list {
+			tooth, err := strconv.Atoi(code.Value)
+			if err != nil || seen[tooth] {
+				continue
+			}
+			if _, err := odontogram.ToothDentition(tooth); err != nil {
+				continue
+			}
+			seen[tooth] = true
+			teeth = append(teeth, tooth)
+		}
+	}
+
+	sort.Ints(teeth)
+	numbers := make([]string, len(teeth))
+	for i, tooth := range teeth {
+		numbers[i] = strconv.Itoa(tooth)
+	}
+	return strings.Join(numbers, ",")
+}
@@ -0,0 +1,333 @@
+package radiographService
+
+import (
+	"bytes"
+	"context"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/radiograph"
+	"encoding/binary"
+	"errors"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeRadiographRepository keeps radiographs in memory
+type fakeRadiographRepository struct {
+	images    map[uint]radiograph.Image
+	createErr error
+}
+
+func (r *fakeRadiographRepository) GetImages(ctx context.Context, clinicID, patientID uint, filter radiograph.Filter) ([]radiograph.Image, error) {
+	var found []radiograph.Image
+	for id := uint(1); id <= uint(len(r.images)); id++ {
+		image := r.images[id]
+		if image.ClinicID == clinicID && image.PatientID == patientID &&
+			(filter.Modality == "" || image.Modality == filter.Modality) {
+			found = append(found, image)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeRadiographRepository) GetImage(ctx context.Context, id uint) (radiograph.Image, error) {
+	image, ok := r.images[id]
+	if !ok {
+		return radiograph.Image{}, radiograph.ErrImageNotFound
+	}
+	return image, nil
+}
+
+func (r *fakeRadiographRepository) GetPreview(ctx context.Context, id uint) ([]byte, error) {
+	image, err := r.GetImage(ctx, id)
+	return image.Preview, err
+}
+
+func (r *fakeRadiographRepository) ImageExists(ctx context.Context, clinicID uint, sopInstanceUID string) (bool, error) {
+	for _, image := range r.images {
+		if image.ClinicID == clinicID && image.SOPInstanceUID == sopInstanceUID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRadiographRepository) CreateImage(ctx context.Context, image radiograph.Image) (radiograph.Image, error) {
+	if r.createErr != nil {
+		return radiograph.Image{}, r.createErr
+	}
+	image.ID = uint(len(r.images) + 1)
+	r.images[image.ID] = image
+	return image, nil
+}
+
+// fakePatientRepository returns fixed patients
+type fakePatientRepository struct {
+	patients []patient.Patient
+}
+
+func (r *fakePatientRepository) GetPatient(ctx context.Context, id uint) (patient.Patient, error) {
+	for _, p := range r.patients {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return patient.Patient{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakePatientRepository) GetPatientByNationalID(ctx context.Context, clinicID uint, nationalID string) (patient.Patient, error) {
+	for _, p := range r.patients {
+		if p.ClinicID == clinicID && p.NationalID == nationalID {
+			return p, nil
+		}
+	}
+	return patient.Patient{}, gorm.ErrRecordNotFound
+}
+
+// fakeAttachmentService records uploaded files
+type fakeAttachmentService struct {
+	uploads map[uint][]byte
+	deleted []uint
+}
+
+func (s *fakeAttachmentService) MaxBytes() int64 {
+	return 1 << 20
+}
+
+func (s *fakeAttachmentService) Upload(ctx context.Context, upload attachment.Upload, r io.Reader) (attachment.Attachment, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return attachment.Attachment{}, err
+	}
+	id := uint(len(s.uploads) + 1)
+	s.uploads[id] = data
+	a := attachment.Attachment{ClinicID: upload.ClinicID, PatientID: upload.PatientID, Category: upload.Category}
+	a.ID = id
+	return a, nil
+}
+
+func (s *fakeAttachmentService) DeleteAttachment(ctx context.Context, id uint) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+// dicomFile builds an explicit VR little endian DICOM file
+type dicomFile struct {
+	buf bytes.Buffer
+}
+
+func newDicomFile() *dicomFile {
+	f := &dicomFile{}
+	f.buf.Write(make([]byte, 128))
+	f.buf.WriteString("DICM")
+	f.element(0x0002, 0x0010, "UI", []byte("1.2.840.10008.1.2.1\x00"))
+	return f
+}
+
+func (f *dicomFile) element(group, number uint16, vr string, value []byte) *dicomFile {
+	if len(value)%2 == 1 {
+		value = append(value, ' ')
+	}
+	_ = binary.Write(&f.buf, binary.LittleEndian, [2]uint16{group, number})
+	f.buf.WriteString(vr)
+	switch vr {
+	case "OB", "OW", "SQ":
+		f.buf.Write([]byte{0, 0})
+		_ = binary.Write(&f.buf, binary.LittleEndian, uint32(len(value)))
+	default:
+		_ = binary.Write(&f.buf, binary.LittleEndian, uint16(len(value)))
+	}
+	f.buf.Write(value)
+	return f
+}
+
+func (f *dicomFile) text(group, number uint16, vr, value string) *dicomFile {
+	return f.element(group, number, vr, []byte(value))
+}
+
+func (f *dicomFile) us(group, number uint16, value uint16) *dicomFile {
+	return f.element(group, number, "US", binary.LittleEndian.AppendUint16(nil, value))
+}
+
+// codes writes a sequence of undefined length whose items hold the given code values
+func (f *dicomFile) codes(number uint16, values ...string) *dicomFile {
+	_ = binary.Write(&f.buf, binary.LittleEndian, [2]uint16{0x0008, number})
+	f.buf.WriteString("SQ\x00\x00")
+	_ = binary.Write(&f.buf, binary.LittleEndian, uint32(0xFFFFFFFF))
+	for _, value := range values {
+		item := &dicomFile{}
+		item.text(0x0008, 0x0100, "SH", value).text(0x0008, 0x0102, "SH", "FDI")
+		_ = binary.Write(&f.buf, binary.LittleEndian, [2]uint16{0xFFFE, 0xE000})
+		_ = binary.Write(&f.buf, binary.LittleEndian, uint32(item.buf.Len()))
+		f.buf.Write(item.buf.Bytes())
+	}
+	_ = binary.Write(&f.buf, binary.LittleEndian, [2]uint16{0xFFFE, 0xE0DD})
+	_ = binary.Write(&f.buf, binary.LittleEndian, uint32(0))
+	return f
+}
+
+// bitewing builds a 4x2 16-bit grayscale intraoral radiograph
+func bitewing(sopUID, patientID string) []byte {
+	pixels := make([]byte, 0, 16)
+	for i := uint16(0); i < 8; i++ {
+		pixels = binary.LittleEndian.AppendUint16(pixels, i*500)
+	}
+	f := newDicomFile().
+		text(0x0008, 0x0018, "UI", sopUID).
+		text(0x0008, 0x0020, "DA", "20260312").
+		text(0x0008, 0x0060, "CS", "io").
+		text(0x0008, 0x1030, "LO", "Bitewings").
+		codes(0x2228, "36", "37", "99").
+		text(0x0010, 0x0010, "PN", "Doe^Jane").
+		text(0x0010, 0x0020, "LO", patientID).
+		text(0x0020, 0x000D, "UI", "1.2.3.4").
+		us(0x0028, 0x0002, 1).
+		text(0x0028, 0x0004, "CS", "MONOCHROME2").
+		us(0x0028, 0x0010, 2).
+		us(0x0028, 0x0011, 4).
+		us(0x0028, 0x0100, 16).
+		us(0x0028, 0x0101, 12).
+		us(0x0028, 0x0103, 0)
+	return f.element(0x7FE0, 0x0010, "OW", pixels).buf.Bytes()
+}
+
+func newTestService() (*RadiographService, *fakeRadiographRepository, *fakeAttachmentService) {
+	repo := &fakeRadiographRepository{images: map[uint]radiograph.Image{}}
+	patients := &fakePatientRepository{patients: []patient.Patient{
+		{Model: gorm.Model{ID: 1}, ClinicID: 1, NationalID: "12345678901"},
+		{Model: gorm.Model{ID: 2}, ClinicID: 1, NationalID: "10987654321"},
+	}}
+	attachments := &fakeAttachmentService{uploads: map[uint][]byte{}}
+	s := NewRadiographService(repo, patients, attachments)
+	s.now = func() time.Time { return time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC) }
+	return s, repo, attachments
+}
+
+func TestIngestMatchesPatientByNationalID(t *testing.T) {
+	s, _, attachments := newTestService()
+	data := bitewing("1.2.3.4.1", "12345678901")
+
+	image, err := s.Ingest(context.Background(), IngestRequest{ClinicID: 1, UploadedByID: 7, FileName: "bw.dcm"}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image.PatientID != 1 || image.StudyDate != "2026-03-12" || image.Modality != radiograph.ModalityIntraoral {
+		t.Errorf("unexpected image %+v", image)
+	}
+	if image.DicomPatientName != "Doe Jane" || image.Rows != 2 || image.Columns != 4 {
+		t.Errorf("header not indexed: %+v", image)
+	}
+	if image.Teeth != "36,37" {
+		t.Errorf("expected teeth 36,37, got %q", image.Teeth)
+	}
+	if !bytes.Equal(attachments.uploads[image.AttachmentID], data) {
+		t.Error("expected the DICOM file to be stored as an attachment")
+	}
+
+	if !image.HasPreview {
+		t.Fatal("expected a preview")
+	}
+	preview, err := png.Decode(bytes.NewReader(image.Preview))
+	if err != nil {
+		t.Fatalf("preview is not a PNG: %v", err)
+	}
+	if bounds := preview.Bounds(); bounds.Dx() != 4 || bounds.Dy() != 2 {
+		t.Errorf("unexpected preview size %v", bounds)
+	}
+}
+
+func TestIngestRejectsInvalidFiles(t *testing.T) {
+	s, _, _ := newTestService()
+
+	_, err := s.Ingest(context.Background(), IngestRequest{ClinicID: 1, PatientID: 1}, bytes.NewReader([]byte("not a dicom file")))
+	if !errors.Is(err, radiograph.ErrInvalidDicom) {
+		t.Errorf("expected ErrInvalidDicom, got %v", err)
+	}
+}
+
+func TestIngestPatientMatching(t *testing.T) {
+	s, _, _ := newTestService()
+
+	_, err := s.Ingest(context.Background(), IngestRequest{ClinicID: 1}, bytes.NewReader(bitewing("1.2.3.4.1", "55555555555")))
+	if !errors.Is(err, radiograph.ErrPatientNotMatched) {
+		t.Errorf("expected ErrPatientNotMatched, got %v", err)
+	}
+
+	_, err = s.Ingest(context.Background(), IngestRequest{ClinicID: 1, PatientID: 2}, bytes.NewReader(bitewing("1.2.3.4.1", "12345678901")))
+	if !errors.Is(err, radiograph.ErrPatientMismatch) {
+		t.Errorf("expected ErrPatientMismatch, got %v", err)
+	}
+
+	image, err := s.Ingest(context.Background(), IngestRequest{ClinicID: 1, PatientID: 2}, bytes.NewReader(bitewing("1.2.3.4.1", "")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image.PatientID != 2 {
+		t.Errorf("expected the file to be ingested for patient 2, got %d", image.PatientID)
+	}
+}
+
+func TestIngestRejectsDuplicates(t *testing.T) {
+	s, _, _ := newTestService()
+	data := bitewing("1.2.3.4.1", "12345678901")
+
+	if _, err := s.Ingest(context.Background(), IngestRequest{ClinicID: 1}, bytes.NewReader(data)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := s.Ingest(context.Background(), IngestRequest{ClinicID: 1}, bytes.NewReader(data))
+	if !errors.Is(err, radiograph.ErrDuplicateImage) {
+		t.Errorf("expected ErrDuplicateImage, got %v", err)
+	}
+}
+
+func TestIngestRemovesAttachmentWhenIndexingFails(t *testing.T) {
+	s, repo, attachments := newTestService()
+	repo.createErr = errors.New("database unavailable")
+
+	_, err := s.Ingest(context.Background(), IngestRequest{ClinicID: 1}, bytes.NewReader(bitewing("1.2.3.4.1", "12345678901")))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(attachments.deleted) != 1 || attachments.deleted[0] != 1 {
+		t.Errorf("expected the attachment to be removed, got %v", attachments.deleted)
+	}
+}
+
+func TestGetTimelineGroupsStudies(t *testing.T) {
+	s, repo, _ := newTestService()
+	for _, image := range []radiograph.Image{
+		{ClinicID: 1, PatientID: 1, StudyInstanceUID: "1.1", StudyDate: "2025-01-10", Modality: "PX"},
+		{ClinicID: 1, PatientID: 1, StudyInstanceUID: "1.2", StudyDate: "2026-02-01", Modality: "IO"},
+		{ClinicID: 1, PatientID: 1, StudyInstanceUID: "1.2", StudyDate: "2026-02-01", Modality: "IO"},
+		{ClinicID: 1, PatientID: 2, StudyInstanceUID: "1.3", StudyDate: "2026-02-01", Modality: "IO"},
+	} {
+		if _, err := repo.CreateImage(context.Background(), image); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	studies, err := s.GetTimeline(context.Background(), 1, 1, radiograph.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(studies) != 2 || studies[0].StudyInstanceUID != "1.2" || len(studies[0].Images) != 2 || studies[1].StudyInstanceUID != "1.1" {
+		t.Errorf("unexpected timeline %+v", studies)
+	}
+
+	studies, err = s.GetTimeline(context.Background(), 1, 1, radiograph.Filter{Modality: "px"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(studies) != 1 || studies[0].Modalities[0] != "PX" {
+		t.Errorf("expected only the panoramic study, got %+v", studies)
+	}
+
+	_, err = s.GetTimeline(context.Background(), 1, 1, radiograph.Filter{From: "12/01/2026"})
+	if !errors.Is(err, radiograph.ErrRadiographValidation) {
+		t.Errorf("expected ErrRadiographValidation, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package reminderService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReminderTimeLayout formats the appointment time shown in reminder emails
+const ReminderTimeLayout = "02.01.2006 15:04"
+
+// AppointmentRepository defines the reminder-related appointment database operations
+type AppointmentRepository interface {
+	GetAppointmentsDueForReminder(ctx context.Context, kind appointment.ReminderKind, from, to time.Time) ([]appointment.Appointment, error)
+	ClaimReminder(ctx context.Context, appointmentID uint, kind appointment.ReminderKind, sentAt time.Time) (bool, error)
+	ReleaseReminder(ctx context.Context, appointmentID uint, kind appointment.ReminderKind) error
+}
+
+// ReminderProducer publishes reminder emails to the email pipeline
+type ReminderProducer interface {
+	SendAppointmentReminder(email string, data map[string]string) error
+}
+
+// ReminderService sends appointment reminders ahead of scheduled visits
+type ReminderService struct {
+	appointmentRepository AppointmentRepository
+	producer              ReminderProducer
+	now                   func() time.Time
+}
+
+// NewReminderService creates a new instance of ReminderService
+func NewReminderService(appointmentRepo AppointmentRepository, producer ReminderProducer) *ReminderService {
+	return &ReminderService{
+		appointmentRepository: appointmentRepo,
+		producer:              producer,
+		now:                   time.Now,
+	}
+}
+
+// SendDueReminders publishes every reminder that is due and has not been sent yet.
+// Each reminder kind covers the range between its own lead time and the next shorter one, so an
+// appointment booked at short notice only receives the reminders that still make sense.
+func (s *ReminderService) SendDueReminders(ctx context.Context) error {
+	now := s.now()
+	sent := 0
+
+	for i, kind := range appointment.ReminderKinds {
+		from := now
+		if i+1 < len(appointment.ReminderKinds) {
+			from = now.Add(appointment.ReminderKinds[i+1].Lead())
+		}
+		to := now.Add(kind.Lead())
+
+		appointments, err := s.appointmentRepository.GetAppointmentsDueForReminder(ctx, kind, from, to)
+		if err != nil {
+			return err
+		}
+
+		for _, appt := range appointments {
+			ok, err := s.sendReminder(ctx, appt, kind, now)
+			if err != nil {
+				return err
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	log.Info().
+		Str("operation", "SendDueReminders").
+		Int("count", sent).
+		Msgf("Sent %d appointment reminders", sent)
+
+	return nil
+}
+
+// sendReminder claims the reminder before publishing it and releases the claim if publishing fails
+func (s *ReminderService) sendReminder(ctx context.Context, appt appointment.Appointment, kind appointment.ReminderKind, now time.Time) (bool, error) {
+	if appt.Patient.Email() == "" {
+		log.Warn().
+			Str("operation", "SendDueReminders").
+			Uint("appointment_id", appt.ID).
+			Msg("Patient has no email address, skipping reminder")
+		return false, nil
+	}
+
+	claimed, err := s.appointmentRepository.ClaimReminder(ctx, appt.ID, kind, now)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := s.producer.SendAppointmentReminder(appt.Patient.Email(), ReminderData(appt, kind)); err != nil {
+		log.Error().
+			Str("operation", "SendDueReminders").
+			Err(err).
+			Uint("appointment_id", appt.ID).
+			Str("kind", string(kind)).
+			Msg("Failed to publish appointment reminder")
+		if releaseErr := s.appointmentRepository.ReleaseReminder(ctx, appt.ID, kind); releaseErr != nil {
+			return false, releaseErr
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// ReminderData builds the template data of a reminder email
+func ReminderData(appt appointment.Appointment, kind appointment.ReminderKind) map[string]string {
+	return map[string]string{
+		"clinic_name":      appt.Clinic.Name,
+		"clinic_address":   appt.Clinic.Address,
+		"clinic_phone":     appt.Clinic.PhoneNumber,
+		"doctor_name":      fullName(appt.Doctor.FirstName, appt.Doctor.LastName),
+		"patient_name":     strings.TrimSpace(appt.Patient.Name),
+		"appointment_time": appt.ScheduledTime.In(appt.Clinic.Location()).Format(ReminderTimeLayout),
+		"reminder":         string(kind),
+	}
+}
+
+func fullName(first, last string) string {
+	return strings.TrimSpace(first + " " + last)
+}
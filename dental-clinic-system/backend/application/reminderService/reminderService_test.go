@@ -0,0 +1,118 @@
+package reminderService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+	"testing"
+	"time"
+)
+
+type reminderKey struct {
+	appointmentID uint
+	kind          appointment.ReminderKind
+}
+
+type fakeAppointmentRepository struct {
+	appointments []appointment.Appointment
+	sent         map[reminderKey]bool
+}
+
+func (r *fakeAppointmentRepository) GetAppointmentsDueForReminder(ctx context.Context, kind appointment.ReminderKind, from, to time.Time) ([]appointment.Appointment, error) {
+	var due []appointment.Appointment
+	for _, appt := range r.appointments {
+		if appt.ScheduledTime.Before(from) || !appt.ScheduledTime.Before(to) || r.sent[reminderKey{appt.ID, kind}] {
+			continue
+		}
+		due = append(due, appt)
+	}
+	return due, nil
+}
+
+func (r *fakeAppointmentRepository) ClaimReminder(ctx context.Context, appointmentID uint, kind appointment.ReminderKind, sentAt time.Time) (bool, error) {
+	key := reminderKey{appointmentID, kind}
+	if r.sent[key] {
+		return false, nil
+	}
+	r.sent[key] = true
+	return true, nil
+}
+
+func (r *fakeAppointmentRepository) ReleaseReminder(ctx context.Context, appointmentID uint, kind appointment.ReminderKind) error {
+	delete(r.sent, reminderKey{appointmentID, kind})
+	return nil
+}
+
+type fakeProducer struct {
+	messages []map[string]string
+	fail     bool
+}
+
+func (p *fakeProducer) SendAppointmentReminder(email string, data map[string]string) error {
+	if p.fail {
+		return errors.New("broker unavailable")
+	}
+	p.messages = append(p.messages, data)
+	return nil
+}
+
+func TestSendDueReminders(t *testing.T) {
+	now := time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+	newAppointment := func(id uint, in time.Duration) appointment.Appointment {
+		appt := appointment.Appointment{
+			ScheduledTime: now.Add(in),
+			Clinic:        clinic.Clinic{Name: "Gülüş Diş", Timezone: "Europe/Istanbul"},
+			Patient:       patient.Patient{Name: "Ayşe Yılmaz", ContactInfo: "patient@example.com"},
+			Doctor:        user.User{FirstName: "Mehmet", LastName: "Demir"},
+		}
+		appt.ID = id
+		return appt
+	}
+
+	appointments := []appointment.Appointment{
+		newAppointment(1, 20*time.Hour),
+		newAppointment(2, time.Hour),
+		newAppointment(3, 30*time.Hour),
+	}
+
+	repository := &fakeAppointmentRepository{appointments: appointments, sent: map[reminderKey]bool{}}
+	producer := &fakeProducer{fail: true}
+	service := NewReminderService(repository, producer)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// A failed publish must not be recorded, otherwise the reminder would be lost
+	if err := service.SendDueReminders(ctx); err != nil {
+		t.Fatalf("SendDueReminders() error = %v", err)
+	}
+	if len(repository.sent) != 0 {
+		t.Fatalf("%d reminders recorded after failed publish, want 0", len(repository.sent))
+	}
+
+	producer.fail = false
+	if err := service.SendDueReminders(ctx); err != nil {
+		t.Fatalf("SendDueReminders() error = %v", err)
+	}
+	if len(producer.messages) != 2 {
+		t.Fatalf("sent %d reminders, want 2", len(producer.messages))
+	}
+	if !repository.sent[reminderKey{1, appointment.Reminder24h}] || !repository.sent[reminderKey{2, appointment.Reminder2h}] {
+		t.Errorf("sent reminders = %v, want 24h for appointment 1 and 2h for appointment 2", repository.sent)
+	}
+
+	first := producer.messages[0]
+	if first["appointment_time"] != "04.03.2025 07:00" || first["doctor_name"] != "Mehmet Demir" || first["clinic_name"] != "Gülüş Diş" {
+		t.Errorf("reminder data = %v", first)
+	}
+
+	// Running again, as after a restart, must not send duplicates
+	if err := service.SendDueReminders(ctx); err != nil {
+		t.Fatalf("SendDueReminders() error = %v", err)
+	}
+	if len(producer.messages) != 2 {
+		t.Errorf("sent %d reminders after rerun, want 2", len(producer.messages))
+	}
+}
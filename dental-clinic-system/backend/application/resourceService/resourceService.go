@@ -0,0 +1,173 @@
+package resourceService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/resource"
+	"dental-clinic-system/models/schedule"
+	"dental-clinic-system/validations"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ResourceRepository defines the resource database operations
+type ResourceRepository interface {
+	GetResources(ctx context.Context, clinicID uint, resourceType resource.Type) ([]resource.Resource, error)
+	GetResource(ctx context.Context, id uint) (resource.Resource, error)
+	CreateResource(ctx context.Context, newResource resource.Resource) (resource.Resource, error)
+	UpdateResource(ctx context.Context, updatedResource resource.Resource) (resource.Resource, error)
+	DeleteResource(ctx context.Context, id uint) error
+	GetResourceBookings(ctx context.Context, resourceIDs []uint, from, to time.Time) (map[uint][]resource.Booking, error)
+}
+
+// ClinicRepository is used to resolve the time zone of a clinic
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// ScheduleService provides the opening hours a resource can be used in
+type ScheduleService interface {
+	GetWorkingIntervals(ctx context.Context, clinicID, doctorID uint, from, to time.Time) ([]schedule.Interval, error)
+}
+
+// ResourceService manages the chairs, rooms and equipment of a clinic
+type ResourceService struct {
+	resourceRepository ResourceRepository
+	clinicRepository   ClinicRepository
+	scheduleService    ScheduleService
+}
+
+// NewResourceService creates a new instance of ResourceService
+func NewResourceService(resourceRepo ResourceRepository, clinicRepo ClinicRepository, scheduleService ScheduleService) *ResourceService {
+	return &ResourceService{
+		resourceRepository: resourceRepo,
+		clinicRepository:   clinicRepo,
+		scheduleService:    scheduleService,
+	}
+}
+
+// GetResources retrieves the resources of a clinic, optionally filtered by type
+func (s *ResourceService) GetResources(ctx context.Context, clinicID uint, resourceType resource.Type) ([]resource.Resource, error) {
+	return s.resourceRepository.GetResources(ctx, clinicID, resourceType)
+}
+
+// GetResource retrieves a resource by its ID
+func (s *ResourceService) GetResource(ctx context.Context, id uint) (resource.Resource, error) {
+	return s.resourceRepository.GetResource(ctx, id)
+}
+
+// CreateResource validates and stores a new, active resource
+func (s *ResourceService) CreateResource(ctx context.Context, newResource resource.Resource) (resource.Resource, error) {
+	if err := validations.ResourceValidation(&newResource); err != nil {
+		return resource.Resource{}, fmt.Errorf("%w: %s", resource.ErrResourceValidation, err.Error())
+	}
+	newResource.Active = true
+	return s.resourceRepository.CreateResource(ctx, newResource)
+}
+
+// UpdateResource validates and stores changes to a resource
+func (s *ResourceService) UpdateResource(ctx context.Context, updatedResource resource.Resource) (resource.Resource, error) {
+	if err := validations.ResourceValidation(&updatedResource); err != nil {
+		return resource.Resource{}, fmt.Errorf("%w: %s", resource.ErrResourceValidation, err.Error())
+	}
+	return s.resourceRepository.UpdateResource(ctx, updatedResource)
+}
+
+// DeleteResource deletes a resource. Past appointments keep their reservation rows.
+func (s *ResourceService) DeleteResource(ctx context.Context, id uint) error {
+	return s.resourceRepository.DeleteResource(ctx, id)
+}
+
+// GetUtilisation reports, for each active resource of the given type, how much of the clinic's
+// opening time on date (YYYY-MM-DD, clinic local time) was reserved by appointments.
+func (s *ResourceService) GetUtilisation(ctx context.Context, clinicID uint, date string, resourceType resource.Type) ([]resource.Utilisation, error) {
+	if resourceType == "" {
+		resourceType = resource.TypeChair
+	}
+	if !resourceType.IsValid() {
+		return nil, fmt.Errorf("%w: unknown resource type %q", resource.ErrResourceValidation, resourceType)
+	}
+
+	cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	loc := cln.Location()
+
+	dayStart, err := time.ParseInLocation(schedule.DateLayout, date, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: date must be in YYYY-MM-DD format", resource.ErrResourceValidation)
+	}
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	// Doctor 0 has no personal schedule, so this yields the clinic's opening hours
+	open, err := s.scheduleService.GetWorkingIntervals(ctx, clinicID, 0, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+	openMinutes := totalMinutes(open)
+
+	resources, err := s.resourceRepository.GetResources(ctx, clinicID, resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, res := range resources {
+		if res.Active {
+			ids = append(ids, res.ID)
+		}
+	}
+
+	bookings, err := s.resourceRepository.GetResourceBookings(ctx, ids, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]resource.Utilisation, 0, len(ids))
+	for _, res := range resources {
+		if !res.Active {
+			continue
+		}
+
+		var booked []schedule.Interval
+		for _, booking := range bookings[res.ID] {
+			booked = append(booked, schedule.Interval{Start: booking.Start, End: booking.End})
+		}
+		// Only booked time within opening hours counts, so utilisation never exceeds 1
+		bookedMinutes := totalMinutes(schedule.Intersect(booked, open))
+
+		utilisation := 0.0
+		if openMinutes > 0 {
+			utilisation = math.Round(float64(bookedMinutes)/float64(openMinutes)*1000) / 1000
+		}
+
+		resourceBookings := bookings[res.ID]
+		if resourceBookings == nil {
+			resourceBookings = []resource.Booking{}
+		}
+
+		report = append(report, resource.Utilisation{
+			ResourceID:    res.ID,
+			Name:          res.Name,
+			Type:          res.Type,
+			Date:          date,
+			OpenMinutes:   openMinutes,
+			BookedMinutes: bookedMinutes,
+			Utilisation:   utilisation,
+			Bookings:      resourceBookings,
+		})
+	}
+
+	return report, nil
+}
+
+// totalMinutes sums the length of the intervals after merging overlaps
+func totalMinutes(intervals []schedule.Interval) int {
+	var total time.Duration
+	for _, interval := range schedule.Normalize(intervals) {
+		total += interval.End.Sub(interval.Start)
+	}
+	return int(total / time.Minute)
+}
@@ -0,0 +1,163 @@
+package resourceService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/resource"
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeResourceRepository struct {
+	resources []resource.Resource
+	bookings  map[uint][]resource.Booking
+	created   []resource.Resource
+}
+
+func (r *fakeResourceRepository) GetResources(ctx context.Context, clinicID uint, resourceType resource.Type) ([]resource.Resource, error) {
+	var resources []resource.Resource
+	for _, res := range r.resources {
+		if res.ClinicID == clinicID && (resourceType == "" || res.Type == resourceType) {
+			resources = append(resources, res)
+		}
+	}
+	return resources, nil
+}
+
+func (r *fakeResourceRepository) GetResource(ctx context.Context, id uint) (resource.Resource, error) {
+	return resource.Resource{}, resource.ErrResourceNotFound
+}
+
+func (r *fakeResourceRepository) CreateResource(ctx context.Context, newResource resource.Resource) (resource.Resource, error) {
+	r.created = append(r.created, newResource)
+	return newResource, nil
+}
+
+func (r *fakeResourceRepository) UpdateResource(ctx context.Context, updatedResource resource.Resource) (resource.Resource, error) {
+	return updatedResource, nil
+}
+
+func (r *fakeResourceRepository) DeleteResource(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *fakeResourceRepository) GetResourceBookings(ctx context.Context, resourceIDs []uint, from, to time.Time) (map[uint][]resource.Booking, error) {
+	return r.bookings, nil
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Timezone: "Europe/Istanbul"}, nil
+}
+
+// openingHoursSchedule opens the clinic from 09:00 to 17:00 local time
+type openingHoursSchedule struct{}
+
+func (openingHoursSchedule) GetWorkingIntervals(ctx context.Context, clinicID, doctorID uint, from, to time.Time) ([]schedule.Interval, error) {
+	interval, err := schedule.ClockInterval(from, "09:00", "17:00")
+	if err != nil {
+		return nil, err
+	}
+	return []schedule.Interval{interval}, nil
+}
+
+func newResource(id uint, name string, resourceType resource.Type, active bool) resource.Resource {
+	res := resource.Resource{ClinicID: 1, Name: name, Type: resourceType, Active: active}
+	res.ID = id
+	return res
+}
+
+func TestGetUtilisation(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, time.March, 3, hour, minute, 0, 0, loc)
+	}
+
+	repository := &fakeResourceRepository{
+		resources: []resource.Resource{
+			newResource(1, "Koltuk 1", resource.TypeChair, true),
+			newResource(2, "Koltuk 2", resource.TypeChair, true),
+			newResource(3, "Eski koltuk", resource.TypeChair, false),
+			newResource(4, "Röntgen odası", resource.TypeRoom, true),
+			newResource(5, "Koltuk 3", resource.TypeChair, true),
+		},
+		bookings: map[uint][]resource.Booking{
+			1: {
+				{AppointmentID: 10, Start: at(9, 0), End: at(10, 0)},
+				// Overlapping bookings are only counted once
+				{AppointmentID: 11, Start: at(9, 30), End: at(11, 0)},
+				// Only the part within opening hours is counted
+				{AppointmentID: 12, Start: at(16, 0), End: at(19, 0)},
+				{AppointmentID: 13, Start: at(23, 0), End: at(23, 0).Add(2 * time.Hour)},
+			},
+			// Booked from before opening until after closing
+			5: {{AppointmentID: 14, Start: at(7, 0), End: at(20, 0)}},
+		},
+	}
+	service := NewResourceService(repository, fakeClinicRepository{}, openingHoursSchedule{})
+
+	report, err := service.GetUtilisation(context.Background(), 1, "2025-03-03", "")
+	if err != nil {
+		t.Fatalf("GetUtilisation() error = %v", err)
+	}
+	if len(report) != 3 {
+		t.Fatalf("GetUtilisation() returned %d resources, want the 3 active chairs", len(report))
+	}
+
+	tests := []struct {
+		name         string
+		got          resource.Utilisation
+		wantBooked   int
+		wantRatio    float64
+		wantResource uint
+	}{
+		{name: "Busy chair", got: report[0], wantResource: 1, wantBooked: 180, wantRatio: 0.375},
+		{name: "Idle chair", got: report[1], wantResource: 2, wantBooked: 0, wantRatio: 0},
+		{name: "Booked after hours", got: report[2], wantResource: 5, wantBooked: 480, wantRatio: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.ResourceID != tt.wantResource || tt.got.OpenMinutes != 480 || tt.got.BookedMinutes != tt.wantBooked || tt.got.Utilisation != tt.wantRatio {
+				t.Errorf("Utilisation = %+v, want resource %d with 480 open, %d booked minutes and ratio %v", tt.got, tt.wantResource, tt.wantBooked, tt.wantRatio)
+			}
+		})
+	}
+
+	if _, err := service.GetUtilisation(context.Background(), 1, "03.03.2025", resource.TypeChair); !errors.Is(err, resource.ErrResourceValidation) {
+		t.Errorf("GetUtilisation() error = %v, want ErrResourceValidation", err)
+	}
+}
+
+func TestCreateResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource resource.Resource
+		wantErr  bool
+	}{
+		{name: "Valid chair", resource: resource.Resource{Name: "Koltuk 1", Type: resource.TypeChair}},
+		{name: "Missing name", resource: resource.Resource{Type: resource.TypeRoom}, wantErr: true},
+		{name: "Unknown type", resource: resource.Resource{Name: "Lazer", Type: "laser"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewResourceService(&fakeResourceRepository{}, fakeClinicRepository{}, openingHoursSchedule{})
+			created, err := service.CreateResource(context.Background(), tt.resource)
+			if tt.wantErr {
+				if !errors.Is(err, resource.ErrResourceValidation) {
+					t.Errorf("CreateResource() error = %v, want ErrResourceValidation", err)
+				}
+				return
+			}
+			if err != nil || !created.Active {
+				t.Errorf("CreateResource() = %+v, %v, want an active resource", created, err)
+			}
+		})
+	}
+}
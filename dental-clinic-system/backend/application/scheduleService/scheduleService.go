@@ -0,0 +1,300 @@
+package scheduleService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/schedule"
+	"dental-clinic-system/validations"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ScheduleRepository defines the interface for schedule-related database operations
+type ScheduleRepository interface {
+	GetOpeningHours(ctx context.Context, clinicID uint) ([]schedule.OpeningHours, error)
+	ReplaceOpeningHours(ctx context.Context, clinicID uint, hours []schedule.OpeningHours) ([]schedule.OpeningHours, error)
+	GetDoctorSchedules(ctx context.Context, doctorID uint) ([]schedule.DoctorSchedule, error)
+	ReplaceDoctorSchedules(ctx context.Context, doctorID uint, schedules []schedule.DoctorSchedule) ([]schedule.DoctorSchedule, error)
+	GetScheduleExceptions(ctx context.Context, clinicID uint, fromDate, toDate string) ([]schedule.ScheduleException, error)
+	GetScheduleException(ctx context.Context, id uint) (schedule.ScheduleException, error)
+	CreateScheduleException(ctx context.Context, exception schedule.ScheduleException) (schedule.ScheduleException, error)
+	DeleteScheduleException(ctx context.Context, id uint) error
+	GetPublicHolidays(ctx context.Context, fromDate, toDate string) ([]schedule.PublicHoliday, error)
+}
+
+// ClinicRepository is used to resolve the time zone of a clinic
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// ScheduleService handles opening hours, doctor schedules and working time checks
+type ScheduleService struct {
+	scheduleRepository ScheduleRepository
+	clinicRepository   ClinicRepository
+}
+
+// NewScheduleService creates a new instance of ScheduleService
+func NewScheduleService(scheduleRepo ScheduleRepository, clinicRepo ClinicRepository) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepository: scheduleRepo,
+		clinicRepository:   clinicRepo,
+	}
+}
+
+// GetOpeningHours retrieves the weekly opening hours of a clinic
+func (s *ScheduleService) GetOpeningHours(ctx context.Context, clinicID uint) ([]schedule.OpeningHours, error) {
+	return s.scheduleRepository.GetOpeningHours(ctx, clinicID)
+}
+
+// SetOpeningHours validates and replaces the weekly opening hours of a clinic
+func (s *ScheduleService) SetOpeningHours(ctx context.Context, clinicID uint, hours []schedule.OpeningHours) ([]schedule.OpeningHours, error) {
+	for i := range hours {
+		if err := validations.OpeningHoursValidation(&hours[i]); err != nil {
+			log.Warn().
+				Str("operation", "SetOpeningHours").
+				Err(err).
+				Uint("clinic_id", clinicID).
+				Msg("Opening hours validation failed")
+			return nil, fmt.Errorf("%w: %s", schedule.ErrScheduleValidation, err.Error())
+		}
+		hours[i].ID = 0
+		hours[i].ClinicID = clinicID
+	}
+
+	return s.scheduleRepository.ReplaceOpeningHours(ctx, clinicID, hours)
+}
+
+// GetDoctorSchedules retrieves the weekly schedule of a doctor
+func (s *ScheduleService) GetDoctorSchedules(ctx context.Context, doctorID uint) ([]schedule.DoctorSchedule, error) {
+	return s.scheduleRepository.GetDoctorSchedules(ctx, doctorID)
+}
+
+// SetDoctorSchedules validates and replaces the weekly schedule of a doctor
+func (s *ScheduleService) SetDoctorSchedules(ctx context.Context, clinicID, doctorID uint, schedules []schedule.DoctorSchedule) ([]schedule.DoctorSchedule, error) {
+	for i := range schedules {
+		if err := validations.DoctorScheduleValidation(&schedules[i]); err != nil {
+			log.Warn().
+				Str("operation", "SetDoctorSchedules").
+				Err(err).
+				Uint("doctor_id", doctorID).
+				Msg("Doctor schedule validation failed")
+			return nil, fmt.Errorf("%w: %s", schedule.ErrScheduleValidation, err.Error())
+		}
+		schedules[i].ID = 0
+		schedules[i].ClinicID = clinicID
+		schedules[i].DoctorID = doctorID
+		for j := range schedules[i].Breaks {
+			schedules[i].Breaks[j].ID = 0
+			schedules[i].Breaks[j].DoctorScheduleID = 0
+		}
+	}
+
+	return s.scheduleRepository.ReplaceDoctorSchedules(ctx, doctorID, schedules)
+}
+
+// GetScheduleExceptions retrieves the exceptions of a clinic between two dates (YYYY-MM-DD)
+func (s *ScheduleService) GetScheduleExceptions(ctx context.Context, clinicID uint, fromDate, toDate string) ([]schedule.ScheduleException, error) {
+	return s.scheduleRepository.GetScheduleExceptions(ctx, clinicID, fromDate, toDate)
+}
+
+// GetScheduleException retrieves a single schedule exception
+func (s *ScheduleService) GetScheduleException(ctx context.Context, id uint) (schedule.ScheduleException, error) {
+	return s.scheduleRepository.GetScheduleException(ctx, id)
+}
+
+// CreateScheduleException validates and creates a leave, closure or holiday entry
+func (s *ScheduleService) CreateScheduleException(ctx context.Context, exception schedule.ScheduleException) (schedule.ScheduleException, error) {
+	if err := validations.ScheduleExceptionValidation(&exception); err != nil {
+		log.Warn().
+			Str("operation", "CreateScheduleException").
+			Err(err).
+			Uint("clinic_id", exception.ClinicID).
+			Msg("Schedule exception validation failed")
+		return schedule.ScheduleException{}, fmt.Errorf("%w: %s", schedule.ErrScheduleValidation, err.Error())
+	}
+
+	return s.scheduleRepository.CreateScheduleException(ctx, exception)
+}
+
+// DeleteScheduleException deletes a schedule exception
+func (s *ScheduleService) DeleteScheduleException(ctx context.Context, id uint) error {
+	return s.scheduleRepository.DeleteScheduleException(ctx, id)
+}
+
+// GetPublicHolidays retrieves the public holidays of a year
+func (s *ScheduleService) GetPublicHolidays(ctx context.Context, year int) ([]schedule.PublicHoliday, error) {
+	return s.publicHolidays(ctx, year, year)
+}
+
+// publicHolidays combines the fixed-date holidays of the given years with the religious holidays
+// stored for them. Religious holidays are only seeded for a limited number of years, so a year
+// without any is logged: bookings on its religious holidays are not blocked.
+func (s *ScheduleService) publicHolidays(ctx context.Context, firstYear, lastYear int) ([]schedule.PublicHoliday, error) {
+	stored, err := s.scheduleRepository.GetPublicHolidays(ctx, fmt.Sprintf("%04d-01-01", firstYear), fmt.Sprintf("%04d-12-31", lastYear))
+	if err != nil {
+		return nil, err
+	}
+
+	var fixed []schedule.PublicHoliday
+	for year := firstYear; year <= lastYear; year++ {
+		fixed = append(fixed, schedule.FixedPublicHolidays(year)...)
+
+		prefix := fmt.Sprintf("%04d-", year)
+		seeded := false
+		for _, holiday := range stored {
+			if strings.HasPrefix(holiday.Date, prefix) {
+				seeded = true
+				break
+			}
+		}
+		if !seeded {
+			log.Warn().
+				Str("operation", "GetPublicHolidays").
+				Int("year", year).
+				Msg("No religious holidays are stored for the year")
+		}
+	}
+
+	return schedule.MergePublicHolidays(fixed, stored), nil
+}
+
+// GetWorkingIntervals returns the time ranges between from and to in which the doctor can be booked.
+// A doctor without a weekly schedule works during the clinic's opening hours.
+func (s *ScheduleService) GetWorkingIntervals(ctx context.Context, clinicID, doctorID uint, from, to time.Time) ([]schedule.Interval, error) {
+	cln, err := s.clinicRepository.GetClinic(ctx, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	loc := cln.Location()
+
+	firstDay := midnight(from.In(loc))
+	lastDay := midnight(to.In(loc))
+	fromDate, toDate := firstDay.Format(schedule.DateLayout), lastDay.Format(schedule.DateLayout)
+
+	openingHours, err := s.scheduleRepository.GetOpeningHours(ctx, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	if len(openingHours) == 0 {
+		openingHours = schedule.AlwaysOpen(clinicID)
+	}
+	doctorSchedules, err := s.scheduleRepository.GetDoctorSchedules(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.scheduleRepository.GetScheduleExceptions(ctx, clinicID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := s.publicHolidays(ctx, firstDay.Year(), lastDay.Year())
+	if err != nil {
+		return nil, err
+	}
+
+	var working []schedule.Interval
+	for day := firstDay; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		working = append(working, workingIntervalsForDay(day, doctorID, openingHours, doctorSchedules, exceptions, holidays)...)
+	}
+
+	return schedule.Intersect(working, []schedule.Interval{{Start: from, End: to}}), nil
+}
+
+// CheckWorkingTime returns ErrOutsideWorkingHours unless the doctor works during the whole range
+func (s *ScheduleService) CheckWorkingTime(ctx context.Context, clinicID, doctorID uint, start, end time.Time) error {
+	intervals, err := s.GetWorkingIntervals(ctx, clinicID, doctorID, start, end)
+	if err != nil {
+		return err
+	}
+
+	for _, interval := range intervals {
+		if interval.Contains(start, end) {
+			return nil
+		}
+	}
+
+	log.Warn().
+		Str("operation", "CheckWorkingTime").
+		Uint("clinic_id", clinicID).
+		Uint("doctor_id", doctorID).
+		Time("start", start).
+		Time("end", end).
+		Msg("Requested time is outside working hours")
+	return schedule.ErrOutsideWorkingHours
+}
+
+// workingIntervalsForDay combines clinic opening hours, the doctor's schedule, exceptions and holidays for a single day
+func workingIntervalsForDay(day time.Time, doctorID uint, openingHours []schedule.OpeningHours, doctorSchedules []schedule.DoctorSchedule, exceptions []schedule.ScheduleException, holidays []schedule.PublicHoliday) []schedule.Interval {
+	date := day.Format(schedule.DateLayout)
+	weekday := day.Weekday()
+	wholeDay := schedule.Interval{Start: day, End: day.AddDate(0, 0, 1)}
+
+	var clinicOpen []schedule.Interval
+	for _, hours := range openingHours {
+		if hours.Weekday != weekday {
+			continue
+		}
+		if interval, err := schedule.ClockInterval(day, hours.OpensAt, hours.ClosesAt); err == nil {
+			clinicOpen = append(clinicOpen, interval)
+		}
+	}
+
+	doctorWorks := clinicOpen
+	if len(doctorSchedules) > 0 {
+		doctorWorks = nil
+		for _, doctorSchedule := range doctorSchedules {
+			if doctorSchedule.Weekday != weekday {
+				continue
+			}
+			interval, err := schedule.ClockInterval(day, doctorSchedule.StartTime, doctorSchedule.EndTime)
+			if err != nil {
+				continue
+			}
+			var breaks []schedule.Interval
+			for _, scheduleBreak := range doctorSchedule.Breaks {
+				if breakInterval, err := schedule.ClockInterval(day, scheduleBreak.StartTime, scheduleBreak.EndTime); err == nil {
+					breaks = append(breaks, breakInterval)
+				}
+			}
+			doctorWorks = append(doctorWorks, schedule.Subtract([]schedule.Interval{interval}, breaks)...)
+		}
+	}
+
+	var blocked []schedule.Interval
+	for _, holiday := range holidays {
+		if holiday.Date != date {
+			continue
+		}
+		if !holiday.HalfDay {
+			blocked = append(blocked, wholeDay)
+			continue
+		}
+		if interval, err := schedule.ClockInterval(day, holiday.HalfDayFrom, "24:00"); err == nil {
+			blocked = append(blocked, interval)
+		}
+	}
+
+	for _, exception := range exceptions {
+		if exception.DoctorID != nil && *exception.DoctorID != doctorID {
+			continue
+		}
+		if date < exception.StartDate || date > exception.EndDate {
+			continue
+		}
+		if exception.StartTime == "" {
+			blocked = append(blocked, wholeDay)
+			continue
+		}
+		if interval, err := schedule.ClockInterval(day, exception.StartTime, exception.EndTime); err == nil {
+			blocked = append(blocked, interval)
+		}
+	}
+
+	return schedule.Subtract(schedule.Intersect(clinicOpen, doctorWorks), blocked)
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,248 @@
+package scheduleService
+
+import (
+	"context"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeScheduleRepository struct {
+	openingHours    []schedule.OpeningHours
+	doctorSchedules []schedule.DoctorSchedule
+	exceptions      []schedule.ScheduleException
+	holidays        []schedule.PublicHoliday
+}
+
+func (r *fakeScheduleRepository) GetOpeningHours(ctx context.Context, clinicID uint) ([]schedule.OpeningHours, error) {
+	return r.openingHours, nil
+}
+
+func (r *fakeScheduleRepository) ReplaceOpeningHours(ctx context.Context, clinicID uint, hours []schedule.OpeningHours) ([]schedule.OpeningHours, error) {
+	r.openingHours = hours
+	return hours, nil
+}
+
+func (r *fakeScheduleRepository) GetDoctorSchedules(ctx context.Context, doctorID uint) ([]schedule.DoctorSchedule, error) {
+	return r.doctorSchedules, nil
+}
+
+func (r *fakeScheduleRepository) ReplaceDoctorSchedules(ctx context.Context, doctorID uint, schedules []schedule.DoctorSchedule) ([]schedule.DoctorSchedule, error) {
+	r.doctorSchedules = schedules
+	return schedules, nil
+}
+
+func (r *fakeScheduleRepository) GetScheduleExceptions(ctx context.Context, clinicID uint, fromDate, toDate string) ([]schedule.ScheduleException, error) {
+	return r.exceptions, nil
+}
+
+func (r *fakeScheduleRepository) GetScheduleException(ctx context.Context, id uint) (schedule.ScheduleException, error) {
+	return schedule.ScheduleException{}, schedule.ErrScheduleExceptionNotFound
+}
+
+func (r *fakeScheduleRepository) CreateScheduleException(ctx context.Context, exception schedule.ScheduleException) (schedule.ScheduleException, error) {
+	r.exceptions = append(r.exceptions, exception)
+	return exception, nil
+}
+
+func (r *fakeScheduleRepository) DeleteScheduleException(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *fakeScheduleRepository) GetPublicHolidays(ctx context.Context, fromDate, toDate string) ([]schedule.PublicHoliday, error) {
+	return r.holidays, nil
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Timezone: "Europe/Istanbul"}, nil
+}
+
+func weekdayHours(from, to string) []schedule.OpeningHours {
+	var hours []schedule.OpeningHours
+	for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+		hours = append(hours, schedule.OpeningHours{Weekday: weekday, OpensAt: from, ClosesAt: to})
+	}
+	return hours
+}
+
+func TestCheckWorkingTime(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	// 2025-03-03 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, istanbul)
+	}
+	doctorID := uint(5)
+	otherDoctorID := uint(6)
+
+	tests := []struct {
+		name       string
+		repository *fakeScheduleRepository
+		start      time.Time
+		end        time.Time
+		wantErr    bool
+	}{
+		{
+			name:       "Inside clinic opening hours without doctor schedule",
+			repository: &fakeScheduleRepository{openingHours: weekdayHours("09:00", "18:00")},
+			start:      at(3, 10, 0),
+			end:        at(3, 10, 30),
+		},
+		{
+			name:       "Sunday at 3am",
+			repository: &fakeScheduleRepository{openingHours: weekdayHours("09:00", "18:00")},
+			start:      at(2, 3, 0),
+			end:        at(2, 3, 30),
+			wantErr:    true,
+		},
+		{
+			name:       "Crossing closing time",
+			repository: &fakeScheduleRepository{openingHours: weekdayHours("09:00", "18:00")},
+			start:      at(3, 17, 45),
+			end:        at(3, 18, 15),
+			wantErr:    true,
+		},
+		{
+			name: "Doctor break",
+			repository: &fakeScheduleRepository{
+				openingHours: weekdayHours("09:00", "18:00"),
+				doctorSchedules: []schedule.DoctorSchedule{{
+					Weekday: time.Monday, StartTime: "09:00", EndTime: "17:00",
+					Breaks: []schedule.ScheduleBreak{{StartTime: "12:00", EndTime: "13:00"}},
+				}},
+			},
+			start:   at(3, 12, 30),
+			end:     at(3, 13, 0),
+			wantErr: true,
+		},
+		{
+			name: "Doctor not scheduled on weekday",
+			repository: &fakeScheduleRepository{
+				openingHours:    weekdayHours("09:00", "18:00"),
+				doctorSchedules: []schedule.DoctorSchedule{{Weekday: time.Tuesday, StartTime: "09:00", EndTime: "17:00"}},
+			},
+			start:   at(3, 10, 0),
+			end:     at(3, 10, 30),
+			wantErr: true,
+		},
+		{
+			name: "Half day holiday afternoon",
+			repository: &fakeScheduleRepository{
+				openingHours: weekdayHours("09:00", "18:00"),
+				holidays:     []schedule.PublicHoliday{{Date: "2025-03-03", HalfDay: true, HalfDayFrom: "13:00"}},
+			},
+			start:   at(3, 14, 0),
+			end:     at(3, 14, 30),
+			wantErr: true,
+		},
+		{
+			name: "Half day holiday morning",
+			repository: &fakeScheduleRepository{
+				openingHours: weekdayHours("09:00", "18:00"),
+				holidays:     []schedule.PublicHoliday{{Date: "2025-03-03", HalfDay: true, HalfDayFrom: "13:00"}},
+			},
+			start: at(3, 10, 0),
+			end:   at(3, 10, 30),
+		},
+		{
+			name: "Doctor on leave",
+			repository: &fakeScheduleRepository{
+				openingHours: weekdayHours("09:00", "18:00"),
+				exceptions:   []schedule.ScheduleException{{DoctorID: &doctorID, Type: schedule.ExceptionLeave, StartDate: "2025-03-01", EndDate: "2025-03-07"}},
+			},
+			start:   at(3, 10, 0),
+			end:     at(3, 10, 30),
+			wantErr: true,
+		},
+		{
+			name:       "No opening hours configured",
+			repository: &fakeScheduleRepository{},
+			start:      at(2, 3, 0),
+			end:        at(2, 3, 30),
+		},
+		{
+			name: "No opening hours configured, doctor not scheduled",
+			repository: &fakeScheduleRepository{
+				doctorSchedules: []schedule.DoctorSchedule{{Weekday: time.Monday, StartTime: "09:00", EndTime: "17:00"}},
+			},
+			start:   at(3, 18, 0),
+			end:     at(3, 18, 30),
+			wantErr: true,
+		},
+		{
+			name:       "Republic Day in a year without stored holidays",
+			repository: &fakeScheduleRepository{openingHours: weekdayHours("09:00", "18:00")},
+			start:      time.Date(2031, time.October, 29, 10, 0, 0, 0, istanbul),
+			end:        time.Date(2031, time.October, 29, 10, 30, 0, 0, istanbul),
+			wantErr:    true,
+		},
+		{
+			name:       "Republic Day eve afternoon in a year without stored holidays",
+			repository: &fakeScheduleRepository{openingHours: weekdayHours("09:00", "18:00")},
+			start:      time.Date(2031, time.October, 28, 14, 0, 0, 0, istanbul),
+			end:        time.Date(2031, time.October, 28, 14, 30, 0, 0, istanbul),
+			wantErr:    true,
+		},
+		{
+			name: "Another doctor on leave",
+			repository: &fakeScheduleRepository{
+				openingHours: weekdayHours("09:00", "18:00"),
+				exceptions:   []schedule.ScheduleException{{DoctorID: &otherDoctorID, Type: schedule.ExceptionLeave, StartDate: "2025-03-01", EndDate: "2025-03-07"}},
+			},
+			start: at(3, 10, 0),
+			end:   at(3, 10, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewScheduleService(tt.repository, fakeClinicRepository{})
+			err := service.CheckWorkingTime(context.Background(), 1, doctorID, tt.start, tt.end)
+			if tt.wantErr && !errors.Is(err, schedule.ErrOutsideWorkingHours) {
+				t.Errorf("CheckWorkingTime() error = %v, want ErrOutsideWorkingHours", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("CheckWorkingTime() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestGetPublicHolidays(t *testing.T) {
+	repository := &fakeScheduleRepository{holidays: []schedule.PublicHoliday{
+		{Date: "2027-05-15", Name: "Kurban Bayramı Arifesi", HalfDay: true, HalfDayFrom: "13:00"},
+		{Date: "2027-05-19", Name: "Kurban Bayramı 4. Gün"},
+		// Stored before fixed-date holidays were computed
+		{Date: "2027-10-29", Name: "Cumhuriyet Bayramı"},
+	}}
+	service := NewScheduleService(repository, fakeClinicRepository{})
+
+	holidays, err := service.GetPublicHolidays(context.Background(), 2027)
+	if err != nil {
+		t.Fatalf("GetPublicHolidays() error = %v", err)
+	}
+	var dates []string
+	for _, holiday := range holidays {
+		dates = append(dates, holiday.Date)
+	}
+	want := []string{"2027-01-01", "2027-04-23", "2027-05-01", "2027-05-15", "2027-05-19", "2027-07-15", "2027-08-30", "2027-10-28", "2027-10-29"}
+	if strings.Join(dates, " ") != strings.Join(want, " ") {
+		t.Errorf("GetPublicHolidays() dates = %v, want %v", dates, want)
+	}
+
+	// Fixed-date holidays are computed for years without stored holidays
+	holidays, err = NewScheduleService(&fakeScheduleRepository{}, fakeClinicRepository{}).GetPublicHolidays(context.Background(), 2040)
+	if err != nil {
+		t.Fatalf("GetPublicHolidays() error = %v", err)
+	}
+	if len(holidays) != 8 || holidays[0].Date != "2040-01-01" || !holidays[6].HalfDay || holidays[6].Date != "2040-10-28" {
+		t.Errorf("GetPublicHolidays(2040) = %+v", holidays)
+	}
+}
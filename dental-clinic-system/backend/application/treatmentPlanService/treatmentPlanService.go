@@ -0,0 +1,228 @@
+package treatmentPlanService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/validations"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TreatmentPlanRepository defines the treatment plan database operations
+type TreatmentPlanRepository interface {
+	GetPlans(ctx context.Context, clinicID, patientID uint, status treatment.PlanStatus) ([]treatment.Plan, error)
+	GetPlan(ctx context.Context, id uint) (treatment.Plan, error)
+	CreatePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error)
+	ReplacePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error)
+	TransitionStatus(ctx context.Context, id uint, from, to treatment.PlanStatus, at time.Time) (treatment.Plan, error)
+	ScheduleItem(ctx context.Context, planID, itemID uint, newAppt appointment.Appointment) (appointment.Appointment, error)
+}
+
+// ProcedureRepository is used to check the procedures referenced by plan items
+type ProcedureRepository interface {
+	GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error)
+}
+
+// ScheduleService checks that scheduled items fall into the doctor's working time
+type ScheduleService interface {
+	CheckWorkingTime(ctx context.Context, clinicID, doctorID uint, start, end time.Time) error
+}
+
+// ScheduleRequest describes the appointment to book for a plan item
+type ScheduleRequest struct {
+	DoctorID        uint      `json:"doctor_id"`
+	ScheduledTime   time.Time `json:"scheduled_time"`
+	DurationMinutes int       `json:"duration_minutes"`
+	ResourceIDs     []uint    `json:"resource_ids"`
+	Notes           string    `json:"notes"`
+}
+
+// TreatmentPlanService manages treatment plans from draft to completion
+type TreatmentPlanService struct {
+	planRepository      TreatmentPlanRepository
+	procedureRepository ProcedureRepository
+	scheduleService     ScheduleService
+	now                 func() time.Time
+}
+
+// NewTreatmentPlanService creates a new instance of TreatmentPlanService
+func NewTreatmentPlanService(planRepo TreatmentPlanRepository, procedureRepo ProcedureRepository, scheduleService ScheduleService) *TreatmentPlanService {
+	return &TreatmentPlanService{
+		planRepository:      planRepo,
+		procedureRepository: procedureRepo,
+		scheduleService:     scheduleService,
+		now:                 time.Now,
+	}
+}
+
+// GetPlans retrieves the plans of a clinic, optionally for one patient or status
+func (s *TreatmentPlanService) GetPlans(ctx context.Context, clinicID, patientID uint, status treatment.PlanStatus) ([]treatment.Plan, error) {
+	plans, err := s.planRepository.GetPlans(ctx, clinicID, patientID, status)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		plans[i].Resolve()
+	}
+	return plans, nil
+}
+
+// GetPlan retrieves a plan with item progress and estimates filled in
+func (s *TreatmentPlanService) GetPlan(ctx context.Context, id uint) (treatment.Plan, error) {
+	plan, err := s.planRepository.GetPlan(ctx, id)
+	if err != nil {
+		return treatment.Plan{}, err
+	}
+	plan.Resolve()
+	return plan, nil
+}
+
+// CreatePlan validates and stores a new draft plan
+func (s *TreatmentPlanService) CreatePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error) {
+	if err := s.validate(ctx, &plan); err != nil {
+		return treatment.Plan{}, err
+	}
+
+	plan.ID = 0
+	plan.Status = treatment.PlanDraft
+	plan.PresentedAt, plan.DecidedAt, plan.CompletedAt = nil, nil, nil
+
+	created, err := s.planRepository.CreatePlan(ctx, plan)
+	if err != nil {
+		return treatment.Plan{}, err
+	}
+	created.Resolve()
+	return created, nil
+}
+
+// UpdatePlan replaces the content of a draft plan
+func (s *TreatmentPlanService) UpdatePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error) {
+	if err := s.validate(ctx, &plan); err != nil {
+		return treatment.Plan{}, err
+	}
+
+	updated, err := s.planRepository.ReplacePlan(ctx, plan)
+	if err != nil {
+		return treatment.Plan{}, err
+	}
+	updated.Resolve()
+	return updated, nil
+}
+
+// TransitionStatus moves a plan through its lifecycle.
+// A plan can only be completed once every item's appointment has been completed.
+func (s *TreatmentPlanService) TransitionStatus(ctx context.Context, id uint, to treatment.PlanStatus) (treatment.Plan, error) {
+	plan, err := s.GetPlan(ctx, id)
+	if err != nil {
+		return treatment.Plan{}, err
+	}
+
+	if !treatment.CanTransitionPlan(plan.Status, to) {
+		return treatment.Plan{}, &treatment.InvalidTransitionError{From: plan.Status, To: to}
+	}
+	if to == treatment.PlanCompleted && !plan.AllItemsCompleted() {
+		return treatment.Plan{}, treatment.ErrPlanNotComplete
+	}
+
+	updated, err := s.planRepository.TransitionStatus(ctx, id, plan.Status, to, s.now())
+	if err != nil {
+		return treatment.Plan{}, err
+	}
+	updated.Resolve()
+	return updated, nil
+}
+
+// ScheduleItem books an appointment for an item of an accepted plan and links it to the item.
+// The appointment lasts the requested duration, else the procedure's default duration.
+func (s *TreatmentPlanService) ScheduleItem(ctx context.Context, planID, itemID uint, req ScheduleRequest) (appointment.Appointment, error) {
+	plan, err := s.GetPlan(ctx, planID)
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+	if !plan.Status.CanSchedule() {
+		return appointment.Appointment{}, treatment.ErrPlanNotSchedulable
+	}
+
+	item, ok := plan.FindItem(itemID)
+	if !ok {
+		return appointment.Appointment{}, treatment.ErrItemNotFound
+	}
+	if item.Status != treatment.ItemPlanned {
+		return appointment.Appointment{}, treatment.ErrItemAlreadyBooked
+	}
+
+	if req.ScheduledTime.IsZero() || req.DurationMinutes < 0 {
+		return appointment.Appointment{}, appointment.ErrInvalidAppointmentTime
+	}
+	doctorID := req.DoctorID
+	if doctorID == 0 {
+		doctorID = plan.DoctorID
+	}
+	if doctorID == 0 {
+		return appointment.Appointment{}, fmt.Errorf("%w: doctor is required", treatment.ErrPlanValidation)
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	if duration == 0 {
+		duration = time.Duration(item.Procedure.DefaultDurationMinutes) * time.Minute
+	}
+	if duration <= 0 {
+		duration = appointment.DefaultDuration
+	}
+
+	newAppt := appointment.Appointment{
+		ClinicID:        plan.ClinicID,
+		PatientID:       plan.PatientID,
+		DoctorID:        doctorID,
+		ScheduledTime:   req.ScheduledTime,
+		EndTime:         req.ScheduledTime.Add(duration),
+		DurationMinutes: int(duration / time.Minute),
+		Status:          appointment.StatusBooked,
+		ProcedureID:     &item.ProcedureID,
+		Treatment:       itemTreatment(item),
+		Notes:           req.Notes,
+		ResourceIDs:     req.ResourceIDs,
+	}
+	if err := s.scheduleService.CheckWorkingTime(ctx, newAppt.ClinicID, newAppt.DoctorID, newAppt.ScheduledTime, newAppt.EndTime); err != nil {
+		return appointment.Appointment{}, err
+	}
+
+	return s.planRepository.ScheduleItem(ctx, planID, itemID, newAppt)
+}
+
+// validate checks the plan and that every referenced procedure belongs to the plan's clinic
+func (s *TreatmentPlanService) validate(ctx context.Context, plan *treatment.Plan) error {
+	if err := validations.TreatmentPlanValidation(plan); err != nil {
+		return fmt.Errorf("%w: %s", treatment.ErrPlanValidation, err.Error())
+	}
+
+	checked := map[uint]bool{}
+	for _, phase := range plan.Phases {
+		for _, item := range phase.Items {
+			if checked[item.ProcedureID] {
+				continue
+			}
+			proc, err := s.procedureRepository.GetProcedure(ctx, item.ProcedureID)
+			if err != nil || proc.ClinicID != plan.ClinicID {
+				return fmt.Errorf("%w: procedure %d not found", treatment.ErrPlanValidation, item.ProcedureID)
+			}
+			checked[item.ProcedureID] = true
+		}
+	}
+	return nil
+}
+
+// itemTreatment describes an item for the appointment, e.g. "Kanal tedavisi 36 MOD"
+func itemTreatment(item treatment.Item) string {
+	parts := []string{item.Procedure.Name}
+	if item.Tooth != nil {
+		parts = append(parts, fmt.Sprint(*item.Tooth))
+	}
+	if item.Surfaces != "" {
+		parts = append(parts, item.Surfaces)
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
@@ -0,0 +1,213 @@
+package treatmentPlanService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/treatment"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeTreatmentPlanRepository keeps a single plan in memory
+type fakeTreatmentPlanRepository struct {
+	plan   treatment.Plan
+	booked []appointment.Appointment
+}
+
+func (r *fakeTreatmentPlanRepository) GetPlans(ctx context.Context, clinicID, patientID uint, status treatment.PlanStatus) ([]treatment.Plan, error) {
+	return []treatment.Plan{r.plan}, nil
+}
+
+func (r *fakeTreatmentPlanRepository) GetPlan(ctx context.Context, id uint) (treatment.Plan, error) {
+	if r.plan.ID != id {
+		return treatment.Plan{}, treatment.ErrPlanNotFound
+	}
+	// Hand out a copy so the service can not change the stored plan behind the repository's back
+	plan := r.plan
+	plan.Phases = append([]treatment.Phase(nil), r.plan.Phases...)
+	for i := range plan.Phases {
+		plan.Phases[i].Items = append([]treatment.Item(nil), r.plan.Phases[i].Items...)
+	}
+	return plan, nil
+}
+
+func (r *fakeTreatmentPlanRepository) CreatePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error) {
+	plan.ID = 1
+	nextItemID := uint(1)
+	for i := range plan.Phases {
+		plan.Phases[i].ID = uint(i + 1)
+		for j := range plan.Phases[i].Items {
+			plan.Phases[i].Items[j].ID = nextItemID
+			plan.Phases[i].Items[j].Procedure = procedure.Procedure{Name: "Dolgu", DefaultDurationMinutes: 45, ClinicID: plan.ClinicID}
+			nextItemID++
+		}
+	}
+	r.plan = plan
+	return r.GetPlan(ctx, plan.ID)
+}
+
+func (r *fakeTreatmentPlanRepository) ReplacePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error) {
+	if r.plan.Status != treatment.PlanDraft {
+		return treatment.Plan{}, treatment.ErrPlanNotEditable
+	}
+	return r.CreatePlan(ctx, plan)
+}
+
+func (r *fakeTreatmentPlanRepository) TransitionStatus(ctx context.Context, id uint, from, to treatment.PlanStatus, at time.Time) (treatment.Plan, error) {
+	if r.plan.Status != from {
+		return treatment.Plan{}, &treatment.InvalidTransitionError{From: from, To: to}
+	}
+	r.plan.Status = to
+	return r.GetPlan(ctx, id)
+}
+
+func (r *fakeTreatmentPlanRepository) ScheduleItem(ctx context.Context, planID, itemID uint, newAppt appointment.Appointment) (appointment.Appointment, error) {
+	newAppt.ID = uint(100 + len(r.booked))
+	r.booked = append(r.booked, newAppt)
+	for i := range r.plan.Phases {
+		for j := range r.plan.Phases[i].Items {
+			if r.plan.Phases[i].Items[j].ID == itemID {
+				r.plan.Phases[i].Items[j].AppointmentID = &newAppt.ID
+				r.plan.Phases[i].Items[j].Appointment = &r.booked[len(r.booked)-1]
+			}
+		}
+	}
+	if r.plan.Status == treatment.PlanAccepted {
+		r.plan.Status = treatment.PlanInProgress
+	}
+	return newAppt, nil
+}
+
+// completeAppointments marks every booked appointment as completed
+func (r *fakeTreatmentPlanRepository) completeAppointments() {
+	for i := range r.plan.Phases {
+		for j := range r.plan.Phases[i].Items {
+			if appt := r.plan.Phases[i].Items[j].Appointment; appt != nil {
+				completed := *appt
+				completed.Status = appointment.StatusCompleted
+				r.plan.Phases[i].Items[j].Appointment = &completed
+			}
+		}
+	}
+}
+
+type fakeProcedureRepository struct{}
+
+func (fakeProcedureRepository) GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error) {
+	if id != 7 {
+		return procedure.Procedure{}, errors.New("record not found")
+	}
+	return procedure.Procedure{Name: "Dolgu", DefaultDurationMinutes: 45, ClinicID: 1}, nil
+}
+
+type alwaysOpenSchedule struct{}
+
+func (alwaysOpenSchedule) CheckWorkingTime(ctx context.Context, clinicID, doctorID uint, start, end time.Time) error {
+	return nil
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func newPlan() treatment.Plan {
+	return treatment.Plan{
+		ClinicID:  1,
+		PatientID: 2,
+		DoctorID:  3,
+		Title:     "Dolgu planı",
+		Phases: []treatment.Phase{
+			{Name: "Acil", Items: []treatment.Item{{ProcedureID: 7, Tooth: intPtr(36), Surfaces: "dom", EstimatedPrice: 150000}}},
+			{Name: "Devam", Items: []treatment.Item{{ProcedureID: 7, Tooth: intPtr(46), Surfaces: "o", EstimatedPrice: 120000}}},
+		},
+	}
+}
+
+func TestTreatmentPlanLifecycle(t *testing.T) {
+	repository := &fakeTreatmentPlanRepository{}
+	service := NewTreatmentPlanService(repository, fakeProcedureRepository{}, alwaysOpenSchedule{})
+	ctx := context.Background()
+
+	plan, err := service.CreatePlan(ctx, newPlan())
+	if err != nil {
+		t.Fatalf("CreatePlan() error = %v", err)
+	}
+	if plan.Status != treatment.PlanDraft || plan.EstimatedTotal != 270000 || plan.Phases[0].EstimatedTotal != 150000 || plan.Currency != treatment.DefaultCurrency {
+		t.Errorf("CreatePlan() = status %s, total %d, phase total %d, currency %s", plan.Status, plan.EstimatedTotal, plan.Phases[0].EstimatedTotal, plan.Currency)
+	}
+	if plan.Phases[0].Items[0].Surfaces != "MOD" || plan.Phases[0].Items[0].Status != treatment.ItemPlanned {
+		t.Errorf("item = %+v, want canonical surfaces and planned status", plan.Phases[0].Items[0])
+	}
+
+	itemID := plan.Phases[0].Items[0].ID
+	start := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+	if _, err := service.ScheduleItem(ctx, plan.ID, itemID, ScheduleRequest{ScheduledTime: start}); !errors.Is(err, treatment.ErrPlanNotSchedulable) {
+		t.Errorf("ScheduleItem() on a draft plan error = %v, want ErrPlanNotSchedulable", err)
+	}
+	if _, err := service.TransitionStatus(ctx, plan.ID, treatment.PlanAccepted); !errors.Is(err, treatment.ErrInvalidStatusTransition) {
+		t.Errorf("accepting a draft error = %v, want ErrInvalidStatusTransition", err)
+	}
+
+	for _, to := range []treatment.PlanStatus{treatment.PlanPresented, treatment.PlanAccepted} {
+		if _, err := service.TransitionStatus(ctx, plan.ID, to); err != nil {
+			t.Fatalf("TransitionStatus(%s) error = %v", to, err)
+		}
+	}
+	if _, err := service.UpdatePlan(ctx, func() treatment.Plan { p := newPlan(); p.ID = plan.ID; return p }()); !errors.Is(err, treatment.ErrPlanNotEditable) {
+		t.Errorf("UpdatePlan() on an accepted plan error = %v, want ErrPlanNotEditable", err)
+	}
+
+	booked, err := service.ScheduleItem(ctx, plan.ID, itemID, ScheduleRequest{ScheduledTime: start})
+	if err != nil {
+		t.Fatalf("ScheduleItem() error = %v", err)
+	}
+	if booked.DoctorID != 3 || booked.PatientID != 2 || booked.DurationMinutes != 45 || booked.Treatment != "Dolgu 36 MOD" || *booked.ProcedureID != 7 {
+		t.Errorf("ScheduleItem() = %+v, want the plan's doctor and patient with the procedure's default duration", booked)
+	}
+	if _, err := service.ScheduleItem(ctx, plan.ID, itemID, ScheduleRequest{ScheduledTime: start.AddDate(0, 0, 1)}); !errors.Is(err, treatment.ErrItemAlreadyBooked) {
+		t.Errorf("ScheduleItem() twice error = %v, want ErrItemAlreadyBooked", err)
+	}
+
+	current, _ := service.GetPlan(ctx, plan.ID)
+	if current.Status != treatment.PlanInProgress || current.Phases[0].Items[0].Status != treatment.ItemScheduled {
+		t.Errorf("plan status %s, item status %s, want in_progress and scheduled", current.Status, current.Phases[0].Items[0].Status)
+	}
+
+	if _, err := service.TransitionStatus(ctx, plan.ID, treatment.PlanCompleted); !errors.Is(err, treatment.ErrPlanNotComplete) {
+		t.Errorf("completing with an open item error = %v, want ErrPlanNotComplete", err)
+	}
+	if _, err := service.ScheduleItem(ctx, plan.ID, plan.Phases[1].Items[0].ID, ScheduleRequest{ScheduledTime: start.Add(time.Hour), DurationMinutes: 30}); err != nil {
+		t.Fatalf("ScheduleItem() error = %v", err)
+	}
+	repository.completeAppointments()
+	if completed, err := service.TransitionStatus(ctx, plan.ID, treatment.PlanCompleted); err != nil || completed.Status != treatment.PlanCompleted {
+		t.Errorf("TransitionStatus(completed) = %s, %v", completed.Status, err)
+	}
+}
+
+func TestCreatePlanValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(plan *treatment.Plan)
+	}{
+		{name: "Missing title", modify: func(plan *treatment.Plan) { plan.Title = "" }},
+		{name: "No phases", modify: func(plan *treatment.Plan) { plan.Phases = nil }},
+		{name: "Unknown tooth", modify: func(plan *treatment.Plan) { plan.Phases[0].Items[0].Tooth = intPtr(99) }},
+		{name: "Negative price", modify: func(plan *treatment.Plan) { plan.Phases[0].Items[0].EstimatedPrice = -1 }},
+		{name: "Procedure of another clinic", modify: func(plan *treatment.Plan) { plan.Phases[0].Items[0].ProcedureID = 8 }},
+		{name: "Invalid currency", modify: func(plan *treatment.Plan) { plan.Currency = "lira" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewTreatmentPlanService(&fakeTreatmentPlanRepository{}, fakeProcedureRepository{}, alwaysOpenSchedule{})
+			plan := newPlan()
+			tt.modify(&plan)
+			if _, err := service.CreatePlan(context.Background(), plan); !errors.Is(err, treatment.ErrPlanValidation) {
+				t.Errorf("CreatePlan() error = %v, want ErrPlanValidation", err)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ var (
 // UserRepository defines the interface for user-related database operations
 type UserRepository interface {
 	GetUsers(ctx context.Context, clinicID uint) ([]user.User, error)
+	GetUsersByRole(ctx context.Context, clinicID uint, roleName user.RoleName) ([]user.User, error)
 	GetUser(ctx context.Context, id uint) (user.User, error)
 	GetUserByEmail(ctx context.Context, email string) (user.User, error)
 	CreateUser(ctx context.Context, usr user.User) (user.User, error)
@@ -77,6 +78,27 @@ func (s *UserService) GetUsers(ctx context.Context, clinicID uint) ([]user.UserG
 	return usersGetModel, nil
 }
 
+// GetUsersByRole retrieves the users of a clinic with the given role and maps them to UserGetModel
+func (s *UserService) GetUsersByRole(ctx context.Context, clinicID uint, roleName user.RoleName) ([]user.UserGetModel, error) {
+	users, err := s.userRepository.GetUsersByRole(ctx, clinicID, roleName)
+	if err != nil {
+		log.Error().
+			Str("operation", "GetUsersByRole").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Str("role", string(roleName)).
+			Msg("Failed to retrieve users by role")
+		return nil, err
+	}
+
+	usersGetModel := make([]user.UserGetModel, 0, len(users))
+	for _, usr := range users {
+		usersGetModel = append(usersGetModel, mapper.MapUserToUserGetModel(usr))
+	}
+
+	return usersGetModel, nil
+}
+
 // GetUser retrieves a single user by its ID and maps it to UserGetModel
 func (s *UserService) GetUser(ctx context.Context, id uint) (user.UserGetModel, error) {
 	log.Info().
@@ -0,0 +1,267 @@
+package waitlistService
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/waitlist"
+	"dental-clinic-system/validations"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// OfferTimeLayout formats times shown in waitlist offer emails
+const OfferTimeLayout = "02.01.2006 15:04"
+
+// offerTokenBytes is the amount of random data in an offer acceptance token
+const offerTokenBytes = 24
+
+// WaitlistRepository defines the waitlist database operations
+type WaitlistRepository interface {
+	GetEntries(ctx context.Context, clinicID uint, status waitlist.EntryStatus) ([]waitlist.Entry, error)
+	GetEntry(ctx context.Context, id uint) (waitlist.Entry, error)
+	CreateEntry(ctx context.Context, entry waitlist.Entry) (waitlist.Entry, error)
+	UpdateEntryStatus(ctx context.Context, id uint, from, to waitlist.EntryStatus) error
+	CreateOffers(ctx context.Context, offers []waitlist.Offer) ([]waitlist.Offer, error)
+	GetOffer(ctx context.Context, id uint) (waitlist.Offer, error)
+	GetOfferByTokenHash(ctx context.Context, tokenHash string) (waitlist.Offer, error)
+	ClaimOffer(ctx context.Context, offerID uint, now time.Time, newAppt appointment.Appointment) (appointment.Appointment, error)
+}
+
+// ClinicRepository defines the clinic lookups needed for backfilling
+type ClinicRepository interface {
+	GetClinic(ctx context.Context, id uint) (clinic.Clinic, error)
+}
+
+// ProcedureRepository defines the procedure lookups needed for waitlist entries
+type ProcedureRepository interface {
+	GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error)
+}
+
+// OfferProducer publishes waitlist offer notifications
+type OfferProducer interface {
+	SendWaitlistOffer(email string, data map[string]string) error
+}
+
+// WaitlistService manages the clinic waitlist and offers freed slots to it
+type WaitlistService struct {
+	waitlistRepository  WaitlistRepository
+	clinicRepository    ClinicRepository
+	procedureRepository ProcedureRepository
+	producer            OfferProducer
+	now                 func() time.Time
+}
+
+// NewWaitlistService creates a new instance of WaitlistService
+func NewWaitlistService(waitlistRepo WaitlistRepository, clinicRepo ClinicRepository, procedureRepo ProcedureRepository, producer OfferProducer) *WaitlistService {
+	return &WaitlistService{
+		waitlistRepository:  waitlistRepo,
+		clinicRepository:    clinicRepo,
+		procedureRepository: procedureRepo,
+		producer:            producer,
+		now:                 time.Now,
+	}
+}
+
+// GetEntries retrieves the waitlist of a clinic in priority order
+func (s *WaitlistService) GetEntries(ctx context.Context, clinicID uint, status waitlist.EntryStatus) ([]waitlist.Entry, error) {
+	return s.waitlistRepository.GetEntries(ctx, clinicID, status)
+}
+
+// GetEntry retrieves a waitlist entry by its ID
+func (s *WaitlistService) GetEntry(ctx context.Context, id uint) (waitlist.Entry, error) {
+	return s.waitlistRepository.GetEntry(ctx, id)
+}
+
+// CreateEntry queues a patient on the waitlist.
+// When a procedure is given, its default duration and name are used unless the entry overrides them.
+func (s *WaitlistService) CreateEntry(ctx context.Context, entry waitlist.Entry) (waitlist.Entry, error) {
+	if err := validations.WaitlistEntryValidation(&entry); err != nil {
+		return waitlist.Entry{}, fmt.Errorf("%w: %s", waitlist.ErrWaitlistValidation, err.Error())
+	}
+
+	if entry.ProcedureID != nil {
+		proc, err := s.procedureRepository.GetProcedure(ctx, *entry.ProcedureID)
+		if err != nil {
+			return waitlist.Entry{}, err
+		}
+		if proc.ClinicID != entry.ClinicID {
+			return waitlist.Entry{}, fmt.Errorf("%w: procedure belongs to another clinic", waitlist.ErrWaitlistValidation)
+		}
+		if entry.DurationMinutes == 0 {
+			entry.DurationMinutes = proc.DefaultDurationMinutes
+		}
+		if entry.Treatment == "" {
+			entry.Treatment = proc.Name
+		}
+	}
+	if entry.DurationMinutes == 0 {
+		entry.DurationMinutes = int(appointment.DefaultDuration / time.Minute)
+	}
+
+	entry.Status = waitlist.EntryWaiting
+	return s.waitlistRepository.CreateEntry(ctx, entry)
+}
+
+// CancelEntry removes a waiting patient from the waitlist and voids their pending offers
+func (s *WaitlistService) CancelEntry(ctx context.Context, id uint) error {
+	return s.waitlistRepository.UpdateEntryStatus(ctx, id, waitlist.EntryWaiting, waitlist.EntryCancelled)
+}
+
+// GetOffer retrieves an offer by its ID
+func (s *WaitlistService) GetOffer(ctx context.Context, id uint) (waitlist.Offer, error) {
+	return s.waitlistRepository.GetOffer(ctx, id)
+}
+
+// ReleaseSlot offers the slot of a cancelled or deleted appointment to the best matching waitlist entries.
+// The top OfferBatchSize entries are notified at once in priority order; the first to accept gets the slot.
+func (s *WaitlistService) ReleaseSlot(ctx context.Context, released appointment.Appointment) error {
+	now := s.now()
+	if !released.ScheduledTime.After(now) {
+		return nil
+	}
+
+	cln := released.Clinic
+	if cln.ID == 0 {
+		var err error
+		if cln, err = s.clinicRepository.GetClinic(ctx, released.ClinicID); err != nil {
+			return err
+		}
+	}
+	loc := cln.Location()
+
+	entries, err := s.waitlistRepository.GetEntries(ctx, released.ClinicID, waitlist.EntryWaiting)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := now.Add(waitlist.OfferTTL)
+	if released.ScheduledTime.Before(expiresAt) {
+		expiresAt = released.ScheduledTime
+	}
+
+	var offers []waitlist.Offer
+	tokens := map[uint]string{}
+	for _, entry := range entries {
+		if len(offers) == waitlist.OfferBatchSize {
+			break
+		}
+		if entry.PatientID == released.PatientID || !entry.Matches(released.DoctorID, released.ScheduledTime, released.EndTime, loc) {
+			continue
+		}
+
+		token, err := newOfferToken()
+		if err != nil {
+			return err
+		}
+		tokens[entry.ID] = token
+		offers = append(offers, waitlist.Offer{
+			ClinicID:            released.ClinicID,
+			EntryID:             entry.ID,
+			Entry:               entry,
+			SourceAppointmentID: released.ID,
+			DoctorID:            released.DoctorID,
+			StartTime:           released.ScheduledTime,
+			EndTime:             released.ScheduledTime.Add(entry.Duration()),
+			Rank:                len(offers) + 1,
+			Status:              waitlist.OfferPending,
+			TokenHash:           hashOfferToken(token),
+			ExpiresAt:           expiresAt,
+		})
+	}
+
+	if len(offers) == 0 {
+		return nil
+	}
+
+	created, err := s.waitlistRepository.CreateOffers(ctx, offers)
+	if err != nil {
+		return err
+	}
+
+	for i, offer := range created {
+		patient := offers[i].Entry.Patient
+		if patient.Email() == "" {
+			continue
+		}
+		data := map[string]string{
+			"patient_name":     strings.TrimSpace(patient.Name),
+			"clinic_name":      cln.Name,
+			"doctor_name":      strings.TrimSpace(released.Doctor.FirstName + " " + released.Doctor.LastName),
+			"treatment":        offers[i].Entry.Treatment,
+			"appointment_time": offer.StartTime.In(loc).Format(OfferTimeLayout),
+			"expires_at":       offer.ExpiresAt.In(loc).Format(OfferTimeLayout),
+			"token":            tokens[offer.EntryID],
+		}
+		if err := s.producer.SendWaitlistOffer(patient.Email(), data); err != nil {
+			log.Error().
+				Str("operation", "ReleaseSlot").
+				Err(err).
+				Uint("offer_id", offer.ID).
+				Msg("Failed to publish waitlist offer")
+		}
+	}
+
+	log.Info().
+		Str("operation", "ReleaseSlot").
+		Uint("appointment_id", released.ID).
+		Int("count", len(created)).
+		Msgf("Offered freed slot to %d waitlist entries", len(created))
+
+	return nil
+}
+
+// AcceptOffer books the offered slot for the waitlisted patient.
+// It returns waitlist.ErrOfferNotAvailable if the offer expired or another entry claimed the slot first.
+func (s *WaitlistService) AcceptOffer(ctx context.Context, offerID uint) (appointment.Appointment, error) {
+	offer, err := s.waitlistRepository.GetOffer(ctx, offerID)
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+	return s.claim(ctx, offer)
+}
+
+// AcceptOfferByToken books the offered slot using the token sent to the patient
+func (s *WaitlistService) AcceptOfferByToken(ctx context.Context, token string) (appointment.Appointment, error) {
+	offer, err := s.waitlistRepository.GetOfferByTokenHash(ctx, hashOfferToken(token))
+	if err != nil {
+		return appointment.Appointment{}, err
+	}
+	return s.claim(ctx, offer)
+}
+
+func (s *WaitlistService) claim(ctx context.Context, offer waitlist.Offer) (appointment.Appointment, error) {
+	return s.waitlistRepository.ClaimOffer(ctx, offer.ID, s.now(), appointment.Appointment{
+		ClinicID:        offer.ClinicID,
+		PatientID:       offer.Entry.PatientID,
+		DoctorID:        offer.DoctorID,
+		ScheduledTime:   offer.StartTime,
+		EndTime:         offer.EndTime,
+		DurationMinutes: int(offer.EndTime.Sub(offer.StartTime) / time.Minute),
+		Status:          appointment.StatusBooked,
+		ProcedureID:     offer.Entry.ProcedureID,
+		Treatment:       offer.Entry.Treatment,
+		Notes:           offer.Entry.Notes,
+	})
+}
+
+func newOfferToken() (string, error) {
+	raw := make([]byte, offerTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashOfferToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
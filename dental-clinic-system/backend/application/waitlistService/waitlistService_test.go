@@ -0,0 +1,248 @@
+package waitlistService
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/waitlist"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeWaitlistRepository struct {
+	mu      sync.Mutex
+	entries []waitlist.Entry
+	offers  []waitlist.Offer
+	booked  []appointment.Appointment
+}
+
+func (r *fakeWaitlistRepository) GetEntries(ctx context.Context, clinicID uint, status waitlist.EntryStatus) ([]waitlist.Entry, error) {
+	var entries []waitlist.Entry
+	for _, entry := range r.entries {
+		if entry.ClinicID == clinicID && (status == "" || entry.Status == status) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *fakeWaitlistRepository) GetEntry(ctx context.Context, id uint) (waitlist.Entry, error) {
+	for _, entry := range r.entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return waitlist.Entry{}, waitlist.ErrEntryNotFound
+}
+
+func (r *fakeWaitlistRepository) CreateEntry(ctx context.Context, entry waitlist.Entry) (waitlist.Entry, error) {
+	entry.ID = uint(len(r.entries) + 1)
+	r.entries = append(r.entries, entry)
+	return entry, nil
+}
+
+func (r *fakeWaitlistRepository) UpdateEntryStatus(ctx context.Context, id uint, from, to waitlist.EntryStatus) error {
+	return nil
+}
+
+func (r *fakeWaitlistRepository) CreateOffers(ctx context.Context, offers []waitlist.Offer) ([]waitlist.Offer, error) {
+	for i := range offers {
+		offers[i].ID = uint(len(r.offers) + 1)
+		r.offers = append(r.offers, offers[i])
+	}
+	return offers, nil
+}
+
+func (r *fakeWaitlistRepository) GetOffer(ctx context.Context, id uint) (waitlist.Offer, error) {
+	for _, offer := range r.offers {
+		if offer.ID == id {
+			return offer, nil
+		}
+	}
+	return waitlist.Offer{}, waitlist.ErrOfferNotFound
+}
+
+func (r *fakeWaitlistRepository) GetOfferByTokenHash(ctx context.Context, tokenHash string) (waitlist.Offer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, offer := range r.offers {
+		if offer.TokenHash == tokenHash {
+			return offer, nil
+		}
+	}
+	return waitlist.Offer{}, waitlist.ErrOfferNotFound
+}
+
+// ClaimOffer mirrors the repository: only one pending offer per source appointment can be accepted
+func (r *fakeWaitlistRepository) ClaimOffer(ctx context.Context, offerID uint, now time.Time, newAppt appointment.Appointment) (appointment.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed *waitlist.Offer
+	for i := range r.offers {
+		if r.offers[i].ID == offerID {
+			claimed = &r.offers[i]
+		}
+	}
+	if claimed == nil || claimed.Status != waitlist.OfferPending || !claimed.ExpiresAt.After(now) {
+		return appointment.Appointment{}, waitlist.ErrOfferNotAvailable
+	}
+	claimed.Status = waitlist.OfferAccepted
+	for i := range r.offers {
+		if r.offers[i].ID != offerID && r.offers[i].SourceAppointmentID == claimed.SourceAppointmentID && r.offers[i].Status == waitlist.OfferPending {
+			r.offers[i].Status = waitlist.OfferSuperseded
+		}
+	}
+	newAppt.ID = uint(100 + len(r.booked))
+	r.booked = append(r.booked, newAppt)
+	return newAppt, nil
+}
+
+type fakeClinicRepository struct{}
+
+func (fakeClinicRepository) GetClinic(ctx context.Context, id uint) (clinic.Clinic, error) {
+	return clinic.Clinic{Name: "Gülüş Diş", Timezone: "Europe/Istanbul"}, nil
+}
+
+type fakeProcedureRepository struct{}
+
+func (fakeProcedureRepository) GetProcedure(ctx context.Context, id uint) (procedure.Procedure, error) {
+	return procedure.Procedure{Name: "Dolgu", DefaultDurationMinutes: 45, ClinicID: 1}, nil
+}
+
+type fakeOfferProducer struct {
+	mu         sync.Mutex
+	recipients []string
+	tokens     []string
+}
+
+func (p *fakeOfferProducer) SendWaitlistOffer(email string, data map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recipients = append(p.recipients, email)
+	p.tokens = append(p.tokens, data["token"])
+	return nil
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestReleaseSlot(t *testing.T) {
+	now := time.Date(2025, 3, 3, 6, 0, 0, 0, time.UTC)
+	// Monday 10:00-10:30 in Istanbul
+	slotStart := time.Date(2025, 3, 3, 7, 0, 0, 0, time.UTC)
+
+	newEntry := func(id uint, email string, priority int) waitlist.Entry {
+		entry := waitlist.Entry{
+			ClinicID:        1,
+			PatientID:       id + 10,
+			Patient:         patient.Patient{ContactInfo: email},
+			DurationMinutes: 30,
+			Priority:        priority,
+			Status:          waitlist.EntryWaiting,
+		}
+		entry.ID = id
+		return entry
+	}
+
+	otherDoctor := newEntry(1, "other-doctor@example.com", 9)
+	otherDoctor.DoctorID = uintPtr(2)
+	tooLong := newEntry(2, "too-long@example.com", 8)
+	tooLong.DurationMinutes = 60
+	afternoonOnly := newEntry(3, "afternoon@example.com", 7)
+	afternoonOnly.Windows = []waitlist.Window{{Weekday: time.Monday, StartTime: "13:00", EndTime: "18:00"}}
+	mondayMorning := newEntry(4, "monday-morning@example.com", 6)
+	mondayMorning.Windows = []waitlist.Window{{Weekday: time.Monday, StartTime: "09:00", EndTime: "12:00"}}
+
+	repository := &fakeWaitlistRepository{entries: []waitlist.Entry{
+		otherDoctor,
+		tooLong,
+		afternoonOnly,
+		mondayMorning,
+		newEntry(5, "first@example.com", 5),
+		newEntry(6, "second@example.com", 4),
+		newEntry(7, "fourth@example.com", 3),
+	}}
+	producer := &fakeOfferProducer{}
+	service := NewWaitlistService(repository, fakeClinicRepository{}, fakeProcedureRepository{}, producer)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	released := appointment.Appointment{ClinicID: 1, DoctorID: 1, PatientID: 99, ScheduledTime: slotStart, EndTime: slotStart.Add(30 * time.Minute)}
+	released.ID = 50
+	if err := service.ReleaseSlot(ctx, released); err != nil {
+		t.Fatalf("ReleaseSlot() error = %v", err)
+	}
+
+	want := []string{"monday-morning@example.com", "first@example.com", "second@example.com"}
+	if len(producer.recipients) != len(want) {
+		t.Fatalf("offers sent to %v, want %v", producer.recipients, want)
+	}
+	for i := range want {
+		if producer.recipients[i] != want[i] || repository.offers[i].Rank != i+1 {
+			t.Errorf("offer %d sent to %s with rank %d, want %s with rank %d", i, producer.recipients[i], repository.offers[i].Rank, want[i], i+1)
+		}
+	}
+
+	// The first patient to accept gets the slot, the others are too late
+	var wg sync.WaitGroup
+	results := make([]error, len(producer.tokens))
+	for i, token := range producer.tokens {
+		wg.Add(1)
+		go func(i int, token string) {
+			defer wg.Done()
+			_, results[i] = service.AcceptOfferByToken(ctx, token)
+		}(i, token)
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, err := range results {
+		switch {
+		case err == nil:
+			accepted++
+		case !errors.Is(err, waitlist.ErrOfferNotAvailable):
+			t.Errorf("AcceptOfferByToken() error = %v, want ErrOfferNotAvailable", err)
+		}
+	}
+	if accepted != 1 || len(repository.booked) != 1 {
+		t.Errorf("%d offers accepted and %d appointments booked, want exactly one", accepted, len(repository.booked))
+	}
+}
+
+func TestReleaseSlotIgnoresPastAppointments(t *testing.T) {
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	repository := &fakeWaitlistRepository{entries: []waitlist.Entry{{ClinicID: 1, PatientID: 2, Status: waitlist.EntryWaiting}}}
+	producer := &fakeOfferProducer{}
+	service := NewWaitlistService(repository, fakeClinicRepository{}, fakeProcedureRepository{}, producer)
+	service.now = func() time.Time { return now }
+
+	released := appointment.Appointment{ClinicID: 1, DoctorID: 1, ScheduledTime: now.Add(-time.Hour), EndTime: now.Add(-30 * time.Minute)}
+	if err := service.ReleaseSlot(context.Background(), released); err != nil {
+		t.Fatalf("ReleaseSlot() error = %v", err)
+	}
+	if len(repository.offers) != 0 {
+		t.Errorf("%d offers created for a past slot, want 0", len(repository.offers))
+	}
+}
+
+func TestCreateEntryUsesProcedureDefaults(t *testing.T) {
+	service := NewWaitlistService(&fakeWaitlistRepository{}, fakeClinicRepository{}, fakeProcedureRepository{}, &fakeOfferProducer{})
+
+	entry, err := service.CreateEntry(context.Background(), waitlist.Entry{ClinicID: 1, PatientID: 2, ProcedureID: uintPtr(3)})
+	if err != nil {
+		t.Fatalf("CreateEntry() error = %v", err)
+	}
+	if entry.DurationMinutes != 45 || entry.Treatment != "Dolgu" || entry.Status != waitlist.EntryWaiting {
+		t.Errorf("CreateEntry() = %+v, want procedure duration and name", entry)
+	}
+
+	_, err = service.CreateEntry(context.Background(), waitlist.Entry{ClinicID: 1, PatientID: 2, Windows: []waitlist.Window{{Weekday: 9, StartTime: "09:00", EndTime: "10:00"}}})
+	if !errors.Is(err, waitlist.ErrWaitlistValidation) {
+		t.Errorf("CreateEntry() error = %v, want ErrWaitlistValidation", err)
+	}
+}
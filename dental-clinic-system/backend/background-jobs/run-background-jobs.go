@@ -15,6 +15,14 @@ type PasswordResetTokenService interface {
 	DeleteExpiredTokens(ctx context.Context) error
 }
 
+type ReminderService interface {
+	SendDueReminders(ctx context.Context) error
+}
+
+type InstallmentService interface {
+	ProcessOverdueInstallments(ctx context.Context) error
+}
+
 func StartCleanExpiredJwtTokens(tokenService TokenService) {
 	ctx := context.Background()
 
@@ -51,3 +59,43 @@ func StartCleanExpiredPasswordResetTokens(passwordResetTokenService PasswordRese
 
 	c.Start()
 }
+
+func StartAppointmentReminders(reminderService ReminderService) {
+	ctx := context.Background()
+
+	c := cron.New()
+	// Her 5 dakikada bir yaklaşan randevuları kontrol et
+	cronExpression := "@every 5m"
+
+	_, err := c.AddFunc(cronExpression, func() {
+		err := reminderService.SendDueReminders(ctx)
+		if err != nil {
+			fmt.Printf("Error sending appointment reminders: %v\n", err)
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	c.Start()
+}
+
+func StartOverdueInstallments(installmentService InstallmentService) {
+	ctx := context.Background()
+
+	c := cron.New()
+	// Her gün sabah 07:00'de vadesi geçen taksitleri işaretle ve hatırlatma gönder
+	cronExpression := "0 7 * * *"
+
+	_, err := c.AddFunc(cronExpression, func() {
+		err := installmentService.ProcessOverdueInstallments(ctx)
+		if err != nil {
+			fmt.Printf("Error processing overdue installments: %v\n", err)
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	c.Start()
+}
@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"context"
+	"dental-clinic-system/models/claims"
+	"dental-clinic-system/models/user"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// TokenParser reads the claims of the request cookie
+type TokenParser interface {
+	ParseTokenFromCookie(c *fiber.Ctx) (*claims.Claims, error)
+}
+
+// UserFinder looks up the user a token was issued for
+type UserFinder interface {
+	GetUserByEmail(ctx context.Context, email string) (user.UserGetModel, error)
+}
+
+// The helpers below write the error response themselves; when they return false the handler only has to return.
+
+// AuthenticatedUser resolves the user behind the request cookie
+func AuthenticatedUser(c *fiber.Ctx, tokens TokenParser, users UserFinder) (user.UserGetModel, bool) {
+	claims, err := tokens.ParseTokenFromCookie(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid token")
+		_ = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+		return user.UserGetModel{}, false
+	}
+
+	authenticatedUser, err := users.GetUserByEmail(c.Context(), claims.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("User not found")
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+		return user.UserGetModel{}, false
+	}
+
+	return authenticatedUser, true
+}
+
+// SameClinic rejects access to a record of another clinic than the authenticated user's
+func SameClinic(c *fiber.Ctx, authenticatedUser user.UserGetModel, recordClinicID uint, record string) bool {
+	if recordClinicID == authenticatedUser.ClinicID {
+		return true
+	}
+	log.Warn().Msgf("Unauthorized access to %s", record)
+	_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": fmt.Sprintf("Unauthorized access to %s", record),
+	})
+	return false
+}
+
+// ParseID reads the :id route parameter as the ID of the named record
+func ParseID(c *fiber.Ctx, record string) (uint, bool) {
+	idStr := c.Params("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		log.Warn().Msgf("Invalid %s ID: %s", record, idStr)
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid %s ID", record),
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -9,14 +9,16 @@ import (
 //so we need to use the min=0 to validate the int values instead of required
 
 type ConfigModel struct {
-	Server   ServerConfig   `yaml:"server" validate:"required"`
-	Database DatabaseConfig `yaml:"database" validate:"required"`
-	Vault    VaultConfig    `yaml:"vault" validate:"required"`
-	Email    EmailConfig    `yaml:"email" validate:"required"`
-	Redis    RedisConfig    `yaml:"redis" validate:"required"`
-	Log      LogConfig      `yaml:"log" validate:"required"`
-	JWT      JWTConfig      `validate:"required"`
-	Kafka    KafkaConfig    `yaml:"kafka" validate:"required"`
+	Server      ServerConfig      `yaml:"server" validate:"required"`
+	Database    DatabaseConfig    `yaml:"database" validate:"required"`
+	Vault       VaultConfig       `yaml:"vault" validate:"required"`
+	Email       EmailConfig       `yaml:"email" validate:"required"`
+	Redis       RedisConfig       `yaml:"redis" validate:"required"`
+	Log         LogConfig         `yaml:"log" validate:"required"`
+	JWT         JWTConfig         `validate:"required"`
+	Kafka       KafkaConfig       `yaml:"kafka" validate:"required"`
+	EInvoice    EInvoiceConfig    `yaml:"eInvoice"`
+	Attachments AttachmentsConfig `yaml:"attachments"`
 }
 
 type ServerConfig struct {
@@ -64,6 +66,29 @@ type KafkaConfig struct {
 	DeadLetterTopic    string   `yaml:"deadLetterTopic" validate:"required"`
 }
 
+// EInvoiceConfig configures where e-invoices go until a GİB integrator client is set up
+type EInvoiceConfig struct {
+	OutboxDir string `yaml:"outboxDir"`
+}
+
+// AttachmentsConfig configures where patient attachments are stored and how large they may be
+type AttachmentsConfig struct {
+	// Driver is "local" (the default) or "s3"
+	Driver string   `yaml:"driver" validate:"omitempty,oneof=local s3"`
+	Dir    string   `yaml:"dir"`
+	MaxMB  int64    `yaml:"maxMB" validate:"min=0"`
+	S3     S3Config `yaml:"s3"`
+}
+
+// S3Config locates an S3-compatible bucket, e.g. AWS S3 or MinIO
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+}
+
 // ValidateConfig validates the configuration using the validator
 func (c *ConfigModel) ValidateConfig() error {
 	validate := validator.New()
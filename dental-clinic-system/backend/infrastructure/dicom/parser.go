@@ -0,0 +1,424 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Transfer syntaxes that change how the data set is encoded. All others are treated as
+// explicit VR little endian with compressed (encapsulated) pixel data.
+const (
+	ImplicitVRLittleEndian         = "1.2.840.10008.1.2"
+	ExplicitVRLittleEndian         = "1.2.840.10008.1.2.1"
+	DeflatedExplicitVRLittleEndian = "1.2.840.10008.1.2.1.99"
+	ExplicitVRBigEndian            = "1.2.840.10008.1.2.2"
+)
+
+// Error types
+var (
+	ErrNotDicom                  = errors.New("file is not a DICOM file")
+	ErrUnsupportedTransferSyntax = errors.New("DICOM transfer syntax is not supported")
+	ErrMalformed                 = errors.New("malformed DICOM file")
+)
+
+// Tags read from the data set, as group<<16 | element
+const (
+	tagTransferSyntax            = 0x00020010
+	tagSOPClassUID               = 0x00080016
+	tagSOPInstanceUID            = 0x00080018
+	tagStudyDate                 = 0x00080020
+	tagModality                  = 0x00080060
+	tagCodeValue                 = 0x00080100
+	tagCodingScheme              = 0x00080102
+	tagCodeMeaning               = 0x00080104
+	tagStudyDescription          = 0x00081030
+	tagSeriesDescription         = 0x0008103E
+	tagAnatomicRegionSequence    = 0x00082218
+	tagAnatomicStructureSequence = 0x00082228
+	tagPatientName               = 0x00100010
+	tagPatientID                 = 0x00100020
+	tagPatientBirthDate          = 0x00100030
+	tagBodyPartExamined          = 0x00180015
+	tagStudyInstanceUID          = 0x0020000D
+	tagSeriesInstanceUID         = 0x0020000E
+	tagSamplesPerPixel           = 0x00280002
+	tagPhotometric               = 0x00280004
+	tagPlanarConfiguration       = 0x00280006
+	tagNumberOfFrames            = 0x00280008
+	tagRows                      = 0x00280010
+	tagColumns                   = 0x00280011
+	tagBitsAllocated             = 0x00280100
+	tagBitsStored                = 0x00280101
+	tagPixelRepresentation       = 0x00280103
+	tagWindowCenter              = 0x00281050
+	tagWindowWidth               = 0x00281051
+	tagRescaleIntercept          = 0x00281052
+	tagRescaleSlope              = 0x00281053
+	tagPixelData                 = 0x7FE00010
+
+	tagItem              = 0xFFFEE000
+	tagItemDelimiter     = 0xFFFEE00D
+	tagSequenceDelimiter = 0xFFFEE0DD
+)
+
+const undefinedLength = 0xFFFFFFFF
+
+// MaxSequenceDepth is how deeply sequences may be nested. Real files nest a few levels; deeper nesting
+// is rejected as malformed instead of recursing without bound.
+const MaxSequenceDepth = 16
+
+// Code is an entry of a coded sequence, e.g. a tooth in the anatomic structure sequence
+type Code struct {
+	Value   string
+	Scheme  string
+	Meaning string
+}
+
+// Header holds the attributes of a DICOM file that identify the study, the patient and the imaged region
+type Header struct {
+	TransferSyntax    string
+	SOPClassUID       string
+	SOPInstanceUID    string
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	// StudyDate is in the DICOM DA format, YYYYMMDD
+	StudyDate         string
+	Modality          string
+	StudyDescription  string
+	SeriesDescription string
+	PatientID         string
+	// PatientName has the DICOM name components separated by spaces
+	PatientName        string
+	PatientBirthDate   string
+	BodyPartExamined   string
+	AnatomicRegions    []Code
+	AnatomicStructures []Code
+	Image              Image
+}
+
+// Image describes the pixel data of a DICOM file
+type Image struct {
+	Rows                      int
+	Columns                   int
+	SamplesPerPixel           int
+	PhotometricInterpretation string
+	PlanarConfiguration       int
+	BitsAllocated             int
+	BitsStored                int
+	PixelRepresentation       int
+	NumberOfFrames            int
+	WindowCenter              float64
+	WindowWidth               float64
+	RescaleSlope              float64
+	RescaleIntercept          float64
+	// Encapsulated is set when the pixel data is compressed; PixelData is then empty
+	Encapsulated bool
+	PixelData    []byte
+}
+
+// dataset is a decoded data set: the raw values of its elements and the items of its sequences
+type dataset struct {
+	values    map[uint32][]byte
+	sequences map[uint32][]dataset
+}
+
+func newDataset() dataset {
+	return dataset{values: map[uint32][]byte{}, sequences: map[uint32][]dataset{}}
+}
+
+type element struct {
+	tag       uint32
+	vr        string
+	undefined bool
+	value     []byte
+}
+
+type parser struct {
+	data         []byte
+	pos          int
+	explicit     bool
+	encapsulated bool
+	// depth is the number of sequences the parser is inside of
+	depth int
+}
+
+// Parse reads the header and pixel data of a DICOM Part 10 file
+func Parse(data []byte) (Header, error) {
+	if len(data) < 132 || string(data[128:132]) != "DICM" {
+		return Header{}, ErrNotDicom
+	}
+
+	// The file meta information is always explicit VR little endian
+	p := &parser{data: data, pos: 132, explicit: true}
+	meta := newDataset()
+	for p.pos+2 <= len(p.data) && binary.LittleEndian.Uint16(p.data[p.pos:]) == 0x0002 {
+		e, err := p.readElement()
+		if err != nil {
+			return Header{}, err
+		}
+		meta.values[e.tag] = e.value
+	}
+
+	syntax := meta.text(tagTransferSyntax)
+	switch syntax {
+	case ImplicitVRLittleEndian:
+		p.explicit = false
+	case ExplicitVRBigEndian, DeflatedExplicitVRLittleEndian:
+		return Header{}, ErrUnsupportedTransferSyntax
+	}
+
+	ds, err := p.readDataset(false)
+	if err != nil {
+		return Header{}, err
+	}
+
+	header := Header{
+		TransferSyntax:     syntax,
+		SOPClassUID:        ds.text(tagSOPClassUID),
+		SOPInstanceUID:     ds.text(tagSOPInstanceUID),
+		StudyInstanceUID:   ds.text(tagStudyInstanceUID),
+		SeriesInstanceUID:  ds.text(tagSeriesInstanceUID),
+		StudyDate:          ds.text(tagStudyDate),
+		Modality:           ds.text(tagModality),
+		StudyDescription:   ds.text(tagStudyDescription),
+		SeriesDescription:  ds.text(tagSeriesDescription),
+		PatientID:          ds.text(tagPatientID),
+		PatientName:        strings.Join(strings.Fields(strings.ReplaceAll(ds.text(tagPatientName), "^", " ")), " "),
+		PatientBirthDate:   ds.text(tagPatientBirthDate),
+		BodyPartExamined:   ds.text(tagBodyPartExamined),
+		AnatomicRegions:    ds.codes(tagAnatomicRegionSequence),
+		AnatomicStructures: ds.codes(tagAnatomicStructureSequence),
+		Image: Image{
+			Rows:                      ds.uint16(tagRows, 0),
+			Columns:                   ds.uint16(tagColumns, 0),
+			SamplesPerPixel:           ds.uint16(tagSamplesPerPixel, 1),
+			PhotometricInterpretation: ds.text(tagPhotometric),
+			PlanarConfiguration:       ds.uint16(tagPlanarConfiguration, 0),
+			BitsAllocated:             ds.uint16(tagBitsAllocated, 0),
+			BitsStored:                ds.uint16(tagBitsStored, 0),
+			PixelRepresentation:       ds.uint16(tagPixelRepresentation, 0),
+			NumberOfFrames:            int(ds.number(tagNumberOfFrames, 1)),
+			WindowCenter:              ds.number(tagWindowCenter, 0),
+			WindowWidth:               ds.number(tagWindowWidth, 0),
+			RescaleSlope:              ds.number(tagRescaleSlope, 1),
+			RescaleIntercept:          ds.number(tagRescaleIntercept, 0),
+			Encapsulated:              p.encapsulated,
+			PixelData:                 ds.values[tagPixelData],
+		},
+	}
+	if header.Image.BitsStored == 0 {
+		header.Image.BitsStored = header.Image.BitsAllocated
+	}
+	return header, nil
+}
+
+// readDataset reads elements until the end of the data, or until an item delimiter when inItem is set
+func (p *parser) readDataset(inItem bool) (dataset, error) {
+	ds := newDataset()
+	for {
+		if p.pos >= len(p.data) {
+			if inItem {
+				return dataset{}, ErrMalformed
+			}
+			return ds, nil
+		}
+
+		e, err := p.readElement()
+		if err != nil {
+			return dataset{}, err
+		}
+		switch {
+		case e.tag == tagItemDelimiter:
+			if !inItem {
+				return dataset{}, ErrMalformed
+			}
+			return ds, nil
+		case e.tag == tagPixelData && e.undefined:
+			// Compressed pixel data is a sequence of fragments, which the preview does not decode
+			p.encapsulated = true
+			if err := p.skipFragments(); err != nil {
+				return dataset{}, err
+			}
+		case e.undefined:
+			// Undefined length elements other than pixel data are sequences; UN ones are encoded as implicit VR
+			explicit := p.explicit
+			if e.vr == "UN" {
+				p.explicit = false
+			}
+			items, err := p.readItems(true)
+			p.explicit = explicit
+			if err != nil {
+				return dataset{}, err
+			}
+			ds.sequences[e.tag] = items
+		case e.vr == "SQ" || (!p.explicit && isSequence(e.tag)):
+			sub := &parser{data: e.value, explicit: p.explicit, depth: p.depth}
+			items, err := sub.readItems(false)
+			if err != nil {
+				return dataset{}, err
+			}
+			ds.sequences[e.tag] = items
+		default:
+			ds.values[e.tag] = e.value
+		}
+	}
+}
+
+// readItems reads the items of a sequence until the end of the data, or until a sequence delimiter when delimited is set
+func (p *parser) readItems(delimited bool) ([]dataset, error) {
+	if p.depth >= MaxSequenceDepth {
+		return nil, ErrMalformed
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	var items []dataset
+	for {
+		if p.pos >= len(p.data) {
+			if delimited {
+				return nil, ErrMalformed
+			}
+			return items, nil
+		}
+
+		e, err := p.readElement()
+		if err != nil {
+			return nil, err
+		}
+		if e.tag == tagSequenceDelimiter {
+			return items, nil
+		}
+		if e.tag != tagItem {
+			return nil, ErrMalformed
+		}
+
+		var item dataset
+		if e.undefined {
+			item, err = p.readDataset(true)
+		} else {
+			item, err = (&parser{data: e.value, explicit: p.explicit, depth: p.depth}).readDataset(false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// skipFragments skips the items of encapsulated pixel data up to its sequence delimiter
+func (p *parser) skipFragments() error {
+	for {
+		e, err := p.readElement()
+		if err != nil {
+			return err
+		}
+		switch {
+		case e.tag == tagSequenceDelimiter:
+			return nil
+		case e.tag != tagItem || e.undefined:
+			return ErrMalformed
+		}
+	}
+}
+
+// readElement reads the next element header and, for elements of defined length, its value
+func (p *parser) readElement() (element, error) {
+	if p.pos+8 > len(p.data) {
+		return element{}, ErrMalformed
+	}
+	group := binary.LittleEndian.Uint16(p.data[p.pos:])
+	number := binary.LittleEndian.Uint16(p.data[p.pos+2:])
+	e := element{tag: uint32(group)<<16 | uint32(number)}
+
+	var length uint32
+	switch {
+	case group == 0xFFFE:
+		// Items and delimiters never have a VR
+		length = binary.LittleEndian.Uint32(p.data[p.pos+4:])
+		p.pos += 8
+	case p.explicit:
+		e.vr = string(p.data[p.pos+4 : p.pos+6])
+		if hasLongLength(e.vr) {
+			if p.pos+12 > len(p.data) {
+				return element{}, ErrMalformed
+			}
+			length = binary.LittleEndian.Uint32(p.data[p.pos+8:])
+			p.pos += 12
+		} else {
+			length = uint32(binary.LittleEndian.Uint16(p.data[p.pos+6:]))
+			p.pos += 8
+		}
+	default:
+		length = binary.LittleEndian.Uint32(p.data[p.pos+4:])
+		p.pos += 8
+	}
+
+	if length == undefinedLength {
+		e.undefined = true
+		return e, nil
+	}
+	if uint64(p.pos)+uint64(length) > uint64(len(p.data)) {
+		return element{}, ErrMalformed
+	}
+	e.value = p.data[p.pos : p.pos+int(length)]
+	p.pos += int(length)
+	return e, nil
+}
+
+// hasLongLength reports whether an explicit VR is followed by two reserved bytes and a 32-bit length
+func hasLongLength(vr string) bool {
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UN", "UR", "UT", "UV":
+		return true
+	}
+	return false
+}
+
+// isSequence reports whether a tag read without explicit VR is one of the sequences the parser looks into
+func isSequence(tag uint32) bool {
+	return tag == tagAnatomicRegionSequence || tag == tagAnatomicStructureSequence
+}
+
+// text returns the first value of a string element without its padding
+func (ds dataset) text(tag uint32) string {
+	value := string(bytes.TrimRight(ds.values[tag], "\x00 "))
+	value, _, _ = strings.Cut(value, `\`)
+	return strings.TrimSpace(value)
+}
+
+// uint16 returns the value of a US element, or def when it is missing
+func (ds dataset) uint16(tag uint32, def int) int {
+	value := ds.values[tag]
+	if len(value) < 2 {
+		return def
+	}
+	return int(binary.LittleEndian.Uint16(value))
+}
+
+// number returns the first value of an IS or DS element, or def when it is missing or invalid
+func (ds dataset) number(tag uint32, def float64) float64 {
+	n, err := strconv.ParseFloat(ds.text(tag), 64)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// codes returns the code items of a coded sequence
+func (ds dataset) codes(tag uint32) []Code {
+	var codes []Code
+	for _, item := range ds.sequences[tag] {
+		code := Code{
+			Value:   item.text(tagCodeValue),
+			Scheme:  item.text(tagCodingScheme),
+			Meaning: item.text(tagCodeMeaning),
+		}
+		if code.Value != "" || code.Meaning != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,91 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// header is the preamble and file meta information of an explicit VR little endian file
+func header() []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 128))
+	buf.WriteString("DICM")
+	_ = binary.Write(&buf, binary.LittleEndian, [2]uint16{0x0002, 0x0010})
+	buf.WriteString("UI")
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(20))
+	buf.WriteString(ExplicitVRLittleEndian + "\x00")
+	return buf.Bytes()
+}
+
+// writeTag writes an item or delimiter tag, or a sequence tag with its VR and reserved bytes, and a length
+func writeTag(buf *bytes.Buffer, group, number uint16, length uint32) {
+	_ = binary.Write(buf, binary.LittleEndian, [2]uint16{group, number})
+	if group != 0xFFFE {
+		buf.WriteString("SQ\x00\x00")
+	}
+	_ = binary.Write(buf, binary.LittleEndian, length)
+}
+
+// nestedUndefined nests depth sequences of undefined length, each with a single item of undefined length
+func nestedUndefined(depth int) []byte {
+	buf := bytes.NewBuffer(header())
+	for i := 0; i < depth; i++ {
+		writeTag(buf, 0x0040, 0xA730, undefinedLength)
+		writeTag(buf, 0xFFFE, 0xE000, undefinedLength)
+	}
+	for i := 0; i < depth; i++ {
+		writeTag(buf, 0xFFFE, 0xE00D, 0)
+		writeTag(buf, 0xFFFE, 0xE0DD, 0)
+	}
+	return buf.Bytes()
+}
+
+// nestedDefined nests depth sequences of defined length, each with a single item of defined length
+func nestedDefined(depth int) []byte {
+	var inner []byte
+	for i := 0; i < depth; i++ {
+		var item bytes.Buffer
+		writeTag(&item, 0xFFFE, 0xE000, uint32(len(inner)))
+		item.Write(inner)
+		var sequence bytes.Buffer
+		writeTag(&sequence, 0x0040, 0xA730, uint32(item.Len()))
+		sequence.Write(item.Bytes())
+		inner = sequence.Bytes()
+	}
+	return append(header(), inner...)
+}
+
+func TestParseNestedSequences(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"Undefined length at the limit", nestedUndefined(MaxSequenceDepth), nil},
+		{"Undefined length past the limit", nestedUndefined(MaxSequenceDepth + 1), ErrMalformed},
+		{"Defined length at the limit", nestedDefined(MaxSequenceDepth), nil},
+		{"Defined length past the limit", nestedDefined(MaxSequenceDepth + 1), ErrMalformed},
+		// Deep enough to exhaust the stack without the limit
+		{"Hostile nesting", nestedUndefined(1 << 20), ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(header())
+	f.Add(nestedUndefined(3))
+	f.Add(nestedDefined(3))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Any input must either parse or fail with an error, never panic or recurse without bound
+		_, _ = Parse(data)
+	})
+}
@@ -0,0 +1,134 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// ErrNoPreview is returned when the pixel data is compressed or in a layout the preview does not render
+var ErrNoPreview = errors.New("DICOM image can not be previewed")
+
+// Preview renders the first frame of uncompressed pixel data as a PNG whose longest side is at most maxSize pixels.
+// Grayscale images use the file's window, or the range of their values when there is none; RGB images are copied.
+func Preview(img Image, maxSize int) ([]byte, error) {
+	if img.Encapsulated || img.Rows <= 0 || img.Columns <= 0 || maxSize <= 0 {
+		return nil, ErrNoPreview
+	}
+
+	width, height := img.Columns, img.Rows
+	if longest := max(width, height); longest > maxSize {
+		width = max(1, width*maxSize/longest)
+		height = max(1, height*maxSize/longest)
+	}
+
+	var out image.Image
+	switch {
+	case img.SamplesPerPixel == 1 && (img.PhotometricInterpretation == "MONOCHROME1" || img.PhotometricInterpretation == "MONOCHROME2"):
+		gray, err := grayscale(img, width, height)
+		if err != nil {
+			return nil, err
+		}
+		out = gray
+	case img.SamplesPerPixel == 3 && img.PhotometricInterpretation == "RGB" && img.BitsAllocated == 8:
+		rgb, err := colour(img, width, height)
+		if err != nil {
+			return nil, err
+		}
+		out = rgb
+	default:
+		return nil, ErrNoPreview
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// grayscale maps the modality values of a monochrome frame to 8-bit grey levels, sampling it down to width x height
+func grayscale(img Image, width, height int) (*image.Gray, error) {
+	bytesPerSample := img.BitsAllocated / 8
+	if (img.BitsAllocated != 8 && img.BitsAllocated != 16) || img.BitsStored <= 0 || img.BitsStored > img.BitsAllocated {
+		return nil, ErrNoPreview
+	}
+	count := img.Rows * img.Columns
+	if len(img.PixelData) < count*bytesPerSample {
+		return nil, ErrNoPreview
+	}
+
+	slope := img.RescaleSlope
+	if slope == 0 {
+		slope = 1
+	}
+	mask := uint32(1)<<img.BitsStored - 1
+	signBit := uint32(1) << (img.BitsStored - 1)
+	values := make([]float64, count)
+	for i := range values {
+		var raw uint32
+		if bytesPerSample == 2 {
+			raw = uint32(binary.LittleEndian.Uint16(img.PixelData[2*i:]))
+		} else {
+			raw = uint32(img.PixelData[i])
+		}
+		raw &= mask
+		stored := float64(raw)
+		if img.PixelRepresentation == 1 && raw&signBit != 0 {
+			stored -= float64(mask) + 1
+		}
+		values[i] = stored*slope + img.RescaleIntercept
+	}
+
+	lower, upper := img.WindowCenter-img.WindowWidth/2, img.WindowCenter+img.WindowWidth/2
+	if img.WindowWidth <= 0 {
+		lower, upper = math.Inf(1), math.Inf(-1)
+		for _, v := range values {
+			lower, upper = math.Min(lower, v), math.Max(upper, v)
+		}
+	}
+	span := upper - lower
+	if span <= 0 {
+		span = 1
+	}
+
+	out := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := values[(y*img.Rows/height)*img.Columns+x*img.Columns/width]
+			level := math.Round(255 * math.Min(math.Max((v-lower)/span, 0), 1))
+			if img.PhotometricInterpretation == "MONOCHROME1" {
+				level = 255 - level
+			}
+			out.SetGray(x, y, color.Gray{Y: uint8(level)})
+		}
+	}
+	return out, nil
+}
+
+// colour copies an 8-bit RGB frame, interleaved or planar, sampling it down to width x height
+func colour(img Image, width, height int) (*image.RGBA, error) {
+	count := img.Rows * img.Columns
+	if len(img.PixelData) < 3*count {
+		return nil, ErrNoPreview
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := (y*img.Rows/height)*img.Columns + x*img.Columns/width
+			var r, g, b uint8
+			if img.PlanarConfiguration == 1 {
+				r, g, b = img.PixelData[i], img.PixelData[count+i], img.PixelData[2*count+i]
+			} else {
+				r, g, b = img.PixelData[3*i], img.PixelData[3*i+1], img.PixelData[3*i+2]
+			}
+			out.SetRGBA(x, y, color.RGBA{R: r, G: g, B: b, A: 255})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,56 @@
+package einvoice
+
+import (
+	"context"
+	"dental-clinic-system/models/einvoice"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultOutboxDir is used when no outbox directory is configured
+const DefaultOutboxDir = "einvoice-outbox"
+
+// LocalSubmitter stands in for a GİB integrator: it writes documents to an outbox directory
+// and accepts them. It is meant for development until a real integrator client is configured.
+type LocalSubmitter struct {
+	dir string
+}
+
+// NewLocalSubmitter creates a LocalSubmitter writing into dir
+func NewLocalSubmitter(dir string) *LocalSubmitter {
+	if dir == "" {
+		dir = DefaultOutboxDir
+	}
+	return &LocalSubmitter{dir: dir}
+}
+
+// Submit stores the document as <id>.xml and accepts it with a local reference
+func (s *LocalSubmitter) Submit(ctx context.Context, doc einvoice.Document) (einvoice.SubmissionResult, error) {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return einvoice.SubmissionResult{}, err
+	}
+
+	path := filepath.Join(s.dir, doc.FileName())
+	if err := os.WriteFile(path, doc.XML, 0o640); err != nil {
+		log.Error().
+			Str("operation", "Submit").
+			Err(err).
+			Str("document_id", doc.ID).
+			Msg("Failed to write e-invoice to the local outbox")
+		return einvoice.SubmissionResult{}, err
+	}
+
+	log.Info().
+		Str("operation", "Submit").
+		Str("document_id", doc.ID).
+		Str("path", path).
+		Msg("E-invoice written to the local outbox")
+
+	return einvoice.SubmissionResult{
+		Status:    einvoice.SubmissionAccepted,
+		Reference: "LOCAL-" + doc.UUID,
+		Message:   "Stored in the local outbox",
+	}, nil
+}
@@ -12,14 +12,26 @@ import (
 )
 
 type EmailMessage struct {
-	Type string            `json:"type"`
-	To   string            `json:"to"`
-	Data map[string]string `json:"data"`
+	Type        string            `json:"type"`
+	To          string            `json:"to"`
+	Data        map[string]string `json:"data"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+}
+
+// Attachment is a file sent along with an email; Content is base64 encoded in the message
+type Attachment struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
 }
 
 type EmailProducer interface {
 	SendVerificationEmail(email, token string) error
 	SendPasswordResetEmail(email, token string) error
+	SendAppointmentReminder(email string, data map[string]string) error
+	SendWaitlistOffer(email string, data map[string]string) error
+	SendInstallmentReminder(email string, data map[string]string) error
+	SendDocument(email string, data map[string]string, fileName, contentType string, content []byte) error
 	Close() error
 }
 
@@ -68,6 +80,49 @@ func (p *kafkaEmailProducer) SendPasswordResetEmail(email, token string) error {
 	return p.sendMessage(p.config.PasswordResetTopic, message)
 }
 
+func (p *kafkaEmailProducer) SendAppointmentReminder(email string, data map[string]string) error {
+	message := EmailMessage{
+		Type: "appointment-reminder",
+		To:   email,
+		Data: data,
+	}
+
+	return p.sendMessage(p.config.GeneralTopic, message)
+}
+
+func (p *kafkaEmailProducer) SendWaitlistOffer(email string, data map[string]string) error {
+	message := EmailMessage{
+		Type: "waitlist-offer",
+		To:   email,
+		Data: data,
+	}
+
+	return p.sendMessage(p.config.GeneralTopic, message)
+}
+
+func (p *kafkaEmailProducer) SendInstallmentReminder(email string, data map[string]string) error {
+	message := EmailMessage{
+		Type: "installment-reminder",
+		To:   email,
+		Data: data,
+	}
+
+	return p.sendMessage(p.config.GeneralTopic, message)
+}
+
+func (p *kafkaEmailProducer) SendDocument(email string, data map[string]string, fileName, contentType string, content []byte) error {
+	message := EmailMessage{
+		Type: "document",
+		To:   email,
+		Data: data,
+		Attachments: []Attachment{
+			{FileName: fileName, ContentType: contentType, Content: content},
+		},
+	}
+
+	return p.sendMessage(p.config.GeneralTopic, message)
+}
+
 func (p *kafkaEmailProducer) sendMessage(topic string, message EmailMessage) error {
 	messageBytes, err := json.Marshal(message)
 	if err != nil {
@@ -0,0 +1,115 @@
+package pdf
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Documents use the standard Helvetica fonts, which every PDF reader provides, so no font has to
+// be embedded. Text is encoded as Windows-1254 and the font encoding maps the six Turkish letters
+// that differ from WinAnsi to their glyph names.
+const fontEncoding = "<< /Type /Encoding /BaseEncoding /WinAnsiEncoding " +
+	"/Differences [208 /Gbreve 221 /Idotaccent 222 /Scedilla 240 /gbreve 253 /dotlessi 254 /scedilla] >>"
+
+type font int
+
+const (
+	fontRegular font = iota
+	fontBold
+)
+
+func (f font) resourceName() string {
+	if f == fontBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+func (f font) baseFont() string {
+	if f == fontBold {
+		return "Helvetica-Bold"
+	}
+	return "Helvetica"
+}
+
+// asciiWidths are the advance widths of the printable ASCII characters (32-126) in 1/1000 em,
+// taken from the Adobe font metrics of Helvetica and Helvetica-Bold
+var asciiWidths = [2][95]int{
+	{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// widths holds the advance width of every Windows-1254 code for both fonts
+var widths = buildWidths()
+
+// buildWidths derives the widths of accented letters from their base letter, which is how
+// Helvetica draws them, and falls back to the width of a digit for other symbols
+func buildWidths() [2][256]int {
+	var table [2][256]int
+	for f := range table {
+		for code := 0; code < 256; code++ {
+			width := 556
+			r := charmap.Windows1254.DecodeByte(byte(code))
+			switch {
+			case r >= 32 && r <= 126:
+				width = asciiWidths[f][r-32]
+			case r == 'ı':
+				width = asciiWidths[f]['i'-32]
+			case r == '•':
+				width = 350
+			case r == '\u00a0':
+				width = asciiWidths[f][0]
+			default:
+				base, _ := utf8.DecodeRuneInString(norm.NFD.String(string(r)))
+				if base >= 32 && base <= 126 {
+					width = asciiWidths[f][base-32]
+				}
+			}
+			table[f][code] = width
+		}
+	}
+	return table
+}
+
+// encode converts text to Windows-1254, replacing characters the fonts can not show with '?'
+func encode(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		if b, ok := charmap.Windows1254.EncodeRune(r); ok {
+			encoded = append(encoded, b)
+			continue
+		}
+		switch r {
+		case '\t', '\n', '\r':
+			encoded = append(encoded, ' ')
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
+
+// textWidth measures encoded text in points
+func textWidth(encoded []byte, f font, size float64) float64 {
+	total := 0
+	for _, b := range encoded {
+		total += widths[f][b]
+	}
+	return float64(total) * size / 1000
+}
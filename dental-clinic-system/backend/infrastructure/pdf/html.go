@@ -0,0 +1,106 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// node is an element or a text node of a parsed template
+type node struct {
+	tag      string
+	attrs    map[string]string
+	text     string
+	children []*node
+}
+
+func (n *node) attr(name string) string {
+	return n.attrs[name]
+}
+
+func (n *node) hasClass(class string) bool {
+	for _, c := range strings.Fields(n.attrs["class"]) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the first element with the given tag
+func (n *node) find(tag string) *node {
+	if n.tag == tag {
+		return n
+	}
+	for _, child := range n.children {
+		if found := child.find(tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent joins the text of a node and its descendants
+func (n *node) textContent() string {
+	if n.tag == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(child.textContent())
+	}
+	return b.String()
+}
+
+// skippedElements carry no printable content
+var skippedElements = map[string]bool{"head": true, "style": true, "script": true, "meta": true, "link": true}
+
+// voidElements never have children, even if the template does not close them
+var voidElements = map[string]bool{"br": true, "hr": true, "img": true, "meta": true, "link": true}
+
+// parseHTML reads the output of an html/template into a node tree. The decoder is lenient so
+// templates can be written as ordinary HTML with unclosed void elements and named entities.
+func parseHTML(src []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(src))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	root := &node{tag: "root"}
+	stack := []*node{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &node{tag: strings.ToLower(t.Name.Local), attrs: map[string]string{}}
+			for _, a := range t.Attr {
+				element.attrs[strings.ToLower(a.Name.Local)] = a.Value
+			}
+			parent.children = append(parent.children, element)
+			if !voidElements[element.tag] {
+				stack = append(stack, element)
+			}
+		case xml.EndElement:
+			tag := strings.ToLower(t.Name.Local)
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					break
+				}
+			}
+		case xml.CharData:
+			parent.children = append(parent.children, &node{text: string(t)})
+		}
+	}
+	return root, nil
+}
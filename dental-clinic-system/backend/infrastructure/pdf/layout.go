@@ -0,0 +1,704 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout settings in points
+const (
+	contentWidth  = pageWidth - 2*marginX
+	lineSpacing   = 1.3
+	cellPaddingX  = 4.0
+	cellPaddingY  = 3.0
+	listIndent    = 12.0
+	floatGap      = 12.0
+	defaultImageH = 50.0
+	footerY       = 30.0
+	footerSize    = 8.0
+	mutedGray     = 0.45
+	ruleGray      = 0.8
+	headerGray    = 0.93
+)
+
+// Options controls how a document is rendered
+type Options struct {
+	// Title is stored in the document properties; the <title> of the template is used when empty
+	Title string
+	// Images are the pictures templates can place with <img src="name">
+	Images map[string][]byte
+	// CreatedAt is stored in the document properties
+	CreatedAt time.Time
+}
+
+// Render lays out the HTML produced by a document template on A4 pages and returns the PDF file.
+//
+// Only a small subset of HTML is understood: headings, paragraphs, divs, lists, tables, horizontal
+// rules and images, with <strong>, <small> and <br> inside text. The classes "right", "center",
+// "muted", "small", "large" and "bold" change alignment and type; tables with the class "plain" are
+// drawn without rules. The text of <footer> is repeated at the bottom of every page, where {page}
+// and {pages} are replaced by the page number and count, and footer children with the class
+// "right" are aligned to the right margin.
+func Render(html []byte, opts Options) ([]byte, error) {
+	root, err := parseHTML(html)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document template output: %w", err)
+	}
+
+	l := &layout{images: map[string]*pdfImage{}, loaded: map[string]*pdfImage{}, sources: opts.Images}
+	l.newPage()
+	body := root.find("body")
+	if body == nil {
+		body = root
+	}
+	if err := l.container(body, baseStyle(), marginX, contentWidth); err != nil {
+		return nil, err
+	}
+	if footer := root.find("footer"); footer != nil {
+		l.footers(footer)
+	}
+
+	title := opts.Title
+	if title == "" {
+		if t := root.find("title"); t != nil {
+			title = strings.TrimSpace(t.textContent())
+		}
+	}
+	return write(l.pages, l.images, title, opts.CreatedAt)
+}
+
+// style is the type and alignment of text
+type style struct {
+	font  font
+	size  float64
+	gray  float64
+	align string
+}
+
+func baseStyle() style {
+	return style{font: fontRegular, size: 10, align: "left"}
+}
+
+// withClasses applies the presentational classes of an element
+func (s style) withClasses(n *node) style {
+	for _, class := range strings.Fields(n.attr("class")) {
+		switch class {
+		case "right", "center", "left":
+			s.align = class
+		case "muted":
+			s.gray = mutedGray
+		case "small":
+			s.size = 8
+		case "large":
+			s.size = 12
+		case "bold":
+			s.font = fontBold
+		}
+	}
+	if align := n.attr("align"); align == "right" || align == "center" || align == "left" {
+		s.align = align
+	}
+	return s
+}
+
+// word is an unbreakable piece of text, or a forced line break
+type word struct {
+	text        []byte
+	style       style
+	spaceBefore bool
+	newline     bool
+}
+
+func (w word) width() float64 {
+	return textWidth(w.text, w.style.font, w.style.size)
+}
+
+func (w word) spaceWidth() float64 {
+	return textWidth([]byte{' '}, w.style.font, w.style.size)
+}
+
+type line struct {
+	words  []word
+	width  float64
+	height float64
+}
+
+type layout struct {
+	pages   []*page
+	page    *page
+	y       float64
+	sources map[string][]byte
+	// loaded holds the images by their source name, images by their resource name
+	loaded map[string]*pdfImage
+	images map[string]*pdfImage
+	// A right aligned image narrows the text beside it until the cursor passes floatBottom
+	floatBottom float64
+	floatLeft   float64
+}
+
+func (l *layout) newPage() {
+	l.page = &page{}
+	l.pages = append(l.pages, l.page)
+	l.y = marginTop
+	l.floatBottom = 0
+}
+
+// ensure starts a new page when a block of the given height does not fit on the current one
+func (l *layout) ensure(height float64) bool {
+	if l.y+height > pageHeight-marginBottom && l.y > marginTop {
+		l.newPage()
+		return true
+	}
+	return false
+}
+
+func (l *layout) clearFloat() {
+	if l.y < l.floatBottom {
+		l.y = l.floatBottom
+	}
+	l.floatBottom = 0
+}
+
+// available is the width left for text starting at x next to a floating image
+func (l *layout) available(x, width float64) float64 {
+	if l.y < l.floatBottom {
+		return math.Min(width, l.floatLeft-x)
+	}
+	return width
+}
+
+// container lays out the children of a block element. Runs of inline children form anonymous paragraphs.
+func (l *layout) container(n *node, st style, x, width float64) error {
+	var inline []*node
+	flush := func() {
+		if len(inline) > 0 {
+			l.paragraph(&node{tag: "p", children: inline}, st, x, width, 0)
+			inline = nil
+		}
+	}
+
+	for _, child := range n.children {
+		if child.tag == "" || isInline(child.tag) {
+			inline = append(inline, child)
+			continue
+		}
+		flush()
+		if err := l.block(child, st, x, width); err != nil {
+			return err
+		}
+	}
+	flush()
+	return nil
+}
+
+func isInline(tag string) bool {
+	switch tag {
+	case "span", "strong", "b", "em", "i", "small", "br", "a", "code":
+		return true
+	}
+	return false
+}
+
+func (l *layout) block(n *node, parent style, x, width float64) error {
+	if skippedElements[n.tag] || n.tag == "title" || n.tag == "footer" {
+		return nil
+	}
+	st := parent.withClasses(n)
+
+	switch n.tag {
+	case "h1":
+		st.font, st.size = fontBold, 18
+		l.paragraph(n, st.withClasses(n), x, width, 8)
+	case "h2":
+		st.font, st.size = fontBold, 13
+		l.y += 6
+		l.paragraph(n, st.withClasses(n), x, width, 6)
+	case "h3":
+		st.font, st.size = fontBold, 11
+		l.y += 4
+		l.paragraph(n, st.withClasses(n), x, width, 4)
+	case "p":
+		l.paragraph(n, st, x, width, 6)
+	case "hr":
+		l.clearFloat()
+		l.y += 4
+		l.rule(x, x+width, l.y, ruleGray, 0.75)
+		l.y += 8
+	case "img":
+		return l.image(n, x, width)
+	case "ul", "ol":
+		l.list(n, st, x, width)
+		l.y += 4
+	case "table":
+		l.clearFloat()
+		l.table(n, st, x, width)
+		l.y += 8
+	default:
+		return l.container(n, st, x, width)
+	}
+	return nil
+}
+
+// words splits the inline content of an element into words, collapsing white space like HTML does
+func words(n *node, st style) []word {
+	var result []word
+	pendingSpace := false
+	var walk func(n *node, st style)
+	walk = func(n *node, st style) {
+		if n.tag == "" {
+			text := n.text
+			for i := 0; i < len(text); {
+				if isSpace(text[i]) {
+					pendingSpace = true
+					i++
+					continue
+				}
+				j := i
+				for j < len(text) && !isSpace(text[j]) {
+					j++
+				}
+				result = append(result, word{text: encode(text[i:j]), style: st, spaceBefore: pendingSpace})
+				pendingSpace = false
+				i = j
+			}
+			return
+		}
+		switch n.tag {
+		case "br":
+			result = append(result, word{newline: true})
+			pendingSpace = false
+			return
+		case "strong", "b":
+			st.font = fontBold
+		case "small":
+			st.size = math.Max(7, st.size*0.85)
+		}
+		st = st.withClasses(n)
+		for _, child := range n.children {
+			walk(child, st)
+		}
+	}
+	for _, child := range n.children {
+		walk(child, st)
+	}
+	return result
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// breakLines fills lines of the given width greedily; words wider than a line are split
+func breakLines(ws []word, width float64, minHeight float64) []line {
+	var lines []line
+	current := line{height: minHeight}
+	push := func() {
+		lines = append(lines, current)
+		current = line{height: minHeight}
+	}
+
+	for _, w := range ws {
+		if w.newline {
+			push()
+			continue
+		}
+		for _, piece := range splitWord(w, width) {
+			space := 0.0
+			if piece.spaceBefore && len(current.words) > 0 {
+				space = piece.spaceWidth()
+			}
+			if len(current.words) > 0 && current.width+space+piece.width() > width {
+				push()
+				space = 0
+			}
+			if len(current.words) == 0 {
+				piece.spaceBefore = false
+			}
+			current.words = append(current.words, piece)
+			current.width += space + piece.width()
+			current.height = math.Max(current.height, piece.style.size*lineSpacing)
+		}
+	}
+	if len(current.words) > 0 {
+		push()
+	}
+	return lines
+}
+
+// splitWord breaks a word that is wider than a whole line into pieces that fit
+func splitWord(w word, width float64) []word {
+	if w.width() <= width || len(w.text) <= 1 {
+		return []word{w}
+	}
+	var pieces []word
+	start := 0
+	for i := 1; i <= len(w.text); i++ {
+		if i == len(w.text) || textWidth(w.text[start:i+1], w.style.font, w.style.size) > width {
+			piece := w
+			piece.text = w.text[start:i]
+			piece.spaceBefore = w.spaceBefore && start == 0
+			pieces = append(pieces, piece)
+			start = i
+		}
+	}
+	return pieces
+}
+
+// paragraph lays out the inline content of an element followed by the given space
+func (l *layout) paragraph(n *node, st style, x, width, spaceAfter float64) {
+	ws := words(n, st)
+	if len(ws) == 0 {
+		return
+	}
+	for _, ln := range breakLines(ws, l.available(x, width), st.size*lineSpacing) {
+		l.ensure(ln.height)
+		l.drawLine(ln, x, l.available(x, width), l.y, st.align)
+		l.y += ln.height
+	}
+	l.y += spaceAfter
+}
+
+// drawLine writes a line of text with its top at y
+func (l *layout) drawLine(ln line, x, width, y float64, align string) {
+	switch align {
+	case "right":
+		x += width - ln.width
+	case "center":
+		x += (width - ln.width) / 2
+	}
+
+	cursor := x
+	for i := 0; i < len(ln.words); {
+		// Consecutive words of the same style are written as one string
+		st := ln.words[i].style
+		var text []byte
+		start := cursor
+		for ; i < len(ln.words) && ln.words[i].style == st; i++ {
+			w := ln.words[i]
+			if w.spaceBefore {
+				if len(text) > 0 {
+					text = append(text, ' ')
+				} else {
+					start += w.spaceWidth()
+				}
+				cursor += w.spaceWidth()
+			}
+			text = append(text, w.text...)
+			cursor += w.width()
+		}
+		baseline := y + (ln.height+st.size*0.7)/2
+		fmt.Fprintf(&l.page.content, "BT /%s %.2f Tf %.3f g 1 0 0 1 %.2f %.2f Tm %s Tj ET\n",
+			st.font.resourceName(), st.size, st.gray, start, pageHeight-baseline, literal(text))
+	}
+}
+
+func (l *layout) rule(x1, x2, y, gray, lineWidth float64) {
+	fmt.Fprintf(&l.page.content, "%.3f G %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		gray, lineWidth, x1, pageHeight-y, x2, pageHeight-y)
+}
+
+func (l *layout) fill(x, y, w, h, gray float64) {
+	fmt.Fprintf(&l.page.content, "%.3f g %.2f %.2f %.2f %.2f re f\n", gray, x, pageHeight-y-h, w, h)
+}
+
+// image places a picture from Options.Images. Images aligned right float beside the following text.
+func (l *layout) image(n *node, x, width float64) error {
+	name := n.attr("src")
+	data, ok := l.sources[name]
+	if !ok || len(data) == 0 {
+		return nil
+	}
+	img, ok := l.loaded[name]
+	if !ok {
+		loaded, err := loadImage(fmt.Sprintf("Im%d", len(l.images)+1), data)
+		if err != nil {
+			return err
+		}
+		img = loaded
+		l.loaded[name] = img
+		l.images[img.name] = img
+	}
+
+	h, w := defaultImageH, 0.0
+	if v, err := strconv.ParseFloat(n.attr("height"), 64); err == nil && v > 0 {
+		h = v
+	}
+	if v, err := strconv.ParseFloat(n.attr("width"), 64); err == nil && v > 0 {
+		w = v
+		if n.attr("height") == "" {
+			h = w * float64(img.height) / float64(img.width)
+		}
+	}
+	if w == 0 {
+		w = h * float64(img.width) / float64(img.height)
+	}
+	if w > width {
+		h, w = h*width/w, width
+	}
+
+	floating := n.attr("align") == "right" || n.hasClass("right")
+	if !floating {
+		l.clearFloat()
+	}
+	l.ensure(h)
+	if floating {
+		x += width - w
+		l.floatBottom = l.y + h
+		l.floatLeft = x - floatGap
+	}
+
+	fmt.Fprintf(&l.page.content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", w, h, x, pageHeight-l.y-h, img.name)
+	l.page.images = appendUnique(l.page.images, img.name)
+	if !floating {
+		l.y += h + 6
+	}
+	return nil
+}
+
+func appendUnique(names []string, name string) []string {
+	for _, existing := range names {
+		if existing == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// list lays out the items of a bulleted or numbered list
+func (l *layout) list(n *node, st style, x, width float64) {
+	number := 0
+	for _, item := range n.children {
+		if item.tag != "li" {
+			continue
+		}
+		number++
+		marker := "•"
+		if n.tag == "ol" {
+			marker = strconv.Itoa(number) + "."
+		}
+		itemStyle := st.withClasses(item)
+		top, pageBefore := l.y, l.page
+		l.paragraph(item, itemStyle, x+listIndent, width-listIndent, 3)
+		// Draw the marker next to the first line, which may have moved to a new page
+		if l.page != pageBefore {
+			top = marginTop
+		}
+		l.drawLine(line{words: []word{{text: encode(marker), style: itemStyle}}, height: itemStyle.size * lineSpacing}, x, listIndent, top, "left")
+	}
+}
+
+// tableCell is a cell of a table row with its wrapped lines
+type tableCell struct {
+	node    *node
+	colspan int
+	header  bool
+	style   style
+	lines   []line
+}
+
+type tableRow struct {
+	cells  []tableCell
+	header bool
+	total  bool
+	height float64
+}
+
+// table lays out a table; header rows are repeated at the top of every page the table continues on
+func (l *layout) table(n *node, st style, x, width float64) {
+	st.size = math.Min(st.size, 9.5)
+	plain := n.hasClass("plain")
+	rows := tableRows(n, st)
+	if len(rows) == 0 {
+		return
+	}
+
+	columns := columnWidths(rows, width)
+	var headers []tableRow
+	for i := range rows {
+		row := &rows[i]
+		col := 0
+		for j := range row.cells {
+			cell := &row.cells[j]
+			cellWidth := 0.0
+			for k := 0; k < cell.colspan && col+k < len(columns); k++ {
+				cellWidth += columns[col+k]
+			}
+			col += cell.colspan
+			cell.lines = breakLines(words(cell.node, cell.style), cellWidth-2*cellPaddingX, cell.style.size*lineSpacing)
+			height := 2 * cellPaddingY
+			for _, ln := range cell.lines {
+				height += ln.height
+			}
+			row.height = math.Max(row.height, height)
+		}
+		if row.header {
+			headers = append(headers, *row)
+		}
+	}
+
+	for _, row := range rows {
+		if l.ensure(row.height) && !row.header {
+			for _, header := range headers {
+				l.tableRow(header, columns, x, plain)
+			}
+		}
+		l.tableRow(row, columns, x, plain)
+	}
+}
+
+func (l *layout) tableRow(row tableRow, columns []float64, x float64, plain bool) {
+	width := 0.0
+	for _, w := range columns {
+		width += w
+	}
+	if row.header && !plain {
+		l.fill(x, l.y, width, row.height, headerGray)
+	}
+	if row.total && !plain {
+		l.rule(x, x+width, l.y, 0.3, 0.75)
+	}
+
+	col := 0
+	cellX := x
+	for _, cell := range row.cells {
+		cellWidth := 0.0
+		for k := 0; k < cell.colspan && col+k < len(columns); k++ {
+			cellWidth += columns[col+k]
+		}
+		col += cell.colspan
+		y := l.y + cellPaddingY
+		for _, ln := range cell.lines {
+			l.drawLine(ln, cellX+cellPaddingX, cellWidth-2*cellPaddingX, y, cell.style.align)
+			y += ln.height
+		}
+		cellX += cellWidth
+	}
+
+	l.y += row.height
+	if !plain {
+		l.rule(x, x+width, l.y, ruleGray, 0.5)
+	}
+}
+
+// tableRows collects the rows of a table from its thead, tbody and tfoot sections
+func tableRows(n *node, st style) []tableRow {
+	var rows []tableRow
+	var collect func(n *node, header bool, st style)
+	collect = func(n *node, header bool, st style) {
+		for _, child := range n.children {
+			switch child.tag {
+			case "thead":
+				collect(child, true, st.withClasses(child))
+			case "tbody", "tfoot":
+				collect(child, false, st.withClasses(child))
+			case "tr":
+				rowStyle := st.withClasses(child)
+				row := tableRow{header: header, total: child.hasClass("total")}
+				if row.total {
+					rowStyle.font = fontBold
+				}
+				allHeaders := true
+				for _, c := range child.children {
+					if c.tag != "td" && c.tag != "th" {
+						continue
+					}
+					cellStyle := rowStyle
+					if c.tag == "th" {
+						cellStyle.font = fontBold
+					} else {
+						allHeaders = false
+					}
+					colspan, err := strconv.Atoi(c.attr("colspan"))
+					if err != nil || colspan < 1 {
+						colspan = 1
+					}
+					row.cells = append(row.cells, tableCell{node: c, colspan: colspan, header: c.tag == "th", style: cellStyle.withClasses(c)})
+				}
+				if len(row.cells) > 0 {
+					row.header = row.header || allHeaders
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	collect(n, false, st)
+	return rows
+}
+
+// columnWidths reads percentage widths from the first row that has them and shares the rest equally
+func columnWidths(rows []tableRow, width float64) []float64 {
+	count := 0
+	for _, row := range rows {
+		span := 0
+		for _, cell := range row.cells {
+			span += cell.colspan
+		}
+		count = max(count, span)
+	}
+
+	columns := make([]float64, count)
+	for _, row := range rows {
+		assigned := false
+		col := 0
+		for _, cell := range row.cells {
+			if cell.colspan == 1 {
+				if pct, err := strconv.ParseFloat(strings.TrimSuffix(cell.node.attr("width"), "%"), 64); err == nil && pct > 0 {
+					columns[col] = width * pct / 100
+					assigned = true
+				}
+			}
+			col += cell.colspan
+		}
+		if assigned {
+			break
+		}
+	}
+
+	used, free := 0.0, 0
+	for _, w := range columns {
+		if w == 0 {
+			free++
+		}
+		used += w
+	}
+	for i, w := range columns {
+		if w == 0 {
+			columns[i] = math.Max(0, width-used) / float64(free)
+		}
+	}
+	return columns
+}
+
+// footers writes the footer text at the bottom of every page
+func (l *layout) footers(footer *node) {
+	var left, right strings.Builder
+	for _, child := range footer.children {
+		if child.tag != "" && child.hasClass("right") {
+			right.WriteString(child.textContent())
+		} else {
+			left.WriteString(child.textContent())
+		}
+	}
+
+	st := style{font: fontRegular, size: footerSize, gray: mutedGray}
+	for i, p := range l.pages {
+		l.page = p
+		replacer := strings.NewReplacer("{page}", strconv.Itoa(i+1), "{pages}", strconv.Itoa(len(l.pages)))
+		for _, part := range []struct {
+			text  string
+			align string
+		}{{left.String(), "left"}, {right.String(), "right"}} {
+			text := strings.Join(strings.Fields(replacer.Replace(part.text)), " ")
+			if text == "" {
+				continue
+			}
+			ln := line{words: []word{{text: encode(text), style: st}}, height: footerSize * lineSpacing}
+			ln.width = ln.words[0].width()
+			l.drawLine(ln, marginX, contentWidth, pageHeight-footerY, part.align)
+		}
+		l.rule(marginX, pageWidth-marginX, pageHeight-footerY-4, ruleGray, 0.5)
+	}
+}
@@ -0,0 +1,209 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"time"
+	"unicode/utf16"
+)
+
+// A4 page size and margins in points
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	marginX      = 50.0
+	marginTop    = 50.0
+	marginBottom = 60.0
+)
+
+// ErrUnsupportedImage is returned for pictures that are not PNG, JPEG or GIF
+var ErrUnsupportedImage = errors.New("image must be a PNG, JPEG or GIF file")
+
+// page is the content stream of one page and the images it draws
+type page struct {
+	content bytes.Buffer
+	images  []string
+}
+
+// pdfImage is a decoded picture ready to be written as an image XObject
+type pdfImage struct {
+	name       string
+	width      int
+	height     int
+	colorSpace string
+	filter     string
+	data       []byte
+}
+
+// loadImage turns PNG, JPEG and GIF data into an image XObject. JPEG files are embedded as they are;
+// other formats are decoded, flattened onto white and stored as compressed RGB.
+func loadImage(name string, data []byte) (*pdfImage, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if format == "jpeg" {
+		switch cfg.ColorModel {
+		case color.YCbCrModel:
+			return &pdfImage{name: name, width: cfg.Width, height: cfg.Height, colorSpace: "/DeviceRGB", filter: "/DCTDecode", data: data}, nil
+		case color.GrayModel:
+			return &pdfImage{name: name, width: cfg.Width, height: cfg.Height, colorSpace: "/DeviceGray", filter: "/DCTDecode", data: data}, nil
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			offset := flat.PixOffset(x, y)
+			rgb = append(rgb, flat.Pix[offset], flat.Pix[offset+1], flat.Pix[offset+2])
+		}
+	}
+	compressed, err := deflate(rgb)
+	if err != nil {
+		return nil, err
+	}
+	return &pdfImage{name: name, width: bounds.Dx(), height: bounds.Dy(), colorSpace: "/DeviceRGB", filter: "/FlateDecode", data: compressed}, nil
+}
+
+// writer assembles numbered objects and the cross-reference table of a PDF file
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// reserve allocates the next object number so objects can refer to each other before they are written
+func (w *writer) reserve() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *writer) object(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (w *writer) stream(id int, dict string, data []byte) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// write serialises the pages into a complete PDF file
+func write(pages []*page, images map[string]*pdfImage, title string, createdAt time.Time) ([]byte, error) {
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	catalogID := w.reserve()
+	pagesID := w.reserve()
+	infoID := w.reserve()
+	fontIDs := map[font]int{}
+	for _, f := range []font{fontRegular, fontBold} {
+		id := w.reserve()
+		fontIDs[f] = id
+		w.object(id, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding %s >>", f.baseFont(), fontEncoding))
+	}
+
+	imageIDs := map[string]int{}
+	for _, p := range pages {
+		for _, name := range p.images {
+			if _, ok := imageIDs[name]; ok {
+				continue
+			}
+			img := images[name]
+			id := w.reserve()
+			imageIDs[name] = id
+			w.stream(id, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter %s",
+				img.width, img.height, img.colorSpace, img.filter), img.data)
+		}
+	}
+
+	var kids bytes.Buffer
+	for _, p := range pages {
+		contentID := w.reserve()
+		compressed, err := deflate(p.content.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		w.stream(contentID, "/Filter /FlateDecode", compressed)
+
+		var xobjects bytes.Buffer
+		for _, name := range p.images {
+			fmt.Fprintf(&xobjects, "/%s %d 0 R ", name, imageIDs[name])
+		}
+		pageID := w.reserve()
+		w.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R "+
+			"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << %s>> >> >>",
+			pagesID, pageWidth, pageHeight, contentID, fontIDs[fontRegular], fontIDs[fontBold], xobjects.String()))
+		fmt.Fprintf(&kids, "%d 0 R ", pageID)
+	}
+
+	w.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(pages)))
+	w.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	w.object(infoID, fmt.Sprintf("<< /Title %s /Producer (I-Dentist) /CreationDate (D:%s) >>",
+		textString(title), createdAt.UTC().Format("20060102150405Z")))
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, catalogID, infoID, xref)
+
+	return w.buf.Bytes(), nil
+}
+
+// literal writes encoded text as a PDF string literal
+func literal(encoded []byte) string {
+	var b bytes.Buffer
+	b.WriteByte('(')
+	for _, c := range encoded {
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// textString writes document metadata as UTF-16, so titles keep their Turkish letters
+func textString(text string) string {
+	var b bytes.Buffer
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
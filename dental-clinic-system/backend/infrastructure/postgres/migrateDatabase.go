@@ -2,34 +2,345 @@ package postgres
 
 import (
 	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/calendar"
 	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/clinicalnote"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/einvoice"
+	"dental-clinic-system/models/insurance"
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/odontogram"
 	"dental-clinic-system/models/patient"
 	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/radiograph"
+	"dental-clinic-system/models/resource"
+	"dental-clinic-system/models/schedule"
 	"dental-clinic-system/models/token"
+	"dental-clinic-system/models/treatment"
 	"dental-clinic-system/models/user"
+	"dental-clinic-system/models/waitlist"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
 func MigrateDatabase(db *gorm.DB) {
+	dropGlobalNationalIDIndex(db)
+	migrateAppointmentPatients(db)
+
 	err := db.AutoMigrate(
 		&appointment.Appointment{},
+		&appointment.StatusHistory{},
+		&appointment.Series{},
+		&appointment.SentReminder{},
+		&attachment.Attachment{},
+		&billing.Invoice{},
+		&billing.Line{},
+		&billing.Payment{},
+		&billing.AuditEntry{},
+		&billing.InstallmentPlan{},
+		&billing.Installment{},
+		&calendar.FeedToken{},
 		&clinic.Clinic{},
+		&clinicalnote.Template{},
+		&clinicalnote.TemplateSection{},
+		&clinicalnote.Note{},
+		&clinicalnote.Section{},
+		&clinicalnote.Addendum{},
+		&einvoice.Submission{},
+		&insurance.Provider{},
+		&insurance.Policy{},
+		&insurance.Claim{},
+		&medical.Allergy{},
+		&medical.Medication{},
+		&medical.Condition{},
+		&medical.Questionnaire{},
+		&medical.QuestionnaireAnswer{},
 		&patient.Patient{},
+		&patient.Merge{},
+		&patient.MergeMove{},
+		&odontogram.Finding{},
 		&procedure.Procedure{},
+		&radiograph.Image{},
+		&consent.Template{},
+		&consent.TemplateProcedure{},
+		&consent.TemplateVersion{},
+		&consent.Form{},
+		&procedure.PriceList{},
+		&procedure.PriceListEntry{},
+		&resource.Resource{},
+		&treatment.Plan{},
+		&treatment.Phase{},
+		&treatment.Item{},
 		&user.Role{},
 		&user.User{},
 		&token.ExpiredTokens{},
 		&token.PasswordResetToken{},
+		&schedule.OpeningHours{},
+		&schedule.DoctorSchedule{},
+		&schedule.ScheduleBreak{},
+		&schedule.ScheduleException{},
+		&schedule.PublicHoliday{},
+		&waitlist.Entry{},
+		&waitlist.Window{},
+		&waitlist.Offer{},
 	)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Error migrating models")
 		panic(err)
 	}
 
+	backfillAppointmentEndTimes(db)
+	migrateLegacyMedicalHistory(db)
+	createPatientSearchIndexes(db)
+	backfillPatientSearchFields(db)
+
 	// Migration'dan sonra rolleri seed et
 	seedRoles(db)
+	seedPublicHolidays(db)
+}
+
+// userPatientTables hold a patient_id that pointed at users before appointments were booked for patient
+// records, with the foreign key each of them had to users. appointment_series never had one.
+var userPatientTables = []struct {
+	Table      string
+	Constraint string
+}{
+	{"appointments", "fk_appointments_patient"},
+	{"appointment_series", ""},
+	{"waitlist_entries", "fk_waitlist_entries_patient"},
+}
+
+// dropGlobalNationalIDIndex drops the index that made a national ID unique across all clinics.
+// Every clinic keeps its own patient records, so AutoMigrate replaces it with one per clinic that
+// also leaves deleted records out.
+func dropGlobalNationalIDIndex(db *gorm.DB) {
+	if err := db.Exec("DROP INDEX IF EXISTS idx_patients_national_id").Error; err != nil {
+		log.Fatal().Err(err).Msg("Failed to drop the national ID index of patients")
+		panic(err)
+	}
+}
+
+// migrateAppointmentPatients moves appointments, series and waitlist entries from the patient's user
+// account to the patient record of the same clinic with the same national ID, creating the record
+// from the user if that clinic has none, and drops the foreign keys to users so AutoMigrate creates
+// them against patients. A user booked in several clinics gets a record in each of them.
+// It only runs while appointments still reference users.
+func migrateAppointmentPatients(db *gorm.DB) {
+	if foreignKeyTarget(db, "appointments", "fk_appointments_patient") != "users" {
+		return
+	}
+
+	var count int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			UserID   uint
+			ClinicID uint
+		}
+		var selects []string
+		for _, t := range userPatientTables {
+			if tx.Migrator().HasTable(t.Table) {
+				selects = append(selects, "SELECT patient_id AS user_id, clinic_id FROM "+t.Table+" WHERE patient_id <> 0")
+			}
+		}
+		if err := tx.Raw("SELECT DISTINCT user_id, clinic_id FROM (" + strings.Join(selects, " UNION ALL ") + ") AS refs ORDER BY user_id, clinic_id").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		var values []string
+		var args []interface{}
+		for _, row := range rows {
+			var usr user.User
+			if err := tx.Unscoped().First(&usr, row.UserID).Error; err != nil {
+				return fmt.Errorf("user %d of an appointment: %w", row.UserID, err)
+			}
+
+			var pt patient.Patient
+			err := tx.Where("clinic_id = ? AND national_id = ?", row.ClinicID, usr.NationalID).First(&pt).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				pt = patient.Patient{
+					NationalID:  usr.NationalID,
+					Name:        strings.TrimSpace(usr.FirstName + " " + usr.LastName),
+					ContactInfo: usr.Email,
+					ClinicID:    row.ClinicID,
+				}
+				if pt.ContactInfo == "" {
+					pt.ContactInfo = usr.CountryCode + usr.PhoneNumber
+				}
+				pt.SetSearchFields()
+				err = tx.Create(&pt).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			values = append(values, "(?::bigint, ?::bigint, ?::bigint)")
+			args = append(args, row.UserID, row.ClinicID, pt.ID)
+		}
+
+		for _, t := range userPatientTables {
+			if !tx.Migrator().HasTable(t.Table) {
+				continue
+			}
+			if t.Constraint != "" {
+				if err := tx.Exec("ALTER TABLE " + t.Table + " DROP CONSTRAINT IF EXISTS " + t.Constraint).Error; err != nil {
+					return err
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			// One statement for all users, so a row moved to patient 7 is not moved again as user 7
+			err := tx.Exec("UPDATE "+t.Table+" AS t SET patient_id = m.patient_id FROM (VALUES "+strings.Join(values, ", ")+
+				") AS m(user_id, clinic_id, patient_id) WHERE t.patient_id = m.user_id AND t.clinic_id = m.clinic_id", args...).Error
+			if err != nil {
+				return err
+			}
+		}
+		count = len(rows)
+		return nil
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to move appointments from users to patient records")
+		panic(err)
+	}
+	log.Info().Int("count", count).Msg("Appointments moved from users to patient records")
+}
+
+// foreignKeyTarget returns the table a foreign key of a table references, or "" if there is no such key
+func foreignKeyTarget(db *gorm.DB, table, constraint string) string {
+	var target string
+	err := db.Raw("SELECT confrelid::regclass::text FROM pg_constraint WHERE conrelid = to_regclass(?) AND conname = ?", table, constraint).
+		Scan(&target).Error
+	if err != nil {
+		log.Error().Err(err).Str("constraint", constraint).Msg("Failed to inspect foreign key")
+		return ""
+	}
+	return target
+}
+
+// backfillAppointmentEndTimes gives appointments created before end times existed the default duration
+func backfillAppointmentEndTimes(db *gorm.DB) {
+	result := db.Model(&appointment.Appointment{}).
+		Where("end_time IS NULL OR end_time <= scheduled_time").
+		Updates(map[string]interface{}{
+			"end_time":         gorm.Expr("scheduled_time + make_interval(mins => ?)", int(appointment.DefaultDuration.Minutes())),
+			"duration_minutes": int(appointment.DefaultDuration.Minutes()),
+		})
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("Failed to backfill appointment end times")
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Info().Int64("count", result.RowsAffected).Msg("Appointment end times backfilled")
+	}
+}
+
+// migrateLegacyMedicalHistory moves the free-text medical history patients had before structured records
+// into a questionnaire note dated at the patient's last update, then drops the old column.
+// The note shows up in the patient's alerts until it is transcribed into structured records.
+func migrateLegacyMedicalHistory(db *gorm.DB) {
+	if !db.Migrator().HasColumn(&patient.Patient{}, "medical_history") {
+		return
+	}
+
+	var count int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var legacy []struct {
+			ID             uint
+			ClinicID       uint
+			MedicalHistory string
+			UpdatedAt      time.Time
+		}
+		if err := tx.Table("patients").
+			Select("id, clinic_id, medical_history, updated_at").
+			Where("TRIM(COALESCE(medical_history, '')) <> ''").
+			Scan(&legacy).Error; err != nil {
+			return err
+		}
+
+		for _, row := range legacy {
+			questionnaire := medical.Questionnaire{
+				ClinicID:   row.ClinicID,
+				PatientID:  row.ID,
+				AnsweredOn: row.UpdatedAt.Format(schedule.DateLayout),
+				Notes:      strings.TrimSpace(row.MedicalHistory),
+			}
+			if err := tx.Create(&questionnaire).Error; err != nil {
+				return err
+			}
+		}
+		count = int64(len(legacy))
+
+		return tx.Migrator().DropColumn(&patient.Patient{}, "medical_history")
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to migrate legacy medical history")
+		return
+	}
+	log.Info().Int64("count", count).Msg("Legacy medical history moved to questionnaires")
+}
+
+// patientSearchIndexes back patient search: trigram indexes for fuzzy and partial matches
+// on names and phone numbers, a full-text index for word prefixes and a prefix index on national IDs
+var patientSearchIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_patients_search_name_trgm ON patients USING gin (search_name gin_trgm_ops)",
+	"CREATE INDEX IF NOT EXISTS idx_patients_search_name_fts ON patients USING gin (to_tsvector('simple', search_name))",
+	"CREATE INDEX IF NOT EXISTS idx_patients_search_phone_trgm ON patients USING gin (search_phone gin_trgm_ops)",
+	"CREATE INDEX IF NOT EXISTS idx_patients_clinic_national_id ON patients (clinic_id, national_id text_pattern_ops)",
+}
+
+// createPatientSearchIndexes enables pg_trgm and creates the indexes patient search needs. It also
+// enables fuzzystrmatch, which duplicate detection uses to compare national IDs.
+func createPatientSearchIndexes(db *gorm.DB) {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Error().Err(err).Msg("Failed to enable pg_trgm; patient search will not work")
+		return
+	}
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS fuzzystrmatch").Error; err != nil {
+		log.Error().Err(err).Msg("Failed to enable fuzzystrmatch; duplicate patient detection will not work")
+	}
+	for _, statement := range patientSearchIndexes {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Error().Err(err).Str("statement", statement).Msg("Failed to create patient search index")
+		}
+	}
+}
+
+// backfillPatientSearchFields fills the search columns of patients created before search existed
+func backfillPatientSearchFields(db *gorm.DB) {
+	var count int64
+	var patients []patient.Patient
+	result := db.Where("search_name = '' OR search_name IS NULL").
+		FindInBatches(&patients, 500, func(_ *gorm.DB, _ int) error {
+			for i := range patients {
+				patients[i].SetSearchFields()
+				err := db.Model(&patients[i]).UpdateColumns(map[string]interface{}{
+					"search_name":       patients[i].SearchName,
+					"search_phone":      patients[i].SearchPhone,
+					"search_birth_date": patients[i].SearchBirthDate,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			count += int64(len(patients))
+			return nil
+		})
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("Failed to backfill patient search fields")
+		return
+	}
+	if count > 0 {
+		log.Info().Int64("count", count).Msg("Patient search fields backfilled")
+	}
 }
 
 // seedRoles veritabanına tüm rolleri ekler (eğer yoksa)
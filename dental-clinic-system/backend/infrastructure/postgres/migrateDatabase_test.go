@@ -0,0 +1,115 @@
+package postgres_test
+
+import (
+	"dental-clinic-system/infrastructure/postgres"
+	"dental-clinic-system/infrastructure/postgres/postgrestest"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"testing"
+)
+
+func TestMigrateAppointmentPatients(t *testing.T) {
+	db := postgrestest.Open(t)
+
+	// Put the database back the way it was while appointments were booked for user accounts
+	for _, statement := range []string{
+		"ALTER TABLE appointments DROP CONSTRAINT fk_appointments_patient",
+		"ALTER TABLE appointments ADD CONSTRAINT fk_appointments_patient FOREIGN KEY (patient_id) REFERENCES users(id)",
+		"ALTER TABLE waitlist_entries DROP CONSTRAINT fk_waitlist_entries_patient",
+		"ALTER TABLE waitlist_entries ADD CONSTRAINT fk_waitlist_entries_patient FOREIGN KEY (patient_id) REFERENCES users(id)",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	cln := clinic.Clinic{Name: "Gülüş Diş", PhoneNumber: "02120000000", Email: "info@example.com"}
+	branch := clinic.Clinic{Name: "Gülüş Diş Kadıköy", PhoneNumber: "02160000000", Email: "kadikoy@example.com"}
+	for _, c := range []*clinic.Clinic{&cln, &branch} {
+		if err := db.Create(c).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Patients first, so patient IDs and user IDs differ
+	var others []patient.Patient
+	for _, nationalID := range []string{"90000000001", "90000000002", "90000000003"} {
+		others = append(others, patient.Patient{NationalID: nationalID, Name: "Başka Hasta", ClinicID: cln.ID})
+	}
+	if err := db.Create(&others).Error; err != nil {
+		t.Fatal(err)
+	}
+	doctor := user.User{NationalID: "10000000001", Email: "doctor@example.com", PhoneNumber: "5320000001", ClinicID: cln.ID}
+	ayse := user.User{NationalID: "10000000146", Email: "ayse@example.com", FirstName: "Ayşe", LastName: "Yılmaz", PhoneNumber: "5320000002", ClinicID: cln.ID}
+	ali := user.User{NationalID: "10000000164", Email: "ali@example.com", FirstName: "Ali", LastName: "Kaya", PhoneNumber: "5320000003", ClinicID: cln.ID}
+	for _, u := range []*user.User{&doctor, &ayse, &ali} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ayseRecord := patient.Patient{NationalID: ayse.NationalID, Name: "Ayşe Yılmaz", ContactInfo: "ayse@example.com", ClinicID: cln.ID}
+	// Ali only has a deleted record in the first clinic and a record in the branch
+	aliDeleted := patient.Patient{NationalID: ali.NationalID, Name: "Ali Kaya", ClinicID: cln.ID}
+	aliBranch := patient.Patient{NationalID: ali.NationalID, Name: "Ali Kaya", ClinicID: branch.ID}
+	for _, p := range []*patient.Patient{&ayseRecord, &aliDeleted, &aliBranch} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(&aliDeleted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, booking := range []struct{ clinicID, patientID uint }{{cln.ID, ayse.ID}, {cln.ID, ali.ID}, {branch.ID, ali.ID}} {
+		err := db.Exec(`INSERT INTO appointments (created_at, updated_at, clinic_id, patient_id, doctor_id, scheduled_time, end_time, status)
+			VALUES (now(), now(), ?, ?, ?, now(), now() + interval '30 minutes', 'booked')`, booking.clinicID, booking.patientID, doctor.ID).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := db.Exec(`INSERT INTO waitlist_entries (created_at, updated_at, clinic_id, patient_id, status) VALUES (now(), now(), ?, ?, 'waiting')`,
+		cln.ID, ali.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	postgres.MigrateDatabase(db)
+
+	var aliRecord patient.Patient
+	if err := db.Where("clinic_id = ? AND national_id = ?", cln.ID, ali.NationalID).First(&aliRecord).Error; err != nil {
+		t.Fatalf("no patient record was created for the user: %v", err)
+	}
+	if aliRecord.ID == aliDeleted.ID || aliRecord.Name != "Ali Kaya" || aliRecord.ContactInfo != "ali@example.com" {
+		t.Errorf("created patient = %+v", aliRecord)
+	}
+
+	var booked []uint
+	if err := db.Raw("SELECT patient_id FROM appointments ORDER BY id").Scan(&booked).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(booked) != 3 || booked[0] != ayseRecord.ID || booked[1] != aliRecord.ID || booked[2] != aliBranch.ID {
+		t.Errorf("appointment patients = %v, want [%d %d %d]", booked, ayseRecord.ID, aliRecord.ID, aliBranch.ID)
+	}
+	var waiting uint
+	if err := db.Raw("SELECT patient_id FROM waitlist_entries").Scan(&waiting).Error; err != nil {
+		t.Fatal(err)
+	}
+	if waiting != aliRecord.ID {
+		t.Errorf("waitlist patient = %d, want %d", waiting, aliRecord.ID)
+	}
+
+	for _, constraint := range []struct{ table, name string }{
+		{"appointments", "fk_appointments_patient"},
+		{"waitlist_entries", "fk_waitlist_entries_patient"},
+	} {
+		var target string
+		err := db.Raw("SELECT confrelid::regclass::text FROM pg_constraint WHERE conrelid = to_regclass(?) AND conname = ?",
+			constraint.table, constraint.name).Scan(&target).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != "patients" {
+			t.Errorf("%s references %q, want patients", constraint.name, target)
+		}
+	}
+}
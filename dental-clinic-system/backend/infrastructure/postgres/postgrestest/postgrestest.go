@@ -0,0 +1,81 @@
+// Package postgrestest opens migrated PostgreSQL databases for repository tests
+package postgrestest
+
+import (
+	"dental-clinic-system/infrastructure/postgres"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	pgdriver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DSNVariable names the environment variable holding the connection string of the test database
+const DSNVariable = "TEST_DATABASE_DSN"
+
+// Open connects to the test database in a schema of its own, migrated like production, and drops the
+// schema when the test ends. The test is skipped when DSNVariable is not set.
+func Open(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, schema := connect(t)
+	postgres.MigrateDatabase(db)
+	t.Cleanup(func() { drop(db, schema) })
+	return db
+}
+
+// OpenEmpty is Open without the migration, for tests that build the schema themselves
+func OpenEmpty(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, schema := connect(t)
+	t.Cleanup(func() { drop(db, schema) })
+	return db
+}
+
+func connect(t *testing.T) (*gorm.DB, string) {
+	dsn := os.Getenv(DSNVariable)
+	if dsn == "" {
+		t.Skipf("%s is not set; skipping PostgreSQL test", DSNVariable)
+	}
+
+	admin, err := gorm.Open(pgdriver.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	if sqlDB, err := admin.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+
+	// pg_trgm is created in public, so public stays on the search path
+	db, err := gorm.Open(pgdriver.Open(withSearchPath(dsn, schema+",public")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("connect to test schema: %v", err)
+	}
+	return db, schema
+}
+
+func drop(db *gorm.DB, schema string) {
+	db.Exec("DROP SCHEMA " + schema + " CASCADE")
+	if sqlDB, err := db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+}
+
+// withSearchPath adds search_path to a URL or key/value connection string
+func withSearchPath(dsn, searchPath string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + strings.ReplaceAll(searchPath, ",", "%2C")
+	}
+	return dsn + " search_path=" + searchPath
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"dental-clinic-system/models/schedule"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Dini bayramlar Hicri takvime göre her yıl değişir; Diyanet takvimine göre girilmiştir.
+// Tarihi sabit olan tatiller kaydedilmez, her yıl için schedule.FixedPublicHolidays ile hesaplanır.
+// Listedeki son yıldan sonrası için yeni yılların bayramları eklenmelidir.
+var religiousTurkishHolidays = []schedule.PublicHoliday{
+	{Date: "2025-03-29", Name: "Ramazan Bayramı Arifesi", HalfDay: true, HalfDayFrom: "13:00"},
+	{Date: "2025-03-30", Name: "Ramazan Bayramı 1. Gün"},
+	{Date: "2025-03-31", Name: "Ramazan Bayramı 2. Gün"},
+	{Date: "2025-04-01", Name: "Ramazan Bayramı 3. Gün"},
+	{Date: "2025-06-05", Name: "Kurban Bayramı Arifesi", HalfDay: true, HalfDayFrom: "13:00"},
+	{Date: "2025-06-06", Name: "Kurban Bayramı 1. Gün"},
+	{Date: "2025-06-07", Name: "Kurban Bayramı 2. Gün"},
+	{Date: "2025-06-08", Name: "Kurban Bayramı 3. Gün"},
+	{Date: "2025-06-09", Name: "Kurban Bayramı 4. Gün"},
+	{Date: "2026-03-19", Name: "Ramazan Bayramı Arifesi", HalfDay: true, HalfDayFrom: "13:00"},
+	{Date: "2026-03-20", Name: "Ramazan Bayramı 1. Gün"},
+	{Date: "2026-03-21", Name: "Ramazan Bayramı 2. Gün"},
+	{Date: "2026-03-22", Name: "Ramazan Bayramı 3. Gün"},
+	{Date: "2026-05-26", Name: "Kurban Bayramı Arifesi", HalfDay: true, HalfDayFrom: "13:00"},
+	{Date: "2026-05-27", Name: "Kurban Bayramı 1. Gün"},
+	{Date: "2026-05-28", Name: "Kurban Bayramı 2. Gün"},
+	{Date: "2026-05-29", Name: "Kurban Bayramı 3. Gün"},
+	{Date: "2026-05-30", Name: "Kurban Bayramı 4. Gün"},
+	{Date: "2027-03-08", Name: "Ramazan Bayramı Arifesi", HalfDay: true, HalfDayFrom: "13:00"},
+	{Date: "2027-03-09", Name: "Ramazan Bayramı 1. Gün"},
+	{Date: "2027-03-10", Name: "Ramazan Bayramı 2. Gün"},
+	{Date: "2027-03-11", Name: "Ramazan Bayramı 3. Gün"},
+	{Date: "2027-05-15", Name: "Kurban Bayramı Arifesi", HalfDay: true, HalfDayFrom: "13:00"},
+	{Date: "2027-05-16", Name: "Kurban Bayramı 1. Gün"},
+	{Date: "2027-05-17", Name: "Kurban Bayramı 2. Gün"},
+	{Date: "2027-05-18", Name: "Kurban Bayramı 3. Gün"},
+	{Date: "2027-05-19", Name: "Kurban Bayramı 4. Gün"},
+}
+
+// seedPublicHolidays dini bayramları veritabanına ekler (eğer yoksa)
+func seedPublicHolidays(db *gorm.DB) {
+	for _, holiday := range religiousTurkishHolidays {
+		var existing schedule.PublicHoliday
+		result := db.Where("date = ?", holiday.Date).Limit(1).Find(&existing)
+		if result.Error != nil {
+			log.Error().Err(result.Error).Str("date", holiday.Date).Msg("Failed to check public holiday")
+			continue
+		}
+		if result.RowsAffected > 0 {
+			continue
+		}
+		if err := db.Create(&holiday).Error; err != nil {
+			log.Error().
+				Err(err).
+				Str("date", holiday.Date).
+				Msg("Failed to seed public holiday")
+		}
+	}
+
+	log.Info().Msg("Public holiday seeding completed")
+}
@@ -0,0 +1,77 @@
+package appointmentRepository
+
+import (
+	"context"
+	"time"
+
+	"dental-clinic-system/models/appointment"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/rs/zerolog/log"
+)
+
+// GetAppointmentsDueForReminder retrieves active appointments scheduled in [from, to) that have not
+// received the given reminder yet
+func (repo *Repository) GetAppointmentsDueForReminder(ctx context.Context, kind appointment.ReminderKind, from, to time.Time) ([]appointment.Appointment, error) {
+	var appointmentsList []appointment.Appointment
+	result := repo.DB.WithContext(ctx).
+		Where("scheduled_time >= ? AND scheduled_time < ?", from, to).
+		Where("status IN ?", []appointment.Status{appointment.StatusBooked, appointment.StatusConfirmed}).
+		Where("NOT EXISTS (SELECT 1 FROM sent_reminders WHERE sent_reminders.appointment_id = appointments.id AND sent_reminders.kind = ? AND sent_reminders.deleted_at IS NULL)", kind).
+		Preload("Clinic").
+		Preload("Patient").
+		Preload("Doctor").
+		Order("scheduled_time").
+		Find(&appointmentsList)
+
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetAppointmentsDueForReminder").
+			Err(result.Error).
+			Str("kind", string(kind)).
+			Msg("Failed to retrieve appointments due for reminder")
+		return nil, result.Error
+	}
+
+	return appointmentsList, nil
+}
+
+// ClaimReminder records that a reminder is being sent for an appointment.
+// It returns false if the reminder was already claimed, so concurrent jobs or restarts never send it twice.
+func (repo *Repository) ClaimReminder(ctx context.Context, appointmentID uint, kind appointment.ReminderKind, sentAt time.Time) (bool, error) {
+	reminder := appointment.SentReminder{AppointmentID: appointmentID, Kind: kind, SentAt: sentAt}
+	result := repo.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&reminder)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "ClaimReminder").
+			Err(result.Error).
+			Uint("appointment_id", appointmentID).
+			Str("kind", string(kind)).
+			Msg("Failed to record appointment reminder")
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseReminder removes a claimed reminder so it is retried on the next run
+func (repo *Repository) ReleaseReminder(ctx context.Context, appointmentID uint, kind appointment.ReminderKind) error {
+	result := repo.DB.WithContext(ctx).
+		Unscoped().
+		Where("appointment_id = ? AND kind = ?", appointmentID, kind).
+		Delete(&appointment.SentReminder{})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "ReleaseReminder").
+			Err(result.Error).
+			Uint("appointment_id", appointmentID).
+			Str("kind", string(kind)).
+			Msg("Failed to release appointment reminder")
+		return result.Error
+	}
+
+	return nil
+}
@@ -3,8 +3,10 @@ package appointmentRepository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/resource"
 
 	"gorm.io/gorm"
 
@@ -21,32 +23,88 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{DB: db}
 }
 
-// GetAppointments retrieves all appointments for a specific clinic
-func (repo *Repository) GetAppointments(ctx context.Context, clinicID uint) ([]appointment.Appointment, error) {
-	var appointmentsList []appointment.Appointment
-	result := repo.DB.WithContext(ctx).
-		Where("clinic_id = ?", clinicID).
+// GetAppointments retrieves one page of a clinic's appointments matching the filter, together with the
+// number of matching appointments across all pages. The filter must have been normalized.
+// Pages are keyset paginated on (sort column, id), which the (clinic_id, scheduled_time) index serves.
+func (repo *Repository) GetAppointments(ctx context.Context, filter appointment.ListFilter) (appointment.Page, error) {
+	cursor, err := filter.After()
+	if err != nil {
+		return appointment.Page{}, err
+	}
+
+	query := repo.DB.WithContext(ctx).
+		Model(&appointment.Appointment{}).
+		Where("clinic_id = ?", filter.ClinicID)
+	if !filter.From.IsZero() {
+		query = query.Where("scheduled_time >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("scheduled_time < ?", filter.To)
+	}
+	if filter.DoctorID != 0 {
+		query = query.Where("doctor_id = ?", filter.DoctorID)
+	}
+	if filter.PatientID != 0 {
+		query = query.Where("patient_id = ?", filter.PatientID)
+	}
+	if filter.ProcedureID != 0 {
+		query = query.Where("procedure_id = ?", filter.ProcedureID)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+
+	var page appointment.Page
+	if err := query.Session(&gorm.Session{}).Count(&page.TotalCount).Error; err != nil {
+		log.Error().
+			Str("operation", "GetAppointments").
+			Err(err).
+			Uint("clinic_id", filter.ClinicID).
+			Msg("Failed to count appointments")
+		return appointment.Page{}, err
+	}
+
+	column, direction, comparison := string(filter.SortBy), "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if cursor != nil {
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), cursor.Value, cursor.ID)
+	}
+
+	result := query.
 		Preload("Clinic").
 		Preload("Patient").
 		Preload("Doctor").
-		Find(&appointmentsList)
-
+		Preload("Resources").
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(filter.Limit + 1).
+		Find(&page.Appointments)
 	if result.Error != nil {
 		log.Error().
 			Str("operation", "GetAppointments").
 			Err(result.Error).
-			Uint("clinic_id", clinicID).
+			Uint("clinic_id", filter.ClinicID).
 			Msg("Failed to retrieve appointments")
-		return nil, result.Error
+		return appointment.Page{}, result.Error
+	}
+
+	if page.Appointments == nil {
+		page.Appointments = []appointment.Appointment{}
+	}
+	if len(page.Appointments) > filter.Limit {
+		page.Appointments = page.Appointments[:filter.Limit]
+		page.NextCursor = filter.NextCursor(page.Appointments[filter.Limit-1])
 	}
 
 	log.Info().
 		Str("operation", "GetAppointments").
-		Uint("clinic_id", clinicID).
-		Int("count", len(appointmentsList)).
-		Msgf("Retrieved %d appointments successfully", len(appointmentsList))
+		Uint("clinic_id", filter.ClinicID).
+		Int("count", len(page.Appointments)).
+		Int64("total_count", page.TotalCount).
+		Msgf("Retrieved %d of %d appointments successfully", len(page.Appointments), page.TotalCount)
 
-	return appointmentsList, nil
+	return page, nil
 }
 
 // GetAppointment retrieves a single appointment by its ID
@@ -56,6 +114,7 @@ func (repo *Repository) GetAppointment(ctx context.Context, id uint) (appointmen
 		Preload("Clinic").
 		Preload("Patient").
 		Preload("Doctor").
+		Preload("Resources").
 		First(&appt, id)
 
 	if result.Error != nil {
@@ -83,15 +142,28 @@ func (repo *Repository) GetAppointment(ctx context.Context, id uint) (appointmen
 	return appt, nil
 }
 
-// CreateAppointment creates a new appointment record in the database
+// CreateAppointment creates a new appointment record in the database.
+// The doctor and patient are locked for the duration of the transaction so that
+// concurrent bookings cannot both pass the overlap check.
 func (repo *Repository) CreateAppointment(ctx context.Context, newAppt appointment.Appointment) (appointment.Appointment, error) {
-	result := repo.DB.WithContext(ctx).Create(&newAppt)
-	if result.Error != nil {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return CreateInTransaction(tx, &newAppt)
+	})
+	if err != nil {
+		var conflictErr *appointment.ConflictError
+		if errors.As(err, &conflictErr) {
+			log.Warn().
+				Str("operation", "CreateAppointment").
+				Str("participant", conflictErr.Participant).
+				Uint("conflicting_appointment_id", conflictErr.ConflictingAppointmentID).
+				Msg("Appointment conflicts with an existing booking")
+			return appointment.Appointment{}, err
+		}
 		log.Error().
 			Str("operation", "CreateAppointment").
-			Err(result.Error).
+			Err(err).
 			Msg("Failed to create appointment")
-		return appointment.Appointment{}, result.Error
+		return appointment.Appointment{}, err
 	}
 
 	log.Info().
@@ -104,14 +176,35 @@ func (repo *Repository) CreateAppointment(ctx context.Context, newAppt appointme
 
 // UpdateAppointment updates an existing appointment record in the database
 func (repo *Repository) UpdateAppointment(ctx context.Context, updatedAppt appointment.Appointment) (appointment.Appointment, error) {
-	result := repo.DB.WithContext(ctx).Save(&updatedAppt)
-	if result.Error != nil {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockParticipants(tx, updatedAppt); err != nil {
+			return err
+		}
+		if err := checkConflicts(tx, updatedAppt); err != nil {
+			return err
+		}
+		if err := saveRevision(tx, &updatedAppt); err != nil {
+			return err
+		}
+		return saveResources(tx, &updatedAppt)
+	})
+	if err != nil {
+		var conflictErr *appointment.ConflictError
+		if errors.As(err, &conflictErr) {
+			log.Warn().
+				Str("operation", "UpdateAppointment").
+				Str("participant", conflictErr.Participant).
+				Uint("appointment_id", updatedAppt.ID).
+				Uint("conflicting_appointment_id", conflictErr.ConflictingAppointmentID).
+				Msg("Appointment conflicts with an existing booking")
+			return appointment.Appointment{}, err
+		}
 		log.Error().
 			Str("operation", "UpdateAppointment").
-			Err(result.Error).
+			Err(err).
 			Uint("appointment_id", updatedAppt.ID).
 			Msg("Failed to update appointment")
-		return appointment.Appointment{}, result.Error
+		return appointment.Appointment{}, err
 	}
 
 	log.Info().
@@ -150,6 +243,7 @@ func (repo *Repository) GetDoctorAppointments(ctx context.Context, doctorID uint
 		Preload("Clinic").
 		Preload("Patient").
 		Preload("Doctor").
+		Preload("Resources").
 		Find(&doctorAppointmentsList)
 
 	if result.Error != nil {
@@ -178,6 +272,7 @@ func (repo *Repository) GetPatientAppointments(ctx context.Context, patientID ui
 		Preload("Clinic").
 		Preload("Patient").
 		Preload("Doctor").
+		Preload("Resources").
 		Find(&patientAppointmentsList)
 
 	if result.Error != nil {
@@ -197,3 +292,333 @@ func (repo *Repository) GetPatientAppointments(ctx context.Context, patientID ui
 
 	return patientAppointmentsList, nil
 }
+
+// TransitionStatus moves an appointment from one status to another and records the change in its history.
+// The update only applies if the appointment is still in the expected status, so concurrent transitions
+// of the same appointment cannot both succeed.
+func (repo *Repository) TransitionStatus(ctx context.Context, id uint, from, to appointment.Status, history appointment.StatusHistory) (appointment.Appointment, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"status": to, "revision": gorm.Expr("revision + 1")}
+		if to == appointment.StatusCancelled {
+			updates["cancellation_reason"] = history.Reason
+		}
+
+		result := tx.Model(&appointment.Appointment{}).
+			Where("id = ? AND status = ?", id, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &appointment.InvalidTransitionError{From: from, To: to}
+		}
+
+		history.AppointmentID = id
+		history.FromStatus = from
+		history.ToStatus = to
+		return tx.Create(&history).Error
+	})
+	if err != nil {
+		log.Error().
+			Str("operation", "TransitionStatus").
+			Err(err).
+			Uint("appointment_id", id).
+			Str("from", string(from)).
+			Str("to", string(to)).
+			Msg("Failed to change appointment status")
+		return appointment.Appointment{}, err
+	}
+
+	log.Info().
+		Str("operation", "TransitionStatus").
+		Uint("appointment_id", id).
+		Str("from", string(from)).
+		Str("to", string(to)).
+		Msg("Appointment status changed successfully")
+
+	return repo.GetAppointment(ctx, id)
+}
+
+// GetStatusHistory retrieves the status transitions of an appointment in chronological order
+func (repo *Repository) GetStatusHistory(ctx context.Context, id uint) ([]appointment.StatusHistory, error) {
+	var history []appointment.StatusHistory
+	result := repo.DB.WithContext(ctx).
+		Where("appointment_id = ?", id).
+		Order("changed_at, id").
+		Find(&history)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetStatusHistory").
+			Err(result.Error).
+			Uint("appointment_id", id).
+			Msg("Failed to retrieve appointment status history")
+		return nil, result.Error
+	}
+	return history, nil
+}
+
+// CreateSeries creates a series and all of its occurrences in one transaction.
+// Every occurrence is conflict-checked; if one of them conflicts nothing is created.
+func (repo *Repository) CreateSeries(ctx context.Context, series appointment.Series, occurrences []appointment.Appointment) (appointment.Series, error) {
+	series.Appointments = nil
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&series).Error; err != nil {
+			return err
+		}
+
+		for i := range occurrences {
+			occurrences[i].SeriesID = &series.ID
+			if err := lockParticipants(tx, occurrences[i]); err != nil {
+				return err
+			}
+			if err := checkConflicts(tx, occurrences[i]); err != nil {
+				return &appointment.OccurrenceError{Index: occurrences[i].OccurrenceIndex, Start: occurrences[i].ScheduledTime, Err: err}
+			}
+			if err := tx.Omit("Resources").Create(&occurrences[i]).Error; err != nil {
+				return err
+			}
+			if err := saveResources(tx, &occurrences[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error().
+			Str("operation", "CreateSeries").
+			Err(err).
+			Int("occurrences", len(occurrences)).
+			Msg("Failed to create appointment series")
+		return appointment.Series{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateSeries").
+		Uint("series_id", series.ID).
+		Int("occurrences", len(occurrences)).
+		Msg("Appointment series created successfully")
+
+	series.Appointments = occurrences
+	return series, nil
+}
+
+// GetSeries retrieves a series with its occurrences in order
+func (repo *Repository) GetSeries(ctx context.Context, id uint) (appointment.Series, error) {
+	var series appointment.Series
+	result := repo.DB.WithContext(ctx).
+		Preload("Appointments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("occurrence_index")
+		}).
+		Preload("Appointments.Resources").
+		First(&series, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			log.Warn().
+				Str("operation", "GetSeries").
+				Uint("series_id", id).
+				Msg("Appointment series not found")
+			return appointment.Series{}, appointment.ErrSeriesNotFound
+		}
+		log.Error().
+			Str("operation", "GetSeries").
+			Err(result.Error).
+			Uint("series_id", id).
+			Msg("Failed to retrieve appointment series")
+		return appointment.Series{}, result.Error
+	}
+	return series, nil
+}
+
+// UpdateSeriesAppointments saves several occurrences of a series in one transaction, conflict-checking each
+func (repo *Repository) UpdateSeriesAppointments(ctx context.Context, appointments []appointment.Appointment) ([]appointment.Appointment, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range appointments {
+			if err := lockParticipants(tx, appointments[i]); err != nil {
+				return err
+			}
+		}
+		// Save everything before checking, otherwise shifting a whole series would
+		// collide with the old position of its own next occurrence
+		for i := range appointments {
+			if err := saveRevision(tx, &appointments[i]); err != nil {
+				return err
+			}
+		}
+		for i := range appointments {
+			if err := checkConflicts(tx, appointments[i]); err != nil {
+				return &appointment.OccurrenceError{Index: appointments[i].OccurrenceIndex, Start: appointments[i].ScheduledTime, Err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error().
+			Str("operation", "UpdateSeriesAppointments").
+			Err(err).
+			Int("occurrences", len(appointments)).
+			Msg("Failed to update series occurrences")
+		return nil, err
+	}
+
+	log.Info().
+		Str("operation", "UpdateSeriesAppointments").
+		Int("occurrences", len(appointments)).
+		Msg("Series occurrences updated successfully")
+	return appointments, nil
+}
+
+// saveRevision saves an existing appointment without its resources and counts up its revision.
+// The revision is incremented in the database, so concurrent updates never end up with the same one.
+func saveRevision(tx *gorm.DB, appt *appointment.Appointment) error {
+	if err := tx.Omit("Resources", "Revision").Save(appt).Error; err != nil {
+		return err
+	}
+	return tx.Raw("UPDATE appointments SET revision = revision + 1 WHERE id = ? RETURNING revision", appt.ID).
+		Scan(&appt.Revision).Error
+}
+
+// CreateInTransaction books an appointment inside a transaction owned by the caller.
+// It takes the same participant locks and overlap check as CreateAppointment, so other
+// repositories can combine a booking with their own changes atomically.
+func CreateInTransaction(tx *gorm.DB, newAppt *appointment.Appointment) error {
+	if err := lockParticipants(tx, *newAppt); err != nil {
+		return err
+	}
+	if err := checkConflicts(tx, *newAppt); err != nil {
+		return err
+	}
+	newAppt.Revision = 0
+	if err := tx.Omit("Resources").Create(newAppt).Error; err != nil {
+		return err
+	}
+	return saveResources(tx, newAppt)
+}
+
+// saveResources replaces the resources reserved by an appointment and reloads them
+func saveResources(tx *gorm.DB, appt *appointment.Appointment) error {
+	ids := appt.ResourceIDList()
+	if err := tx.Exec("DELETE FROM appointment_resources WHERE appointment_id = ?", appt.ID).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := tx.Exec("INSERT INTO appointment_resources (appointment_id, resource_id) VALUES (?, ?)", appt.ID, id).Error; err != nil {
+			return err
+		}
+	}
+
+	appt.Resources = nil
+	appt.ResourceIDs = nil
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Where("id IN ?", ids).Order("id").Find(&appt.Resources).Error
+}
+
+// Advisory lock namespaces used to serialise bookings per participant
+const (
+	doctorLockNamespace   = 1001
+	patientLockNamespace  = 1002
+	resourceLockNamespace = 1003
+)
+
+// lockParticipants takes transaction-scoped advisory locks on the doctor, the patient and the reserved resources.
+// Locks are always acquired doctor first, then patient, then resources in ascending ID order to keep the
+// ordering consistent between transactions.
+func lockParticipants(tx *gorm.DB, appt appointment.Appointment) error {
+	keys := []int64{lockKey(doctorLockNamespace, appt.DoctorID), lockKey(patientLockNamespace, appt.PatientID)}
+	for _, id := range appt.ResourceIDList() {
+		keys = append(keys, lockKey(resourceLockNamespace, id))
+	}
+	for _, key := range keys {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockKey combines a namespace and an ID into a single bigint advisory lock key. The namespace takes the
+// high 32 bits, so IDs up to 2^32 never share a lock with another namespace or ID.
+func lockKey(namespace int64, id uint) int64 {
+	return namespace<<32 | int64(id&0xFFFFFFFF)
+}
+
+// checkConflicts looks for another appointment of the same doctor or patient overlapping the given one
+func checkConflicts(tx *gorm.DB, appt appointment.Appointment) error {
+	participants := []struct {
+		name   string
+		column string
+		id     uint
+	}{
+		{appointment.ConflictDoctor, "doctor_id", appt.DoctorID},
+		{appointment.ConflictPatient, "patient_id", appt.PatientID},
+	}
+
+	for _, participant := range participants {
+		var existing appointment.Appointment
+		result := tx.
+			Where(participant.column+" = ?", participant.id).
+			Where("id <> ?", appt.ID).
+			Where("status NOT IN ?", appointment.InactiveStatuses).
+			Where("scheduled_time < ? AND end_time > ?", appt.EndTime, appt.ScheduledTime).
+			Limit(1).
+			Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return &appointment.ConflictError{
+				Participant:              participant.name,
+				ConflictingAppointmentID: existing.ID,
+			}
+		}
+	}
+
+	return checkResourceConflicts(tx, appt)
+}
+
+// checkResourceConflicts ensures every reserved resource belongs to the appointment's clinic, is active
+// and is not reserved by another overlapping appointment
+func checkResourceConflicts(tx *gorm.DB, appt appointment.Appointment) error {
+	ids := appt.ResourceIDList()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var resources []resource.Resource
+	if err := tx.Where("id IN ? AND clinic_id = ?", ids, appt.ClinicID).Find(&resources).Error; err != nil {
+		return err
+	}
+	if len(resources) != len(ids) {
+		return resource.ErrResourceNotFound
+	}
+	for _, res := range resources {
+		if !res.Active {
+			return fmt.Errorf("%w: %s", resource.ErrResourceInactive, res.Name)
+		}
+	}
+
+	for _, id := range ids {
+		var existing appointment.Appointment
+		result := tx.
+			Joins("JOIN appointment_resources ON appointment_resources.appointment_id = appointments.id").
+			Where("appointment_resources.resource_id = ?", id).
+			Where("appointments.id <> ?", appt.ID).
+			Where("appointments.status NOT IN ?", appointment.InactiveStatuses).
+			Where("appointments.scheduled_time < ? AND appointments.end_time > ?", appt.EndTime, appt.ScheduledTime).
+			Limit(1).
+			Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return &appointment.ConflictError{
+				Participant:              appointment.ConflictResource,
+				ConflictingAppointmentID: existing.ID,
+				ResourceID:               id,
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package appointmentRepository
+
+import (
+	"context"
+	"dental-clinic-system/infrastructure/postgres/postgrestest"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockKey(t *testing.T) {
+	seen := map[int64]string{}
+	for _, namespace := range []int64{doctorLockNamespace, patientLockNamespace, resourceLockNamespace} {
+		for _, id := range []uint{1, 2, 1 << 16, 1<<31 + 5, 1<<32 - 1} {
+			key := lockKey(namespace, id)
+			name := fmt.Sprintf("%d/%d", namespace, id)
+			if other, ok := seen[key]; ok {
+				t.Errorf("lock key %d is shared by %s and %s", key, other, name)
+			}
+			seen[key] = name
+		}
+	}
+}
+
+func TestCreateAppointment_ConcurrentBookings(t *testing.T) {
+	db := postgrestest.Open(t)
+	repo := NewRepository(db)
+
+	cln := clinic.Clinic{Name: "Gülüş Diş", PhoneNumber: "02120000000", Email: "info@example.com"}
+	if err := db.Create(&cln).Error; err != nil {
+		t.Fatal(err)
+	}
+	doctor := user.User{NationalID: "10000000001", Email: "doctor@example.com", PhoneNumber: "5320000001", ClinicID: cln.ID}
+	if err := db.Create(&doctor).Error; err != nil {
+		t.Fatal(err)
+	}
+	const bookings = 8
+	patients := make([]patient.Patient, bookings)
+	for i := range patients {
+		patients[i] = patient.Patient{NationalID: fmt.Sprintf("2000000%04d", i), Name: fmt.Sprintf("Hasta %d", i), ClinicID: cln.ID}
+	}
+	if err := db.Create(&patients).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Every patient tries to book the same doctor at overlapping times
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	errs := make([]error, bookings)
+	var wg sync.WaitGroup
+	for i := range patients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			scheduled := start.Add(time.Duration(i) * time.Minute)
+			_, errs[i] = repo.CreateAppointment(context.Background(), appointment.Appointment{
+				ClinicID: cln.ID, PatientID: patients[i].ID, DoctorID: doctor.ID,
+				ScheduledTime: scheduled, EndTime: scheduled.Add(30 * time.Minute), Status: appointment.StatusBooked,
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	booked := 0
+	for i, err := range errs {
+		var conflictErr *appointment.ConflictError
+		switch {
+		case err == nil:
+			booked++
+		case errors.As(err, &conflictErr):
+			if conflictErr.Participant != appointment.ConflictDoctor {
+				t.Errorf("booking %d conflicts on %s, want %s", i, conflictErr.Participant, appointment.ConflictDoctor)
+			}
+		default:
+			t.Errorf("booking %d: unexpected error %v", i, err)
+		}
+	}
+	if booked != 1 {
+		t.Errorf("%d overlapping bookings succeeded, want exactly 1", booked)
+	}
+	var stored int64
+	if err := db.Model(&appointment.Appointment{}).Where("doctor_id = ?", doctor.ID).Count(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1 {
+		t.Errorf("doctor has %d appointments stored, want 1", stored)
+	}
+}
+
+func TestUpdateAppointment_CountsRevisions(t *testing.T) {
+	db := postgrestest.Open(t)
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	cln := clinic.Clinic{Name: "Gülüş Diş", PhoneNumber: "02120000000", Email: "info@example.com"}
+	if err := db.Create(&cln).Error; err != nil {
+		t.Fatal(err)
+	}
+	doctor := user.User{NationalID: "10000000001", Email: "doctor@example.com", PhoneNumber: "5320000001", ClinicID: cln.ID}
+	if err := db.Create(&doctor).Error; err != nil {
+		t.Fatal(err)
+	}
+	pt := patient.Patient{NationalID: "10000000146", Name: "Ayşe Yılmaz", ClinicID: cln.ID}
+	if err := db.Create(&pt).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	appt, err := repo.CreateAppointment(ctx, appointment.Appointment{
+		ClinicID: cln.ID, PatientID: pt.ID, DoctorID: doctor.ID,
+		ScheduledTime: start, EndTime: start.Add(30 * time.Minute), Status: appointment.StatusBooked, Revision: 7,
+	})
+	if err != nil {
+		t.Fatalf("CreateAppointment() error = %v", err)
+	}
+	if appt.Revision != 0 {
+		t.Errorf("new appointment has revision %d, want 0", appt.Revision)
+	}
+
+	// The revision sent by the client is ignored
+	appt.Notes = "Bring previous x-rays"
+	appt.Revision = 99
+	if appt, err = repo.UpdateAppointment(ctx, appt); err != nil {
+		t.Fatalf("UpdateAppointment() error = %v", err)
+	}
+	appt.ScheduledTime, appt.EndTime = start.Add(time.Hour), start.Add(90*time.Minute)
+	if appt, err = repo.UpdateAppointment(ctx, appt); err != nil {
+		t.Fatalf("UpdateAppointment() error = %v", err)
+	}
+	if appt.Revision != 2 {
+		t.Errorf("revision after two updates = %d, want 2", appt.Revision)
+	}
+
+	cancelled, err := repo.TransitionStatus(ctx, appt.ID, appointment.StatusBooked, appointment.StatusCancelled, appointment.StatusHistory{Reason: "Patient is ill"})
+	if err != nil {
+		t.Fatalf("TransitionStatus() error = %v", err)
+	}
+	if cancelled.Revision != 3 {
+		t.Errorf("revision after cancelling = %d, want 3", cancelled.Revision)
+	}
+}
@@ -0,0 +1,105 @@
+package attachmentRepository
+
+import (
+	"context"
+	"errors"
+
+	"dental-clinic-system/models/attachment"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles attachment database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetAttachments retrieves the attachments of a patient, newest first, optionally of one category
+func (repo *Repository) GetAttachments(ctx context.Context, clinicID, patientID uint, category attachment.Category) ([]attachment.Attachment, error) {
+	var attachments []attachment.Attachment
+	query := repo.DB.WithContext(ctx).
+		Where("clinic_id = ? AND patient_id = ?", clinicID, patientID).
+		Order("created_at DESC, id DESC")
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	if err := query.Find(&attachments).Error; err != nil {
+		log.Error().
+			Str("operation", "GetAttachments").
+			Err(err).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve attachments")
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetAttachment retrieves an attachment by its ID
+func (repo *Repository) GetAttachment(ctx context.Context, id uint) (attachment.Attachment, error) {
+	var a attachment.Attachment
+	result := repo.DB.WithContext(ctx).First(&a, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return attachment.Attachment{}, attachment.ErrAttachmentNotFound
+		}
+		log.Error().
+			Str("operation", "GetAttachment").
+			Err(result.Error).
+			Uint("attachment_id", id).
+			Msg("Failed to retrieve attachment")
+		return attachment.Attachment{}, result.Error
+	}
+	return a, nil
+}
+
+// CreateAttachment records a stored file
+func (repo *Repository) CreateAttachment(ctx context.Context, a attachment.Attachment) (attachment.Attachment, error) {
+	if err := repo.DB.WithContext(ctx).Create(&a).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateAttachment").
+			Err(err).
+			Uint("patient_id", a.PatientID).
+			Msg("Failed to create attachment")
+		return attachment.Attachment{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateAttachment").
+		Uint("attachment_id", a.ID).
+		Uint("patient_id", a.PatientID).
+		Int64("size_bytes", a.SizeBytes).
+		Msg("Attachment created successfully")
+
+	return a, nil
+}
+
+// DeleteAttachment soft deletes an attachment
+func (repo *Repository) DeleteAttachment(ctx context.Context, id uint) error {
+	result := repo.DB.WithContext(ctx).Delete(&attachment.Attachment{}, id)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "DeleteAttachment").
+			Err(result.Error).
+			Uint("attachment_id", id).
+			Msg("Failed to delete attachment")
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return attachment.ErrAttachmentNotFound
+	}
+
+	log.Info().
+		Str("operation", "DeleteAttachment").
+		Uint("attachment_id", id).
+		Msg("Attachment deleted successfully")
+
+	return nil
+}
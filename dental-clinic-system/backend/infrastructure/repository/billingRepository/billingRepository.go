@@ -0,0 +1,368 @@
+package billingRepository
+
+import (
+	"context"
+	"dental-clinic-system/models/billing"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// invoiceLockNamespace is the advisory lock namespace that serialises invoice numbering of a clinic
+const invoiceLockNamespace = 1301
+
+// Repository handles billing database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetInvoices retrieves the invoices of a clinic, newest first, optionally filtered by patient and statuses
+func (repo *Repository) GetInvoices(ctx context.Context, clinicID, patientID uint, statuses []billing.InvoiceStatus) ([]billing.Invoice, error) {
+	var invoices []billing.Invoice
+	query := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Order("issue_date DESC, id DESC")
+	if patientID != 0 {
+		query = query.Where("patient_id = ?", patientID)
+	}
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+
+	if err := query.Find(&invoices).Error; err != nil {
+		log.Error().
+			Str("operation", "GetInvoices").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve invoices")
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// GetInvoice retrieves an invoice with its lines and payments
+func (repo *Repository) GetInvoice(ctx context.Context, id uint) (billing.Invoice, error) {
+	var invoice billing.Invoice
+	result := repo.DB.WithContext(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Preload("Payments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("received_at, id")
+		}).
+		Preload("Installments").
+		Preload("Installments.Installments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sequence")
+		}).
+		First(&invoice, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return billing.Invoice{}, billing.ErrInvoiceNotFound
+		}
+		log.Error().
+			Str("operation", "GetInvoice").
+			Err(result.Error).
+			Uint("invoice_id", id).
+			Msg("Failed to retrieve invoice")
+		return billing.Invoice{}, result.Error
+	}
+	return invoice, nil
+}
+
+// GetInvoicesIssuedBetween retrieves the live invoices of a clinic issued between two dates inclusive, with their lines
+func (repo *Repository) GetInvoicesIssuedBetween(ctx context.Context, clinicID uint, from, to string) ([]billing.Invoice, error) {
+	var invoices []billing.Invoice
+	if err := repo.DB.WithContext(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Where("clinic_id = ? AND issue_date BETWEEN ? AND ? AND status <> ?", clinicID, from, to, billing.InvoiceVoid).
+		Order("issue_date, id").
+		Find(&invoices).Error; err != nil {
+		log.Error().
+			Str("operation", "GetInvoicesIssuedBetween").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve invoices")
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// CreateInvoice numbers and stores an invoice with its audit entry. Invoices of a clinic are created
+// one at a time so numbers have no gaps and no appointment or plan item is billed twice.
+func (repo *Repository) CreateInvoice(ctx context.Context, invoice billing.Invoice, actorID uint, at time.Time) (billing.Invoice, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", invoiceLockNamespace, int32(invoice.ClinicID)).Error; err != nil {
+			return err
+		}
+
+		var appointmentIDs, planItemIDs []uint
+		for _, line := range invoice.Lines {
+			if line.AppointmentID != nil {
+				appointmentIDs = append(appointmentIDs, *line.AppointmentID)
+			}
+			if line.PlanItemID != nil {
+				planItemIDs = append(planItemIDs, *line.PlanItemID)
+			}
+		}
+		if len(appointmentIDs) > 0 || len(planItemIDs) > 0 {
+			var billed int64
+			if err := tx.Model(&billing.Line{}).
+				Joins("JOIN invoices ON invoices.id = invoice_lines.invoice_id AND invoices.deleted_at IS NULL").
+				Where("invoices.status <> ?", billing.InvoiceVoid).
+				Where("(invoice_lines.appointment_id IN ? OR invoice_lines.plan_item_id IN ?)", nonEmpty(appointmentIDs), nonEmpty(planItemIDs)).
+				Count(&billed).Error; err != nil {
+				return err
+			}
+			if billed > 0 {
+				return billing.ErrAlreadyInvoiced
+			}
+		}
+
+		year := invoice.IssueDate[:4]
+		var issued int64
+		if err := tx.Unscoped().Model(&billing.Invoice{}).
+			Where("clinic_id = ? AND number LIKE ?", invoice.ClinicID, year+"-%").
+			Count(&issued).Error; err != nil {
+			return err
+		}
+		invoice.Number = fmt.Sprintf("%s-%06d", year, issued+1)
+
+		if err := tx.Create(&invoice).Error; err != nil {
+			return err
+		}
+
+		entry := billing.NewAuditEntry(billing.AuditInvoiceIssued, invoice, actorID, at, map[string]interface{}{
+			"number":   invoice.Number,
+			"total":    invoice.Total,
+			"currency": invoice.Currency,
+			"lines":    len(invoice.Lines),
+		})
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "CreateInvoice").
+			Err(err).
+			Uint("clinic_id", invoice.ClinicID).
+			Uint("patient_id", invoice.PatientID).
+			Msg("Failed to create invoice")
+		return billing.Invoice{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateInvoice").
+		Uint("invoice_id", invoice.ID).
+		Str("number", invoice.Number).
+		Int64("total", invoice.Total).
+		Msg("Invoice created successfully")
+
+	return repo.GetInvoice(ctx, invoice.ID)
+}
+
+// RecordPayment books a payment or refund against a locked invoice, allocates it to the installments
+// and writes its audit entry
+func (repo *Repository) RecordPayment(ctx context.Context, invoiceID uint, payment billing.Payment) (billing.Invoice, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return RecordPaymentInTransaction(tx, invoiceID, &payment)
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "RecordPayment").
+			Err(err).
+			Uint("invoice_id", invoiceID).
+			Str("kind", string(payment.Kind)).
+			Int64("amount", payment.Amount).
+			Msg("Failed to record payment")
+		return billing.Invoice{}, err
+	}
+
+	log.Info().
+		Str("operation", "RecordPayment").
+		Uint("invoice_id", invoiceID).
+		Uint("payment_id", payment.ID).
+		Str("kind", string(payment.Kind)).
+		Int64("amount", payment.Amount).
+		Msg("Payment recorded successfully")
+
+	return repo.GetInvoice(ctx, invoiceID)
+}
+
+// RecordPaymentInTransaction books a payment or refund inside a transaction owned by the caller.
+// Insurance payments count towards the insurer's share of the invoice, all others towards the patient's,
+// so other repositories can settle their own records together with the invoice atomically.
+func RecordPaymentInTransaction(tx *gorm.DB, invoiceID uint, payment *billing.Payment) error {
+	invoice, err := LockInvoice(tx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	apply := invoice.Apply
+	if payment.Method == billing.MethodInsurance {
+		apply = invoice.ApplyInsurer
+	}
+	if err := apply(payment.Kind, payment.Amount); err != nil {
+		return err
+	}
+
+	payment.InvoiceID = invoice.ID
+	payment.ClinicID = invoice.ClinicID
+	payment.PatientID = invoice.PatientID
+	if err := tx.Create(payment).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&billing.Invoice{}).
+		Where("id = ?", invoice.ID).
+		Updates(map[string]interface{}{
+			"paid_amount":  invoice.PaidAmount,
+			"insurer_paid": invoice.InsurerPaid,
+			"status":       invoice.Status,
+		}).Error; err != nil {
+		return err
+	}
+	if err := allocateInstallments(tx, invoice); err != nil {
+		return err
+	}
+
+	action := billing.AuditPaymentRecorded
+	if payment.Kind == billing.KindRefund {
+		action = billing.AuditRefundRecorded
+	}
+	entry := billing.NewAuditEntry(action, invoice, payment.RecordedByID, payment.ReceivedAt, map[string]interface{}{
+		"amount":      payment.Amount,
+		"method":      payment.Method,
+		"reference":   payment.Reference,
+		"notes":       payment.Notes,
+		"paid_amount": invoice.PaidAmount,
+		"status":      invoice.Status,
+	})
+	entry.PaymentID = &payment.ID
+	return tx.Create(&entry).Error
+}
+
+// VoidInvoice voids an invoice without payments, drops its installment plan and writes its audit entry
+func (repo *Repository) VoidInvoice(ctx context.Context, invoiceID, actorID uint, at time.Time, reason string) (billing.Invoice, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		invoice, err := LockInvoice(tx, invoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice.Status == billing.InvoiceVoid {
+			return billing.ErrInvoiceVoid
+		}
+		if invoice.PaidAmount != 0 {
+			return billing.ErrInvoiceHasPayments
+		}
+
+		if err := tx.Model(&billing.Invoice{}).
+			Where("id = ?", invoice.ID).
+			Updates(map[string]interface{}{
+				"status":       billing.InvoiceVoid,
+				"voided_at":    at,
+				"voided_by_id": actorID,
+				"void_reason":  reason,
+			}).Error; err != nil {
+			return err
+		}
+		if _, err := deleteInstallmentPlan(tx, invoice.ID); err != nil {
+			return err
+		}
+
+		entry := billing.NewAuditEntry(billing.AuditInvoiceVoided, invoice, actorID, at, map[string]interface{}{
+			"number": invoice.Number,
+			"total":  invoice.Total,
+			"reason": reason,
+		})
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "VoidInvoice").
+			Err(err).
+			Uint("invoice_id", invoiceID).
+			Msg("Failed to void invoice")
+		return billing.Invoice{}, err
+	}
+
+	log.Info().
+		Str("operation", "VoidInvoice").
+		Uint("invoice_id", invoiceID).
+		Msg("Invoice voided successfully")
+
+	return repo.GetInvoice(ctx, invoiceID)
+}
+
+// GetPatientBalances sums the patient's share of their live invoices per currency
+func (repo *Repository) GetPatientBalances(ctx context.Context, clinicID, patientID uint) ([]billing.Balance, error) {
+	var balances []billing.Balance
+	result := repo.DB.WithContext(ctx).
+		Model(&billing.Invoice{}).
+		Select(`patient_id, currency,
+			COALESCE(SUM(total - insurer_share), 0) AS invoiced,
+			COALESCE(SUM(paid_amount - insurer_paid), 0) AS paid,
+			COALESCE(SUM(total - insurer_share - paid_amount + insurer_paid), 0) AS outstanding,
+			COALESCE(SUM(insurer_share - insurer_paid), 0) AS insurer_outstanding,
+			COUNT(*) FILTER (WHERE status IN ?) AS open_invoices`, billing.UnpaidStatuses).
+		Where("clinic_id = ? AND patient_id = ? AND status <> ?", clinicID, patientID, billing.InvoiceVoid).
+		Group("patient_id, currency").
+		Order("currency").
+		Scan(&balances)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetPatientBalances").
+			Err(result.Error).
+			Uint("patient_id", patientID).
+			Msg("Failed to calculate patient balance")
+		return nil, result.Error
+	}
+	return balances, nil
+}
+
+// GetAuditTrail retrieves the audit entries of an invoice, oldest first
+func (repo *Repository) GetAuditTrail(ctx context.Context, invoiceID uint) ([]billing.AuditEntry, error) {
+	var entries []billing.AuditEntry
+	if err := repo.DB.WithContext(ctx).
+		Where("invoice_id = ?", invoiceID).
+		Order("at, id").
+		Find(&entries).Error; err != nil {
+		log.Error().
+			Str("operation", "GetAuditTrail").
+			Err(err).
+			Uint("invoice_id", invoiceID).
+			Msg("Failed to retrieve billing audit trail")
+		return nil, err
+	}
+	return entries, nil
+}
+
+// LockInvoice loads an invoice and holds its row lock until the transaction ends.
+// Other repositories use it to change an invoice together with their own records.
+func LockInvoice(tx *gorm.DB, id uint) (billing.Invoice, error) {
+	var invoice billing.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return billing.Invoice{}, billing.ErrInvoiceNotFound
+		}
+		return billing.Invoice{}, err
+	}
+	return invoice, nil
+}
+
+// nonEmpty keeps IN clauses valid when a list has no IDs
+func nonEmpty(ids []uint) []uint {
+	if len(ids) == 0 {
+		return []uint{0}
+	}
+	return ids
+}
@@ -0,0 +1,307 @@
+package billingRepository
+
+import (
+	"context"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/patient"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetInstallmentPlan retrieves the installment plan of an invoice
+func (repo *Repository) GetInstallmentPlan(ctx context.Context, invoiceID uint) (billing.InstallmentPlan, error) {
+	var plan billing.InstallmentPlan
+	result := repo.DB.WithContext(ctx).
+		Preload("Installments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sequence")
+		}).
+		Where("invoice_id = ?", invoiceID).
+		First(&plan)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return billing.InstallmentPlan{}, billing.ErrInstallmentPlanNotFound
+		}
+		log.Error().
+			Str("operation", "GetInstallmentPlan").
+			Err(result.Error).
+			Uint("invoice_id", invoiceID).
+			Msg("Failed to retrieve installment plan")
+		return billing.InstallmentPlan{}, result.Error
+	}
+	return plan, nil
+}
+
+// CreateInstallmentPlan attaches an installment plan to a locked invoice. The installments must add up
+// to the patient's balance at that moment; what the patient paid before is kept as the plan's down payment.
+func (repo *Repository) CreateInstallmentPlan(ctx context.Context, plan billing.InstallmentPlan, actorID uint, at time.Time) (billing.InstallmentPlan, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		invoice, err := LockInvoice(tx, plan.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice.Status == billing.InvoiceVoid {
+			return billing.ErrInvoiceVoid
+		}
+		invoice.Resolve()
+		balance := invoice.PatientBalance
+		if balance <= 0 {
+			return billing.ErrInvoiceSettled
+		}
+		if plan.Total != balance {
+			return fmt.Errorf("%w: installments add up to %d but the invoice balance is %d", billing.ErrInstallmentValidation, plan.Total, balance)
+		}
+
+		var existing int64
+		if err := tx.Model(&billing.InstallmentPlan{}).Where("invoice_id = ?", invoice.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return billing.ErrInstallmentPlanExists
+		}
+
+		plan.ClinicID = invoice.ClinicID
+		plan.PatientID = invoice.PatientID
+		plan.Currency = invoice.Currency
+		plan.DownPayment = invoice.PatientPaid()
+		for i := range plan.Installments {
+			plan.Installments[i].InvoiceID = invoice.ID
+			plan.Installments[i].ClinicID = invoice.ClinicID
+			plan.Installments[i].PatientID = invoice.PatientID
+		}
+		if err := tx.Create(&plan).Error; err != nil {
+			return err
+		}
+
+		entry := billing.NewAuditEntry(billing.AuditInstallmentsScheduled, invoice, actorID, at, map[string]interface{}{
+			"installments": len(plan.Installments),
+			"total":        plan.Total,
+			"down_payment": plan.DownPayment,
+			"first_due":    plan.Installments[0].DueDate,
+			"last_due":     plan.Installments[len(plan.Installments)-1].DueDate,
+		})
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "CreateInstallmentPlan").
+			Err(err).
+			Uint("invoice_id", plan.InvoiceID).
+			Msg("Failed to create installment plan")
+		return billing.InstallmentPlan{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateInstallmentPlan").
+		Uint("invoice_id", plan.InvoiceID).
+		Uint("plan_id", plan.ID).
+		Int("installments", len(plan.Installments)).
+		Msg("Installment plan created successfully")
+
+	return repo.GetInstallmentPlan(ctx, plan.InvoiceID)
+}
+
+// DeleteInstallmentPlan removes the installment plan of an invoice and writes its audit entry
+func (repo *Repository) DeleteInstallmentPlan(ctx context.Context, invoiceID, actorID uint, at time.Time) error {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		invoice, err := LockInvoice(tx, invoiceID)
+		if err != nil {
+			return err
+		}
+
+		removed, err := deleteInstallmentPlan(tx, invoice.ID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return billing.ErrInstallmentPlanNotFound
+		}
+
+		entry := billing.NewAuditEntry(billing.AuditInstallmentsCancelled, invoice, actorID, at, map[string]interface{}{
+			"paid_amount": invoice.PaidAmount,
+		})
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "DeleteInstallmentPlan").
+			Err(err).
+			Uint("invoice_id", invoiceID).
+			Msg("Failed to delete installment plan")
+		return err
+	}
+
+	log.Info().
+		Str("operation", "DeleteInstallmentPlan").
+		Uint("invoice_id", invoiceID).
+		Msg("Installment plan deleted successfully")
+	return nil
+}
+
+// GetInstallmentClinics retrieves the clinics that have installments still to be paid
+func (repo *Repository) GetInstallmentClinics(ctx context.Context) ([]clinic.Clinic, error) {
+	var clinics []clinic.Clinic
+	if err := repo.DB.WithContext(ctx).
+		Where("id IN (?)", repo.DB.Model(&billing.Installment{}).
+			Distinct("clinic_id").
+			Where("status IN ?", billing.OpenInstallmentStatuses)).
+		Find(&clinics).Error; err != nil {
+		log.Error().
+			Str("operation", "GetInstallmentClinics").
+			Err(err).
+			Msg("Failed to retrieve clinics with open installments")
+		return nil, err
+	}
+	return clinics, nil
+}
+
+// MarkOverdueInstallments flags the pending installments of a clinic that were due before today
+func (repo *Repository) MarkOverdueInstallments(ctx context.Context, clinicID uint, today string) (int64, error) {
+	result := repo.DB.WithContext(ctx).
+		Model(&billing.Installment{}).
+		Where("clinic_id = ? AND status = ? AND due_date < ?", clinicID, billing.InstallmentPending, today).
+		Update("status", billing.InstallmentOverdue)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "MarkOverdueInstallments").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to mark overdue installments")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// GetOverdueInstallments retrieves the unpaid installments of a clinic that were due before the given date,
+// with the invoice, patient and clinic details, oldest first
+func (repo *Repository) GetOverdueInstallments(ctx context.Context, clinicID uint, before string) ([]billing.OverdueInstallment, error) {
+	var rows []struct {
+		billing.OverdueInstallment
+		ContactInfo string
+	}
+	result := repo.DB.WithContext(ctx).
+		Model(&billing.Installment{}).
+		Select(`invoice_installments.id AS installment_id, invoice_installments.invoice_id, invoice_installments.clinic_id,
+			invoice_installments.patient_id, invoice_installments.sequence, invoice_installments.due_date,
+			invoice_installments.amount, invoice_installments.paid_amount, invoice_installments.status,
+			invoice_installments.last_reminder_date,
+			(SELECT COUNT(*) FROM invoice_installments AS siblings
+				WHERE siblings.plan_id = invoice_installments.plan_id AND siblings.deleted_at IS NULL) AS installments,
+			invoices.number AS invoice_number, invoices.currency,
+			patients.name AS patient_name, patients.contact_info,
+			clinics.name AS clinic_name, clinics.phone_number AS clinic_phone`).
+		Joins("JOIN invoices ON invoices.id = invoice_installments.invoice_id AND invoices.deleted_at IS NULL").
+		Joins("JOIN clinics ON clinics.id = invoice_installments.clinic_id").
+		Joins("LEFT JOIN patients ON patients.id = invoice_installments.patient_id").
+		Where("invoice_installments.clinic_id = ?", clinicID).
+		Where("invoice_installments.status IN ?", billing.OpenInstallmentStatuses).
+		Where("invoice_installments.due_date < ?", before).
+		Where("invoices.status <> ?", billing.InvoiceVoid).
+		Order("invoice_installments.due_date, invoice_installments.id").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetOverdueInstallments").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve overdue installments")
+		return nil, result.Error
+	}
+
+	overdue := make([]billing.OverdueInstallment, len(rows))
+	for i, row := range rows {
+		overdue[i] = row.OverdueInstallment
+		pt := patient.Patient{ContactInfo: row.ContactInfo}
+		if overdue[i].PatientEmail = pt.Email(); overdue[i].PatientEmail == "" {
+			overdue[i].PatientPhone = strings.TrimSpace(row.ContactInfo)
+		}
+	}
+	return overdue, nil
+}
+
+// ClaimInstallmentReminder records today's reminder of an installment. It reports false when another
+// run has already reminded the patient since previous was read, so each reminder is sent once.
+func (repo *Repository) ClaimInstallmentReminder(ctx context.Context, installmentID uint, previous, today string) (bool, error) {
+	result := repo.DB.WithContext(ctx).
+		Model(&billing.Installment{}).
+		Where("id = ? AND last_reminder_date = ?", installmentID, previous).
+		Updates(map[string]interface{}{
+			"last_reminder_date": today,
+			"reminder_count":     gorm.Expr("reminder_count + 1"),
+		})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "ClaimInstallmentReminder").
+			Err(result.Error).
+			Uint("installment_id", installmentID).
+			Msg("Failed to record installment reminder")
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseInstallmentReminder undoes a claimed reminder so it is retried on the next run
+func (repo *Repository) ReleaseInstallmentReminder(ctx context.Context, installmentID uint, previous, today string) error {
+	result := repo.DB.WithContext(ctx).
+		Model(&billing.Installment{}).
+		Where("id = ? AND last_reminder_date = ?", installmentID, today).
+		Updates(map[string]interface{}{
+			"last_reminder_date": previous,
+			"reminder_count":     gorm.Expr("reminder_count - 1"),
+		})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "ReleaseInstallmentReminder").
+			Err(result.Error).
+			Uint("installment_id", installmentID).
+			Msg("Failed to release installment reminder")
+		return result.Error
+	}
+	return nil
+}
+
+// allocateInstallments spreads what the patient paid on an invoice over its installment plan, if it has one
+func allocateInstallments(tx *gorm.DB, invoice billing.Invoice) error {
+	var plan billing.InstallmentPlan
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("invoice_id = ?", invoice.ID).
+		First(&plan).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Where("plan_id = ?", plan.ID).Order("sequence").Find(&plan.Installments).Error; err != nil {
+		return err
+	}
+	plan.Allocate(invoice.PatientPaid())
+
+	for _, inst := range plan.Installments {
+		if err := tx.Model(&billing.Installment{}).
+			Where("id = ?", inst.ID).
+			Updates(map[string]interface{}{
+				"paid_amount": inst.PaidAmount,
+				"status":      inst.Status,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteInstallmentPlan removes the installment plan of an invoice with its installments
+func deleteInstallmentPlan(tx *gorm.DB, invoiceID uint) (bool, error) {
+	if err := tx.Where("invoice_id = ?", invoiceID).Delete(&billing.Installment{}).Error; err != nil {
+		return false, err
+	}
+	result := tx.Where("invoice_id = ?", invoiceID).Delete(&billing.InstallmentPlan{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,60 @@
+package billingRepository
+
+import (
+	"context"
+	"dental-clinic-system/infrastructure/postgres/postgrestest"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"fmt"
+	"testing"
+)
+
+func TestGetOverdueInstallments_PatientContact(t *testing.T) {
+	db := postgrestest.Open(t)
+	repo := NewRepository(db)
+
+	cln := clinic.Clinic{Name: "Gülüş Diş", PhoneNumber: "02120000000", Email: "info@example.com"}
+	if err := db.Create(&cln).Error; err != nil {
+		t.Fatal(err)
+	}
+	// A staff account with the same ID as the patient must not receive the patient's reminders
+	staff := user.User{NationalID: "10000000001", Email: "staff@example.com", FirstName: "Personel", PhoneNumber: "5320000001", ClinicID: cln.ID}
+	staff.ID = 7
+	ayse := patient.Patient{NationalID: "10000000146", Name: "Ayşe Yılmaz", ContactInfo: " ayse@example.com ", ClinicID: cln.ID}
+	ayse.ID = 7
+	mehmet := patient.Patient{NationalID: "10000000164", Name: "Mehmet Öz", ContactInfo: "0532 123 45 67", ClinicID: cln.ID}
+	mehmet.ID = 8
+	for _, record := range []interface{}{&staff, &ayse, &mehmet} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, pt := range []patient.Patient{ayse, mehmet} {
+		invoice := billing.Invoice{ClinicID: cln.ID, PatientID: pt.ID, Number: fmt.Sprintf("2026-%06d", i+1), IssueDate: "2026-01-01", Total: 50000}
+		if err := db.Create(&invoice).Error; err != nil {
+			t.Fatal(err)
+		}
+		plan := billing.InstallmentPlan{InvoiceID: invoice.ID, ClinicID: cln.ID, PatientID: pt.ID, Currency: "TRY", Total: 50000,
+			Installments: []billing.Installment{{InvoiceID: invoice.ID, ClinicID: cln.ID, PatientID: pt.ID, Sequence: 1, DueDate: "2026-02-01", Amount: 50000}}}
+		if err := db.Create(&plan).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	overdue, err := repo.GetOverdueInstallments(context.Background(), cln.ID, "2026-03-01")
+	if err != nil {
+		t.Fatalf("GetOverdueInstallments() error = %v", err)
+	}
+	if len(overdue) != 2 {
+		t.Fatalf("got %d overdue installments, want 2", len(overdue))
+	}
+	if got := overdue[0]; got.PatientName != "Ayşe Yılmaz" || got.PatientEmail != "ayse@example.com" || got.PatientPhone != "" {
+		t.Errorf("first installment patient = %q, %q, %q; want the patient record's name and email", got.PatientName, got.PatientEmail, got.PatientPhone)
+	}
+	if got := overdue[1]; got.PatientName != "Mehmet Öz" || got.PatientEmail != "" || got.PatientPhone != "0532 123 45 67" {
+		t.Errorf("second installment patient = %q, %q, %q; want the patient record's name and phone", got.PatientName, got.PatientEmail, got.PatientPhone)
+	}
+}
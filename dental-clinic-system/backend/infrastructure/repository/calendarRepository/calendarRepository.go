@@ -0,0 +1,130 @@
+package calendarRepository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dental-clinic-system/models/calendar"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles calendar feed token database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// CreateFeedToken stores a new feed token
+func (repo *Repository) CreateFeedToken(ctx context.Context, token calendar.FeedToken) (calendar.FeedToken, error) {
+	result := repo.DB.WithContext(ctx).Create(&token)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "CreateFeedToken").
+			Err(result.Error).
+			Uint("doctor_id", token.DoctorID).
+			Msg("Failed to create calendar feed token")
+		return calendar.FeedToken{}, result.Error
+	}
+
+	log.Info().
+		Str("operation", "CreateFeedToken").
+		Uint("doctor_id", token.DoctorID).
+		Uint("token_id", token.ID).
+		Msg("Calendar feed token created successfully")
+
+	return token, nil
+}
+
+// GetFeedTokens retrieves all feed tokens of a doctor, including revoked ones
+func (repo *Repository) GetFeedTokens(ctx context.Context, doctorID uint) ([]calendar.FeedToken, error) {
+	var tokens []calendar.FeedToken
+	result := repo.DB.WithContext(ctx).
+		Where("doctor_id = ?", doctorID).
+		Order("created_at DESC").
+		Find(&tokens)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetFeedTokens").
+			Err(result.Error).
+			Uint("doctor_id", doctorID).
+			Msg("Failed to retrieve calendar feed tokens")
+		return nil, result.Error
+	}
+	return tokens, nil
+}
+
+// GetFeedToken retrieves a feed token by its ID
+func (repo *Repository) GetFeedToken(ctx context.Context, id uint) (calendar.FeedToken, error) {
+	var token calendar.FeedToken
+	result := repo.DB.WithContext(ctx).First(&token, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return calendar.FeedToken{}, calendar.ErrFeedTokenNotFound
+		}
+		log.Error().
+			Str("operation", "GetFeedToken").
+			Err(result.Error).
+			Uint("token_id", id).
+			Msg("Failed to retrieve calendar feed token")
+		return calendar.FeedToken{}, result.Error
+	}
+	return token, nil
+}
+
+// GetActiveFeedTokenByHash retrieves a token that has not been revoked by the hash of its value
+func (repo *Repository) GetActiveFeedTokenByHash(ctx context.Context, tokenHash string) (calendar.FeedToken, error) {
+	var token calendar.FeedToken
+	result := repo.DB.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL", tokenHash).
+		First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return calendar.FeedToken{}, calendar.ErrFeedTokenNotFound
+		}
+		log.Error().
+			Str("operation", "GetActiveFeedTokenByHash").
+			Err(result.Error).
+			Msg("Failed to retrieve calendar feed token")
+		return calendar.FeedToken{}, result.Error
+	}
+	return token, nil
+}
+
+// TouchFeedToken records when a feed token was last used
+func (repo *Repository) TouchFeedToken(ctx context.Context, id uint, usedAt time.Time) error {
+	return repo.DB.WithContext(ctx).
+		Model(&calendar.FeedToken{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", usedAt).Error
+}
+
+// RevokeFeedToken marks a feed token as revoked
+func (repo *Repository) RevokeFeedToken(ctx context.Context, id uint, revokedAt time.Time) error {
+	result := repo.DB.WithContext(ctx).
+		Model(&calendar.FeedToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "RevokeFeedToken").
+			Err(result.Error).
+			Uint("token_id", id).
+			Msg("Failed to revoke calendar feed token")
+		return result.Error
+	}
+
+	log.Info().
+		Str("operation", "RevokeFeedToken").
+		Uint("token_id", id).
+		Msg("Calendar feed token revoked successfully")
+
+	return nil
+}
@@ -83,9 +83,9 @@ func (repo *Repository) CreateClinic(ctx context.Context, newCln clinic.Clinic)
 	return newCln, nil
 }
 
-// UpdateClinic updates an existing clinic record in the database
+// UpdateClinic updates an existing clinic record in the database. The logo is left untouched; it is changed with UpdateLogo.
 func (repo *Repository) UpdateClinic(ctx context.Context, updatedCln clinic.Clinic) (clinic.Clinic, error) {
-	result := repo.DB.WithContext(ctx).Save(&updatedCln)
+	result := repo.DB.WithContext(ctx).Omit("Logo", "LogoContentType").Save(&updatedCln)
 	if result.Error != nil {
 		log.Error().
 			Str("operation", "UpdateClinic").
@@ -101,6 +101,35 @@ func (repo *Repository) UpdateClinic(ctx context.Context, updatedCln clinic.Clin
 	return updatedCln, nil
 }
 
+// UpdateLogo stores or, with empty data, removes the logo of a clinic
+func (repo *Repository) UpdateLogo(ctx context.Context, id uint, data []byte, contentType string) error {
+	result := repo.DB.WithContext(ctx).
+		Model(&clinic.Clinic{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"logo": data, "logo_content_type": contentType})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "UpdateLogo").
+			Err(result.Error).
+			Uint("clinic_id", id).
+			Msg("Failed to update clinic logo")
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		log.Warn().
+			Str("operation", "UpdateLogo").
+			Uint("clinic_id", id).
+			Msg("Clinic not found")
+		return clinic.ErrClinicNotFound
+	}
+	log.Info().
+		Str("operation", "UpdateLogo").
+		Uint("clinic_id", id).
+		Int("size", len(data)).
+		Msg("Clinic logo updated successfully")
+	return nil
+}
+
 // DeleteClinic deletes a clinic record from the database by its ID
 func (repo *Repository) DeleteClinic(ctx context.Context, id uint) error {
 	result := repo.DB.WithContext(ctx).Delete(&clinic.Clinic{}, id)
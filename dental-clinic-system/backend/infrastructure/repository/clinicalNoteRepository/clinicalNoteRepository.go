@@ -0,0 +1,351 @@
+package clinicalNoteRepository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dental-clinic-system/models/clinicalnote"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles clinical note database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// withSections preloads the sections of templates in display order
+func withSections(db *gorm.DB) *gorm.DB {
+	return db.Preload("Sections", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	})
+}
+
+// withContent preloads the sections and addenda of notes in display order
+func withContent(db *gorm.DB) *gorm.DB {
+	return withSections(db).Preload("Addenda", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	})
+}
+
+// GetTemplates retrieves the templates of a clinic by name
+func (repo *Repository) GetTemplates(ctx context.Context, clinicID uint) ([]clinicalnote.Template, error) {
+	var templates []clinicalnote.Template
+	result := withSections(repo.DB.WithContext(ctx)).
+		Where("clinic_id = ?", clinicID).
+		Order("name, id").
+		Find(&templates)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetTemplates").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve clinical note templates")
+		return nil, result.Error
+	}
+	return templates, nil
+}
+
+// GetTemplate retrieves a template with its sections
+func (repo *Repository) GetTemplate(ctx context.Context, id uint) (clinicalnote.Template, error) {
+	var template clinicalnote.Template
+	result := withSections(repo.DB.WithContext(ctx)).First(&template, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return clinicalnote.Template{}, clinicalnote.ErrTemplateNotFound
+		}
+		log.Error().
+			Str("operation", "GetTemplate").
+			Err(result.Error).
+			Uint("template_id", id).
+			Msg("Failed to retrieve clinical note template")
+		return clinicalnote.Template{}, result.Error
+	}
+	return template, nil
+}
+
+// CreateTemplates stores templates together with their sections
+func (repo *Repository) CreateTemplates(ctx context.Context, templates []clinicalnote.Template) ([]clinicalnote.Template, error) {
+	if err := repo.DB.WithContext(ctx).Create(&templates).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateTemplates").
+			Err(err).
+			Msg("Failed to create clinical note templates")
+		return nil, err
+	}
+
+	log.Info().
+		Str("operation", "CreateTemplates").
+		Int("count", len(templates)).
+		Msg("Clinical note templates created successfully")
+
+	return templates, nil
+}
+
+// ReplaceTemplate overwrites the details and sections of a template
+func (repo *Repository) ReplaceTemplate(ctx context.Context, template clinicalnote.Template) (clinicalnote.Template, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&clinicalnote.Template{}).
+			Where("id = ?", template.ID).
+			Updates(map[string]interface{}{
+				"name":        template.Name,
+				"description": template.Description,
+				"active":      template.Active,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return clinicalnote.ErrTemplateNotFound
+		}
+
+		if err := tx.Unscoped().Where("template_id = ?", template.ID).Delete(&clinicalnote.TemplateSection{}).Error; err != nil {
+			return err
+		}
+		for i := range template.Sections {
+			template.Sections[i].ID = 0
+			template.Sections[i].TemplateID = template.ID
+		}
+		return tx.Create(&template.Sections).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "ReplaceTemplate").
+			Err(err).
+			Uint("template_id", template.ID).
+			Msg("Failed to update clinical note template")
+		return clinicalnote.Template{}, err
+	}
+
+	log.Info().
+		Str("operation", "ReplaceTemplate").
+		Uint("template_id", template.ID).
+		Msg("Clinical note template updated successfully")
+
+	return repo.GetTemplate(ctx, template.ID)
+}
+
+// GetNotes retrieves the notes of a clinic, newest first, optionally filtered by patient and appointment
+func (repo *Repository) GetNotes(ctx context.Context, clinicID, patientID, appointmentID uint) ([]clinicalnote.Note, error) {
+	var notes []clinicalnote.Note
+	query := withContent(repo.DB.WithContext(ctx)).
+		Where("clinic_id = ?", clinicID).
+		Order("created_at DESC, id DESC")
+	if patientID != 0 {
+		query = query.Where("patient_id = ?", patientID)
+	}
+	if appointmentID != 0 {
+		query = query.Where("appointment_id = ?", appointmentID)
+	}
+
+	if err := query.Find(&notes).Error; err != nil {
+		log.Error().
+			Str("operation", "GetNotes").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve clinical notes")
+		return nil, err
+	}
+	return notes, nil
+}
+
+// GetNote retrieves a note with its sections and addenda
+func (repo *Repository) GetNote(ctx context.Context, id uint) (clinicalnote.Note, error) {
+	var note clinicalnote.Note
+	result := withContent(repo.DB.WithContext(ctx)).First(&note, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return clinicalnote.Note{}, clinicalnote.ErrNoteNotFound
+		}
+		log.Error().
+			Str("operation", "GetNote").
+			Err(result.Error).
+			Uint("note_id", id).
+			Msg("Failed to retrieve clinical note")
+		return clinicalnote.Note{}, result.Error
+	}
+	return note, nil
+}
+
+// CreateNote stores a draft note together with its sections
+func (repo *Repository) CreateNote(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error) {
+	if err := repo.DB.WithContext(ctx).Create(&note).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateNote").
+			Err(err).
+			Uint("appointment_id", note.AppointmentID).
+			Msg("Failed to create clinical note")
+		return clinicalnote.Note{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateNote").
+		Uint("note_id", note.ID).
+		Uint("appointment_id", note.AppointmentID).
+		Msg("Clinical note created successfully")
+
+	return repo.GetNote(ctx, note.ID)
+}
+
+// ReplaceDraft overwrites the title and sections of a note that is still a draft
+func (repo *Repository) ReplaceDraft(ctx context.Context, note clinicalnote.Note) (clinicalnote.Note, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockNote(tx, note.ID); err != nil {
+			return err
+		}
+
+		result := tx.Model(&clinicalnote.Note{}).
+			Where("id = ? AND status = ?", note.ID, clinicalnote.StatusDraft).
+			Update("title", note.Title)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return clinicalnote.ErrNoteSigned
+		}
+
+		if err := tx.Unscoped().Where("note_id = ?", note.ID).Delete(&clinicalnote.Section{}).Error; err != nil {
+			return err
+		}
+		for i := range note.Sections {
+			note.Sections[i].ID = 0
+			note.Sections[i].NoteID = note.ID
+		}
+		return tx.Create(&note.Sections).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "ReplaceDraft").
+			Err(err).
+			Uint("note_id", note.ID).
+			Msg("Failed to update clinical note")
+		return clinicalnote.Note{}, err
+	}
+
+	log.Info().
+		Str("operation", "ReplaceDraft").
+		Uint("note_id", note.ID).
+		Msg("Clinical note updated successfully")
+
+	return repo.GetNote(ctx, note.ID)
+}
+
+// SignNote signs a draft note for its author. The note is locked while its content is checked and hashed,
+// so the signed hash always matches the stored sections.
+func (repo *Repository) SignNote(ctx context.Context, id, signerID uint, at time.Time) (clinicalnote.Note, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		note, err := lockNote(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", id).Order("position").Find(&note.Sections).Error; err != nil {
+			return err
+		}
+		if err := note.CanSign(signerID); err != nil {
+			return err
+		}
+
+		return tx.Model(&clinicalnote.Note{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"status":       clinicalnote.StatusSigned,
+				"signed_at":    at,
+				"content_hash": note.Hash(),
+			}).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "SignNote").
+			Err(err).
+			Uint("note_id", id).
+			Msg("Failed to sign clinical note")
+		return clinicalnote.Note{}, err
+	}
+
+	log.Info().
+		Str("operation", "SignNote").
+		Uint("note_id", id).
+		Uint("signed_by_id", signerID).
+		Msg("Clinical note signed successfully")
+
+	return repo.GetNote(ctx, id)
+}
+
+// DeleteDraft removes a note that was never signed
+func (repo *Repository) DeleteDraft(ctx context.Context, id uint) error {
+	result := repo.DB.WithContext(ctx).
+		Where("id = ? AND status = ?", id, clinicalnote.StatusDraft).
+		Delete(&clinicalnote.Note{})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "DeleteDraft").
+			Err(result.Error).
+			Uint("note_id", id).
+			Msg("Failed to delete clinical note")
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := repo.GetNote(ctx, id); err != nil {
+			return err
+		}
+		return clinicalnote.ErrNoteSigned
+	}
+
+	log.Info().
+		Str("operation", "DeleteDraft").
+		Uint("note_id", id).
+		Msg("Clinical note deleted successfully")
+
+	return nil
+}
+
+// CreateAddendum appends an addendum to a signed note
+func (repo *Repository) CreateAddendum(ctx context.Context, addendum clinicalnote.Addendum) (clinicalnote.Addendum, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		note, err := lockNote(tx, addendum.NoteID)
+		if err != nil {
+			return err
+		}
+		if note.Status != clinicalnote.StatusSigned {
+			return clinicalnote.ErrNoteNotSigned
+		}
+		return tx.Create(&addendum).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "CreateAddendum").
+			Err(err).
+			Uint("note_id", addendum.NoteID).
+			Msg("Failed to add addendum to clinical note")
+		return clinicalnote.Addendum{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateAddendum").
+		Uint("addendum_id", addendum.ID).
+		Uint("note_id", addendum.NoteID).
+		Msg("Clinical note addendum added successfully")
+
+	return addendum, nil
+}
+
+// lockNote loads a note and locks its row until the transaction ends
+func lockNote(tx *gorm.DB, id uint) (clinicalnote.Note, error) {
+	var note clinicalnote.Note
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&note, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return clinicalnote.Note{}, clinicalnote.ErrNoteNotFound
+		}
+		return clinicalnote.Note{}, err
+	}
+	return note, nil
+}
@@ -0,0 +1,343 @@
+package consentRepository
+
+import (
+	"context"
+	"errors"
+
+	"dental-clinic-system/models/consent"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles consent template and form database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetTemplates retrieves the templates of a clinic by name
+func (repo *Repository) GetTemplates(ctx context.Context, clinicID uint) ([]consent.Template, error) {
+	var templates []consent.Template
+	result := repo.DB.WithContext(ctx).
+		Preload("Procedures").
+		Where("clinic_id = ?", clinicID).
+		Order("name, id").
+		Find(&templates)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetTemplates").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve consent templates")
+		return nil, result.Error
+	}
+	return templates, nil
+}
+
+// GetTemplate retrieves a template with its procedures
+func (repo *Repository) GetTemplate(ctx context.Context, id uint) (consent.Template, error) {
+	var template consent.Template
+	result := repo.DB.WithContext(ctx).Preload("Procedures").First(&template, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return consent.Template{}, consent.ErrTemplateNotFound
+		}
+		log.Error().
+			Str("operation", "GetTemplate").
+			Err(result.Error).
+			Uint("template_id", id).
+			Msg("Failed to retrieve consent template")
+		return consent.Template{}, result.Error
+	}
+	return template, nil
+}
+
+// CreateTemplate stores a new template with its procedures as version 1
+func (repo *Repository) CreateTemplate(ctx context.Context, template consent.Template, createdByID uint) (consent.Template, error) {
+	template.Version = 1
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		return tx.Create(&consent.TemplateVersion{
+			TemplateID:  template.ID,
+			Version:     template.Version,
+			Title:       template.Title,
+			Body:        template.Body,
+			CreatedByID: createdByID,
+		}).Error
+	})
+	if err != nil {
+		log.Error().
+			Str("operation", "CreateTemplate").
+			Err(err).
+			Uint("clinic_id", template.ClinicID).
+			Msg("Failed to create consent template")
+		return consent.Template{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateTemplate").
+		Uint("template_id", template.ID).
+		Msg("Consent template created successfully")
+
+	return template, nil
+}
+
+// UpdateTemplate overwrites the details and procedures of a template. When its title or body
+// changes the template moves to a new version and the new text is kept as that version.
+func (repo *Repository) UpdateTemplate(ctx context.Context, template consent.Template, editorID uint) (consent.Template, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current consent.Template
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, template.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return consent.ErrTemplateNotFound
+			}
+			return err
+		}
+
+		version := current.Version
+		if current.Title != template.Title || current.Body != template.Body {
+			version++
+			err := tx.Create(&consent.TemplateVersion{
+				TemplateID:  template.ID,
+				Version:     version,
+				Title:       template.Title,
+				Body:        template.Body,
+				CreatedByID: editorID,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&consent.Template{}).
+			Where("id = ?", template.ID).
+			Updates(map[string]interface{}{
+				"name":    template.Name,
+				"active":  template.Active,
+				"version": version,
+				"title":   template.Title,
+				"body":    template.Body,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("template_id = ?", template.ID).Delete(&consent.TemplateProcedure{}).Error; err != nil {
+			return err
+		}
+		if len(template.Procedures) == 0 {
+			return nil
+		}
+		for i := range template.Procedures {
+			template.Procedures[i].ID = 0
+			template.Procedures[i].TemplateID = template.ID
+		}
+		return tx.Create(&template.Procedures).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "UpdateTemplate").
+			Err(err).
+			Uint("template_id", template.ID).
+			Msg("Failed to update consent template")
+		return consent.Template{}, err
+	}
+
+	log.Info().
+		Str("operation", "UpdateTemplate").
+		Uint("template_id", template.ID).
+		Msg("Consent template updated successfully")
+
+	return repo.GetTemplate(ctx, template.ID)
+}
+
+// GetTemplateVersions retrieves every version of a template, newest first
+func (repo *Repository) GetTemplateVersions(ctx context.Context, templateID uint) ([]consent.TemplateVersion, error) {
+	var versions []consent.TemplateVersion
+	result := repo.DB.WithContext(ctx).
+		Where("template_id = ?", templateID).
+		Order("version DESC").
+		Find(&versions)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetTemplateVersions").
+			Err(result.Error).
+			Uint("template_id", templateID).
+			Msg("Failed to retrieve consent template versions")
+		return nil, result.Error
+	}
+	return versions, nil
+}
+
+// GetRequiredTemplates retrieves the clinic's active templates that must be signed before the procedure is started
+func (repo *Repository) GetRequiredTemplates(ctx context.Context, clinicID, procedureID uint) ([]consent.Template, error) {
+	var templates []consent.Template
+	result := repo.DB.WithContext(ctx).
+		Where("clinic_id = ? AND active", clinicID).
+		Where("id IN (?)", repo.DB.Model(&consent.TemplateProcedure{}).Select("template_id").Where("procedure_id = ?", procedureID)).
+		Order("name, id").
+		Find(&templates)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetRequiredTemplates").
+			Err(result.Error).
+			Uint("procedure_id", procedureID).
+			Msg("Failed to retrieve required consent templates")
+		return nil, result.Error
+	}
+	return templates, nil
+}
+
+// GetSignedForms retrieves the patient's signed forms for a procedure, newest first
+func (repo *Repository) GetSignedForms(ctx context.Context, patientID, procedureID uint) ([]consent.Form, error) {
+	var forms []consent.Form
+	result := repo.DB.WithContext(ctx).
+		Omit("signature").
+		Where("patient_id = ? AND procedure_id = ? AND status = ?", patientID, procedureID, consent.StatusSigned).
+		Order("signed_at DESC, id DESC").
+		Find(&forms)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetSignedForms").
+			Err(result.Error).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve signed consent forms")
+		return nil, result.Error
+	}
+	return forms, nil
+}
+
+// GetForms retrieves the forms of a patient, newest first, without their signature images
+func (repo *Repository) GetForms(ctx context.Context, clinicID, patientID uint) ([]consent.Form, error) {
+	var forms []consent.Form
+	result := repo.DB.WithContext(ctx).
+		Omit("signature").
+		Where("clinic_id = ? AND patient_id = ?", clinicID, patientID).
+		Order("created_at DESC, id DESC").
+		Find(&forms)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetForms").
+			Err(result.Error).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve consent forms")
+		return nil, result.Error
+	}
+	return forms, nil
+}
+
+// GetForm retrieves a form by its ID
+func (repo *Repository) GetForm(ctx context.Context, id uint) (consent.Form, error) {
+	var form consent.Form
+	result := repo.DB.WithContext(ctx).First(&form, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return consent.Form{}, consent.ErrFormNotFound
+		}
+		log.Error().
+			Str("operation", "GetForm").
+			Err(result.Error).
+			Uint("form_id", id).
+			Msg("Failed to retrieve consent form")
+		return consent.Form{}, result.Error
+	}
+	return form, nil
+}
+
+// CreateForm stores a form prepared for signing
+func (repo *Repository) CreateForm(ctx context.Context, form consent.Form) (consent.Form, error) {
+	if err := repo.DB.WithContext(ctx).Create(&form).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateForm").
+			Err(err).
+			Uint("patient_id", form.PatientID).
+			Msg("Failed to create consent form")
+		return consent.Form{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateForm").
+		Uint("form_id", form.ID).
+		Uint("patient_id", form.PatientID).
+		Msg("Consent form created successfully")
+
+	return form, nil
+}
+
+// SignForm records the signature of a pending form and the PDF it was frozen as
+func (repo *Repository) SignForm(ctx context.Context, form consent.Form) (consent.Form, error) {
+	result := repo.DB.WithContext(ctx).
+		Model(&consent.Form{}).
+		Where("id = ? AND status = ?", form.ID, consent.StatusPending).
+		Updates(map[string]interface{}{
+			"status":         consent.StatusSigned,
+			"signer_name":    form.SignerName,
+			"signature_type": form.SignatureType,
+			"signature":      form.Signature,
+			"signed_at":      form.SignedAt,
+			"signed_ip":      form.SignedIP,
+			"witness_id":     form.WitnessID,
+			"content_hash":   form.ContentHash,
+			"attachment_id":  form.AttachmentID,
+			"document_hash":  form.DocumentHash,
+		})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "SignForm").
+			Err(result.Error).
+			Uint("form_id", form.ID).
+			Msg("Failed to sign consent form")
+		return consent.Form{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := repo.GetForm(ctx, form.ID); err != nil {
+			return consent.Form{}, err
+		}
+		return consent.Form{}, consent.ErrFormSigned
+	}
+
+	log.Info().
+		Str("operation", "SignForm").
+		Uint("form_id", form.ID).
+		Uint("patient_id", form.PatientID).
+		Msg("Consent form signed successfully")
+
+	return repo.GetForm(ctx, form.ID)
+}
+
+// DeletePendingForm removes a form that was never signed
+func (repo *Repository) DeletePendingForm(ctx context.Context, id uint) error {
+	result := repo.DB.WithContext(ctx).
+		Where("id = ? AND status = ?", id, consent.StatusPending).
+		Delete(&consent.Form{})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "DeletePendingForm").
+			Err(result.Error).
+			Uint("form_id", id).
+			Msg("Failed to delete consent form")
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := repo.GetForm(ctx, id); err != nil {
+			return err
+		}
+		return consent.ErrFormSigned
+	}
+
+	log.Info().
+		Str("operation", "DeletePendingForm").
+		Uint("form_id", id).
+		Msg("Consent form deleted successfully")
+
+	return nil
+}
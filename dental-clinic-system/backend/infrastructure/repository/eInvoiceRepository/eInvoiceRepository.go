@@ -0,0 +1,112 @@
+package eInvoiceRepository
+
+import (
+	"context"
+	"dental-clinic-system/models/einvoice"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository handles e-invoice submission database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetSubmission retrieves the submission of an invoice
+func (repo *Repository) GetSubmission(ctx context.Context, invoiceID uint) (einvoice.Submission, error) {
+	var submission einvoice.Submission
+	result := repo.DB.WithContext(ctx).Where("invoice_id = ?", invoiceID).First(&submission)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return einvoice.Submission{}, einvoice.ErrSubmissionNotFound
+		}
+		log.Error().
+			Str("operation", "GetSubmission").
+			Err(result.Error).
+			Uint("invoice_id", invoiceID).
+			Msg("Failed to retrieve e-invoice submission")
+		return einvoice.Submission{}, result.Error
+	}
+	return submission, nil
+}
+
+// ClaimSubmission records a pending submission before the document is sent, so two requests can not
+// send the same invoice. A rejected earlier submission is replaced.
+func (repo *Repository) ClaimSubmission(ctx context.Context, submission einvoice.Submission) (einvoice.Submission, error) {
+	submission.Status = einvoice.SubmissionPending
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("invoice_id = ? AND status = ?", submission.InvoiceID, einvoice.SubmissionRejected).
+			Delete(&einvoice.Submission{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&submission)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return einvoice.ErrAlreadySubmitted
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "ClaimSubmission").
+			Err(err).
+			Uint("invoice_id", submission.InvoiceID).
+			Msg("Failed to claim e-invoice submission")
+		return einvoice.Submission{}, err
+	}
+	return submission, nil
+}
+
+// CompleteSubmission stores the integrator's answer to a claimed submission
+func (repo *Repository) CompleteSubmission(ctx context.Context, submission einvoice.Submission) (einvoice.Submission, error) {
+	result := repo.DB.WithContext(ctx).
+		Model(&einvoice.Submission{}).
+		Where("id = ?", submission.ID).
+		Updates(map[string]interface{}{
+			"status":    submission.Status,
+			"reference": submission.Reference,
+			"message":   submission.Message,
+		})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "CompleteSubmission").
+			Err(result.Error).
+			Uint("invoice_id", submission.InvoiceID).
+			Msg("Failed to store e-invoice submission result")
+		return einvoice.Submission{}, result.Error
+	}
+
+	log.Info().
+		Str("operation", "CompleteSubmission").
+		Uint("invoice_id", submission.InvoiceID).
+		Str("document_id", submission.DocumentID).
+		Str("status", string(submission.Status)).
+		Msg("E-invoice submitted")
+	return submission, nil
+}
+
+// ReleaseSubmission removes a claimed submission that could not be sent, so it can be retried
+func (repo *Repository) ReleaseSubmission(ctx context.Context, id uint) error {
+	result := repo.DB.WithContext(ctx).Unscoped().Delete(&einvoice.Submission{}, id)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "ReleaseSubmission").
+			Err(result.Error).
+			Uint("submission_id", id).
+			Msg("Failed to release e-invoice submission")
+		return result.Error
+	}
+	return nil
+}
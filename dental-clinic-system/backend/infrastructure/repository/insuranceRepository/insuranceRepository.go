@@ -0,0 +1,476 @@
+package insuranceRepository
+
+import (
+	"context"
+	"dental-clinic-system/infrastructure/repository/billingRepository"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/insurance"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository handles insurance provider, policy and claim database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetProviders retrieves the insurance providers of a clinic by name
+func (repo *Repository) GetProviders(ctx context.Context, clinicID uint) ([]insurance.Provider, error) {
+	var providers []insurance.Provider
+	if err := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Order("name, id").
+		Find(&providers).Error; err != nil {
+		log.Error().
+			Str("operation", "GetProviders").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve insurance providers")
+		return nil, err
+	}
+	return providers, nil
+}
+
+// GetProvider retrieves a single insurance provider by its ID
+func (repo *Repository) GetProvider(ctx context.Context, id uint) (insurance.Provider, error) {
+	var provider insurance.Provider
+	result := repo.DB.WithContext(ctx).First(&provider, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return insurance.Provider{}, insurance.ErrProviderNotFound
+		}
+		log.Error().
+			Str("operation", "GetProvider").
+			Err(result.Error).
+			Uint("provider_id", id).
+			Msg("Failed to retrieve insurance provider")
+		return insurance.Provider{}, result.Error
+	}
+	return provider, nil
+}
+
+// CreateProvider creates a new insurance provider record in the database
+func (repo *Repository) CreateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error) {
+	if err := repo.DB.WithContext(ctx).Create(&provider).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateProvider").
+			Err(err).
+			Uint("clinic_id", provider.ClinicID).
+			Msg("Failed to create insurance provider")
+		return insurance.Provider{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateProvider").
+		Uint("provider_id", provider.ID).
+		Msg("Insurance provider created successfully")
+
+	return provider, nil
+}
+
+// UpdateProvider updates an existing insurance provider record in the database
+func (repo *Repository) UpdateProvider(ctx context.Context, provider insurance.Provider) (insurance.Provider, error) {
+	if err := repo.DB.WithContext(ctx).Save(&provider).Error; err != nil {
+		log.Error().
+			Str("operation", "UpdateProvider").
+			Err(err).
+			Uint("provider_id", provider.ID).
+			Msg("Failed to update insurance provider")
+		return insurance.Provider{}, err
+	}
+
+	log.Info().
+		Str("operation", "UpdateProvider").
+		Uint("provider_id", provider.ID).
+		Msg("Insurance provider updated successfully")
+
+	return provider, nil
+}
+
+// GetPolicies retrieves the policies of a patient with their providers, most recent first
+func (repo *Repository) GetPolicies(ctx context.Context, clinicID, patientID uint) ([]insurance.Policy, error) {
+	var policies []insurance.Policy
+	if err := repo.DB.WithContext(ctx).
+		Preload("Provider").
+		Where("clinic_id = ? AND patient_id = ?", clinicID, patientID).
+		Order("valid_from DESC, id DESC").
+		Find(&policies).Error; err != nil {
+		log.Error().
+			Str("operation", "GetPolicies").
+			Err(err).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve insurance policies")
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetPolicy retrieves a policy with its provider
+func (repo *Repository) GetPolicy(ctx context.Context, id uint) (insurance.Policy, error) {
+	var policy insurance.Policy
+	result := repo.DB.WithContext(ctx).Preload("Provider").First(&policy, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return insurance.Policy{}, insurance.ErrPolicyNotFound
+		}
+		log.Error().
+			Str("operation", "GetPolicy").
+			Err(result.Error).
+			Uint("policy_id", id).
+			Msg("Failed to retrieve insurance policy")
+		return insurance.Policy{}, result.Error
+	}
+	return policy, nil
+}
+
+// CreatePolicy creates a new policy record in the database
+func (repo *Repository) CreatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error) {
+	if err := repo.DB.WithContext(ctx).Omit("Provider").Create(&policy).Error; err != nil {
+		log.Error().
+			Str("operation", "CreatePolicy").
+			Err(err).
+			Uint("patient_id", policy.PatientID).
+			Msg("Failed to create insurance policy")
+		return insurance.Policy{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreatePolicy").
+		Uint("policy_id", policy.ID).
+		Msg("Insurance policy created successfully")
+
+	return repo.GetPolicy(ctx, policy.ID)
+}
+
+// UpdatePolicy updates an existing policy record in the database
+func (repo *Repository) UpdatePolicy(ctx context.Context, policy insurance.Policy) (insurance.Policy, error) {
+	if err := repo.DB.WithContext(ctx).Omit("Provider").Save(&policy).Error; err != nil {
+		log.Error().
+			Str("operation", "UpdatePolicy").
+			Err(err).
+			Uint("policy_id", policy.ID).
+			Msg("Failed to update insurance policy")
+		return insurance.Policy{}, err
+	}
+
+	log.Info().
+		Str("operation", "UpdatePolicy").
+		Uint("policy_id", policy.ID).
+		Msg("Insurance policy updated successfully")
+
+	return repo.GetPolicy(ctx, policy.ID)
+}
+
+// GetUsage sums what the claims under a policy for services between two dates inclusive have taken of its limit
+func (repo *Repository) GetUsage(ctx context.Context, policyID uint, from, to string) (insurance.Usage, error) {
+	usage, err := usageOf(repo.DB.WithContext(ctx), policyID, from, to)
+	if err != nil {
+		log.Error().
+			Str("operation", "GetUsage").
+			Err(err).
+			Uint("policy_id", policyID).
+			Msg("Failed to calculate insurance usage")
+		return insurance.Usage{}, err
+	}
+	return usage, nil
+}
+
+// GetClaims retrieves the claims of a clinic, newest first, optionally filtered by patient and status
+func (repo *Repository) GetClaims(ctx context.Context, clinicID, patientID uint, status insurance.ClaimStatus) ([]insurance.Claim, error) {
+	var claims []insurance.Claim
+	query := repo.DB.WithContext(ctx).
+		Preload("Policy").
+		Preload("Policy.Provider").
+		Where("clinic_id = ?", clinicID).
+		Order("submitted_at DESC, id DESC")
+	if patientID != 0 {
+		query = query.Where("patient_id = ?", patientID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&claims).Error; err != nil {
+		log.Error().
+			Str("operation", "GetClaims").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve insurance claims")
+		return nil, err
+	}
+	return claims, nil
+}
+
+// GetClaim retrieves a claim with its policy and provider
+func (repo *Repository) GetClaim(ctx context.Context, id uint) (insurance.Claim, error) {
+	var claim insurance.Claim
+	result := repo.DB.WithContext(ctx).
+		Preload("Policy").
+		Preload("Policy.Provider").
+		First(&claim, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return insurance.Claim{}, insurance.ErrClaimNotFound
+		}
+		log.Error().
+			Str("operation", "GetClaim").
+			Err(result.Error).
+			Uint("claim_id", id).
+			Msg("Failed to retrieve insurance claim")
+		return insurance.Claim{}, result.Error
+	}
+	return claim, nil
+}
+
+// CreateClaim submits a claim for an invoice under a policy. The policy and the invoice are locked so the
+// annual limit and the invoice's shares can not be taken twice; the insurer's share is the policy's coverage
+// of the invoice, capped by the rest of the annual limit and what the patient has not paid yet.
+func (repo *Repository) CreateClaim(ctx context.Context, claim insurance.Claim) (insurance.Claim, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var policy insurance.Policy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&policy, claim.PolicyID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return insurance.ErrPolicyNotFound
+			}
+			return err
+		}
+		invoice, err := billingRepository.LockInvoice(tx, claim.InvoiceID)
+		if err != nil {
+			return err
+		}
+
+		if invoice.Status == billing.InvoiceVoid {
+			return billing.ErrInvoiceVoid
+		}
+		if invoice.PatientID != policy.PatientID || invoice.ClinicID != policy.ClinicID {
+			return fmt.Errorf("%w: the policy belongs to another patient", insurance.ErrClaimValidation)
+		}
+		if invoice.Currency != policy.Currency {
+			return fmt.Errorf("%w: the policy is in %s but the invoice in %s", insurance.ErrClaimValidation, policy.Currency, invoice.Currency)
+		}
+		if !policy.ValidOn(invoice.IssueDate) {
+			return insurance.ErrPolicyNotValid
+		}
+
+		var live int64
+		if err := tx.Model(&insurance.Claim{}).
+			Where("invoice_id = ? AND status <> ?", invoice.ID, insurance.ClaimRejected).
+			Count(&live).Error; err != nil {
+			return err
+		}
+		if live > 0 {
+			return insurance.ErrClaimExists
+		}
+		var plans int64
+		if err := tx.Model(&billing.InstallmentPlan{}).Where("invoice_id = ?", invoice.ID).Count(&plans).Error; err != nil {
+			return err
+		}
+		if plans > 0 {
+			return insurance.ErrInvoiceHasPlan
+		}
+
+		yearStart, yearEnd, err := policy.PolicyYear(invoice.IssueDate)
+		if err != nil {
+			return err
+		}
+		usage, err := usageOf(tx, policy.ID, yearStart, yearEnd)
+		if err != nil {
+			return err
+		}
+		share := min(policy.Share(invoice.Total, usage.Total()), invoice.Total-invoice.PatientPaid())
+		if share <= 0 {
+			return insurance.ErrNothingToClaim
+		}
+
+		claim.ClinicID = invoice.ClinicID
+		claim.PatientID = invoice.PatientID
+		claim.Status = insurance.ClaimSubmitted
+		claim.Currency = invoice.Currency
+		claim.ServiceDate = invoice.IssueDate
+		claim.InvoiceTotal = invoice.Total
+		claim.ClaimedAmount = share
+		if err := tx.Omit("Policy").Create(&claim).Error; err != nil {
+			return err
+		}
+		if err := updateInsurerShare(tx, invoice.ID, claim.CoveredAmount()); err != nil {
+			return err
+		}
+
+		entry := billing.NewAuditEntry(billing.AuditClaimSubmitted, invoice, claim.SubmittedByID, claim.SubmittedAt, map[string]interface{}{
+			"claim_id":       claim.ID,
+			"policy_id":      policy.ID,
+			"policy_number":  policy.PolicyNumber,
+			"claimed_amount": claim.ClaimedAmount,
+			"insurer_share":  claim.CoveredAmount(),
+		})
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "CreateClaim").
+			Err(err).
+			Uint("invoice_id", claim.InvoiceID).
+			Uint("policy_id", claim.PolicyID).
+			Msg("Failed to submit insurance claim")
+		return insurance.Claim{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateClaim").
+		Uint("claim_id", claim.ID).
+		Uint("invoice_id", claim.InvoiceID).
+		Int64("claimed_amount", claim.ClaimedAmount).
+		Msg("Insurance claim submitted successfully")
+
+	return repo.GetClaim(ctx, claim.ID)
+}
+
+// DecideClaim records the insurer's decision on a submitted claim and moves the invoice's insurer share
+// to the approved amount; whatever the insurer does not cover falls back to the patient
+func (repo *Repository) DecideClaim(ctx context.Context, claimID uint, decision insurance.Decision, actorID uint, at time.Time) (insurance.Claim, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		claim, err := lockClaim(tx, claimID)
+		if err != nil {
+			return err
+		}
+		if err := claim.Decide(decision, actorID, at); err != nil {
+			return err
+		}
+		invoice, err := billingRepository.LockInvoice(tx, claim.InvoiceID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&insurance.Claim{}).
+			Where("id = ?", claim.ID).
+			Updates(map[string]interface{}{
+				"status":           claim.Status,
+				"approved_amount":  claim.ApprovedAmount,
+				"rejection_reason": claim.RejectionReason,
+				"reference":        claim.Reference,
+				"decided_at":       claim.DecidedAt,
+				"decided_by_id":    claim.DecidedByID,
+			}).Error; err != nil {
+			return err
+		}
+		if err := updateInsurerShare(tx, invoice.ID, claim.CoveredAmount()); err != nil {
+			return err
+		}
+
+		entry := billing.NewAuditEntry(billing.AuditClaimDecided, invoice, actorID, at, map[string]interface{}{
+			"claim_id":         claim.ID,
+			"status":           claim.Status,
+			"claimed_amount":   claim.ClaimedAmount,
+			"approved_amount":  claim.ApprovedAmount,
+			"rejection_reason": claim.RejectionReason,
+			"insurer_share":    claim.CoveredAmount(),
+		})
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "DecideClaim").
+			Err(err).
+			Uint("claim_id", claimID).
+			Str("status", string(decision.Status)).
+			Msg("Failed to decide insurance claim")
+		return insurance.Claim{}, err
+	}
+
+	log.Info().
+		Str("operation", "DecideClaim").
+		Uint("claim_id", claimID).
+		Str("status", string(decision.Status)).
+		Int64("approved_amount", decision.ApprovedAmount).
+		Msg("Insurance claim decided successfully")
+
+	return repo.GetClaim(ctx, claimID)
+}
+
+// PayClaim books the insurer's payment of an approved claim against its invoice and marks the claim paid
+func (repo *Repository) PayClaim(ctx context.Context, claimID uint, payment billing.Payment) (insurance.Claim, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		claim, err := lockClaim(tx, claimID)
+		if err != nil {
+			return err
+		}
+		if claim.Status != insurance.ClaimApproved && claim.Status != insurance.ClaimPartiallyApproved {
+			return insurance.ErrClaimNotApproved
+		}
+
+		payment.Kind = billing.KindPayment
+		payment.Method = billing.MethodInsurance
+		payment.Amount = claim.ApprovedAmount
+		if err := billingRepository.RecordPaymentInTransaction(tx, claim.InvoiceID, &payment); err != nil {
+			return err
+		}
+
+		return tx.Model(&insurance.Claim{}).
+			Where("id = ?", claim.ID).
+			Updates(map[string]interface{}{
+				"status":      insurance.ClaimPaid,
+				"paid_amount": payment.Amount,
+				"paid_at":     payment.ReceivedAt,
+				"payment_id":  payment.ID,
+			}).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "PayClaim").
+			Err(err).
+			Uint("claim_id", claimID).
+			Msg("Failed to record insurance claim payment")
+		return insurance.Claim{}, err
+	}
+
+	log.Info().
+		Str("operation", "PayClaim").
+		Uint("claim_id", claimID).
+		Uint("payment_id", payment.ID).
+		Int64("amount", payment.Amount).
+		Msg("Insurance claim paid successfully")
+
+	return repo.GetClaim(ctx, claimID)
+}
+
+// lockClaim loads a claim and holds its row lock until the transaction ends
+func lockClaim(tx *gorm.DB, id uint) (insurance.Claim, error) {
+	var claim insurance.Claim
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&claim, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return insurance.Claim{}, insurance.ErrClaimNotFound
+		}
+		return insurance.Claim{}, err
+	}
+	return claim, nil
+}
+
+// updateInsurerShare stores the part of an invoice the insurer covers
+func updateInsurerShare(tx *gorm.DB, invoiceID uint, share int64) error {
+	return tx.Model(&billing.Invoice{}).
+		Where("id = ?", invoiceID).
+		Update("insurer_share", share).Error
+}
+
+// usageOf sums the decided and pending coverage of the claims under a policy for services between two dates
+func usageOf(db *gorm.DB, policyID uint, from, to string) (insurance.Usage, error) {
+	var usage insurance.Usage
+	err := db.Model(&insurance.Claim{}).
+		Select(`COALESCE(SUM(approved_amount) FILTER (WHERE status IN ?), 0) AS used,
+			COALESCE(SUM(claimed_amount) FILTER (WHERE status = ?), 0) AS pending`,
+			[]insurance.ClaimStatus{insurance.ClaimApproved, insurance.ClaimPartiallyApproved, insurance.ClaimPaid},
+			insurance.ClaimSubmitted).
+		Where("policy_id = ? AND service_date BETWEEN ? AND ?", policyID, from, to).
+		Scan(&usage).Error
+	return usage, err
+}
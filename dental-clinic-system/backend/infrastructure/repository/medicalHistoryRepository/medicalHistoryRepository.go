@@ -0,0 +1,402 @@
+package medicalHistoryRepository
+
+import (
+	"context"
+	"errors"
+
+	"dental-clinic-system/models/medical"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles medical history related database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetRecords retrieves the allergies, medications, conditions and latest questionnaire of each patient.
+// Every requested patient has an entry, even when nothing is on file.
+func (repo *Repository) GetRecords(ctx context.Context, patientIDs []uint) (map[uint]medical.Record, error) {
+	records := map[uint]medical.Record{}
+	for _, id := range patientIDs {
+		records[id] = medical.Record{
+			PatientID:   id,
+			Allergies:   []medical.Allergy{},
+			Medications: []medical.Medication{},
+			Conditions:  []medical.Condition{},
+		}
+	}
+	if len(patientIDs) == 0 {
+		return records, nil
+	}
+
+	db := repo.DB.WithContext(ctx)
+
+	var allergies []medical.Allergy
+	if err := db.Where("patient_id IN ?", patientIDs).Order("id").Find(&allergies).Error; err != nil {
+		return nil, logRecordsError(err, patientIDs)
+	}
+	for _, allergy := range allergies {
+		record := records[allergy.PatientID]
+		record.Allergies = append(record.Allergies, allergy)
+		records[allergy.PatientID] = record
+	}
+
+	var medications []medical.Medication
+	if err := db.Where("patient_id IN ?", patientIDs).Order("start_date, id").Find(&medications).Error; err != nil {
+		return nil, logRecordsError(err, patientIDs)
+	}
+	for _, medication := range medications {
+		record := records[medication.PatientID]
+		record.Medications = append(record.Medications, medication)
+		records[medication.PatientID] = record
+	}
+
+	var conditions []medical.Condition
+	if err := db.Where("patient_id IN ?", patientIDs).Order("diagnosed_on, id").Find(&conditions).Error; err != nil {
+		return nil, logRecordsError(err, patientIDs)
+	}
+	for _, condition := range conditions {
+		record := records[condition.PatientID]
+		record.Conditions = append(record.Conditions, condition)
+		records[condition.PatientID] = record
+	}
+
+	var latest []medical.Questionnaire
+	err := db.Raw(`SELECT DISTINCT ON (patient_id) *
+		FROM medical_questionnaires
+		WHERE patient_id IN ? AND deleted_at IS NULL
+		ORDER BY patient_id, answered_on DESC, id DESC`, patientIDs).
+		Scan(&latest).Error
+	if err != nil {
+		return nil, logRecordsError(err, patientIDs)
+	}
+	if len(latest) == 0 {
+		return records, nil
+	}
+
+	questionnaireIDs := make([]uint, 0, len(latest))
+	for _, questionnaire := range latest {
+		questionnaireIDs = append(questionnaireIDs, questionnaire.ID)
+	}
+	var answers []medical.QuestionnaireAnswer
+	if err := db.Where("questionnaire_id IN ?", questionnaireIDs).Order("id").Find(&answers).Error; err != nil {
+		return nil, logRecordsError(err, patientIDs)
+	}
+	byQuestionnaire := map[uint][]medical.QuestionnaireAnswer{}
+	for _, answer := range answers {
+		byQuestionnaire[answer.QuestionnaireID] = append(byQuestionnaire[answer.QuestionnaireID], answer)
+	}
+
+	for _, questionnaire := range latest {
+		questionnaire.Answers = byQuestionnaire[questionnaire.ID]
+		record := records[questionnaire.PatientID]
+		record.Questionnaire = &questionnaire
+		records[questionnaire.PatientID] = record
+	}
+
+	return records, nil
+}
+
+func logRecordsError(err error, patientIDs []uint) error {
+	log.Error().
+		Str("operation", "GetRecords").
+		Err(err).
+		Interface("patient_ids", patientIDs).
+		Msg("Failed to retrieve medical records")
+	return err
+}
+
+// GetAllergy retrieves an allergy by its ID
+func (repo *Repository) GetAllergy(ctx context.Context, id uint) (medical.Allergy, error) {
+	var allergy medical.Allergy
+	result := repo.DB.WithContext(ctx).First(&allergy, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return medical.Allergy{}, medical.ErrAllergyNotFound
+		}
+		log.Error().
+			Str("operation", "GetAllergy").
+			Err(result.Error).
+			Uint("allergy_id", id).
+			Msg("Failed to retrieve allergy")
+		return medical.Allergy{}, result.Error
+	}
+	return allergy, nil
+}
+
+// CreateAllergy records a new allergy
+func (repo *Repository) CreateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error) {
+	if err := repo.DB.WithContext(ctx).Create(&allergy).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateAllergy").
+			Err(err).
+			Uint("patient_id", allergy.PatientID).
+			Msg("Failed to record allergy")
+		return medical.Allergy{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateAllergy").
+		Uint("allergy_id", allergy.ID).
+		Uint("patient_id", allergy.PatientID).
+		Msg("Allergy recorded successfully")
+
+	return allergy, nil
+}
+
+// UpdateAllergy saves changes to an allergy
+func (repo *Repository) UpdateAllergy(ctx context.Context, allergy medical.Allergy) (medical.Allergy, error) {
+	if err := repo.DB.WithContext(ctx).Save(&allergy).Error; err != nil {
+		log.Error().
+			Str("operation", "UpdateAllergy").
+			Err(err).
+			Uint("allergy_id", allergy.ID).
+			Msg("Failed to update allergy")
+		return medical.Allergy{}, err
+	}
+
+	log.Info().
+		Str("operation", "UpdateAllergy").
+		Uint("allergy_id", allergy.ID).
+		Msg("Allergy updated successfully")
+
+	return repo.GetAllergy(ctx, allergy.ID)
+}
+
+// DeleteAllergy removes an allergy recorded by mistake
+func (repo *Repository) DeleteAllergy(ctx context.Context, id uint) error {
+	if err := repo.DB.WithContext(ctx).Delete(&medical.Allergy{}, id).Error; err != nil {
+		log.Error().
+			Str("operation", "DeleteAllergy").
+			Err(err).
+			Uint("allergy_id", id).
+			Msg("Failed to delete allergy")
+		return err
+	}
+
+	log.Info().
+		Str("operation", "DeleteAllergy").
+		Uint("allergy_id", id).
+		Msg("Allergy deleted successfully")
+
+	return nil
+}
+
+// GetMedication retrieves a medication by its ID
+func (repo *Repository) GetMedication(ctx context.Context, id uint) (medical.Medication, error) {
+	var medication medical.Medication
+	result := repo.DB.WithContext(ctx).First(&medication, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return medical.Medication{}, medical.ErrMedicationNotFound
+		}
+		log.Error().
+			Str("operation", "GetMedication").
+			Err(result.Error).
+			Uint("medication_id", id).
+			Msg("Failed to retrieve medication")
+		return medical.Medication{}, result.Error
+	}
+	return medication, nil
+}
+
+// CreateMedication records a new medication
+func (repo *Repository) CreateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error) {
+	if err := repo.DB.WithContext(ctx).Create(&medication).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateMedication").
+			Err(err).
+			Uint("patient_id", medication.PatientID).
+			Msg("Failed to record medication")
+		return medical.Medication{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateMedication").
+		Uint("medication_id", medication.ID).
+		Uint("patient_id", medication.PatientID).
+		Msg("Medication recorded successfully")
+
+	return medication, nil
+}
+
+// UpdateMedication saves changes to a medication, e.g. its end date when the patient stops taking it
+func (repo *Repository) UpdateMedication(ctx context.Context, medication medical.Medication) (medical.Medication, error) {
+	if err := repo.DB.WithContext(ctx).Save(&medication).Error; err != nil {
+		log.Error().
+			Str("operation", "UpdateMedication").
+			Err(err).
+			Uint("medication_id", medication.ID).
+			Msg("Failed to update medication")
+		return medical.Medication{}, err
+	}
+
+	log.Info().
+		Str("operation", "UpdateMedication").
+		Uint("medication_id", medication.ID).
+		Msg("Medication updated successfully")
+
+	return repo.GetMedication(ctx, medication.ID)
+}
+
+// DeleteMedication removes a medication recorded by mistake
+func (repo *Repository) DeleteMedication(ctx context.Context, id uint) error {
+	if err := repo.DB.WithContext(ctx).Delete(&medical.Medication{}, id).Error; err != nil {
+		log.Error().
+			Str("operation", "DeleteMedication").
+			Err(err).
+			Uint("medication_id", id).
+			Msg("Failed to delete medication")
+		return err
+	}
+
+	log.Info().
+		Str("operation", "DeleteMedication").
+		Uint("medication_id", id).
+		Msg("Medication deleted successfully")
+
+	return nil
+}
+
+// GetCondition retrieves a condition by its ID
+func (repo *Repository) GetCondition(ctx context.Context, id uint) (medical.Condition, error) {
+	var condition medical.Condition
+	result := repo.DB.WithContext(ctx).First(&condition, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return medical.Condition{}, medical.ErrConditionNotFound
+		}
+		log.Error().
+			Str("operation", "GetCondition").
+			Err(result.Error).
+			Uint("condition_id", id).
+			Msg("Failed to retrieve condition")
+		return medical.Condition{}, result.Error
+	}
+	return condition, nil
+}
+
+// CreateCondition records a new systemic condition
+func (repo *Repository) CreateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error) {
+	if err := repo.DB.WithContext(ctx).Create(&condition).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateCondition").
+			Err(err).
+			Uint("patient_id", condition.PatientID).
+			Msg("Failed to record condition")
+		return medical.Condition{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateCondition").
+		Uint("condition_id", condition.ID).
+		Uint("patient_id", condition.PatientID).
+		Msg("Condition recorded successfully")
+
+	return condition, nil
+}
+
+// UpdateCondition saves changes to a condition, e.g. its resolution date
+func (repo *Repository) UpdateCondition(ctx context.Context, condition medical.Condition) (medical.Condition, error) {
+	if err := repo.DB.WithContext(ctx).Save(&condition).Error; err != nil {
+		log.Error().
+			Str("operation", "UpdateCondition").
+			Err(err).
+			Uint("condition_id", condition.ID).
+			Msg("Failed to update condition")
+		return medical.Condition{}, err
+	}
+
+	log.Info().
+		Str("operation", "UpdateCondition").
+		Uint("condition_id", condition.ID).
+		Msg("Condition updated successfully")
+
+	return repo.GetCondition(ctx, condition.ID)
+}
+
+// DeleteCondition removes a condition recorded by mistake
+func (repo *Repository) DeleteCondition(ctx context.Context, id uint) error {
+	if err := repo.DB.WithContext(ctx).Delete(&medical.Condition{}, id).Error; err != nil {
+		log.Error().
+			Str("operation", "DeleteCondition").
+			Err(err).
+			Uint("condition_id", id).
+			Msg("Failed to delete condition")
+		return err
+	}
+
+	log.Info().
+		Str("operation", "DeleteCondition").
+		Uint("condition_id", id).
+		Msg("Condition deleted successfully")
+
+	return nil
+}
+
+// GetQuestionnaires retrieves every questionnaire of a patient with its answers, newest first
+func (repo *Repository) GetQuestionnaires(ctx context.Context, patientID uint) ([]medical.Questionnaire, error) {
+	var questionnaires []medical.Questionnaire
+	result := repo.DB.WithContext(ctx).
+		Preload("Answers", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("patient_id = ?", patientID).
+		Order("answered_on DESC, id DESC").
+		Find(&questionnaires)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetQuestionnaires").
+			Err(result.Error).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve medical questionnaires")
+		return nil, result.Error
+	}
+	return questionnaires, nil
+}
+
+// GetQuestionnaire retrieves a questionnaire with its answers
+func (repo *Repository) GetQuestionnaire(ctx context.Context, id uint) (medical.Questionnaire, error) {
+	var questionnaire medical.Questionnaire
+	result := repo.DB.WithContext(ctx).
+		Preload("Answers", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&questionnaire, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return medical.Questionnaire{}, medical.ErrQuestionnaireNotFound
+		}
+		log.Error().
+			Str("operation", "GetQuestionnaire").
+			Err(result.Error).
+			Uint("questionnaire_id", id).
+			Msg("Failed to retrieve medical questionnaire")
+		return medical.Questionnaire{}, result.Error
+	}
+	return questionnaire, nil
+}
+
+// CreateQuestionnaire stores a questionnaire and its answers
+func (repo *Repository) CreateQuestionnaire(ctx context.Context, questionnaire medical.Questionnaire) (medical.Questionnaire, error) {
+	if err := repo.DB.WithContext(ctx).Create(&questionnaire).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateQuestionnaire").
+			Err(err).
+			Uint("patient_id", questionnaire.PatientID).
+			Msg("Failed to record medical questionnaire")
+		return medical.Questionnaire{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateQuestionnaire").
+		Uint("questionnaire_id", questionnaire.ID).
+		Uint("patient_id", questionnaire.PatientID).
+		Msg("Medical questionnaire recorded successfully")
+
+	return questionnaire, nil
+}
@@ -0,0 +1,203 @@
+package odontogramRepository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dental-clinic-system/models/odontogram"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// chartLockNamespace is the advisory lock namespace serialising chart changes per patient
+const chartLockNamespace = 1101
+
+// Repository handles odontogram-related database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetFindingsAt retrieves the findings that were on the patient's chart at the given time
+func (repo *Repository) GetFindingsAt(ctx context.Context, patientID uint, at time.Time) ([]odontogram.Finding, error) {
+	var findings []odontogram.Finding
+	result := repo.DB.WithContext(ctx).
+		Where("patient_id = ? AND recorded_at <= ?", patientID, at).
+		Where("removed_at IS NULL OR removed_at > ?", at).
+		Order("tooth, recorded_at, id").
+		Find(&findings)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetFindingsAt").
+			Err(result.Error).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve odontogram findings")
+		return nil, result.Error
+	}
+	return findings, nil
+}
+
+// GetFindingHistory retrieves every finding ever charted for the patient, including removed ones, oldest first
+func (repo *Repository) GetFindingHistory(ctx context.Context, patientID uint) ([]odontogram.Finding, error) {
+	var findings []odontogram.Finding
+	result := repo.DB.WithContext(ctx).
+		Where("patient_id = ?", patientID).
+		Order("recorded_at, id").
+		Find(&findings)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetFindingHistory").
+			Err(result.Error).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve odontogram history")
+		return nil, result.Error
+	}
+	return findings, nil
+}
+
+// GetAppointmentFindings retrieves the findings charted during an appointment, including ones removed from the chart since
+func (repo *Repository) GetAppointmentFindings(ctx context.Context, appointmentID uint) ([]odontogram.Finding, error) {
+	var findings []odontogram.Finding
+	result := repo.DB.WithContext(ctx).
+		Where("appointment_id = ?", appointmentID).
+		Order("tooth, recorded_at, id").
+		Find(&findings)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetAppointmentFindings").
+			Err(result.Error).
+			Uint("appointment_id", appointmentID).
+			Msg("Failed to retrieve appointment findings")
+		return nil, result.Error
+	}
+	return findings, nil
+}
+
+// GetFinding retrieves a finding by its ID
+func (repo *Repository) GetFinding(ctx context.Context, id uint) (odontogram.Finding, error) {
+	var finding odontogram.Finding
+	result := repo.DB.WithContext(ctx).First(&finding, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return odontogram.Finding{}, odontogram.ErrFindingNotFound
+		}
+		log.Error().
+			Str("operation", "GetFinding").
+			Err(result.Error).
+			Uint("finding_id", id).
+			Msg("Failed to retrieve odontogram finding")
+		return odontogram.Finding{}, result.Error
+	}
+	return finding, nil
+}
+
+// CreateFinding charts a new finding and removes the findings it supersedes in a single transaction.
+// Chart changes of a patient are serialised, so the same condition can not be charted twice on a surface.
+func (repo *Repository) CreateFinding(ctx context.Context, finding odontogram.Finding) (odontogram.Finding, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", chartLockNamespace, int32(finding.PatientID)).Error; err != nil {
+			return err
+		}
+
+		var active []odontogram.Finding
+		if err := tx.Where("patient_id = ? AND tooth = ? AND removed_at IS NULL", finding.PatientID, finding.Tooth).Find(&active).Error; err != nil {
+			return err
+		}
+
+		superseded := map[uint]bool{}
+		for _, id := range finding.Supersedes {
+			superseded[id] = true
+		}
+		for _, id := range finding.Supersedes {
+			var previous odontogram.Finding
+			if err := tx.Where("id = ? AND patient_id = ?", id, finding.PatientID).First(&previous).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return odontogram.ErrFindingNotFound
+				}
+				return err
+			}
+			if previous.RemovedAt != nil {
+				return odontogram.ErrFindingNotRemovable
+			}
+		}
+
+		for _, existing := range active {
+			if !superseded[existing.ID] && existing.Overlaps(finding) {
+				return odontogram.ErrFindingConflict
+			}
+		}
+
+		if err := tx.Create(&finding).Error; err != nil {
+			return err
+		}
+
+		if len(finding.Supersedes) == 0 {
+			return nil
+		}
+		return tx.Model(&odontogram.Finding{}).
+			Where("id IN ?", finding.Supersedes).
+			Updates(map[string]interface{}{
+				"removed_at":       finding.RecordedAt,
+				"removed_by_id":    finding.RecordedByID,
+				"removal_reason":   "superseded",
+				"superseded_by_id": finding.ID,
+			}).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "CreateFinding").
+			Err(err).
+			Uint("patient_id", finding.PatientID).
+			Int("tooth", finding.Tooth).
+			Msg("Failed to chart odontogram finding")
+		return odontogram.Finding{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateFinding").
+		Uint("finding_id", finding.ID).
+		Uint("patient_id", finding.PatientID).
+		Msg("Odontogram finding charted successfully")
+
+	return finding, nil
+}
+
+// RemoveFinding takes a finding off the chart. The row is kept so the chart history stays intact.
+func (repo *Repository) RemoveFinding(ctx context.Context, id, removedByID uint, at time.Time, reason string) (odontogram.Finding, error) {
+	result := repo.DB.WithContext(ctx).
+		Model(&odontogram.Finding{}).
+		Where("id = ? AND removed_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"removed_at":     at,
+			"removed_by_id":  removedByID,
+			"removal_reason": reason,
+		})
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "RemoveFinding").
+			Err(result.Error).
+			Uint("finding_id", id).
+			Msg("Failed to remove odontogram finding")
+		return odontogram.Finding{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := repo.GetFinding(ctx, id); err != nil {
+			return odontogram.Finding{}, err
+		}
+		return odontogram.Finding{}, odontogram.ErrFindingNotRemovable
+	}
+
+	log.Info().
+		Str("operation", "RemoveFinding").
+		Uint("finding_id", id).
+		Msg("Odontogram finding removed successfully")
+
+	return repo.GetFinding(ctx, id)
+}
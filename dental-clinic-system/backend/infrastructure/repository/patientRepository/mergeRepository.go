@@ -0,0 +1,298 @@
+package patientRepository
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/attachment"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinicalnote"
+	"dental-clinic-system/models/consent"
+	"dental-clinic-system/models/insurance"
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/odontogram"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/radiograph"
+	"dental-clinic-system/models/treatment"
+	"dental-clinic-system/models/waitlist"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/rs/zerolog/log"
+)
+
+// patientRecords are the models whose patient_id is moved to the survivor when patients are merged
+var patientRecords = []interface{}{
+	&appointment.Appointment{},
+	&appointment.Series{},
+	&attachment.Attachment{},
+	&billing.Invoice{},
+	&billing.Payment{},
+	&billing.InstallmentPlan{},
+	&billing.Installment{},
+	&clinicalnote.Note{},
+	&consent.Form{},
+	&insurance.Policy{},
+	&insurance.Claim{},
+	&medical.Allergy{},
+	&medical.Medication{},
+	&medical.Condition{},
+	&medical.Questionnaire{},
+	&odontogram.Finding{},
+	&radiograph.Image{},
+	&treatment.Plan{},
+	&waitlist.Entry{},
+}
+
+// GetDuplicateCandidates retrieves pairs of the clinic's patients that share a phone number or birth
+// date, have similar names or national IDs at most two digits apart, roughly the likeliest first.
+// The pairs are scored by the caller.
+func (repo *Repository) GetDuplicateCandidates(ctx context.Context, clinicID uint, limit int) ([][2]patient.Patient, error) {
+	var pairs []struct {
+		FirstID  uint
+		SecondID uint
+	}
+	err := repo.DB.WithContext(ctx).Raw(`SELECT first_id, second_id FROM (
+			SELECT a.id AS first_id, b.id AS second_id,
+				(a.search_phone <> '' AND b.search_phone = a.search_phone) AS same_phone,
+				(a.search_birth_date <> '' AND b.search_birth_date = a.search_birth_date) AS same_birth_date,
+				(a.national_id <> '' AND length(b.national_id) = length(a.national_id)
+					AND levenshtein_less_equal(a.national_id, b.national_id, 2) <= 2) AS similar_national_id,
+				similarity(a.search_name, b.search_name) AS name_similarity
+			FROM patients a
+			JOIN patients b ON b.clinic_id = a.clinic_id AND b.id > a.id AND b.deleted_at IS NULL
+			WHERE a.clinic_id = ? AND a.deleted_at IS NULL
+		) AS pairs
+		WHERE same_phone OR same_birth_date OR similar_national_id OR name_similarity >= show_limit()
+		ORDER BY same_phone::int * ? + same_birth_date::int * ? + similar_national_id::int * ? + name_similarity * ? DESC,
+			first_id, second_id
+		LIMIT ?`,
+		clinicID, patient.PhoneWeight, patient.BirthDateWeight, patient.SimilarNationalIDWeight, patient.NameWeight, limit).
+		Scan(&pairs).Error
+	if err != nil {
+		log.Error().
+			Str("operation", "GetDuplicateCandidates").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to find duplicate patient candidates")
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return [][2]patient.Patient{}, nil
+	}
+
+	ids := make([]uint, 0, len(pairs)*2)
+	for _, pair := range pairs {
+		ids = append(ids, pair.FirstID, pair.SecondID)
+	}
+	var patients []patient.Patient
+	if err := repo.DB.WithContext(ctx).Where("id IN ?", ids).Find(&patients).Error; err != nil {
+		log.Error().
+			Str("operation", "GetDuplicateCandidates").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve duplicate patient candidates")
+		return nil, err
+	}
+	byID := make(map[uint]patient.Patient, len(patients))
+	for _, p := range patients {
+		byID[p.ID] = p
+	}
+
+	candidates := make([][2]patient.Patient, 0, len(pairs))
+	for _, pair := range pairs {
+		first, okFirst := byID[pair.FirstID]
+		second, okSecond := byID[pair.SecondID]
+		if okFirst && okSecond {
+			candidates = append(candidates, [2]patient.Patient{first, second})
+		}
+	}
+	return candidates, nil
+}
+
+// MergePatients moves every record of the merged patient to the survivor, soft deletes the merged
+// patient and stores the merge with the moved records, all in one transaction
+func (repo *Repository) MergePatients(ctx context.Context, merge patient.Merge) (patient.Merge, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var patients []patient.Patient
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND clinic_id = ?", []uint{merge.SurvivorID, merge.MergedID}, merge.ClinicID).
+			Order("id").
+			Find(&patients).Error
+		if err != nil {
+			return err
+		}
+		if len(patients) != 2 {
+			return patient.ErrPatientNotFound
+		}
+
+		merge.Moves = nil
+		for _, model := range patientRecords {
+			stmt := &gorm.Statement{DB: tx}
+			if err := stmt.Parse(model); err != nil {
+				return err
+			}
+			table := stmt.Schema.Table
+
+			var ids []uint
+			if err := tx.Table(table).Where("patient_id = ?", merge.MergedID).Order("id").Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			if err := tx.Table(table).Where("id IN ?", ids).Update("patient_id", merge.SurvivorID).Error; err != nil {
+				return err
+			}
+			for _, id := range ids {
+				merge.Moves = append(merge.Moves, patient.MergeMove{RecordTable: table, RecordID: id})
+			}
+		}
+
+		if err := tx.Delete(&patient.Patient{}, merge.MergedID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&merge).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "MergePatients").
+			Err(err).
+			Uint("survivor_id", merge.SurvivorID).
+			Uint("merged_id", merge.MergedID).
+			Msg("Failed to merge patients")
+		return patient.Merge{}, err
+	}
+
+	log.Info().
+		Str("operation", "MergePatients").
+		Uint("merge_id", merge.ID).
+		Uint("survivor_id", merge.SurvivorID).
+		Uint("merged_id", merge.MergedID).
+		Int("moved", len(merge.Moves)).
+		Msg("Patients merged successfully")
+
+	return merge, nil
+}
+
+// UndoMerge moves the records a merge moved back to the merged patient and restores it. Records the
+// survivor gained after the merge stay with the survivor.
+func (repo *Repository) UndoMerge(ctx context.Context, id, undoneByID uint, undoneAt time.Time) (patient.Merge, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var merge patient.Merge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Moves").First(&merge, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return patient.ErrMergeNotFound
+			}
+			return err
+		}
+		if merge.UndoneAt != nil {
+			return patient.ErrMergeUndone
+		}
+
+		var survivor patient.Patient
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&survivor, merge.SurvivorID).Error; err != nil {
+			return err
+		}
+		if survivor.DeletedAt.Valid {
+			return patient.ErrMergeSuperseded
+		}
+
+		moved := map[string][]uint{}
+		for _, move := range merge.Moves {
+			moved[move.RecordTable] = append(moved[move.RecordTable], move.RecordID)
+		}
+		for table, ids := range moved {
+			err := tx.Table(table).
+				Where("id IN ? AND patient_id = ?", ids, merge.SurvivorID).
+				Update("patient_id", merge.MergedID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Model(&patient.Patient{}).Where("id = ?", merge.MergedID).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Model(&merge).Updates(map[string]interface{}{
+			"undone_at":    undoneAt,
+			"undone_by_id": undoneByID,
+		}).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "UndoMerge").
+			Err(err).
+			Uint("merge_id", id).
+			Msg("Failed to undo patient merge")
+		return patient.Merge{}, err
+	}
+
+	log.Info().
+		Str("operation", "UndoMerge").
+		Uint("merge_id", id).
+		Msg("Patient merge undone successfully")
+
+	return repo.GetMerge(ctx, id)
+}
+
+// GetMerges retrieves the clinic's merges, newest first, without their moved records
+func (repo *Repository) GetMerges(ctx context.Context, clinicID uint) ([]patient.Merge, error) {
+	var merges []patient.Merge
+	result := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Order("created_at DESC, id DESC").
+		Find(&merges)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetMerges").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve patient merges")
+		return nil, result.Error
+	}
+	return merges, nil
+}
+
+// GetMerge retrieves a merge with the records it moved
+func (repo *Repository) GetMerge(ctx context.Context, id uint) (patient.Merge, error) {
+	var merge patient.Merge
+	result := repo.DB.WithContext(ctx).Preload("Moves", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&merge, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return patient.Merge{}, patient.ErrMergeNotFound
+		}
+		log.Error().
+			Str("operation", "GetMerge").
+			Err(result.Error).
+			Uint("merge_id", id).
+			Msg("Failed to retrieve patient merge")
+		return patient.Merge{}, result.Error
+	}
+	return merge, nil
+}
+
+// GetActiveMerge retrieves the merge, not undone, that merged the patient into another
+func (repo *Repository) GetActiveMerge(ctx context.Context, mergedID uint) (patient.Merge, error) {
+	var merge patient.Merge
+	result := repo.DB.WithContext(ctx).
+		Where("merged_id = ? AND undone_at IS NULL", mergedID).
+		Order("id DESC").
+		First(&merge)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return patient.Merge{}, patient.ErrMergeNotFound
+		}
+		log.Error().
+			Str("operation", "GetActiveMerge").
+			Err(result.Error).
+			Uint("patient_id", mergedID).
+			Msg("Failed to retrieve patient merge")
+		return patient.Merge{}, result.Error
+	}
+	return merge, nil
+}
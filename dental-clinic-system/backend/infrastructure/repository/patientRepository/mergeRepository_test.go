@@ -0,0 +1,155 @@
+package patientRepository
+
+import (
+	"context"
+	"dental-clinic-system/infrastructure/postgres/postgrestest"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/user"
+	"dental-clinic-system/models/waitlist"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mergeFixture is a clinic with a doctor, a surviving patient and a duplicate that has an appointment,
+// a waitlist entry and an invoice. The doctor's user ID equals the duplicate's patient ID.
+type mergeFixture struct {
+	clinic    clinic.Clinic
+	survivor  patient.Patient
+	duplicate patient.Patient
+	booked    appointment.Appointment
+	waiting   waitlist.Entry
+	invoice   billing.Invoice
+}
+
+func newMergeFixture(t *testing.T, db *gorm.DB) mergeFixture {
+	t.Helper()
+	f := mergeFixture{clinic: clinic.Clinic{Name: "Gülüş Diş", PhoneNumber: "02120000000", Email: "info@example.com"}}
+	create := func(value interface{}) {
+		t.Helper()
+		if err := db.Omit(clause.Associations).Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	create(&f.clinic)
+
+	f.survivor = patient.Patient{NationalID: "10000000146", Name: "Şükrü Öztürk", BirthDate: "1985-03-12", ContactInfo: "0532 123 45 67", ClinicID: f.clinic.ID}
+	f.survivor.SetSearchFields()
+	create(&f.survivor)
+	f.duplicate = patient.Patient{NationalID: "10000000164", Name: "Sukru Ozturk", BirthDate: "12.03.1985", ClinicID: f.clinic.ID}
+	f.duplicate.SetSearchFields()
+	create(&f.duplicate)
+
+	doctor := user.User{NationalID: "20000000000", Email: "doctor@example.com", PhoneNumber: "5320000001", ClinicID: f.clinic.ID}
+	doctor.ID = f.duplicate.ID
+	create(&doctor)
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	// The doctor's own appointment with the survivor must stay where it is
+	create(&appointment.Appointment{ClinicID: f.clinic.ID, PatientID: f.survivor.ID, DoctorID: doctor.ID, ScheduledTime: start, EndTime: start.Add(30 * time.Minute), Status: appointment.StatusBooked})
+	f.booked = appointment.Appointment{ClinicID: f.clinic.ID, PatientID: f.duplicate.ID, DoctorID: doctor.ID, ScheduledTime: start.Add(time.Hour), EndTime: start.Add(90 * time.Minute), Status: appointment.StatusBooked}
+	create(&f.booked)
+	f.waiting = waitlist.Entry{ClinicID: f.clinic.ID, PatientID: f.duplicate.ID, DurationMinutes: 30, Status: waitlist.EntryWaiting}
+	create(&f.waiting)
+	f.invoice = billing.Invoice{ClinicID: f.clinic.ID, PatientID: f.duplicate.ID, Number: "2026-000001", IssueDate: "2026-03-02"}
+	create(&f.invoice)
+	return f
+}
+
+func patientOf(t *testing.T, db *gorm.DB, table string, id uint) uint {
+	t.Helper()
+	var patientID uint
+	if err := db.Table(table).Where("id = ?", id).Pluck("patient_id", &patientID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return patientID
+}
+
+func TestMergeAndUndo(t *testing.T) {
+	db := postgrestest.Open(t)
+	repo := NewRepository(db)
+	ctx := context.Background()
+	f := newMergeFixture(t, db)
+
+	merge, err := repo.MergePatients(ctx, patient.Merge{ClinicID: f.clinic.ID, SurvivorID: f.survivor.ID, MergedID: f.duplicate.ID, MergedByID: 1})
+	if err != nil {
+		t.Fatalf("MergePatients() error = %v", err)
+	}
+	if len(merge.Moves) != 3 {
+		t.Errorf("moved %d records, want the appointment, waitlist entry and invoice: %+v", len(merge.Moves), merge.Moves)
+	}
+	for table, id := range map[string]uint{"appointments": f.booked.ID, "waitlist_entries": f.waiting.ID, "invoices": f.invoice.ID} {
+		if got := patientOf(t, db, table, id); got != f.survivor.ID {
+			t.Errorf("%s %d belongs to patient %d after the merge, want %d", table, id, got, f.survivor.ID)
+		}
+	}
+	var doctorAppointments int64
+	db.Model(&appointment.Appointment{}).Where("patient_id = ?", f.survivor.ID).Count(&doctorAppointments)
+	if doctorAppointments != 2 {
+		t.Errorf("survivor has %d appointments, want 2", doctorAppointments)
+	}
+	if _, err := repo.GetPatient(ctx, f.duplicate.ID); err == nil {
+		t.Error("the duplicate is still visible after the merge")
+	}
+
+	undone, err := repo.UndoMerge(ctx, merge.ID, 1, time.Now())
+	if err != nil {
+		t.Fatalf("UndoMerge() error = %v", err)
+	}
+	if undone.UndoneAt == nil {
+		t.Error("UndoMerge() did not record the undo")
+	}
+	for table, id := range map[string]uint{"appointments": f.booked.ID, "waitlist_entries": f.waiting.ID, "invoices": f.invoice.ID} {
+		if got := patientOf(t, db, table, id); got != f.duplicate.ID {
+			t.Errorf("%s %d belongs to patient %d after the undo, want %d", table, id, got, f.duplicate.ID)
+		}
+	}
+	if _, err := repo.GetPatient(ctx, f.duplicate.ID); err != nil {
+		t.Errorf("the duplicate was not restored: %v", err)
+	}
+}
+
+func TestGetDuplicateCandidates(t *testing.T) {
+	db := postgrestest.Open(t)
+	repo := NewRepository(db)
+	f := newMergeFixture(t, db)
+
+	// Same birth date, national ID with two digits swapped, nothing else in common
+	swapped := patient.Patient{NationalID: "10000000416", Name: "Can Demir", BirthDate: "1970-01-01", ClinicID: f.clinic.ID}
+	twin := patient.Patient{NationalID: "30000000000", Name: "Zeynep Ak", BirthDate: "1970-01-01", ClinicID: f.clinic.ID}
+	stranger := patient.Patient{NationalID: "98765432109", Name: "Mehmet Öz", BirthDate: "2001-07-07", ClinicID: f.clinic.ID}
+	for _, p := range []*patient.Patient{&swapped, &twin, &stranger} {
+		p.SetSearchFields()
+		if err := db.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	candidates, err := repo.GetDuplicateCandidates(context.Background(), f.clinic.ID, 100)
+	if err != nil {
+		t.Fatalf("GetDuplicateCandidates() error = %v", err)
+	}
+	found := map[[2]uint]bool{}
+	for _, pair := range candidates {
+		found[[2]uint{pair[0].ID, pair[1].ID}] = true
+	}
+	for _, want := range [][2]uint{
+		{f.survivor.ID, f.duplicate.ID},
+		{f.survivor.ID, swapped.ID},
+		{swapped.ID, twin.ID},
+	} {
+		if !found[want] {
+			t.Errorf("pair %v was not a candidate; got %v", want, found)
+		}
+	}
+	for pair := range found {
+		if pair[0] == stranger.ID || pair[1] == stranger.ID {
+			t.Errorf("unrelated patient paired: %v", pair)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"dental-clinic-system/models/patient"
 	"errors"
+	"strings"
 
 	"gorm.io/gorm"
 
@@ -40,6 +41,88 @@ func (repo *Repository) GetPatients(ctx context.Context, clinicID uint) ([]patie
 	return patients, nil
 }
 
+// SearchPatients retrieves a page of the clinic's patients matching the query, best matches first.
+// Names are matched by trigram word similarity and by full-text prefix search on the folded name,
+// national IDs by prefix, phone numbers by any part of the national number and birth dates exactly.
+func (repo *Repository) SearchPatients(ctx context.Context, query patient.SearchQuery) (patient.SearchPage, error) {
+	terms := query.Terms()
+
+	var conditions, ranks []string
+	var conditionArgs, rankArgs []interface{}
+	if len(terms.Words) > 0 {
+		tsQuery := strings.Join(terms.Words, ":* & ") + ":*"
+		conditions = append(conditions, "to_tsvector('simple', search_name) @@ to_tsquery('simple', ?)", "? <% search_name")
+		conditionArgs = append(conditionArgs, tsQuery, terms.Name)
+		ranks = append(ranks, "0.7 * word_similarity(?, search_name) + 0.3 * ts_rank(to_tsvector('simple', search_name), to_tsquery('simple', ?))")
+		rankArgs = append(rankArgs, terms.Name, tsQuery)
+	}
+	if terms.NationalID != "" {
+		conditions = append(conditions, "national_id LIKE ?")
+		conditionArgs = append(conditionArgs, terms.NationalID+"%")
+		ranks = append(ranks, "CASE WHEN national_id = ? THEN 1 WHEN national_id LIKE ? THEN 0.9 ELSE 0 END")
+		rankArgs = append(rankArgs, terms.NationalID, terms.NationalID+"%")
+	}
+	if terms.Phone != "" {
+		conditions = append(conditions, "search_phone LIKE ?")
+		conditionArgs = append(conditionArgs, "%"+terms.Phone+"%")
+		ranks = append(ranks, "CASE WHEN search_phone = ? THEN 0.95 WHEN search_phone LIKE ? THEN 0.8 ELSE 0 END")
+		rankArgs = append(rankArgs, terms.Phone, "%"+terms.Phone+"%")
+	}
+	if terms.BirthDate != "" {
+		conditions = append(conditions, "search_birth_date = ?")
+		conditionArgs = append(conditionArgs, terms.BirthDate)
+		ranks = append(ranks, "CASE WHEN search_birth_date = ? THEN 0.8 ELSE 0 END")
+		rankArgs = append(rankArgs, terms.BirthDate)
+	}
+
+	page := patient.SearchPage{Patients: []patient.SearchResult{}, Page: query.Page, PageSize: query.PageSize}
+	if len(conditions) == 0 {
+		return page, nil
+	}
+
+	db := repo.DB.WithContext(ctx).
+		Model(&patient.Patient{}).
+		Where("clinic_id = ?", query.ClinicID).
+		Where("("+strings.Join(conditions, " OR ")+")", conditionArgs...)
+
+	if err := db.Session(&gorm.Session{}).Count(&page.TotalCount).Error; err != nil {
+		log.Error().
+			Str("operation", "SearchPatients").
+			Err(err).
+			Uint("clinic_id", query.ClinicID).
+			Msg("Failed to count patients")
+		return patient.SearchPage{}, err
+	}
+
+	rank := "GREATEST(" + strings.Join(ranks, ", ") + ")"
+	if len(ranks) == 1 {
+		rank = ranks[0]
+	}
+	result := db.
+		Select("patients.*, "+rank+" AS rank", rankArgs...).
+		Order("rank DESC, name, id").
+		Limit(query.PageSize).
+		Offset(query.Offset()).
+		Find(&page.Patients)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "SearchPatients").
+			Err(result.Error).
+			Uint("clinic_id", query.ClinicID).
+			Msg("Failed to search patients")
+		return patient.SearchPage{}, result.Error
+	}
+
+	log.Info().
+		Str("operation", "SearchPatients").
+		Uint("clinic_id", query.ClinicID).
+		Int("count", len(page.Patients)).
+		Int64("total_count", page.TotalCount).
+		Msg("Searched patients successfully")
+
+	return page, nil
+}
+
 // GetPatient retrieves a single patient by its ID
 func (repo *Repository) GetPatient(ctx context.Context, id uint) (patient.Patient, error) {
 	var pt patient.Patient
@@ -67,8 +150,26 @@ func (repo *Repository) GetPatient(ctx context.Context, id uint) (patient.Patien
 	return pt, nil
 }
 
+// GetPatientByNationalID retrieves the patient of a clinic with the given national ID
+func (repo *Repository) GetPatientByNationalID(ctx context.Context, clinicID uint, nationalID string) (patient.Patient, error) {
+	var pt patient.Patient
+	result := repo.DB.WithContext(ctx).Where("clinic_id = ? AND national_id = ?", clinicID, nationalID).First(&pt)
+	if result.Error != nil {
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			log.Error().
+				Str("operation", "GetPatientByNationalID").
+				Err(result.Error).
+				Uint("clinic_id", clinicID).
+				Msg("Failed to retrieve patient")
+		}
+		return patient.Patient{}, result.Error
+	}
+	return pt, nil
+}
+
 // CreatePatient creates a new patient record in the database
 func (repo *Repository) CreatePatient(ctx context.Context, newPt patient.Patient) (patient.Patient, error) {
+	newPt.SetSearchFields()
 	result := repo.DB.WithContext(ctx).Create(&newPt)
 	if result.Error != nil {
 		log.Error().
@@ -86,6 +187,7 @@ func (repo *Repository) CreatePatient(ctx context.Context, newPt patient.Patient
 
 // UpdatePatient updates an existing patient record in the database
 func (repo *Repository) UpdatePatient(ctx context.Context, updatedPt patient.Patient) (patient.Patient, error) {
+	updatedPt.SetSearchFields()
 	result := repo.DB.WithContext(ctx).Save(&updatedPt)
 	if result.Error != nil {
 		log.Error().
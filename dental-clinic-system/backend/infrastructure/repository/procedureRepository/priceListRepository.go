@@ -0,0 +1,200 @@
+package procedureRepository
+
+import (
+	"context"
+	"dental-clinic-system/models/procedure"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// priceListLockNamespace is the advisory lock namespace that serialises price list changes of a clinic
+const priceListLockNamespace = 1201
+
+// GetPriceLists retrieves the price lists of a clinic, newest first, without their entries
+func (repo *Repository) GetPriceLists(ctx context.Context, clinicID uint) ([]procedure.PriceList, error) {
+	var lists []procedure.PriceList
+	result := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Order("effective_from DESC").
+		Find(&lists)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetPriceLists").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve price lists")
+		return nil, result.Error
+	}
+	return lists, nil
+}
+
+// GetPriceList retrieves a price list with its entries ordered by procedure code
+func (repo *Repository) GetPriceList(ctx context.Context, id uint) (procedure.PriceList, error) {
+	var list procedure.PriceList
+	result := repo.DB.WithContext(ctx).
+		Preload("Entries", func(db *gorm.DB) *gorm.DB {
+			return db.Order("code, procedure_id")
+		}).
+		First(&list, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return procedure.PriceList{}, procedure.ErrPriceListNotFound
+		}
+		log.Error().
+			Str("operation", "GetPriceList").
+			Err(result.Error).
+			Uint("price_list_id", id).
+			Msg("Failed to retrieve price list")
+		return procedure.PriceList{}, result.Error
+	}
+	return list, nil
+}
+
+// CreatePriceList saves the given procedures, creating those without an ID, and then creates the price list,
+// all in a single transaction. Entries without a procedure ID are matched to the clinic's procedures by code, and every entry gets
+// the current code of its procedure.
+func (repo *Repository) CreatePriceList(ctx context.Context, list procedure.PriceList, procedures []procedure.Procedure) (procedure.PriceList, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", priceListLockNamespace, int32(list.ClinicID)).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&procedure.PriceList{}).
+			Where("clinic_id = ? AND effective_from = ?", list.ClinicID, list.EffectiveFrom).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return procedure.ErrPriceListConflict
+		}
+
+		for i := range procedures {
+			procedures[i].ClinicID = list.ClinicID
+			if err := tx.Omit(clause.Associations).Save(&procedures[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		var procs []procedure.Procedure
+		if err := tx.Where("clinic_id = ?", list.ClinicID).Find(&procs).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]procedure.Procedure, len(procs))
+		byCode := make(map[string]procedure.Procedure, len(procs))
+		for _, proc := range procs {
+			byID[proc.ID] = proc
+			if proc.Code != "" {
+				byCode[proc.Code] = proc
+			}
+		}
+
+		listed := make(map[uint]bool, len(list.Entries))
+		for i := range list.Entries {
+			entry := &list.Entries[i]
+			proc, ok := byID[entry.ProcedureID]
+			if entry.ProcedureID == 0 {
+				proc, ok = byCode[entry.Code]
+			}
+			if !ok {
+				return fmt.Errorf("%w: entry %d: unknown procedure %s", procedure.ErrPriceListValidation, i+1, entryReference(*entry))
+			}
+			if listed[proc.ID] {
+				return fmt.Errorf("%w: entry %d: procedure %d is listed more than once", procedure.ErrPriceListValidation, i+1, proc.ID)
+			}
+			listed[proc.ID] = true
+			entry.ID = 0
+			entry.ProcedureID = proc.ID
+			entry.Code = proc.Code
+		}
+
+		return tx.Create(&list).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "CreatePriceList").
+			Err(err).
+			Uint("clinic_id", list.ClinicID).
+			Str("effective_from", list.EffectiveFrom).
+			Msg("Failed to create price list")
+		return procedure.PriceList{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreatePriceList").
+		Uint("price_list_id", list.ID).
+		Uint("clinic_id", list.ClinicID).
+		Int("entries", len(list.Entries)).
+		Int("saved_procedures", len(procedures)).
+		Msg("Price list created successfully")
+
+	return repo.GetPriceList(ctx, list.ID)
+}
+
+// DeletePriceList deletes a price list that takes effect after the given date (YYYY-MM-DD)
+func (repo *Repository) DeletePriceList(ctx context.Context, id uint, today string) error {
+	result := repo.DB.WithContext(ctx).
+		Where("effective_from > ?", today).
+		Delete(&procedure.PriceList{}, id)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "DeletePriceList").
+			Err(result.Error).
+			Uint("price_list_id", id).
+			Msg("Failed to delete price list")
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return procedure.ErrPriceListInEffect
+	}
+
+	log.Info().
+		Str("operation", "DeletePriceList").
+		Uint("price_list_id", id).
+		Msg("Price list deleted successfully")
+	return nil
+}
+
+// GetPriceEntry finds the price of a procedure in the newest of its clinic's price lists that
+// took effect on or before the given date (YYYY-MM-DD) and lists the procedure
+func (repo *Repository) GetPriceEntry(ctx context.Context, procedureID uint, date string) (procedure.PriceList, procedure.PriceListEntry, error) {
+	var entry procedure.PriceListEntry
+	result := repo.DB.WithContext(ctx).
+		Select("price_list_entries.*").
+		Joins("JOIN price_lists ON price_lists.id = price_list_entries.price_list_id AND price_lists.deleted_at IS NULL").
+		Joins("JOIN procedures ON procedures.id = price_list_entries.procedure_id AND procedures.clinic_id = price_lists.clinic_id").
+		Where("price_list_entries.procedure_id = ? AND price_lists.effective_from <= ?", procedureID, date).
+		Order("price_lists.effective_from DESC").
+		Limit(1).
+		Find(&entry)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetPriceEntry").
+			Err(result.Error).
+			Uint("procedure_id", procedureID).
+			Str("date", date).
+			Msg("Failed to retrieve procedure price")
+		return procedure.PriceList{}, procedure.PriceListEntry{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return procedure.PriceList{}, procedure.PriceListEntry{}, procedure.ErrPriceNotFound
+	}
+
+	var list procedure.PriceList
+	if err := repo.DB.WithContext(ctx).First(&list, entry.PriceListID).Error; err != nil {
+		return procedure.PriceList{}, procedure.PriceListEntry{}, err
+	}
+	return list, entry, nil
+}
+
+// entryReference describes the procedure an entry refers to for error messages
+func entryReference(entry procedure.PriceListEntry) string {
+	if entry.ProcedureID != 0 {
+		return fmt.Sprintf("%d", entry.ProcedureID)
+	}
+	return entry.Code
+}
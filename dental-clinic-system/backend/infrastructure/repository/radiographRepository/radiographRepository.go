@@ -0,0 +1,125 @@
+package radiographRepository
+
+import (
+	"context"
+	"errors"
+
+	"dental-clinic-system/models/radiograph"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles radiograph database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetImages retrieves the radiographs of a patient, newest study first, without their previews
+func (repo *Repository) GetImages(ctx context.Context, clinicID, patientID uint, filter radiograph.Filter) ([]radiograph.Image, error) {
+	var images []radiograph.Image
+	query := repo.DB.WithContext(ctx).
+		Omit("preview").
+		Where("clinic_id = ? AND patient_id = ?", clinicID, patientID).
+		Order("study_date DESC, study_instance_uid, id")
+	if filter.Modality != "" {
+		query = query.Where("modality = ?", filter.Modality)
+	}
+	if filter.From != "" {
+		query = query.Where("study_date >= ?", filter.From)
+	}
+	if filter.To != "" {
+		query = query.Where("study_date <= ?", filter.To)
+	}
+
+	if err := query.Find(&images).Error; err != nil {
+		log.Error().
+			Str("operation", "GetImages").
+			Err(err).
+			Uint("patient_id", patientID).
+			Msg("Failed to retrieve radiographs")
+		return nil, err
+	}
+	return images, nil
+}
+
+// GetImage retrieves a radiograph without its preview
+func (repo *Repository) GetImage(ctx context.Context, id uint) (radiograph.Image, error) {
+	var image radiograph.Image
+	result := repo.DB.WithContext(ctx).Omit("preview").First(&image, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return radiograph.Image{}, radiograph.ErrImageNotFound
+		}
+		log.Error().
+			Str("operation", "GetImage").
+			Err(result.Error).
+			Uint("radiograph_id", id).
+			Msg("Failed to retrieve radiograph")
+		return radiograph.Image{}, result.Error
+	}
+	return image, nil
+}
+
+// GetPreview retrieves the PNG preview of a radiograph
+func (repo *Repository) GetPreview(ctx context.Context, id uint) ([]byte, error) {
+	var image radiograph.Image
+	result := repo.DB.WithContext(ctx).Select("id", "preview").First(&image, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, radiograph.ErrImageNotFound
+		}
+		log.Error().
+			Str("operation", "GetPreview").
+			Err(result.Error).
+			Uint("radiograph_id", id).
+			Msg("Failed to retrieve radiograph preview")
+		return nil, result.Error
+	}
+	return image.Preview, nil
+}
+
+// ImageExists reports whether the clinic already has the DICOM image with the given SOP instance UID
+func (repo *Repository) ImageExists(ctx context.Context, clinicID uint, sopInstanceUID string) (bool, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).
+		Model(&radiograph.Image{}).
+		Where("clinic_id = ? AND sop_instance_uid = ?", clinicID, sopInstanceUID).
+		Count(&count).Error
+	if err != nil {
+		log.Error().
+			Str("operation", "ImageExists").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to look up radiograph")
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateImage stores an ingested radiograph
+func (repo *Repository) CreateImage(ctx context.Context, image radiograph.Image) (radiograph.Image, error) {
+	if err := repo.DB.WithContext(ctx).Create(&image).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateImage").
+			Err(err).
+			Uint("patient_id", image.PatientID).
+			Msg("Failed to create radiograph")
+		return radiograph.Image{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateImage").
+		Uint("radiograph_id", image.ID).
+		Uint("patient_id", image.PatientID).
+		Str("modality", image.Modality).
+		Msg("Radiograph created successfully")
+
+	return image, nil
+}
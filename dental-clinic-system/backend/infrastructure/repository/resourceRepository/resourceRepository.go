@@ -0,0 +1,163 @@
+package resourceRepository
+
+import (
+	"context"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/resource"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles resource-related database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetResources retrieves the resources of a clinic, optionally filtered by type
+func (repo *Repository) GetResources(ctx context.Context, clinicID uint, resourceType resource.Type) ([]resource.Resource, error) {
+	var resources []resource.Resource
+	query := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Order("type, name, id")
+	if resourceType != "" {
+		query = query.Where("type = ?", resourceType)
+	}
+
+	if err := query.Find(&resources).Error; err != nil {
+		log.Error().
+			Str("operation", "GetResources").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve resources")
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// GetResource retrieves a single resource by its ID
+func (repo *Repository) GetResource(ctx context.Context, id uint) (resource.Resource, error) {
+	var res resource.Resource
+	result := repo.DB.WithContext(ctx).First(&res, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return resource.Resource{}, resource.ErrResourceNotFound
+		}
+		log.Error().
+			Str("operation", "GetResource").
+			Err(result.Error).
+			Uint("resource_id", id).
+			Msg("Failed to retrieve resource")
+		return resource.Resource{}, result.Error
+	}
+	return res, nil
+}
+
+// CreateResource creates a new resource record in the database
+func (repo *Repository) CreateResource(ctx context.Context, newResource resource.Resource) (resource.Resource, error) {
+	if err := repo.DB.WithContext(ctx).Create(&newResource).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateResource").
+			Err(err).
+			Uint("clinic_id", newResource.ClinicID).
+			Msg("Failed to create resource")
+		return resource.Resource{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateResource").
+		Uint("resource_id", newResource.ID).
+		Msg("Resource created successfully")
+
+	return newResource, nil
+}
+
+// UpdateResource updates an existing resource record in the database
+func (repo *Repository) UpdateResource(ctx context.Context, updatedResource resource.Resource) (resource.Resource, error) {
+	if err := repo.DB.WithContext(ctx).Omit("Clinic").Save(&updatedResource).Error; err != nil {
+		log.Error().
+			Str("operation", "UpdateResource").
+			Err(err).
+			Uint("resource_id", updatedResource.ID).
+			Msg("Failed to update resource")
+		return resource.Resource{}, err
+	}
+
+	log.Info().
+		Str("operation", "UpdateResource").
+		Uint("resource_id", updatedResource.ID).
+		Msg("Resource updated successfully")
+
+	return updatedResource, nil
+}
+
+// DeleteResource deletes a resource record from the database by its ID
+func (repo *Repository) DeleteResource(ctx context.Context, id uint) error {
+	if err := repo.DB.WithContext(ctx).Delete(&resource.Resource{}, id).Error; err != nil {
+		log.Error().
+			Str("operation", "DeleteResource").
+			Err(err).
+			Uint("resource_id", id).
+			Msg("Failed to delete resource")
+		return err
+	}
+
+	log.Info().
+		Str("operation", "DeleteResource").
+		Uint("resource_id", id).
+		Msg("Resource deleted successfully")
+
+	return nil
+}
+
+// GetResourceBookings retrieves, per resource, the active appointments reserving it that overlap [from, to)
+func (repo *Repository) GetResourceBookings(ctx context.Context, resourceIDs []uint, from, to time.Time) (map[uint][]resource.Booking, error) {
+	bookings := map[uint][]resource.Booking{}
+	if len(resourceIDs) == 0 {
+		return bookings, nil
+	}
+
+	var rows []struct {
+		ResourceID    uint
+		AppointmentID uint
+		DoctorID      uint
+		ScheduledTime time.Time
+		EndTime       time.Time
+	}
+	err := repo.DB.WithContext(ctx).
+		Model(&appointment.Appointment{}).
+		Select("appointment_resources.resource_id, appointments.id AS appointment_id, appointments.doctor_id, appointments.scheduled_time, appointments.end_time").
+		Joins("JOIN appointment_resources ON appointment_resources.appointment_id = appointments.id").
+		Where("appointment_resources.resource_id IN ?", resourceIDs).
+		Where("appointments.status NOT IN ?", appointment.InactiveStatuses).
+		Where("appointments.scheduled_time < ? AND appointments.end_time > ?", to, from).
+		Order("appointments.scheduled_time").
+		Scan(&rows).Error
+	if err != nil {
+		log.Error().
+			Str("operation", "GetResourceBookings").
+			Err(err).
+			Msg("Failed to retrieve resource bookings")
+		return nil, err
+	}
+
+	for _, row := range rows {
+		bookings[row.ResourceID] = append(bookings[row.ResourceID], resource.Booking{
+			AppointmentID: row.AppointmentID,
+			DoctorID:      row.DoctorID,
+			Start:         row.ScheduledTime,
+			End:           row.EndTime,
+		})
+	}
+
+	return bookings, nil
+}
@@ -0,0 +1,218 @@
+package scheduleRepository
+
+import (
+	"context"
+	"dental-clinic-system/models/schedule"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles opening hours, doctor schedules, exceptions and public holidays
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetOpeningHours retrieves the weekly opening hours of a clinic
+func (repo *Repository) GetOpeningHours(ctx context.Context, clinicID uint) ([]schedule.OpeningHours, error) {
+	var hours []schedule.OpeningHours
+	result := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Order("weekday, opens_at").
+		Find(&hours)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetOpeningHours").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve opening hours")
+		return nil, result.Error
+	}
+	return hours, nil
+}
+
+// ReplaceOpeningHours replaces the whole weekly opening hours of a clinic
+func (repo *Repository) ReplaceOpeningHours(ctx context.Context, clinicID uint, hours []schedule.OpeningHours) ([]schedule.OpeningHours, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("clinic_id = ?", clinicID).Delete(&schedule.OpeningHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+	if err != nil {
+		log.Error().
+			Str("operation", "ReplaceOpeningHours").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to replace opening hours")
+		return nil, err
+	}
+
+	log.Info().
+		Str("operation", "ReplaceOpeningHours").
+		Uint("clinic_id", clinicID).
+		Int("count", len(hours)).
+		Msg("Opening hours replaced successfully")
+	return hours, nil
+}
+
+// GetDoctorSchedules retrieves the weekly schedule of a doctor including breaks
+func (repo *Repository) GetDoctorSchedules(ctx context.Context, doctorID uint) ([]schedule.DoctorSchedule, error) {
+	var schedules []schedule.DoctorSchedule
+	result := repo.DB.WithContext(ctx).
+		Where("doctor_id = ?", doctorID).
+		Preload("Breaks").
+		Order("weekday, start_time").
+		Find(&schedules)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetDoctorSchedules").
+			Err(result.Error).
+			Uint("doctor_id", doctorID).
+			Msg("Failed to retrieve doctor schedules")
+		return nil, result.Error
+	}
+	return schedules, nil
+}
+
+// ReplaceDoctorSchedules replaces the whole weekly schedule of a doctor
+func (repo *Repository) ReplaceDoctorSchedules(ctx context.Context, doctorID uint, schedules []schedule.DoctorSchedule) ([]schedule.DoctorSchedule, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var scheduleIDs []uint
+		if err := tx.Model(&schedule.DoctorSchedule{}).Where("doctor_id = ?", doctorID).Pluck("id", &scheduleIDs).Error; err != nil {
+			return err
+		}
+		if len(scheduleIDs) > 0 {
+			if err := tx.Unscoped().Where("doctor_schedule_id IN ?", scheduleIDs).Delete(&schedule.ScheduleBreak{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN ?", scheduleIDs).Delete(&schedule.DoctorSchedule{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(schedules) == 0 {
+			return nil
+		}
+		return tx.Create(&schedules).Error
+	})
+	if err != nil {
+		log.Error().
+			Str("operation", "ReplaceDoctorSchedules").
+			Err(err).
+			Uint("doctor_id", doctorID).
+			Msg("Failed to replace doctor schedules")
+		return nil, err
+	}
+
+	log.Info().
+		Str("operation", "ReplaceDoctorSchedules").
+		Uint("doctor_id", doctorID).
+		Int("count", len(schedules)).
+		Msg("Doctor schedules replaced successfully")
+	return schedules, nil
+}
+
+// GetScheduleExceptions retrieves the exceptions of a clinic overlapping the given date range
+func (repo *Repository) GetScheduleExceptions(ctx context.Context, clinicID uint, fromDate, toDate string) ([]schedule.ScheduleException, error) {
+	var exceptions []schedule.ScheduleException
+	result := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Where("start_date <= ? AND end_date >= ?", toDate, fromDate).
+		Order("start_date").
+		Find(&exceptions)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetScheduleExceptions").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve schedule exceptions")
+		return nil, result.Error
+	}
+	return exceptions, nil
+}
+
+// GetScheduleException retrieves a single schedule exception by its ID
+func (repo *Repository) GetScheduleException(ctx context.Context, id uint) (schedule.ScheduleException, error) {
+	var exception schedule.ScheduleException
+	result := repo.DB.WithContext(ctx).First(&exception, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			log.Warn().
+				Str("operation", "GetScheduleException").
+				Uint("exception_id", id).
+				Msg("Schedule exception not found")
+			return schedule.ScheduleException{}, schedule.ErrScheduleExceptionNotFound
+		}
+		log.Error().
+			Str("operation", "GetScheduleException").
+			Err(result.Error).
+			Uint("exception_id", id).
+			Msg("Failed to retrieve schedule exception")
+		return schedule.ScheduleException{}, result.Error
+	}
+	return exception, nil
+}
+
+// CreateScheduleException creates a new schedule exception
+func (repo *Repository) CreateScheduleException(ctx context.Context, exception schedule.ScheduleException) (schedule.ScheduleException, error) {
+	result := repo.DB.WithContext(ctx).Create(&exception)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "CreateScheduleException").
+			Err(result.Error).
+			Msg("Failed to create schedule exception")
+		return schedule.ScheduleException{}, result.Error
+	}
+
+	log.Info().
+		Str("operation", "CreateScheduleException").
+		Uint("exception_id", exception.ID).
+		Msg("Schedule exception created successfully")
+	return exception, nil
+}
+
+// DeleteScheduleException deletes a schedule exception by its ID
+func (repo *Repository) DeleteScheduleException(ctx context.Context, id uint) error {
+	result := repo.DB.WithContext(ctx).Delete(&schedule.ScheduleException{}, id)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "DeleteScheduleException").
+			Err(result.Error).
+			Uint("exception_id", id).
+			Msg("Failed to delete schedule exception")
+		return result.Error
+	}
+
+	log.Info().
+		Str("operation", "DeleteScheduleException").
+		Uint("exception_id", id).
+		Msg("Schedule exception deleted successfully")
+	return nil
+}
+
+// GetPublicHolidays retrieves the public holidays in the given date range
+func (repo *Repository) GetPublicHolidays(ctx context.Context, fromDate, toDate string) ([]schedule.PublicHoliday, error) {
+	var holidays []schedule.PublicHoliday
+	result := repo.DB.WithContext(ctx).
+		Where("date BETWEEN ? AND ?", fromDate, toDate).
+		Order("date").
+		Find(&holidays)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetPublicHolidays").
+			Err(result.Error).
+			Msg("Failed to retrieve public holidays")
+		return nil, result.Error
+	}
+	return holidays, nil
+}
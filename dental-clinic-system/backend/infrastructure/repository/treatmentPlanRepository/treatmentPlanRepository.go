@@ -0,0 +1,328 @@
+package treatmentPlanRepository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dental-clinic-system/infrastructure/repository/appointmentRepository"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/procedure"
+	"dental-clinic-system/models/treatment"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles treatment plan database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// withContent preloads the phases and items of plans in display order
+func withContent(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Phases", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Preload("Phases.Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Preload("Phases.Items.Procedure").
+		Preload("Phases.Items.Appointment")
+}
+
+// GetPlans retrieves the plans of a clinic, newest first, optionally filtered by patient and status
+func (repo *Repository) GetPlans(ctx context.Context, clinicID, patientID uint, status treatment.PlanStatus) ([]treatment.Plan, error) {
+	var plans []treatment.Plan
+	query := withContent(repo.DB.WithContext(ctx)).
+		Where("clinic_id = ?", clinicID).
+		Order("created_at DESC, id DESC")
+	if patientID != 0 {
+		query = query.Where("patient_id = ?", patientID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&plans).Error; err != nil {
+		log.Error().
+			Str("operation", "GetPlans").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve treatment plans")
+		return nil, err
+	}
+
+	return plans, nil
+}
+
+// GetPlan retrieves a plan with its phases, items and linked appointments
+func (repo *Repository) GetPlan(ctx context.Context, id uint) (treatment.Plan, error) {
+	var plan treatment.Plan
+	result := withContent(repo.DB.WithContext(ctx)).First(&plan, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return treatment.Plan{}, treatment.ErrPlanNotFound
+		}
+		log.Error().
+			Str("operation", "GetPlan").
+			Err(result.Error).
+			Uint("plan_id", id).
+			Msg("Failed to retrieve treatment plan")
+		return treatment.Plan{}, result.Error
+	}
+	return plan, nil
+}
+
+// GetPlanByItem retrieves the plan that contains the given item
+func (repo *Repository) GetPlanByItem(ctx context.Context, itemID uint) (treatment.Plan, error) {
+	var planID uint
+	result := repo.DB.WithContext(ctx).
+		Table("treatment_plan_items").
+		Select("treatment_plan_phases.plan_id").
+		Joins("JOIN treatment_plan_phases ON treatment_plan_phases.id = treatment_plan_items.phase_id").
+		Where("treatment_plan_items.id = ? AND treatment_plan_items.deleted_at IS NULL", itemID).
+		Scan(&planID)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetPlanByItem").
+			Err(result.Error).
+			Uint("item_id", itemID).
+			Msg("Failed to retrieve treatment plan item")
+		return treatment.Plan{}, result.Error
+	}
+	if planID == 0 {
+		return treatment.Plan{}, treatment.ErrItemNotFound
+	}
+	return repo.GetPlan(ctx, planID)
+}
+
+// CreatePlan stores a plan together with its phases and items
+func (repo *Repository) CreatePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error) {
+	detachReferences(plan.Phases)
+	if err := repo.DB.WithContext(ctx).Create(&plan).Error; err != nil {
+		log.Error().
+			Str("operation", "CreatePlan").
+			Err(err).
+			Uint("patient_id", plan.PatientID).
+			Msg("Failed to create treatment plan")
+		return treatment.Plan{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreatePlan").
+		Uint("plan_id", plan.ID).
+		Msg("Treatment plan created successfully")
+
+	return repo.GetPlan(ctx, plan.ID)
+}
+
+// ReplacePlan overwrites the details, phases and items of a draft plan
+func (repo *Repository) ReplacePlan(ctx context.Context, plan treatment.Plan) (treatment.Plan, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&treatment.Plan{}).
+			Where("id = ? AND status = ?", plan.ID, treatment.PlanDraft).
+			Updates(map[string]interface{}{
+				"title":     plan.Title,
+				"notes":     plan.Notes,
+				"currency":  plan.Currency,
+				"doctor_id": plan.DoctorID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return treatment.ErrPlanNotEditable
+		}
+
+		phaseIDs := tx.Model(&treatment.Phase{}).Select("id").Where("plan_id = ?", plan.ID)
+		if err := tx.Unscoped().Where("phase_id IN (?)", phaseIDs).Delete(&treatment.Item{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("plan_id = ?", plan.ID).Delete(&treatment.Phase{}).Error; err != nil {
+			return err
+		}
+
+		detachReferences(plan.Phases)
+		for i := range plan.Phases {
+			plan.Phases[i].ID = 0
+			plan.Phases[i].PlanID = plan.ID
+			for j := range plan.Phases[i].Items {
+				plan.Phases[i].Items[j].ID = 0
+			}
+		}
+		if len(plan.Phases) == 0 {
+			return nil
+		}
+		return tx.Create(&plan.Phases).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "ReplacePlan").
+			Err(err).
+			Uint("plan_id", plan.ID).
+			Msg("Failed to update treatment plan")
+		return treatment.Plan{}, err
+	}
+
+	log.Info().
+		Str("operation", "ReplacePlan").
+		Uint("plan_id", plan.ID).
+		Msg("Treatment plan updated successfully")
+
+	return repo.GetPlan(ctx, plan.ID)
+}
+
+// detachReferences clears the procedures and appointments loaded into items,
+// so saving a plan only writes the referencing IDs and never the referenced rows
+func detachReferences(phases []treatment.Phase) {
+	for i := range phases {
+		for j := range phases[i].Items {
+			phases[i].Items[j].Procedure = procedure.Procedure{}
+			phases[i].Items[j].Appointment = nil
+		}
+	}
+}
+
+// TransitionStatus moves a plan to a new status if it is still in the expected status.
+// The timestamp column belonging to the new status, if any, is set to at.
+func (repo *Repository) TransitionStatus(ctx context.Context, id uint, from, to treatment.PlanStatus, at time.Time) (treatment.Plan, error) {
+	updates := map[string]interface{}{"status": to}
+	switch to {
+	case treatment.PlanPresented:
+		updates["presented_at"] = at
+	case treatment.PlanAccepted, treatment.PlanDeclined:
+		updates["decided_at"] = at
+	case treatment.PlanCompleted:
+		updates["completed_at"] = at
+	case treatment.PlanDraft:
+		updates["presented_at"] = nil
+	}
+
+	result := repo.DB.WithContext(ctx).
+		Model(&treatment.Plan{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "TransitionStatus").
+			Err(result.Error).
+			Uint("plan_id", id).
+			Msg("Failed to change treatment plan status")
+		return treatment.Plan{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return treatment.Plan{}, &treatment.InvalidTransitionError{From: from, To: to}
+	}
+
+	log.Info().
+		Str("operation", "TransitionStatus").
+		Uint("plan_id", id).
+		Str("status", string(to)).
+		Msg("Treatment plan status changed successfully")
+
+	return repo.GetPlan(ctx, id)
+}
+
+// ScheduleItem books an appointment for a plan item and links it in a single transaction.
+// The item row is locked so two bookings for the same item can not both succeed, and the
+// first booked item moves an accepted plan to in progress.
+func (repo *Repository) ScheduleItem(ctx context.Context, planID, itemID uint, newAppt appointment.Appointment) (appointment.Appointment, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var plan treatment.Plan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, planID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return treatment.ErrPlanNotFound
+			}
+			return err
+		}
+		if !plan.Status.CanSchedule() {
+			return treatment.ErrPlanNotSchedulable
+		}
+
+		var item treatment.Item
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Joins("JOIN treatment_plan_phases ON treatment_plan_phases.id = treatment_plan_items.phase_id").
+			Where("treatment_plan_items.id = ? AND treatment_plan_phases.plan_id = ?", itemID, planID).
+			First(&item).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return treatment.ErrItemNotFound
+			}
+			return err
+		}
+
+		if item.AppointmentID != nil {
+			var linked appointment.Appointment
+			result := tx.Limit(1).Find(&linked, *item.AppointmentID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 && treatment.ItemStatusFor(&linked) != treatment.ItemPlanned {
+				return treatment.ErrItemAlreadyBooked
+			}
+		}
+
+		if err := appointmentRepository.CreateInTransaction(tx, &newAppt); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&treatment.Item{}).
+			Where("id = ?", itemID).
+			Update("appointment_id", newAppt.ID).Error; err != nil {
+			return err
+		}
+
+		if plan.Status == treatment.PlanAccepted {
+			return tx.Model(&treatment.Plan{}).
+				Where("id = ?", planID).
+				Update("status", treatment.PlanInProgress).Error
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "ScheduleItem").
+			Err(err).
+			Uint("plan_id", planID).
+			Uint("item_id", itemID).
+			Msg("Failed to schedule treatment plan item")
+		return appointment.Appointment{}, err
+	}
+
+	log.Info().
+		Str("operation", "ScheduleItem").
+		Uint("plan_id", planID).
+		Uint("item_id", itemID).
+		Uint("appointment_id", newAppt.ID).
+		Msg("Treatment plan item scheduled successfully")
+
+	return newAppt, nil
+}
+
+// GetAppointmentProcedureIDs returns the procedures of the plan items booked on an appointment
+func (repo *Repository) GetAppointmentProcedureIDs(ctx context.Context, appointmentID uint) ([]uint, error) {
+	var ids []uint
+	result := repo.DB.WithContext(ctx).
+		Model(&treatment.Item{}).
+		Where("appointment_id = ?", appointmentID).
+		Distinct("procedure_id").
+		Pluck("procedure_id", &ids)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetAppointmentProcedureIDs").
+			Err(result.Error).
+			Uint("appointment_id", appointmentID).
+			Msg("Failed to retrieve planned procedures of appointment")
+		return nil, result.Error
+	}
+	return ids, nil
+}
@@ -40,6 +40,33 @@ func (repo *Repository) GetUsers(ctx context.Context, clinicID uint) ([]user.Use
 	return usersList, nil
 }
 
+// GetUsersByRole retrieves the users of a clinic that have the given role
+func (repo *Repository) GetUsersByRole(ctx context.Context, clinicID uint, roleName user.RoleName) ([]user.User, error) {
+	var usersList []user.User
+	result := repo.DB.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.user_id = users.id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("users.clinic_id = ? AND roles.name = ?", clinicID, roleName).
+		Preload("Roles").
+		Find(&usersList)
+	if result.Error != nil {
+		log.Error().
+			Str("operation", "GetUsersByRole").
+			Err(result.Error).
+			Uint("clinic_id", clinicID).
+			Str("role", string(roleName)).
+			Msg("Failed to retrieve users by role")
+		return nil, result.Error
+	}
+	log.Info().
+		Str("operation", "GetUsersByRole").
+		Uint("clinic_id", clinicID).
+		Str("role", string(roleName)).
+		Int("count", len(usersList)).
+		Msg("Retrieved users by role successfully")
+	return usersList, nil
+}
+
 // GetUser retrieves a single user by its ID
 func (repo *Repository) GetUser(ctx context.Context, id uint) (user.User, error) {
 	var usr user.User
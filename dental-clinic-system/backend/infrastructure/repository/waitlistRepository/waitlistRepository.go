@@ -0,0 +1,235 @@
+package waitlistRepository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dental-clinic-system/infrastructure/repository/appointmentRepository"
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/waitlist"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Repository handles waitlist-related database operations
+type Repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository creates a new instance of Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// GetEntries retrieves the waitlist of a clinic in priority order, optionally filtered by status
+func (repo *Repository) GetEntries(ctx context.Context, clinicID uint, status waitlist.EntryStatus) ([]waitlist.Entry, error) {
+	var entries []waitlist.Entry
+	query := repo.DB.WithContext(ctx).
+		Where("clinic_id = ?", clinicID).
+		Preload("Windows").
+		Preload("Patient").
+		Order("priority DESC, created_at, id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&entries).Error; err != nil {
+		log.Error().
+			Str("operation", "GetEntries").
+			Err(err).
+			Uint("clinic_id", clinicID).
+			Msg("Failed to retrieve waitlist entries")
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetEntry retrieves a waitlist entry by its ID
+func (repo *Repository) GetEntry(ctx context.Context, id uint) (waitlist.Entry, error) {
+	var entry waitlist.Entry
+	result := repo.DB.WithContext(ctx).
+		Preload("Windows").
+		First(&entry, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return waitlist.Entry{}, waitlist.ErrEntryNotFound
+		}
+		log.Error().
+			Str("operation", "GetEntry").
+			Err(result.Error).
+			Uint("entry_id", id).
+			Msg("Failed to retrieve waitlist entry")
+		return waitlist.Entry{}, result.Error
+	}
+	return entry, nil
+}
+
+// CreateEntry adds a patient to the waitlist together with their preferred time windows
+func (repo *Repository) CreateEntry(ctx context.Context, entry waitlist.Entry) (waitlist.Entry, error) {
+	if err := repo.DB.WithContext(ctx).Create(&entry).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateEntry").
+			Err(err).
+			Uint("patient_id", entry.PatientID).
+			Msg("Failed to create waitlist entry")
+		return waitlist.Entry{}, err
+	}
+
+	log.Info().
+		Str("operation", "CreateEntry").
+		Uint("entry_id", entry.ID).
+		Msg("Waitlist entry created successfully")
+
+	return entry, nil
+}
+
+// UpdateEntryStatus changes the status of an entry if it is still in the expected status
+func (repo *Repository) UpdateEntryStatus(ctx context.Context, id uint, from, to waitlist.EntryStatus) error {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&waitlist.Entry{}).
+			Where("id = ? AND status = ?", id, from).
+			Update("status", to)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return waitlist.ErrEntryNotCancellable
+		}
+		return tx.Model(&waitlist.Offer{}).
+			Where("entry_id = ? AND status = ?", id, waitlist.OfferPending).
+			Update("status", waitlist.OfferSuperseded).Error
+	})
+	if err != nil {
+		log.Error().
+			Str("operation", "UpdateEntryStatus").
+			Err(err).
+			Uint("entry_id", id).
+			Msg("Failed to update waitlist entry status")
+		return err
+	}
+	return nil
+}
+
+// CreateOffers stores the offers made for a freed slot
+func (repo *Repository) CreateOffers(ctx context.Context, offers []waitlist.Offer) ([]waitlist.Offer, error) {
+	if len(offers) == 0 {
+		return offers, nil
+	}
+	if err := repo.DB.WithContext(ctx).Omit("Entry").Create(&offers).Error; err != nil {
+		log.Error().
+			Str("operation", "CreateOffers").
+			Err(err).
+			Uint("source_appointment_id", offers[0].SourceAppointmentID).
+			Msg("Failed to create waitlist offers")
+		return nil, err
+	}
+
+	log.Info().
+		Str("operation", "CreateOffers").
+		Uint("source_appointment_id", offers[0].SourceAppointmentID).
+		Int("count", len(offers)).
+		Msgf("Created %d waitlist offers", len(offers))
+
+	return offers, nil
+}
+
+// GetOffer retrieves an offer with its waitlist entry
+func (repo *Repository) GetOffer(ctx context.Context, id uint) (waitlist.Offer, error) {
+	return repo.getOffer(ctx, "id = ?", id)
+}
+
+// GetOfferByTokenHash retrieves an offer by the hash of its acceptance token
+func (repo *Repository) GetOfferByTokenHash(ctx context.Context, tokenHash string) (waitlist.Offer, error) {
+	return repo.getOffer(ctx, "token_hash = ?", tokenHash)
+}
+
+func (repo *Repository) getOffer(ctx context.Context, query string, args ...interface{}) (waitlist.Offer, error) {
+	var offer waitlist.Offer
+	result := repo.DB.WithContext(ctx).
+		Preload("Entry").
+		Where(query, args...).
+		First(&offer)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return waitlist.Offer{}, waitlist.ErrOfferNotFound
+		}
+		log.Error().
+			Str("operation", "GetOffer").
+			Err(result.Error).
+			Msg("Failed to retrieve waitlist offer")
+		return waitlist.Offer{}, result.Error
+	}
+	return offer, nil
+}
+
+// ClaimOffer accepts an offer and books its slot in a single transaction.
+// Only one pending, unexpired offer of a slot can be accepted: the offer row update acts as the claim,
+// the competing offers are superseded, and the booking itself goes through the regular overlap check,
+// so the slot can not be taken twice even if it was booked outside the waitlist meanwhile.
+func (repo *Repository) ClaimOffer(ctx context.Context, offerID uint, now time.Time, newAppt appointment.Appointment) (appointment.Appointment, error) {
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var offer waitlist.Offer
+		if err := tx.First(&offer, offerID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return waitlist.ErrOfferNotFound
+			}
+			return err
+		}
+
+		result := tx.Model(&waitlist.Offer{}).
+			Where("id = ? AND status = ? AND expires_at > ?", offerID, waitlist.OfferPending, now).
+			Update("status", waitlist.OfferAccepted)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return waitlist.ErrOfferNotAvailable
+		}
+
+		result = tx.Model(&waitlist.Entry{}).
+			Where("id = ? AND status = ?", offer.EntryID, waitlist.EntryWaiting).
+			Update("status", waitlist.EntryBooked)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return waitlist.ErrOfferNotAvailable
+		}
+
+		// Competing offers for the same slot and other offers made to the same entry are void now
+		if err := tx.Model(&waitlist.Offer{}).
+			Where("id <> ? AND status = ?", offerID, waitlist.OfferPending).
+			Where("source_appointment_id = ? OR entry_id = ?", offer.SourceAppointmentID, offer.EntryID).
+			Update("status", waitlist.OfferSuperseded).Error; err != nil {
+			return err
+		}
+
+		if err := appointmentRepository.CreateInTransaction(tx, &newAppt); err != nil {
+			return err
+		}
+
+		return tx.Model(&waitlist.Offer{}).
+			Where("id = ?", offerID).
+			Update("appointment_id", newAppt.ID).Error
+	})
+	if err != nil {
+		log.Warn().
+			Str("operation", "ClaimOffer").
+			Err(err).
+			Uint("offer_id", offerID).
+			Msg("Failed to claim waitlist offer")
+		return appointment.Appointment{}, err
+	}
+
+	log.Info().
+		Str("operation", "ClaimOffer").
+		Uint("offer_id", offerID).
+		Uint("appointment_id", newAppt.ID).
+		Msg("Waitlist offer claimed successfully")
+
+	return newAppt, nil
+}
@@ -0,0 +1,93 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+)
+
+// Content types of captured signatures
+const (
+	TypePNG = "image/png"
+	TypeSVG = "image/svg+xml"
+)
+
+// Limits of captured signatures
+const (
+	MaxBytes     = 1 << 20
+	MaxDimension = 4000
+)
+
+// Error types
+var (
+	ErrInvalidSignature     = errors.New("invalid signature")
+	ErrUnsupportedSignature = errors.New("signatures must be PNG or SVG images")
+)
+
+// DecodeDataURL reads a signature sent as a base64 data URL, as signature pads produce them,
+// and returns its content type and bytes
+func DecodeDataURL(value string) (string, []byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimSpace(value), ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return "", nil, fmt.Errorf("%w: expected a base64 data URL", ErrInvalidSignature)
+	}
+
+	contentType := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64"))
+	contentType, _, _ = strings.Cut(contentType, ";")
+	if contentType != TypePNG && contentType != TypeSVG {
+		return "", nil, ErrUnsupportedSignature
+	}
+	if base64.StdEncoding.DecodedLen(len(payload)) > MaxBytes {
+		return "", nil, fmt.Errorf("%w: the image is larger than %d KB", ErrInvalidSignature, MaxBytes>>10)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+	}
+	return contentType, data, nil
+}
+
+// ToPNG checks a captured signature and returns it as a PNG that can be placed on documents.
+// PNG signatures are returned as they are; SVG signatures are drawn with Rasterize.
+func ToPNG(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case TypePNG:
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+		}
+		if cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+			return nil, fmt.Errorf("%w: the image is larger than %dx%d pixels", ErrInvalidSignature, MaxDimension, MaxDimension)
+		}
+		if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+		}
+		return data, nil
+	case TypeSVG:
+		img, err := Rasterize(data)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, ErrUnsupportedSignature
+}
+
+// isBlank reports whether nothing was drawn on the image
+func isBlank(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,450 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rasterizing settings
+const (
+	// maxRasterSize is the longest side, in pixels, of a rasterized signature
+	maxRasterSize = 1000
+	// defaultStrokeWidth is used for strokes without a stroke-width, in SVG user units
+	defaultStrokeWidth = 2.0
+	maxStrokePixels    = 40.0
+	// curveSegments is the number of straight lines a Bézier curve is drawn with
+	curveSegments = 16
+	// maxStamps bounds the work done for a single signature
+	maxStamps = 4_000_000
+)
+
+type point struct {
+	x, y float64
+}
+
+// canvas draws strokes as rows of round stamps
+type canvas struct {
+	img    *image.NRGBA
+	scale  float64
+	minX   float64
+	minY   float64
+	stamps int
+}
+
+// Rasterize draws the strokes of an SVG signature in black on a transparent image. Signature pads
+// write strokes as paths, polylines, lines and dots as circles, which is all that is drawn; fills
+// other than those of circles, transforms, styles and text are ignored and arcs are drawn as straight lines.
+// The image keeps the aspect ratio of the SVG's viewBox, or of its width and height, and is at most
+// 1000 pixels wide or high.
+func Rasterize(data []byte) (*image.NRGBA, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var c *canvas
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, a := range start.Attr {
+			attrs[strings.ToLower(a.Name.Local)] = a.Value
+		}
+
+		name := strings.ToLower(start.Name.Local)
+		if c == nil {
+			if name != "svg" {
+				return nil, fmt.Errorf("%w: the document is not an SVG image", ErrInvalidSignature)
+			}
+			if c, err = newCanvas(attrs); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := c.element(name, attrs); err != nil {
+			return nil, err
+		}
+	}
+
+	if c == nil {
+		return nil, fmt.Errorf("%w: the document is not an SVG image", ErrInvalidSignature)
+	}
+	if isBlank(c.img) {
+		return nil, fmt.Errorf("%w: the signature is empty", ErrInvalidSignature)
+	}
+	return c.img, nil
+}
+
+// newCanvas sizes the image from the viewBox, or the width and height, of the root element
+func newCanvas(attrs map[string]string) (*canvas, error) {
+	var minX, minY, width, height float64
+	if box := numbers(attrs["viewbox"]); len(box) == 4 {
+		minX, minY, width, height = box[0], box[1], box[2], box[3]
+	} else {
+		width, height = length(attrs["width"]), length(attrs["height"])
+	}
+	if !(width > 0 && height > 0) || math.IsInf(width, 0) || math.IsInf(height, 0) {
+		return nil, fmt.Errorf("%w: the SVG needs a viewBox or a width and height", ErrInvalidSignature)
+	}
+
+	scale := math.Min(1, maxRasterSize/math.Max(width, height))
+	w := max(1, int(math.Ceil(width*scale)))
+	h := max(1, int(math.Ceil(height*scale)))
+	return &canvas{img: image.NewNRGBA(image.Rect(0, 0, w, h)), scale: scale, minX: minX, minY: minY}, nil
+}
+
+// element draws a shape of the SVG
+func (c *canvas) element(name string, attrs map[string]string) error {
+	width := defaultStrokeWidth
+	if v := length(attrs["stroke-width"]); v > 0 {
+		width = v
+	}
+
+	switch name {
+	case "path":
+		lines, err := pathLines(attrs["d"])
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := c.stroke(line, width); err != nil {
+				return err
+			}
+		}
+	case "polyline", "polygon":
+		values := numbers(attrs["points"])
+		var line []point
+		for i := 0; i+1 < len(values); i += 2 {
+			line = append(line, point{values[i], values[i+1]})
+		}
+		if name == "polygon" && len(line) > 0 {
+			line = append(line, line[0])
+		}
+		return c.stroke(line, width)
+	case "line":
+		return c.stroke([]point{
+			{length(attrs["x1"]), length(attrs["y1"])},
+			{length(attrs["x2"]), length(attrs["y2"])},
+		}, width)
+	case "circle":
+		if r := length(attrs["r"]); r > 0 {
+			return c.stamp(point{length(attrs["cx"]), length(attrs["cy"])}, r*c.scale)
+		}
+	}
+	return nil
+}
+
+// stroke draws a line through the points with round caps and joins
+func (c *canvas) stroke(line []point, width float64) error {
+	radius := math.Min(math.Max(width*c.scale, 1), maxStrokePixels) / 2
+	for i, p := range line {
+		if i == 0 {
+			if err := c.stamp(p, radius); err != nil {
+				return err
+			}
+			continue
+		}
+		prev := line[i-1]
+		distance := math.Hypot(p.x-prev.x, p.y-prev.y) * c.scale
+		steps := int(math.Ceil(distance / math.Max(radius/2, 0.5)))
+		if steps > maxStamps || math.IsNaN(distance) {
+			return fmt.Errorf("%w: the signature is too complex", ErrInvalidSignature)
+		}
+		for step := 1; step <= steps; step++ {
+			t := float64(step) / float64(steps)
+			if err := c.stamp(point{prev.x + (p.x-prev.x)*t, prev.y + (p.y-prev.y)*t}, radius); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stamp fills a disc of the given pixel radius centred on a point in SVG user units
+func (c *canvas) stamp(p point, radius float64) error {
+	c.stamps++
+	if c.stamps > maxStamps {
+		return fmt.Errorf("%w: the signature is too complex", ErrInvalidSignature)
+	}
+
+	cx, cy := (p.x-c.minX)*c.scale, (p.y-c.minY)*c.scale
+	bounds := c.img.Bounds()
+	x0, x1 := max(bounds.Min.X, int(math.Floor(cx-radius))), min(bounds.Max.X-1, int(math.Ceil(cx+radius)))
+	y0, y1 := max(bounds.Min.Y, int(math.Floor(cy-radius))), min(bounds.Max.Y-1, int(math.Ceil(cy+radius)))
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if dx*dx+dy*dy <= radius*radius+0.25 {
+				c.img.SetNRGBA(x, y, color.NRGBA{A: 255})
+			}
+		}
+	}
+	return nil
+}
+
+// pathLines flattens the path data of a <path> into polylines, one for each subpath
+func pathLines(d string) ([][]point, error) {
+	p := &pathParser{s: d}
+	var lines [][]point
+	var current []point
+	var pos, start, control point
+	var command, previous byte
+
+	flush := func() {
+		if len(current) > 0 {
+			lines = append(lines, current)
+		}
+		current = nil
+	}
+	lineTo := func(to point) {
+		if len(current) == 0 {
+			current = append(current, pos)
+		}
+		current = append(current, to)
+		pos = to
+	}
+
+	for {
+		p.skip()
+		if p.done() {
+			break
+		}
+		if next, ok := p.command(); ok {
+			command = next
+		} else if command == 0 {
+			return nil, fmt.Errorf("%w: path data must start with a command", ErrInvalidSignature)
+		}
+
+		relative := command >= 'a' && command <= 'z'
+		base := point{}
+		if relative {
+			base = pos
+		}
+		args, err := p.arguments(command)
+		if err != nil {
+			return nil, err
+		}
+		at := func(i int) point {
+			return point{base.x + args[i], base.y + args[i+1]}
+		}
+
+		switch command {
+		case 'M', 'm':
+			flush()
+			pos = at(0)
+			start = pos
+			current = []point{pos}
+			// Further coordinate pairs after a move are lines
+			if relative {
+				command = 'l'
+			} else {
+				command = 'L'
+			}
+		case 'L', 'l', 'T', 't':
+			to := at(0)
+			if command == 'T' || command == 't' {
+				control = reflect(previous, "QqTt", control, pos)
+				current = appendQuadratic(current, pos, control, to)
+				pos = to
+			} else {
+				lineTo(to)
+			}
+		case 'H', 'h':
+			lineTo(point{base.x + args[0], pos.y})
+		case 'V', 'v':
+			lineTo(point{pos.x, base.y + args[0]})
+		case 'C', 'c':
+			control = at(2)
+			to := at(4)
+			current = appendCubic(current, pos, at(0), control, to)
+			pos = to
+		case 'S', 's':
+			first := reflect(previous, "CcSs", control, pos)
+			control = at(0)
+			to := at(2)
+			current = appendCubic(current, pos, first, control, to)
+			pos = to
+		case 'Q', 'q':
+			control = at(0)
+			to := at(2)
+			current = appendQuadratic(current, pos, control, to)
+			pos = to
+		case 'A', 'a':
+			lineTo(at(5))
+		case 'Z', 'z':
+			lineTo(start)
+			flush()
+		default:
+			return nil, fmt.Errorf("%w: unknown path command %q", ErrInvalidSignature, command)
+		}
+		previous = command
+	}
+	flush()
+	return lines, nil
+}
+
+// reflect mirrors the previous control point around the current point when the previous command was a curve
+// of the same kind; otherwise the current point is the control point
+func reflect(previous byte, curves string, control, pos point) point {
+	if previous != 0 && strings.IndexByte(curves, previous) >= 0 {
+		return point{2*pos.x - control.x, 2*pos.y - control.y}
+	}
+	return pos
+}
+
+func appendCubic(line []point, p0, p1, p2, p3 point) []point {
+	if len(line) == 0 {
+		line = append(line, p0)
+	}
+	for i := 1; i <= curveSegments; i++ {
+		t := float64(i) / curveSegments
+		u := 1 - t
+		line = append(line, point{
+			x: u*u*u*p0.x + 3*u*u*t*p1.x + 3*u*t*t*p2.x + t*t*t*p3.x,
+			y: u*u*u*p0.y + 3*u*u*t*p1.y + 3*u*t*t*p2.y + t*t*t*p3.y,
+		})
+	}
+	return line
+}
+
+func appendQuadratic(line []point, p0, p1, p2 point) []point {
+	if len(line) == 0 {
+		line = append(line, p0)
+	}
+	for i := 1; i <= curveSegments; i++ {
+		t := float64(i) / curveSegments
+		u := 1 - t
+		line = append(line, point{
+			x: u*u*p0.x + 2*u*t*p1.x + t*t*p2.x,
+			y: u*u*p0.y + 2*u*t*p1.y + t*t*p2.y,
+		})
+	}
+	return line
+}
+
+// pathParser reads the commands and numbers of SVG path data
+type pathParser struct {
+	s   string
+	pos int
+}
+
+func (p *pathParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+// skip passes white space and commas
+func (p *pathParser) skip() {
+	for !p.done() && strings.IndexByte(" \t\r\n,", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// command reads a command letter if one comes next
+func (p *pathParser) command() (byte, bool) {
+	c := p.s[p.pos]
+	if (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && c != 'e' && c != 'E' {
+		p.pos++
+		return c, true
+	}
+	return 0, false
+}
+
+// argumentCounts is the number of numbers each command takes
+var argumentCounts = map[byte]int{'M': 2, 'L': 2, 'T': 2, 'H': 1, 'V': 1, 'C': 6, 'S': 4, 'Q': 4, 'A': 7, 'Z': 0}
+
+// arguments reads the numbers of one command
+func (p *pathParser) arguments(command byte) ([]float64, error) {
+	count, ok := argumentCounts[command&^0x20]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown path command %q", ErrInvalidSignature, command)
+	}
+	args := make([]float64, count)
+	for i := range args {
+		p.skip()
+		v, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+// number reads a number such as 12, -3.5, .5 or 1e-3
+func (p *pathParser) number() (float64, error) {
+	start := p.pos
+	if !p.done() && (p.s[p.pos] == '+' || p.s[p.pos] == '-') {
+		p.pos++
+	}
+	digits := p.digits()
+	if !p.done() && p.s[p.pos] == '.' {
+		p.pos++
+		digits += p.digits()
+	}
+	if digits > 0 && !p.done() && (p.s[p.pos] == 'e' || p.s[p.pos] == 'E') {
+		p.pos++
+		if !p.done() && (p.s[p.pos] == '+' || p.s[p.pos] == '-') {
+			p.pos++
+		}
+		p.digits()
+	}
+	if digits == 0 {
+		return 0, fmt.Errorf("%w: invalid path data near %q", ErrInvalidSignature, p.s[start:min(len(p.s), start+10)])
+	}
+	v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%w: invalid number %q in path data", ErrInvalidSignature, p.s[start:p.pos])
+	}
+	return v, nil
+}
+
+func (p *pathParser) digits() int {
+	n := 0
+	for !p.done() && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+		n++
+	}
+	return n
+}
+
+// numbers reads a list of numbers separated by white space or commas; it stops at the first invalid one
+func numbers(value string) []float64 {
+	p := &pathParser{s: value}
+	var values []float64
+	for {
+		p.skip()
+		if p.done() {
+			return values
+		}
+		v, err := p.number()
+		if err != nil {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// length reads an SVG length in user units or pixels; other units and percentages read as zero
+func length(value string) float64 {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
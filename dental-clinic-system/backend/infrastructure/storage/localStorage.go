@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"dental-clinic-system/models/attachment"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultLocalDir is used when no attachment directory is configured
+const DefaultLocalDir = "attachments"
+
+// LocalStorage keeps attachment files in a directory on the server's filesystem
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage creates a LocalStorage writing into dir
+func NewLocalStorage(dir string) *LocalStorage {
+	if dir == "" {
+		dir = DefaultLocalDir
+	}
+	return &LocalStorage{dir: dir}
+}
+
+// Put writes the file under key. The file is written next to its destination and renamed into place,
+// so readers never see a partial file.
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType, checksum string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("wrote %d bytes, expected %d", written, size)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		log.Error().
+			Str("operation", "Put").
+			Err(err).
+			Str("key", key).
+			Msg("Failed to write attachment to local storage")
+		return err
+	}
+	return nil
+}
+
+// Get opens the file stored under key
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, attachment.ErrObjectNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file stored under key; removing a missing file is not an error
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file inside the storage directory, rejecting keys that would escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
@@ -2,48 +2,96 @@ package main
 
 import (
 	"dental-clinic-system/api/appointment"
+	"dental-clinic-system/api/attachment"
+	"dental-clinic-system/api/billing"
+	"dental-clinic-system/api/calendar"
 	"dental-clinic-system/api/clinic"
+	"dental-clinic-system/api/clinicalNote"
+	"dental-clinic-system/api/consent"
+	"dental-clinic-system/api/document"
+	"dental-clinic-system/api/eInvoice"
 	"dental-clinic-system/api/forgotPassword"
+	"dental-clinic-system/api/insurance"
 	"dental-clinic-system/api/login"
 	"dental-clinic-system/api/logout"
+	"dental-clinic-system/api/medicalHistory"
+	"dental-clinic-system/api/odontogram"
 	"dental-clinic-system/api/patient"
 	"dental-clinic-system/api/procedure"
+	"dental-clinic-system/api/radiograph"
 	"dental-clinic-system/api/resetPassword"
+	"dental-clinic-system/api/resource"
 	"dental-clinic-system/api/role"
+	"dental-clinic-system/api/schedule"
 	"dental-clinic-system/api/sendEmail"
 	"dental-clinic-system/api/signUpClinic"
 	"dental-clinic-system/api/singUpUser"
+	"dental-clinic-system/api/treatmentPlan"
 	"dental-clinic-system/api/user"
 	"dental-clinic-system/api/verifyEmail"
+	"dental-clinic-system/api/waitlist"
 	"dental-clinic-system/application/appointmentService"
+	"dental-clinic-system/application/attachmentService"
+	"dental-clinic-system/application/billingService"
+	"dental-clinic-system/application/calendarService"
 	"dental-clinic-system/application/clinicService"
+	"dental-clinic-system/application/clinicalNoteService"
+	"dental-clinic-system/application/consentService"
+	"dental-clinic-system/application/documentService"
+	"dental-clinic-system/application/eInvoiceService"
 	"dental-clinic-system/application/emailService"
+	"dental-clinic-system/application/insuranceService"
 	"dental-clinic-system/application/jwtService"
 	"dental-clinic-system/application/loginService"
+	"dental-clinic-system/application/medicalHistoryService"
+	"dental-clinic-system/application/odontogramService"
 	"dental-clinic-system/application/passwordResetService"
 	"dental-clinic-system/application/patientService"
 	"dental-clinic-system/application/procedureService"
+	"dental-clinic-system/application/radiographService"
+	"dental-clinic-system/application/reminderService"
+	"dental-clinic-system/application/resourceService"
 	"dental-clinic-system/application/roleService"
+	"dental-clinic-system/application/scheduleService"
 	"dental-clinic-system/application/signUpClinicService"
 	"dental-clinic-system/application/singUpUserService"
 	"dental-clinic-system/application/tokenService"
+	"dental-clinic-system/application/treatmentPlanService"
 	"dental-clinic-system/application/userService"
+	"dental-clinic-system/application/waitlistService"
 	"dental-clinic-system/background-jobs"
 	config2 "dental-clinic-system/infrastructure/config"
+	"dental-clinic-system/infrastructure/einvoice"
 	"dental-clinic-system/infrastructure/kafka"
 	"dental-clinic-system/infrastructure/postgres"
 	redis2 "dental-clinic-system/infrastructure/redis"
 	"dental-clinic-system/infrastructure/repository/appointmentRepository"
+	"dental-clinic-system/infrastructure/repository/attachmentRepository"
+	"dental-clinic-system/infrastructure/repository/billingRepository"
+	"dental-clinic-system/infrastructure/repository/calendarRepository"
 	"dental-clinic-system/infrastructure/repository/clinicRepository"
+	"dental-clinic-system/infrastructure/repository/clinicalNoteRepository"
+	"dental-clinic-system/infrastructure/repository/consentRepository"
+	"dental-clinic-system/infrastructure/repository/eInvoiceRepository"
+	"dental-clinic-system/infrastructure/repository/insuranceRepository"
 	"dental-clinic-system/infrastructure/repository/loginRepository"
+	"dental-clinic-system/infrastructure/repository/medicalHistoryRepository"
+	"dental-clinic-system/infrastructure/repository/odontogramRepository"
 	"dental-clinic-system/infrastructure/repository/passwordResetTokenRepository"
 	"dental-clinic-system/infrastructure/repository/patientRepository"
 	"dental-clinic-system/infrastructure/repository/procedureRepository"
+	"dental-clinic-system/infrastructure/repository/radiographRepository"
 	"dental-clinic-system/infrastructure/repository/redisRepository"
+	"dental-clinic-system/infrastructure/repository/resourceRepository"
 	"dental-clinic-system/infrastructure/repository/roleRepository"
+	"dental-clinic-system/infrastructure/repository/scheduleRepository"
 	"dental-clinic-system/infrastructure/repository/tokenRepository"
+	"dental-clinic-system/infrastructure/repository/treatmentPlanRepository"
 	"dental-clinic-system/infrastructure/repository/userRepository"
+	"dental-clinic-system/infrastructure/repository/waitlistRepository"
+	"dental-clinic-system/infrastructure/storage"
 	"dental-clinic-system/middleware/authMiddleware"
+	"dental-clinic-system/middleware/bodyLimitMiddleware"
 	"dental-clinic-system/middleware/contextTimeoutMiddleware"
 	"dental-clinic-system/vault"
 	"fmt"
@@ -51,6 +99,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
@@ -98,15 +147,35 @@ func main() {
 	newLoginRepository := loginRepository.NewRepository(db)
 	newTokenRepository := tokenRepository.NewRepository(db)
 	newPasswordResetTokenRepository := passwordResetTokenRepository.NewRepository(db)
+	newScheduleRepository := scheduleRepository.NewRepository(db)
+	newCalendarRepository := calendarRepository.NewRepository(db)
+	newWaitlistRepository := waitlistRepository.NewRepository(db)
+	newResourceRepository := resourceRepository.NewRepository(db)
+	newOdontogramRepository := odontogramRepository.NewRepository(db)
+	newTreatmentPlanRepository := treatmentPlanRepository.NewRepository(db)
+	newBillingRepository := billingRepository.NewRepository(db)
+	newEInvoiceRepository := eInvoiceRepository.NewRepository(db)
+	newInsuranceRepository := insuranceRepository.NewRepository(db)
+	newMedicalHistoryRepository := medicalHistoryRepository.NewRepository(db)
+	newClinicalNoteRepository := clinicalNoteRepository.NewRepository(db)
+	newAttachmentRepository := attachmentRepository.NewRepository(db)
+	newRadiographRepository := radiographRepository.NewRepository(db)
+	newConsentRepository := consentRepository.NewRepository(db)
 
 	//Redis Repository
 	newRedisRepository := redisRepository.NewRepository(Rdb)
 
 	//Services
 	newClinicService := clinicService.NewClinicService(newClinicRepository)
-	newAppointmentService := appointmentService.NewAppointmentService(newAppointmentRepository)
+	newScheduleService := scheduleService.NewScheduleService(newScheduleRepository, newClinicRepository)
+	newWaitlistService := waitlistService.NewWaitlistService(newWaitlistRepository, newClinicRepository, newProcedureRepository, kafkaProducer)
+	newMedicalHistoryService := medicalHistoryService.NewMedicalHistoryService(newMedicalHistoryRepository, newClinicRepository)
+	newAttachmentService := attachmentService.NewAttachmentService(newAttachmentRepository, newAttachmentStorage(configModel.Attachments), configModel.JWT.SecretKey, configModel.Attachments.MaxMB<<20)
+	newDocumentService := documentService.NewDocumentService(newBillingRepository, newTreatmentPlanRepository, newAppointmentRepository, newClinicRepository, newPatientRepository, newUserRepository, newOdontogramRepository, kafkaProducer, "templates/documents")
+	newConsentService := consentService.NewConsentService(newConsentRepository, newProcedureRepository, newDocumentService, newAttachmentService)
+	newAppointmentService := appointmentService.NewAppointmentService(newAppointmentRepository, newScheduleService, newWaitlistService, newMedicalHistoryService, newConsentService, newTreatmentPlanRepository)
 	newPatientService := patientService.NewPatientService(newPatientRepository)
-	newProcedureService := procedureService.NewProcedureService(newProcedureRepository)
+	newProcedureService := procedureService.NewProcedureService(newProcedureRepository, newClinicRepository)
 	newRoleService := roleService.NewRoleService(newRoleRepository)
 	newUserService := userService.NewUserService(newUserRepository, newRoleService)
 	newLoginService := loginService.NewLoginService(newLoginRepository)
@@ -115,11 +184,21 @@ func main() {
 	newSignUpUserService := signUpUserService.NewSignUpUserService(newUserRepository, newRedisRepository, newUserService)
 	newEmailService := emailService.NewEmailService(newUserRepository, newTokenRepository, kafkaProducer)
 	newJwtService := jwtService.NewJwtService(configModel.JWT.SecretKey)
+	newReminderService := reminderService.NewReminderService(newAppointmentRepository, kafkaProducer)
+	newCalendarService := calendarService.NewCalendarService(newCalendarRepository, newAppointmentRepository, newClinicRepository)
+	newResourceService := resourceService.NewResourceService(newResourceRepository, newClinicRepository, newScheduleService)
+	newOdontogramService := odontogramService.NewOdontogramService(newOdontogramRepository, newClinicRepository)
+	newTreatmentPlanService := treatmentPlanService.NewTreatmentPlanService(newTreatmentPlanRepository, newProcedureRepository, newScheduleService)
+	newBillingService := billingService.NewBillingService(newBillingRepository, newAppointmentRepository, newTreatmentPlanRepository, newProcedureService, newClinicRepository, kafkaProducer)
+	newEInvoiceService := eInvoiceService.NewEInvoiceService(newBillingRepository, newClinicRepository, newPatientRepository, newEInvoiceRepository, einvoice.NewLocalSubmitter(configModel.EInvoice.OutboxDir))
+	newInsuranceService := insuranceService.NewInsuranceService(newInsuranceRepository, newBillingRepository, newClinicRepository)
+	newClinicalNoteService := clinicalNoteService.NewClinicalNoteService(newClinicalNoteRepository, newAppointmentRepository)
+	newRadiographService := radiographService.NewRadiographService(newRadiographRepository, newPatientRepository, newAttachmentService)
 	newPasswordResetService := passwordResetService.NewPasswordResetService(newEmailService, newPasswordResetTokenRepository, newUserRepository)
 
 	//Handlers
 	newClinicHandler := clinic.NewClinicHandlerController(newClinicService, newUserService, newRoleService, newJwtService)
-	newAppointmentHandler := appointment.NewAppointmentHandler(newAppointmentService, newUserService, newPatientService, newJwtService)
+	newAppointmentHandler := appointment.NewAppointmentHandler(newAppointmentService, newUserService, newPatientService, newProcedureService, newJwtService)
 	newPatientHandler := patient.NewPatientController(newPatientService, newUserService, newJwtService)
 	newProcedureHandler := procedure.NewProcedureController(newProcedureService, newUserService, newRoleService, newJwtService)
 	newRoleHandler := role.NewRoleController(newRoleService)
@@ -132,19 +211,40 @@ func main() {
 	newSendEmailHandler := sendEmail.NewSendEmailController(newEmailService, newJwtService)
 	newForgotPasswordHandler := forgotPassword.NewForgotPasswordController(newPasswordResetService)
 	newResetPasswordHandler := resetPassword.NewResetPasswordController(newPasswordResetService)
+	newScheduleHandler := schedule.NewScheduleHandler(newScheduleService, newUserService, newJwtService)
+	newCalendarHandler := calendar.NewCalendarHandler(newCalendarService, newAppointmentService, newUserService, newJwtService)
+	newWaitlistHandler := waitlist.NewWaitlistHandler(newWaitlistService, newUserService, newJwtService)
+	newResourceHandler := resource.NewResourceHandler(newResourceService, newUserService, newJwtService)
+	newOdontogramHandler := odontogram.NewOdontogramHandler(newOdontogramService, newPatientService, newUserService, newJwtService)
+	newTreatmentPlanHandler := treatmentPlan.NewTreatmentPlanHandler(newTreatmentPlanService, newPatientService, newUserService, newJwtService)
+	newBillingHandler := billing.NewBillingHandler(newBillingService, newPatientService, newUserService, newJwtService)
+	newEInvoiceHandler := eInvoice.NewEInvoiceHandler(newEInvoiceService, newBillingService, newUserService, newJwtService)
+	newInsuranceHandler := insurance.NewInsuranceHandler(newInsuranceService, newPatientService, newUserService, newJwtService)
+	newDocumentHandler := document.NewDocumentHandler(newDocumentService, newUserService, newJwtService)
+	newMedicalHistoryHandler := medicalHistory.NewMedicalHistoryHandler(newMedicalHistoryService, newPatientService, newUserService, newJwtService)
+	newClinicalNoteHandler := clinicalNote.NewClinicalNoteHandler(newClinicalNoteService, newUserService, newJwtService)
+	newAttachmentHandler := attachment.NewAttachmentHandler(newAttachmentService, newPatientService, newUserService, newJwtService)
+	newRadiographHandler := radiograph.NewRadiographHandler(newRadiographService, newAttachmentService, newPatientService, newUserService, newJwtService)
+	newConsentHandler := consent.NewConsentHandler(newConsentService, newPatientService, newUserService, newJwtService)
 
 	//Create a new Fiber app
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
+		// Attachment uploads are streamed instead of being buffered; other requests keep the default
+		// body limit through bodyLimitMiddleware
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	})
 
 	//Middlewares
 	newAuthMiddleware := authMiddleware.NewAuthMiddleware(newTokenService, newJwtService)
 
 	//Global middlewares
-	app.Use(contextTimeoutMiddleware.TimeoutMiddleware(5))
+	isUpload := func(c *fiber.Ctx) bool { return attachment.IsUpload(c) || radiograph.IsUpload(c) }
+	app.Use(contextTimeoutMiddleware.TimeoutMiddleware(5, isUpload))
+	app.Use(bodyLimitMiddleware.LimitBody(fiber.DefaultBodyLimit, isUpload))
 
 	// Public routes (no authentication required)
 	login.RegisterAuthRoutes(app, newLoginHandler)
@@ -153,6 +253,9 @@ func main() {
 	verifyEmail.RegisterVerifyEmailRoutes(app, newVerifyEmailHandler)
 	forgotPassword.RegisterForgotPasswordRoutes(app, newForgotPasswordHandler)
 	resetPassword.RegisterResetPasswordRoutes(app, newResetPasswordHandler)
+	calendar.RegisterCalendarFeedRoutes(app, newCalendarHandler)
+	waitlist.RegisterWaitlistOfferRoutes(app, newWaitlistHandler)
+	attachment.RegisterAttachmentDownloadRoutes(app, newAttachmentHandler)
 
 	// Create API group with authentication middleware
 	api := app.Group("/api", newAuthMiddleware.Authenticate())
@@ -166,10 +269,27 @@ func main() {
 	user.RegisterUserRoutes(api, newUserHandler)
 	logout.RegisterLogoutRoutes(api, newLogoutHandler)
 	sendEmail.RegisterSendEmailRoutes(api, newSendEmailHandler)
+	schedule.RegisterScheduleRoutes(api, newScheduleHandler)
+	calendar.RegisterCalendarRoutes(api, newCalendarHandler)
+	waitlist.RegisterWaitlistRoutes(api, newWaitlistHandler)
+	resource.RegisterResourceRoutes(api, newResourceHandler)
+	odontogram.RegisterOdontogramRoutes(api, newOdontogramHandler)
+	treatmentPlan.RegisterTreatmentPlanRoutes(api, newTreatmentPlanHandler)
+	billing.RegisterBillingRoutes(api, newBillingHandler)
+	eInvoice.RegisterEInvoiceRoutes(api, newEInvoiceHandler)
+	insurance.RegisterInsuranceRoutes(api, newInsuranceHandler)
+	document.RegisterDocumentRoutes(api, newDocumentHandler)
+	medicalHistory.RegisterMedicalHistoryRoutes(api, newMedicalHistoryHandler)
+	clinicalNote.RegisterClinicalNoteRoutes(api, newClinicalNoteHandler)
+	attachment.RegisterAttachmentRoutes(api, newAttachmentHandler)
+	radiograph.RegisterRadiographRoutes(api, newRadiographHandler)
+	consent.RegisterConsentRoutes(api, newConsentHandler)
 
 	//background services
 	background_jobs.StartCleanExpiredJwtTokens(newTokenService)
 	background_jobs.StartCleanExpiredPasswordResetTokens(newPasswordResetTokenRepository)
+	background_jobs.StartAppointmentReminders(newReminderService)
+	background_jobs.StartOverdueInstallments(newBillingService)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -188,6 +308,25 @@ func main() {
 	log.Info().Msg("Successful shutdown of the server.")
 }
 
+// newAttachmentStorage creates the configured storage for patient attachments
+func newAttachmentStorage(config config2.AttachmentsConfig) attachmentService.Storage {
+	if config.Driver != "s3" {
+		return storage.NewLocalStorage(config.Dir)
+	}
+
+	s3Storage, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint:  config.S3.Endpoint,
+		Region:    config.S3.Region,
+		Bucket:    config.S3.Bucket,
+		AccessKey: config.S3.AccessKey,
+		SecretKey: config.S3.SecretKey,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error configuring S3 attachment storage")
+	}
+	return s3Storage
+}
+
 func gracefulShutdown(app *fiber.App, db *gorm.DB, redis *redis.Client, vaultClient *api.Client, kafkaProducer kafka.EmailProducer) {
 	log.Info().Msg("Shutting down server...")
 
@@ -7,11 +7,19 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func TimeoutMiddleware(timeoutValue int) fiber.Handler {
+// TimeoutMiddleware answers with 504 when a request takes longer than timeoutValue seconds.
+// Requests for which one of skip returns true, e.g. long uploads, have no timeout.
+func TimeoutMiddleware(timeoutValue int, skip ...func(c *fiber.Ctx) bool) fiber.Handler {
 	// Convert timeout to a duration
 	timeout := time.Duration(timeoutValue) * time.Second
 
 	return func(c *fiber.Ctx) error {
+		for _, exempt := range skip {
+			if exempt(c) {
+				return c.Next()
+			}
+		}
+
 		// Create a context with a timeout
 		ctx, cancel := context.WithTimeout(c.Context(), timeout)
 		defer cancel()
@@ -2,21 +2,119 @@ package appointment
 
 import (
 	"dental-clinic-system/models/clinic"
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/resource"
 	"dental-clinic-system/models/user"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// DefaultDuration is used when an appointment is created without an end time or duration
+const DefaultDuration = 30 * time.Minute
+
+// Slot search settings
+const (
+	SlotStep           = 15 * time.Minute
+	MaxSlotSearchRange = 31 * 24 * time.Hour
+)
+
 type Appointment struct {
 	gorm.Model
-	ClinicID      uint          `json:"clinic_id"`
-	Clinic        clinic.Clinic `gorm:"foreignKey:ClinicID"`
-	PatientID     uint          `json:"patient_id"`
-	Patient       user.User     `gorm:"foreignKey:PatientID"`
-	DoctorID      uint          `json:"doctor_id"`
-	Doctor        user.User     `gorm:"foreignKey:DoctorID"`
-	ScheduledTime time.Time     `json:"scheduled_time"`
-	Treatment     string        `json:"treatment"`
-	Notes         string        `json:"notes"`
+	ClinicID        uint            `json:"clinic_id" gorm:"index:idx_appointments_clinic_scheduled,priority:1"`
+	Clinic          clinic.Clinic   `gorm:"foreignKey:ClinicID"`
+	PatientID       uint            `json:"patient_id" gorm:"index:idx_appointments_patient_scheduled,priority:1"`
+	Patient         patient.Patient `gorm:"foreignKey:PatientID"`
+	DoctorID        uint            `json:"doctor_id" gorm:"index:idx_appointments_doctor_scheduled,priority:1"`
+	Doctor          user.User       `gorm:"foreignKey:DoctorID"`
+	ScheduledTime   time.Time       `json:"scheduled_time" gorm:"index:idx_appointments_clinic_scheduled,priority:2;index:idx_appointments_doctor_scheduled,priority:2;index:idx_appointments_patient_scheduled,priority:2"`
+	EndTime         time.Time       `json:"end_time"`
+	DurationMinutes int             `json:"duration_minutes"`
+	Status          Status          `json:"status" gorm:"default:booked;index"`
+	// Revision counts the changes saved since the appointment was booked. Calendar feeds publish it as
+	// the event SEQUENCE; it is maintained by the repository and ignored in requests.
+	Revision           int    `json:"revision" gorm:"not null;default:0"`
+	CancellationReason string `json:"cancellation_reason"`
+	SeriesID           *uint  `json:"series_id" gorm:"index"`
+	OccurrenceIndex    int    `json:"occurrence_index"`
+	ProcedureID        *uint  `json:"procedure_id" gorm:"index"`
+	Treatment          string `json:"treatment"`
+	// Notes are booking notes for the front desk. Clinical findings belong in signed clinical notes.
+	Notes string `json:"notes"`
+	// Resources are the chairs, rooms and equipment reserved for the appointment.
+	// Requests may list them as ResourceIDs instead.
+	Resources   []resource.Resource `json:"resources" gorm:"many2many:appointment_resources;"`
+	ResourceIDs []uint              `json:"resource_ids,omitempty" gorm:"-"`
+	// PatientAlerts are the patient's medical alerts, critical first. They are filled in whenever
+	// the appointment is returned by the appointment service and are null if they could not be loaded.
+	PatientAlerts []medical.Alert `json:"patient_alerts" gorm:"-"`
+}
+
+// AvailableSlot is a bookable time range of a doctor
+type AvailableSlot struct {
+	DoctorID uint      `json:"doctor_id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// ResourceIDList returns the IDs of all reserved resources without duplicates, in ascending order
+func (a Appointment) ResourceIDList() []uint {
+	seen := map[uint]bool{}
+	var ids []uint
+	for _, id := range a.ResourceIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, res := range a.Resources {
+		if res.ID != 0 && !seen[res.ID] {
+			seen[res.ID] = true
+			ids = append(ids, res.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Overlaps reports whether the two appointments share any point in time
+func (a Appointment) Overlaps(other Appointment) bool {
+	return a.ScheduledTime.Before(other.EndTime) && other.ScheduledTime.Before(a.EndTime)
+}
+
+// Error types
+var (
+	ErrAppointmentConflict    = errors.New("appointment conflicts with an existing booking")
+	ErrInvalidAppointmentTime = errors.New("appointment must have a scheduled time before its end time")
+	ErrInvalidSlotSearch      = errors.New("invalid available slot search")
+)
+
+// Conflict participants
+const (
+	ConflictDoctor   = "doctor"
+	ConflictPatient  = "patient"
+	ConflictResource = "resource"
+)
+
+// ConflictError describes which existing appointment blocks a booking
+type ConflictError struct {
+	Participant              string
+	ConflictingAppointmentID uint
+	// ResourceID is set when the conflicting participant is a resource
+	ResourceID uint
+}
+
+func (e *ConflictError) Error() string {
+	if e.Participant == ConflictResource {
+		return fmt.Sprintf("resource %d is already booked by appointment %d", e.ResourceID, e.ConflictingAppointmentID)
+	}
+	return fmt.Sprintf("%s is already booked by appointment %d", e.Participant, e.ConflictingAppointmentID)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrAppointmentConflict
 }
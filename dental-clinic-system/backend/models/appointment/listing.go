@@ -0,0 +1,136 @@
+package appointment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Listing page sizes
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// SortField is a column appointment listings can be ordered by
+type SortField string
+
+const (
+	SortScheduledTime SortField = "scheduled_time"
+	SortCreatedAt     SortField = "created_at"
+)
+
+// IsValid reports whether the listing can be sorted by the field
+func (f SortField) IsValid() bool {
+	return f == SortScheduledTime || f == SortCreatedAt
+}
+
+// ListFilter selects a page of a clinic's appointments.
+// Zero values mean "no restriction"; From is inclusive and To exclusive on the scheduled time.
+type ListFilter struct {
+	ClinicID    uint
+	From        time.Time
+	To          time.Time
+	DoctorID    uint
+	PatientID   uint
+	ProcedureID uint
+	Statuses    []Status
+	SortBy      SortField
+	Descending  bool
+	Limit       int
+	// Cursor is the opaque NextCursor of the previous page
+	Cursor string
+}
+
+// Cursor is the position after which the next page starts: the sort value and ID of the last row returned
+type Cursor struct {
+	SortBy     SortField `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	Value      time.Time `json:"v"`
+	ID         uint      `json:"i"`
+}
+
+// Page is one page of an appointment listing
+type Page struct {
+	Appointments []Appointment
+	TotalCount   int64
+	NextCursor   string
+}
+
+// ErrInvalidListFilter is returned for unusable listing parameters
+var ErrInvalidListFilter = errors.New("invalid appointment listing filter")
+
+// ParseSort reads a sort parameter such as "scheduled_time" or "-created_at", where "-" sorts descending.
+// An empty value sorts by scheduled time, oldest first.
+func ParseSort(value string) (SortField, bool, error) {
+	if value == "" {
+		return SortScheduledTime, false, nil
+	}
+	descending := strings.HasPrefix(value, "-")
+	field := SortField(strings.TrimPrefix(value, "-"))
+	if !field.IsValid() {
+		return "", false, fmt.Errorf("%w: unknown sort field %q", ErrInvalidListFilter, field)
+	}
+	return field, descending, nil
+}
+
+// Normalize applies the defaults and validates the filter
+func (f *ListFilter) Normalize() error {
+	if f.SortBy == "" {
+		f.SortBy = SortScheduledTime
+	}
+	if !f.SortBy.IsValid() {
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidListFilter, f.SortBy)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		return fmt.Errorf("%w: limit can not exceed %d", ErrInvalidListFilter, MaxPageSize)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.To.After(f.From) {
+		return fmt.Errorf("%w: to must be after from", ErrInvalidListFilter)
+	}
+	for _, status := range f.Statuses {
+		if !status.IsValid() {
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidListFilter, status)
+		}
+	}
+	if _, err := f.After(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// After decodes the cursor of the filter. It returns nil for the first page.
+// A cursor produced for another sort order is rejected.
+func (f ListFilter) After() (*Cursor, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListFilter)
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListFilter)
+	}
+	if cursor.SortBy != f.SortBy || cursor.Descending != f.Descending {
+		return nil, fmt.Errorf("%w: cursor belongs to a different sort order", ErrInvalidListFilter)
+	}
+	return &cursor, nil
+}
+
+// NextCursor encodes the position after the given appointment for the filter's sort order
+func (f ListFilter) NextCursor(last Appointment) string {
+	cursor := Cursor{SortBy: f.SortBy, Descending: f.Descending, Value: last.ScheduledTime, ID: last.ID}
+	if f.SortBy == SortCreatedAt {
+		cursor.Value = last.CreatedAt
+	}
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
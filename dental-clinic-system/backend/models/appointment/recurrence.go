@@ -0,0 +1,133 @@
+package appointment
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSeriesOccurrences caps how many appointments a single series may create
+const MaxSeriesOccurrences = 100
+
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+)
+
+// Recurrence is the subset of an RFC 5545 RRULE supported for appointment series:
+// FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL and one of COUNT or UNTIL.
+type Recurrence struct {
+	Frequency Frequency
+	Interval  int
+	Count     int
+	Until     time.Time
+}
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// ParseRecurrence parses a rule such as "FREQ=WEEKLY;INTERVAL=4;COUNT=13".
+// The optional "RRULE:" prefix is ignored.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	recurrence := Recurrence{Interval: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return Recurrence{}, fmt.Errorf("%w: rule is empty", ErrInvalidRecurrence)
+	}
+
+	for _, part := range strings.Split(rule, ";") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return Recurrence{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRecurrence, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			recurrence.Frequency = Frequency(strings.ToUpper(value))
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return Recurrence{}, fmt.Errorf("%w: INTERVAL must be a positive number", ErrInvalidRecurrence)
+			}
+			recurrence.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return Recurrence{}, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRecurrence)
+			}
+			recurrence.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return Recurrence{}, fmt.Errorf("%w: UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ", ErrInvalidRecurrence)
+			}
+			recurrence.Until = until
+		default:
+			return Recurrence{}, fmt.Errorf("%w: %s is not supported", ErrInvalidRecurrence, key)
+		}
+	}
+
+	switch recurrence.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	default:
+		return Recurrence{}, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidRecurrence)
+	}
+
+	if (recurrence.Count == 0) == recurrence.Until.IsZero() {
+		return Recurrence{}, fmt.Errorf("%w: exactly one of COUNT or UNTIL is required", ErrInvalidRecurrence)
+	}
+
+	if recurrence.Count > MaxSeriesOccurrences {
+		return Recurrence{}, fmt.Errorf("%w: COUNT can not exceed %d", ErrInvalidRecurrence, MaxSeriesOccurrences)
+	}
+
+	return recurrence, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	until, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// A date-only UNTIL includes the whole day
+	return until.Add(24*time.Hour - time.Second), nil
+}
+
+// Occurrences expands the rule from the first start time. Monthly occurrences that fall on a day
+// missing from the month (e.g. the 31st) are skipped, as RFC 5545 requires.
+func (r Recurrence) Occurrences(start time.Time) ([]time.Time, error) {
+	var occurrences []time.Time
+	for step := 0; ; step++ {
+		var next time.Time
+		switch r.Frequency {
+		case FrequencyDaily:
+			next = start.AddDate(0, 0, step*r.Interval)
+		case FrequencyWeekly:
+			next = start.AddDate(0, 0, 7*step*r.Interval)
+		case FrequencyMonthly:
+			next = start.AddDate(0, step*r.Interval, 0)
+			if next.Day() != start.Day() {
+				continue
+			}
+		}
+
+		if !r.Until.IsZero() && next.After(r.Until) {
+			break
+		}
+		occurrences = append(occurrences, next)
+		if r.Count > 0 && len(occurrences) == r.Count {
+			break
+		}
+		if len(occurrences) > MaxSeriesOccurrences {
+			return nil, fmt.Errorf("%w: rule produces more than %d occurrences", ErrInvalidRecurrence, MaxSeriesOccurrences)
+		}
+	}
+	return occurrences, nil
+}
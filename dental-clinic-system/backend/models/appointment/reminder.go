@@ -0,0 +1,37 @@
+package appointment
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReminderKind identifies how long before an appointment a reminder is sent
+type ReminderKind string
+
+const (
+	Reminder24h ReminderKind = "24h"
+	Reminder2h  ReminderKind = "2h"
+)
+
+// ReminderKinds lists the reminders sent for each appointment, furthest first
+var ReminderKinds = []ReminderKind{Reminder24h, Reminder2h}
+
+// Lead returns how long before the appointment the reminder is due
+func (k ReminderKind) Lead() time.Duration {
+	switch k {
+	case Reminder24h:
+		return 24 * time.Hour
+	case Reminder2h:
+		return 2 * time.Hour
+	}
+	return 0
+}
+
+// SentReminder records a reminder that was published for an appointment so it is never sent twice
+type SentReminder struct {
+	gorm.Model
+	AppointmentID uint         `json:"appointment_id" gorm:"uniqueIndex:idx_sent_reminder_appointment_kind"`
+	Kind          ReminderKind `json:"kind" gorm:"uniqueIndex:idx_sent_reminder_appointment_kind"`
+	SentAt        time.Time    `json:"sent_at"`
+}
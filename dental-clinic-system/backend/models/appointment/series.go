@@ -0,0 +1,77 @@
+package appointment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Series groups the appointments generated from one recurrence rule
+type Series struct {
+	gorm.Model
+	ClinicID        uint          `json:"clinic_id" gorm:"index"`
+	PatientID       uint          `json:"patient_id"`
+	DoctorID        uint          `json:"doctor_id"`
+	RRule           string        `json:"rrule"`
+	StartTime       time.Time     `json:"start_time"`
+	DurationMinutes int           `json:"duration_minutes"`
+	Treatment       string        `json:"treatment"`
+	Notes           string        `json:"notes"`
+	Appointments    []Appointment `json:"appointments" gorm:"foreignKey:SeriesID"`
+	// ResourceIDs are reserved for every occurrence when the series is created
+	ResourceIDs []uint `json:"resource_ids,omitempty" gorm:"-"`
+}
+
+func (Series) TableName() string {
+	return "appointment_series"
+}
+
+// EditScope selects which occurrences of a series an edit or cancellation applies to
+type EditScope string
+
+const (
+	ScopeThis      EditScope = "this"
+	ScopeFollowing EditScope = "following"
+	ScopeAll       EditScope = "all"
+)
+
+// IsValid reports whether the scope is a known edit scope
+func (s EditScope) IsValid() bool {
+	return s == ScopeThis || s == ScopeFollowing || s == ScopeAll
+}
+
+// Includes reports whether the occurrence at index falls into the scope anchored at anchorIndex
+func (s EditScope) Includes(anchorIndex, index int) bool {
+	switch s {
+	case ScopeThis:
+		return index == anchorIndex
+	case ScopeFollowing:
+		return index >= anchorIndex
+	default:
+		return true
+	}
+}
+
+// Error types
+var (
+	ErrSeriesNotFound         = errors.New("appointment series not found")
+	ErrAppointmentNotInSeries = errors.New("appointment does not belong to the series")
+	ErrInvalidEditScope       = errors.New("scope must be one of this, following or all")
+)
+
+// OccurrenceError reports which occurrence of a series could not be booked
+type OccurrenceError struct {
+	Index int
+	Start time.Time
+	Err   error
+}
+
+func (e *OccurrenceError) Error() string {
+	return fmt.Sprintf("occurrence %d at %s: %v", e.Index+1, e.Start.Format(time.RFC3339), e.Err)
+}
+
+func (e *OccurrenceError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,98 @@
+package appointment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Status string
+
+const (
+	StatusBooked    Status = "booked"
+	StatusConfirmed Status = "confirmed"
+	StatusCheckedIn Status = "checked_in"
+	StatusInChair   Status = "in_chair"
+	StatusCompleted Status = "completed"
+	StatusCancelled Status = "cancelled"
+	StatusNoShow    Status = "no_show"
+)
+
+// transitions lists the statuses an appointment may move to from each status
+var transitions = map[Status][]Status{
+	StatusBooked:    {StatusConfirmed, StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusConfirmed: {StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusCheckedIn: {StatusInChair, StatusCancelled},
+	StatusInChair:   {StatusCompleted},
+	StatusCompleted: {},
+	StatusCancelled: {},
+	StatusNoShow:    {},
+}
+
+// InactiveStatuses are the statuses of appointments that no longer occupy their time slot
+var InactiveStatuses = []Status{StatusCancelled, StatusNoShow}
+
+// CanTransition reports whether an appointment may move from one status to another
+func CanTransition(from, to Status) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid reports whether the status is a known appointment status
+func (s Status) IsValid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// OccupiesSlot reports whether an appointment with this status still blocks its time slot
+func (s Status) OccupiesSlot() bool {
+	for _, inactive := range InactiveStatuses {
+		if s == inactive {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEditable reports whether an appointment with this status may still be rescheduled
+func (s Status) IsEditable() bool {
+	return s == StatusBooked || s == StatusConfirmed
+}
+
+// StatusHistory records a single status transition of an appointment
+type StatusHistory struct {
+	gorm.Model
+	AppointmentID uint      `json:"appointment_id" gorm:"index"`
+	FromStatus    Status    `json:"from_status"`
+	ToStatus      Status    `json:"to_status"`
+	ChangedByID   uint      `json:"changed_by_id"`
+	ChangedAt     time.Time `json:"changed_at"`
+	Reason        string    `json:"reason"`
+}
+
+// Error types
+var (
+	ErrInvalidStatusTransition    = errors.New("invalid appointment status transition")
+	ErrCancellationReasonRequired = errors.New("a reason is required to cancel an appointment")
+	ErrAppointmentNotEditable     = errors.New("only booked or confirmed appointments can be moved to another time, doctor or resource")
+)
+
+// InvalidTransitionError describes a status change that the transition table does not allow
+type InvalidTransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("appointment can not move from %s to %s", e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Unwrap() error {
+	return ErrInvalidStatusTransition
+}
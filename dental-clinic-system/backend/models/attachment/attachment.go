@@ -0,0 +1,105 @@
+package attachment
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultMaxBytes is the largest file accepted when no limit is configured
+const DefaultMaxBytes int64 = 50 << 20
+
+// Category says what an attachment shows and decides which file types it accepts
+type Category string
+
+const (
+	CategoryXRay           Category = "xray"
+	CategoryIntraoralPhoto Category = "intraoral_photo"
+	CategoryConsent        Category = "consent"
+	CategoryLabReport      Category = "lab_report"
+	CategoryOther          Category = "other"
+)
+
+// Content types detected in uploaded files
+const (
+	TypeJPEG  = "image/jpeg"
+	TypePNG   = "image/png"
+	TypeTIFF  = "image/tiff"
+	TypePDF   = "application/pdf"
+	TypeDICOM = "application/dicom"
+)
+
+// allowedTypes lists the content types each category accepts
+var allowedTypes = map[Category][]string{
+	CategoryXRay:           {TypeDICOM, TypeJPEG, TypePNG, TypeTIFF},
+	CategoryIntraoralPhoto: {TypeJPEG, TypePNG},
+	CategoryConsent:        {TypePDF, TypeJPEG, TypePNG},
+	CategoryLabReport:      {TypePDF, TypeJPEG, TypePNG},
+	CategoryOther:          {TypePDF, TypeJPEG, TypePNG, TypeTIFF, TypeDICOM},
+}
+
+// IsValid reports whether the category is one of the known categories
+func (c Category) IsValid() bool {
+	_, ok := allowedTypes[c]
+	return ok
+}
+
+// Accepts reports whether files of the given content type can be filed under the category
+func (c Category) Accepts(contentType string) bool {
+	for _, allowed := range allowedTypes[c] {
+		if allowed == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedTypes returns the content types the category accepts
+func (c Category) AllowedTypes() []string {
+	return append([]string(nil), allowedTypes[c]...)
+}
+
+// Attachment is a file kept with a patient's record, e.g. a panoramic X-ray or a signed consent scan.
+// The file itself lives in the configured storage under StorageKey.
+type Attachment struct {
+	gorm.Model
+	ClinicID     uint     `json:"clinic_id" gorm:"index"`
+	PatientID    uint     `json:"patient_id" gorm:"index"`
+	UploadedByID uint     `json:"uploaded_by_id"`
+	Category     Category `json:"category" gorm:"index"`
+	Description  string   `json:"description"`
+	FileName     string   `json:"file_name"`
+	// ContentType is detected from the file's content, not taken from the upload
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+	// SHA256 is the hex checksum of the stored file
+	SHA256     string `json:"sha256"`
+	StorageKey string `json:"-" gorm:"uniqueIndex"`
+}
+
+// Upload describes a file being attached to a patient
+type Upload struct {
+	ClinicID     uint
+	PatientID    uint
+	UploadedByID uint
+	Category     Category
+	Description  string
+	FileName     string
+}
+
+// DownloadLink is a short-lived URL serving an attachment without further authentication
+type DownloadLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Error types
+var (
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrAttachmentValidation = errors.New("invalid attachment")
+	ErrFileTooLarge         = errors.New("file is too large")
+	ErrUnsupportedType      = errors.New("file type is not accepted for this category")
+	ErrInvalidSignature     = errors.New("download link is invalid or has expired")
+	ErrObjectNotFound       = errors.New("stored file not found")
+)
@@ -0,0 +1,203 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency is used for invoices without catalogue-priced lines
+const DefaultCurrency = "TRY"
+
+// DateLayout is the layout of invoice issue and service dates
+const DateLayout = "2006-01-02"
+
+// InvoiceStatus is the payment state of an invoice
+type InvoiceStatus string
+
+const (
+	InvoiceIssued        InvoiceStatus = "issued"
+	InvoicePartiallyPaid InvoiceStatus = "partially_paid"
+	InvoicePaid          InvoiceStatus = "paid"
+	InvoiceVoid          InvoiceStatus = "void"
+)
+
+// UnpaidStatuses are the statuses of invoices that still have a balance
+var UnpaidStatuses = []InvoiceStatus{InvoiceIssued, InvoicePartiallyPaid}
+
+// PaymentMethod is how money was received or paid back
+type PaymentMethod string
+
+const (
+	MethodCash         PaymentMethod = "cash"
+	MethodCard         PaymentMethod = "card"
+	MethodBankTransfer PaymentMethod = "bank_transfer"
+	// MethodInsurance is money paid by an insurer for a claim; it is only recorded through the claims workflow
+	MethodInsurance PaymentMethod = "insurance"
+)
+
+// IsValid reports whether the method is one of the payment methods a patient can pay with
+func (m PaymentMethod) IsValid() bool {
+	switch m {
+	case MethodCash, MethodCard, MethodBankTransfer:
+		return true
+	}
+	return false
+}
+
+// PaymentKind tells payments and refunds apart; both are stored with a positive amount
+type PaymentKind string
+
+const (
+	KindPayment PaymentKind = "payment"
+	KindRefund  PaymentKind = "refund"
+)
+
+// Invoice is issued to a patient for completed treatment. Issued invoices are never edited;
+// mistakes are corrected by voiding the invoice and issuing a new one.
+// Amounts are in minor currency units (kuruş for TRY).
+type Invoice struct {
+	gorm.Model
+	ClinicID      uint          `json:"clinic_id" gorm:"uniqueIndex:idx_invoices_clinic_number,priority:1"`
+	PatientID     uint          `json:"patient_id" gorm:"index"`
+	Number        string        `json:"number" gorm:"uniqueIndex:idx_invoices_clinic_number,priority:2"`
+	IssueDate     string        `json:"issue_date"`
+	Currency      string        `json:"currency" gorm:"default:TRY"`
+	Status        InvoiceStatus `json:"status" gorm:"default:issued;index"`
+	Notes         string        `json:"notes"`
+	Subtotal      int64         `json:"subtotal"`
+	DiscountTotal int64         `json:"discount_total"`
+	NetTotal      int64         `json:"net_total"`
+	VATTotal      int64         `json:"vat_total"`
+	Total         int64         `json:"total"`
+	PaidAmount    int64         `json:"paid_amount"`
+	Balance       int64         `json:"balance" gorm:"-"`
+	// InsurerShare is the part of the total covered by an insurance claim; the patient owes the rest.
+	// InsurerPaid is the part of PaidAmount received from the insurer.
+	InsurerShare   int64            `json:"insurer_share"`
+	InsurerPaid    int64            `json:"insurer_paid"`
+	PatientShare   int64            `json:"patient_share" gorm:"-"`
+	PatientBalance int64            `json:"patient_balance" gorm:"-"`
+	InsurerBalance int64            `json:"insurer_balance" gorm:"-"`
+	IssuedByID     uint             `json:"issued_by_id"`
+	VoidedAt       *time.Time       `json:"voided_at"`
+	VoidedByID     *uint            `json:"voided_by_id"`
+	VoidReason     string           `json:"void_reason"`
+	Lines          []Line           `json:"lines" gorm:"foreignKey:InvoiceID;constraint:OnDelete:CASCADE"`
+	Payments       []Payment        `json:"payments,omitempty" gorm:"foreignKey:InvoiceID"`
+	Installments   *InstallmentPlan `json:"installment_plan,omitempty" gorm:"foreignKey:InvoiceID"`
+}
+
+// Line is one charged item of an invoice. It keeps the source it was generated from
+// so the same appointment or plan item can not be billed twice.
+type Line struct {
+	ID              uint   `json:"id" gorm:"primaryKey"`
+	InvoiceID       uint   `json:"invoice_id" gorm:"index"`
+	Position        int    `json:"position"`
+	AppointmentID   *uint  `json:"appointment_id" gorm:"index"`
+	PlanItemID      *uint  `json:"plan_item_id" gorm:"index"`
+	ProcedureID     *uint  `json:"procedure_id"`
+	PriceListID     *uint  `json:"price_list_id"`
+	Code            string `json:"code"`
+	Description     string `json:"description"`
+	ServiceDate     string `json:"service_date"`
+	Quantity        int    `json:"quantity"`
+	UnitPrice       int64  `json:"unit_price"`
+	DiscountPercent int    `json:"discount_percent"`
+	DiscountAmount  int64  `json:"discount_amount"`
+	VATRate         int    `json:"vat_rate"`
+	NetAmount       int64  `json:"net_amount"`
+	VATAmount       int64  `json:"vat_amount"`
+	Total           int64  `json:"total"`
+}
+
+func (Line) TableName() string {
+	return "invoice_lines"
+}
+
+// Payment is money received for an invoice, or paid back to the patient when Kind is refund
+type Payment struct {
+	gorm.Model
+	InvoiceID    uint          `json:"invoice_id" gorm:"index"`
+	ClinicID     uint          `json:"clinic_id" gorm:"index"`
+	PatientID    uint          `json:"patient_id" gorm:"index"`
+	Kind         PaymentKind   `json:"kind"`
+	Method       PaymentMethod `json:"method"`
+	Amount       int64         `json:"amount"`
+	Reference    string        `json:"reference"`
+	Notes        string        `json:"notes"`
+	ReceivedAt   time.Time     `json:"received_at"`
+	RecordedByID uint          `json:"recorded_by_id"`
+}
+
+// AuditAction is a change recorded in the billing audit trail
+type AuditAction string
+
+const (
+	AuditInvoiceIssued         AuditAction = "invoice_issued"
+	AuditPaymentRecorded       AuditAction = "payment_recorded"
+	AuditRefundRecorded        AuditAction = "refund_recorded"
+	AuditInvoiceVoided         AuditAction = "invoice_voided"
+	AuditInstallmentsScheduled AuditAction = "installments_scheduled"
+	AuditInstallmentsCancelled AuditAction = "installments_cancelled"
+	AuditClaimSubmitted        AuditAction = "claim_submitted"
+	AuditClaimDecided          AuditAction = "claim_decided"
+)
+
+// AuditEntry is an append-only record of a billing change and who made it
+type AuditEntry struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	ClinicID  uint        `json:"clinic_id" gorm:"index"`
+	InvoiceID uint        `json:"invoice_id" gorm:"index"`
+	PaymentID *uint       `json:"payment_id"`
+	Action    AuditAction `json:"action"`
+	ActorID   uint        `json:"actor_id"`
+	At        time.Time   `json:"at"`
+	Details   string      `json:"details"`
+}
+
+func (AuditEntry) TableName() string {
+	return "billing_audit_entries"
+}
+
+// NewAuditEntry builds an audit entry for an invoice with the details stored as JSON
+func NewAuditEntry(action AuditAction, invoice Invoice, actorID uint, at time.Time, details map[string]interface{}) AuditEntry {
+	encoded, _ := json.Marshal(details)
+	return AuditEntry{
+		ClinicID:  invoice.ClinicID,
+		InvoiceID: invoice.ID,
+		Action:    action,
+		ActorID:   actorID,
+		At:        at,
+		Details:   string(encoded),
+	}
+}
+
+// Balance is what a patient has been invoiced and still owes in one currency. Amounts claimed from
+// insurers are left out of the patient's figures; InsurerOutstanding is what the insurers still owe.
+type Balance struct {
+	PatientID          uint   `json:"patient_id"`
+	Currency           string `json:"currency"`
+	Invoiced           int64  `json:"invoiced"`
+	Paid               int64  `json:"paid"`
+	Outstanding        int64  `json:"outstanding"`
+	InsurerOutstanding int64  `json:"insurer_outstanding"`
+	OpenInvoices       int    `json:"open_invoices"`
+}
+
+// Error types
+var (
+	ErrInvoiceNotFound       = errors.New("invoice not found")
+	ErrInvoiceValidation     = errors.New("invalid invoice")
+	ErrPaymentValidation     = errors.New("invalid payment")
+	ErrAlreadyInvoiced       = errors.New("appointment or treatment plan item has already been invoiced")
+	ErrInvoiceVoid           = errors.New("invoice is void")
+	ErrOverpayment           = errors.New("payment exceeds the outstanding balance")
+	ErrRefundExceedsPaid     = errors.New("refund exceeds the amount paid")
+	ErrInvoiceHasPayments    = errors.New("invoices with payments can not be voided; refund the payments first")
+	ErrSourceNotBillable     = errors.New("only completed appointments and treatment plan items can be invoiced")
+	ErrCurrencyMismatch      = errors.New("all lines of an invoice must be priced in the same currency")
+	ErrBillingSourceNotFound = errors.New("appointment or treatment plan item not found")
+)
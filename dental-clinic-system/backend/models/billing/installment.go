@@ -0,0 +1,233 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InstallmentStatus is the payment state of one installment
+type InstallmentStatus string
+
+const (
+	InstallmentPending InstallmentStatus = "pending"
+	InstallmentOverdue InstallmentStatus = "overdue"
+	InstallmentPaid    InstallmentStatus = "paid"
+)
+
+// OpenInstallmentStatuses are the statuses of installments that still have something to pay
+var OpenInstallmentStatuses = []InstallmentStatus{InstallmentPending, InstallmentOverdue}
+
+// OverdueReminderIntervalDays is how often a patient is reminded of an installment that stays overdue
+const OverdueReminderIntervalDays = 7
+
+// InstallmentPlan spreads the balance the patient had on an invoice when the plan was agreed over dated
+// installments. The patient's payments on the invoice are allocated to the installments in due date order.
+type InstallmentPlan struct {
+	gorm.Model
+	InvoiceID    uint          `json:"invoice_id" gorm:"uniqueIndex:idx_installment_plans_invoice,where:deleted_at IS NULL"`
+	ClinicID     uint          `json:"clinic_id" gorm:"index"`
+	PatientID    uint          `json:"patient_id" gorm:"index"`
+	Currency     string        `json:"currency"`
+	DownPayment  int64         `json:"down_payment"`
+	Total        int64         `json:"total"`
+	CreatedByID  uint          `json:"created_by_id"`
+	Installments []Installment `json:"installments" gorm:"foreignKey:PlanID;constraint:OnDelete:CASCADE"`
+}
+
+// Installment is one dated amount of an installment plan
+type Installment struct {
+	gorm.Model
+	PlanID           uint              `json:"plan_id" gorm:"index"`
+	InvoiceID        uint              `json:"invoice_id" gorm:"index"`
+	ClinicID         uint              `json:"clinic_id" gorm:"index:idx_invoice_installments_clinic_due,priority:1"`
+	PatientID        uint              `json:"patient_id" gorm:"index"`
+	Sequence         int               `json:"sequence"`
+	DueDate          string            `json:"due_date" gorm:"index:idx_invoice_installments_clinic_due,priority:2"`
+	Amount           int64             `json:"amount"`
+	PaidAmount       int64             `json:"paid_amount"`
+	Balance          int64             `json:"balance" gorm:"-"`
+	Status           InstallmentStatus `json:"status" gorm:"default:pending;index"`
+	LastReminderDate string            `json:"last_reminder_date"`
+	ReminderCount    int               `json:"reminder_count"`
+}
+
+func (Installment) TableName() string {
+	return "invoice_installments"
+}
+
+// Resolve fills in the outstanding balances of the installments
+func (p *InstallmentPlan) Resolve() {
+	for i := range p.Installments {
+		p.Installments[i].Balance = p.Installments[i].Amount - p.Installments[i].PaidAmount
+	}
+}
+
+// Allocate spreads what the patient has paid on the invoice since the plan was agreed over the installments,
+// oldest first. Installments emptied again by a refund go back to pending; the overdue job flags them.
+func (p *InstallmentPlan) Allocate(patientPaid int64) {
+	remaining := max(patientPaid-p.DownPayment, 0)
+	for i := range p.Installments {
+		inst := &p.Installments[i]
+		inst.PaidAmount = min(remaining, inst.Amount)
+		remaining -= inst.PaidAmount
+		switch {
+		case inst.PaidAmount == inst.Amount:
+			inst.Status = InstallmentPaid
+		case inst.Status == InstallmentPaid:
+			inst.Status = InstallmentPending
+		}
+	}
+	p.Resolve()
+}
+
+// SplitInstallments divides total into count monthly installments starting on firstDueDate.
+// The remainder of the division is added to the first installment.
+func SplitInstallments(total int64, count int, firstDueDate string) ([]Installment, error) {
+	if count <= 0 {
+		return nil, errors.New("count must be positive")
+	}
+	first, err := time.Parse(DateLayout, firstDueDate)
+	if err != nil {
+		return nil, errors.New("first due date must be in YYYY-MM-DD format")
+	}
+
+	amount := total / int64(count)
+	installments := make([]Installment, count)
+	for i := range installments {
+		installments[i].DueDate = addMonths(first, i).Format(DateLayout)
+		installments[i].Amount = amount
+	}
+	installments[0].Amount += total - amount*int64(count)
+	return installments, nil
+}
+
+// addMonths adds months to a date, keeping it on the last day of shorter months instead of spilling over
+func addMonths(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), min(t.Day(), lastDay), 0, 0, 0, 0, time.UTC)
+}
+
+// OverdueInstallment is an unpaid installment past its due date with the details needed
+// to remind the patient and to report it
+type OverdueInstallment struct {
+	InstallmentID    uint              `json:"installment_id"`
+	InvoiceID        uint              `json:"invoice_id"`
+	ClinicID         uint              `json:"clinic_id"`
+	PatientID        uint              `json:"patient_id"`
+	Sequence         int               `json:"sequence"`
+	Installments     int               `json:"installments"`
+	DueDate          string            `json:"due_date"`
+	Amount           int64             `json:"amount"`
+	PaidAmount       int64             `json:"paid_amount"`
+	Status           InstallmentStatus `json:"status"`
+	LastReminderDate string            `json:"last_reminder_date"`
+	InvoiceNumber    string            `json:"invoice_number"`
+	Currency         string            `json:"currency"`
+	PatientName      string            `json:"patient_name"`
+	// PatientEmail and PatientPhone are the patient's contact info, whichever of the two it holds
+	PatientEmail string `json:"patient_email"`
+	PatientPhone string `json:"patient_phone"`
+	ClinicName   string `json:"clinic_name"`
+	ClinicPhone  string `json:"clinic_phone"`
+}
+
+// Balance returns what is still owed on the installment
+func (o OverdueInstallment) Balance() int64 {
+	return o.Amount - o.PaidAmount
+}
+
+// ReminderDue reports whether the patient should be reminded of the installment today
+func (o OverdueInstallment) ReminderDue(today string) bool {
+	if o.LastReminderDate == "" {
+		return true
+	}
+	last, err := time.Parse(DateLayout, o.LastReminderDate)
+	if err != nil {
+		return true
+	}
+	return last.AddDate(0, 0, OverdueReminderIntervalDays).Format(DateLayout) <= today
+}
+
+// AgingBuckets splits overdue amounts by how many days they are past due
+type AgingBuckets struct {
+	Days1To30  int64 `json:"days_1_30"`
+	Days31To60 int64 `json:"days_31_60"`
+	Days61To90 int64 `json:"days_61_90"`
+	Over90     int64 `json:"over_90"`
+}
+
+// Add puts an amount into the bucket for the given number of days overdue
+func (b *AgingBuckets) Add(daysOverdue int, amount int64) {
+	switch {
+	case daysOverdue <= 30:
+		b.Days1To30 += amount
+	case daysOverdue <= 60:
+		b.Days31To60 += amount
+	case daysOverdue <= 90:
+		b.Days61To90 += amount
+	default:
+		b.Over90 += amount
+	}
+}
+
+// OverduePatient is one patient's overdue receivables in one currency
+type OverduePatient struct {
+	PatientID     uint   `json:"patient_id"`
+	PatientName   string `json:"patient_name"`
+	PatientEmail  string `json:"patient_email"`
+	PatientPhone  string `json:"patient_phone"`
+	Currency      string `json:"currency"`
+	Installments  int    `json:"installments"`
+	Overdue       int64  `json:"overdue"`
+	OldestDueDate string `json:"oldest_due_date"`
+	DaysOverdue   int    `json:"days_overdue"`
+	AgingBuckets
+}
+
+// OverdueTotal sums the overdue receivables of a clinic in one currency
+type OverdueTotal struct {
+	Currency     string `json:"currency"`
+	Patients     int    `json:"patients"`
+	Installments int    `json:"installments"`
+	Overdue      int64  `json:"overdue"`
+	AgingBuckets
+}
+
+// OverdueReport lists a clinic's overdue installments by patient, most overdue first
+type OverdueReport struct {
+	ClinicID uint             `json:"clinic_id"`
+	AsOf     string           `json:"as_of"`
+	Totals   []OverdueTotal   `json:"totals"`
+	Patients []OverduePatient `json:"patients"`
+}
+
+// FormatAmount formats minor units the Turkish way, e.g. 125000 TRY as "1.250,00 TRY"
+func FormatAmount(minor int64, currency string) string {
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	units := fmt.Sprintf("%d", minor/100)
+	var grouped strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%s%s,%02d %s", sign, grouped.String(), minor%100, currency)
+}
+
+// Error types
+var (
+	ErrInstallmentPlanNotFound = errors.New("installment plan not found")
+	ErrInstallmentValidation   = errors.New("invalid installment plan")
+	ErrInstallmentPlanExists   = errors.New("invoice already has an installment plan")
+	ErrInvoiceSettled          = errors.New("invoice has no outstanding balance")
+)
@@ -0,0 +1,117 @@
+package billing
+
+import "dental-clinic-system/models/procedure"
+
+// Calculate fills in the discount, net, VAT and total amounts of the line.
+// A discount amount takes precedence over a discount percentage.
+func (l *Line) Calculate() {
+	gross := l.UnitPrice * int64(l.Quantity)
+	if l.DiscountAmount == 0 && l.DiscountPercent > 0 {
+		l.DiscountAmount = (gross*int64(l.DiscountPercent) + 50) / 100
+	}
+	l.NetAmount = gross - l.DiscountAmount
+	l.VATAmount = procedure.VATAmount(l.NetAmount, l.VATRate)
+	l.Total = l.NetAmount + l.VATAmount
+}
+
+// Calculate numbers the lines, calculates them and sums them into the invoice totals
+func (inv *Invoice) Calculate() {
+	inv.Subtotal, inv.DiscountTotal, inv.NetTotal, inv.VATTotal, inv.Total = 0, 0, 0, 0, 0
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		line.Position = i + 1
+		line.Calculate()
+		inv.Subtotal += line.UnitPrice * int64(line.Quantity)
+		inv.DiscountTotal += line.DiscountAmount
+		inv.NetTotal += line.NetAmount
+		inv.VATTotal += line.VATAmount
+		inv.Total += line.Total
+	}
+}
+
+// Resolve fills in the outstanding balance, its split between the patient and the insurer and the
+// balances of the installments; void invoices owe nothing
+func (inv *Invoice) Resolve() {
+	inv.PatientShare = inv.Total - inv.InsurerShare
+	inv.Balance, inv.PatientBalance, inv.InsurerBalance = 0, 0, 0
+	if inv.Status != InvoiceVoid {
+		inv.Balance = inv.Total - inv.PaidAmount
+		inv.PatientBalance = inv.PatientShare - inv.PatientPaid()
+		inv.InsurerBalance = inv.InsurerShare - inv.InsurerPaid
+	}
+	if inv.Installments != nil {
+		inv.Installments.Resolve()
+	}
+}
+
+// PatientPaid is the part of the paid amount received from the patient
+func (inv *Invoice) PatientPaid() int64 {
+	return inv.PaidAmount - inv.InsurerPaid
+}
+
+// StatusFor derives the status of a live invoice from its total and the amount paid
+func StatusFor(total, paid int64) InvoiceStatus {
+	switch {
+	case paid >= total:
+		return InvoicePaid
+	case paid > 0:
+		return InvoicePartiallyPaid
+	}
+	return InvoiceIssued
+}
+
+// Apply books a payment or refund of the patient against the invoice and updates its paid amount and status.
+// Patients can not pay more than their share and refunds can not exceed what they paid.
+func (inv *Invoice) Apply(kind PaymentKind, amount int64) error {
+	if inv.Status == InvoiceVoid {
+		return ErrInvoiceVoid
+	}
+
+	switch kind {
+	case KindPayment:
+		if amount > inv.Total-inv.InsurerShare-inv.PatientPaid() {
+			return ErrOverpayment
+		}
+		inv.PaidAmount += amount
+	case KindRefund:
+		if amount > inv.PatientPaid() {
+			return ErrRefundExceedsPaid
+		}
+		inv.PaidAmount -= amount
+	default:
+		return ErrPaymentValidation
+	}
+
+	inv.Status = StatusFor(inv.Total, inv.PaidAmount)
+	inv.Resolve()
+	return nil
+}
+
+// ApplyInsurer books a payment or refund of the insurer against the invoice. Insurers can not pay
+// more than their share and refunds can not exceed what they paid.
+func (inv *Invoice) ApplyInsurer(kind PaymentKind, amount int64) error {
+	if inv.Status == InvoiceVoid {
+		return ErrInvoiceVoid
+	}
+
+	switch kind {
+	case KindPayment:
+		if amount > inv.InsurerShare-inv.InsurerPaid {
+			return ErrOverpayment
+		}
+		inv.InsurerPaid += amount
+		inv.PaidAmount += amount
+	case KindRefund:
+		if amount > inv.InsurerPaid {
+			return ErrRefundExceedsPaid
+		}
+		inv.InsurerPaid -= amount
+		inv.PaidAmount -= amount
+	default:
+		return ErrPaymentValidation
+	}
+
+	inv.Status = StatusFor(inv.Total, inv.PaidAmount)
+	inv.Resolve()
+	return nil
+}
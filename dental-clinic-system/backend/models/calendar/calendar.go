@@ -0,0 +1,30 @@
+package calendar
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FeedToken grants read-only access to a doctor's iCalendar feed.
+// Only the SHA-256 hash of the token is stored; the token itself is shown once when it is created.
+type FeedToken struct {
+	gorm.Model
+	ClinicID    uint       `json:"clinic_id"`
+	DoctorID    uint       `json:"doctor_id" gorm:"index"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex"`
+	CreatedByID uint       `json:"created_by_id"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+// IsRevoked reports whether the token can no longer be used
+func (t FeedToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// Error types
+var (
+	ErrFeedTokenNotFound = errors.New("calendar feed token not found")
+)
@@ -2,6 +2,7 @@ package clinic
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -12,6 +13,45 @@ type Clinic struct {
 	Address     string `json:"address"`
 	PhoneNumber string `json:"phone_number" gorm:"uniqueIndex"`
 	Email       string `json:"email" gorm:"uniqueIndex"`
+	Timezone    string `json:"timezone" gorm:"default:Europe/Istanbul"`
+	// Tax details printed on e-invoices: a 10 digit VKN, or the 11 digit TCKN of a sole proprietor
+	TaxNumber      string `json:"tax_number"`
+	TaxOffice      string `json:"tax_office"`
+	District       string `json:"district"`
+	City           string `json:"city"`
+	EInvoicePrefix string `json:"e_invoice_prefix"`
+	// Logo is printed on patient documents; it is managed through its own endpoint and never serialised
+	Logo            []byte `json:"-"`
+	LogoContentType string `json:"logo_content_type"`
+}
+
+// HasLogo reports whether the clinic has uploaded a logo
+func (c Clinic) HasLogo() bool {
+	return len(c.Logo) > 0
+}
+
+// Logo limits
+const (
+	MaxLogoBytes     = 256 << 10
+	MaxLogoDimension = 2000
+)
+
+// DefaultTimezone is used for clinics that have not configured a time zone
+const DefaultTimezone = "Europe/Istanbul"
+
+// Location returns the clinic's time zone, falling back to DefaultTimezone
+func (c Clinic) Location() *time.Location {
+	name := c.Timezone
+	if name == "" {
+		name = DefaultTimezone
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	if loc, err := time.LoadLocation(DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
 }
 
 // Error types
@@ -23,4 +63,5 @@ var (
 	ErrClinicUpdate         = errors.New("failed to update clinic")
 	ErrClinicDeletion       = errors.New("failed to delete clinic")
 	ErrClinicExistenceCheck = errors.New("failed to check clinic existence")
+	ErrInvalidLogo          = errors.New("invalid clinic logo")
 )
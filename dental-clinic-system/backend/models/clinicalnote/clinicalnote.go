@@ -0,0 +1,197 @@
+package clinicalnote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Template is a clinic-defined layout for clinical notes, e.g. SOAP or an extraction report.
+// Notes copy the sections of their template, so changing a template never alters existing notes.
+type Template struct {
+	gorm.Model
+	ClinicID    uint              `json:"clinic_id" gorm:"index"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Active      bool              `json:"active" gorm:"default:true"`
+	Sections    []TemplateSection `json:"sections" gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE"`
+}
+
+func (Template) TableName() string {
+	return "clinical_note_templates"
+}
+
+// TemplateSection is a heading of a template. Required sections must be filled in before a note is signed.
+type TemplateSection struct {
+	gorm.Model
+	TemplateID uint   `json:"template_id" gorm:"index"`
+	Position   int    `json:"position"`
+	Key        string `json:"key"`
+	Title      string `json:"title"`
+	Hint       string `json:"hint"`
+	Required   bool   `json:"required"`
+}
+
+func (TemplateSection) TableName() string {
+	return "clinical_note_template_sections"
+}
+
+// DefaultTemplates are given to every clinic the first time it lists its templates
+func DefaultTemplates() []Template {
+	return []Template{
+		{
+			Name:        "SOAP",
+			Description: "Subjective, objective, assessment and plan",
+			Active:      true,
+			Sections: []TemplateSection{
+				{Key: "subjective", Title: "Subjective", Hint: "Chief complaint and history in the patient's words", Required: true},
+				{Key: "objective", Title: "Objective", Hint: "Examination findings, tests and radiographs", Required: true},
+				{Key: "assessment", Title: "Assessment", Hint: "Diagnosis", Required: true},
+				{Key: "plan", Title: "Plan", Hint: "Treatment done today and next steps", Required: true},
+			},
+		},
+		{
+			Name:        "Extraction",
+			Description: "Tooth extraction report",
+			Active:      true,
+			Sections: []TemplateSection{
+				{Key: "indication", Title: "Indication", Hint: "Tooth and reason for extraction", Required: true},
+				{Key: "anaesthesia", Title: "Anaesthesia", Hint: "Agent, dose and technique", Required: true},
+				{Key: "procedure", Title: "Procedure", Hint: "Simple or surgical, sectioning, sutures", Required: true},
+				{Key: "complications", Title: "Complications", Hint: "Bleeding, root fracture, sinus exposure"},
+				{Key: "post_op", Title: "Post-operative instructions", Hint: "Instructions and prescriptions given", Required: true},
+			},
+		},
+		{
+			Name:        "Root canal",
+			Description: "Endodontic treatment record",
+			Active:      true,
+			Sections: []TemplateSection{
+				{Key: "diagnosis", Title: "Diagnosis", Hint: "Tooth, pulpal and periapical diagnosis", Required: true},
+				{Key: "anaesthesia", Title: "Anaesthesia", Hint: "Agent, dose and technique"},
+				{Key: "canals", Title: "Canals and working lengths", Hint: "Canal, reference point, length in mm", Required: true},
+				{Key: "irrigation", Title: "Irrigation and medication", Hint: "Irrigants and intracanal medicament"},
+				{Key: "obturation", Title: "Obturation", Hint: "Technique and sealer, or temporary filling"},
+				{Key: "next_visit", Title: "Next visit", Hint: "Planned next step"},
+			},
+		},
+	}
+}
+
+// Status is the state of a clinical note
+type Status string
+
+const (
+	StatusDraft  Status = "draft"
+	StatusSigned Status = "signed"
+)
+
+// Note is a clinical note written about an appointment. Drafts can be changed by their author;
+// once the author signs the note it can never change again and corrections are added as addenda.
+type Note struct {
+	gorm.Model
+	ClinicID      uint   `json:"clinic_id" gorm:"index"`
+	PatientID     uint   `json:"patient_id" gorm:"index"`
+	AppointmentID uint   `json:"appointment_id" gorm:"index"`
+	AuthorID      uint   `json:"author_id"`
+	TemplateID    *uint  `json:"template_id"`
+	Title         string `json:"title"`
+	Status        Status `json:"status" gorm:"default:draft"`
+	// ContentHash is the SHA-256 of the note's content taken when it was signed
+	ContentHash string     `json:"content_hash"`
+	SignedAt    *time.Time `json:"signed_at"`
+	Sections    []Section  `json:"sections" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	Addenda     []Addendum `json:"addenda" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+}
+
+func (Note) TableName() string {
+	return "clinical_notes"
+}
+
+// Section is a heading of a note with the text written under it
+type Section struct {
+	gorm.Model
+	NoteID   uint   `json:"note_id" gorm:"index"`
+	Position int    `json:"position"`
+	Key      string `json:"key"`
+	Title    string `json:"title"`
+	Required bool   `json:"required"`
+	Body     string `json:"body"`
+}
+
+func (Section) TableName() string {
+	return "clinical_note_sections"
+}
+
+// Addendum is a correction or later observation appended to a signed note. Addenda are never changed.
+type Addendum struct {
+	gorm.Model
+	NoteID      uint   `json:"note_id" gorm:"index"`
+	AuthorID    uint   `json:"author_id"`
+	Body        string `json:"body"`
+	ContentHash string `json:"content_hash"`
+}
+
+func (Addendum) TableName() string {
+	return "clinical_note_addenda"
+}
+
+// MissingSections lists the titles of required sections that are still empty
+func (n Note) MissingSections() []string {
+	var missing []string
+	for _, section := range n.Sections {
+		if section.Required && strings.TrimSpace(section.Body) == "" {
+			missing = append(missing, section.Title)
+		}
+	}
+	return missing
+}
+
+// CanSign reports why the given user can not sign the note, or nil if they can
+func (n Note) CanSign(signerID uint) error {
+	if n.Status == StatusSigned {
+		return ErrNoteSigned
+	}
+	if n.AuthorID != signerID {
+		return ErrNotAuthor
+	}
+	if missing := n.MissingSections(); len(missing) > 0 {
+		return fmt.Errorf("%w: %s must be filled in before signing", ErrNoteValidation, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Hash returns the SHA-256 of what the author signs: the note's patient, appointment, author, title and sections
+func (n Note) Hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "patient:%d\nappointment:%d\nauthor:%d\ntitle:%s\n", n.PatientID, n.AppointmentID, n.AuthorID, n.Title)
+	for _, section := range n.Sections {
+		fmt.Fprintf(h, "section:%s:%d:%s\n%d:%s\n", section.Key, len(section.Title), section.Title, len(section.Body), section.Body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Hash returns the SHA-256 of the addendum's note, author, body and time
+func (a Addendum) Hash(at time.Time) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "note:%d\nauthor:%d\nat:%s\n%s", a.NoteID, a.AuthorID, at.UTC().Format(time.RFC3339Nano), a.Body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Error types
+var (
+	ErrNoteNotFound               = errors.New("clinical note not found")
+	ErrTemplateNotFound           = errors.New("clinical note template not found")
+	ErrAppointmentNotFound        = errors.New("appointment not found")
+	ErrNoteValidation             = errors.New("invalid clinical note")
+	ErrTemplateValidation         = errors.New("invalid clinical note template")
+	ErrNoteSigned                 = errors.New("signed clinical notes can not be changed, add an addendum instead")
+	ErrNoteNotSigned              = errors.New("addenda can only be added to signed clinical notes")
+	ErrNotAuthor                  = errors.New("only the author can change or sign a clinical note")
+	ErrAppointmentNotDocumentable = errors.New("clinical notes can not be written for cancelled or missed appointments")
+)
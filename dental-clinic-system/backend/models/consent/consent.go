@@ -0,0 +1,161 @@
+package consent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Template is a clinic's consent form, e.g. for extractions or implants. Every change to its title or
+// body is kept as a new version, so the exact text a patient signed can always be shown again.
+type Template struct {
+	gorm.Model
+	ClinicID uint   `json:"clinic_id" gorm:"index"`
+	Name     string `json:"name"`
+	Active   bool   `json:"active" gorm:"default:true"`
+	// Version is the number of the current version of the title and body
+	Version int    `json:"version"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	// Procedures are the procedures that may not be started before the patient has signed this form
+	Procedures []TemplateProcedure `json:"procedures" gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE"`
+}
+
+func (Template) TableName() string {
+	return "consent_templates"
+}
+
+// TemplateProcedure makes a signed consent a requirement for starting a procedure
+type TemplateProcedure struct {
+	ID          uint `json:"id" gorm:"primaryKey"`
+	TemplateID  uint `json:"template_id" gorm:"uniqueIndex:idx_consent_template_procedures_template_procedure"`
+	ProcedureID uint `json:"procedure_id" gorm:"uniqueIndex:idx_consent_template_procedures_template_procedure;index"`
+}
+
+func (TemplateProcedure) TableName() string {
+	return "consent_template_procedures"
+}
+
+// TemplateVersion is the text of a template as it was between two changes. Versions are never changed.
+type TemplateVersion struct {
+	gorm.Model
+	TemplateID  uint   `json:"template_id" gorm:"uniqueIndex:idx_consent_template_versions_template_version"`
+	Version     int    `json:"version" gorm:"uniqueIndex:idx_consent_template_versions_template_version"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	CreatedByID uint   `json:"created_by_id"`
+}
+
+func (TemplateVersion) TableName() string {
+	return "consent_template_versions"
+}
+
+// Status is the state of a consent form
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusSigned  Status = "signed"
+)
+
+// Form is a consent template prepared for a patient and procedure. It copies the template's current
+// text when it is prepared; once signed it never changes and is kept as a PDF attachment.
+type Form struct {
+	gorm.Model
+	ClinicID      uint   `json:"clinic_id" gorm:"index"`
+	PatientID     uint   `json:"patient_id" gorm:"index:idx_consent_forms_patient_procedure,priority:1"`
+	ProcedureID   uint   `json:"procedure_id" gorm:"index:idx_consent_forms_patient_procedure,priority:2"`
+	ProcedureName string `json:"procedure_name"`
+	TemplateID    uint   `json:"template_id" gorm:"index"`
+	Version       int    `json:"version"`
+	Title         string `json:"title"`
+	Body          string `json:"body"`
+	PreparedByID  uint   `json:"prepared_by_id"`
+	Status        Status `json:"status" gorm:"default:pending"`
+	// SignerName is the patient, or the guardian signing for them
+	SignerName    string     `json:"signer_name"`
+	SignatureType string     `json:"signature_type"`
+	Signature     []byte     `json:"-"`
+	SignedAt      *time.Time `json:"signed_at"`
+	SignedIP      string     `json:"signed_ip"`
+	WitnessID     *uint      `json:"witness_id"`
+	// ContentHash is the SHA-256 of what was signed, printed on the PDF
+	ContentHash string `json:"content_hash"`
+	// AttachmentID is the signed PDF and DocumentHash its SHA-256
+	AttachmentID *uint  `json:"attachment_id"`
+	DocumentHash string `json:"document_hash"`
+}
+
+func (Form) TableName() string {
+	return "consent_forms"
+}
+
+// SignRequest captures a drawn signature. Signature is a PNG or SVG image as a base64 data URL.
+type SignRequest struct {
+	SignerName string `json:"signer_name"`
+	Signature  string `json:"signature"`
+}
+
+// Requirement is a consent a procedure needs, with the signed form that satisfies it if there is one
+type Requirement struct {
+	TemplateID uint   `json:"template_id"`
+	Name       string `json:"name"`
+	Signed     bool   `json:"signed"`
+	FormID     *uint  `json:"form_id"`
+}
+
+// Hash returns the SHA-256 of what is signed: the form's patient, procedure, template version and text,
+// the signer and their signature, and when, where and before whom it was signed
+func (f Form) Hash() string {
+	var signedAt string
+	if f.SignedAt != nil {
+		signedAt = f.SignedAt.UTC().Format(time.RFC3339Nano)
+	}
+	var witnessID uint
+	if f.WitnessID != nil {
+		witnessID = *f.WitnessID
+	}
+	signature := sha256.Sum256(f.Signature)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "patient:%d\nprocedure:%d\ntemplate:%d\nversion:%d\n", f.PatientID, f.ProcedureID, f.TemplateID, f.Version)
+	fmt.Fprintf(h, "title:%d:%s\nbody:%d:%s\n", len(f.Title), f.Title, len(f.Body), f.Body)
+	fmt.Fprintf(h, "signer:%s\nsignature:%s:%s\nat:%s\nip:%s\nwitness:%d\n",
+		f.SignerName, f.SignatureType, hex.EncodeToString(signature[:]), signedAt, f.SignedIP, witnessID)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Error types
+var (
+	ErrTemplateNotFound   = errors.New("consent template not found")
+	ErrFormNotFound       = errors.New("consent form not found")
+	ErrProcedureNotFound  = errors.New("procedure not found")
+	ErrTemplateValidation = errors.New("invalid consent template")
+	ErrFormValidation     = errors.New("invalid consent form")
+	ErrFormSigned         = errors.New("signed consent forms can not be changed")
+	ErrFormNotSigned      = errors.New("consent form has not been signed yet")
+	ErrConsentRequired    = errors.New("signed consent is required before the procedure is started")
+)
+
+// MissingConsentError lists the consents a procedure still needs
+type MissingConsentError struct {
+	ProcedureID uint
+	Missing     []Requirement
+}
+
+func (e *MissingConsentError) Error() string {
+	names := make([]string, len(e.Missing))
+	for i, requirement := range e.Missing {
+		names[i] = requirement.Name
+	}
+	return fmt.Sprintf("%s: %s", ErrConsentRequired.Error(), strings.Join(names, ", "))
+}
+
+func (e *MissingConsentError) Unwrap() error {
+	return ErrConsentRequired
+}
@@ -0,0 +1,47 @@
+package document
+
+import "errors"
+
+// ContentTypePDF is the content type of rendered documents
+const ContentTypePDF = "application/pdf"
+
+// MaxAttachmentBytes keeps emailed documents well below the size limit of a Kafka message,
+// which also has to carry the base64 encoded file
+const MaxAttachmentBytes = 512 << 10
+
+// Kind is the type of a printable document
+type Kind string
+
+const (
+	KindInvoice       Kind = "invoice"
+	KindTreatmentPlan Kind = "treatment_plan"
+	KindVisitSummary  Kind = "visit_summary"
+	KindConsent       Kind = "consent"
+)
+
+// Document is a rendered patient document ready to be downloaded or emailed
+type Document struct {
+	Kind        Kind   `json:"kind"`
+	ClinicID    uint   `json:"clinic_id"`
+	ClinicName  string `json:"clinic_name"`
+	PatientName string `json:"patient_name"`
+	Title       string `json:"title"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"-"`
+	// Recipient is the patient's email address on file, if there is one
+	Recipient string `json:"-"`
+}
+
+// EmailRequest asks for a document to be emailed; the patient's address on file is used when To is empty
+type EmailRequest struct {
+	To string `json:"to"`
+}
+
+// Error types
+var (
+	ErrNoRecipient         = errors.New("patient has no email address on file; provide one in the request")
+	ErrInvalidRecipient    = errors.New("invalid recipient email address")
+	ErrAttachmentTooBig    = errors.New("document is too large to be sent by email")
+	ErrAppointmentNotFound = errors.New("appointment not found")
+)
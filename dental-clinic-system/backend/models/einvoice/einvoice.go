@@ -0,0 +1,85 @@
+package einvoice
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UBL-TR 1.2 document constants
+const (
+	UBLVersionID    = "2.1"
+	CustomizationID = "TR1.2"
+
+	// ProfileEArchive is used for invoices to patients, who are not registered e-Fatura users
+	ProfileEArchive = "EARSIVFATURA"
+
+	InvoiceTypeSales = "SATIS"
+
+	// DefaultPrefix starts the document IDs of clinics that have not chosen their own prefix
+	DefaultPrefix = "DNT"
+
+	// AnonymousTCKN is accepted by GİB for e-Arşiv invoices to consumers without a known TCKN
+	AnonymousTCKN = "11111111111"
+)
+
+// Document is a generated UBL-TR invoice ready to be downloaded or submitted
+type Document struct {
+	InvoiceID uint   `json:"invoice_id"`
+	ClinicID  uint   `json:"clinic_id"`
+	ID        string `json:"id"`
+	UUID      string `json:"uuid"`
+	Profile   string `json:"profile"`
+	IssueDate string `json:"issue_date"`
+	XML       []byte `json:"-"`
+}
+
+// FileName is the name the document is downloaded and archived under
+func (d Document) FileName() string {
+	return d.ID + ".xml"
+}
+
+// SubmissionStatus is the state of a document at the GİB integrator
+type SubmissionStatus string
+
+const (
+	SubmissionAccepted SubmissionStatus = "accepted"
+	SubmissionPending  SubmissionStatus = "pending"
+	SubmissionRejected SubmissionStatus = "rejected"
+)
+
+// SubmissionResult is what an integrator answers when a document is sent
+type SubmissionResult struct {
+	Status    SubmissionStatus
+	Reference string
+	Message   string
+}
+
+// Submission records that an invoice was sent to the integrator, so it is not sent twice
+type Submission struct {
+	gorm.Model
+	InvoiceID     uint             `json:"invoice_id" gorm:"uniqueIndex:idx_einvoice_submissions_invoice,where:deleted_at IS NULL"`
+	ClinicID      uint             `json:"clinic_id" gorm:"index"`
+	DocumentID    string           `json:"document_id"`
+	UUID          string           `json:"uuid"`
+	Profile       string           `json:"profile"`
+	Status        SubmissionStatus `json:"status"`
+	Reference     string           `json:"reference"`
+	Message       string           `json:"message"`
+	SubmittedAt   time.Time        `json:"submitted_at"`
+	SubmittedByID uint             `json:"submitted_by_id"`
+}
+
+func (Submission) TableName() string {
+	return "einvoice_submissions"
+}
+
+// Error types
+var (
+	ErrNotConfigured      = errors.New("clinic tax details are incomplete; set tax number, tax office, district and city")
+	ErrInvoiceVoid        = errors.New("void invoices can not be exported as e-invoices")
+	ErrInvalidRange       = errors.New("invalid export date range")
+	ErrAlreadySubmitted   = errors.New("invoice has already been submitted")
+	ErrSubmissionNotFound = errors.New("invoice has not been submitted")
+)
@@ -0,0 +1,229 @@
+package insurance
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DateLayout is the layout of policy validity and claim service dates
+const DateLayout = "2006-01-02"
+
+// Provider is a private insurance company whose policies a clinic accepts
+type Provider struct {
+	gorm.Model
+	ClinicID uint   `json:"clinic_id" gorm:"index:idx_insurance_providers_clinic_code,priority:1"`
+	Name     string `json:"name"`
+	Code     string `json:"code" gorm:"index:idx_insurance_providers_clinic_code,unique,priority:2,where:code <> '' AND deleted_at IS NULL"`
+	Phone    string `json:"phone"`
+	Email    string `json:"email"`
+	Active   bool   `json:"active"`
+}
+
+func (Provider) TableName() string {
+	return "insurance_providers"
+}
+
+// Policy is a patient's dental insurance with a provider. The insurer covers CoveragePercent of each
+// invoice up to AnnualLimit per policy year; an annual limit of zero means there is no limit.
+// Policy years start on the anniversaries of ValidFrom. Amounts are in minor currency units.
+type Policy struct {
+	gorm.Model
+	ClinicID        uint     `json:"clinic_id" gorm:"index"`
+	PatientID       uint     `json:"patient_id" gorm:"index"`
+	ProviderID      uint     `json:"provider_id" gorm:"index"`
+	Provider        Provider `json:"provider" gorm:"foreignKey:ProviderID"`
+	PolicyNumber    string   `json:"policy_number"`
+	CoveragePercent int      `json:"coverage_percent"`
+	AnnualLimit     int64    `json:"annual_limit"`
+	Currency        string   `json:"currency" gorm:"default:TRY"`
+	ValidFrom       string   `json:"valid_from"`
+	ValidTo         string   `json:"valid_to"`
+	Active          bool     `json:"active"`
+}
+
+func (Policy) TableName() string {
+	return "insurance_policies"
+}
+
+// ValidOn reports whether the policy is active and in force on date (YYYY-MM-DD)
+func (p Policy) ValidOn(date string) bool {
+	return p.Active && date >= p.ValidFrom && (p.ValidTo == "" || date <= p.ValidTo)
+}
+
+// PolicyYear returns the first and last day of the policy year that date falls in
+func (p Policy) PolicyYear(date string) (string, string, error) {
+	from, err := time.Parse(DateLayout, p.ValidFrom)
+	if err != nil {
+		return "", "", err
+	}
+	day, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return "", "", err
+	}
+
+	years := day.Year() - from.Year()
+	start := from.AddDate(years, 0, 0)
+	if start.After(day) {
+		start = from.AddDate(years-1, 0, 0)
+	}
+	end := start.AddDate(1, 0, -1)
+	return start.Format(DateLayout), end.Format(DateLayout), nil
+}
+
+// Share is what the insurer covers of amount: the coverage percentage of it, capped by what is left
+// of the annual limit after the amount already used in the policy year
+func (p Policy) Share(amount, used int64) int64 {
+	share := (amount*int64(p.CoveragePercent) + 50) / 100
+	if p.AnnualLimit > 0 {
+		share = min(share, max(p.AnnualLimit-used, 0))
+	}
+	return max(share, 0)
+}
+
+// ClaimStatus is the state of an insurance claim
+type ClaimStatus string
+
+const (
+	ClaimSubmitted         ClaimStatus = "submitted"
+	ClaimApproved          ClaimStatus = "approved"
+	ClaimPartiallyApproved ClaimStatus = "partially_approved"
+	ClaimRejected          ClaimStatus = "rejected"
+	ClaimPaid              ClaimStatus = "paid"
+)
+
+// IsValid reports whether the status is one of the known claim statuses
+func (s ClaimStatus) IsValid() bool {
+	switch s {
+	case ClaimSubmitted, ClaimApproved, ClaimPartiallyApproved, ClaimRejected, ClaimPaid:
+		return true
+	}
+	return false
+}
+
+// IsDecision reports whether the status is one an insurer can decide a submitted claim with
+func (s ClaimStatus) IsDecision() bool {
+	switch s {
+	case ClaimApproved, ClaimPartiallyApproved, ClaimRejected:
+		return true
+	}
+	return false
+}
+
+// Claim asks an insurer to pay its share of an invoice under a policy. A claim moves from submitted
+// to approved, partially approved or rejected, and approved claims move to paid when the insurer pays.
+// An invoice has at most one claim that is not rejected.
+type Claim struct {
+	gorm.Model
+	ClinicID        uint        `json:"clinic_id" gorm:"index"`
+	InvoiceID       uint        `json:"invoice_id" gorm:"index"`
+	PolicyID        uint        `json:"policy_id" gorm:"index"`
+	Policy          Policy      `json:"policy" gorm:"foreignKey:PolicyID"`
+	PatientID       uint        `json:"patient_id" gorm:"index"`
+	Status          ClaimStatus `json:"status" gorm:"default:submitted;index"`
+	Currency        string      `json:"currency"`
+	ServiceDate     string      `json:"service_date"`
+	InvoiceTotal    int64       `json:"invoice_total"`
+	ClaimedAmount   int64       `json:"claimed_amount"`
+	ApprovedAmount  int64       `json:"approved_amount"`
+	PaidAmount      int64       `json:"paid_amount"`
+	Reference       string      `json:"reference"`
+	RejectionReason string      `json:"rejection_reason"`
+	Notes           string      `json:"notes"`
+	SubmittedAt     time.Time   `json:"submitted_at"`
+	SubmittedByID   uint        `json:"submitted_by_id"`
+	DecidedAt       *time.Time  `json:"decided_at"`
+	DecidedByID     *uint       `json:"decided_by_id"`
+	PaidAt          *time.Time  `json:"paid_at"`
+	PaymentID       *uint       `json:"payment_id"`
+}
+
+func (Claim) TableName() string {
+	return "insurance_claims"
+}
+
+// CoveredAmount is the part of the invoice the claim takes off the patient's share: the claimed amount
+// while the insurer decides, the approved amount afterwards and nothing once rejected
+func (c Claim) CoveredAmount() int64 {
+	switch c.Status {
+	case ClaimSubmitted:
+		return c.ClaimedAmount
+	case ClaimApproved, ClaimPartiallyApproved, ClaimPaid:
+		return c.ApprovedAmount
+	}
+	return 0
+}
+
+// Decide applies the insurer's decision to a submitted claim
+func (c *Claim) Decide(decision Decision, actorID uint, at time.Time) error {
+	if c.Status != ClaimSubmitted {
+		return ErrClaimNotSubmitted
+	}
+	c.Status = decision.Status
+	c.ApprovedAmount = decision.ApprovedAmount
+	c.RejectionReason = decision.RejectionReason
+	if decision.Reference != "" {
+		c.Reference = decision.Reference
+	}
+	c.DecidedAt = &at
+	c.DecidedByID = &actorID
+	return nil
+}
+
+// Decision is an insurer's answer to a claim. An approval covers the claimed amount, a partial approval
+// a smaller amount, and a rejection needs a reason.
+type Decision struct {
+	Status          ClaimStatus `json:"status"`
+	ApprovedAmount  int64       `json:"approved_amount"`
+	Reference       string      `json:"reference"`
+	RejectionReason string      `json:"rejection_reason"`
+}
+
+// Coverage is what is left of a policy's annual limit in a policy year. Pending is reserved by claims
+// the insurer has not decided yet. Remaining is nil for policies without an annual limit.
+// The estimates are filled in when coverage is asked for a planned treatment amount.
+type Coverage struct {
+	PolicyID              uint   `json:"policy_id"`
+	PolicyNumber          string `json:"policy_number"`
+	ProviderID            uint   `json:"provider_id"`
+	ProviderName          string `json:"provider_name"`
+	CoveragePercent       int    `json:"coverage_percent"`
+	Currency              string `json:"currency"`
+	YearStart             string `json:"year_start"`
+	YearEnd               string `json:"year_end"`
+	AnnualLimit           int64  `json:"annual_limit"`
+	Used                  int64  `json:"used"`
+	Pending               int64  `json:"pending"`
+	Remaining             *int64 `json:"remaining"`
+	EstimatedInsurerShare *int64 `json:"estimated_insurer_share,omitempty"`
+	EstimatedPatientShare *int64 `json:"estimated_patient_share,omitempty"`
+}
+
+// Usage is what claims under a policy have taken of its annual limit
+type Usage struct {
+	Used    int64 `json:"used"`
+	Pending int64 `json:"pending"`
+}
+
+// Total is the part of the annual limit no longer available
+func (u Usage) Total() int64 {
+	return u.Used + u.Pending
+}
+
+// Error types
+var (
+	ErrProviderNotFound   = errors.New("insurance provider not found")
+	ErrProviderValidation = errors.New("invalid insurance provider")
+	ErrProviderInactive   = errors.New("insurance provider is not active")
+	ErrPolicyNotFound     = errors.New("insurance policy not found")
+	ErrPolicyValidation   = errors.New("invalid insurance policy")
+	ErrPolicyNotValid     = errors.New("insurance policy is not in force on the invoice date")
+	ErrClaimNotFound      = errors.New("insurance claim not found")
+	ErrClaimValidation    = errors.New("invalid insurance claim")
+	ErrClaimExists        = errors.New("invoice already has an open or settled insurance claim")
+	ErrClaimNotSubmitted  = errors.New("only submitted claims can be decided")
+	ErrClaimNotApproved   = errors.New("only approved or partially approved claims can be paid")
+	ErrNothingToClaim     = errors.New("the policy covers nothing of this invoice")
+	ErrInvoiceHasPlan     = errors.New("invoices with an installment plan can not be claimed; cancel the plan first")
+)
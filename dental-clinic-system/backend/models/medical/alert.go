@@ -0,0 +1,179 @@
+package medical
+
+import (
+	"dental-clinic-system/models/schedule"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlertLevel is how urgently an alert must be read before treatment
+type AlertLevel string
+
+const (
+	AlertCritical AlertLevel = "critical"
+	AlertWarning  AlertLevel = "warning"
+	AlertInfo     AlertLevel = "info"
+)
+
+func (l AlertLevel) rank() int {
+	switch l {
+	case AlertCritical:
+		return 0
+	case AlertWarning:
+		return 1
+	}
+	return 2
+}
+
+// AlertKind tells which part of the medical history raised an alert
+type AlertKind string
+
+const (
+	AlertKindAllergy       AlertKind = "allergy"
+	AlertKindMedication    AlertKind = "medication"
+	AlertKindCondition     AlertKind = "condition"
+	AlertKindQuestionnaire AlertKind = "questionnaire"
+)
+
+// Alert is a flag a clinician must see before treating the patient.
+// SourceID is the allergy, medication, condition or questionnaire that raised it; 0 when nothing is on file.
+type Alert struct {
+	Level    AlertLevel `json:"level"`
+	Kind     AlertKind  `json:"kind"`
+	SourceID uint       `json:"source_id"`
+	Message  string     `json:"message"`
+}
+
+// medicationLevels are the medication classes that need attention; other classes are listed as info
+var medicationLevels = map[MedicationClass]AlertLevel{
+	ClassAnticoagulant:     AlertCritical,
+	ClassAntiplatelet:      AlertCritical,
+	ClassBisphosphonate:    AlertWarning,
+	ClassImmunosuppressant: AlertWarning,
+	ClassInsulin:           AlertWarning,
+}
+
+// medicationRisks explains why a medication class matters in the chair
+var medicationRisks = map[MedicationClass]string{
+	ClassAnticoagulant:     "bleeding risk",
+	ClassAntiplatelet:      "bleeding risk",
+	ClassBisphosphonate:    "osteonecrosis risk after extractions",
+	ClassImmunosuppressant: "infection risk",
+	ClassInsulin:           "hypoglycaemia risk",
+}
+
+// conditionLevels are the conditions that need attention; other conditions are listed as warnings
+var conditionLevels = map[ConditionCode]AlertLevel{
+	ConditionAnticoagulantTherapy: AlertCritical,
+	ConditionBleedingDisorder:     AlertCritical,
+	ConditionPregnancy:            AlertCritical,
+	ConditionHeartDisease:         AlertCritical,
+}
+
+// BuildAlerts derives the alerts of a patient from their medical history as of today (YYYY-MM-DD).
+// Alerts are ordered critical first; within a level allergies come before medications, conditions and the questionnaire.
+func BuildAlerts(record Record, today string) []Alert {
+	alerts := []Alert{}
+
+	for _, allergy := range record.Allergies {
+		level := AlertWarning
+		if allergy.Severity == SeveritySevere || allergy.Severity == SeverityLifeThreatening {
+			level = AlertCritical
+		}
+		message := fmt.Sprintf("Allergic to %s (%s)", allergy.Allergen, strings.ReplaceAll(string(allergy.Severity), "_", " "))
+		if allergy.Reaction != "" {
+			message += ": " + allergy.Reaction
+		}
+		alerts = append(alerts, Alert{Level: level, Kind: AlertKindAllergy, SourceID: allergy.ID, Message: message})
+	}
+
+	for _, medication := range record.Medications {
+		if !medication.CurrentOn(today) {
+			continue
+		}
+		level, ok := medicationLevels[medication.Class]
+		if !ok {
+			level = AlertInfo
+		}
+		message := "Takes " + medication.Name
+		if medication.Dosage != "" {
+			message += " " + medication.Dosage
+		}
+		if risk, ok := medicationRisks[medication.Class]; ok {
+			message += " (" + risk + ")"
+		}
+		alerts = append(alerts, Alert{Level: level, Kind: AlertKindMedication, SourceID: medication.ID, Message: message})
+	}
+
+	for _, condition := range record.Conditions {
+		if !condition.ActiveOn(today) {
+			continue
+		}
+		level, ok := conditionLevels[condition.Code]
+		if !ok {
+			level = AlertWarning
+		}
+		name := condition.Name
+		if name == "" {
+			name = strings.ReplaceAll(string(condition.Code), "_", " ")
+		}
+		alerts = append(alerts, Alert{Level: level, Kind: AlertKindCondition, SourceID: condition.ID, Message: "Condition: " + name})
+	}
+
+	alerts = append(alerts, questionnaireAlerts(record.Questionnaire, today)...)
+
+	kindOrder := map[AlertKind]int{AlertKindAllergy: 0, AlertKindMedication: 1, AlertKindCondition: 2, AlertKindQuestionnaire: 3}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if alerts[i].Level != alerts[j].Level {
+			return alerts[i].Level.rank() < alerts[j].Level.rank()
+		}
+		return kindOrder[alerts[i].Kind] < kindOrder[alerts[j].Kind]
+	})
+	return alerts
+}
+
+// questionnaireAlerts flags a missing or outdated questionnaire and every "yes" answer of the latest one
+func questionnaireAlerts(questionnaire *Questionnaire, today string) []Alert {
+	if questionnaire == nil {
+		return []Alert{{Level: AlertInfo, Kind: AlertKindQuestionnaire, Message: "No medical questionnaire on file"}}
+	}
+
+	var alerts []Alert
+	for _, question := range Questions {
+		for _, answer := range questionnaire.Answers {
+			if answer.Question != question.Key || !answer.Answer {
+				continue
+			}
+			message := "Answered yes: " + question.Text
+			if answer.Details != "" {
+				message += ": " + answer.Details
+			}
+			alerts = append(alerts, Alert{Level: question.Level, Kind: AlertKindQuestionnaire, SourceID: questionnaire.ID, Message: message})
+		}
+	}
+
+	if questionnaire.Notes != "" {
+		alerts = append(alerts, Alert{Level: AlertInfo, Kind: AlertKindQuestionnaire, SourceID: questionnaire.ID, Message: "Questionnaire notes: " + questionnaire.Notes})
+	}
+
+	if Outdated(questionnaire.AnsweredOn, today) {
+		alerts = append(alerts, Alert{
+			Level:    AlertInfo,
+			Kind:     AlertKindQuestionnaire,
+			SourceID: questionnaire.ID,
+			Message:  fmt.Sprintf("Medical questionnaire from %s is older than %d months", questionnaire.AnsweredOn, QuestionnaireValidityMonths),
+		})
+	}
+	return alerts
+}
+
+// Outdated reports whether a questionnaire answered on the given day must be renewed by today
+func Outdated(answeredOn, today string) bool {
+	answered, err := time.Parse(schedule.DateLayout, answeredOn)
+	if err != nil {
+		return true
+	}
+	return answered.AddDate(0, QuestionnaireValidityMonths, 0).Format(schedule.DateLayout) <= today
+}
@@ -0,0 +1,182 @@
+package medical
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Severity is how strongly a patient reacts to an allergen
+type Severity string
+
+const (
+	SeverityMild            Severity = "mild"
+	SeverityModerate        Severity = "moderate"
+	SeveritySevere          Severity = "severe"
+	SeverityLifeThreatening Severity = "life_threatening"
+)
+
+// IsValid reports whether the severity is one of the known severities
+func (s Severity) IsValid() bool {
+	switch s {
+	case SeverityMild, SeverityModerate, SeveritySevere, SeverityLifeThreatening:
+		return true
+	}
+	return false
+}
+
+// AllergyCategory groups allergens so that, e.g., all drug allergies can be checked before prescribing
+type AllergyCategory string
+
+const (
+	AllergyDrug     AllergyCategory = "drug"
+	AllergyMaterial AllergyCategory = "material"
+	AllergyFood     AllergyCategory = "food"
+	AllergyOther    AllergyCategory = "other"
+)
+
+// IsValid reports whether the category is one of the known categories
+func (c AllergyCategory) IsValid() bool {
+	switch c {
+	case AllergyDrug, AllergyMaterial, AllergyFood, AllergyOther:
+		return true
+	}
+	return false
+}
+
+// Allergy is a known allergy of a patient, e.g. penicillin or latex
+type Allergy struct {
+	gorm.Model
+	ClinicID     uint            `json:"clinic_id"`
+	PatientID    uint            `json:"patient_id" gorm:"index"`
+	Allergen     string          `json:"allergen"`
+	Category     AllergyCategory `json:"category"`
+	Severity     Severity        `json:"severity"`
+	Reaction     string          `json:"reaction"`
+	Notes        string          `json:"notes"`
+	RecordedByID uint            `json:"recorded_by_id"`
+}
+
+func (Allergy) TableName() string {
+	return "patient_allergies"
+}
+
+// MedicationClass marks the drug groups that change how dental treatment is done
+type MedicationClass string
+
+const (
+	ClassAnticoagulant     MedicationClass = "anticoagulant"
+	ClassAntiplatelet      MedicationClass = "antiplatelet"
+	ClassBisphosphonate    MedicationClass = "bisphosphonate"
+	ClassImmunosuppressant MedicationClass = "immunosuppressant"
+	ClassInsulin           MedicationClass = "insulin"
+	ClassOther             MedicationClass = "other"
+)
+
+// IsValid reports whether the class is one of the known medication classes
+func (c MedicationClass) IsValid() bool {
+	switch c {
+	case ClassAnticoagulant, ClassAntiplatelet, ClassBisphosphonate, ClassImmunosuppressant, ClassInsulin, ClassOther:
+		return true
+	}
+	return false
+}
+
+// Medication is a drug the patient takes or used to take. Dates are YYYY-MM-DD;
+// a medication without an end date is still being taken.
+type Medication struct {
+	gorm.Model
+	ClinicID     uint            `json:"clinic_id"`
+	PatientID    uint            `json:"patient_id" gorm:"index"`
+	Name         string          `json:"name"`
+	Class        MedicationClass `json:"class"`
+	Dosage       string          `json:"dosage"`
+	Frequency    string          `json:"frequency"`
+	StartDate    string          `json:"start_date"`
+	EndDate      string          `json:"end_date"`
+	Notes        string          `json:"notes"`
+	RecordedByID uint            `json:"recorded_by_id"`
+}
+
+func (Medication) TableName() string {
+	return "patient_medications"
+}
+
+// CurrentOn reports whether the patient takes the medication on the given day (YYYY-MM-DD)
+func (m Medication) CurrentOn(date string) bool {
+	return (m.StartDate == "" || m.StartDate <= date) && (m.EndDate == "" || m.EndDate >= date)
+}
+
+// ConditionCode is a systemic condition relevant to dental treatment
+type ConditionCode string
+
+const (
+	ConditionDiabetes             ConditionCode = "diabetes"
+	ConditionHypertension         ConditionCode = "hypertension"
+	ConditionHeartDisease         ConditionCode = "heart_disease"
+	ConditionAnticoagulantTherapy ConditionCode = "anticoagulant_therapy"
+	ConditionBleedingDisorder     ConditionCode = "bleeding_disorder"
+	ConditionPregnancy            ConditionCode = "pregnancy"
+	ConditionAsthma               ConditionCode = "asthma"
+	ConditionEpilepsy             ConditionCode = "epilepsy"
+	ConditionImmunosuppression    ConditionCode = "immunosuppression"
+	ConditionHepatitis            ConditionCode = "hepatitis"
+	ConditionHIV                  ConditionCode = "hiv"
+	ConditionKidneyDisease        ConditionCode = "kidney_disease"
+	ConditionOther                ConditionCode = "other"
+)
+
+// IsValid reports whether the code is one of the known conditions
+func (c ConditionCode) IsValid() bool {
+	switch c {
+	case ConditionDiabetes, ConditionHypertension, ConditionHeartDisease, ConditionAnticoagulantTherapy,
+		ConditionBleedingDisorder, ConditionPregnancy, ConditionAsthma, ConditionEpilepsy,
+		ConditionImmunosuppression, ConditionHepatitis, ConditionHIV, ConditionKidneyDisease, ConditionOther:
+		return true
+	}
+	return false
+}
+
+// Condition is a systemic condition of the patient. Dates are YYYY-MM-DD;
+// a condition stays active until it is resolved.
+type Condition struct {
+	gorm.Model
+	ClinicID  uint          `json:"clinic_id"`
+	PatientID uint          `json:"patient_id" gorm:"index"`
+	Code      ConditionCode `json:"code"`
+	// Name describes the condition; required for ConditionOther
+	Name         string `json:"name"`
+	DiagnosedOn  string `json:"diagnosed_on"`
+	ResolvedOn   string `json:"resolved_on"`
+	Notes        string `json:"notes"`
+	RecordedByID uint   `json:"recorded_by_id"`
+}
+
+func (Condition) TableName() string {
+	return "patient_conditions"
+}
+
+// ActiveOn reports whether the patient has the condition on the given day (YYYY-MM-DD)
+func (c Condition) ActiveOn(date string) bool {
+	return c.ResolvedOn == "" || c.ResolvedOn > date
+}
+
+// Record is the structured medical history of a patient together with the alerts derived from it
+type Record struct {
+	PatientID   uint         `json:"patient_id"`
+	Allergies   []Allergy    `json:"allergies"`
+	Medications []Medication `json:"medications"`
+	Conditions  []Condition  `json:"conditions"`
+	// Questionnaire is the most recent medical questionnaire, nil if the patient never filled one in
+	Questionnaire *Questionnaire `json:"questionnaire"`
+	Alerts        []Alert        `json:"alerts"`
+}
+
+// Error types
+var (
+	ErrAllergyNotFound       = errors.New("allergy not found")
+	ErrMedicationNotFound    = errors.New("medication not found")
+	ErrConditionNotFound     = errors.New("condition not found")
+	ErrQuestionnaireNotFound = errors.New("medical questionnaire not found")
+	ErrMedicalValidation     = errors.New("invalid medical record")
+)
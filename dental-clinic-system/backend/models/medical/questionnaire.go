@@ -0,0 +1,70 @@
+package medical
+
+import (
+	"gorm.io/gorm"
+)
+
+// QuestionnaireValidityMonths is how long a questionnaire is trusted before the patient is asked to fill in a new one
+const QuestionnaireValidityMonths = 12
+
+// Question is one item of the medical questionnaire. A "yes" answer raises an alert of the given level.
+type Question struct {
+	Key   string     `json:"key"`
+	Text  string     `json:"text"`
+	Level AlertLevel `json:"level"`
+}
+
+// Questions is the medical questionnaire asked before treatment, in the order it is presented
+var Questions = []Question{
+	{Key: "bleeding_problems", Text: "Do you bleed for a long time after a cut or a tooth extraction?", Level: AlertCritical},
+	{Key: "blood_thinners", Text: "Do you take blood thinners such as warfarin or aspirin?", Level: AlertCritical},
+	{Key: "anaesthesia_reaction", Text: "Have you ever reacted badly to a local anaesthetic?", Level: AlertCritical},
+	{Key: "pregnant", Text: "Are you pregnant or could you be pregnant?", Level: AlertCritical},
+	{Key: "heart_problems", Text: "Do you have a heart condition, a pacemaker or an artificial heart valve?", Level: AlertCritical},
+	{Key: "bisphosphonates", Text: "Have you ever taken bisphosphonates for osteoporosis or cancer?", Level: AlertWarning},
+	{Key: "diabetes", Text: "Do you have diabetes?", Level: AlertWarning},
+	{Key: "high_blood_pressure", Text: "Do you have high blood pressure?", Level: AlertWarning},
+	{Key: "fainting", Text: "Have you fainted or had a seizure in the last year?", Level: AlertWarning},
+	{Key: "infectious_disease", Text: "Do you have hepatitis, HIV or another infectious disease?", Level: AlertWarning},
+	{Key: "recent_hospital_stay", Text: "Have you been in hospital or had surgery in the last year?", Level: AlertInfo},
+	{Key: "smoking", Text: "Do you smoke?", Level: AlertInfo},
+}
+
+// QuestionByKey looks up a question of the questionnaire
+func QuestionByKey(key string) (Question, bool) {
+	for _, question := range Questions {
+		if question.Key == key {
+			return question, true
+		}
+	}
+	return Question{}, false
+}
+
+// Questionnaire is a medical questionnaire filled in by the patient on a given day (YYYY-MM-DD).
+// Questionnaires are never edited; a change in the patient's health is recorded as a new questionnaire.
+type Questionnaire struct {
+	gorm.Model
+	ClinicID     uint                  `json:"clinic_id"`
+	PatientID    uint                  `json:"patient_id" gorm:"index:idx_medical_questionnaire_patient_date,priority:1"`
+	AnsweredOn   string                `json:"answered_on" gorm:"index:idx_medical_questionnaire_patient_date,priority:2"`
+	Notes        string                `json:"notes"`
+	RecordedByID uint                  `json:"recorded_by_id"`
+	Answers      []QuestionnaireAnswer `json:"answers" gorm:"foreignKey:QuestionnaireID"`
+}
+
+func (Questionnaire) TableName() string {
+	return "medical_questionnaires"
+}
+
+// QuestionnaireAnswer is the patient's answer to one question
+type QuestionnaireAnswer struct {
+	gorm.Model
+	QuestionnaireID uint   `json:"questionnaire_id" gorm:"index"`
+	Question        string `json:"question"`
+	Answer          bool   `json:"answer"`
+	Details         string `json:"details"`
+}
+
+func (QuestionnaireAnswer) TableName() string {
+	return "medical_questionnaire_answers"
+}
@@ -0,0 +1,127 @@
+package odontogram
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Condition is a tooth-level finding recorded on the chart
+type Condition string
+
+const (
+	ConditionCaries    Condition = "caries"
+	ConditionFilling   Condition = "filling"
+	ConditionCrown     Condition = "crown"
+	ConditionImplant   Condition = "implant"
+	ConditionMissing   Condition = "missing"
+	ConditionRootCanal Condition = "root_canal"
+)
+
+// IsValid reports whether the condition is one of the known conditions
+func (c Condition) IsValid() bool {
+	switch c {
+	case ConditionCaries, ConditionFilling, ConditionCrown, ConditionImplant, ConditionMissing, ConditionRootCanal:
+		return true
+	}
+	return false
+}
+
+// HasSurfaces reports whether the condition is charted on individual surfaces rather than the whole tooth
+func (c Condition) HasSurfaces() bool {
+	return c == ConditionCaries || c == ConditionFilling
+}
+
+// Finding is a condition charted on a tooth. Findings are never edited: a change to the chart
+// removes the outdated finding and records a new one, so every past state of the chart can be rebuilt.
+type Finding struct {
+	gorm.Model
+	ClinicID      uint       `json:"clinic_id"`
+	PatientID     uint       `json:"patient_id" gorm:"index:idx_odontogram_finding_patient_tooth,priority:1"`
+	Tooth         int        `json:"tooth" gorm:"index:idx_odontogram_finding_patient_tooth,priority:2"`
+	Condition     Condition  `json:"condition"`
+	Surfaces      string     `json:"surfaces"`
+	Notes         string     `json:"notes"`
+	AppointmentID *uint      `json:"appointment_id"`
+	RecordedAt    time.Time  `json:"recorded_at"`
+	RecordedByID  uint       `json:"recorded_by_id"`
+	RemovedAt     *time.Time `json:"removed_at"`
+	RemovedByID   *uint      `json:"removed_by_id"`
+	RemovalReason string     `json:"removal_reason"`
+	// SupersededByID points to the finding that replaced this one, e.g. the filling that treated a caries
+	SupersededByID *uint `json:"superseded_by_id"`
+	// Supersedes lists findings to remove when this one is recorded; only used in requests
+	Supersedes []uint `json:"supersedes,omitempty" gorm:"-"`
+}
+
+func (Finding) TableName() string {
+	return "odontogram_findings"
+}
+
+// ActiveAt reports whether the finding was on the chart at the given time
+func (f Finding) ActiveAt(at time.Time) bool {
+	return !f.RecordedAt.After(at) && (f.RemovedAt == nil || f.RemovedAt.After(at))
+}
+
+// Overlaps reports whether two findings describe the same condition on the same part of the same tooth
+func (f Finding) Overlaps(other Finding) bool {
+	if f.Tooth != other.Tooth || f.Condition != other.Condition {
+		return false
+	}
+	if !f.Condition.HasSurfaces() {
+		return true
+	}
+	for _, surface := range f.Surfaces {
+		for _, otherSurface := range other.Surfaces {
+			if surface == otherSurface {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ToothChart is the state of a single tooth
+type ToothChart struct {
+	Tooth     int       `json:"tooth"`
+	Dentition Dentition `json:"dentition"`
+	Findings  []Finding `json:"findings"`
+}
+
+// Chart is the odontogram of a patient at a point in time. Teeth without findings are omitted.
+type Chart struct {
+	PatientID uint         `json:"patient_id"`
+	AsOf      time.Time    `json:"as_of"`
+	Teeth     []ToothChart `json:"teeth"`
+}
+
+// BuildChart groups the findings active at the given time by tooth, in FDI order
+func BuildChart(patientID uint, at time.Time, findings []Finding) Chart {
+	byTooth := map[int][]Finding{}
+	for _, finding := range findings {
+		if finding.ActiveAt(at) {
+			byTooth[finding.Tooth] = append(byTooth[finding.Tooth], finding)
+		}
+	}
+
+	chart := Chart{PatientID: patientID, AsOf: at, Teeth: []ToothChart{}}
+	for tooth, toothFindings := range byTooth {
+		dentition, _ := ToothDentition(tooth)
+		sort.Slice(toothFindings, func(i, j int) bool {
+			return toothFindings[i].RecordedAt.Before(toothFindings[j].RecordedAt)
+		})
+		chart.Teeth = append(chart.Teeth, ToothChart{Tooth: tooth, Dentition: dentition, Findings: toothFindings})
+	}
+	sort.Slice(chart.Teeth, func(i, j int) bool { return chart.Teeth[i].Tooth < chart.Teeth[j].Tooth })
+	return chart
+}
+
+// Error types
+var (
+	ErrFindingNotFound     = errors.New("odontogram finding not found")
+	ErrFindingValidation   = errors.New("invalid odontogram finding")
+	ErrFindingConflict     = errors.New("tooth already has this finding")
+	ErrFindingNotRemovable = errors.New("odontogram finding was already removed")
+)
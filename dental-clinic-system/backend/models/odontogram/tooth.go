@@ -0,0 +1,77 @@
+package odontogram
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dentition tells permanent and primary (deciduous) teeth apart
+type Dentition string
+
+const (
+	DentitionPermanent Dentition = "permanent"
+	DentitionPrimary   Dentition = "primary"
+)
+
+// Surface is a tooth surface in the usual charting abbreviation
+type Surface string
+
+const (
+	SurfaceMesial   Surface = "M"
+	SurfaceOcclusal Surface = "O"
+	SurfaceIncisal  Surface = "I"
+	SurfaceDistal   Surface = "D"
+	SurfaceBuccal   Surface = "B"
+	SurfaceLingual  Surface = "L"
+)
+
+// surfaceOrder is the order surfaces are written in, e.g. "MOD"
+var surfaceOrder = []Surface{SurfaceMesial, SurfaceOcclusal, SurfaceIncisal, SurfaceDistal, SurfaceBuccal, SurfaceLingual}
+
+// ToothDentition returns the dentition of a tooth in FDI two-digit notation.
+// Quadrants 1-4 hold the permanent teeth 1-8, quadrants 5-8 the primary teeth 1-5.
+func ToothDentition(tooth int) (Dentition, error) {
+	quadrant, position := tooth/10, tooth%10
+	switch {
+	case quadrant >= 1 && quadrant <= 4 && position >= 1 && position <= 8:
+		return DentitionPermanent, nil
+	case quadrant >= 5 && quadrant <= 8 && position >= 1 && position <= 5:
+		return DentitionPrimary, nil
+	}
+	return "", fmt.Errorf("%d is not an FDI tooth number", tooth)
+}
+
+// IsAnterior reports whether the tooth is an incisor or canine, which have an incisal edge instead of an occlusal surface
+func IsAnterior(tooth int) bool {
+	return tooth%10 <= 3
+}
+
+// ParseSurfaces reads a surface list such as "MOD" and returns it in canonical order without duplicates
+func ParseSurfaces(tooth int, value string) (string, error) {
+	present := map[Surface]bool{}
+	for _, r := range strings.ToUpper(strings.TrimSpace(value)) {
+		surface := Surface(string(r))
+		switch surface {
+		case SurfaceMesial, SurfaceDistal, SurfaceBuccal, SurfaceLingual:
+		case SurfaceOcclusal:
+			if IsAnterior(tooth) {
+				return "", fmt.Errorf("tooth %d has no occlusal surface, use I", tooth)
+			}
+		case SurfaceIncisal:
+			if !IsAnterior(tooth) {
+				return "", fmt.Errorf("tooth %d has no incisal edge, use O", tooth)
+			}
+		default:
+			return "", fmt.Errorf("unknown tooth surface %q", string(r))
+		}
+		present[surface] = true
+	}
+
+	var canonical strings.Builder
+	for _, surface := range surfaceOrder {
+		if present[surface] {
+			canonical.WriteString(string(surface))
+		}
+	}
+	return canonical.String(), nil
+}
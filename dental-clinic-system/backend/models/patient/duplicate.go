@@ -0,0 +1,114 @@
+package patient
+
+import (
+	"math"
+	"strings"
+)
+
+// Duplicate scoring weights; they add up to 1. National IDs are unique, so two patients can only
+// have IDs that look alike, such as one with a typing mistake.
+const (
+	NameWeight              = 0.4
+	PhoneWeight             = 0.3
+	BirthDateWeight         = 0.2
+	SimilarNationalIDWeight = 0.1
+)
+
+// DefaultDuplicateScore is the lowest score reported as a likely duplicate unless asked otherwise
+const DefaultDuplicateScore = 0.6
+
+// Reasons a pair of patients looks like a duplicate
+const (
+	ReasonSimilarNationalID = "similar_national_id"
+	ReasonPhone             = "phone"
+	ReasonBirthDate         = "birth_date"
+	ReasonName              = "name"
+)
+
+// Duplicate is a pair of patients that may be the same person, scored between 0 and 1
+type Duplicate struct {
+	First   Patient  `json:"first"`
+	Second  Patient  `json:"second"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// ScoreDuplicate scores how likely two patients are the same person from the likeness of their national IDs,
+// phone numbers, birth dates and the trigram similarity of their names
+func ScoreDuplicate(a, b Patient) Duplicate {
+	a.SetSearchFields()
+	b.SetSearchFields()
+	d := Duplicate{First: a, Second: b, Reasons: []string{}}
+
+	if similarNationalIDs(a.NationalID, b.NationalID) {
+		d.Score += SimilarNationalIDWeight
+		d.Reasons = append(d.Reasons, ReasonSimilarNationalID)
+	}
+	if a.SearchPhone != "" && a.SearchPhone == b.SearchPhone {
+		d.Score += PhoneWeight
+		d.Reasons = append(d.Reasons, ReasonPhone)
+	}
+	if a.SearchBirthDate != "" && a.SearchBirthDate == b.SearchBirthDate {
+		d.Score += BirthDateWeight
+		d.Reasons = append(d.Reasons, ReasonBirthDate)
+	}
+	if similarity := NameSimilarity(a.Name, b.Name); similarity > 0 {
+		d.Score += NameWeight * similarity
+		if similarity >= 0.5 {
+			d.Reasons = append(d.Reasons, ReasonName)
+		}
+	}
+
+	d.Score = math.Round(d.Score*1000) / 1000
+	return d
+}
+
+// NameSimilarity compares two names by the trigrams of their folded words, as pg_trgm does:
+// the number of trigrams they share divided by the number of distinct trigrams in either
+func NameSimilarity(a, b string) float64 {
+	ta, tb := trigrams(Fold(a)), trigrams(Fold(b))
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams returns the trigrams of every word, padded with two spaces in front and one behind
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(s) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// similarNationalIDs reports whether two national IDs of the same length differ in one digit
+// or by two swapped neighbouring digits, the usual typing mistakes
+func similarNationalIDs(a, b string) bool {
+	if a == "" || len(a) != len(b) || a == b {
+		return false
+	}
+	var diff []int
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			diff = append(diff, i)
+			if len(diff) > 2 {
+				return false
+			}
+		}
+	}
+	if len(diff) == 1 {
+		return true
+	}
+	i, j := diff[0], diff[1]
+	return j == i+1 && a[i] == b[j] && a[j] == b[i]
+}
@@ -0,0 +1,75 @@
+package patient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MergeUndoWindow is how long a merge can be undone after it was made
+const MergeUndoWindow = 30 * 24 * time.Hour
+
+// Merge records that a duplicate patient was merged into the surviving one. The duplicate is soft
+// deleted and its records moved to the survivor; the merge redirects its ID to the survivor and
+// remembers every moved record so it can be undone within MergeUndoWindow.
+type Merge struct {
+	gorm.Model
+	ClinicID   uint `json:"clinic_id" gorm:"index"`
+	SurvivorID uint `json:"survivor_id" gorm:"index"`
+	// MergedID is the duplicate that was merged away
+	MergedID   uint       `json:"merged_id" gorm:"index"`
+	MergedByID uint       `json:"merged_by_id"`
+	UndoneAt   *time.Time `json:"undone_at"`
+	UndoneByID *uint      `json:"undone_by_id"`
+	// Moves are the records that were moved from the duplicate to the survivor
+	Moves []MergeMove `json:"moves,omitempty" gorm:"foreignKey:MergeID;constraint:OnDelete:CASCADE"`
+}
+
+func (Merge) TableName() string {
+	return "patient_merges"
+}
+
+// MergeMove is a record that was moved to the survivor, by table and ID
+type MergeMove struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	MergeID     uint   `json:"merge_id" gorm:"index"`
+	RecordTable string `json:"record_table"`
+	RecordID    uint   `json:"record_id"`
+}
+
+func (MergeMove) TableName() string {
+	return "patient_merge_moves"
+}
+
+// MergeRequest names the patient that is kept and the duplicate merged into it
+type MergeRequest struct {
+	SurvivorID uint `json:"survivor_id"`
+	MergedID   uint `json:"merged_id"`
+}
+
+// Redirect tells where a patient's records are now
+type Redirect struct {
+	PatientID uint `json:"patient_id"`
+	// CurrentID is the patient itself, or the survivor it was merged into
+	CurrentID uint   `json:"current_id"`
+	Merged    bool   `json:"merged"`
+	MergeIDs  []uint `json:"merge_ids,omitempty"`
+}
+
+// UndoableUntil is the last moment the merge can be undone
+func (m Merge) UndoableUntil() time.Time {
+	return m.CreatedAt.Add(MergeUndoWindow)
+}
+
+// Error types
+var (
+	ErrPatientNotFound = errors.New("patient not found")
+	ErrMergeNotFound   = errors.New("patient merge not found")
+	ErrMergeValidation = errors.New("invalid patient merge")
+	ErrMergeUndone     = errors.New("patient merge has already been undone")
+	ErrMergeExpired    = fmt.Errorf("patient merges can only be undone within %d days", int(MergeUndoWindow.Hours()/24))
+	// ErrMergeSuperseded is returned when records moved by a merge have since been merged again
+	ErrMergeSuperseded = errors.New("the surviving patient has since been merged into another patient; undo that merge first")
+)
@@ -2,17 +2,32 @@ package patient
 
 import (
 	"dental-clinic-system/models/clinic"
+	"net/mail"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
 type Patient struct {
 	gorm.Model
-	NationalID     string        `json:"national_id" gorm:"uniqueIndex"`
-	Name           string        `json:"name"`
-	BirthDate      string        `json:"birth_date"`
-	ContactInfo    string        `json:"contact_info"`
-	MedicalHistory string        `json:"medical_history"`
-	ClinicID       uint          `json:"clinic_id"`
-	Clinic         clinic.Clinic `gorm:"foreignKey:ClinicID"`
+	// A national ID is unique among the clinic's patients that are not deleted
+	NationalID  string        `json:"national_id" gorm:"uniqueIndex:idx_patients_national_id_clinic,where:deleted_at IS NULL"`
+	Name        string        `json:"name"`
+	BirthDate   string        `json:"birth_date"`
+	ContactInfo string        `json:"contact_info"`
+	ClinicID    uint          `json:"clinic_id" gorm:"uniqueIndex:idx_patients_national_id_clinic"`
+	Clinic      clinic.Clinic `gorm:"foreignKey:ClinicID"`
+	// The search columns hold the name, phone number and birth date as they are searched by; see SetSearchFields
+	SearchName      string `json:"-"`
+	SearchPhone     string `json:"-"`
+	SearchBirthDate string `json:"-" gorm:"index"`
+}
+
+// Email returns the patient's contact info when it is an email address; it may also hold a phone number
+func (p Patient) Email() string {
+	contactInfo := strings.TrimSpace(p.ContactInfo)
+	if addr, err := mail.ParseAddress(contactInfo); err == nil && addr.Address == contactInfo {
+		return contactInfo
+	}
+	return ""
 }
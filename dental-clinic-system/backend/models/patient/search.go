@@ -0,0 +1,164 @@
+package patient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search page sizes
+const (
+	DefaultSearchPageSize = 20
+	MaxSearchPageSize     = 100
+)
+
+// MinSearchLength is the shortest query searched for, after folding
+const MinSearchLength = 2
+
+// minPhoneDigits is the shortest run of digits matched against phone numbers
+const minPhoneDigits = 4
+
+// ErrInvalidSearch is returned for unusable search parameters
+var ErrInvalidSearch = errors.New("invalid patient search")
+
+// SearchQuery selects a page of the patients of a clinic matching a free-text query
+type SearchQuery struct {
+	ClinicID uint
+	Query    string
+	Page     int
+	PageSize int
+}
+
+// SearchTerms are the parts of a query matched against the search columns of patients
+type SearchTerms struct {
+	// Name is the folded query, matched against the folded patient name
+	Name string
+	// Words are the words of Name, matched as prefixes by full-text search
+	Words []string
+	// NationalID is set when the query is all digits and is matched as a prefix of the national ID
+	NationalID string
+	// Phone is set when the query holds enough digits to be part of a phone number
+	Phone string
+	// BirthDate is set when the query is a date, as YYYY-MM-DD
+	BirthDate string
+}
+
+// SearchResult is a matching patient and how well it matched, between 0 and 1
+type SearchResult struct {
+	Patient
+	Rank float64 `json:"rank"`
+}
+
+// SearchPage is one page of search results, best matches first
+type SearchPage struct {
+	Patients   []SearchResult `json:"patients"`
+	TotalCount int64          `json:"total_count"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"page_size"`
+}
+
+// Normalize applies the defaults and validates the query
+func (q *SearchQuery) Normalize() error {
+	q.Query = strings.TrimSpace(q.Query)
+	if len([]rune(Fold(q.Query))) < MinSearchLength {
+		return fmt.Errorf("%w: the query must be at least %d characters", ErrInvalidSearch, MinSearchLength)
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = DefaultSearchPageSize
+	}
+	if q.PageSize > MaxSearchPageSize {
+		return fmt.Errorf("%w: page_size can not exceed %d", ErrInvalidSearch, MaxSearchPageSize)
+	}
+	return nil
+}
+
+// Offset is the number of results before the query's page
+func (q SearchQuery) Offset() int {
+	return (q.Page - 1) * q.PageSize
+}
+
+// Terms splits the query into what is matched against names, national IDs, phone numbers and birth dates
+func (q SearchQuery) Terms() SearchTerms {
+	terms := SearchTerms{Name: Fold(q.Query)}
+	terms.Words = strings.Fields(terms.Name)
+
+	if date, ok := NormalizeDate(q.Query); ok {
+		terms.BirthDate = date
+	}
+
+	compact := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", "+", "").Replace(q.Query)
+	if compact != "" && strings.Trim(compact, "0123456789") == "" {
+		terms.NationalID = compact
+		if phone := NormalizePhone(compact); len(phone) >= minPhoneDigits {
+			terms.Phone = phone
+		}
+	}
+	return terms
+}
+
+// SetSearchFields fills the columns patients are searched by from their name, contact info and birth date
+func (p *Patient) SetSearchFields() {
+	p.SearchName = Fold(p.Name)
+	p.SearchPhone = ""
+	if !strings.Contains(p.ContactInfo, "@") {
+		p.SearchPhone = NormalizePhone(p.ContactInfo)
+	}
+	p.SearchBirthDate, _ = NormalizeDate(p.BirthDate)
+}
+
+// turkishFolding maps the letters of the Turkish alphabet, and the circumflexed vowels of loanwords,
+// to the ASCII letters they are typed as on keyboards without them
+var turkishFolding = strings.NewReplacer(
+	"ç", "c", "ğ", "g", "ı", "i", "ö", "o", "ş", "s", "ü", "u",
+	"â", "a", "î", "i", "û", "u",
+)
+
+// Fold lower-cases text with Turkish rules and folds away diacritics, so "Şükrü Öztürk", "ŞÜKRÜ ÖZTÜRK"
+// and "sukru ozturk" are the same. Punctuation becomes a space and runs of spaces are collapsed.
+func Fold(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, strings.ToLowerSpecial(unicode.TurkishCase, s))
+	s = turkishFolding.Replace(s)
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// NormalizePhone returns the national number of a Turkish phone number: its digits without
+// the country code or trunk prefix, e.g. "5321234567" for "+90 (532) 123 45 67"
+func NormalizePhone(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	if len(digits) == 12 && strings.HasPrefix(digits, "90") {
+		digits = digits[2:]
+	}
+	return strings.TrimLeft(digits, "0")
+}
+
+// dateLayouts are the ways birth dates are written
+var dateLayouts = []string{"2006-01-02", "02.01.2006", "2.1.2006", "02/01/2006", "2/1/2006", "02-01-2006"}
+
+// NormalizeDate reads a date written as YYYY-MM-DD or day first as DD.MM.YYYY, DD/MM/YYYY or DD-MM-YYYY
+// and returns it as YYYY-MM-DD
+func NormalizeDate(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
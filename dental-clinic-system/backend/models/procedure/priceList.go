@@ -0,0 +1,77 @@
+package procedure
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency is used for price lists unless another currency is given
+const DefaultCurrency = "TRY"
+
+// DateLayout is the layout of price list effective dates
+const DateLayout = "2006-01-02"
+
+// PriceList is one version of a clinic's fee schedule. It applies from EffectiveFrom until the
+// next list of the clinic takes effect, so prices charged in the past can always be looked up again.
+// Prices are net of VAT, in minor currency units (kuruş for TRY).
+type PriceList struct {
+	gorm.Model
+	ClinicID      uint             `json:"clinic_id" gorm:"uniqueIndex:idx_price_lists_clinic_effective,where:deleted_at IS NULL"`
+	Name          string           `json:"name"`
+	Currency      string           `json:"currency" gorm:"default:TRY"`
+	EffectiveFrom string           `json:"effective_from" gorm:"uniqueIndex:idx_price_lists_clinic_effective"`
+	CreatedByID   uint             `json:"created_by_id"`
+	Entries       []PriceListEntry `json:"entries" gorm:"foreignKey:PriceListID;constraint:OnDelete:CASCADE"`
+}
+
+func (PriceList) TableName() string {
+	return "price_lists"
+}
+
+// PriceListEntry is the price of one procedure in a price list.
+// The procedure code is copied so the list still reads correctly if the procedure is recoded.
+type PriceListEntry struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	PriceListID uint   `json:"price_list_id" gorm:"uniqueIndex:idx_price_list_entries_list_procedure"`
+	ProcedureID uint   `json:"procedure_id" gorm:"uniqueIndex:idx_price_list_entries_list_procedure;index"`
+	Code        string `json:"code"`
+	Price       int64  `json:"price"`
+}
+
+func (PriceListEntry) TableName() string {
+	return "price_list_entries"
+}
+
+// Price is the price of a procedure on a given date, as resolved from the clinic's price lists
+type Price struct {
+	ProcedureID   uint   `json:"procedure_id"`
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	Date          string `json:"date"`
+	PriceListID   uint   `json:"price_list_id"`
+	EffectiveFrom string `json:"effective_from"`
+	Currency      string `json:"currency"`
+	NetPrice      int64  `json:"net_price"`
+	VATRate       int    `json:"vat_rate"`
+	VATAmount     int64  `json:"vat_amount"`
+	GrossPrice    int64  `json:"gross_price"`
+}
+
+// VATAmount returns the VAT on a net amount at a whole-percent rate, rounded half up to the minor unit
+func VATAmount(net int64, rate int) int64 {
+	product := net * int64(rate)
+	if product >= 0 {
+		return (product + 50) / 100
+	}
+	return -((-product + 50) / 100)
+}
+
+// Error types
+var (
+	ErrPriceListNotFound   = errors.New("price list not found")
+	ErrPriceListValidation = errors.New("invalid price list")
+	ErrPriceListConflict   = errors.New("a price list already takes effect on this date")
+	ErrPriceListInEffect   = errors.New("price lists that have taken effect can not be changed")
+	ErrPriceNotFound       = errors.New("procedure has no price on this date")
+)
@@ -2,14 +2,26 @@ package procedure
 
 import (
 	"dental-clinic-system/models/clinic"
+	"errors"
 
 	"gorm.io/gorm"
 )
 
+// Procedure is an entry of a clinic's fee schedule.
+// Code is the clinic's billing code, e.g. a CDT code or the Turkish Dental Association fee list code.
 type Procedure struct {
 	gorm.Model
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	ClinicID    uint          `json:"clinic_id"`
-	Clinic      clinic.Clinic `gorm:"foreignKey:ClinicID"`
+	Code                   string        `json:"code" gorm:"index:idx_procedures_clinic_code,unique,priority:2,where:code <> '' AND deleted_at IS NULL"`
+	Name                   string        `json:"name"`
+	Description            string        `json:"description"`
+	DefaultDurationMinutes int           `json:"default_duration_minutes"`
+	VATRate                int           `json:"vat_rate"`
+	ClinicID               uint          `json:"clinic_id" gorm:"index:idx_procedures_clinic_code,priority:1"`
+	Clinic                 clinic.Clinic `gorm:"foreignKey:ClinicID"`
 }
+
+// Error types
+var (
+	ErrProcedureNotFound   = errors.New("procedure not found")
+	ErrProcedureValidation = errors.New("invalid procedure")
+)
@@ -0,0 +1,127 @@
+package radiograph
+
+import (
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Modalities produced by dental imaging devices
+const (
+	ModalityIntraoral    = "IO"
+	ModalityPanoramic    = "PX"
+	ModalityDigital      = "DX"
+	ModalityCT           = "CT"
+	ModalityPhotograph   = "XC"
+	ModalityCephalometry = "RG"
+)
+
+// ModalityLabels names the modalities dental clinics commonly see
+var ModalityLabels = map[string]string{
+	ModalityIntraoral:    "Intraoral radiograph",
+	ModalityPanoramic:    "Panoramic radiograph",
+	ModalityDigital:      "Digital radiograph",
+	ModalityCT:           "Cone beam CT",
+	ModalityPhotograph:   "Clinical photograph",
+	ModalityCephalometry: "Cephalometric radiograph",
+}
+
+// Image is a DICOM file ingested for a patient. The file itself is kept as an X-ray attachment;
+// the image indexes its header so studies can be listed without reading the files.
+type Image struct {
+	gorm.Model
+	ClinicID     uint `json:"clinic_id" gorm:"uniqueIndex:idx_radiograph_images_clinic_sop,priority:1"`
+	PatientID    uint `json:"patient_id" gorm:"index:idx_radiograph_images_patient_date,priority:1"`
+	AttachmentID uint `json:"attachment_id" gorm:"uniqueIndex"`
+	UploadedByID uint `json:"uploaded_by_id"`
+	// StudyDate is the date the study was taken, or the upload date when the file has none
+	StudyDate         string `json:"study_date" gorm:"index:idx_radiograph_images_patient_date,priority:2"`
+	Modality          string `json:"modality" gorm:"index"`
+	StudyInstanceUID  string `json:"study_instance_uid" gorm:"index"`
+	SeriesInstanceUID string `json:"series_instance_uid"`
+	SOPInstanceUID    string `json:"sop_instance_uid" gorm:"uniqueIndex:idx_radiograph_images_clinic_sop,priority:2"`
+	Description       string `json:"description"`
+	BodyPart          string `json:"body_part"`
+	// Region is the anatomic region named in the file, e.g. "Maxilla"
+	Region string `json:"region"`
+	// Teeth are the FDI numbers of the imaged teeth, comma separated
+	Teeth            string `json:"teeth"`
+	DicomPatientID   string `json:"dicom_patient_id"`
+	DicomPatientName string `json:"dicom_patient_name"`
+	Rows             int    `json:"rows"`
+	Columns          int    `json:"columns"`
+	// Preview is a downscaled PNG of the image; it is served through its own endpoint and never serialised
+	Preview    []byte `json:"-"`
+	HasPreview bool   `json:"has_preview"`
+}
+
+func (Image) TableName() string {
+	return "radiograph_images"
+}
+
+// Study groups the images taken together, e.g. the films of a full mouth series
+type Study struct {
+	StudyInstanceUID string   `json:"study_instance_uid"`
+	StudyDate        string   `json:"study_date"`
+	Modalities       []string `json:"modalities"`
+	Description      string   `json:"description"`
+	Images           []Image  `json:"images"`
+}
+
+// Filter narrows a patient's radiograph timeline. Dates use schedule.DateLayout and are inclusive.
+type Filter struct {
+	Modality string
+	From     string
+	To       string
+}
+
+// Timeline groups images into studies, newest study first. Images without a study UID form a study of their own.
+func Timeline(images []Image) []Study {
+	var studies []Study
+	index := map[string]int{}
+	for _, image := range images {
+		key := image.StudyInstanceUID
+		i, ok := index[key]
+		if !ok || key == "" {
+			studies = append(studies, Study{
+				StudyInstanceUID: image.StudyInstanceUID,
+				StudyDate:        image.StudyDate,
+				Description:      image.Description,
+			})
+			i = len(studies) - 1
+			index[key] = i
+		}
+
+		study := &studies[i]
+		study.Images = append(study.Images, image)
+		if image.StudyDate > study.StudyDate {
+			study.StudyDate = image.StudyDate
+		}
+		if !contains(study.Modalities, image.Modality) && image.Modality != "" {
+			study.Modalities = append(study.Modalities, image.Modality)
+		}
+	}
+
+	sort.SliceStable(studies, func(i, j int) bool { return studies[i].StudyDate > studies[j].StudyDate })
+	return studies
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Error types
+var (
+	ErrImageNotFound        = errors.New("radiograph not found")
+	ErrInvalidDicom         = errors.New("invalid DICOM file")
+	ErrPatientNotMatched    = errors.New("no patient of the clinic matches the DICOM patient ID")
+	ErrPatientMismatch      = errors.New("the DICOM patient ID does not match the patient's national ID")
+	ErrDuplicateImage       = errors.New("this DICOM image has already been ingested")
+	ErrRadiographValidation = errors.New("invalid radiograph query")
+)
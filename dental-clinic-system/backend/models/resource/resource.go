@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"dental-clinic-system/models/clinic"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Type is the kind of a bookable resource
+type Type string
+
+const (
+	TypeChair     Type = "chair"
+	TypeRoom      Type = "room"
+	TypeEquipment Type = "equipment"
+)
+
+// IsValid reports whether the type is one of the known resource types
+func (t Type) IsValid() bool {
+	switch t {
+	case TypeChair, TypeRoom, TypeEquipment:
+		return true
+	}
+	return false
+}
+
+// Resource is a chair, room or piece of equipment of a clinic that appointments can reserve
+type Resource struct {
+	gorm.Model
+	ClinicID    uint          `json:"clinic_id" gorm:"index"`
+	Clinic      clinic.Clinic `json:"-" gorm:"foreignKey:ClinicID"`
+	Name        string        `json:"name"`
+	Type        Type          `json:"type" gorm:"index"`
+	Active      bool          `json:"active"`
+	Description string        `json:"description"`
+}
+
+// Booking is a time range in which a resource is reserved by an appointment
+type Booking struct {
+	AppointmentID uint      `json:"appointment_id"`
+	DoctorID      uint      `json:"doctor_id"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+}
+
+// Utilisation summarises how much of a day's opening time a resource was booked
+type Utilisation struct {
+	ResourceID    uint      `json:"resource_id"`
+	Name          string    `json:"name"`
+	Type          Type      `json:"type"`
+	Date          string    `json:"date"`
+	OpenMinutes   int       `json:"open_minutes"`
+	BookedMinutes int       `json:"booked_minutes"`
+	Utilisation   float64   `json:"utilisation"`
+	Bookings      []Booking `json:"bookings"`
+}
+
+// Error types
+var (
+	ErrResourceNotFound   = errors.New("resource not found")
+	ErrResourceValidation = errors.New("invalid resource")
+	ErrResourceInactive   = errors.New("resource is not active")
+)
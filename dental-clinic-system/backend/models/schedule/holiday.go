@@ -0,0 +1,61 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+)
+
+// fixedPublicHolidays are the holidays on the same day of every year
+var fixedPublicHolidays = []struct {
+	monthDay string
+	name     string
+}{
+	{"01-01", "Yılbaşı"},
+	{"04-23", "Ulusal Egemenlik ve Çocuk Bayramı"},
+	{"05-01", "Emek ve Dayanışma Günü"},
+	{"05-19", "Atatürk'ü Anma, Gençlik ve Spor Bayramı"},
+	{"07-15", "Demokrasi ve Milli Birlik Günü"},
+	{"08-30", "Zafer Bayramı"},
+	{"10-29", "Cumhuriyet Bayramı"},
+}
+
+// FixedPublicHolidays returns the holidays of a year that fall on the same date every year,
+// including the half-day eve of Republic Day. Religious holidays follow the lunar calendar
+// and are stored in the database instead.
+func FixedPublicHolidays(year int) []PublicHoliday {
+	var holidays []PublicHoliday
+	for _, holiday := range fixedPublicHolidays {
+		holidays = append(holidays, PublicHoliday{Date: fmt.Sprintf("%04d-%s", year, holiday.monthDay), Name: holiday.name})
+	}
+	return append(holidays, PublicHoliday{
+		Date:        fmt.Sprintf("%04d-10-28", year),
+		Name:        "Cumhuriyet Bayramı Arifesi",
+		HalfDay:     true,
+		HalfDayFrom: "13:00",
+	})
+}
+
+// MergePublicHolidays combines holiday lists into one sorted by date with a single holiday per day.
+// When holidays fall on the same day a whole-day holiday wins over a half day, otherwise the first is kept.
+func MergePublicHolidays(lists ...[]PublicHoliday) []PublicHoliday {
+	byDate := map[string]PublicHoliday{}
+	var dates []string
+	for _, list := range lists {
+		for _, holiday := range list {
+			existing, ok := byDate[holiday.Date]
+			if !ok {
+				dates = append(dates, holiday.Date)
+			}
+			if !ok || existing.HalfDay && !holiday.HalfDay {
+				byDate[holiday.Date] = holiday
+			}
+		}
+	}
+
+	sort.Strings(dates)
+	merged := make([]PublicHoliday, 0, len(dates))
+	for _, date := range dates {
+		merged = append(merged, byDate[date])
+	}
+	return merged
+}
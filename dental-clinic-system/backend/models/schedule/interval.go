@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Interval is a half-open time range [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Contains reports whether the range [start, end) lies completely inside the interval
+func (i Interval) Contains(start, end time.Time) bool {
+	return !start.Before(i.Start) && !end.After(i.End)
+}
+
+// ParseClock parses an "HH:MM" wall clock value into an offset from midnight.
+// "24:00" is accepted to express the end of a day.
+func ParseClock(value string) (time.Duration, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil || len(value) != 5 {
+		return 0, fmt.Errorf("invalid clock value %q, expected HH:MM", value)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid clock value %q", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// ClockInterval builds the interval between two wall clock values on the given day
+func ClockInterval(day time.Time, from, to string) (Interval, error) {
+	start, err := ParseClock(from)
+	if err != nil {
+		return Interval{}, err
+	}
+	end, err := ParseClock(to)
+	if err != nil {
+		return Interval{}, err
+	}
+	return Interval{Start: atOffset(day, start), End: atOffset(day, end)}, nil
+}
+
+// atOffset returns the wall clock time offset from midnight of day, respecting DST changes
+func atOffset(day time.Time, offset time.Duration) time.Time {
+	hours := int(offset / time.Hour)
+	minutes := int((offset % time.Hour) / time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hours, minutes, 0, 0, day.Location())
+}
+
+// Normalize sorts the intervals and merges the ones that overlap or touch
+func Normalize(intervals []Interval) []Interval {
+	valid := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.End.After(interval.Start) {
+			valid = append(valid, interval)
+		}
+	}
+	sort.Slice(valid, func(a, b int) bool { return valid[a].Start.Before(valid[b].Start) })
+
+	merged := make([]Interval, 0, len(valid))
+	for _, interval := range valid {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// Intersect returns the ranges covered by both interval sets
+func Intersect(a, b []Interval) []Interval {
+	a, b = Normalize(a), Normalize(b)
+	var result []Interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start := later(a[i].Start, b[j].Start)
+		end := earlier(a[i].End, b[j].End)
+		if end.After(start) {
+			result = append(result, Interval{Start: start, End: end})
+		}
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// Subtract removes the ranges in blocked from the interval set
+func Subtract(intervals, blocked []Interval) []Interval {
+	result := Normalize(intervals)
+	for _, block := range Normalize(blocked) {
+		var next []Interval
+		for _, interval := range result {
+			if !block.Start.Before(interval.End) || !interval.Start.Before(block.End) {
+				next = append(next, interval)
+				continue
+			}
+			if interval.Start.Before(block.Start) {
+				next = append(next, Interval{Start: interval.Start, End: block.Start})
+			}
+			if block.End.Before(interval.End) {
+				next = append(next, Interval{Start: block.End, End: interval.End})
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OpeningHours is a time window in which a clinic is open on a given weekday.
+// A weekday may have several windows, e.g. to close for lunch.
+type OpeningHours struct {
+	gorm.Model
+	ClinicID uint         `json:"clinic_id" gorm:"index"`
+	Weekday  time.Weekday `json:"weekday"`
+	OpensAt  string       `json:"opens_at"`
+	ClosesAt string       `json:"closes_at"`
+}
+
+// AlwaysOpen is used for clinics that have not set their opening hours yet: they are open around the
+// clock, as every clinic was before opening hours existed, and only doctor schedules, exceptions and
+// holidays limit their bookings.
+func AlwaysOpen(clinicID uint) []OpeningHours {
+	hours := make([]OpeningHours, 0, 7)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		hours = append(hours, OpeningHours{ClinicID: clinicID, Weekday: weekday, OpensAt: "00:00", ClosesAt: "24:00"})
+	}
+	return hours
+}
+
+// DoctorSchedule is a weekly working window of a doctor
+type DoctorSchedule struct {
+	gorm.Model
+	ClinicID  uint            `json:"clinic_id" gorm:"index"`
+	DoctorID  uint            `json:"doctor_id" gorm:"index"`
+	Weekday   time.Weekday    `json:"weekday"`
+	StartTime string          `json:"start_time"`
+	EndTime   string          `json:"end_time"`
+	Breaks    []ScheduleBreak `json:"breaks" gorm:"foreignKey:DoctorScheduleID;constraint:OnDelete:CASCADE"`
+}
+
+// ScheduleBreak is a pause inside a doctor's working window
+type ScheduleBreak struct {
+	gorm.Model
+	DoctorScheduleID uint   `json:"doctor_schedule_id" gorm:"index"`
+	StartTime        string `json:"start_time"`
+	EndTime          string `json:"end_time"`
+}
+
+type ExceptionType string
+
+const (
+	ExceptionLeave         ExceptionType = "leave"
+	ExceptionSickLeave     ExceptionType = "sick_leave"
+	ExceptionClinicClosure ExceptionType = "clinic_closure"
+	ExceptionHoliday       ExceptionType = "holiday"
+)
+
+// ScheduleException blocks a dated range for a single doctor, or for the whole clinic when DoctorID is nil.
+// StartTime and EndTime are optional; when empty the exception covers whole days.
+type ScheduleException struct {
+	gorm.Model
+	ClinicID    uint          `json:"clinic_id" gorm:"index"`
+	DoctorID    *uint         `json:"doctor_id" gorm:"index"`
+	Type        ExceptionType `json:"type"`
+	StartDate   string        `json:"start_date"`
+	EndDate     string        `json:"end_date"`
+	StartTime   string        `json:"start_time"`
+	EndTime     string        `json:"end_time"`
+	Description string        `json:"description"`
+}
+
+// PublicHoliday is a national holiday that closes every clinic.
+// Half-day holidays (arife) close from HalfDayFrom until the end of the day.
+type PublicHoliday struct {
+	gorm.Model
+	Date        string `json:"date" gorm:"uniqueIndex"`
+	Name        string `json:"name"`
+	HalfDay     bool   `json:"half_day"`
+	HalfDayFrom string `json:"half_day_from"`
+}
+
+// DateLayout is the layout used for all dates in the schedule models
+const DateLayout = "2006-01-02"
+
+// Error types
+var (
+	ErrOutsideWorkingHours       = errors.New("appointment is outside working hours")
+	ErrScheduleValidation        = errors.New("schedule validation errors")
+	ErrScheduleExceptionNotFound = errors.New("schedule exception not found")
+)
@@ -0,0 +1,196 @@
+package treatment
+
+import (
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/procedure"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency is used for plan estimates unless another currency is given
+const DefaultCurrency = "TRY"
+
+// PlanStatus is the state of a treatment plan
+type PlanStatus string
+
+const (
+	PlanDraft      PlanStatus = "draft"
+	PlanPresented  PlanStatus = "presented"
+	PlanAccepted   PlanStatus = "accepted"
+	PlanDeclined   PlanStatus = "declined"
+	PlanInProgress PlanStatus = "in_progress"
+	PlanCompleted  PlanStatus = "completed"
+)
+
+// planTransitions lists the statuses a plan may move to from each status.
+// A presented plan can go back to draft to be revised before the patient decides.
+var planTransitions = map[PlanStatus][]PlanStatus{
+	PlanDraft:      {PlanPresented},
+	PlanPresented:  {PlanDraft, PlanAccepted, PlanDeclined},
+	PlanAccepted:   {PlanInProgress},
+	PlanDeclined:   {},
+	PlanInProgress: {PlanCompleted},
+	PlanCompleted:  {},
+}
+
+// CanTransitionPlan reports whether a plan may move from one status to another
+func CanTransitionPlan(from, to PlanStatus) bool {
+	for _, allowed := range planTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSchedule reports whether items of a plan in this status may be booked
+func (s PlanStatus) CanSchedule() bool {
+	return s == PlanAccepted || s == PlanInProgress
+}
+
+// ItemStatus is the progress of a plan item, derived from its linked appointment
+type ItemStatus string
+
+const (
+	ItemPlanned   ItemStatus = "planned"
+	ItemScheduled ItemStatus = "scheduled"
+	ItemCompleted ItemStatus = "completed"
+)
+
+// Plan is a proposed course of treatment for a patient, grouped into phases.
+// Prices are in minor currency units (kuruş for TRY).
+type Plan struct {
+	gorm.Model
+	ClinicID       uint       `json:"clinic_id" gorm:"index"`
+	PatientID      uint       `json:"patient_id" gorm:"index"`
+	DoctorID       uint       `json:"doctor_id"`
+	Title          string     `json:"title"`
+	Notes          string     `json:"notes"`
+	Currency       string     `json:"currency" gorm:"default:TRY"`
+	Status         PlanStatus `json:"status" gorm:"default:draft;index"`
+	CreatedByID    uint       `json:"created_by_id"`
+	PresentedAt    *time.Time `json:"presented_at"`
+	DecidedAt      *time.Time `json:"decided_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	Phases         []Phase    `json:"phases" gorm:"foreignKey:PlanID;constraint:OnDelete:CASCADE"`
+	EstimatedTotal int64      `json:"estimated_total" gorm:"-"`
+}
+
+func (Plan) TableName() string {
+	return "treatment_plans"
+}
+
+// Phase is an ordered stage of a plan, e.g. "Acil tedavi" before "Protetik tedavi"
+type Phase struct {
+	gorm.Model
+	PlanID         uint   `json:"plan_id" gorm:"index"`
+	Position       int    `json:"position"`
+	Name           string `json:"name"`
+	Items          []Item `json:"items" gorm:"foreignKey:PhaseID;constraint:OnDelete:CASCADE"`
+	EstimatedTotal int64  `json:"estimated_total" gorm:"-"`
+}
+
+func (Phase) TableName() string {
+	return "treatment_plan_phases"
+}
+
+// Item is a procedure planned on a tooth, or on the whole mouth when Tooth is nil
+type Item struct {
+	gorm.Model
+	PhaseID        uint                     `json:"phase_id" gorm:"index"`
+	Position       int                      `json:"position"`
+	ProcedureID    uint                     `json:"procedure_id"`
+	Procedure      procedure.Procedure      `json:"procedure" gorm:"foreignKey:ProcedureID"`
+	Tooth          *int                     `json:"tooth"`
+	Surfaces       string                   `json:"surfaces"`
+	FindingID      *uint                    `json:"finding_id"`
+	Description    string                   `json:"description"`
+	EstimatedPrice int64                    `json:"estimated_price"`
+	AppointmentID  *uint                    `json:"appointment_id" gorm:"index"`
+	Appointment    *appointment.Appointment `json:"-" gorm:"foreignKey:AppointmentID"`
+	Status         ItemStatus               `json:"status" gorm:"-"`
+}
+
+func (Item) TableName() string {
+	return "treatment_plan_items"
+}
+
+// ItemStatusFor derives the progress of an item from its linked appointment.
+// An item whose appointment was cancelled or missed is planned again.
+func ItemStatusFor(appt *appointment.Appointment) ItemStatus {
+	switch {
+	case appt == nil || appt.ID == 0 || !appt.Status.OccupiesSlot():
+		return ItemPlanned
+	case appt.Status == appointment.StatusCompleted:
+		return ItemCompleted
+	}
+	return ItemScheduled
+}
+
+// Resolve fills in the derived item statuses and the phase and plan estimates
+func (p *Plan) Resolve() {
+	p.EstimatedTotal = 0
+	for i := range p.Phases {
+		phase := &p.Phases[i]
+		phase.EstimatedTotal = 0
+		for j := range phase.Items {
+			item := &phase.Items[j]
+			item.Status = ItemStatusFor(item.Appointment)
+			phase.EstimatedTotal += item.EstimatedPrice
+		}
+		p.EstimatedTotal += phase.EstimatedTotal
+	}
+}
+
+// FindItem returns the item with the given ID
+func (p Plan) FindItem(itemID uint) (Item, bool) {
+	for _, phase := range p.Phases {
+		for _, item := range phase.Items {
+			if item.ID == itemID {
+				return item, true
+			}
+		}
+	}
+	return Item{}, false
+}
+
+// AllItemsCompleted reports whether every item of a resolved plan has been carried out
+func (p Plan) AllItemsCompleted() bool {
+	for _, phase := range p.Phases {
+		for _, item := range phase.Items {
+			if item.Status != ItemCompleted {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Error types
+var (
+	ErrPlanNotFound            = errors.New("treatment plan not found")
+	ErrItemNotFound            = errors.New("treatment plan item not found")
+	ErrPlanValidation          = errors.New("invalid treatment plan")
+	ErrPlanNotEditable         = errors.New("only draft treatment plans can be edited")
+	ErrPlanNotSchedulable      = errors.New("treatment plan must be accepted before its items are scheduled")
+	ErrItemAlreadyBooked       = errors.New("treatment plan item already has an active appointment")
+	ErrPlanNotComplete         = errors.New("treatment plan still has items that are not completed")
+	ErrInvalidStatusTransition = errors.New("invalid treatment plan status transition")
+)
+
+// InvalidTransitionError describes a plan status change that the transition table does not allow
+type InvalidTransitionError struct {
+	From PlanStatus
+	To   PlanStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("treatment plan can not move from %s to %s", e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Unwrap() error {
+	return ErrInvalidStatusTransition
+}
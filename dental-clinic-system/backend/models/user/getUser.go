@@ -18,3 +18,18 @@ type UserGetModel struct {
 	PhoneNumber string    `json:"phone_number"`
 	Roles       []*Role   `gorm:"many2many:user_roles;"`
 }
+
+// HasRole reports whether the user has any of the given roles
+func (u UserGetModel) HasRole(names ...RoleName) bool {
+	for _, role := range u.Roles {
+		if role == nil {
+			continue
+		}
+		for _, name := range names {
+			if role.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
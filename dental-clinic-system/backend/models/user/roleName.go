@@ -22,3 +22,24 @@ const (
 	RoleClinicAdmin             RoleName = "clinic_admin"
 	RoleSuperAdmin              RoleName = "super_admin"
 )
+
+// ClinicalRoles are the roles that treat patients and may edit their clinical records
+var ClinicalRoles = []RoleName{RoleDoctor, RoleOrthodontist, RoleAssistant, RoleIntern}
+
+// ClinicalNoteRoles are the roles that may read clinical notes
+var ClinicalNoteRoles = []RoleName{RoleDoctor, RoleOrthodontist, RoleAssistant}
+
+// NoteSigningRoles are the roles that may write and sign clinical notes and add addenda to them
+var NoteSigningRoles = []RoleName{RoleDoctor, RoleOrthodontist}
+
+// RadiographyRoles are the roles that take, ingest and read radiographs
+var RadiographyRoles = []RoleName{RoleRadiologyTechnician, RoleDoctor, RoleOrthodontist, RoleAssistant, RoleIntern}
+
+// ConsentRoles are the roles that prepare consent forms and witness patients signing them
+var ConsentRoles = []RoleName{RoleDoctor, RoleOrthodontist, RoleAssistant, RoleIntern, RoleSecretary, RolePatientConsultant}
+
+// PatientMergeRoles are the roles that review duplicate patients and merge them
+var PatientMergeRoles = []RoleName{RoleSecretary, RoleManager, RoleClinicAdmin}
+
+// BillingRoles are the roles that issue invoices and take payments
+var BillingRoles = []RoleName{RoleAccountant, RoleSecretary, RoleManager, RoleClinicAdmin}
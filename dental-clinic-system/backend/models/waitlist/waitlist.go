@@ -0,0 +1,139 @@
+package waitlist
+
+import (
+	"dental-clinic-system/models/appointment"
+	"dental-clinic-system/models/patient"
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Backfill settings
+const (
+	// OfferBatchSize is how many waitlist entries are offered a freed slot at once; the first to accept wins
+	OfferBatchSize = 3
+	// OfferTTL is how long an offer stays valid, unless the slot starts earlier
+	OfferTTL = 2 * time.Hour
+)
+
+// EntryStatus is the state of a waitlist entry
+type EntryStatus string
+
+const (
+	EntryWaiting   EntryStatus = "waiting"
+	EntryBooked    EntryStatus = "booked"
+	EntryCancelled EntryStatus = "cancelled"
+)
+
+// OfferStatus is the state of a slot offer
+type OfferStatus string
+
+const (
+	OfferPending    OfferStatus = "pending"
+	OfferAccepted   OfferStatus = "accepted"
+	OfferSuperseded OfferStatus = "superseded"
+)
+
+// Entry is a patient queued for an earlier or any appointment at a clinic
+type Entry struct {
+	gorm.Model
+	ClinicID        uint            `json:"clinic_id" gorm:"index"`
+	PatientID       uint            `json:"patient_id"`
+	Patient         patient.Patient `gorm:"foreignKey:PatientID"`
+	DoctorID        *uint           `json:"doctor_id"`
+	ProcedureID     *uint           `json:"procedure_id"`
+	Treatment       string          `json:"treatment"`
+	DurationMinutes int             `json:"duration_minutes"`
+	Priority        int             `json:"priority"`
+	Status          EntryStatus     `json:"status" gorm:"default:waiting;index"`
+	Notes           string          `json:"notes"`
+	Windows         []Window        `json:"windows" gorm:"foreignKey:EntryID;constraint:OnDelete:CASCADE"`
+}
+
+func (Entry) TableName() string {
+	return "waitlist_entries"
+}
+
+// Window is a weekly time range in which a waitlisted patient can come, in clinic local time
+type Window struct {
+	gorm.Model
+	EntryID   uint         `json:"entry_id" gorm:"index"`
+	Weekday   time.Weekday `json:"weekday"`
+	StartTime string       `json:"start_time"`
+	EndTime   string       `json:"end_time"`
+}
+
+func (Window) TableName() string {
+	return "waitlist_windows"
+}
+
+// Offer proposes a freed slot to a waitlist entry
+type Offer struct {
+	gorm.Model
+	ClinicID            uint        `json:"clinic_id"`
+	EntryID             uint        `json:"entry_id" gorm:"index"`
+	Entry               Entry       `gorm:"foreignKey:EntryID"`
+	SourceAppointmentID uint        `json:"source_appointment_id" gorm:"index"`
+	DoctorID            uint        `json:"doctor_id"`
+	StartTime           time.Time   `json:"start_time"`
+	EndTime             time.Time   `json:"end_time"`
+	Rank                int         `json:"rank"`
+	Status              OfferStatus `json:"status" gorm:"default:pending;index"`
+	TokenHash           string      `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt           time.Time   `json:"expires_at"`
+	AppointmentID       *uint       `json:"appointment_id"`
+}
+
+func (Offer) TableName() string {
+	return "waitlist_offers"
+}
+
+// Matches reports whether the entry accepts a slot of the given doctor and time.
+// The slot must be long enough and, when the entry has windows, fit into one of them on its weekday in loc.
+func (e Entry) Matches(doctorID uint, start, end time.Time, loc *time.Location) bool {
+	if e.Status != EntryWaiting {
+		return false
+	}
+	if e.DoctorID != nil && *e.DoctorID != doctorID {
+		return false
+	}
+	if end.Sub(start) < e.Duration() {
+		return false
+	}
+	if len(e.Windows) == 0 {
+		return true
+	}
+
+	localStart := start.In(loc)
+	day := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, loc)
+	slotEnd := start.Add(e.Duration())
+	for _, window := range e.Windows {
+		if window.Weekday != localStart.Weekday() {
+			continue
+		}
+		interval, err := schedule.ClockInterval(day, window.StartTime, window.EndTime)
+		if err == nil && interval.Contains(start, slotEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+// Duration returns how long the appointment offered to the entry has to be
+func (e Entry) Duration() time.Duration {
+	if e.DurationMinutes <= 0 {
+		return appointment.DefaultDuration
+	}
+	return time.Duration(e.DurationMinutes) * time.Minute
+}
+
+// Error types
+var (
+	ErrEntryNotFound       = errors.New("waitlist entry not found")
+	ErrOfferNotFound       = errors.New("waitlist offer not found")
+	ErrOfferNotAvailable   = errors.New("waitlist offer is no longer available")
+	ErrWaitlistValidation  = errors.New("invalid waitlist entry")
+	ErrEntryNotCancellable = errors.New("waitlist entry can no longer be cancelled")
+)
@@ -0,0 +1,106 @@
+package validations
+
+import (
+	"dental-clinic-system/models/billing"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// InvoiceValidation checks an invoice and its lines before the totals are calculated
+func InvoiceValidation(invoice *billing.Invoice) error {
+	if invoice.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	if invoice.Currency == "" {
+		invoice.Currency = billing.DefaultCurrency
+	}
+	currencyRegex := `^[A-Z]{3}$`
+	if !regexp.MustCompile(currencyRegex).MatchString(invoice.Currency) {
+		return errors.New("currency must be a three letter ISO 4217 code")
+	}
+
+	if len(invoice.Lines) == 0 {
+		return errors.New("at least one line is required")
+	}
+
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		if strings.TrimSpace(line.Description) == "" {
+			return fmt.Errorf("line %d: description is required", i+1)
+		}
+		if line.Quantity == 0 {
+			line.Quantity = 1
+		}
+		if line.Quantity < 0 {
+			return fmt.Errorf("line %d: quantity must be positive", i+1)
+		}
+		if line.UnitPrice < 0 {
+			return fmt.Errorf("line %d: unit price can not be negative", i+1)
+		}
+		if line.VATRate < 0 || line.VATRate > 100 {
+			return fmt.Errorf("line %d: vat rate must be a percentage between 0 and 100", i+1)
+		}
+		if line.DiscountPercent < 0 || line.DiscountPercent > 100 {
+			return fmt.Errorf("line %d: discount percent must be between 0 and 100", i+1)
+		}
+		if line.DiscountAmount < 0 || line.DiscountAmount > line.UnitPrice*int64(line.Quantity) {
+			return fmt.Errorf("line %d: discount amount must be between zero and the line amount", i+1)
+		}
+		if line.DiscountAmount > 0 && line.DiscountPercent > 0 {
+			return fmt.Errorf("line %d: give either a discount amount or a discount percent", i+1)
+		}
+	}
+
+	return nil
+}
+
+func PaymentValidation(payment *billing.Payment) error {
+	if payment.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	if !payment.Method.IsValid() {
+		return errors.New("method must be cash, card or bank_transfer")
+	}
+
+	return nil
+}
+
+// InstallmentPlanValidation checks the due dates and amounts of an installment plan and numbers the installments.
+// The first installment can not be due before today.
+func InstallmentPlanValidation(plan *billing.InstallmentPlan, today string) error {
+	if len(plan.Installments) == 0 {
+		return errors.New("at least one installment is required")
+	}
+	if len(plan.Installments) > 60 {
+		return errors.New("an installment plan can have at most 60 installments")
+	}
+
+	plan.Total = 0
+	previous := ""
+	for i := range plan.Installments {
+		inst := &plan.Installments[i]
+		if _, err := time.Parse(billing.DateLayout, inst.DueDate); err != nil {
+			return fmt.Errorf("installment %d: due date must be in YYYY-MM-DD format", i+1)
+		}
+		if i == 0 && inst.DueDate < today {
+			return fmt.Errorf("installment %d: due date can not be in the past", i+1)
+		}
+		if inst.DueDate <= previous {
+			return fmt.Errorf("installment %d: due dates must be in ascending order", i+1)
+		}
+		if inst.Amount <= 0 {
+			return fmt.Errorf("installment %d: amount must be positive", i+1)
+		}
+		previous = inst.DueDate
+		inst.Sequence = i + 1
+		inst.Status = billing.InstallmentPending
+		plan.Total += inst.Amount
+	}
+
+	return nil
+}
@@ -1,8 +1,13 @@
 package validations
 
 import (
+	"bytes"
 	"dental-clinic-system/models/clinic"
 	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"regexp"
 	"strings"
 )
@@ -28,6 +33,11 @@ func ClinicValidation(clinic *clinic.Clinic) error {
 		return err
 	}
 
+	err = ClinicTaxValidation(clinic)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -88,3 +98,48 @@ func ClinicEmailValidation(clinic *clinic.Clinic) error {
 	clinic.Email = strings.TrimSpace(clinic.Email)
 	return nil
 }
+
+// ClinicTaxValidation checks the optional tax details used on e-invoices
+func ClinicTaxValidation(clinic *clinic.Clinic) error {
+	clinic.TaxNumber = strings.TrimSpace(clinic.TaxNumber)
+	if clinic.TaxNumber != "" && !regexp.MustCompile(`^([0-9]{10}|[1-9][0-9]{10})$`).MatchString(clinic.TaxNumber) {
+		return errors.New("tax number must be a 10 digit VKN or an 11 digit TCKN")
+	}
+
+	clinic.EInvoicePrefix = strings.ToUpper(strings.TrimSpace(clinic.EInvoicePrefix))
+	if clinic.EInvoicePrefix != "" && !regexp.MustCompile(`^[A-Z0-9]{3}$`).MatchString(clinic.EInvoicePrefix) {
+		return errors.New("e-invoice prefix must be 3 letters or digits")
+	}
+
+	clinic.TaxOffice = strings.TrimSpace(clinic.TaxOffice)
+	clinic.District = strings.TrimSpace(clinic.District)
+	clinic.City = strings.TrimSpace(clinic.City)
+	return nil
+}
+
+// ClinicLogoValidation checks an uploaded logo and returns its content type. Only PNG and JPEG files
+// within the size and dimension limits are accepted, since the logo is embedded in every document.
+func ClinicLogoValidation(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("logo file is empty")
+	}
+	if len(data) > clinic.MaxLogoBytes {
+		return "", fmt.Errorf("logo must be at most %d KB", clinic.MaxLogoBytes>>10)
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", errors.New("logo must be a PNG or JPEG image")
+	}
+	if cfg.Width > clinic.MaxLogoDimension || cfg.Height > clinic.MaxLogoDimension {
+		return "", fmt.Errorf("logo must be at most %dx%d pixels", clinic.MaxLogoDimension, clinic.MaxLogoDimension)
+	}
+
+	switch format {
+	case "png":
+		return "image/png", nil
+	case "jpeg":
+		return "image/jpeg", nil
+	}
+	return "", errors.New("logo must be a PNG or JPEG image")
+}
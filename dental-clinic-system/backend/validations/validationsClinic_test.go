@@ -1,7 +1,12 @@
 package validations
 
 import (
+	"bytes"
 	"dental-clinic-system/models/clinic"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"testing"
 )
 
@@ -61,6 +66,41 @@ func TestClinicValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Valid tax details",
+			clinic: &clinic.Clinic{
+				Name:           "Healthy Smiles",
+				Address:        "123 Dental St",
+				PhoneNumber:    "1234567890",
+				Email:          "contact@healthysmiles.com",
+				TaxNumber:      "1234567890",
+				TaxOffice:      "Kadıköy",
+				EInvoicePrefix: "dnt",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid tax number",
+			clinic: &clinic.Clinic{
+				Name:        "Healthy Smiles",
+				Address:     "123 Dental St",
+				PhoneNumber: "1234567890",
+				Email:       "contact@healthysmiles.com",
+				TaxNumber:   "123456789",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid e-invoice prefix",
+			clinic: &clinic.Clinic{
+				Name:           "Healthy Smiles",
+				Address:        "123 Dental St",
+				PhoneNumber:    "1234567890",
+				Email:          "contact@healthysmiles.com",
+				EInvoicePrefix: "DENT",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -72,3 +112,51 @@ func TestClinicValidation(t *testing.T) {
 		})
 	}
 }
+
+func encodeLogo(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+func TestClinicLogoValidation(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		wantContentType string
+		wantErr         bool
+	}{
+		{name: "PNG logo", data: encodeLogo(t, "png", 120, 60), wantContentType: "image/png"},
+		{name: "JPEG logo", data: encodeLogo(t, "jpeg", 120, 60), wantContentType: "image/jpeg"},
+		{name: "GIF logo", data: encodeLogo(t, "gif", 120, 60), wantErr: true},
+		{name: "Empty file", data: nil, wantErr: true},
+		{name: "Not an image", data: []byte("%PDF-1.4"), wantErr: true},
+		{name: "Too large", data: make([]byte, clinic.MaxLogoBytes+1), wantErr: true},
+		{name: "Too many pixels", data: encodeLogo(t, "png", clinic.MaxLogoDimension+1, 10), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, err := ClinicLogoValidation(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClinicLogoValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("ClinicLogoValidation() content type = %q, want %q", contentType, tt.wantContentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package validations
+
+import (
+	"dental-clinic-system/models/clinicalnote"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var sectionKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ClinicalNoteTemplateValidation checks the name and sections of a template and numbers its sections in order
+func ClinicalNoteTemplateValidation(template *clinicalnote.Template) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return errors.New("template name is required")
+	}
+
+	if len(template.Sections) == 0 {
+		return errors.New("template needs at least one section")
+	}
+
+	seen := map[string]bool{}
+	for i := range template.Sections {
+		section := &template.Sections[i]
+		if err := sectionValidation(section.Key, &section.Title, seen); err != nil {
+			return err
+		}
+		section.Position = i
+	}
+
+	return nil
+}
+
+// ClinicalNoteValidation checks that a note belongs to an appointment and has uniquely keyed, titled sections,
+// and numbers its sections in order
+func ClinicalNoteValidation(note *clinicalnote.Note) error {
+	if note.PatientID == 0 || note.AppointmentID == 0 {
+		return errors.New("patient and appointment are required")
+	}
+
+	note.Title = strings.TrimSpace(note.Title)
+	if note.Title == "" {
+		return errors.New("title is required")
+	}
+
+	if len(note.Sections) == 0 {
+		return errors.New("note needs at least one section")
+	}
+
+	seen := map[string]bool{}
+	for i := range note.Sections {
+		section := &note.Sections[i]
+		if err := sectionValidation(section.Key, &section.Title, seen); err != nil {
+			return err
+		}
+		section.Position = i
+	}
+
+	return nil
+}
+
+func sectionValidation(key string, title *string, seen map[string]bool) error {
+	if !sectionKeyPattern.MatchString(key) {
+		return fmt.Errorf("section key %q must be lower case letters, digits and underscores", key)
+	}
+	if seen[key] {
+		return fmt.Errorf("section %q appears twice", key)
+	}
+	seen[key] = true
+
+	*title = strings.TrimSpace(*title)
+	if *title == "" {
+		return fmt.Errorf("section %q needs a title", key)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package validations
+
+import (
+	"dental-clinic-system/models/clinicalnote"
+	"testing"
+)
+
+func TestClinicalNoteTemplateValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		template clinicalnote.Template
+		wantErr  bool
+	}{
+		{
+			name: "Implant template",
+			template: clinicalnote.Template{Name: " Implant ", Sections: []clinicalnote.TemplateSection{
+				{Key: "site", Title: "Site", Required: true},
+				{Key: "implant_system", Title: "Implant system"},
+			}},
+		},
+		{
+			name:     "Missing name",
+			template: clinicalnote.Template{Sections: []clinicalnote.TemplateSection{{Key: "site", Title: "Site"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "No sections",
+			template: clinicalnote.Template{Name: "Implant"},
+			wantErr:  true,
+		},
+		{
+			name: "Duplicate key",
+			template: clinicalnote.Template{Name: "Implant", Sections: []clinicalnote.TemplateSection{
+				{Key: "site", Title: "Site"},
+				{Key: "site", Title: "Location"},
+			}},
+			wantErr: true,
+		},
+		{
+			name:     "Key with spaces",
+			template: clinicalnote.Template{Name: "Implant", Sections: []clinicalnote.TemplateSection{{Key: "implant system", Title: "System"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "Section without title",
+			template: clinicalnote.Template{Name: "Implant", Sections: []clinicalnote.TemplateSection{{Key: "site", Title: " "}}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClinicalNoteTemplateValidation(&tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClinicalNoteTemplateValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, section := range tt.template.Sections {
+				if section.Position != i {
+					t.Errorf("section %q position = %d, want %d", section.Key, section.Position, i)
+				}
+			}
+		})
+	}
+}
+
+func TestClinicalNoteValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		note    clinicalnote.Note
+		wantErr bool
+	}{
+		{
+			name: "Free note",
+			note: clinicalnote.Note{PatientID: 1, AppointmentID: 2, Title: "Check-up", Sections: []clinicalnote.Section{{Key: "findings", Title: "Findings", Body: "No caries"}}},
+		},
+		{
+			name:    "Missing appointment",
+			note:    clinicalnote.Note{PatientID: 1, Title: "Check-up", Sections: []clinicalnote.Section{{Key: "findings", Title: "Findings"}}},
+			wantErr: true,
+		},
+		{
+			name:    "Missing title",
+			note:    clinicalnote.Note{PatientID: 1, AppointmentID: 2, Sections: []clinicalnote.Section{{Key: "findings", Title: "Findings"}}},
+			wantErr: true,
+		},
+		{
+			name:    "No sections",
+			note:    clinicalnote.Note{PatientID: 1, AppointmentID: 2, Title: "Check-up"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClinicalNoteValidation(&tt.note)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClinicalNoteValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
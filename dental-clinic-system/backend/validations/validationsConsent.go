@@ -0,0 +1,55 @@
+package validations
+
+import (
+	"dental-clinic-system/models/consent"
+	"errors"
+	"strings"
+)
+
+// ConsentTemplateValidation checks the name and text of a template and removes repeated procedures
+func ConsentTemplateValidation(template *consent.Template) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return errors.New("template name is required")
+	}
+
+	template.Title = strings.TrimSpace(template.Title)
+	if template.Title == "" {
+		return errors.New("title is required")
+	}
+
+	template.Body = strings.TrimSpace(template.Body)
+	if template.Body == "" {
+		return errors.New("body is required")
+	}
+
+	seen := map[uint]bool{}
+	procedures := template.Procedures[:0]
+	for _, p := range template.Procedures {
+		if p.ProcedureID == 0 {
+			return errors.New("procedure_id is required for each procedure")
+		}
+		if seen[p.ProcedureID] {
+			continue
+		}
+		seen[p.ProcedureID] = true
+		procedures = append(procedures, consent.TemplateProcedure{ProcedureID: p.ProcedureID})
+	}
+	template.Procedures = procedures
+
+	return nil
+}
+
+// ConsentSignatureValidation checks that a signature request names the signer and carries a signature
+func ConsentSignatureValidation(req *consent.SignRequest) error {
+	req.SignerName = strings.TrimSpace(req.SignerName)
+	if req.SignerName == "" {
+		return errors.New("signer name is required")
+	}
+
+	if strings.TrimSpace(req.Signature) == "" {
+		return errors.New("signature is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package validations
+
+import (
+	"dental-clinic-system/models/consent"
+	"testing"
+)
+
+func TestConsentTemplateValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		template consent.Template
+		wantErr  bool
+	}{
+		{
+			name: "Extraction consent",
+			template: consent.Template{Name: " Extraction ", Title: "Diş Çekimi Onam Formu", Body: "Çekim sonrası ağrı ve şişlik olabilir.",
+				Procedures: []consent.TemplateProcedure{{ProcedureID: 3}, {ProcedureID: 4}, {ProcedureID: 3}}},
+		},
+		{
+			name:     "Missing name",
+			template: consent.Template{Title: "Onam", Body: "Metin"},
+			wantErr:  true,
+		},
+		{
+			name:     "Missing title",
+			template: consent.Template{Name: "Implant", Body: "Metin"},
+			wantErr:  true,
+		},
+		{
+			name:     "Empty body",
+			template: consent.Template{Name: "Implant", Title: "Onam", Body: " \n "},
+			wantErr:  true,
+		},
+		{
+			name:     "Procedure without ID",
+			template: consent.Template{Name: "Implant", Title: "Onam", Body: "Metin", Procedures: []consent.TemplateProcedure{{}}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConsentTemplateValidation(&tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConsentTemplateValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.template.Name != "Extraction" {
+				t.Errorf("name = %q, want it trimmed", tt.template.Name)
+			}
+			if len(tt.template.Procedures) != 2 {
+				t.Errorf("procedures = %+v, want the repeated procedure removed", tt.template.Procedures)
+			}
+		})
+	}
+}
+
+func TestConsentSignatureValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     consent.SignRequest
+		wantErr bool
+	}{
+		{name: "Signed by the patient", req: consent.SignRequest{SignerName: "Ayşe Yılmaz", Signature: "data:image/png;base64,iVBORw0KGgo="}},
+		{name: "Missing signer", req: consent.SignRequest{SignerName: " ", Signature: "data:image/png;base64,iVBORw0KGgo="}, wantErr: true},
+		{name: "Missing signature", req: consent.SignRequest{SignerName: "Ayşe Yılmaz"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ConsentSignatureValidation(&tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("ConsentSignatureValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package validations
+
+import (
+	"dental-clinic-system/models/billing"
+	"dental-clinic-system/models/insurance"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func InsuranceProviderValidation(provider *insurance.Provider) error {
+	provider.Name = strings.TrimSpace(provider.Name)
+	if provider.Name == "" {
+		return errors.New("name is required")
+	}
+
+	provider.Code = strings.ToUpper(strings.TrimSpace(provider.Code))
+	codeRegex := `^[A-Z0-9_-]{0,20}$`
+	if !regexp.MustCompile(codeRegex).MatchString(provider.Code) {
+		return errors.New("code can have at most 20 letters, digits, dashes or underscores")
+	}
+
+	if provider.Email != "" {
+		emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
+		if !regexp.MustCompile(emailRegex).MatchString(provider.Email) {
+			return errors.New("invalid email format")
+		}
+	}
+
+	return nil
+}
+
+// InsurancePolicyValidation checks the number, coverage, limit and validity dates of a policy
+func InsurancePolicyValidation(policy *insurance.Policy) error {
+	if policy.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+	if policy.ProviderID == 0 {
+		return errors.New("provider is required")
+	}
+
+	policy.PolicyNumber = strings.TrimSpace(policy.PolicyNumber)
+	if policy.PolicyNumber == "" {
+		return errors.New("policy number is required")
+	}
+
+	if policy.CoveragePercent <= 0 || policy.CoveragePercent > 100 {
+		return errors.New("coverage percent must be between 1 and 100")
+	}
+	if policy.AnnualLimit < 0 {
+		return errors.New("annual limit can not be negative; use zero for no limit")
+	}
+
+	if policy.Currency == "" {
+		policy.Currency = billing.DefaultCurrency
+	}
+	currencyRegex := `^[A-Z]{3}$`
+	if !regexp.MustCompile(currencyRegex).MatchString(policy.Currency) {
+		return errors.New("currency must be a three letter ISO 4217 code")
+	}
+
+	if _, err := time.Parse(insurance.DateLayout, policy.ValidFrom); err != nil {
+		return errors.New("valid from must be in YYYY-MM-DD format")
+	}
+	if policy.ValidTo != "" {
+		if _, err := time.Parse(insurance.DateLayout, policy.ValidTo); err != nil {
+			return errors.New("valid to must be in YYYY-MM-DD format")
+		}
+		if policy.ValidTo < policy.ValidFrom {
+			return errors.New("valid to can not be before valid from")
+		}
+	}
+
+	return nil
+}
+
+// ClaimDecisionValidation checks an insurer's decision against the claimed amount.
+// An approval without an amount approves the claimed amount.
+func ClaimDecisionValidation(decision *insurance.Decision, claimed int64) error {
+	if !decision.Status.IsDecision() {
+		return errors.New("status must be approved, partially_approved or rejected")
+	}
+
+	switch decision.Status {
+	case insurance.ClaimApproved:
+		if decision.ApprovedAmount == 0 {
+			decision.ApprovedAmount = claimed
+		}
+		if decision.ApprovedAmount != claimed {
+			return errors.New("an approval covers the claimed amount; use partially_approved for less")
+		}
+	case insurance.ClaimPartiallyApproved:
+		if decision.ApprovedAmount <= 0 || decision.ApprovedAmount >= claimed {
+			return errors.New("a partial approval must be more than zero and less than the claimed amount")
+		}
+	case insurance.ClaimRejected:
+		if decision.ApprovedAmount != 0 {
+			return errors.New("a rejection can not approve an amount")
+		}
+		decision.RejectionReason = strings.TrimSpace(decision.RejectionReason)
+		if decision.RejectionReason == "" {
+			return errors.New("a reason is required to reject a claim")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package validations
+
+import (
+	"dental-clinic-system/models/insurance"
+	"testing"
+)
+
+func TestInsurancePolicyValidation(t *testing.T) {
+	valid := func() insurance.Policy {
+		return insurance.Policy{PatientID: 1, ProviderID: 2, PolicyNumber: " AX-1 ", CoveragePercent: 80, AnnualLimit: 1000000, ValidFrom: "2026-03-01"}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(p *insurance.Policy)
+		wantErr bool
+	}{
+		{
+			name:   "Open ended policy",
+			change: func(p *insurance.Policy) {},
+		},
+		{
+			name:   "Policy without an annual limit",
+			change: func(p *insurance.Policy) { p.AnnualLimit = 0; p.ValidTo = "2027-02-28" },
+		},
+		{
+			name:    "Missing policy number",
+			change:  func(p *insurance.Policy) { p.PolicyNumber = "  " },
+			wantErr: true,
+		},
+		{
+			name:    "Coverage above 100 percent",
+			change:  func(p *insurance.Policy) { p.CoveragePercent = 110 },
+			wantErr: true,
+		},
+		{
+			name:    "No coverage",
+			change:  func(p *insurance.Policy) { p.CoveragePercent = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Negative annual limit",
+			change:  func(p *insurance.Policy) { p.AnnualLimit = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "Ends before it starts",
+			change:  func(p *insurance.Policy) { p.ValidTo = "2026-02-28" },
+			wantErr: true,
+		},
+		{
+			name:    "Invalid start date",
+			change:  func(p *insurance.Policy) { p.ValidFrom = "01.03.2026" },
+			wantErr: true,
+		},
+		{
+			name:    "Lower case currency",
+			change:  func(p *insurance.Policy) { p.Currency = "try" },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := valid()
+			tt.change(&policy)
+			err := InsurancePolicyValidation(&policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InsurancePolicyValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (policy.PolicyNumber != "AX-1" || policy.Currency != "TRY") {
+				t.Errorf("policy number %q currency %q, want trimmed number and default currency", policy.PolicyNumber, policy.Currency)
+			}
+		})
+	}
+}
+
+func TestClaimDecisionValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		decision     insurance.Decision
+		wantApproved int64
+		wantErr      bool
+	}{
+		{
+			name:         "Approval defaults to the claimed amount",
+			decision:     insurance.Decision{Status: insurance.ClaimApproved},
+			wantApproved: 40000,
+		},
+		{
+			name:     "Approval of a different amount",
+			decision: insurance.Decision{Status: insurance.ClaimApproved, ApprovedAmount: 30000},
+			wantErr:  true,
+		},
+		{
+			name:         "Partial approval",
+			decision:     insurance.Decision{Status: insurance.ClaimPartiallyApproved, ApprovedAmount: 25000},
+			wantApproved: 25000,
+		},
+		{
+			name:     "Partial approval of the whole claim",
+			decision: insurance.Decision{Status: insurance.ClaimPartiallyApproved, ApprovedAmount: 40000},
+			wantErr:  true,
+		},
+		{
+			name:     "Rejection with a reason",
+			decision: insurance.Decision{Status: insurance.ClaimRejected, RejectionReason: "Not covered"},
+		},
+		{
+			name:     "Rejection without a reason",
+			decision: insurance.Decision{Status: insurance.ClaimRejected},
+			wantErr:  true,
+		},
+		{
+			name:     "Paid is not a decision",
+			decision: insurance.Decision{Status: insurance.ClaimPaid},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClaimDecisionValidation(&tt.decision, 40000)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClaimDecisionValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.decision.ApprovedAmount != tt.wantApproved {
+				t.Errorf("ApprovedAmount = %d, want %d", tt.decision.ApprovedAmount, tt.wantApproved)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package validations
+
+import (
+	"dental-clinic-system/models/medical"
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AllergyValidation checks the allergen, category and severity of an allergy
+func AllergyValidation(allergy *medical.Allergy) error {
+	if allergy.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	allergy.Allergen = strings.TrimSpace(allergy.Allergen)
+	if allergy.Allergen == "" {
+		return errors.New("allergen is required")
+	}
+
+	if allergy.Category == "" {
+		allergy.Category = medical.AllergyOther
+	}
+	if !allergy.Category.IsValid() {
+		return fmt.Errorf("unknown allergy category %q", allergy.Category)
+	}
+
+	if !allergy.Severity.IsValid() {
+		return fmt.Errorf("unknown severity %q", allergy.Severity)
+	}
+
+	return nil
+}
+
+// MedicationValidation checks the name, class and dates of a medication
+func MedicationValidation(medication *medical.Medication) error {
+	if medication.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	medication.Name = strings.TrimSpace(medication.Name)
+	if medication.Name == "" {
+		return errors.New("medication name is required")
+	}
+
+	if medication.Class == "" {
+		medication.Class = medical.ClassOther
+	}
+	if !medication.Class.IsValid() {
+		return fmt.Errorf("unknown medication class %q", medication.Class)
+	}
+
+	return dateRangeValidation("start_date", medication.StartDate, "end_date", medication.EndDate)
+}
+
+// ConditionValidation checks the code and dates of a systemic condition
+func ConditionValidation(condition *medical.Condition) error {
+	if condition.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	if !condition.Code.IsValid() {
+		return fmt.Errorf("unknown condition %q", condition.Code)
+	}
+
+	condition.Name = strings.TrimSpace(condition.Name)
+	if condition.Code == medical.ConditionOther && condition.Name == "" {
+		return errors.New("name is required for other conditions")
+	}
+
+	return dateRangeValidation("diagnosed_on", condition.DiagnosedOn, "resolved_on", condition.ResolvedOn)
+}
+
+// QuestionnaireValidation checks that a questionnaire is dated and answers every question exactly once
+func QuestionnaireValidation(questionnaire *medical.Questionnaire) error {
+	if questionnaire.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	if _, err := time.Parse(schedule.DateLayout, questionnaire.AnsweredOn); err != nil {
+		return errors.New("answered_on must be YYYY-MM-DD")
+	}
+
+	answered := map[string]bool{}
+	for _, answer := range questionnaire.Answers {
+		if _, ok := medical.QuestionByKey(answer.Question); !ok {
+			return fmt.Errorf("unknown question %q", answer.Question)
+		}
+		if answered[answer.Question] {
+			return fmt.Errorf("question %q is answered twice", answer.Question)
+		}
+		answered[answer.Question] = true
+	}
+
+	for _, question := range medical.Questions {
+		if !answered[question.Key] {
+			return fmt.Errorf("question %q is not answered", question.Key)
+		}
+	}
+
+	return nil
+}
+
+// dateRangeValidation checks two optional YYYY-MM-DD dates and that the second is not before the first
+func dateRangeValidation(fromName, from, toName, to string) error {
+	if from != "" {
+		if _, err := time.Parse(schedule.DateLayout, from); err != nil {
+			return fmt.Errorf("%s must be YYYY-MM-DD", fromName)
+		}
+	}
+	if to != "" {
+		if _, err := time.Parse(schedule.DateLayout, to); err != nil {
+			return fmt.Errorf("%s must be YYYY-MM-DD", toName)
+		}
+	}
+	if from != "" && to != "" && to < from {
+		return fmt.Errorf("%s can not be before %s", toName, fromName)
+	}
+	return nil
+}
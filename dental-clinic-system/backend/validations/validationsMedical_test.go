@@ -0,0 +1,194 @@
+package validations
+
+import (
+	"dental-clinic-system/models/medical"
+	"testing"
+)
+
+func TestAllergyValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		allergy      medical.Allergy
+		wantCategory medical.AllergyCategory
+		wantErr      bool
+	}{
+		{
+			name:         "Drug allergy",
+			allergy:      medical.Allergy{PatientID: 1, Allergen: " Penicillin ", Category: medical.AllergyDrug, Severity: medical.SeverityLifeThreatening},
+			wantCategory: medical.AllergyDrug,
+		},
+		{
+			name:         "Category defaults to other",
+			allergy:      medical.Allergy{PatientID: 1, Allergen: "Latex", Severity: medical.SeverityMild},
+			wantCategory: medical.AllergyOther,
+		},
+		{
+			name:    "Missing allergen",
+			allergy: medical.Allergy{PatientID: 1, Allergen: "  ", Severity: medical.SeverityMild},
+			wantErr: true,
+		},
+		{
+			name:    "Missing severity",
+			allergy: medical.Allergy{PatientID: 1, Allergen: "Latex"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown category",
+			allergy: medical.Allergy{PatientID: 1, Allergen: "Pollen", Category: "seasonal", Severity: medical.SeverityMild},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AllergyValidation(&tt.allergy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AllergyValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.allergy.Category != tt.wantCategory {
+				t.Errorf("Category = %q, want %q", tt.allergy.Category, tt.wantCategory)
+			}
+		})
+	}
+}
+
+func TestMedicationValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		medication medical.Medication
+		wantErr    bool
+	}{
+		{
+			name:       "Current anticoagulant",
+			medication: medical.Medication{PatientID: 1, Name: "Warfarin", Class: medical.ClassAnticoagulant, StartDate: "2024-01-10"},
+		},
+		{
+			name:       "Finished course",
+			medication: medical.Medication{PatientID: 1, Name: "Amoxicillin", StartDate: "2025-02-01", EndDate: "2025-02-07"},
+		},
+		{
+			name:       "Ends before it starts",
+			medication: medical.Medication{PatientID: 1, Name: "Amoxicillin", StartDate: "2025-02-07", EndDate: "2025-02-01"},
+			wantErr:    true,
+		},
+		{
+			name:       "Invalid start date",
+			medication: medical.Medication{PatientID: 1, Name: "Warfarin", StartDate: "10.01.2024"},
+			wantErr:    true,
+		},
+		{
+			name:       "Unknown class",
+			medication: medical.Medication{PatientID: 1, Name: "Warfarin", Class: "blood_thinner"},
+			wantErr:    true,
+		},
+		{
+			name:       "Missing name",
+			medication: medical.Medication{PatientID: 1},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MedicationValidation(&tt.medication)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MedicationValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition medical.Condition
+		wantErr   bool
+	}{
+		{
+			name:      "Diabetes",
+			condition: medical.Condition{PatientID: 1, Code: medical.ConditionDiabetes, DiagnosedOn: "2019-05-01"},
+		},
+		{
+			name:      "Resolved pregnancy",
+			condition: medical.Condition{PatientID: 1, Code: medical.ConditionPregnancy, DiagnosedOn: "2024-01-01", ResolvedOn: "2024-09-15"},
+		},
+		{
+			name:      "Other condition with a name",
+			condition: medical.Condition{PatientID: 1, Code: medical.ConditionOther, Name: "Sjögren syndrome"},
+		},
+		{
+			name:      "Other condition without a name",
+			condition: medical.Condition{PatientID: 1, Code: medical.ConditionOther},
+			wantErr:   true,
+		},
+		{
+			name:      "Unknown code",
+			condition: medical.Condition{PatientID: 1, Code: "flu"},
+			wantErr:   true,
+		},
+		{
+			name:      "Resolved before diagnosed",
+			condition: medical.Condition{PatientID: 1, Code: medical.ConditionHypertension, DiagnosedOn: "2024-01-01", ResolvedOn: "2023-01-01"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConditionValidation(&tt.condition)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConditionValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuestionnaireValidation(t *testing.T) {
+	allAnswered := func() []medical.QuestionnaireAnswer {
+		var answers []medical.QuestionnaireAnswer
+		for _, question := range medical.Questions {
+			answers = append(answers, medical.QuestionnaireAnswer{Question: question.Key})
+		}
+		return answers
+	}
+
+	tests := []struct {
+		name          string
+		questionnaire medical.Questionnaire
+		wantErr       bool
+	}{
+		{
+			name:          "Every question answered",
+			questionnaire: medical.Questionnaire{PatientID: 1, AnsweredOn: "2025-03-01", Answers: allAnswered()},
+		},
+		{
+			name:          "Missing date",
+			questionnaire: medical.Questionnaire{PatientID: 1, Answers: allAnswered()},
+			wantErr:       true,
+		},
+		{
+			name:          "Unanswered question",
+			questionnaire: medical.Questionnaire{PatientID: 1, AnsweredOn: "2025-03-01", Answers: allAnswered()[1:]},
+			wantErr:       true,
+		},
+		{
+			name:          "Question answered twice",
+			questionnaire: medical.Questionnaire{PatientID: 1, AnsweredOn: "2025-03-01", Answers: append(allAnswered(), medical.QuestionnaireAnswer{Question: "smoking", Answer: true})},
+			wantErr:       true,
+		},
+		{
+			name:          "Unknown question",
+			questionnaire: medical.Questionnaire{PatientID: 1, AnsweredOn: "2025-03-01", Answers: append(allAnswered(), medical.QuestionnaireAnswer{Question: "favourite_colour"})},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := QuestionnaireValidation(&tt.questionnaire)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("QuestionnaireValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package validations
+
+import (
+	"dental-clinic-system/models/odontogram"
+	"errors"
+	"fmt"
+)
+
+// OdontogramFindingValidation checks the tooth number, condition and surfaces of a finding
+// and rewrites its surfaces in canonical order
+func OdontogramFindingValidation(finding *odontogram.Finding) error {
+	if finding.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	if _, err := odontogram.ToothDentition(finding.Tooth); err != nil {
+		return err
+	}
+
+	if !finding.Condition.IsValid() {
+		return fmt.Errorf("unknown condition %q", finding.Condition)
+	}
+
+	surfaces, err := odontogram.ParseSurfaces(finding.Tooth, finding.Surfaces)
+	if err != nil {
+		return err
+	}
+	if finding.Condition.HasSurfaces() && surfaces == "" {
+		return fmt.Errorf("%s must be charted on at least one surface", finding.Condition)
+	}
+	if !finding.Condition.HasSurfaces() && surfaces != "" {
+		return fmt.Errorf("%s applies to the whole tooth and takes no surfaces", finding.Condition)
+	}
+	finding.Surfaces = surfaces
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package validations
+
+import (
+	"dental-clinic-system/models/odontogram"
+	"testing"
+)
+
+func TestOdontogramFindingValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		finding      odontogram.Finding
+		wantSurfaces string
+		wantErr      bool
+	}{
+		{
+			name:         "Caries on a permanent molar",
+			finding:      odontogram.Finding{PatientID: 1, Tooth: 36, Condition: odontogram.ConditionCaries, Surfaces: "dom"},
+			wantSurfaces: "MOD",
+		},
+		{
+			name:         "Filling on a primary incisor",
+			finding:      odontogram.Finding{PatientID: 1, Tooth: 51, Condition: odontogram.ConditionFilling, Surfaces: "IMI"},
+			wantSurfaces: "MI",
+		},
+		{
+			name:    "Whole tooth condition",
+			finding: odontogram.Finding{PatientID: 1, Tooth: 18, Condition: odontogram.ConditionMissing},
+		},
+		{
+			name:    "Permanent tooth 9 does not exist",
+			finding: odontogram.Finding{PatientID: 1, Tooth: 19, Condition: odontogram.ConditionMissing},
+			wantErr: true,
+		},
+		{
+			name:    "Primary dentition has no molar 6",
+			finding: odontogram.Finding{PatientID: 1, Tooth: 56, Condition: odontogram.ConditionMissing},
+			wantErr: true,
+		},
+		{
+			name:    "Incisor has no occlusal surface",
+			finding: odontogram.Finding{PatientID: 1, Tooth: 11, Condition: odontogram.ConditionCaries, Surfaces: "O"},
+			wantErr: true,
+		},
+		{
+			name:    "Caries without surfaces",
+			finding: odontogram.Finding{PatientID: 1, Tooth: 46, Condition: odontogram.ConditionCaries},
+			wantErr: true,
+		},
+		{
+			name:    "Crown with surfaces",
+			finding: odontogram.Finding{PatientID: 1, Tooth: 46, Condition: odontogram.ConditionCrown, Surfaces: "O"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown condition",
+			finding: odontogram.Finding{PatientID: 1, Tooth: 46, Condition: "bridge"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := OdontogramFindingValidation(&tt.finding)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OdontogramFindingValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.finding.Surfaces != tt.wantSurfaces {
+				t.Errorf("Surfaces = %q, want %q", tt.finding.Surfaces, tt.wantSurfaces)
+			}
+		})
+	}
+}
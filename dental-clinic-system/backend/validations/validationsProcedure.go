@@ -0,0 +1,78 @@
+package validations
+
+import (
+	"dental-clinic-system/models/procedure"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func ProcedureValidation(proc *procedure.Procedure) error {
+	if strings.TrimSpace(proc.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	proc.Code = strings.TrimSpace(proc.Code)
+	codeRegex := `^[A-Za-z0-9][A-Za-z0-9./-]{0,19}$`
+	if proc.Code != "" && !regexp.MustCompile(codeRegex).MatchString(proc.Code) {
+		return errors.New("code must be up to 20 letters, digits, dots, slashes or dashes")
+	}
+
+	if proc.DefaultDurationMinutes < 0 || proc.DefaultDurationMinutes > 480 {
+		return errors.New("default duration must be between 0 and 480 minutes")
+	}
+
+	if proc.VATRate < 0 || proc.VATRate > 100 {
+		return errors.New("vat rate must be a percentage between 0 and 100")
+	}
+
+	return nil
+}
+
+// PriceListValidation checks a price list and its entries. Entries refer to a procedure by ID or,
+// failing that, by code, and may name each procedure only once.
+func PriceListValidation(list *procedure.PriceList) error {
+	if _, err := time.Parse(procedure.DateLayout, list.EffectiveFrom); err != nil {
+		return errors.New("effective_from must be a date in YYYY-MM-DD format")
+	}
+
+	if list.Currency == "" {
+		list.Currency = procedure.DefaultCurrency
+	}
+	currencyRegex := `^[A-Z]{3}$`
+	if !regexp.MustCompile(currencyRegex).MatchString(list.Currency) {
+		return errors.New("currency must be a three letter ISO 4217 code")
+	}
+
+	if len(list.Entries) == 0 {
+		return errors.New("at least one price is required")
+	}
+
+	seenIDs := make(map[uint]bool, len(list.Entries))
+	seenCodes := make(map[string]bool, len(list.Entries))
+	for i := range list.Entries {
+		entry := &list.Entries[i]
+		entry.Code = strings.TrimSpace(entry.Code)
+		switch {
+		case entry.ProcedureID != 0:
+			if seenIDs[entry.ProcedureID] {
+				return fmt.Errorf("entry %d: procedure %d is listed more than once", i+1, entry.ProcedureID)
+			}
+			seenIDs[entry.ProcedureID] = true
+		case entry.Code != "":
+			if seenCodes[entry.Code] {
+				return fmt.Errorf("entry %d: procedure code %s is listed more than once", i+1, entry.Code)
+			}
+			seenCodes[entry.Code] = true
+		default:
+			return fmt.Errorf("entry %d: procedure or procedure code is required", i+1)
+		}
+		if entry.Price < 0 {
+			return fmt.Errorf("entry %d: price can not be negative", i+1)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package validations
+
+import (
+	"dental-clinic-system/models/resource"
+	"errors"
+	"strings"
+)
+
+func ResourceValidation(res *resource.Resource) error {
+	if strings.TrimSpace(res.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	if !res.Type.IsValid() {
+		return errors.New("type must be chair, room or equipment")
+	}
+
+	return nil
+}
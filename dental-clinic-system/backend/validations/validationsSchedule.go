@@ -0,0 +1,98 @@
+package validations
+
+import (
+	"dental-clinic-system/models/schedule"
+	"errors"
+	"time"
+)
+
+func OpeningHoursValidation(hours *schedule.OpeningHours) error {
+	if err := WeekdayValidation(hours.Weekday); err != nil {
+		return err
+	}
+
+	return ClockRangeValidation(hours.OpensAt, hours.ClosesAt)
+}
+
+func DoctorScheduleValidation(doctorSchedule *schedule.DoctorSchedule) error {
+	if err := WeekdayValidation(doctorSchedule.Weekday); err != nil {
+		return err
+	}
+
+	if err := ClockRangeValidation(doctorSchedule.StartTime, doctorSchedule.EndTime); err != nil {
+		return err
+	}
+
+	start, _ := schedule.ParseClock(doctorSchedule.StartTime)
+	end, _ := schedule.ParseClock(doctorSchedule.EndTime)
+	for _, scheduleBreak := range doctorSchedule.Breaks {
+		if err := ClockRangeValidation(scheduleBreak.StartTime, scheduleBreak.EndTime); err != nil {
+			return err
+		}
+
+		breakStart, _ := schedule.ParseClock(scheduleBreak.StartTime)
+		breakEnd, _ := schedule.ParseClock(scheduleBreak.EndTime)
+		if breakStart < start || breakEnd > end {
+			return errors.New("breaks must be inside the working time")
+		}
+	}
+
+	return nil
+}
+
+func ScheduleExceptionValidation(exception *schedule.ScheduleException) error {
+	switch exception.Type {
+	case schedule.ExceptionLeave, schedule.ExceptionSickLeave, schedule.ExceptionClinicClosure, schedule.ExceptionHoliday:
+	default:
+		return errors.New("unknown schedule exception type")
+	}
+
+	startDate, err := time.Parse(schedule.DateLayout, exception.StartDate)
+	if err != nil {
+		return errors.New("start date must be in YYYY-MM-DD format")
+	}
+
+	if exception.EndDate == "" {
+		exception.EndDate = exception.StartDate
+	}
+	endDate, err := time.Parse(schedule.DateLayout, exception.EndDate)
+	if err != nil {
+		return errors.New("end date must be in YYYY-MM-DD format")
+	}
+
+	if endDate.Before(startDate) {
+		return errors.New("end date can not be before start date")
+	}
+
+	if exception.StartTime == "" && exception.EndTime == "" {
+		return nil
+	}
+
+	return ClockRangeValidation(exception.StartTime, exception.EndTime)
+}
+
+func WeekdayValidation(weekday time.Weekday) error {
+	if weekday < time.Sunday || weekday > time.Saturday {
+		return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+
+	return nil
+}
+
+func ClockRangeValidation(from, to string) error {
+	start, err := schedule.ParseClock(from)
+	if err != nil {
+		return err
+	}
+
+	end, err := schedule.ParseClock(to)
+	if err != nil {
+		return err
+	}
+
+	if end <= start {
+		return errors.New("end time must be after start time")
+	}
+
+	return nil
+}
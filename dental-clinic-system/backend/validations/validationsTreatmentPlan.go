@@ -0,0 +1,81 @@
+package validations
+
+import (
+	"dental-clinic-system/models/odontogram"
+	"dental-clinic-system/models/treatment"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TreatmentPlanValidation checks a plan with its phases and items, numbers them in the given order
+// and rewrites item surfaces in canonical order
+func TreatmentPlanValidation(plan *treatment.Plan) error {
+	if plan.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	if strings.TrimSpace(plan.Title) == "" {
+		return errors.New("title is required")
+	}
+
+	if plan.Currency == "" {
+		plan.Currency = treatment.DefaultCurrency
+	}
+	currencyRegex := `^[A-Z]{3}$`
+	if !regexp.MustCompile(currencyRegex).MatchString(plan.Currency) {
+		return errors.New("currency must be a three letter ISO 4217 code")
+	}
+
+	if len(plan.Phases) == 0 {
+		return errors.New("at least one phase is required")
+	}
+
+	for i := range plan.Phases {
+		phase := &plan.Phases[i]
+		if strings.TrimSpace(phase.Name) == "" {
+			return fmt.Errorf("phase %d: name is required", i+1)
+		}
+		phase.Position = i + 1
+
+		for j := range phase.Items {
+			item := &phase.Items[j]
+			if err := treatmentPlanItemValidation(item); err != nil {
+				return fmt.Errorf("phase %d item %d: %w", i+1, j+1, err)
+			}
+			item.Position = j + 1
+		}
+	}
+
+	return nil
+}
+
+func treatmentPlanItemValidation(item *treatment.Item) error {
+	if item.ProcedureID == 0 {
+		return errors.New("procedure is required")
+	}
+
+	if item.EstimatedPrice < 0 {
+		return errors.New("estimated price can not be negative")
+	}
+
+	if item.Tooth == nil {
+		if item.Surfaces != "" {
+			return errors.New("surfaces require a tooth")
+		}
+		return nil
+	}
+
+	if _, err := odontogram.ToothDentition(*item.Tooth); err != nil {
+		return err
+	}
+
+	surfaces, err := odontogram.ParseSurfaces(*item.Tooth, item.Surfaces)
+	if err != nil {
+		return err
+	}
+	item.Surfaces = surfaces
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package validations
+
+import (
+	"dental-clinic-system/models/waitlist"
+	"errors"
+)
+
+func WaitlistEntryValidation(entry *waitlist.Entry) error {
+	if entry.PatientID == 0 {
+		return errors.New("patient is required")
+	}
+
+	if entry.DurationMinutes < 0 {
+		return errors.New("duration can not be negative")
+	}
+
+	for _, window := range entry.Windows {
+		if err := WeekdayValidation(window.Weekday); err != nil {
+			return err
+		}
+
+		if err := ClockRangeValidation(window.StartTime, window.EndTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"bytes"
 	"html/template"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,9 +12,17 @@ import (
 )
 
 type EmailMessage struct {
-	To   string            `json:"to"`
-	Type string            `json:"type"` // verification, password_reset, notification
-	Data map[string]string `json:"data,omitempty"`
+	To          string            `json:"to"`
+	Type        string            `json:"type"` // verification, password_reset, appointment-reminder, waitlist-offer, installment-reminder, document
+	Data        map[string]string `json:"data,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+}
+
+// Attachment is a file sent along with the email; Content arrives base64 encoded in the JSON message
+type Attachment struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
 }
 
 type Mailer interface {
@@ -38,6 +47,14 @@ func NewEmailService(mailer Mailer) *EmailService {
 func (s *EmailService) SendEmail(msg EmailMessage) error {
 	if msg.Type == "verification" {
 		return s.sendVerificationEmail(msg.To, msg.Data["token"])
+	} else if msg.Type == "appointment-reminder" {
+		return s.sendAppointmentReminderEmail(msg.To, msg.Data)
+	} else if msg.Type == "waitlist-offer" {
+		return s.sendWaitlistOfferEmail(msg.To, msg.Data)
+	} else if msg.Type == "installment-reminder" {
+		return s.sendInstallmentReminderEmail(msg.To, msg.Data)
+	} else if msg.Type == "document" {
+		return s.sendDocumentEmail(msg.To, msg.Data, msg.Attachments)
 	} else {
 		return s.sendPasswordResetEmail(msg.To, msg.Data["token"])
 	}
@@ -62,6 +79,72 @@ func (s *EmailService) sendPasswordResetEmail(email, token string) error {
 	)
 }
 
+func (s *EmailService) sendAppointmentReminderEmail(email string, data map[string]string) error {
+	return s.sendTemplateEmail(
+		email,
+		"Randevu Hatırlatması",
+		"templates/appointment_reminder_email.html",
+		map[string]string{
+			"PATIENT_NAME":     data["patient_name"],
+			"CLINIC_NAME":      data["clinic_name"],
+			"CLINIC_ADDRESS":   data["clinic_address"],
+			"CLINIC_PHONE":     data["clinic_phone"],
+			"DOCTOR_NAME":      data["doctor_name"],
+			"APPOINTMENT_TIME": data["appointment_time"],
+		},
+	)
+}
+
+func (s *EmailService) sendWaitlistOfferEmail(email string, data map[string]string) error {
+	return s.sendTemplateEmail(
+		email,
+		"Erken Randevu Fırsatı",
+		"templates/waitlist_offer_email.html",
+		map[string]string{
+			"PATIENT_NAME":     data["patient_name"],
+			"CLINIC_NAME":      data["clinic_name"],
+			"DOCTOR_NAME":      data["doctor_name"],
+			"TREATMENT":        data["treatment"],
+			"APPOINTMENT_TIME": data["appointment_time"],
+			"EXPIRES_AT":       data["expires_at"],
+			"ACCEPT_LINK":      os.Getenv("FRONTEND_URL") + "/waitlist/accept?token=" + data["token"],
+		},
+	)
+}
+
+func (s *EmailService) sendInstallmentReminderEmail(email string, data map[string]string) error {
+	return s.sendTemplateEmail(
+		email,
+		"Taksit Ödeme Hatırlatması",
+		"templates/installment_reminder_email.html",
+		map[string]string{
+			"PATIENT_NAME":   data["patient_name"],
+			"CLINIC_NAME":    data["clinic_name"],
+			"CLINIC_PHONE":   data["clinic_phone"],
+			"INVOICE_NUMBER": data["invoice_number"],
+			"INSTALLMENT":    data["installment"],
+			"DUE_DATE":       data["due_date"],
+			"DAYS_OVERDUE":   data["days_overdue"],
+			"AMOUNT_DUE":     data["amount_due"],
+		},
+	)
+}
+
+func (s *EmailService) sendDocumentEmail(email string, data map[string]string, attachments []Attachment) error {
+	return s.sendTemplateEmail(
+		email,
+		data["document_title"],
+		"templates/document_email.html",
+		map[string]string{
+			"PATIENT_NAME":   data["patient_name"],
+			"CLINIC_NAME":    data["clinic_name"],
+			"CLINIC_PHONE":   data["clinic_phone"],
+			"DOCUMENT_TITLE": data["document_title"],
+		},
+		attachments...,
+	)
+}
+
 //func (s *EmailService) sendNotificationEmail(to, subject, body string) error {
 //	return s.sendPlainEmail(to, subject, body)
 //}
@@ -76,7 +159,7 @@ func (s *EmailService) sendPasswordResetEmail(email, token string) error {
 //	return s.mailer.SendMail(*m)
 //}
 
-func (s *EmailService) sendTemplateEmail(to, subject, templateFile string, data map[string]string) error {
+func (s *EmailService) sendTemplateEmail(to, subject, templateFile string, data map[string]string, attachments ...Attachment) error {
 	// Template dosyasının tam yolunu oluştur
 	templatePath := filepath.Join("templates", filepath.Base(templateFile))
 
@@ -98,6 +181,16 @@ func (s *EmailService) sendTemplateEmail(to, subject, templateFile string, data
 	m.SetHeader("To", to)
 	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", body.String())
+	for _, attachment := range attachments {
+		content := attachment.Content
+		m.Attach(attachment.FileName,
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(content)
+				return err
+			}),
+			gomail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}),
+		)
+	}
 
 	return s.mailer.SendMail(*m)
 }
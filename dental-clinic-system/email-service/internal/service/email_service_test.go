@@ -1,8 +1,13 @@
 package service
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
+	"io"
+	"mime/quotedprintable"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,6 +52,17 @@ func setupTestTemplates(t *testing.T) {
     <p>Click the link below to reset your password:</p>
     <a href="{{.RESET_LINK}}">Reset Password</a>
 </body>
+</html>`
+
+	appointmentReminderTemplate := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Appointment Reminder</title>
+</head>
+<body>
+    <h1>Appointment Reminder</h1>
+    <p>{{.CLINIC_NAME}} - {{.DOCTOR_NAME}} - {{.APPOINTMENT_TIME}}</p>
+</body>
 </html>`
 
 	err = os.WriteFile("templates/verification_email.html", []byte(verificationTemplate), 0644)
@@ -54,6 +70,52 @@ func setupTestTemplates(t *testing.T) {
 
 	err = os.WriteFile("templates/password_reset_email.html", []byte(passwordResetTemplate), 0644)
 	assert.NoError(t, err)
+
+	waitlistOfferTemplate := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Waitlist Offer</title>
+</head>
+<body>
+    <h1>An earlier appointment is available</h1>
+    <p>{{.CLINIC_NAME}} - {{.DOCTOR_NAME}} - {{.APPOINTMENT_TIME}}</p>
+    <a href="{{.ACCEPT_LINK}}">Accept</a>
+</body>
+</html>`
+
+	err = os.WriteFile("templates/appointment_reminder_email.html", []byte(appointmentReminderTemplate), 0644)
+	assert.NoError(t, err)
+
+	err = os.WriteFile("templates/waitlist_offer_email.html", []byte(waitlistOfferTemplate), 0644)
+	assert.NoError(t, err)
+
+	installmentReminderTemplate := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Installment Reminder</title>
+</head>
+<body>
+    <h1>Installment overdue</h1>
+    <p>{{.INVOICE_NUMBER}} - {{.INSTALLMENT}} - {{.DUE_DATE}} - {{.AMOUNT_DUE}}</p>
+</body>
+</html>`
+
+	err = os.WriteFile("templates/installment_reminder_email.html", []byte(installmentReminderTemplate), 0644)
+	assert.NoError(t, err)
+
+	documentTemplate := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Document</title>
+</head>
+<body>
+    <h1>{{.DOCUMENT_TITLE}}</h1>
+    <p>{{.PATIENT_NAME}} - {{.CLINIC_NAME}}</p>
+</body>
+</html>`
+
+	err = os.WriteFile("templates/document_email.html", []byte(documentTemplate), 0644)
+	assert.NoError(t, err)
 }
 
 // cleanupTestTemplates removes test template files
@@ -217,6 +279,187 @@ func TestEmailService_SendEmail_MissingToken(t *testing.T) {
 	mockMailer.AssertExpectations(t)
 }
 
+func TestEmailService_SendEmail_AppointmentReminderType(t *testing.T) {
+	// Setup
+	mockMailer := &MockMailer{}
+	service := NewEmailService(mockMailer)
+
+	// Setup test templates
+	setupTestTemplates(t)
+	defer cleanupTestTemplates()
+
+	os.Setenv("SMTP_FROM", "test@example.com")
+	defer os.Unsetenv("SMTP_FROM")
+
+	// Mock expectations - the rendered body must contain the clinic, doctor and time
+	mockMailer.On("SendMail", mock.MatchedBy(func(message gomail.Message) bool {
+		var body bytes.Buffer
+		if _, err := message.WriteTo(&body); err != nil {
+			return false
+		}
+		return strings.Contains(body.String(), "Smile Clinic - Mehmet Demir - 04.03.2025 07:00")
+	})).Return(nil)
+
+	// Test data
+	emailMsg := EmailMessage{
+		To:   "user@example.com",
+		Type: "appointment-reminder",
+		Data: map[string]string{
+			"clinic_name":      "Smile Clinic",
+			"doctor_name":      "Mehmet Demir",
+			"appointment_time": "04.03.2025 07:00",
+		},
+	}
+
+	// Execute
+	err := service.SendEmail(emailMsg)
+
+	// Assert
+	assert.NoError(t, err)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestEmailService_SendEmail_WaitlistOfferType(t *testing.T) {
+	// Setup
+	mockMailer := &MockMailer{}
+	service := NewEmailService(mockMailer)
+
+	// Setup test templates
+	setupTestTemplates(t)
+	defer cleanupTestTemplates()
+
+	os.Setenv("FRONTEND_URL", "http://localhost:3000")
+	os.Setenv("SMTP_FROM", "test@example.com")
+	defer func() {
+		os.Unsetenv("FRONTEND_URL")
+		os.Unsetenv("SMTP_FROM")
+	}()
+
+	// Mock expectations - the rendered body must contain the accept link with the offer token
+	mockMailer.On("SendMail", mock.MatchedBy(func(message gomail.Message) bool {
+		var body bytes.Buffer
+		if _, err := message.WriteTo(&body); err != nil {
+			return false
+		}
+		decoded, err := io.ReadAll(quotedprintable.NewReader(&body))
+		if err != nil {
+			return false
+		}
+		return strings.Contains(string(decoded), "http://localhost:3000/waitlist/accept?token=offer-token-123")
+	})).Return(nil)
+
+	// Test data
+	emailMsg := EmailMessage{
+		To:   "user@example.com",
+		Type: "waitlist-offer",
+		Data: map[string]string{
+			"clinic_name":      "Smile Clinic",
+			"doctor_name":      "Mehmet Demir",
+			"appointment_time": "04.03.2025 07:00",
+			"token":            "offer-token-123",
+		},
+	}
+
+	// Execute
+	err := service.SendEmail(emailMsg)
+
+	// Assert
+	assert.NoError(t, err)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestEmailService_SendEmail_InstallmentReminderType(t *testing.T) {
+	// Setup
+	mockMailer := &MockMailer{}
+	service := NewEmailService(mockMailer)
+
+	// Setup test templates
+	setupTestTemplates(t)
+	defer cleanupTestTemplates()
+
+	os.Setenv("SMTP_FROM", "test@example.com")
+	defer os.Unsetenv("SMTP_FROM")
+
+	// Mock expectations - the rendered body must contain the invoice, installment, due date and amount
+	mockMailer.On("SendMail", mock.MatchedBy(func(message gomail.Message) bool {
+		var body bytes.Buffer
+		if _, err := message.WriteTo(&body); err != nil {
+			return false
+		}
+		return strings.Contains(body.String(), "2026-000042 - 2/6 - 01.03.2026 - 1.250,00 TRY")
+	})).Return(nil)
+
+	// Test data
+	emailMsg := EmailMessage{
+		To:   "user@example.com",
+		Type: "installment-reminder",
+		Data: map[string]string{
+			"clinic_name":    "Smile Clinic",
+			"invoice_number": "2026-000042",
+			"installment":    "2/6",
+			"due_date":       "01.03.2026",
+			"days_overdue":   "4",
+			"amount_due":     "1.250,00 TRY",
+		},
+	}
+
+	// Execute
+	err := service.SendEmail(emailMsg)
+
+	// Assert
+	assert.NoError(t, err)
+	mockMailer.AssertExpectations(t)
+}
+
+func TestEmailService_SendEmail_DocumentType(t *testing.T) {
+	// Setup
+	mockMailer := &MockMailer{}
+	service := NewEmailService(mockMailer)
+
+	// Setup test templates
+	setupTestTemplates(t)
+	defer cleanupTestTemplates()
+
+	os.Setenv("SMTP_FROM", "test@example.com")
+	defer os.Unsetenv("SMTP_FROM")
+
+	pdf := []byte("%PDF-1.4 test document")
+
+	// Mock expectations - the document title is the subject and the PDF is attached
+	mockMailer.On("SendMail", mock.MatchedBy(func(message gomail.Message) bool {
+		var body bytes.Buffer
+		if _, err := message.WriteTo(&body); err != nil {
+			return false
+		}
+		raw := body.String()
+		return strings.Contains(raw, "Ali Veli - Smile Clinic") &&
+			strings.Contains(raw, `filename="invoice-2026-000042.pdf"`) &&
+			strings.Contains(raw, "Content-Type: application/pdf") &&
+			strings.Contains(raw, base64.StdEncoding.EncodeToString(pdf))
+	})).Return(nil)
+
+	// Test data
+	emailMsg := EmailMessage{
+		To:   "user@example.com",
+		Type: "document",
+		Data: map[string]string{
+			"patient_name":   "Ali Veli",
+			"clinic_name":    "Smile Clinic",
+			"document_title": "Fatura 2026-000042",
+		},
+		Attachments: []Attachment{
+			{FileName: "invoice-2026-000042.pdf", ContentType: "application/pdf", Content: pdf},
+		},
+	}
+
+	// Execute
+	err := service.SendEmail(emailMsg)
+
+	// Assert
+	assert.NoError(t, err)
+	mockMailer.AssertExpectations(t)
+}
+
 func TestEmailService_SendVerificationEmail(t *testing.T) {
 	// Setup
 	mockMailer := &MockMailer{}